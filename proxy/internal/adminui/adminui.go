@@ -0,0 +1,43 @@
+// Package adminui serves a small embedded admin single-page app under /admin.
+// It is static files only: every action goes through the existing JSON endpoints,
+// which enforce JWT auth and roles exactly as they do for any other client.
+package adminui
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+//go:embed static
+var staticFiles embed.FS
+
+// Handler returns the /admin/ file server
+func Handler() http.Handler {
+	assets, err := fs.Sub(staticFiles, "static")
+	if err != nil {
+		// The embedded directory is fixed at build time, so this is a programming error
+		panic(err)
+	}
+	files := http.StripPrefix("/admin/", http.FileServer(http.FS(assets)))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		// index.html must always be revalidated so a deploy is picked up; assets can be cached briefly
+		name := path.Base(r.URL.Path)
+		if strings.HasSuffix(r.URL.Path, "/") || name == "index.html" {
+			w.Header().Set("Cache-Control", "no-cache")
+		} else {
+			w.Header().Set("Cache-Control", "public, max-age=3600")
+		}
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("X-Frame-Options", "DENY")
+		files.ServeHTTP(w, r)
+	})
+}
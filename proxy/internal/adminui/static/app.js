@@ -0,0 +1,194 @@
+// Minimal admin UI: every call goes to the proxy's existing JSON endpoints with the JWT
+// from /login; the server enforces auth and roles, this page only renders responses.
+(function () {
+  "use strict";
+
+  const TOKEN_KEY = "proxyAdminToken";
+  const ROLE_KEY = "proxyAdminRole";
+
+  const $ = (id) => document.getElementById(id);
+
+  function token() {
+    return sessionStorage.getItem(TOKEN_KEY);
+  }
+
+  async function api(path, options) {
+    const headers = { Accept: "application/json" };
+    if (token()) {
+      headers.Authorization = "Bearer " + token();
+    }
+    const response = await fetch(path, Object.assign({ headers: headers }, options));
+    if (response.status === 401) {
+      logout();
+      throw new Error("session expired, please sign in again");
+    }
+    const body = await response.json().catch(() => ({}));
+    if (!response.ok) {
+      throw new Error(body.error || "request failed with status " + response.status);
+    }
+    return body;
+  }
+
+  // Text-only rendering: values from the API are never interpreted as HTML
+  function el(tag, text, className) {
+    const node = document.createElement(tag);
+    if (text !== undefined && text !== null) {
+      node.textContent = String(text);
+    }
+    if (className) {
+      node.className = className;
+    }
+    return node;
+  }
+
+  function table(headers, rows) {
+    const t = el("table");
+    const head = el("tr");
+    headers.forEach((h) => head.appendChild(el("th", h)));
+    t.appendChild(head);
+    rows.forEach((cells) => {
+      const tr = el("tr");
+      cells.forEach((cell) => tr.appendChild(cell instanceof Node ? wrap(cell) : el("td", cell)));
+      t.appendChild(tr);
+    });
+    return t;
+  }
+
+  function wrap(node) {
+    const td = el("td");
+    td.appendChild(node);
+    return td;
+  }
+
+  function showError(container, err) {
+    container.replaceChildren(el("p", err.message, "error"));
+  }
+
+  async function loadStatus() {
+    const container = $("tab-status");
+    try {
+      const [status, ready] = await Promise.all([
+        api("/__proxy/status"),
+        fetch("/readyz").then((r) => r.json()),
+      ]);
+      const rows = Object.entries(status).map(([key, value]) => [key, value]);
+      rows.push(["ready", ready.ready + (ready.reason ? " (" + ready.reason + ")" : "")]);
+      container.replaceChildren(table(["Setting", "Value"], rows));
+    } catch (err) {
+      showError(container, err);
+    }
+  }
+
+  async function loadSchema() {
+    const container = $("tab-schema");
+    try {
+      const schema = await api("/__proxy/schema");
+      const rows = Object.entries(schema.tables || {}).map(([key, t]) => [
+        key,
+        t.logical_name,
+        t.table_id,
+        (t.operations || []).join(", "),
+        Object.keys(t.links || {}).join(", "),
+      ]);
+      container.replaceChildren(
+        el("p", "Mode: " + schema.mode),
+        table(["Key", "Name", "Table ID", "Operations", "Links"], rows)
+      );
+    } catch (err) {
+      showError(container, err);
+    }
+  }
+
+  async function loadSummaries() {
+    const container = $("tab-summaries");
+    try {
+      const response = await api("/proxy/_summaries");
+      const rows = Object.entries(response.summaries || {}).map(([name, s]) => {
+        const refresh = el("button", "Recompute");
+        refresh.addEventListener("click", async () => {
+          refresh.disabled = true;
+          try {
+            await api("/proxy/_summaries/" + encodeURIComponent(name) + "/refresh");
+          } catch (err) {
+            alert(err.message);
+          }
+          loadSummaries();
+        });
+        return [
+          name,
+          s.value === null ? "—" : s.value,
+          s.computed_at || "never",
+          el("span", s.stale ? "stale" + (s.error ? ": " + s.error : "") : "fresh", s.stale ? "stale" : ""),
+          refresh,
+        ];
+      });
+      container.replaceChildren(table(["Name", "Value", "Computed at", "State", ""], rows));
+    } catch (err) {
+      showError(container, err);
+    }
+  }
+
+  async function loadErrors() {
+    const container = $("tab-errors");
+    try {
+      const catalog = await api("/__proxy/errors");
+      const rows = (catalog.errors || []).map((e) => [e.code, e.status, e.retryable ? "yes" : "no", e.description]);
+      container.replaceChildren(table(["Code", "Status", "Retryable", "Description"], rows));
+    } catch (err) {
+      showError(container, err);
+    }
+  }
+
+  const loaders = { status: loadStatus, schema: loadSchema, summaries: loadSummaries, errors: loadErrors };
+
+  function selectTab(name) {
+    document.querySelectorAll("nav button").forEach((b) => b.classList.toggle("active", b.dataset.tab === name));
+    document.querySelectorAll(".tab").forEach((t) => (t.hidden = t.id !== "tab-" + name));
+    loaders[name]();
+  }
+
+  function showAdmin() {
+    $("login-view").hidden = true;
+    $("admin-view").hidden = false;
+    $("logout").hidden = false;
+    $("whoami").textContent = "role: " + sessionStorage.getItem(ROLE_KEY);
+    selectTab("status");
+  }
+
+  function logout() {
+    sessionStorage.removeItem(TOKEN_KEY);
+    sessionStorage.removeItem(ROLE_KEY);
+    $("login-view").hidden = false;
+    $("admin-view").hidden = true;
+    $("logout").hidden = true;
+    $("whoami").textContent = "";
+  }
+
+  $("login-form").addEventListener("submit", async (event) => {
+    event.preventDefault();
+    const form = new FormData(event.target);
+    $("login-error").textContent = "";
+    try {
+      const result = await api("/login", {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ email: form.get("email"), password: form.get("password") }),
+      });
+      if (result.role !== "admin") {
+        throw new Error("this account is not an admin");
+      }
+      sessionStorage.setItem(TOKEN_KEY, result.token);
+      sessionStorage.setItem(ROLE_KEY, result.role);
+      showAdmin();
+    } catch (err) {
+      $("login-error").textContent = err.message;
+    }
+  });
+
+  $("logout").addEventListener("click", logout);
+  document.querySelectorAll("nav button").forEach((b) => b.addEventListener("click", () => selectTab(b.dataset.tab)));
+
+  if (token()) {
+    showAdmin();
+  }
+})();
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/grove/generic-proxy/internal/db"
+	"github.com/markbates/goth"
+)
+
+// Email collision policies: what happens when an OAuth sign-in matches an existing user's email
+// but comes from a different provider than the one the user was created with
+const (
+	CollisionStrict        = "strict"         // only the original provider or explicitly linked ones
+	CollisionVerifiedMerge = "verified-merge" // other providers too, if they assert the email is verified
+	CollisionLegacy        = "legacy"         // match by email alone
+)
+
+// collisionDecision is the outcome of checking a sign-in against the policy; Reason goes to the audit log
+type collisionDecision struct {
+	Allowed bool
+	Link    bool // remember the provider as a linked identity
+	Reason  string
+}
+
+// decideCollision applies the policy to a sign-in through provider for an existing user.
+// linked says whether provider was already linked to the user.
+func decideCollision(policy string, existing *db.User, provider string, linked, emailVerified bool) collisionDecision {
+	if existing.Provider == provider {
+		return collisionDecision{Allowed: true, Reason: "same_provider"}
+	}
+	if linked {
+		return collisionDecision{Allowed: true, Reason: "linked_identity"}
+	}
+
+	switch policy {
+	case CollisionLegacy:
+		return collisionDecision{Allowed: true, Reason: "legacy_email_match"}
+	case CollisionVerifiedMerge:
+		if emailVerified {
+			return collisionDecision{Allowed: true, Link: true, Reason: "verified_merge"}
+		}
+		return collisionDecision{Reason: "unverified_email"}
+	default:
+		return collisionDecision{Reason: "provider_mismatch"}
+	}
+}
+
+// collisionMessage explains a rejected sign-in to the user
+func collisionMessage(existing *db.User, provider string) string {
+	return fmt.Sprintf("an account with this email already exists and signs in with %s; sign in with %s or ask an administrator to link your %s account",
+		existing.Provider, existing.Provider, provider)
+}
+
+// emailVerified reports whether the provider asserted the email is verified. Google returns
+// "email_verified" (OpenID) or "verified_email" (userinfo v2); providers that say nothing are unverified.
+func emailVerified(user goth.User) bool {
+	for _, key := range []string{"email_verified", "verified_email"} {
+		switch value := user.RawData[key].(type) {
+		case bool:
+			return value
+		case string:
+			return strings.EqualFold(value, "true")
+		}
+	}
+	return false
+}
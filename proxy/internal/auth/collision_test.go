@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gorilla/sessions"
+	"github.com/grove/generic-proxy/internal/db"
+	"github.com/markbates/goth"
+	"github.com/markbates/goth/gothic"
+)
+
+func TestDecideCollision(t *testing.T) {
+	existing := &db.User{ID: 1, Email: "admin@example.com", Provider: "github"}
+	tests := []struct {
+		policy   string
+		provider string
+		linked   bool
+		verified bool
+		allowed  bool
+		link     bool
+		reason   string
+	}{
+		{CollisionStrict, "github", false, false, true, false, "same_provider"},
+		{CollisionStrict, "google", true, false, true, false, "linked_identity"},
+		{CollisionStrict, "google", false, true, false, false, "provider_mismatch"},
+		{CollisionStrict, "google", false, false, false, false, "provider_mismatch"},
+		{CollisionVerifiedMerge, "github", false, false, true, false, "same_provider"},
+		{CollisionVerifiedMerge, "google", true, false, true, false, "linked_identity"},
+		{CollisionVerifiedMerge, "google", false, true, true, true, "verified_merge"},
+		{CollisionVerifiedMerge, "google", false, false, false, false, "unverified_email"},
+		{CollisionLegacy, "google", false, false, true, false, "legacy_email_match"},
+		{"", "google", false, true, false, false, "provider_mismatch"}, // unset means strict
+	}
+	for _, tt := range tests {
+		got := decideCollision(tt.policy, existing, tt.provider, tt.linked, tt.verified)
+		if got.Allowed != tt.allowed || got.Link != tt.link || got.Reason != tt.reason {
+			t.Errorf("policy %q, provider %s, linked %v, verified %v: %+v; want allowed %v, link %v, reason %s",
+				tt.policy, tt.provider, tt.linked, tt.verified, got, tt.allowed, tt.link, tt.reason)
+		}
+	}
+}
+
+func TestEmailVerified(t *testing.T) {
+	for _, tt := range []struct {
+		raw  map[string]interface{}
+		want bool
+	}{
+		{map[string]interface{}{"email_verified": true}, true},
+		{map[string]interface{}{"verified_email": true}, true},
+		{map[string]interface{}{"email_verified": "true"}, true},
+		{map[string]interface{}{"email_verified": false, "verified_email": true}, false},
+		{map[string]interface{}{"email_verified": "yes"}, false},
+		{nil, false},
+	} {
+		if got := emailVerified(goth.User{RawData: tt.raw}); got != tt.want {
+			t.Errorf("emailVerified(%v) = %v, want %v", tt.raw, got, tt.want)
+		}
+	}
+}
+
+// newCollisionHandler returns a handler with policy whose database has admin@example.com,
+// created through GitHub
+func newCollisionHandler(t *testing.T, policy string) (*Handler, *db.Database) {
+	t.Helper()
+	database, err := db.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	if _, err := database.CreateUser("admin@example.com", "github", "Admin", ""); err != nil {
+		t.Fatalf("seed user: %v", err)
+	}
+
+	previous := gothic.Store
+	gothic.Store = sessions.NewCookieStore([]byte("test-session-secret"))
+	t.Cleanup(func() { gothic.Store = previous })
+
+	h := NewHandler(database, "test-jwt-secret-that-is-long-enough", "http://frontend")
+	h.CollisionPolicy = policy
+	return h, database
+}
+
+func signIn(h *Handler, user goth.User) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.completeSignIn(rec, httptest.NewRequest(http.MethodGet, "/auth/"+user.Provider+"/callback", nil), user)
+	return rec
+}
+
+func googleUser(email string, verified bool) goth.User {
+	return goth.User{Email: email, Provider: "google", Name: "Someone", RawData: map[string]interface{}{"email_verified": verified}}
+}
+
+func TestSignInCollisionPolicies(t *testing.T) {
+	tests := []struct {
+		policy     string
+		user       goth.User
+		wantStatus int
+	}{
+		{CollisionStrict, goth.User{Email: "admin@example.com", Provider: "github"}, http.StatusTemporaryRedirect},
+		{CollisionStrict, googleUser("admin@example.com", true), http.StatusForbidden},
+		{CollisionStrict, googleUser("new@example.com", false), http.StatusTemporaryRedirect},
+		{CollisionVerifiedMerge, googleUser("admin@example.com", true), http.StatusTemporaryRedirect},
+		{CollisionVerifiedMerge, googleUser("admin@example.com", false), http.StatusForbidden},
+		{CollisionLegacy, googleUser("admin@example.com", false), http.StatusTemporaryRedirect},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy+" "+tt.user.Provider+" "+tt.user.Email, func(t *testing.T) {
+			h, _ := newCollisionHandler(t, tt.policy)
+			rec := signIn(h, tt.user)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus == http.StatusForbidden && rec.Header().Get("Location") != "" {
+				t.Error("a rejected sign-in was redirected with a token")
+			}
+		})
+	}
+}
+
+func TestVerifiedMergeLinksIdentity(t *testing.T) {
+	h, database := newCollisionHandler(t, CollisionVerifiedMerge)
+	if rec := signIn(h, googleUser("admin@example.com", true)); rec.Code != http.StatusTemporaryRedirect {
+		t.Fatalf("verified sign-in: status = %d", rec.Code)
+	}
+	admin, err := database.GetUserByEmail("admin@example.com")
+	if err != nil {
+		t.Fatalf("GetUserByEmail: %v", err)
+	}
+	if linked, err := database.HasIdentity(admin.ID, "google"); err != nil || !linked {
+		t.Fatalf("google linked = %v (err %v) after a verified merge", linked, err)
+	}
+
+	// Once linked the identity counts under strict too, verified or not
+	h.CollisionPolicy = CollisionStrict
+	if rec := signIn(h, googleUser("admin@example.com", false)); rec.Code != http.StatusTemporaryRedirect {
+		t.Errorf("linked identity under strict: status = %d, want 307", rec.Code)
+	}
+}
@@ -2,12 +2,17 @@ package auth
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/grove/generic-proxy/internal/db"
+	"github.com/grove/generic-proxy/internal/middleware"
+	"github.com/grove/generic-proxy/internal/utils"
+	"github.com/markbates/goth"
 	"github.com/markbates/goth/gothic"
 )
 
@@ -15,6 +20,16 @@ type Handler struct {
 	database    *db.Database
 	jwtSecret   string
 	frontendURL string
+
+	// CollisionPolicy decides whether a sign-in may use an account created with another provider
+	// (strict, verified-merge or legacy)
+	CollisionPolicy string
+
+	// Cookies, when set, also hands the token to the browser in the auth cookie and clears it on logout
+	Cookies *middleware.CookieAuth
+
+	// TokenTTL is the lifetime of the tokens issued after an OAuth sign-in
+	TokenTTL time.Duration
 }
 
 type AuthResponse struct {
@@ -27,9 +42,11 @@ type AuthResponse struct {
 
 func NewHandler(database *db.Database, jwtSecret, frontendURL string) *Handler {
 	return &Handler{
-		database:    database,
-		jwtSecret:   jwtSecret,
-		frontendURL: frontendURL,
+		database:        database,
+		jwtSecret:       jwtSecret,
+		frontendURL:     frontendURL,
+		CollisionPolicy: CollisionStrict,
+		TokenTTL:        utils.DefaultTokenTTL,
 	}
 }
 
@@ -54,11 +71,55 @@ func (h *Handler) CallbackAuth(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log.Printf("[AUTH] OAuth successful - Email: %s, Provider: %s, Name: %s",
+	log.Printf("[AUTH] OAuth successful - Email: %s, Provider: %s, Name: %q",
 		gothUser.Email, gothUser.Provider, gothUser.Name)
+	h.completeSignIn(w, r, gothUser)
+}
+
+// completeSignIn signs in the user the provider vouched for: it applies the email collision
+// policy, saves the user and redirects to the frontend with a token
+func (h *Handler) completeSignIn(w http.ResponseWriter, r *http.Request, gothUser goth.User) {
+	database := h.database.WithContext(r.Context())
+
+	// An existing account may only be entered through a provider the collision policy accepts
+	existing, err := database.GetUserByEmail(gothUser.Email)
+	if err != nil {
+		log.Printf("[AUTH ERROR] Failed to look up user: %v", err)
+		http.Error(w, "Failed to save user", http.StatusInternalServerError)
+		return
+	}
+	if existing != nil {
+		linked, err := database.HasIdentity(existing.ID, gothUser.Provider)
+		if err != nil {
+			http.Error(w, "Failed to save user", http.StatusInternalServerError)
+			return
+		}
+
+		decision := decideCollision(h.CollisionPolicy, existing, gothUser.Provider, linked, emailVerified(gothUser))
+		log.Printf("[AUDIT] OAuth sign-in user=%d email=%s provider=%s account_provider=%s policy=%s allowed=%v reason=%s",
+			existing.ID, existing.Email, gothUser.Provider, existing.Provider, h.CollisionPolicy, decision.Allowed, decision.Reason)
+		if !decision.Allowed {
+			if err := gothic.Logout(w, r); err != nil {
+				log.Printf("[AUTH WARN] Failed to clear gothic session after rejected sign-in: %v", err)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]string{
+				"error":  collisionMessage(existing, gothUser.Provider),
+				"reason": decision.Reason,
+			})
+			return
+		}
+		if decision.Link {
+			if err := database.LinkIdentity(existing.ID, gothUser.Provider); err != nil {
+				http.Error(w, "Failed to save user", http.StatusInternalServerError)
+				return
+			}
+		}
+	}
 
 	// Save or update user in database
-	user, err := h.database.CreateUser(
+	user, err := database.CreateUser(
 		gothUser.Email,
 		gothUser.Provider,
 		gothUser.Name,
@@ -80,7 +141,7 @@ func (h *Handler) CallbackAuth(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Generate JWT token
-	token, err := GenerateJWT(user.ID, user.Email, user.Provider, role, h.jwtSecret)
+	token, err := GenerateJWT(user.ID, user.Email, user.Provider, role, h.jwtSecret, utils.TokenOptions{TTL: h.TokenTTL})
 	if err != nil {
 		log.Printf("[AUTH ERROR] Failed to generate JWT: %v", err)
 		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
@@ -88,7 +149,19 @@ func (h *Handler) CallbackAuth(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Printf("[AUTH] JWT generated successfully for user: %s", user.Email)
+
+	// The OAuth session has served its purpose; don't let it linger until max-age
+	if err := gothic.Logout(w, r); err != nil {
+		log.Printf("[AUTH WARN] Failed to clear gothic session after callback: %v", err)
+	}
 	log.Printf("[AUTH] Token preview: %s...%s (length: %d)", token[:20], token[len(token)-20:], len(token))
+	if h.Cookies != nil {
+		if err := h.Cookies.SetTokenCookies(w, token, h.TokenTTL); err != nil {
+			log.Printf("[AUTH ERROR] Failed to set auth cookies: %v", err)
+			http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+			return
+		}
+	}
 
 	// Redirect to frontend callback page with token in URL
 	callbackURL := fmt.Sprintf("%s/auth/callback?token=%s&user_id=%d&email=%s&role=%s",
@@ -113,6 +186,25 @@ func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
 		log.Printf("[AUTH WARN] Failed to clear gothic session: %v", err)
 	}
 
+	// Blocklist the presented token, which would otherwise stay valid until it expires
+	tokenString, _, err := middleware.BearerToken(r, h.Cookies)
+	if errors.Is(err, middleware.ErrCSRFTokenMismatch) {
+		http.Error(w, "Forbidden: "+err.Error(), http.StatusForbidden)
+		return
+	}
+	if err == nil {
+		if claims, err := ValidateJWT(tokenString, h.jwtSecret); err == nil && claims.ID != "" && claims.ExpiresAt != nil {
+			if err := h.database.RevokeToken(claims.ID, claims.ExpiresAt.Time); err != nil {
+				http.Error(w, "Failed to revoke token", http.StatusInternalServerError)
+				return
+			}
+			log.Printf("[AUTH] Revoked token of user %s", claims.UserID)
+		}
+	}
+	if h.Cookies != nil {
+		h.Cookies.ClearTokenCookies(w)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
 		"message": "Logged out successfully",
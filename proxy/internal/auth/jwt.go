@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/grove/generic-proxy/internal/utils"
 )
 
 type JWTClaims struct {
@@ -18,16 +19,22 @@ type JWTClaims struct {
 }
 
 // GenerateJWT creates a new JWT token with user claims
-func GenerateJWT(userID int64, email, provider, role, secret string) (string, error) {
+func GenerateJWT(userID int64, email, provider, role, secret string, opts utils.TokenOptions) (string, error) {
+	jti, err := utils.NewTokenID()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate token ID: %w", err)
+	}
+	now := time.Now()
 	claims := JWTClaims{
 		UserID:   strconv.FormatInt(userID, 10),
 		Email:    email,
 		Provider: provider,
 		Role:     role,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(opts.Expiry(now)),
+			IssuedAt:  jwt.NewNumericDate(now),
 			Subject:   email,
+			ID:        jti,
 		},
 	}
 
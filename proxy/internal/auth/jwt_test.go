@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/utils"
+	"github.com/markbates/goth"
+)
+
+func TestGenerateJWTExpiry(t *testing.T) {
+	tests := []struct {
+		ttl  time.Duration
+		want time.Duration
+	}{
+		{time.Hour, time.Hour},
+		{90 * time.Second, 90 * time.Second},
+		{0, utils.DefaultTokenTTL},
+	}
+	for _, tt := range tests {
+		token, err := GenerateJWT(1, "a@example.com", "github", "user", "secret", utils.TokenOptions{TTL: tt.ttl})
+		if err != nil {
+			t.Fatalf("GenerateJWT: %v", err)
+		}
+		claims, err := ValidateJWT(token, "secret")
+		if err != nil {
+			t.Fatalf("ValidateJWT: %v", err)
+		}
+		if got := claims.ExpiresAt.Sub(claims.IssuedAt.Time); got != tt.want {
+			t.Errorf("TTL %v: exp - iat = %v, want %v", tt.ttl, got, tt.want)
+		}
+	}
+}
+
+func TestSignInTokenUsesConfiguredTTL(t *testing.T) {
+	h, _ := newCollisionHandler(t, CollisionStrict)
+	h.TokenTTL = 2 * time.Hour
+
+	rec := signIn(h, goth.User{Email: "admin@example.com", Provider: "github"})
+	if rec.Code != http.StatusTemporaryRedirect {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	location, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("Location: %v", err)
+	}
+	claims, err := ValidateJWT(location.Query().Get("token"), h.jwtSecret)
+	if err != nil {
+		t.Fatalf("ValidateJWT: %v", err)
+	}
+	if got := time.Until(claims.ExpiresAt.Time); got < 2*time.Hour-time.Minute || got > 2*time.Hour {
+		t.Errorf("token expires in %v, want 2h", got)
+	}
+}
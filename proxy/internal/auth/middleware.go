@@ -2,33 +2,35 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
-	"strings"
+
+	"github.com/grove/generic-proxy/internal/middleware"
 )
 
-// AuthMiddleware validates JWT tokens on protected routes
-func AuthMiddleware(jwtSecret string) func(http.Handler) http.Handler {
+// AuthMiddleware validates JWT tokens on protected routes, rejecting tokens on the blocklist.
+// With cookies set, the token may also come from the auth cookie (see middleware.BearerToken).
+func AuthMiddleware(jwtSecret string, blocklist middleware.TokenBlocklist, cookies *middleware.CookieAuth) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Extract token from Authorization header
-			authHeader := r.Header.Get("Authorization")
-			if authHeader == "" {
+			// Extract token from Authorization header or the auth cookie
+			tokenString, _, err := middleware.BearerToken(r, cookies)
+			switch {
+			case errors.Is(err, middleware.ErrNoToken):
 				log.Printf("[AUTH MIDDLEWARE] No Authorization header found")
 				http.Error(w, "Unauthorized: No token provided", http.StatusUnauthorized)
 				return
-			}
-
-			// Check for Bearer token format
-			parts := strings.Split(authHeader, " ")
-			if len(parts) != 2 || parts[0] != "Bearer" {
+			case errors.Is(err, middleware.ErrInvalidAuthHeader):
 				log.Printf("[AUTH MIDDLEWARE] Invalid Authorization header format")
 				http.Error(w, "Unauthorized: Invalid token format", http.StatusUnauthorized)
 				return
+			case err != nil:
+				log.Printf("[AUTH MIDDLEWARE] %v", err)
+				http.Error(w, "Forbidden: "+err.Error(), http.StatusForbidden)
+				return
 			}
 
-			tokenString := parts[1]
-
 			// Validate JWT
 			claims, err := ValidateJWT(tokenString, jwtSecret)
 			if err != nil {
@@ -37,6 +39,19 @@ func AuthMiddleware(jwtSecret string) func(http.Handler) http.Handler {
 				return
 			}
 
+			if blocklist != nil && claims.ID != "" {
+				revoked, err := blocklist.IsTokenRevoked(claims.ID)
+				if err != nil {
+					http.Error(w, "Failed to verify token", http.StatusInternalServerError)
+					return
+				}
+				if revoked {
+					log.Printf("[AUTH MIDDLEWARE] Revoked token presented by user %s", claims.UserID)
+					http.Error(w, "Unauthorized: Token has been revoked", http.StatusUnauthorized)
+					return
+				}
+			}
+
 			log.Printf("[AUTH MIDDLEWARE] Token validated for user: %s (ID: %s)", claims.Email, claims.UserID)
 
 			// Add claims to request context
@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grove/generic-proxy/internal/utils"
+)
+
+func TestLogoutRevokesToken(t *testing.T) {
+	h, database := newCollisionHandler(t, CollisionStrict)
+	user, err := database.GetUserByEmail("admin@example.com")
+	if err != nil {
+		t.Fatalf("load user: %v", err)
+	}
+	token, err := GenerateJWT(user.ID, user.Email, user.Provider, "user", h.jwtSecret, utils.TokenOptions{})
+	if err != nil {
+		t.Fatalf("GenerateJWT: %v", err)
+	}
+	other, _ := GenerateJWT(user.ID, user.Email, user.Provider, "user", h.jwtSecret, utils.TokenOptions{})
+
+	protected := AuthMiddleware(h.jwtSecret, database, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	request := func(handler http.Handler, path, token string) int {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := request(protected, "/api/me", token); code != http.StatusOK {
+		t.Fatalf("before logout: status %d", code)
+	}
+	if code := request(http.HandlerFunc(h.Logout), "/auth/logout", token); code != http.StatusOK {
+		t.Fatalf("logout: status %d", code)
+	}
+	if code := request(protected, "/api/me", token); code != http.StatusUnauthorized {
+		t.Errorf("after logout: status %d, want 401", code)
+	}
+	if code := request(protected, "/api/me", other); code != http.StatusOK {
+		t.Errorf("another token of the same user: status %d, want it still valid", code)
+	}
+}
@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"unicode"
+)
+
+//go:embed common_passwords.txt
+var builtinDenylist string
+
+// Password denylist settings (PASSWORD_DENYLIST); anything else is a file path
+const (
+	DenylistBuiltin = "builtin" // the embedded list of common passwords
+	DenylistOff     = "off"     // no denylist
+)
+
+// PasswordPolicy decides which passwords local accounts may use
+type PasswordPolicy struct {
+	MinLength  int
+	MinEntropy float64 // estimated bits, 0 = no entropy check
+
+	denylist map[string]bool // lower-cased
+}
+
+// NewPasswordPolicy creates a policy. denylist is DenylistBuiltin, DenylistOff or the path of a
+// file with one password per line, which is checked in addition to the built-in list.
+func NewPasswordPolicy(minLength int, minEntropy float64, denylist string) (*PasswordPolicy, error) {
+	policy := &PasswordPolicy{
+		MinLength:  minLength,
+		MinEntropy: minEntropy,
+		denylist:   make(map[string]bool),
+	}
+
+	switch denylist {
+	case DenylistOff, "":
+		return policy, nil
+	case DenylistBuiltin:
+		policy.addDenylist(builtinDenylist)
+	default:
+		data, err := os.ReadFile(denylist)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read password denylist: %w", err)
+		}
+		policy.addDenylist(builtinDenylist)
+		policy.addDenylist(string(data))
+	}
+	return policy, nil
+}
+
+// addDenylist adds one password per line, skipping blank lines and # comments
+func (p *PasswordPolicy) addDenylist(list string) {
+	scanner := bufio.NewScanner(strings.NewReader(list))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p.denylist[strings.ToLower(line)] = true
+	}
+}
+
+// DenylistSize returns how many passwords are denied outright
+func (p *PasswordPolicy) DenylistSize() int {
+	return len(p.denylist)
+}
+
+// Check returns a client-facing reason when the password is not acceptable
+func (p *PasswordPolicy) Check(password string) error {
+	if len([]rune(password)) < p.MinLength {
+		return fmt.Errorf("password must be at least %d characters", p.MinLength)
+	}
+	if p.denylist[strings.ToLower(password)] {
+		return fmt.Errorf("password is too common, please choose another one")
+	}
+	if p.MinEntropy > 0 && passwordEntropy(password) < p.MinEntropy {
+		return fmt.Errorf("password is too easy to guess: use a longer password or mix letters, digits and symbols")
+	}
+	return nil
+}
+
+// passwordEntropy estimates a password's strength in bits: its length times the information per
+// character, which is bounded both by the character classes used and by how often characters repeat
+// (so "aaaaaaaaaaaa" scores low despite its length)
+func passwordEntropy(password string) float64 {
+	runes := []rune(password)
+	if len(runes) == 0 {
+		return 0
+	}
+
+	var lower, upper, digit, other bool
+	counts := make(map[rune]int)
+	for _, r := range runes {
+		counts[r]++
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+
+	pool := 0
+	for _, class := range []struct {
+		used bool
+		size int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {other, 33}} {
+		if class.used {
+			pool += class.size
+		}
+	}
+
+	// Shannon entropy of the character distribution
+	shannon := 0.0
+	for _, count := range counts {
+		frequency := float64(count) / float64(len(runes))
+		shannon -= frequency * math.Log2(frequency)
+	}
+
+	return float64(len(runes)) * math.Min(shannon, math.Log2(float64(pool)))
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPasswordPolicyCheck(t *testing.T) {
+	policy, err := NewPasswordPolicy(8, 40, DenylistBuiltin)
+	if err != nil {
+		t.Fatalf("NewPasswordPolicy: %v", err)
+	}
+	tests := []struct {
+		password string
+		want     string // substring of the error, "" = accepted
+	}{
+		{"short", "at least 8 characters"},
+		{"password", "too common"},
+		{"PassWord", "too common"}, // the denylist ignores case
+		{"12345678", "too common"},
+		{"aaaaaaaaaaaa", "too easy to guess"},
+		{"abcabcabcabc", "too easy to guess"},
+		{"correct-Horse7battery", ""},
+		{"vN4#qz8!Lp2w", ""},
+	}
+	for _, tt := range tests {
+		err := policy.Check(tt.password)
+		if tt.want == "" && err != nil {
+			t.Errorf("Check(%q) = %v, want it accepted", tt.password, err)
+		}
+		if tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+			t.Errorf("Check(%q) = %v, want %q", tt.password, err, tt.want)
+		}
+	}
+}
+
+func TestPasswordPolicyDenylistSettings(t *testing.T) {
+	off, err := NewPasswordPolicy(6, 0, DenylistOff)
+	if err != nil {
+		t.Fatalf("NewPasswordPolicy(off): %v", err)
+	}
+	if err := off.Check("password"); err != nil {
+		t.Errorf("denylist off: Check = %v, want the common password accepted", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "denylist.txt")
+	if err := os.WriteFile(path, []byte("# company words\nGroveQuotes\n\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	custom, err := NewPasswordPolicy(6, 0, path)
+	if err != nil {
+		t.Fatalf("NewPasswordPolicy(file): %v", err)
+	}
+	for _, password := range []string{"grovequotes", "password"} {
+		if err := custom.Check(password); err == nil {
+			t.Errorf("denylist file: %q accepted, want it denied along with the built-in list", password)
+		}
+	}
+	if custom.DenylistSize() <= 1 {
+		t.Errorf("denylist size = %d, want the file added to the built-in list", custom.DenylistSize())
+	}
+
+	if _, err := NewPasswordPolicy(6, 0, filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("missing denylist file accepted")
+	}
+}
+
+func TestPasswordEntropy(t *testing.T) {
+	if got := passwordEntropy(""); got != 0 {
+		t.Errorf("entropy of empty password = %v", got)
+	}
+	if weak, strong := passwordEntropy("aaaaaaaaaaaa"), passwordEntropy("aZ3$bY4%cX5^"); weak >= strong {
+		t.Errorf("repeated characters scored %.1f bits, mixed ones %.1f", weak, strong)
+	}
+}
@@ -0,0 +1,288 @@
+// Package comments stores discussion threads on NocoDB records in the proxy's own database,
+// so teams can talk about a record without adding columns to it.
+package comments
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/db"
+	"github.com/grove/generic-proxy/internal/httperr"
+	"github.com/grove/generic-proxy/internal/middleware"
+	"github.com/grove/generic-proxy/internal/proxy"
+)
+
+// Listing limits
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// Handler serves /proxy/{table}/records/{id}/comments[/{commentId}]
+type Handler struct {
+	database  *db.Database
+	proxy     *proxy.ProxyHandler // table permissions
+	maxLength int                 // comment body cap in characters
+}
+
+// NewHandler creates the comments handler
+func NewHandler(database *db.Database, proxyHandler *proxy.ProxyHandler, maxLength int) *Handler {
+	if maxLength <= 0 {
+		maxLength = DefaultMaxLength
+	}
+	return &Handler{database: database, proxy: proxyHandler, maxLength: maxLength}
+}
+
+// CommentResponse is the public view of a comment
+type CommentResponse struct {
+	ID        int64   `json:"id"`
+	Table     string  `json:"table"`
+	RecordID  string  `json:"record_id"`
+	UserID    string  `json:"user_id"`
+	Body      string  `json:"body"`
+	CreatedAt string  `json:"created_at"`
+	EditedAt  *string `json:"edited_at"`
+}
+
+// commentPath is a parsed comments URL
+type commentPath struct {
+	table     string
+	recordID  string
+	commentID int64 // 0 for the collection
+}
+
+// Wrap sends comment URLs to h and everything else under /proxy/ to next
+func (h *Handler) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := parseCommentPath(r.URL.Path); ok {
+			h.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ServeHTTP handles GET/POST on the collection and PATCH/DELETE on a single comment
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path, ok := parseCommentPath(r.URL.Path)
+	if !ok {
+		httperr.WriteError(w, httperr.InvalidPath, "invalid comments path")
+		return
+	}
+
+	// Database calls end with the request
+	h = &Handler{database: h.database.WithContext(r.Context()), proxy: h.proxy, maxLength: h.maxLength}
+
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	role, _ := r.Context().Value(middleware.RoleKey).(string)
+
+	// Comments follow the record's visibility: whoever can't read the record can't see or add comments
+	if err := h.proxy.CheckRead(r.Context(), path.table, path.recordID, userID, role); err != nil {
+		var validationErr *proxy.ValidationError
+		if errors.As(err, &validationErr) {
+			httperr.WriteError(w, validationErr.Code, validationErr.Message)
+			return
+		}
+		log.Printf("[COMMENTS ERROR] Failed to check access to %s/%s: %v", path.table, path.recordID, err)
+		httperr.WriteError(w, httperr.UpstreamReadFailed, "failed to check access to the record")
+		return
+	}
+
+	switch {
+	case path.commentID == 0 && r.Method == http.MethodGet:
+		h.list(w, r, path)
+	case path.commentID == 0 && r.Method == http.MethodPost:
+		h.create(w, r, path, userID)
+	case path.commentID != 0 && r.Method == http.MethodPatch:
+		h.update(w, r, path, userID, role)
+	case path.commentID != 0 && r.Method == http.MethodDelete:
+		h.delete(w, path, userID, role)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// list returns comments newest first; ?before= takes next_before from the previous page
+func (h *Handler) list(w http.ResponseWriter, r *http.Request, path commentPath) {
+	limit := defaultPageSize
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			httperr.WriteError(w, httperr.InvalidBody, "limit must be a positive integer")
+			return
+		}
+		limit = min(n, maxPageSize)
+	}
+	var before int64
+	if value := r.URL.Query().Get("before"); value != "" {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n <= 0 {
+			httperr.WriteError(w, httperr.InvalidBody, "before must be a comment id")
+			return
+		}
+		before = n
+	}
+
+	// One extra row tells whether another page exists
+	stored, err := h.database.ListComments(path.table, path.recordID, before, limit+1)
+	if err != nil {
+		log.Printf("[COMMENTS ERROR] Failed to list comments for %s/%s: %v", path.table, path.recordID, err)
+		http.Error(w, "failed to load comments", http.StatusInternalServerError)
+		return
+	}
+
+	var nextBefore *int64
+	if len(stored) > limit {
+		stored = stored[:limit]
+		nextBefore = &stored[limit-1].ID
+	}
+	comments := make([]CommentResponse, 0, len(stored))
+	for _, comment := range stored {
+		comments = append(comments, toResponse(comment))
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"comments":    comments,
+		"next_before": nextBefore,
+	})
+}
+
+func (h *Handler) create(w http.ResponseWriter, r *http.Request, path commentPath, userID string) {
+	body, ok := h.readBody(w, r)
+	if !ok {
+		return
+	}
+
+	comment, err := h.database.CreateComment(path.table, path.recordID, userID, body)
+	if err != nil {
+		http.Error(w, "failed to save comment", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[COMMENTS] User %s commented on %s/%s (comment %d)", userID, path.table, path.recordID, comment.ID)
+	writeJSON(w, http.StatusCreated, toResponse(comment))
+}
+
+func (h *Handler) update(w http.ResponseWriter, r *http.Request, path commentPath, userID, role string) {
+	if _, ok := h.ownedComment(w, path, userID, role); !ok {
+		return
+	}
+	body, ok := h.readBody(w, r)
+	if !ok {
+		return
+	}
+
+	comment, err := h.database.UpdateComment(path.commentID, body)
+	if errors.Is(err, db.ErrCommentNotFound) {
+		httperr.WriteError(w, httperr.CommentNotFound, "comment not found")
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to save comment", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[COMMENTS] User %s edited comment %d", userID, comment.ID)
+	writeJSON(w, http.StatusOK, toResponse(comment))
+}
+
+func (h *Handler) delete(w http.ResponseWriter, path commentPath, userID, role string) {
+	if _, ok := h.ownedComment(w, path, userID, role); !ok {
+		return
+	}
+
+	err := h.database.DeleteComment(path.commentID)
+	if errors.Is(err, db.ErrCommentNotFound) {
+		httperr.WriteError(w, httperr.CommentNotFound, "comment not found")
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to delete comment", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[COMMENTS] User %s deleted comment %d", userID, path.commentID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ownedComment loads the addressed comment and checks the caller wrote it (admins may act on any)
+func (h *Handler) ownedComment(w http.ResponseWriter, path commentPath, userID, role string) (*db.Comment, bool) {
+	comment, err := h.database.GetComment(path.commentID)
+	if errors.Is(err, db.ErrCommentNotFound) || (err == nil && (comment.TableAlias != path.table || comment.RecordID != path.recordID)) {
+		httperr.WriteError(w, httperr.CommentNotFound, "comment not found")
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("[COMMENTS ERROR] Failed to load comment %d: %v", path.commentID, err)
+		http.Error(w, "failed to load comment", http.StatusInternalServerError)
+		return nil, false
+	}
+	if comment.UserID != userID && role != "admin" {
+		httperr.WriteError(w, httperr.NotCommentAuthor, "only the author or an admin can change this comment")
+		return nil, false
+	}
+	return comment, true
+}
+
+// readBody decodes {"body": "..."} and sanitizes the text
+func (h *Handler) readBody(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var request struct {
+		Body string `json:"body"`
+	}
+	// Bytes, not characters: multi-byte text still fits well within 4x the cap
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, int64(h.maxLength)*4+1024)).Decode(&request); err != nil {
+		httperr.WriteError(w, httperr.InvalidBody, "request body must be JSON with a \"body\" string")
+		return "", false
+	}
+
+	body, err := sanitizeBody(request.Body, h.maxLength)
+	if err != nil {
+		httperr.WriteError(w, httperr.InvalidBody, err.Error())
+		return "", false
+	}
+	return body, true
+}
+
+// parseCommentPath matches /proxy/{table}/records/{id}/comments[/{commentId}]
+func parseCommentPath(urlPath string) (commentPath, bool) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(urlPath, "/proxy/"), "/"), "/")
+	if len(parts) < 4 || len(parts) > 5 || parts[1] != "records" || parts[3] != "comments" || parts[0] == "" || parts[2] == "" {
+		return commentPath{}, false
+	}
+
+	path := commentPath{table: parts[0], recordID: parts[2]}
+	if len(parts) == 5 {
+		id, err := strconv.ParseInt(parts[4], 10, 64)
+		if err != nil || id <= 0 {
+			return commentPath{}, false
+		}
+		path.commentID = id
+	}
+	return path, true
+}
+
+func toResponse(comment *db.Comment) CommentResponse {
+	response := CommentResponse{
+		ID:        comment.ID,
+		Table:     comment.TableAlias,
+		RecordID:  comment.RecordID,
+		UserID:    comment.UserID,
+		Body:      comment.Body,
+		CreatedAt: comment.CreatedAt.UTC().Format(time.RFC3339),
+	}
+	if comment.EditedAt != nil {
+		editedAt := comment.EditedAt.UTC().Format(time.RFC3339)
+		response.EditedAt = &editedAt
+	}
+	return response
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Printf("[COMMENTS ERROR] Failed to encode response: %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package comments
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/db"
+	"github.com/grove/generic-proxy/internal/middleware"
+	"github.com/grove/generic-proxy/internal/proxy"
+)
+
+// newTestHandler serves comments on table "quotes" (owner_field Owner) of a fake NocoDB where
+// record 5 belongs to user 7 and record 6 to user 8
+func newTestHandler(t *testing.T) (*Handler, *db.Database) {
+	t.Helper()
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v2/tables/t1/records/5":
+			io.WriteString(w, `{"Id":5,"Owner":"7"}`)
+		case "/api/v2/tables/t1/records/6":
+			io.WriteString(w, `{"Id":6,"Owner":"8"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `{"msg":"Record not found"}`)
+		}
+	}))
+	t.Cleanup(upstream.Close)
+
+	proxyHandler := proxy.NewProxyHandler(upstream.URL+"/api/v2/tables/", "test-token", nil, upstream.Client())
+	proxyHandler.SetResolvedConfig(&config.ResolvedConfig{BaseID: "base", Tables: map[string]config.ResolvedTable{
+		"quotes": {Name: "Quotes", TableID: "t1", Operations: []string{"read"}, OwnerField: "Owner"},
+	}})
+
+	database, err := db.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	return NewHandler(database, proxyHandler, 0), database
+}
+
+func serveComments(h *Handler, method, target, body, userID, role string) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, target, reader)
+	ctx := context.WithValue(req.Context(), middleware.UserIDKey, userID)
+	ctx = context.WithValue(ctx, middleware.RoleKey, role)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req.WithContext(ctx))
+	return rec
+}
+
+func errorCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body struct {
+		Code string `json:"code"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("error body %q is not JSON: %v", rec.Body, err)
+	}
+	return body.Code
+}
+
+func TestCommentsFollowRecordOwnership(t *testing.T) {
+	h, database := newTestHandler(t)
+	if _, err := database.CreateComment("quotes", "6", "8", "secret margin talk"); err != nil {
+		t.Fatalf("seed comment: %v", err)
+	}
+
+	// The owner of record 5 may comment on it and read the thread
+	if rec := serveComments(h, http.MethodPost, "/proxy/quotes/records/5/comments", `{"body":"looks good"}`, "7", "user"); rec.Code != http.StatusCreated {
+		t.Fatalf("owner create: status = %d, body %s", rec.Code, rec.Body)
+	}
+	if rec := serveComments(h, http.MethodGet, "/proxy/quotes/records/5/comments", "", "7", "user"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "looks good") {
+		t.Fatalf("owner list: status = %d, body %s", rec.Code, rec.Body)
+	}
+
+	// Someone else's record is invisible, comments included
+	rec := serveComments(h, http.MethodGet, "/proxy/quotes/records/6/comments", "", "7", "user")
+	if rec.Code != http.StatusNotFound || errorCode(t, rec) != "record_not_found" {
+		t.Errorf("foreign list: status = %d, body %s", rec.Code, rec.Body)
+	}
+	if strings.Contains(rec.Body.String(), "secret") {
+		t.Errorf("foreign list leaked a comment: %s", rec.Body)
+	}
+	if rec := serveComments(h, http.MethodPost, "/proxy/quotes/records/6/comments", `{"body":"hi"}`, "7", "user"); rec.Code != http.StatusNotFound {
+		t.Errorf("foreign create: status = %d, want 404", rec.Code)
+	}
+	stored, err := database.ListComments("quotes", "6", 0, 10)
+	if err != nil || len(stored) != 1 {
+		t.Errorf("record 6 has %d comments (err %v), want only the seeded one", len(stored), err)
+	}
+
+	// Missing records have no comments either
+	if rec := serveComments(h, http.MethodPost, "/proxy/quotes/records/9/comments", `{"body":"hi"}`, "7", "user"); rec.Code != http.StatusNotFound {
+		t.Errorf("missing record create: status = %d, want 404", rec.Code)
+	}
+
+	// Admins see every record's thread
+	if rec := serveComments(h, http.MethodGet, "/proxy/quotes/records/6/comments", "", "1", "admin"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "secret") {
+		t.Errorf("admin list: status = %d, body %s", rec.Code, rec.Body)
+	}
+}
+
+func TestCommentsUnknownTable(t *testing.T) {
+	h, _ := newTestHandler(t)
+	rec := serveComments(h, http.MethodGet, "/proxy/orders/records/5/comments", "", "7", "user")
+	if rec.Code != http.StatusNotFound || errorCode(t, rec) != "table_not_found" {
+		t.Errorf("status = %d, body %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,40 @@
+package comments
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultMaxLength is the comment body cap in characters
+const DefaultMaxLength = 4000
+
+// sanitizeBody normalizes a plain-text comment: invalid UTF-8 and control/format characters are
+// removed (newlines and tabs are kept), line endings become \n and surrounding whitespace is trimmed.
+// Empty bodies and bodies over maxLength characters are rejected rather than truncated.
+func sanitizeBody(body string, maxLength int) (string, error) {
+	body = strings.ToValidUTF8(body, "")
+	body = strings.ReplaceAll(body, "\r\n", "\n")
+
+	body = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n' || r == '\t':
+			return r
+		case r == '\r':
+			return '\n'
+		case unicode.IsControl(r), unicode.Is(unicode.Cf, r):
+			return -1
+		}
+		return r
+	}, body)
+	body = strings.TrimSpace(body)
+
+	if body == "" {
+		return "", fmt.Errorf("comment body is empty")
+	}
+	if maxLength > 0 && utf8.RuneCountInString(body) > maxLength {
+		return "", fmt.Errorf("comment body exceeds %d characters", maxLength)
+	}
+	return body, nil
+}
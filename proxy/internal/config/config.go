@@ -3,6 +3,9 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -11,13 +14,43 @@ type Config struct {
 	// Server
 	Port string
 
+	// Connection limits (slow clients are cut off instead of holding sockets open)
+	ServerReadHeaderTimeout time.Duration
+	ServerReadTimeout       time.Duration // whole request including body
+	ServerWriteTimeout      time.Duration // streaming handlers extend their own deadline
+	ServerIdleTimeout       time.Duration // keep-alive connections
+	ServerMaxHeaderBytes    int64
+	RequestHeaderMaxCount   int   // header lines per request (431 beyond it), 0 = unlimited
+	RequestHeaderMaxBytes   int64 // total header size per request (431 beyond it), 0 = unlimited
+	ServerMaxConnections    int   // concurrent connections, 0 = unlimited
+
 	// NocoDB
 	NocoDBURL    string
 	NocoDBToken  string
 	NocoDBBaseID string
 
+	// MetaCache
+	MetaRefreshInterval  time.Duration
+	MetaMinTables        int  // readiness requires at least this many tables
+	MetaLegacyFallback   bool // keep serving in legacy mode when the token lacks meta permissions
+	MetaFetchConcurrency int  // table details fetched in parallel during a refresh
+	MetaFetchRate        int  // table details requests per second, 0 = unlimited
+	MetaFetchRetries     int  // retries of a failing table details request
+
+	// proxy.yaml history: the last ConfigHistorySize applied configs, kept in ConfigHistoryDir ("" = memory only)
+	ConfigHistoryDir  string
+	ConfigHistorySize int
+
 	// JWT
-	JWTSecret string
+	JWTSecret       string
+	JWTTTL          time.Duration // lifetime of tokens issued after an OAuth sign-in, which come without a refresh token
+	AccessTokenTTL  time.Duration // lifetime of access tokens issued by /login, /signup and /api/auth/refresh
+	RefreshTokenTTL time.Duration // lifetime of the single-use refresh tokens issued with them
+
+	TokenCleanupInterval time.Duration // how often expired refresh tokens and revoked access tokens are purged
+
+	AuthCookieName   string // cookie the access token may be sent in instead of Authorization, empty = header only
+	AuthCookieSecure bool   // auth and CSRF cookies are only sent over HTTPS
 
 	// OAuth - Google
 	GoogleClientID     string
@@ -29,11 +62,116 @@ type Config struct {
 	GitHubClientSecret string
 	GitHubCallbackURL  string
 
+	// OAuth sign-ins whose email matches an account from another provider: strict | verified-merge | legacy
+	OAuthCollisionPolicy string
+
+	// Local account passwords
+	PasswordMinLength  int
+	PasswordMinEntropy int    // estimated bits, 0 = no entropy check
+	PasswordDenylist   string // builtin | off | path to an extra list
+
+	// Password reset for local accounts: tokens are POSTed to the webhook, which delivers them
+	PasswordResetTTL       time.Duration
+	PasswordResetWebhook   string        // empty = forgot-password issues no tokens
+	PasswordResetInterval  time.Duration // minimum time between reset tokens for one email; 0 = no throttle
+	PasswordResetQueueSize int           // pending forgot-password requests; more are dropped
+
+	// Login lockout: failures per email (0 = off) and per client IP (0 = emails only) before logins are refused for the cooldown
+	LoginMaxFailures     int
+	LoginMaxIPFailures   int
+	LoginLockoutCooldown time.Duration
+
 	// Database
 	DatabasePath string
+	DatabaseKey  string // SQLCipher key; requires a build with -tags sqlcipher
+
+	DatabaseQueryTimeout time.Duration // per-call cap on top of the request deadline, 0 = none
+
+	// Users
+	UserDisplayCacheTTL  time.Duration
+	UserDisplayCacheSize int  // entries per display cache, 0 = users.DefaultDisplayCacheSize
+	UserDisplayRateLimit int  // /api/users/display requests per user per minute, 0 = unlimited
+	UserNameMaxLength    int  // OAuth display names are truncated to this many characters
+	UserFieldTranslation bool // NocoDB collaborators in user fields become proxy users
+	UserFieldsAdminRaw   bool // admins see collaborators untranslated
+
+	// Record comments
+	CommentMaxLength int // characters
+
+	// In-flight requests per user on /proxy/ (429 beyond it), 0 = unlimited
+	MaxConcurrentPerUser   int
+	ConcurrencyAdminBypass bool
+
+	// Requests per client IP on every endpoint (429 beyond it), 0 = unlimited
+	IPRateLimit       int
+	IPRateLimitWindow time.Duration
+	IPRateLimitMode   string   // enforce | log_only
+	TrustedProxies    []string // CIDRs or addresses whose X-Forwarded-For is believed
+
+	// Admin UI (static assets under /admin, disabled by default)
+	AdminUIEnabled bool
+
+	// Prometheus metrics on /metrics
+	MetricsEnabled bool
 
 	// Session
 	SessionSecret string
+	SessionMaxAge time.Duration // OAuth flow sessions expire after this
+
+	// Proxy
+	MaxResponseRecords          int           // 0 = unlimited
+	ResponseAliasDepth          int           // levels of linked records renamed by rename_response_fields
+	UpstreamTimeout             time.Duration // per NocoDB request, 0 = none
+	UpstreamMaxIdleConns        int           // pooled keep-alive connections to NocoDB
+	UpstreamMaxIdleConnsPerHost int           // defaults to UpstreamMaxIdleConns (NocoDB is usually one host)
+	UpstreamIdleConnTimeout     time.Duration
+	UpstreamMaxAttempts         int           // tries of idempotent NocoDB requests on connection errors and 502/503/504
+	UpstreamCallBudget          int           // NocoDB calls per client request, retries and pages included; 0 = unlimited
+	UpstreamRetryBudget         int           // retries per client request across all its NocoDB calls; 0 = unlimited
+	UpstreamRetryIdempotencyKey bool          // also retry requests carrying an Idempotency-Key
+	MaxPaginationFanout         int           // upstream page requests per client request, 0 = unlimited
+	MaxPaginationFanoutRatio    int           // page requests per client request as a multiple of the average, 0 = unlimited
+	MaxPaginationRecords        int           // records merged per client request, 0 = unlimited
+	PaginationTimeout           time.Duration // whole aggregation of one list request, 0 = none
+	PaginationWorkers           int           // concurrent page fetches for v2 offset paging
+	PaginationOnCancel          string        // cancelled aggregations: abort | partial
+	SortVerifyMaxRecords        int           // verify_sort re-sorts aggregated lists up to this size, 0 = unlimited
+	LinkNotFoundMode            string        // structured | passthrough
+	MaxBodyBytes                int64         // request body limit, 0 = unlimited
+	SelectValidation            string        // strict | refresh | off
+	ComputedFields              string        // strip | reject
+	BulkWrites                  string        // bulk writes with failing rows: atomic | best_effort
+	BulkCreatedIDs              bool          // bulk create responses list the new records' IDs as created_ids
+	JSONContentType             string        // Content-Type of JSON responses: passthrough | canonical
+	TransformMaxBytes           int64         // optional response transforms are skipped above this body size, 0 = unlimited
+	ValidationErrors            string        // typed | legacy
+	PathValidation              string        // strict | off
+	RecordIDPattern             string        // regexp record IDs in proxy paths must match, empty = letters, digits, '_' and '-'
+	UnresolvedFields            string        // where/sort and write body fields that don't resolve: lenient | strict
+	LinkTargetPermissions       string        // link requests also need the target table's operation: off | enforce
+	LinkImpliesUnlink           bool          // tables allowing link may also unlink (configs predating the unlink operation)
+	RowLevelDefault             string        // non-admin reads of tables without owner_field: open | deny
+	UpstreamErrors              string        // NocoDB error responses: passthrough | mapped
+	UpstreamErrorMap            string        // YAML file with rules checked before the built-in ones, "" = built-in only
+	ReloadConcurrency           string        // refresh or rollback while another runs: reject | queue
+	DeprecationGrace            time.Duration // deprecated fields keep working this long past their sunset date
+	PublicBaseURL               string        // external URL of the proxy for rewritten next/prev links, "" = request host
+
+	// Client-facing error messages: locale used without a matching Accept-Language,
+	// and an optional directory of <locale>.yaml catalogs added to the built-in en and de
+	ErrorLocale     string
+	ErrorCatalogDir string
+
+	// Pagination cursors (signed with CURSOR_SECRET, falling back to JWT_SECRET)
+	CursorSecret     string
+	CursorTTL        time.Duration
+	LegacyOperations []string // legacy-mode operations allowlist, empty = all
+
+	// Extra upstream response headers relayed to clients on top of the default allowlist
+	ResponseHeadersExtra []string
+
+	// Experimental subsystems switched off with FEATURES, e.g. "streaming=off"; proxy.yaml's features override it
+	Features Features
 }
 
 func Load() *Config {
@@ -48,13 +186,42 @@ func Load() *Config {
 		// Server
 		Port: getEnv("PORT", "8080"),
 
+		ServerReadHeaderTimeout: getEnvDuration("SERVER_READ_HEADER_TIMEOUT", 10*time.Second),
+		ServerReadTimeout:       getEnvDuration("SERVER_READ_TIMEOUT", 60*time.Second),
+		ServerWriteTimeout:      getEnvDuration("SERVER_WRITE_TIMEOUT", 120*time.Second),
+		ServerIdleTimeout:       getEnvDuration("SERVER_IDLE_TIMEOUT", 120*time.Second),
+		ServerMaxHeaderBytes:    getEnvByteSize("SERVER_MAX_HEADER_BYTES", 64<<10),
+		RequestHeaderMaxCount:   getEnvInt("REQUEST_HEADER_MAX_COUNT", 100),
+		RequestHeaderMaxBytes:   getEnvByteSize("REQUEST_HEADER_MAX_BYTES", 32<<10),
+		ServerMaxConnections:    getEnvInt("SERVER_MAX_CONNECTIONS", 0),
+
 		// NocoDB
 		NocoDBURL:    getEnv("NOCODB_URL", "http://localhost:8090/api/v3/data/project/"),
 		NocoDBToken:  getEnv("NOCODB_TOKEN", "secret123"),
 		NocoDBBaseID: getEnv("NOCODB_BASE_ID", ""),
 
+		// MetaCache
+		MetaRefreshInterval:  getEnvDuration("META_REFRESH_INTERVAL", 10*time.Minute),
+		MetaMinTables:        getEnvInt("META_MIN_TABLES", 1),
+		MetaLegacyFallback:   getEnvBool("META_LEGACY_FALLBACK", false),
+		MetaFetchConcurrency: getEnvInt("META_FETCH_CONCURRENCY", 4),
+		MetaFetchRate:        getEnvInt("META_FETCH_RATE", 10),
+		MetaFetchRetries:     getEnvInt("META_FETCH_RETRIES", 2),
+
+		// proxy.yaml history
+		ConfigHistoryDir:  getEnv("CONFIG_HISTORY_DIR", "./config/history"),
+		ConfigHistorySize: getEnvInt("CONFIG_HISTORY_SIZE", 10),
+
 		// JWT
-		JWTSecret: getEnv("JWT_SECRET", "myjwtsecret"),
+		JWTSecret:       getEnv("JWT_SECRET", "myjwtsecret"),
+		JWTTTL:          getEnvDuration("JWT_TTL", 24*time.Hour),
+		AccessTokenTTL:  getEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL: getEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+
+		TokenCleanupInterval: getEnvDuration("TOKEN_CLEANUP_INTERVAL", time.Hour),
+
+		AuthCookieName:   getEnv("AUTH_COOKIE_NAME", ""),
+		AuthCookieSecure: getEnvBool("AUTH_COOKIE_SECURE", true),
 
 		// OAuth - Google
 		GoogleClientID:     getEnv("GOOGLE_CLIENT_ID", ""),
@@ -66,11 +233,105 @@ func Load() *Config {
 		GitHubClientSecret: getEnv("GITHUB_CLIENT_SECRET", ""),
 		GitHubCallbackURL:  getEnv("GITHUB_CALLBACK_URL", "http://localhost:8080/auth/github/callback"),
 
+		OAuthCollisionPolicy: getEnv("OAUTH_EMAIL_COLLISION_POLICY", "strict"),
+
+		// Local account passwords
+		PasswordMinLength:  getEnvInt("PASSWORD_MIN_LENGTH", 6),
+		PasswordMinEntropy: getEnvInt("PASSWORD_MIN_ENTROPY", 0),
+		PasswordDenylist:   getEnv("PASSWORD_DENYLIST", "builtin"),
+
+		PasswordResetTTL:       getEnvDuration("PASSWORD_RESET_TTL", 30*time.Minute),
+		PasswordResetWebhook:   getEnv("PASSWORD_RESET_WEBHOOK", ""),
+		PasswordResetInterval:  getEnvDuration("PASSWORD_RESET_INTERVAL", 5*time.Minute),
+		PasswordResetQueueSize: getEnvInt("PASSWORD_RESET_QUEUE_SIZE", 100),
+		LoginMaxFailures:       getEnvInt("LOGIN_MAX_FAILURES", 5),
+		LoginMaxIPFailures:     getEnvInt("LOGIN_MAX_IP_FAILURES", 20),
+		LoginLockoutCooldown:   getEnvDuration("LOGIN_LOCKOUT_COOLDOWN", 15*time.Minute),
+
 		// Database
 		DatabasePath: getEnv("DATABASE_PATH", "./users.db"),
+		DatabaseKey:  getEnv("DATABASE_KEY", ""),
+
+		DatabaseQueryTimeout: getEnvDuration("DATABASE_QUERY_TIMEOUT", 5*time.Second),
+
+		// Users
+		UserDisplayCacheTTL:  getEnvDuration("USER_DISPLAY_CACHE_TTL", 5*time.Minute),
+		UserDisplayCacheSize: getEnvInt("USER_DISPLAY_CACHE_SIZE", 10000),
+		UserDisplayRateLimit: getEnvInt("USER_DISPLAY_RATE_LIMIT", 120),
+		UserNameMaxLength:    getEnvInt("USER_NAME_MAX_LENGTH", 100),
+		UserFieldTranslation: getEnvBool("USER_FIELD_TRANSLATION", true),
+		UserFieldsAdminRaw:   getEnvBool("USER_FIELDS_ADMIN_RAW", false),
+
+		// Record comments
+		CommentMaxLength: getEnvInt("COMMENT_MAX_LENGTH", 4000),
+
+		MaxConcurrentPerUser:   getEnvInt("MAX_CONCURRENT_REQUESTS_PER_USER", 0),
+		ConcurrencyAdminBypass: getEnvBool("CONCURRENCY_ADMIN_BYPASS", true),
+
+		IPRateLimit:       getEnvInt("IP_RATE_LIMIT", 0),
+		IPRateLimitWindow: getEnvDuration("IP_RATE_LIMIT_WINDOW", time.Minute),
+		IPRateLimitMode:   getEnv("IP_RATE_LIMIT_MODE", "enforce"),
+		TrustedProxies:    getEnvList("TRUSTED_PROXIES"),
+
+		// Admin UI
+		AdminUIEnabled: getEnvBool("ADMIN_UI_ENABLED", false),
+
+		// Metrics
+		MetricsEnabled: getEnvBool("METRICS_ENABLED", true),
 
 		// Session
 		SessionSecret: getEnv("SESSION_SECRET", "session-secret-key"),
+		SessionMaxAge: getEnvDuration("SESSION_MAX_AGE", 10*time.Minute),
+
+		// Proxy
+		MaxResponseRecords:          getEnvInt("MAX_RESPONSE_RECORDS", 0),
+		ResponseAliasDepth:          getEnvInt("RESPONSE_ALIAS_DEPTH", 1),
+		UpstreamTimeout:             getEnvDuration("UPSTREAM_TIMEOUT", 30*time.Second),
+		UpstreamMaxIdleConns:        getEnvInt("UPSTREAM_MAX_IDLE_CONNS", 32),
+		UpstreamMaxIdleConnsPerHost: getEnvInt("UPSTREAM_MAX_IDLE_CONNS_PER_HOST", getEnvInt("UPSTREAM_MAX_IDLE_CONNS", 32)),
+		UpstreamIdleConnTimeout:     getEnvDuration("UPSTREAM_IDLE_CONN_TIMEOUT", 90*time.Second),
+		UpstreamMaxAttempts:         getEnvInt("UPSTREAM_MAX_ATTEMPTS", 3),
+		UpstreamCallBudget:          getEnvInt("UPSTREAM_CALL_BUDGET", 100),
+		UpstreamRetryBudget:         getEnvInt("UPSTREAM_RETRY_BUDGET", 10),
+		UpstreamRetryIdempotencyKey: getEnvBool("UPSTREAM_RETRY_IDEMPOTENCY_KEY", false),
+		MaxPaginationFanout:         getEnvInt("MAX_PAGINATION_FANOUT", 50),
+		MaxPaginationFanoutRatio:    getEnvInt("MAX_PAGINATION_FANOUT_RATIO", 10),
+		MaxPaginationRecords:        getEnvInt("MAX_PAGINATION_RECORDS", 0),
+		PaginationTimeout:           getEnvDuration("PAGINATION_TIMEOUT", 60*time.Second),
+		PaginationWorkers:           getEnvInt("PAGINATION_WORKERS", 4),
+		PaginationOnCancel:          getEnv("PAGINATION_ON_CANCEL", "abort"),
+		SortVerifyMaxRecords:        getEnvInt("SORT_VERIFY_MAX_RECORDS", 10000),
+		LinkNotFoundMode:            getEnv("LINK_NOT_FOUND_MODE", "structured"),
+		MaxBodyBytes:                getEnvByteSize("MAX_BODY_BYTES", 0),
+		SelectValidation:            getEnv("SELECT_VALIDATION", "refresh"),
+		ComputedFields:              getEnv("COMPUTED_FIELDS", "strip"),
+		BulkWrites:                  getEnv("BULK_WRITES", "atomic"),
+		BulkCreatedIDs:              getEnvBool("BULK_CREATED_IDS", false),
+		JSONContentType:             getEnv("JSON_CONTENT_TYPE", "passthrough"),
+		TransformMaxBytes:           getEnvByteSize("TRANSFORM_MAX_BYTES", 0),
+		ValidationErrors:            getEnv("VALIDATION_ERRORS", "typed"),
+		PathValidation:              getEnv("PATH_VALIDATION", "strict"),
+		RecordIDPattern:             getEnv("RECORD_ID_PATTERN", ""),
+		UnresolvedFields:            getEnv("UNRESOLVED_FIELDS", "lenient"),
+		LinkTargetPermissions:       getEnv("LINK_TARGET_PERMISSIONS", "off"),
+		LinkImpliesUnlink:           getEnvBool("LINK_IMPLIES_UNLINK", true),
+		RowLevelDefault:             getEnv("ROW_LEVEL_DEFAULT", "open"),
+		UpstreamErrors:              getEnv("UPSTREAM_ERRORS", "passthrough"),
+		UpstreamErrorMap:            getEnv("UPSTREAM_ERROR_MAP", ""),
+		ReloadConcurrency:           getEnv("RELOAD_CONCURRENCY", "reject"),
+		DeprecationGrace:            getEnvDuration("DEPRECATION_GRACE", 0),
+		PublicBaseURL:               getEnv("PUBLIC_BASE_URL", ""),
+
+		ErrorLocale:     getEnv("ERROR_LOCALE", "en"),
+		ErrorCatalogDir: getEnv("ERROR_CATALOG_DIR", ""),
+
+		CursorSecret:     getEnv("CURSOR_SECRET", getEnv("JWT_SECRET", "myjwtsecret")),
+		CursorTTL:        getEnvDuration("CURSOR_TTL", time.Hour),
+		LegacyOperations: getEnvList("LEGACY_OPERATIONS"),
+
+		ResponseHeadersExtra: getEnvList("RESPONSE_HEADERS_EXTRA"),
+
+		Features: getEnvFeatures("FEATURES"),
 	}
 }
 
@@ -81,6 +342,78 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("[CONFIG WARN] Invalid integer for %s=%q, using default %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return n
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("[CONFIG WARN] Invalid boolean for %s=%q, using default %v", key, value, defaultValue)
+		return defaultValue
+	}
+	return b
+}
+
+// getEnvList reads a comma-separated list, ignoring empty entries
+func getEnvList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := ParseDuration(value)
+	if err != nil {
+		log.Printf("[CONFIG WARN] %s: %v, using default %v", key, err, defaultValue)
+		return defaultValue
+	}
+	return d
+}
+
+func getEnvByteSize(key string, defaultValue int64) int64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := ParseByteSize(value)
+	if err != nil {
+		log.Printf("[CONFIG WARN] %s: %v, using default %d", key, err, defaultValue)
+		return defaultValue
+	}
+	return n
+}
+
+func getEnvFeatures(key string) Features {
+	features, err := ParseFeatures(os.Getenv(key))
+	if err != nil {
+		log.Printf("[CONFIG WARN] %s: %v, enabling every feature", key, err)
+		return Features{}
+	}
+	return features
+}
+
 func (c *Config) MaskSecret(secret string) string {
 	if len(secret) <= 8 {
 		return "****"
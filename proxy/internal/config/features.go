@@ -0,0 +1,105 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Feature flags switch experimental subsystems off without code changes. Every flag is on unless
+// FEATURES or the features map of proxy.yaml turns it off; both are read at startup only.
+const (
+	FeatureCaching     = "caching"     // user display names are cached for USER_DISPLAY_CACHE_TTL
+	FeatureStreaming   = "streaming"   // responses that need no rewriting are streamed instead of buffered
+	FeatureConcurrency = "concurrency" // v2 list pages are fetched by PAGINATION_WORKERS in parallel
+)
+
+// KnownFeatures lists every feature flag
+var KnownFeatures = []string{FeatureCaching, FeatureConcurrency, FeatureStreaming}
+
+// Features maps feature flag names to whether they are on. Flags missing from the map are on, so
+// the zero value enables everything.
+type Features map[string]bool
+
+// Enabled reports whether a feature is on
+func (f Features) Enabled(name string) bool {
+	on, set := f[name]
+	return !set || on
+}
+
+// All returns the state of every known flag
+func (f Features) All() map[string]bool {
+	all := make(map[string]bool, len(KnownFeatures))
+	for _, name := range KnownFeatures {
+		all[name] = f.Enabled(name)
+	}
+	return all
+}
+
+// Merge returns the flags of f with the ones set in overrides replacing them
+func (f Features) Merge(overrides Features) Features {
+	merged := make(Features, len(f)+len(overrides))
+	for name, on := range f {
+		merged[name] = on
+	}
+	for name, on := range overrides {
+		merged[name] = on
+	}
+	return merged
+}
+
+// validate rejects flags that aren't known, in a stable order so errors are reproducible
+func (f Features) validate() error {
+	names := make([]string, 0, len(f))
+	for name := range f {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !containsString(KnownFeatures, name) {
+			return fmt.Errorf("unknown feature '%s' (expected one of %v)", name, KnownFeatures)
+		}
+	}
+	return nil
+}
+
+// ParseFeatures parses a FEATURES value: comma-separated name=on|off entries (true/false work
+// too), e.g. "streaming=off, concurrency=off"
+func ParseFeatures(value string) (Features, error) {
+	features := Features{}
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		name, state, found := strings.Cut(entry, "=")
+		if !found {
+			return nil, fmt.Errorf("feature entry %q must be name=on or name=off", entry)
+		}
+		name = strings.TrimSpace(name)
+		on, err := parseFeatureState(strings.TrimSpace(state))
+		if err != nil {
+			return nil, fmt.Errorf("feature '%s': %v", name, err)
+		}
+		features[name] = on
+	}
+	if err := features.validate(); err != nil {
+		return nil, err
+	}
+	return features, nil
+}
+
+// parseFeatureState accepts on/off and everything strconv.ParseBool does
+func parseFeatureState(state string) (bool, error) {
+	switch strings.ToLower(state) {
+	case "on":
+		return true, nil
+	case "off":
+		return false, nil
+	}
+	on, err := strconv.ParseBool(state)
+	if err != nil {
+		return false, fmt.Errorf("invalid state %q (expected on or off)", state)
+	}
+	return on, nil
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseFeatures(t *testing.T) {
+	features, err := ParseFeatures(" streaming=off, concurrency=ON ,caching=false,")
+	if err != nil {
+		t.Fatalf("ParseFeatures: %v", err)
+	}
+	if features.Enabled(FeatureStreaming) || !features.Enabled(FeatureConcurrency) || features.Enabled(FeatureCaching) {
+		t.Errorf("features = %v, want streaming and caching off", features.All())
+	}
+
+	for value, want := range map[string]string{
+		"turbo=on":      "unknown feature 'turbo'",
+		"streaming":     "must be name=on or name=off",
+		"streaming=meh": "feature 'streaming': invalid state",
+	} {
+		if _, err := ParseFeatures(value); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ParseFeatures(%q): error %v, want it to contain %q", value, err, want)
+		}
+	}
+}
+
+func TestFeaturesDefaultOn(t *testing.T) {
+	var features Features
+	for _, name := range KnownFeatures {
+		if !features.Enabled(name) {
+			t.Errorf("%s is off without any flags set", name)
+		}
+	}
+}
+
+func TestFeaturesMerge(t *testing.T) {
+	env := Features{FeatureStreaming: false, FeatureCaching: false}
+	merged := env.Merge(Features{FeatureStreaming: true})
+	if !merged.Enabled(FeatureStreaming) || merged.Enabled(FeatureCaching) {
+		t.Errorf("merged = %v, want proxy.yaml's streaming=on over FEATURES and caching still off", merged.All())
+	}
+	if env.Enabled(FeatureStreaming) {
+		t.Error("Merge changed the flags it was called on")
+	}
+}
+
+func TestParseProxyConfigRejectsUnknownFeature(t *testing.T) {
+	_, err := ParseProxyConfig([]byte(`
+nocodb:
+  base_id: b1
+features:
+  turbo: true
+tables:
+  quotes:
+    name: Quotes
+    operations: [read]
+`))
+	if err == nil || !strings.Contains(err.Error(), "features: unknown feature 'turbo'") {
+		t.Errorf("error = %v, want the unknown feature named", err)
+	}
+}
@@ -0,0 +1,175 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// minHashPrefix is the shortest hash prefix FindSnapshot accepts
+const minHashPrefix = 7
+
+// ConfigSnapshot is a proxy.yaml that loaded and resolved successfully
+type ConfigSnapshot struct {
+	Hash      string    `json:"hash"`
+	AppliedAt time.Time `json:"applied_at"`
+	source    []byte
+}
+
+// Source returns the YAML of the snapshot
+func (s ConfigSnapshot) Source() []byte {
+	return s.source
+}
+
+// ConfigHistory keeps the last applied proxy configurations, newest first. Each one is also
+// written to dir as <hash>.yaml (modification time = when it was applied) so the history
+// survives restarts.
+type ConfigHistory struct {
+	dir  string // "" = memory only
+	size int
+
+	mu        sync.RWMutex
+	snapshots []ConfigSnapshot
+	active    string // hash of the configuration in use
+}
+
+// HashConfig returns the content hash identifying a proxy.yaml
+func HashConfig(source []byte) string {
+	sum := sha256.Sum256(source)
+	return hex.EncodeToString(sum[:])
+}
+
+// NewConfigHistory creates a history of at most size snapshots and loads the ones kept in dir
+func NewConfigHistory(dir string, size int) (*ConfigHistory, error) {
+	if size < 1 {
+		size = 1
+	}
+	h := &ConfigHistory{dir: dir, size: size}
+	if dir == "" {
+		return h, nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("config history: %w", err)
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("config history: %w", err)
+	}
+	for _, path := range paths {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			log.Printf("[CONFIG WARN] Skipping config history entry %s: %v", path, err)
+			continue
+		}
+		hash := HashConfig(source)
+		if strings.TrimSuffix(filepath.Base(path), ".yaml") != hash {
+			log.Printf("[CONFIG WARN] Skipping config history entry %s: content doesn't match its hash", path)
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		h.snapshots = append(h.snapshots, ConfigSnapshot{Hash: hash, AppliedAt: info.ModTime(), source: source})
+	}
+	sort.Slice(h.snapshots, func(i, j int) bool { return h.snapshots[i].AppliedAt.After(h.snapshots[j].AppliedAt) })
+	h.prune()
+
+	log.Printf("[CONFIG] Loaded %d config history entries from %s", len(h.snapshots), dir)
+	return h, nil
+}
+
+// Record marks source as the active configuration and moves it to the front of the history.
+// Failing to persist it only costs the on-disk copy.
+func (h *ConfigHistory) Record(source []byte) ConfigSnapshot {
+	snapshot := ConfigSnapshot{Hash: HashConfig(source), AppliedAt: time.Now(), source: source}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, existing := range h.snapshots {
+		if existing.Hash == snapshot.Hash {
+			h.snapshots = append(h.snapshots[:i], h.snapshots[i+1:]...)
+			break
+		}
+	}
+	h.snapshots = append([]ConfigSnapshot{snapshot}, h.snapshots...)
+	h.active = snapshot.Hash
+	h.prune()
+
+	if h.dir != "" {
+		path := filepath.Join(h.dir, snapshot.Hash+".yaml")
+		if err := os.WriteFile(path, source, 0o644); err != nil {
+			log.Printf("[CONFIG WARN] Failed to persist config history entry: %v", err)
+		} else if err := os.Chtimes(path, snapshot.AppliedAt, snapshot.AppliedAt); err != nil {
+			log.Printf("[CONFIG WARN] Failed to timestamp config history entry: %v", err)
+		}
+	}
+	return snapshot
+}
+
+// prune drops snapshots beyond size, including their files. Callers hold mu.
+func (h *ConfigHistory) prune() {
+	if len(h.snapshots) <= h.size {
+		return
+	}
+	for _, dropped := range h.snapshots[h.size:] {
+		if h.dir != "" {
+			if err := os.Remove(filepath.Join(h.dir, dropped.Hash+".yaml")); err != nil && !os.IsNotExist(err) {
+				log.Printf("[CONFIG WARN] Failed to remove config history entry: %v", err)
+			}
+		}
+	}
+	h.snapshots = h.snapshots[:h.size]
+}
+
+// Snapshots returns the history, newest first
+func (h *ConfigHistory) Snapshots() []ConfigSnapshot {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return append([]ConfigSnapshot(nil), h.snapshots...)
+}
+
+// Active returns the active snapshot, reporting false before the first Record
+func (h *ConfigHistory) Active() (ConfigSnapshot, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, snapshot := range h.snapshots {
+		if snapshot.Hash == h.active {
+			return snapshot, true
+		}
+	}
+	return ConfigSnapshot{}, false
+}
+
+// FindSnapshot returns the snapshot whose hash starts with prefix (at least 7 characters).
+// Unknown and ambiguous prefixes are errors.
+func (h *ConfigHistory) FindSnapshot(prefix string) (ConfigSnapshot, error) {
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	if len(prefix) < minHashPrefix {
+		return ConfigSnapshot{}, fmt.Errorf("config hash '%s' is too short (at least %d characters)", prefix, minHashPrefix)
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var found []ConfigSnapshot
+	for _, snapshot := range h.snapshots {
+		if strings.HasPrefix(snapshot.Hash, prefix) {
+			found = append(found, snapshot)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return ConfigSnapshot{}, fmt.Errorf("no config with hash '%s' in history", prefix)
+	case 1:
+		return found[0], nil
+	}
+	return ConfigSnapshot{}, fmt.Errorf("config hash '%s' is ambiguous", prefix)
+}
@@ -1,10 +1,15 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/grove/generic-proxy/internal/jsonpath"
 	"gopkg.in/yaml.v3"
 )
 
@@ -16,9 +21,24 @@ func LoadProxyConfig(path string) (*ProxyConfig, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
+	return ParseProxyConfig(data)
+}
 
+// ParseProxyConfig parses and validates proxy configuration YAML
+func ParseProxyConfig(data []byte) (*ProxyConfig, error) {
+	// Decoded through the node tree, so a value that fails to parse can be traced back to its key
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML config: %w", err)
+	}
 	var config ProxyConfig
-	if err := yaml.Unmarshal(data, &config); err != nil {
+	if err := root.Decode(&config); err != nil {
+		var valueErr *valueError
+		if errors.As(err, &valueErr) {
+			if path, ok := keyPath(&root, valueErr.node, ""); ok {
+				err = fmt.Errorf("%s: %w", path, err)
+			}
+		}
 		return nil, fmt.Errorf("failed to parse YAML config: %w", err)
 	}
 
@@ -30,28 +50,147 @@ func LoadProxyConfig(path string) (*ProxyConfig, error) {
 	return &config, nil
 }
 
+// keyPath returns the keys and sequence indexes leading from node to target, e.g.
+// "tables.quotes.write_cooldown" or "summaries[0].interval"
+func keyPath(node, target *yaml.Node, prefix string) (string, bool) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			if path, ok := keyPath(child, target, prefix); ok {
+				return path, true
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			path := node.Content[i].Value
+			if prefix != "" {
+				path = prefix + "." + path
+			}
+			if node.Content[i+1] == target {
+				return path, true
+			}
+			if path, ok := keyPath(node.Content[i+1], target, path); ok {
+				return path, true
+			}
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			path := fmt.Sprintf("%s[%d]", prefix, i)
+			if child == target {
+				return path, true
+			}
+			if path, ok := keyPath(child, target, path); ok {
+				return path, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Bounds for typed settings; values outside them are almost certainly typos
+const (
+	minMetaRefreshInterval = 10 * time.Second
+	maxMetaRefreshInterval = 24 * time.Hour
+	maxBodyBytesLimit      = 1 << 30 // 1GiB
+	minSummaryInterval     = 10 * time.Second
+)
+
+// summaryAggregations lists the supported summaries[].aggregation values
+var summaryAggregations = map[string]bool{"count": true, "sum": true, "avg": true, "min": true, "max": true}
+
 // validateConfig performs basic validation on the configuration
 func validateConfig(config *ProxyConfig) error {
 	if config.NocoDB.BaseID == "" {
 		return fmt.Errorf("nocodb.base_id is required")
 	}
 
+	if interval := config.NocoDB.MetaRefreshInterval.Duration(); interval != 0 {
+		if interval < minMetaRefreshInterval || interval > maxMetaRefreshInterval {
+			return fmt.Errorf("nocodb.meta_refresh_interval: %v is out of range (%v to %v)", interval, minMetaRefreshInterval, maxMetaRefreshInterval)
+		}
+	}
+
+	if err := config.Features.validate(); err != nil {
+		return fmt.Errorf("features: %v", err)
+	}
+
 	if len(config.Tables) == 0 {
 		return fmt.Errorf("at least one table must be defined")
 	}
 
+	if err := validateUniqueTableNames(config.Tables); err != nil {
+		return err
+	}
+
 	for tableName, table := range config.Tables {
 		if table.Name == "" {
 			return fmt.Errorf("table '%s': name is required", tableName)
 		}
 
-		if len(table.Operations) == 0 {
+		if table.Operations.Empty() {
 			return fmt.Errorf("table '%s': at least one operation must be specified", tableName)
 		}
 
-		for _, op := range table.Operations {
-			if !isValidOperation(op) {
-				return fmt.Errorf("table '%s': invalid operation '%s'", tableName, op)
+		if table.MaxBodyBytes < 0 || table.MaxBodyBytes > maxBodyBytesLimit {
+			return fmt.Errorf("table '%s': max_body_bytes %d is out of range (0 to %d)", tableName, table.MaxBodyBytes, maxBodyBytesLimit)
+		}
+
+		if table.MaxPaginationPages < 0 || table.MaxPaginationRecords < 0 {
+			return fmt.Errorf("table '%s': max_pagination_pages and max_pagination_records must not be negative", tableName)
+		}
+
+		if table.UpstreamCallBudget < 0 {
+			return fmt.Errorf("table '%s': upstream_call_budget must not be negative", tableName)
+		}
+		if table.WriteCooldown.Duration() < 0 {
+			return fmt.Errorf("table '%s': write_cooldown must not be negative", tableName)
+		}
+
+		if err := table.Operations.validate(); err != nil {
+			return fmt.Errorf("table '%s': %v", tableName, err)
+		}
+
+		if _, err := ParseSortSpec(table.DefaultSort); err != nil {
+			return fmt.Errorf("table '%s': default_sort: %v", tableName, err)
+		}
+		if table.VerifySort && table.DefaultSort == "" {
+			return fmt.Errorf("table '%s': verify_sort requires default_sort", tableName)
+		}
+		for _, expr := range table.ResponseFilter {
+			if _, err := jsonpath.Parse(expr); err != nil {
+				return fmt.Errorf("table '%s': response_filter: %v", tableName, err)
+			}
+		}
+		if table.RenameResponseFields && len(table.Fields) == 0 {
+			return fmt.Errorf("table '%s': rename_response_fields requires fields", tableName)
+		}
+		for _, field := range table.WritableFields {
+			if !hasField(table.Fields, field) {
+				return fmt.Errorf("table '%s': writable_fields: '%s' is not in fields", tableName, field)
+			}
+		}
+		for _, field := range table.HiddenFields {
+			if strings.TrimSpace(field) == "" {
+				return fmt.Errorf("table '%s': hidden_fields contains an empty field name", tableName)
+			}
+		}
+		if table.OwnerAdminOverride && table.OwnerField == "" {
+			return fmt.Errorf("table '%s': owner_admin_override requires owner_field", tableName)
+		}
+		if table.AdminBypass && len(table.WritableFields) == 0 {
+			return fmt.Errorf("table '%s': admin_bypass requires writable_fields", tableName)
+		}
+		for field, deprecation := range table.DeprecatedFields {
+			if deprecation.Sunset == "" {
+				continue
+			}
+			if _, err := time.Parse(SunsetDateLayout, deprecation.Sunset); err != nil {
+				return fmt.Errorf("table '%s': deprecated_fields.%s.sunset: %q is not a date (expected YYYY-MM-DD)", tableName, field, deprecation.Sunset)
+			}
+		}
+		for _, field := range table.PrimaryKey {
+			if strings.TrimSpace(field) == "" {
+				return fmt.Errorf("table '%s': primary_key contains an empty field name", tableName)
 			}
 		}
 
@@ -65,17 +204,98 @@ func validateConfig(config *ProxyConfig) error {
 		}
 	}
 
+	for name, id := range config.Overrides.Tables {
+		if strings.TrimSpace(id) == "" {
+			return fmt.Errorf("overrides.tables: table '%s' has an empty ID", name)
+		}
+	}
+	for tableName, fields := range config.Overrides.Fields {
+		for name, id := range fields {
+			if strings.TrimSpace(id) == "" {
+				return fmt.Errorf("overrides.fields: field '%s.%s' has an empty ID", tableName, name)
+			}
+		}
+	}
+
+	for name, summary := range config.Summaries {
+		table, ok := config.Tables[summary.Table]
+		if !ok {
+			return fmt.Errorf("summary '%s': table '%s' is not defined in tables", name, summary.Table)
+		}
+		// Summaries are computed in the background with the admin role's permissions
+		if !table.Operations.Allows("admin", "read") {
+			return fmt.Errorf("summary '%s': table '%s' does not allow read for admin", name, summary.Table)
+		}
+		if !summaryAggregations[summary.Aggregation] {
+			return fmt.Errorf("summary '%s': invalid aggregation '%s' (expected count, sum, avg, min or max)", name, summary.Aggregation)
+		}
+		if summary.Aggregation != "count" && summary.Field == "" {
+			return fmt.Errorf("summary '%s': field is required for aggregation '%s'", name, summary.Aggregation)
+		}
+		if summary.Interval.Duration() < minSummaryInterval {
+			return fmt.Errorf("summary '%s': interval must be at least %v", name, minSummaryInterval)
+		}
+	}
+
+	return nil
+}
+
+// validateUniqueTableNames rejects table keys that point at the same NocoDB table name.
+// Names are compared case-insensitively, like MetaCache resolves them.
+func validateUniqueTableNames(tables map[string]TableConfig) error {
+	keysByName := make(map[string][]string)
+	for tableKey, table := range tables {
+		if table.Name == "" {
+			continue // reported as missing name below
+		}
+		name := strings.ToLower(table.Name)
+		keysByName[name] = append(keysByName[name], tableKey)
+	}
+
+	var conflicts []string
+	for _, keys := range keysByName {
+		if len(keys) > 1 {
+			sort.Strings(keys)
+			conflicts = append(conflicts, fmt.Sprintf("'%s' used by tables %s", tables[keys[0]].Name, strings.Join(keys, ", ")))
+		}
+	}
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		return fmt.Errorf("duplicate table names: %s", strings.Join(conflicts, "; "))
+	}
 	return nil
 }
 
+// containsString reports whether list contains value
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// hasField reports whether name is a field name or an alias in a table's fields section
+func hasField(fields map[string]string, name string) bool {
+	for fieldName, alias := range fields {
+		if fieldName == name || alias == name {
+			return true
+		}
+	}
+	return false
+}
+
 // isValidOperation checks if an operation is valid
 func isValidOperation(op string) bool {
 	validOps := map[string]bool{
-		"read":   true,
-		"create": true,
-		"update": true,
-		"delete": true,
-		"link":   true,
+		"read":       true,
+		"read_links": true,
+		"create":     true,
+		"update":     true,
+		"delete":     true,
+		"link":       true,
+		"unlink":     true,
 	}
 	return validOps[op]
 }
@@ -0,0 +1,151 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseProxyConfigNamesBadValues(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want string
+	}{
+		{"table duration", `
+nocodb:
+  base_id: b1
+tables:
+  quotes:
+    name: Quotes
+    write_cooldown: soon
+`, "tables.quotes.write_cooldown: line 7: invalid duration"},
+		{"table size", `
+nocodb:
+  base_id: b1
+tables:
+  quotes:
+    name: Quotes
+    max_body_bytes: 10XB
+`, "tables.quotes.max_body_bytes: line 7: invalid size"},
+		{"summary", `
+nocodb:
+  base_id: b1
+tables:
+  quotes:
+    name: Quotes
+summaries:
+  open:
+    table: quotes
+    aggregation: count
+    interval: -5s
+`, "summaries.open.interval: line 11: duration"},
+		{"owner override without owner", `
+nocodb:
+  base_id: b1
+tables:
+  quotes:
+    name: Quotes
+    operations: [read, create]
+    owner_admin_override: true
+`, "owner_admin_override requires owner_field"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseProxyConfig([]byte(tt.yaml))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseProxyConfigUnits(t *testing.T) {
+	config, err := ParseProxyConfig([]byte(`
+nocodb:
+  base_id: b1
+tables:
+  quotes:
+    name: Quotes
+    operations: [read]
+    write_cooldown: 2s
+    max_body_bytes: 1.5MiB
+`))
+	if err != nil {
+		t.Fatalf("ParseProxyConfig: %v", err)
+	}
+	table := config.Tables["quotes"]
+	if table.WriteCooldown.Duration().Seconds() != 2 || table.MaxBodyBytes != 3<<19 {
+		t.Errorf("write_cooldown = %v, max_body_bytes = %d", table.WriteCooldown.Duration(), table.MaxBodyBytes)
+	}
+}
+
+func TestParseProxyConfigReadLinks(t *testing.T) {
+	config, err := ParseProxyConfig([]byte(`
+nocodb:
+  base_id: b1
+tables:
+  quotes:
+    name: Quotes
+    operations: [read, read_links]
+    require_read_links: true
+`))
+	if err != nil {
+		t.Fatalf("ParseProxyConfig: %v", err)
+	}
+	if !config.Tables["quotes"].RequireReadLinks {
+		t.Error("require_read_links was not parsed")
+	}
+}
+
+func TestParseProxyConfigDuplicateTableNames(t *testing.T) {
+	_, err := ParseProxyConfig([]byte(`
+nocodb:
+  base_id: b1
+tables:
+  quotes:
+    name: Quotes
+    operations: [read]
+  offers:
+    name: quotes
+    operations: [read]
+  orders:
+    name: Orders
+    operations: [read]
+  sales:
+    name: Orders
+    operations: [read]
+`))
+	want := "duplicate table names: 'Orders' used by tables orders, sales; 'quotes' used by tables offers, quotes"
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("error = %v, want it to contain %q", err, want)
+	}
+
+	if _, err := ParseProxyConfig([]byte(`
+nocodb:
+  base_id: b1
+tables:
+  quotes:
+    name: Quotes
+    operations: [read]
+  orders:
+    name: Orders
+    operations: [read]
+`)); err != nil {
+		t.Errorf("distinct names: %v", err)
+	}
+}
+
+func TestParseProxyConfigResponseFilter(t *testing.T) {
+	_, err := ParseProxyConfig([]byte(`
+nocodb:
+  base_id: b1
+tables:
+  quotes:
+    name: Quotes
+    operations: [read]
+    response_filter: ["$.list[*].Id", "$..Title"]
+`))
+	if err == nil || !strings.Contains(err.Error(), "table 'quotes': response_filter") || !strings.Contains(err.Error(), "recursive descent") {
+		t.Errorf("error = %v, want the invalid expression reported", err)
+	}
+}
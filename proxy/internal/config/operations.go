@@ -0,0 +1,107 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// KnownRoles are the roles a user can have; per-role operations may only name these
+var KnownRoles = []string{"admin", "user"}
+
+// Operations are the operations allowed on a table. proxy.yaml accepts a flat list, which applies
+// to every role, or a list per role:
+//
+//	operations: [read, create]
+//	operations: {admin: [read, create, update, delete], user: [read, create]}
+//
+// Roles missing from the per-role form may do nothing.
+type Operations struct {
+	All    []string            // the flat form
+	ByRole map[string][]string // the per-role form; nil for the flat form
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (o *Operations) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.SequenceNode:
+		*o = Operations{}
+		return value.Decode(&o.All)
+	case yaml.MappingNode:
+		byRole := make(map[string][]string)
+		if err := value.Decode(&byRole); err != nil {
+			return err
+		}
+		*o = Operations{ByRole: byRole}
+		return nil
+	}
+	return fmt.Errorf("line %d: operations must be a list or a map of role to list", value.Line)
+}
+
+// PerRole reports whether the operations are given per role
+func (o Operations) PerRole() bool {
+	return o.ByRole != nil
+}
+
+// For returns the operations allowed to a role
+func (o Operations) For(role string) []string {
+	if o.ByRole == nil {
+		return o.All
+	}
+	return o.ByRole[role]
+}
+
+// Allows reports whether a role may perform an operation
+func (o Operations) Allows(role, operation string) bool {
+	return containsString(o.For(role), operation)
+}
+
+// Empty reports whether no role may do anything
+func (o Operations) Empty() bool {
+	if o.ByRole == nil {
+		return len(o.All) == 0
+	}
+	for _, operations := range o.ByRole {
+		if len(operations) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// validate checks role names and operations, in a stable order so errors are reproducible
+func (o Operations) validate() error {
+	if o.ByRole == nil {
+		for _, op := range o.All {
+			if !isValidOperation(op) {
+				return fmt.Errorf("invalid operation '%s'", op)
+			}
+		}
+		return nil
+	}
+	roles := make([]string, 0, len(o.ByRole))
+	for role := range o.ByRole {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	for _, role := range roles {
+		if !containsString(KnownRoles, role) {
+			return fmt.Errorf("operations: unknown role '%s' (expected one of %v)", role, KnownRoles)
+		}
+		for _, op := range o.ByRole[role] {
+			if !isValidOperation(op) {
+				return fmt.Errorf("operations.%s: invalid operation '%s'", role, op)
+			}
+		}
+	}
+	return nil
+}
+
+// OperationsFor returns the operations a role may perform on the table
+func (t ResolvedTable) OperationsFor(role string) []string {
+	if t.RoleOperations == nil {
+		return t.Operations
+	}
+	return t.RoleOperations[role]
+}
@@ -3,6 +3,10 @@ package config
 import (
 	"fmt"
 	"log"
+	"strings"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/jsonpath"
 )
 
 // MetaCacheInterface defines the interface for resolving table/field names to IDs
@@ -32,10 +36,11 @@ func (r *Resolver) Resolve(config *ProxyConfig) (*ResolvedConfig, error) {
 		Tables: make(map[string]ResolvedTable),
 	}
 
+	linkedAliases := make(map[string]*ResponseAliases)
 	for tableKey, tableConfig := range config.Tables {
 		log.Printf("[RESOLVER] Resolving table: %s (name: %s)", tableKey, tableConfig.Name)
 
-		tableID, ok := r.metaCache.ResolveTable(tableConfig.Name)
+		tableID, ok := r.resolveTable(config.Overrides, tableConfig.Name)
 		if !ok {
 			return nil, fmt.Errorf("failed to resolve table '%s' to ID", tableConfig.Name)
 		}
@@ -43,16 +48,25 @@ func (r *Resolver) Resolve(config *ProxyConfig) (*ResolvedConfig, error) {
 		log.Printf("[RESOLVER] Resolved table '%s' -> '%s'", tableConfig.Name, tableID)
 
 		resolvedTable := ResolvedTable{
-			Name:       tableConfig.Name,
-			TableID:    tableID,
-			Operations: tableConfig.Operations,
-			Fields:     make(map[string]string),
-			Links:      make(map[string]ResolvedLink),
+			Name:             tableConfig.Name,
+			TableID:          tableID,
+			Operations:       tableConfig.Operations.All,
+			RoleOperations:   tableConfig.Operations.ByRole,
+			Fields:           make(map[string]string),
+			FieldTitles:      make(map[string]string),
+			Links:            make(map[string]ResolvedLink),
+			MaxBodyBytes:     int64(tableConfig.MaxBodyBytes),
+			RequireReadLinks: tableConfig.RequireReadLinks,
+
+			MaxPaginationPages:   tableConfig.MaxPaginationPages,
+			MaxPaginationRecords: tableConfig.MaxPaginationRecords,
+			UpstreamCallBudget:   tableConfig.UpstreamCallBudget,
+			WriteCooldown:        tableConfig.WriteCooldown.Duration(),
 		}
 
 		// Resolve field names to IDs
 		for fieldName, fieldAlias := range tableConfig.Fields {
-			fieldID, ok := r.metaCache.ResolveField(tableID, fieldName)
+			fieldID, ok := r.resolveField(config.Overrides, tableConfig.Name, tableID, fieldName)
 			if !ok {
 				log.Printf("[RESOLVER WARN] Failed to resolve field '%s' in table '%s', using as-is", fieldName, tableConfig.Name)
 				fieldID = fieldName
@@ -60,11 +74,65 @@ func (r *Resolver) Resolve(config *ProxyConfig) (*ResolvedConfig, error) {
 				log.Printf("[RESOLVER] Resolved field '%s' -> '%s'", fieldName, fieldID)
 			}
 			resolvedTable.Fields[fieldAlias] = fieldID
+			resolvedTable.FieldTitles[fieldAlias] = fieldName
+		}
+		if tableConfig.RenameResponseFields {
+			resolvedTable.ResponseAliases = responseAliases(config, tableConfig, resolvedTable.Fields, linkedAliases)
+		}
+
+		// Default sort is sent to NocoDB by field title
+		sortKeys, _ := ParseSortSpec(tableConfig.DefaultSort) // validated at load time
+		resolvedTable.DefaultSort = resolveSortAliases(sortKeys, tableConfig.Fields)
+		resolvedTable.VerifySort = tableConfig.VerifySort
+		for _, expr := range tableConfig.ResponseFilter {
+			path, _ := jsonpath.Parse(expr) // validated at load time
+			resolvedTable.ResponseFilter = append(resolvedTable.ResponseFilter, path)
+		}
+		for _, field := range tableConfig.PrimaryKey {
+			resolvedTable.PrimaryKey = append(resolvedTable.PrimaryKey, fieldTitle(field, tableConfig.Fields))
+		}
+		if len(tableConfig.WritableFields) > 0 {
+			resolvedTable.WritableFields = make(map[string]bool, len(tableConfig.WritableFields))
+			for _, field := range tableConfig.WritableFields {
+				resolvedTable.WritableFields[fieldTitle(field, tableConfig.Fields)] = true
+			}
+			resolvedTable.AdminBypass = tableConfig.AdminBypass
+		}
+		for _, field := range tableConfig.HiddenFields {
+			if resolvedTable.HiddenFields == nil {
+				resolvedTable.HiddenFields = make(map[string]bool)
+			}
+			title := fieldTitle(field, tableConfig.Fields)
+			resolvedTable.HiddenFields[title] = true
+			resolvedTable.HiddenTitles = append(resolvedTable.HiddenTitles, title)
+			if fieldID, ok := r.resolveField(config.Overrides, tableConfig.Name, tableID, title); ok {
+				resolvedTable.HiddenFields[fieldID] = true
+			}
+		}
+		if tableConfig.ArchiveField != "" {
+			resolvedTable.ArchiveField = fieldTitle(tableConfig.ArchiveField, tableConfig.Fields)
+		}
+		if tableConfig.OwnerField != "" {
+			resolvedTable.OwnerField = fieldTitle(tableConfig.OwnerField, tableConfig.Fields)
+			resolvedTable.OwnerOverride = tableConfig.OwnerAdminOverride
+		}
+		for name, deprecation := range tableConfig.DeprecatedFields {
+			if !deprecation.Deprecated {
+				continue
+			}
+			if resolvedTable.Deprecations == nil {
+				resolvedTable.Deprecations = make(map[string]FieldDeprecation)
+			}
+			title := fieldTitle(name, tableConfig.Fields)
+			fieldID, _ := r.resolveField(config.Overrides, tableConfig.Name, tableID, title)
+			sunset, _ := time.Parse(SunsetDateLayout, deprecation.Sunset) // validated at load time; zero when empty
+			resolvedTable.Deprecations[title] = FieldDeprecation{Name: name, Title: title, FieldID: fieldID, Sunset: sunset}
 		}
 
 		// Resolve link field names to IDs
 		for linkName, link := range tableConfig.Links {
-			fieldID, ok := r.metaCache.ResolveField(tableID, link.Field)
+			_, pinned := r.fieldOverride(config.Overrides, tableConfig.Name, link.Field)
+			fieldID, ok := r.resolveField(config.Overrides, tableConfig.Name, tableID, link.Field)
 			if !ok {
 				log.Printf("[RESOLVER WARN] Failed to resolve link field '%s' in table '%s', using as-is", link.Field, tableConfig.Name)
 				fieldID = link.Field
@@ -74,13 +142,130 @@ func (r *Resolver) Resolve(config *ProxyConfig) (*ResolvedConfig, error) {
 
 			resolvedTable.Links[linkName] = ResolvedLink{
 				FieldID:     fieldID,
+				Title:       link.Field,
 				TargetTable: link.TargetTable,
+				Pinned:      pinned,
 			}
 		}
 
 		resolved.Tables[tableKey] = resolvedTable
 	}
 
+	// Linked records are stripped of their target table's hidden fields, known once every table is resolved
+	for tableKey, table := range resolved.Tables {
+		for linkName, link := range table.Links {
+			if targetKey, ok := linkTargetKey(config, link.TargetTable); ok {
+				link.HiddenFields = resolved.Tables[targetKey].HiddenFields
+				table.Links[linkName] = link
+			}
+		}
+		resolved.Tables[tableKey] = table
+	}
+
 	log.Printf("[RESOLVER] Successfully resolved %d tables", len(resolved.Tables))
 	return resolved, nil
 }
+
+// responseAliases maps a table's field titles and IDs back to their aliases, and the fields of its
+// linked records to the aliases of their target tables. linked holds the aliases built so far for
+// linked records, by table key.
+func responseAliases(config *ProxyConfig, tableConfig TableConfig, fieldIDs map[string]string, linked map[string]*ResponseAliases) *ResponseAliases {
+	aliases := &ResponseAliases{Fields: make(map[string]string), Links: make(map[string]*ResponseAliases)}
+	for fieldName, alias := range tableConfig.Fields {
+		aliases.Fields[fieldName] = alias
+		if fieldID := fieldIDs[alias]; fieldID != fieldName {
+			aliases.Fields[fieldID] = alias
+		}
+	}
+	addLinkedAliases(config, aliases, tableConfig, linked)
+	return aliases
+}
+
+// linkedResponseAliases returns the aliases of a table's records nested in a link field: its field
+// titles and, for the records linked from them in turn, their target tables'. Each table's are built
+// once, so tables linking back to each other share them and the depth is left to the renaming.
+func linkedResponseAliases(config *ProxyConfig, tableKey string, linked map[string]*ResponseAliases) *ResponseAliases {
+	if aliases, ok := linked[tableKey]; ok {
+		return aliases
+	}
+	tableConfig := config.Tables[tableKey]
+	aliases := &ResponseAliases{Fields: tableConfig.Fields, Links: make(map[string]*ResponseAliases)}
+	linked[tableKey] = aliases
+	addLinkedAliases(config, aliases, tableConfig, linked)
+	return aliases
+}
+
+// addLinkedAliases adds the aliases of the records linked from a table
+func addLinkedAliases(config *ProxyConfig, aliases *ResponseAliases, tableConfig TableConfig, linked map[string]*ResponseAliases) {
+	for _, link := range tableConfig.Links {
+		if targetKey, ok := linkTargetKey(config, link.TargetTable); ok {
+			aliases.Links[link.Field] = linkedResponseAliases(config, targetKey, linked)
+		}
+	}
+}
+
+// linkTargetKey returns the table key of a link's target table, found by table key or, failing that,
+// by NocoDB table name
+func linkTargetKey(config *ProxyConfig, targetTable string) (string, bool) {
+	if _, ok := config.Tables[targetTable]; ok {
+		return targetTable, true
+	}
+	for tableKey, table := range config.Tables {
+		if strings.EqualFold(table.Name, targetTable) {
+			return tableKey, true
+		}
+	}
+	return "", false
+}
+
+// resolveTable looks up a table ID, preferring a pinned override over MetaCache
+func (r *Resolver) resolveTable(overrides Overrides, name string) (string, bool) {
+	if id, ok := lookupFold(overrides.Tables, name); ok {
+		log.Printf("[RESOLVER] Using override for table '%s' -> '%s'", name, id)
+		return id, true
+	}
+	return r.metaCache.ResolveTable(name)
+}
+
+// resolveField looks up a field ID, preferring a pinned override over MetaCache
+func (r *Resolver) resolveField(overrides Overrides, tableName, tableID, fieldName string) (string, bool) {
+	if id, ok := r.fieldOverride(overrides, tableName, fieldName); ok {
+		log.Printf("[RESOLVER] Using override for field '%s.%s' -> '%s'", tableName, fieldName, id)
+		return id, true
+	}
+	return r.metaCache.ResolveField(tableID, fieldName)
+}
+
+// fieldOverride returns the pinned ID of a field, if any
+func (r *Resolver) fieldOverride(overrides Overrides, tableName, fieldName string) (string, bool) {
+	fields, ok := lookupFold(overrides.Fields, tableName)
+	if !ok {
+		return "", false
+	}
+	return lookupFold(fields, fieldName)
+}
+
+// lookupFold finds a map entry by case-insensitive key, preferring an exact match
+func lookupFold[V any](m map[string]V, key string) (V, bool) {
+	if value, ok := m[key]; ok {
+		return value, true
+	}
+	for k, value := range m {
+		if strings.EqualFold(k, key) {
+			return value, true
+		}
+	}
+	var zero V
+	return zero, false
+}
+
+// fieldTitle returns the NocoDB field title for an alias from a table's fields section;
+// anything that isn't an alias is taken to be a title already
+func fieldTitle(name string, fields map[string]string) string {
+	for fieldName, alias := range fields {
+		if alias == name {
+			return fieldName
+		}
+	}
+	return name
+}
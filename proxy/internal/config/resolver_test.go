@@ -0,0 +1,134 @@
+package config
+
+import "testing"
+
+// fakeMetaCache resolves names from fixed maps and counts the lookups it answers
+type fakeMetaCache struct {
+	tables  map[string]string
+	fields  map[string]string // "tableID.fieldName" -> field ID
+	lookups int
+}
+
+func (m *fakeMetaCache) ResolveTable(name string) (string, bool) {
+	m.lookups++
+	id, ok := m.tables[name]
+	return id, ok
+}
+
+func (m *fakeMetaCache) ResolveField(tableID, fieldName string) (string, bool) {
+	m.lookups++
+	id, ok := m.fields[tableID+"."+fieldName]
+	return id, ok
+}
+
+func TestResolverOverridesTakePrecedence(t *testing.T) {
+	metaCache := &fakeMetaCache{
+		tables: map[string]string{"Quotes": "t_meta"},
+		fields: map[string]string{"t_pinned.Title": "c_meta", "t_pinned.Author": "c_author", "t_pinned.Items": "c_items_meta"},
+	}
+	config := &ProxyConfig{
+		NocoDB: NocoDBConfig{BaseID: "b1"},
+		Tables: map[string]TableConfig{"quotes": {
+			Name:       "Quotes",
+			Operations: Operations{All: []string{"read"}},
+			Fields:     map[string]string{"Title": "title", "Author": "author"},
+			Links:      map[string]Link{"items": {Field: "Items", TargetTable: "items"}},
+		}},
+		Overrides: Overrides{
+			Tables: map[string]string{"quotes": "t_pinned"}, // matched case-insensitively
+			Fields: map[string]map[string]string{"Quotes": {"TITLE": "c_pinned", "Items": "c_items"}},
+		},
+	}
+
+	resolved, err := NewResolver(metaCache).Resolve(config)
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	table := resolved.Tables["quotes"]
+	if table.TableID != "t_pinned" {
+		t.Errorf("table ID = %q, want the override", table.TableID)
+	}
+	if table.Fields["title"] != "c_pinned" || table.Fields["author"] != "c_author" {
+		t.Errorf("field IDs = %v, want Title pinned and Author from MetaCache", table.Fields)
+	}
+	link := table.Links["items"]
+	if link.FieldID != "c_items" || !link.Pinned {
+		t.Errorf("link = %+v, want the pinned field ID", link)
+	}
+	if metaCache.lookups != 1 {
+		t.Errorf("MetaCache answered %d lookups, want only the one for Author", metaCache.lookups)
+	}
+}
+
+func TestResolverWithoutOverrideUsesMetaCache(t *testing.T) {
+	metaCache := &fakeMetaCache{tables: map[string]string{"Quotes": "t_meta"}}
+	config := &ProxyConfig{
+		NocoDB:    NocoDBConfig{BaseID: "b1"},
+		Tables:    map[string]TableConfig{"quotes": {Name: "Quotes", Operations: Operations{All: []string{"read"}}}},
+		Overrides: Overrides{Tables: map[string]string{"Orders": "t_orders"}},
+	}
+	resolved, err := NewResolver(metaCache).Resolve(config)
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if id := resolved.Tables["quotes"].TableID; id != "t_meta" {
+		t.Errorf("table ID = %q, want MetaCache's", id)
+	}
+}
+
+func TestResolverArchiveFieldAlias(t *testing.T) {
+	config := &ProxyConfig{
+		NocoDB: NocoDBConfig{BaseID: "b1"},
+		Tables: map[string]TableConfig{"quotes": {
+			Name:         "Quotes",
+			Operations:   Operations{All: []string{"read"}},
+			Fields:       map[string]string{"Archived": "archived"},
+			ArchiveField: "archived",
+		}},
+	}
+	resolved, err := NewResolver(&fakeMetaCache{tables: map[string]string{"Quotes": "t1"}}).Resolve(config)
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if field := resolved.Tables["quotes"].ArchiveField; field != "Archived" {
+		t.Errorf("archive field = %q, want the title behind the alias", field)
+	}
+}
+
+func TestResolverResponseAliasesFollowLinks(t *testing.T) {
+	metaCache := &fakeMetaCache{tables: map[string]string{"Quotes": "t_quotes", "Items": "t_items"}}
+	config := &ProxyConfig{
+		NocoDB: NocoDBConfig{BaseID: "b1"},
+		Tables: map[string]TableConfig{
+			"quotes": {
+				Name:                 "Quotes",
+				Operations:           Operations{All: []string{"read"}},
+				Fields:               map[string]string{"Title": "title"},
+				Links:                map[string]Link{"items": {Field: "Items", TargetTable: "items"}},
+				RenameResponseFields: true,
+			},
+			"items": {
+				Name:       "Items",
+				Operations: Operations{All: []string{"read"}},
+				Fields:     map[string]string{"Quantity": "qty"},
+				Links:      map[string]Link{"quote": {Field: "Quote", TargetTable: "Quotes"}}, // by NocoDB name
+			},
+		},
+	}
+	resolved, err := NewResolver(metaCache).Resolve(config)
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	aliases := resolved.Tables["quotes"].ResponseAliases
+	items := aliases.Links["Items"]
+	if items == nil || items.Fields["Quantity"] != "qty" {
+		t.Fatalf("Items aliases = %+v, want the items table's", items)
+	}
+	quote := items.Links["Quote"]
+	if quote == nil || quote.Fields["Title"] != "title" {
+		t.Fatalf("Items.Quote aliases = %+v, want the quotes table's", quote)
+	}
+	if quote.Links["Items"] != items {
+		t.Errorf("quotes linked back from items build new aliases, want the items aliases shared")
+	}
+}
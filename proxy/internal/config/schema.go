@@ -1,22 +1,113 @@
 package config
 
+import (
+	"time"
+
+	"github.com/grove/generic-proxy/internal/jsonpath"
+)
+
 // ProxyConfig represents the complete schema-driven configuration
 type ProxyConfig struct {
-	NocoDB NocoDBConfig           `yaml:"nocodb"`
-	Tables map[string]TableConfig `yaml:"tables"`
+	NocoDB    NocoDBConfig             `yaml:"nocodb"`
+	Tables    map[string]TableConfig   `yaml:"tables"`
+	Summaries map[string]SummaryConfig `yaml:"summaries,omitempty"`
+	Overrides Overrides                `yaml:"overrides,omitempty"`
+	Features  Features                 `yaml:"features,omitempty"` // overrides FEATURES, read at startup only
+}
+
+// Overrides pin NocoDB IDs for tables and fields MetaCache can't resolve (e.g. custom naming).
+// The resolver uses them instead of MetaCache; names match case-insensitively like MetaCache.
+type Overrides struct {
+	Tables map[string]string            `yaml:"tables,omitempty"` // NocoDB table name -> table ID
+	Fields map[string]map[string]string `yaml:"fields,omitempty"` // NocoDB table name -> (field name -> field ID)
 }
 
 // NocoDBConfig holds NocoDB connection details
 type NocoDBConfig struct {
-	BaseID string `yaml:"base_id"`
+	BaseID              string   `yaml:"base_id"`
+	MetaRefreshInterval Duration `yaml:"meta_refresh_interval,omitempty"` // overrides META_REFRESH_INTERVAL
 }
 
 // TableConfig defines configuration for a single table
 type TableConfig struct {
-	Name       string            `yaml:"name"`
-	Operations []string          `yaml:"operations"`
-	Fields     map[string]string `yaml:"fields,omitempty"`
-	Links      map[string]Link   `yaml:"links,omitempty"`
+	Name         string            `yaml:"name"`
+	Operations   Operations        `yaml:"operations"` // a list for every role, or a list per role
+	Fields       map[string]string `yaml:"fields,omitempty"`
+	Links        map[string]Link   `yaml:"links,omitempty"`
+	MaxBodyBytes ByteSize          `yaml:"max_body_bytes,omitempty"` // overrides MAX_BODY_BYTES for this table
+
+	// RequireReadLinks makes GET on links/... need the read_links operation instead of read
+	RequireReadLinks bool `yaml:"require_read_links,omitempty"`
+
+	// DefaultSort is applied to record lists without ?sort=, e.g. "-created_at, Title" (aliases allowed)
+	DefaultSort string `yaml:"default_sort,omitempty"`
+	// VerifySort checks aggregated pages are ordered by DefaultSort and re-sorts them if not
+	VerifySort bool `yaml:"verify_sort,omitempty"`
+
+	// PrimaryKey lists the fields identifying a record, for tables with composite keys (aliases allowed).
+	// Empty means NocoDB's record id.
+	PrimaryKey []string `yaml:"primary_key,omitempty"`
+
+	// ArchiveField is a checkbox marking records as archived (alias allowed). List reads hide
+	// archived records unless an admin sends ?include_archived=true.
+	ArchiveField string `yaml:"archive_field,omitempty"`
+
+	// OwnerField holds the ID of the user owning a record (alias allowed). Non-admin users only
+	// read the records they own; without it ROW_LEVEL_DEFAULT decides.
+	OwnerField string `yaml:"owner_field,omitempty"`
+	// OwnerAdminOverride lets admins set or change owner_field in writes; everyone else's creates
+	// get their own user ID and their updates can't touch the field
+	OwnerAdminOverride bool `yaml:"owner_admin_override,omitempty"`
+
+	// Caps on list aggregation for this table, overriding MAX_PAGINATION_FANOUT / MAX_PAGINATION_RECORDS (0 = use those)
+	MaxPaginationPages   int `yaml:"max_pagination_pages,omitempty"`
+	MaxPaginationRecords int `yaml:"max_pagination_records,omitempty"`
+
+	// UpstreamCallBudget caps the NocoDB calls per client request for this table, overriding UPSTREAM_CALL_BUDGET (0 = use that)
+	UpstreamCallBudget int `yaml:"upstream_call_budget,omitempty"`
+
+	// WriteCooldown is the minimum time between two writes of one user to this table; earlier ones get 429 (0 = none)
+	WriteCooldown Duration `yaml:"write_cooldown,omitempty"`
+
+	// ResponseFilter prunes GET responses to the subtrees matched by these JSONPath expressions,
+	// e.g. ["$.records[*].id", "$.records[*].fields.Title"]
+	ResponseFilter []string `yaml:"response_filter,omitempty"`
+
+	// DeprecatedFields marks fields scheduled for removal, keyed by field name (aliases allowed)
+	DeprecatedFields map[string]DeprecatedField `yaml:"deprecated_fields,omitempty"`
+
+	// RenameResponseFields renames fields in GET responses to their aliases from the fields section,
+	// so clients read the names they write with. Linked records use their target table's aliases.
+	RenameResponseFields bool `yaml:"rename_response_fields,omitempty"`
+
+	// WritableFields limits the fields create and update bodies may set (aliases or names from fields);
+	// empty = any field. AdminBypass lets requests with the admin role write any field.
+	WritableFields []string `yaml:"writable_fields,omitempty"`
+	AdminBypass    bool     `yaml:"admin_bypass,omitempty"`
+
+	// HiddenFields are never returned to clients (aliases or field names allowed): they are stripped
+	// from every record read, linked records included, and can't be selected, filtered or sorted on
+	HiddenFields []string `yaml:"hidden_fields,omitempty"`
+}
+
+// DeprecatedField annotates a field scheduled for removal. Clients using it are warned until the
+// sunset date (plus DEPRECATION_GRACE); after that it is stripped from reads and rejected in writes.
+type DeprecatedField struct {
+	Deprecated bool   `yaml:"deprecated"`
+	Sunset     string `yaml:"sunset,omitempty"` // YYYY-MM-DD, empty = no removal date yet
+}
+
+// SunsetDateLayout is the format of deprecated_fields[].sunset
+const SunsetDateLayout = "2006-01-02"
+
+// SummaryConfig defines a pre-aggregated value recomputed in the background
+type SummaryConfig struct {
+	Table       string   `yaml:"table"`            // table key from the tables section
+	Filter      string   `yaml:"filter,omitempty"` // NocoDB where clause, e.g. (Status,eq,Open)
+	Aggregation string   `yaml:"aggregation"`      // count, sum, avg, min or max
+	Field       string   `yaml:"field,omitempty"`  // field title; required unless aggregation is count
+	Interval    Duration `yaml:"interval"`
+	Roles       []string `yaml:"roles,omitempty"` // roles allowed to read the summary, empty = everyone
 }
 
 // Link defines a relationship between tables
@@ -33,15 +124,54 @@ type ResolvedConfig struct {
 
 // ResolvedTable contains resolved IDs for a table
 type ResolvedTable struct {
-	Name       string
-	TableID    string
-	Operations []string
-	Fields     map[string]string // field name -> field ID
-	Links      map[string]ResolvedLink
+	Name             string
+	TableID          string
+	Operations       []string            // every role's operations; nil when RoleOperations is set
+	RoleOperations   map[string][]string // per-role operations (role -> operations), nil for a flat list
+	Fields           map[string]string   // field name -> field ID
+	FieldTitles      map[string]string   // field alias -> NocoDB field title, for where/sort
+	Links            map[string]ResolvedLink
+	MaxBodyBytes     int64
+	RequireReadLinks bool
+	DefaultSort      []SortKey // field titles, aliases already resolved
+	VerifySort       bool
+	PrimaryKey       []string // field titles, empty = record id
+	ArchiveField     string   // field title, empty = no archive filter
+	OwnerField       string   // field title, empty = ROW_LEVEL_DEFAULT applies
+	OwnerOverride    bool     // admins may set OwnerField in writes
+	ResponseFilter   []jsonpath.Path
+	Deprecations     map[string]FieldDeprecation // NocoDB field title -> deprecation
+	ResponseAliases  *ResponseAliases            // nil unless rename_response_fields
+	WritableFields   map[string]bool             // field titles writes may set, nil = any
+	AdminBypass      bool                        // admins may write fields outside WritableFields
+	HiddenFields     map[string]bool             // field titles and IDs never returned, nil = none
+	HiddenTitles     []string                    // the field titles in HiddenFields, without the IDs
+
+	MaxPaginationPages   int           // 0 = handler-wide limit
+	MaxPaginationRecords int           // 0 = handler-wide limit
+	UpstreamCallBudget   int           // 0 = handler-wide budget
+	WriteCooldown        time.Duration // 0 = writes aren't throttled
+}
+
+// ResponseAliases rename the fields of records in GET responses
+type ResponseAliases struct {
+	Fields map[string]string           // NocoDB field title or field ID -> alias
+	Links  map[string]*ResponseAliases // link field title -> the target table's aliases, shared by tables linking back
+}
+
+// FieldDeprecation is a resolved deprecated_fields entry
+type FieldDeprecation struct {
+	Name    string    // as configured (alias or title)
+	Title   string    // NocoDB field title
+	FieldID string    // empty when the field didn't resolve
+	Sunset  time.Time // zero = no removal date
 }
 
 // ResolvedLink contains resolved IDs for a link
 type ResolvedLink struct {
-	FieldID     string
-	TargetTable string
+	FieldID      string
+	Title        string // link field title, the key of linked records embedded in a record
+	TargetTable  string
+	Pinned       bool            // FieldID comes from overrides and is used without asking MetaCache
+	HiddenFields map[string]bool // the target table's hidden fields, nil = none
 }
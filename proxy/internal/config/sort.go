@@ -0,0 +1,41 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SortKey is one field of a sort specification
+type SortKey struct {
+	Field string // NocoDB field title
+	Desc  bool
+}
+
+// ParseSortSpec parses a comma-separated sort spec such as "-created_at, Title" ("-" = descending)
+func ParseSortSpec(spec string) ([]SortKey, error) {
+	var keys []SortKey
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key := SortKey{Field: part}
+		if strings.HasPrefix(part, "-") {
+			key = SortKey{Field: strings.TrimSpace(part[1:]), Desc: true}
+		}
+		if key.Field == "" {
+			return nil, fmt.Errorf("invalid sort spec %q: empty field name", spec)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// resolveSortAliases maps aliases from the table's fields section back to NocoDB field titles
+func resolveSortAliases(keys []SortKey, fields map[string]string) []SortKey {
+	resolved := make([]SortKey, len(keys))
+	for i, key := range keys {
+		resolved[i] = SortKey{Field: fieldTitle(key.Field, fields), Desc: key.Desc}
+	}
+	return resolved
+}
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Duration is a time.Duration that accepts Go duration strings ("30s", "5m")
+// or plain integers, which are interpreted as seconds
+type Duration time.Duration
+
+// ByteSize is a size in bytes that accepts unit suffixes ("10MB", "512KiB")
+// or plain integers, which are interpreted as bytes
+type ByteSize int64
+
+// byteUnits maps size suffixes to multipliers (decimal SI and binary IEC)
+var byteUnits = map[string]int64{
+	"b":   1,
+	"kb":  1000,
+	"mb":  1000 * 1000,
+	"gb":  1000 * 1000 * 1000,
+	"kib": 1 << 10,
+	"mib": 1 << 20,
+	"gib": 1 << 30,
+}
+
+// ParseDuration parses a duration string; plain integers are seconds
+func ParseDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, fmt.Errorf("duration %q must not be negative", value)
+		}
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q (expected e.g. \"30s\", \"5m\" or integer seconds)", value)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("duration %q must not be negative", value)
+	}
+	return d, nil
+}
+
+// maxSizeFractionDigits bounds the decimals of a size, so the fraction fits an int64 exactly
+const maxSizeFractionDigits = 18
+
+// ParseByteSize parses a size string; plain integers are bytes. Fractions ("1.5MB") are rounded
+// down to whole bytes. The arithmetic is exact, and sizes beyond an int64 are rejected.
+func ParseByteSize(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if n < 0 {
+			return 0, fmt.Errorf("size %q must not be negative", value)
+		}
+		return n, nil
+	} else if errors.Is(err, strconv.ErrRange) && !strings.HasPrefix(value, "-") {
+		return 0, fmt.Errorf("size %q is too large", value)
+	}
+
+	// Split numeric prefix from unit suffix
+	i := 0
+	for i < len(value) && (value[i] >= '0' && value[i] <= '9' || value[i] == '.') {
+		i++
+	}
+	number, unit := value[:i], strings.ToLower(strings.TrimSpace(value[i:]))
+
+	multiplier, ok := byteUnits[unit]
+	if !ok || number == "" {
+		return 0, fmt.Errorf("invalid size %q (expected e.g. \"10MB\", \"512KiB\" or integer bytes)", value)
+	}
+
+	whole, fraction, _ := strings.Cut(number, ".")
+	if whole == "" && fraction == "" || strings.Contains(fraction, ".") || len(fraction) > maxSizeFractionDigits {
+		return 0, fmt.Errorf("invalid size %q (expected e.g. \"10MB\", \"512KiB\" or integer bytes)", value)
+	}
+	var n int64
+	if whole != "" {
+		w, err := strconv.ParseInt(whole, 10, 64)
+		if err != nil || w > math.MaxInt64/multiplier {
+			return 0, fmt.Errorf("size %q is too large", value)
+		}
+		n = w * multiplier
+	}
+	if fraction != "" {
+		// floor(fraction / 10^digits * multiplier), in 128 bits
+		f, _ := strconv.ParseUint(fraction, 10, 64)
+		scale := uint64(1)
+		for range fraction {
+			scale *= 10
+		}
+		hi, lo := bits.Mul64(f, uint64(multiplier))
+		part, _ := bits.Div64(hi, lo, scale)
+		if int64(part) > math.MaxInt64-n {
+			return 0, fmt.Errorf("size %q is too large", value)
+		}
+		n += int64(part)
+	}
+	return n, nil
+}
+
+// valueError is a setting that failed to parse; the config loader prefixes it with the key path
+type valueError struct {
+	node *yaml.Node
+	err  error
+}
+
+func (e *valueError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.node.Line, e.err)
+}
+
+func (e *valueError) Unwrap() error {
+	return e.err
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	parsed, err := ParseDuration(value.Value)
+	if err != nil {
+		return &valueError{node: value, err: err}
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (b *ByteSize) UnmarshalYAML(value *yaml.Node) error {
+	parsed, err := ParseByteSize(value.Value)
+	if err != nil {
+		return &valueError{node: value, err: err}
+	}
+	*b = ByteSize(parsed)
+	return nil
+}
+
+// Duration returns the value as a time.Duration
+func (d Duration) Duration() time.Duration {
+	return time.Duration(d)
+}
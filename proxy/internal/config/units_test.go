@@ -0,0 +1,63 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int64
+	}{
+		{"0", 0},
+		{"1024", 1024},
+		{"10MB", 10 * 1000 * 1000},
+		{"512KiB", 512 << 10},
+		{" 2 gib ", 2 << 30},
+		{"1.5KB", 1500},
+		{"1.5KiB", 1536},
+		{".5MiB", 1 << 19},
+		{"3.KB", 3000},
+		{"0.0001KB", 0}, // rounded down to whole bytes
+		{"1.999999999999999999B", 1},
+		{"0.1GiB", 107374182},
+		{"9007199254740993KB", 9007199254740993000}, // beyond float64 precision
+		{"8589934591GiB", 8589934591 << 30},
+		{"9223372036854775807", 9223372036854775807},
+	}
+	for _, tt := range tests {
+		got, err := ParseByteSize(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseByteSize(%q) = %d, %v; want %d", tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseByteSizeRejects(t *testing.T) {
+	for _, value := range []string{"", "-1", "MB", ".MB", "1.2.3MB", "10XB", "1e3KB", "1.0000000000000000001KB"} {
+		if n, err := ParseByteSize(value); err == nil {
+			t.Errorf("ParseByteSize(%q) = %d, want an error", value, n)
+		}
+	}
+	for _, value := range []string{"8589934592GiB", "9223372036854775808", "99999999999999999999KB", "9223372036854775.808KB"} {
+		_, err := ParseByteSize(value)
+		if err == nil || !strings.Contains(err.Error(), "too large") {
+			t.Errorf("ParseByteSize(%q) error = %v, want too large", value, err)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	for value, want := range map[string]time.Duration{"30": 30 * time.Second, "5m": 5 * time.Minute, "1h30m": 90 * time.Minute} {
+		if got, err := ParseDuration(value); err != nil || got != want {
+			t.Errorf("ParseDuration(%q) = %v, %v; want %v", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"-5", "-1s", "soon"} {
+		if _, err := ParseDuration(value); err == nil {
+			t.Errorf("ParseDuration(%q) succeeded, want an error", value)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+
+	"github.com/grove/generic-proxy/internal/httperr"
+	"gopkg.in/yaml.v3"
+)
+
+// UpstreamErrorRule maps a NocoDB error response to a proxy error code. Every condition that is
+// set must match; a rule needs at least an upstream code or a message pattern.
+type UpstreamErrorRule struct {
+	Status       int    `yaml:"status,omitempty"`        // upstream HTTP status, 0 = any
+	UpstreamCode string `yaml:"upstream_code,omitempty"` // NocoDB's "error" field, e.g. ERR_RECORD_NOT_FOUND
+	Message      string `yaml:"message,omitempty"`       // regular expression matched against "msg" / "message"
+	Code         string `yaml:"code"`                    // proxy error code returned instead, with its registered status
+
+	pattern *regexp.Regexp
+}
+
+// Matches reports whether the rule applies to an upstream error
+func (r UpstreamErrorRule) Matches(status int, upstreamCode, message string) bool {
+	if r.Status != 0 && r.Status != status {
+		return false
+	}
+	if r.UpstreamCode != "" && r.UpstreamCode != upstreamCode {
+		return false
+	}
+	if r.Message == "" {
+		return true
+	}
+	if r.pattern == nil {
+		// Built without LoadUpstreamErrorRules, e.g. DefaultUpstreamErrorRules used directly
+		matched, err := regexp.MatchString(r.Message, message)
+		return err == nil && matched
+	}
+	return r.pattern.MatchString(message)
+}
+
+// DefaultUpstreamErrorRules cover the errors frontends most often branch on, in the codes of
+// current NocoDB releases and the messages of older ones
+var DefaultUpstreamErrorRules = []UpstreamErrorRule{
+	{UpstreamCode: "ERR_RECORD_NOT_FOUND", Code: httperr.RecordNotFound},
+	{Status: http.StatusNotFound, Message: `(?i)record '.*' not found`, Code: httperr.RecordNotFound},
+	{UpstreamCode: "ERR_FIELD_NOT_FOUND", Code: httperr.UnknownField},
+	{Message: `(?i)field '.*' not found|column .* does not exist|no such column`, Code: httperr.UnknownField},
+	{Message: `(?i)unique constraint|duplicate (key|entry)`, Code: httperr.DuplicateValue},
+}
+
+// LoadUpstreamErrorRules returns the rules of the YAML file at path (a list under "rules") followed
+// by the defaults, so the file can override them; an empty path returns the defaults only
+func LoadUpstreamErrorRules(path string) ([]UpstreamErrorRule, error) {
+	var file struct {
+		Rules []UpstreamErrorRule `yaml:"rules"`
+	}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read upstream error map: %w", err)
+		}
+		if err := yaml.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("failed to parse upstream error map: %w", err)
+		}
+	}
+
+	rules := append(file.Rules, DefaultUpstreamErrorRules...)
+	for i := range rules {
+		rule := &rules[i]
+		if rule.UpstreamCode == "" && rule.Message == "" {
+			return nil, fmt.Errorf("upstream error map: rule %d needs upstream_code or message", i+1)
+		}
+		if _, ok := httperr.Lookup(rule.Code); !ok {
+			return nil, fmt.Errorf("upstream error map: rule %d: '%s' is not a registered error code", i+1, rule.Code)
+		}
+		if rule.Message != "" {
+			pattern, err := regexp.Compile(rule.Message)
+			if err != nil {
+				return nil, fmt.Errorf("upstream error map: rule %d: message: %v", i+1, err)
+			}
+			rule.pattern = pattern
+		}
+	}
+	log.Printf("[CONFIG] Loaded %d upstream error rule(s) (%d from %q)", len(rules), len(file.Rules), path)
+	return rules, nil
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/grove/generic-proxy/internal/httperr"
+)
+
+func TestLoadUpstreamErrorRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "errors.yaml")
+	os.WriteFile(path, []byte(`
+rules:
+  - upstream_code: ERR_RECORD_NOT_FOUND
+    code: table_not_found
+  - status: 422
+    message: "(?i)is required"
+    code: invalid_body
+`), 0o644)
+
+	rules, err := LoadUpstreamErrorRules(path)
+	if err != nil {
+		t.Fatalf("LoadUpstreamErrorRules: %v", err)
+	}
+	if len(rules) != 2+len(DefaultUpstreamErrorRules) {
+		t.Fatalf("got %d rules, want the file's followed by the defaults", len(rules))
+	}
+	first := func(status int, upstreamCode, message string) string {
+		for _, rule := range rules {
+			if rule.Matches(status, upstreamCode, message) {
+				return rule.Code
+			}
+		}
+		return ""
+	}
+	if code := first(http.StatusNotFound, "ERR_RECORD_NOT_FOUND", ""); code != httperr.TableNotFound {
+		t.Errorf("ERR_RECORD_NOT_FOUND -> %q, want the file's rule to win over the default", code)
+	}
+	if code := first(http.StatusUnprocessableEntity, "", "Title IS REQUIRED"); code != httperr.InvalidBody {
+		t.Errorf("422 message -> %q, want %s", code, httperr.InvalidBody)
+	}
+	if code := first(http.StatusBadRequest, "", "Title is required"); code != "" {
+		t.Errorf("400 message -> %q, want no match for another status", code)
+	}
+	if code := first(http.StatusBadRequest, "", "duplicate key value"); code != httperr.DuplicateValue {
+		t.Errorf("duplicate key -> %q, want the default %s", code, httperr.DuplicateValue)
+	}
+}
+
+func TestLoadUpstreamErrorRulesRejectsBadRules(t *testing.T) {
+	tests := []struct {
+		yaml string
+		want string
+	}{
+		{"rules:\n  - code: table_not_found\n", "needs upstream_code or message"},
+		{"rules:\n  - message: x\n    code: no_such_code\n", "not a registered error code"},
+		{"rules:\n  - message: \"(\"\n    code: table_not_found\n", "message"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "errors.yaml")
+		os.WriteFile(path, []byte(tt.yaml), 0o644)
+		if _, err := LoadUpstreamErrorRules(path); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: error %v, want it to mention %q", tt.yaml, err, tt.want)
+		}
+	}
+}
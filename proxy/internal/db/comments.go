@@ -0,0 +1,193 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"time"
+)
+
+// ErrCommentNotFound is returned for missing or deleted comments
+var ErrCommentNotFound = errors.New("comment not found")
+
+// Comment is a discussion entry attached to a NocoDB record. Records are addressed by the
+// table alias used in /proxy/{table}/..., not the NocoDB table ID.
+type Comment struct {
+	ID         int64
+	TableAlias string
+	RecordID   string
+	UserID     string
+	Body       string
+	CreatedAt  time.Time
+	EditedAt   *time.Time
+}
+
+// CreateComment stores a new comment
+func (d *Database) CreateComment(tableAlias, recordID, userID, body string) (*Comment, error) {
+	comment := &Comment{
+		TableAlias: tableAlias,
+		RecordID:   recordID,
+		UserID:     userID,
+		Body:       body,
+		CreatedAt:  time.Now().UTC(),
+	}
+	ctx, cancel := d.queryContext()
+	defer cancel()
+
+	result, err := d.db.ExecContext(ctx, `
+		INSERT INTO comments (table_alias, record_id, user_id, body, created_at) VALUES (?, ?, ?, ?, ?)
+	`, tableAlias, recordID, userID, body, comment.CreatedAt)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to create comment: %v", err)
+		return nil, err
+	}
+	comment.ID, _ = result.LastInsertId()
+	return comment, nil
+}
+
+// GetComment returns a comment that hasn't been deleted
+func (d *Database) GetComment(id int64) (*Comment, error) {
+	ctx, cancel := d.queryContext()
+	defer cancel()
+
+	row := d.db.QueryRowContext(ctx, `
+		SELECT id, table_alias, record_id, user_id, body, created_at, edited_at
+		FROM comments WHERE id = ? AND deleted_at IS NULL
+	`, id)
+	comment, err := scanComment(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrCommentNotFound
+	}
+	return comment, err
+}
+
+// ListComments returns a record's comments newest first. beforeID > 0 continues a previous page.
+func (d *Database) ListComments(tableAlias, recordID string, beforeID int64, limit int) ([]*Comment, error) {
+	query := `
+		SELECT id, table_alias, record_id, user_id, body, created_at, edited_at
+		FROM comments WHERE table_alias = ? AND record_id = ? AND deleted_at IS NULL`
+	args := []interface{}{tableAlias, recordID}
+	if beforeID > 0 {
+		query += " AND id < ?"
+		args = append(args, beforeID)
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	ctx, cancel := d.queryContext()
+	defer cancel()
+
+	rows, err := d.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []*Comment
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+	return comments, rows.Err()
+}
+
+// CountComments returns the number of comments on each of the given records
+func (d *Database) CountComments(tableAlias string, recordIDs []string) (map[string]int, error) {
+	counts := make(map[string]int, len(recordIDs))
+	if len(recordIDs) == 0 {
+		return counts, nil
+	}
+
+	placeholders := strings.Repeat("?,", len(recordIDs))
+	args := []interface{}{tableAlias}
+	for _, id := range recordIDs {
+		args = append(args, id)
+	}
+	ctx, cancel := d.queryContext()
+	defer cancel()
+
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT record_id, COUNT(*) FROM comments
+		WHERE table_alias = ? AND record_id IN (`+placeholders[:len(placeholders)-1]+`) AND deleted_at IS NULL
+		GROUP BY record_id
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var recordID string
+		var count int
+		if err := rows.Scan(&recordID, &count); err != nil {
+			return nil, err
+		}
+		counts[recordID] = count
+	}
+	return counts, rows.Err()
+}
+
+// UpdateComment replaces a comment's body and sets edited_at
+func (d *Database) UpdateComment(id int64, body string) (*Comment, error) {
+	ctx, cancel := d.queryContext()
+	defer cancel()
+
+	result, err := d.db.ExecContext(ctx, `
+		UPDATE comments SET body = ?, edited_at = ? WHERE id = ? AND deleted_at IS NULL
+	`, body, time.Now().UTC(), id)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to update comment %d: %v", id, err)
+		return nil, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, ErrCommentNotFound
+	}
+	return d.GetComment(id)
+}
+
+// DeleteComment tombstones a comment: the row stays for auditing but its body is cleared
+func (d *Database) DeleteComment(id int64) error {
+	ctx, cancel := d.queryContext()
+	defer cancel()
+
+	result, err := d.db.ExecContext(ctx, `
+		UPDATE comments SET body = '', deleted_at = ? WHERE id = ? AND deleted_at IS NULL
+	`, time.Now().UTC(), id)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to delete comment %d: %v", id, err)
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrCommentNotFound
+	}
+	return nil
+}
+
+// tombstoneUserComments clears every comment written by an erased user
+func tombstoneUserComments(ctx context.Context, tx *sql.Tx, userID string) error {
+	_, err := tx.Exec(`
+		UPDATE comments SET body = '', deleted_at = ? WHERE user_id = ? AND deleted_at IS NULL
+	`, time.Now().UTC(), userID)
+	return err
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanComment(row rowScanner) (*Comment, error) {
+	var comment Comment
+	var editedAt sql.NullTime
+	if err := row.Scan(&comment.ID, &comment.TableAlias, &comment.RecordID, &comment.UserID, &comment.Body, &comment.CreatedAt, &editedAt); err != nil {
+		return nil, err
+	}
+	if editedAt.Valid {
+		comment.EditedAt = &editedAt.Time
+	}
+	return &comment, nil
+}
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultQueryTimeout bounds a single database call when no shorter deadline applies
+const DefaultQueryTimeout = 5 * time.Second
+
+// WithContext returns a view of the database whose calls are cancelled together with ctx,
+// typically the request context, so a slow query can't outlive the request that made it
+func (d *Database) WithContext(ctx context.Context) *Database {
+	bound := *d
+	bound.ctx = ctx
+	return &bound
+}
+
+// SetQueryTimeout caps every database call at timeout, on top of any context bound with
+// WithContext; 0 disables the cap. SQLite's wait for a database locked by another connection
+// can't be cancelled, so the connections' busy timeout is set to the same value (the driver
+// default of 5s when the cap is off).
+func (d *Database) SetQueryTimeout(timeout time.Duration) {
+	d.queryTimeout = timeout
+	d.connector.setBusyTimeout(timeout)
+	// Idle connections keep the busy timeout they were opened with; drop them
+	d.db.SetMaxIdleConns(0)
+	d.db.SetMaxIdleConns(defaultMaxIdleConns)
+}
+
+// defaultMaxIdleConns is database/sql's default idle pool size
+const defaultMaxIdleConns = 2
+
+// queryContext returns the context for one database call: the bound context, if any,
+// capped at the query timeout (0 = no cap)
+func (d *Database) queryContext() (context.Context, context.CancelFunc) {
+	parent := d.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	if d.queryTimeout <= 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithTimeout(parent, d.queryTimeout)
+}
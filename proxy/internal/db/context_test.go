@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+// endlessQuery never finishes on its own
+const endlessQuery = "WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x+1 FROM c) SELECT count(*) FROM c"
+
+func TestSlowQueryIsCancelledAtQueryTimeout(t *testing.T) {
+	database := newTestDatabase(t)
+	database.SetQueryTimeout(100 * time.Millisecond)
+
+	ctx, cancel := database.queryContext()
+	defer cancel()
+	start := time.Now()
+	var n int
+	err := database.db.QueryRowContext(ctx, endlessQuery).Scan(&n)
+	if err == nil {
+		t.Fatal("endless query returned a result")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("query ran for %v, want it cancelled after about 100ms", elapsed)
+	}
+}
+
+func TestSlowQueryIsCancelledWithRequest(t *testing.T) {
+	database := newTestDatabase(t)
+	database.SetQueryTimeout(0) // only the request deadline applies
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	bound := database.WithContext(ctx)
+	qctx, qcancel := bound.queryContext()
+	defer qcancel()
+
+	start := time.Now()
+	var n int
+	if err := bound.db.QueryRowContext(qctx, endlessQuery).Scan(&n); err == nil {
+		t.Fatal("endless query returned a result")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("query ran for %v, want it cancelled with the request after about 100ms", elapsed)
+	}
+}
+
+// A user lookup blocked behind another connection's write lock gives up at the query timeout
+func TestBlockedLookupGivesUp(t *testing.T) {
+	database := newTestDatabase(t)
+	if _, err := database.CreateUser("ada@example.com", "google", "Ada", ""); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	// Hold the write lock from a second connection
+	locker := sql.OpenDB(&keyedConnector{driver: sqliteDriver(), path: database.connector.path})
+	defer locker.Close()
+	conn, err := locker.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("second connection: %v", err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(context.Background(), "BEGIN EXCLUSIVE"); err != nil {
+		t.Fatalf("lock: %v", err)
+	}
+	defer conn.ExecContext(context.Background(), "ROLLBACK")
+
+	database.SetQueryTimeout(200 * time.Millisecond)
+	start := time.Now()
+	_, err = database.CreateUser("grace@example.com", "google", "Grace", "")
+	if err == nil {
+		t.Fatal("CreateUser succeeded while another connection held the write lock")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("CreateUser waited %v, want it to give up after about 200ms", elapsed)
+	}
+
+	// Canceled request contexts fail at once
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := database.WithContext(canceled).GetUserByEmail("ada@example.com"); !errors.Is(err, context.Canceled) {
+		t.Errorf("lookup with a canceled request = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build sqlcipher
+
+package db
+
+import (
+	"database/sql/driver"
+
+	sqlite3 "github.com/mutecomm/go-sqlcipher/v4"
+)
+
+// encryptionSupported is true for SQLCipher builds (go build -tags sqlcipher)
+const encryptionSupported = true
+
+func sqliteDriver() driver.Driver {
+	return &sqlite3.SQLiteDriver{}
+}
@@ -0,0 +1,16 @@
+//go:build !sqlcipher
+
+package db
+
+import (
+	"database/sql/driver"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// encryptionSupported is false for plain go-sqlite3; build with -tags sqlcipher to use DATABASE_KEY
+const encryptionSupported = false
+
+func sqliteDriver() driver.Driver {
+	return &sqlite3.SQLiteDriver{}
+}
@@ -0,0 +1,97 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrEncryptionUnsupported is returned when a key is configured but the binary uses plain go-sqlite3
+var ErrEncryptionUnsupported = errors.New("database encryption requires a build with -tags sqlcipher")
+
+// EncryptionSupported reports whether this binary was built with the SQLCipher driver
+func EncryptionSupported() bool {
+	return encryptionSupported
+}
+
+// keyedConnector opens SQLite connections with the current key and busy timeout. The key is
+// swapped after a re-key so any connection opened later (e.g. after a dropped one) uses the new key.
+type keyedConnector struct {
+	driver driver.Driver
+	path   string
+
+	mu          sync.RWMutex
+	key         string
+	busyTimeout time.Duration // 0 = driver default
+}
+
+func (c *keyedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return c.Driver().Open(c.dsn())
+}
+
+func (c *keyedConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// dsn adds the busy timeout and the SQLCipher key pragma to the database path
+func (c *keyedConnector) dsn() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var params []string
+	if c.busyTimeout > 0 {
+		params = append(params, "_busy_timeout="+strconv.FormatInt(c.busyTimeout.Milliseconds(), 10))
+	}
+	if c.key != "" {
+		params = append(params, "_pragma_key="+url.QueryEscape(c.key))
+	}
+	if len(params) == 0 {
+		return c.path
+	}
+	separator := "?"
+	if strings.Contains(c.path, "?") {
+		separator = "&"
+	}
+	return c.path + separator + strings.Join(params, "&")
+}
+
+func (c *keyedConnector) setKey(key string) {
+	c.mu.Lock()
+	c.key = key
+	c.mu.Unlock()
+}
+
+func (c *keyedConnector) setBusyTimeout(timeout time.Duration) {
+	c.mu.Lock()
+	c.busyTimeout = timeout
+	c.mu.Unlock()
+}
+
+// Rekey re-encrypts the database with a new key (SQLCipher builds only).
+// DATABASE_KEY must be updated before the next restart, or the database won't open.
+func (d *Database) Rekey(newKey string) error {
+	if !encryptionSupported {
+		return ErrEncryptionUnsupported
+	}
+	if !d.encrypted {
+		return errors.New("database is not encrypted: start with DATABASE_KEY set before re-keying")
+	}
+	if newKey == "" {
+		return errors.New("new key must not be empty")
+	}
+
+	// PRAGMA doesn't accept bound parameters, so quote the key as an SQL string literal
+	if _, err := d.db.Exec("PRAGMA rekey = '" + strings.ReplaceAll(newKey, "'", "''") + "'"); err != nil {
+		log.Printf("[DB ERROR] Re-key failed: %v", err)
+		return err
+	}
+	d.connector.setKey(newKey)
+
+	log.Println("[DB] Database re-keyed - update DATABASE_KEY before the next restart")
+	return nil
+}
@@ -0,0 +1,83 @@
+//go:build sqlcipher
+
+package db
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// openWithKey opens path with key; the database is closed when the test ends
+func openWithKey(t *testing.T, path, key string) (*Database, error) {
+	t.Helper()
+	database, err := NewDatabaseWithKey(path, key)
+	if err == nil {
+		t.Cleanup(func() { database.Close() })
+	}
+	return database, err
+}
+
+func TestOpenWithKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	database, err := openWithKey(t, path, "s3cret")
+	if err != nil {
+		t.Fatalf("open with key: %v", err)
+	}
+	if _, err := database.CreateUser("ada@example.com", "google", "Ada", ""); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	database.Close()
+
+	if _, err := openWithKey(t, path, "wrong"); err == nil {
+		t.Error("database opened with the wrong key")
+	}
+	if _, err := openWithKey(t, path, ""); err == nil {
+		t.Error("encrypted database opened without a key")
+	}
+	database, err = openWithKey(t, path, "s3cret")
+	if err != nil {
+		t.Fatalf("reopen with key: %v", err)
+	}
+	if _, err := database.GetUserByEmail("ada@example.com"); err != nil {
+		t.Errorf("user lost after reopening: %v", err)
+	}
+}
+
+func TestRekey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	database, err := openWithKey(t, path, "old-key")
+	if err != nil {
+		t.Fatalf("open with key: %v", err)
+	}
+	if _, err := database.CreateUser("ada@example.com", "google", "Ada", ""); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if err := database.Rekey(""); err == nil {
+		t.Error("Rekey accepted an empty key")
+	}
+	if err := database.Rekey("new-key"); err != nil {
+		t.Fatalf("Rekey: %v", err)
+	}
+	// The open handle keeps working after the re-key
+	if _, err := database.GetUserByEmail("ada@example.com"); err != nil {
+		t.Errorf("read after Rekey: %v", err)
+	}
+	database.Close()
+
+	if _, err := openWithKey(t, path, "old-key"); err == nil {
+		t.Error("database still opens with the old key")
+	}
+	database, err = openWithKey(t, path, "new-key")
+	if err != nil {
+		t.Fatalf("open with the new key: %v", err)
+	}
+	if _, err := database.GetUserByEmail("ada@example.com"); err != nil {
+		t.Errorf("user lost after re-keying: %v", err)
+	}
+}
+
+func TestRekeyNeedsEncryptedDatabase(t *testing.T) {
+	if err := newTestDatabase(t).Rekey("s3cret"); err == nil {
+		t.Error("Rekey of an unencrypted database succeeded")
+	}
+}
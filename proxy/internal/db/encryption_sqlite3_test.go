@@ -0,0 +1,21 @@
+//go:build !sqlcipher
+
+package db
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestPlainBuildRejectsKey(t *testing.T) {
+	if EncryptionSupported() {
+		t.Fatal("EncryptionSupported = true without -tags sqlcipher")
+	}
+	if _, err := NewDatabaseWithKey(filepath.Join(t.TempDir(), "test.db"), "s3cret"); !errors.Is(err, ErrEncryptionUnsupported) {
+		t.Errorf("open with key = %v, want ErrEncryptionUnsupported", err)
+	}
+	if err := newTestDatabase(t).Rekey("s3cret"); !errors.Is(err, ErrEncryptionUnsupported) {
+		t.Errorf("Rekey = %v, want ErrEncryptionUnsupported", err)
+	}
+}
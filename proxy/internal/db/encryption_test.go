@@ -0,0 +1,39 @@
+package db
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestKeyedConnectorDSN(t *testing.T) {
+	tests := []struct {
+		path string
+		key  string
+		want string
+	}{
+		{"/data/users.db", "", "/data/users.db"},
+		{"/data/users.db", "s3cret", "/data/users.db?_pragma_key=s3cret"},
+		{"/data/users.db?_busy_timeout=5000", "s3cret", "/data/users.db?_busy_timeout=5000&_pragma_key=s3cret"},
+		{"/data/users.db", "a&b=c '#", "/data/users.db?_pragma_key=" + url.QueryEscape("a&b=c '#")},
+	}
+	for _, tt := range tests {
+		c := &keyedConnector{path: tt.path, key: tt.key}
+		if got := c.dsn(); got != tt.want {
+			t.Errorf("dsn(%q, key %q) = %q, want %q", tt.path, tt.key, got, tt.want)
+		}
+	}
+
+	// The busy timeout follows the query timeout
+	c := &keyedConnector{path: "/data/users.db", key: "s3cret", busyTimeout: 1500 * time.Millisecond}
+	if got, want := c.dsn(), "/data/users.db?_busy_timeout=1500&_pragma_key=s3cret"; got != want {
+		t.Errorf("dsn with busy timeout = %q, want %q", got, want)
+	}
+
+	// Connections opened after a re-key use the new key
+	c = &keyedConnector{path: "/data/users.db", key: "old"}
+	c.setKey("new")
+	if got, want := c.dsn(), "/data/users.db?_pragma_key=new"; got != want {
+		t.Errorf("dsn after setKey = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,41 @@
+package db
+
+import (
+	"log"
+	"time"
+)
+
+// HasIdentity reports whether a provider was explicitly linked to a user. The provider a user
+// was created with (User.Provider) always counts and is not stored here.
+func (d *Database) HasIdentity(userID int64, provider string) (bool, error) {
+	ctx, cancel := d.queryContext()
+	defer cancel()
+
+	var count int
+	err := d.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM user_identities WHERE user_id = ? AND provider = ?
+	`, userID, provider).Scan(&count)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to check identity %s for user %d: %v", provider, userID, err)
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// LinkIdentity lets a user sign in through another OAuth provider with the same email
+func (d *Database) LinkIdentity(userID int64, provider string) error {
+	ctx, cancel := d.queryContext()
+	defer cancel()
+
+	_, err := d.db.ExecContext(ctx, `
+		INSERT INTO user_identities (user_id, provider, linked_at) VALUES (?, ?, ?)
+		ON CONFLICT(user_id, provider) DO NOTHING
+	`, userID, provider, time.Now().UTC())
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to link identity %s to user %d: %v", provider, userID, err)
+		return err
+	}
+
+	log.Printf("[DB] Linked %s identity to user %d", provider, userID)
+	return nil
+}
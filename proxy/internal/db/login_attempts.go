@@ -0,0 +1,155 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"strings"
+	"time"
+)
+
+// Login failures are counted separately per email and per client IP, so spreading guesses over many
+// addresses still locks the account and guessing many accounts from one address still locks the address
+const (
+	loginFailureEmail = "email"
+	loginFailureIP    = "ip"
+)
+
+// RecordFailedLogin counts a failed login against the email and against the client IP. Once a counter
+// reaches its limit (emailLimit, ipLimit; 0 = that counter is off) its key is locked out for cooldown
+// and the count starts over. The returned time is the end of the longer lockout, zero while neither
+// key is locked.
+func (d *Database) RecordFailedLogin(email, ip string, emailLimit, ipLimit int, cooldown time.Duration) (time.Time, error) {
+	ctx, cancel := d.queryContext()
+	defer cancel()
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to record failed login: %v", err)
+		return time.Time{}, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	var until time.Time
+	for _, counter := range []struct {
+		kind, key string
+		limit     int
+	}{
+		{loginFailureEmail, normalizeLoginEmail(email), emailLimit},
+		{loginFailureIP, ip, ipLimit},
+	} {
+		if counter.limit <= 0 || counter.key == "" {
+			continue
+		}
+		lockedUntil, err := countLoginFailure(ctx, tx, counter.kind, counter.key, counter.limit, cooldown, now)
+		if err != nil {
+			log.Printf("[DB ERROR] Failed to record failed login: %v", err)
+			return time.Time{}, err
+		}
+		if lockedUntil.After(until) {
+			until = lockedUntil
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("[DB ERROR] Failed to record failed login: %v", err)
+		return time.Time{}, err
+	}
+	return until, nil
+}
+
+// countLoginFailure adds one failure to a counter and returns the end of its lockout, zero when it isn't locked
+func countLoginFailure(ctx context.Context, tx *sql.Tx, kind, key string, limit int, cooldown time.Duration, now time.Time) (time.Time, error) {
+	var failures int
+	var lockedUntil sql.NullTime
+	err := tx.QueryRowContext(ctx, `
+		SELECT failures, locked_until FROM login_failures WHERE kind = ? AND subject = ?
+	`, kind, key).Scan(&failures, &lockedUntil)
+	if err != nil && err != sql.ErrNoRows {
+		return time.Time{}, err
+	}
+
+	failures++
+	var until time.Time
+	if failures >= limit {
+		until = now.Add(cooldown)
+		failures = 0
+	} else if lockedUntil.Valid && lockedUntil.Time.After(now) {
+		until = lockedUntil.Time // still locked: a failure doesn't shorten the lockout
+	}
+
+	var stored interface{}
+	if !until.IsZero() {
+		stored = until
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO login_failures (kind, subject, failures, locked_until, updated_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (kind, subject) DO UPDATE SET failures = excluded.failures, locked_until = excluded.locked_until, updated_at = excluded.updated_at
+	`, kind, key, failures, stored, now)
+	return until, err
+}
+
+// IsLockedOut returns how long logins for an email or from a client IP stay locked, whichever lockout
+// ends later; 0 when neither is locked
+func (d *Database) IsLockedOut(email, ip string) (time.Duration, error) {
+	ctx, cancel := d.queryContext()
+	defer cancel()
+
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT locked_until FROM login_failures
+		WHERE locked_until IS NOT NULL AND ((kind = ? AND subject = ?) OR (kind = ? AND subject = ?))
+	`, loginFailureEmail, normalizeLoginEmail(email), loginFailureIP, ip)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to check login lockout: %v", err)
+		return 0, err
+	}
+	defer rows.Close()
+
+	var remaining time.Duration
+	for rows.Next() {
+		var lockedUntil time.Time
+		if err := rows.Scan(&lockedUntil); err != nil {
+			log.Printf("[DB ERROR] Failed to check login lockout: %v", err)
+			return 0, err
+		}
+		if left := time.Until(lockedUntil); left > remaining {
+			remaining = left
+		}
+	}
+	return remaining, rows.Err()
+}
+
+// ClearFailedLogins resets the failure count of an email after a successful login. The client IP's
+// count is kept: otherwise one valid account would let an address reset its count between guesses.
+func (d *Database) ClearFailedLogins(email string) error {
+	ctx, cancel := d.queryContext()
+	defer cancel()
+
+	if _, err := d.db.ExecContext(ctx, "DELETE FROM login_failures WHERE kind = ? AND subject = ?", loginFailureEmail, normalizeLoginEmail(email)); err != nil {
+		log.Printf("[DB ERROR] Failed to clear failed logins: %v", err)
+		return err
+	}
+	return nil
+}
+
+// DeleteStaleLoginAttempts removes failure counts untouched since before cutoff whose lockout has
+// ended, and returns how many were removed
+func (d *Database) DeleteStaleLoginAttempts(cutoff time.Time) (int64, error) {
+	ctx, cancel := d.queryContext()
+	defer cancel()
+
+	result, err := d.db.ExecContext(ctx, `
+		DELETE FROM login_failures WHERE updated_at <= ? AND (locked_until IS NULL OR locked_until <= ?)
+	`, cutoff.UTC(), time.Now().UTC())
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to delete stale login attempts: %v", err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// normalizeLoginEmail makes "User@Example.com " and "user@example.com" count as the same account
+func normalizeLoginEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
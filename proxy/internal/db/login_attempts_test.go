@@ -0,0 +1,109 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestDatabase(t *testing.T) *Database {
+	t.Helper()
+	database, err := NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	return database
+}
+
+// fail records n failed logins and returns the lockout end reported by the last one
+func fail(t *testing.T, database *Database, email, ip string, n, emailLimit, ipLimit int) time.Time {
+	t.Helper()
+	var until time.Time
+	for i := 0; i < n; i++ {
+		var err error
+		if until, err = database.RecordFailedLogin(email, ip, emailLimit, ipLimit, time.Minute); err != nil {
+			t.Fatalf("RecordFailedLogin: %v", err)
+		}
+	}
+	return until
+}
+
+func lockedOut(t *testing.T, database *Database, email, ip string) bool {
+	t.Helper()
+	remaining, err := database.IsLockedOut(email, ip)
+	if err != nil {
+		t.Fatalf("IsLockedOut: %v", err)
+	}
+	return remaining > 0
+}
+
+func TestEmailLockoutAcrossAddresses(t *testing.T) {
+	database := newTestDatabase(t)
+	for i, ip := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+		until := fail(t, database, "Alice@Example.com", ip, 1, 3, 20)
+		if locked := !until.IsZero(); locked != (i == 2) {
+			t.Fatalf("failure %d: locked = %v", i+1, locked)
+		}
+	}
+	if !lockedOut(t, database, "alice@example.com", "10.0.0.9") {
+		t.Error("email spread over several addresses isn't locked")
+	}
+	if lockedOut(t, database, "bob@example.com", "10.0.0.9") {
+		t.Error("an unrelated email from an unrelated address is locked")
+	}
+}
+
+func TestIPLockoutAcrossEmails(t *testing.T) {
+	database := newTestDatabase(t)
+	for i := 0; i < 4; i++ {
+		fail(t, database, string(rune('a'+i))+"@example.com", "10.0.0.1", 1, 3, 4)
+	}
+	if !lockedOut(t, database, "new@example.com", "10.0.0.1") {
+		t.Error("address guessing many emails isn't locked")
+	}
+	if lockedOut(t, database, "new@example.com", "10.0.0.2") {
+		t.Error("another address is locked")
+	}
+}
+
+func TestIPCounterOff(t *testing.T) {
+	database := newTestDatabase(t)
+	for i := 0; i < 10; i++ {
+		fail(t, database, string(rune('a'+i))+"@example.com", "10.0.0.1", 1, 3, 0)
+	}
+	if lockedOut(t, database, "new@example.com", "10.0.0.1") {
+		t.Error("address locked with LOGIN_MAX_IP_FAILURES=0")
+	}
+}
+
+func TestSuccessResetsEmailCountOnly(t *testing.T) {
+	database := newTestDatabase(t)
+	fail(t, database, "alice@example.com", "10.0.0.1", 2, 3, 3)
+	if err := database.ClearFailedLogins("ALICE@example.com"); err != nil {
+		t.Fatalf("ClearFailedLogins: %v", err)
+	}
+
+	// The email starts over, so one more failure doesn't lock it...
+	if until := fail(t, database, "alice@example.com", "10.0.0.2", 1, 3, 3); !until.IsZero() {
+		t.Error("email locked after a reset and one failure")
+	}
+	// ...but the first address still has two failures on record
+	if until := fail(t, database, "bob@example.com", "10.0.0.1", 1, 3, 3); until.IsZero() {
+		t.Error("a successful login reset the address count")
+	}
+}
+
+func TestLockoutEndsAndStaleCountsArePurged(t *testing.T) {
+	database := newTestDatabase(t)
+	if _, err := database.RecordFailedLogin("alice@example.com", "10.0.0.1", 1, 0, -time.Second); err != nil {
+		t.Fatalf("RecordFailedLogin: %v", err)
+	}
+	if lockedOut(t, database, "alice@example.com", "10.0.0.1") {
+		t.Error("lockout that already ended still applies")
+	}
+	removed, err := database.DeleteStaleLoginAttempts(time.Now().Add(time.Second))
+	if err != nil || removed != 1 {
+		t.Errorf("DeleteStaleLoginAttempts = %d, %v; want 1 row", removed, err)
+	}
+}
@@ -0,0 +1,147 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Errors returned when a password reset token can't be used
+var (
+	ErrResetTokenInvalid = errors.New("reset token is invalid or was already used")
+	ErrResetTokenExpired = errors.New("reset token has expired")
+)
+
+// CreatePasswordResetToken stores the hash of a new reset token for a user, replacing any token
+// the user was sent before, so only the latest reset link works
+func (d *Database) CreatePasswordResetToken(tokenHash string, userID int64, expiresAt time.Time) error {
+	ctx, cancel := d.queryContext()
+	defer cancel()
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to store reset token: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM password_reset_tokens WHERE user_id = ?", userID); err != nil {
+		log.Printf("[DB ERROR] Failed to store reset token: %v", err)
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO password_reset_tokens (token_hash, user_id, expires_at, created_at) VALUES (?, ?, ?, ?)
+	`, tokenHash, userID, expiresAt.UTC(), time.Now().UTC()); err != nil {
+		log.Printf("[DB ERROR] Failed to store reset token: %v", err)
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("[DB ERROR] Failed to store reset token: %v", err)
+		return err
+	}
+	return nil
+}
+
+// ConsumePasswordResetToken deletes a reset token and returns the ID of the user it was issued to.
+// Each token works once: a missing or already used token fails with ErrResetTokenInvalid, an
+// expired one with ErrResetTokenExpired (and is removed).
+func (d *Database) ConsumePasswordResetToken(tokenHash string) (int64, error) {
+	ctx, cancel := d.queryContext()
+	defer cancel()
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to consume reset token: %v", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var userID int64
+	var expiresAt time.Time
+	err = tx.QueryRowContext(ctx, `
+		SELECT user_id, expires_at FROM password_reset_tokens WHERE token_hash = ?
+	`, tokenHash).Scan(&userID, &expiresAt)
+	if err == sql.ErrNoRows {
+		return 0, ErrResetTokenInvalid
+	}
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to consume reset token: %v", err)
+		return 0, err
+	}
+
+	// Deleting first makes a concurrent second use of the same token find nothing
+	result, err := tx.ExecContext(ctx, "DELETE FROM password_reset_tokens WHERE token_hash = ?", tokenHash)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to consume reset token: %v", err)
+		return 0, err
+	}
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		return 0, ErrResetTokenInvalid
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("[DB ERROR] Failed to consume reset token: %v", err)
+		return 0, err
+	}
+
+	if !time.Now().Before(expiresAt) {
+		return 0, ErrResetTokenExpired
+	}
+	return userID, nil
+}
+
+// DeleteExpiredPasswordResetTokens removes reset tokens past their expiry and returns how many were removed
+func (d *Database) DeleteExpiredPasswordResetTokens() (int64, error) {
+	ctx, cancel := d.queryContext()
+	defer cancel()
+
+	result, err := d.db.ExecContext(ctx, "DELETE FROM password_reset_tokens WHERE expires_at <= ?", time.Now().UTC())
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to delete expired reset tokens: %v", err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// UpdatePassword sets a local user's password and revokes their refresh tokens, so sessions
+// started with the old password end once their access tokens expire
+func (d *Database) UpdatePassword(id int64, password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to hash password: %v", err)
+		return err
+	}
+
+	ctx, cancel := d.queryContext()
+	defer cancel()
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to update password: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "UPDATE users SET password_hash = ? WHERE id = ?", string(hashedPassword), id)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to update password: %v", err)
+		return err
+	}
+	if updated, _ := result.RowsAffected(); updated == 0 {
+		return sql.ErrNoRows
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM refresh_tokens WHERE user_id = ?", strconv.FormatInt(id, 10)); err != nil {
+		log.Printf("[DB ERROR] Failed to revoke refresh tokens of user %d: %v", id, err)
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("[DB ERROR] Failed to update password: %v", err)
+		return err
+	}
+
+	log.Printf("[DB] Password updated: ID=%d", id)
+	return nil
+}
@@ -0,0 +1,151 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+)
+
+// Errors returned when a refresh token can't be used
+var (
+	ErrRefreshTokenInvalid = errors.New("refresh token is invalid or was already used")
+	ErrRefreshTokenExpired = errors.New("refresh token has expired")
+)
+
+// RefreshToken is a stored refresh token. Only the SHA-256 hash of the token is kept, so a
+// leaked database can't be used to mint access tokens.
+type RefreshToken struct {
+	TokenHash string
+	UserID    string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// StoreRefreshToken saves a new refresh token for a user
+func (d *Database) StoreRefreshToken(tokenHash, userID string, expiresAt time.Time) error {
+	ctx, cancel := d.queryContext()
+	defer cancel()
+
+	_, err := d.db.ExecContext(ctx, `
+		INSERT INTO refresh_tokens (token_hash, user_id, expires_at, created_at) VALUES (?, ?, ?, ?)
+	`, tokenHash, userID, expiresAt.UTC(), time.Now().UTC())
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to store refresh token: %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetRefreshToken looks up a refresh token by hash; nil if it doesn't exist (expired ones are returned)
+func (d *Database) GetRefreshToken(tokenHash string) (*RefreshToken, error) {
+	ctx, cancel := d.queryContext()
+	defer cancel()
+
+	token, err := getRefreshToken(ctx, d.db, tokenHash)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to get refresh token: %v", err)
+		return nil, err
+	}
+	return token, nil
+}
+
+// RotateRefreshToken consumes a refresh token and stores its replacement in one transaction, so
+// each token can be used exactly once. The consumed token is returned. A missing or already used
+// token fails with ErrRefreshTokenInvalid, an expired one with ErrRefreshTokenExpired (and is removed).
+func (d *Database) RotateRefreshToken(tokenHash, newTokenHash string, newExpiresAt time.Time) (*RefreshToken, error) {
+	ctx, cancel := d.queryContext()
+	defer cancel()
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to rotate refresh token: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	token, err := getRefreshToken(ctx, tx, tokenHash)
+	if err == sql.ErrNoRows {
+		return nil, ErrRefreshTokenInvalid
+	}
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to rotate refresh token: %v", err)
+		return nil, err
+	}
+
+	// Deleting first makes a concurrent second use of the same token find nothing
+	result, err := tx.ExecContext(ctx, "DELETE FROM refresh_tokens WHERE token_hash = ?", tokenHash)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to rotate refresh token: %v", err)
+		return nil, err
+	}
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	if !time.Now().Before(token.ExpiresAt) {
+		if err := tx.Commit(); err != nil {
+			log.Printf("[DB ERROR] Failed to remove expired refresh token: %v", err)
+			return nil, err
+		}
+		return nil, ErrRefreshTokenExpired
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO refresh_tokens (token_hash, user_id, expires_at, created_at) VALUES (?, ?, ?, ?)
+	`, newTokenHash, token.UserID, newExpiresAt.UTC(), time.Now().UTC()); err != nil {
+		log.Printf("[DB ERROR] Failed to rotate refresh token: %v", err)
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("[DB ERROR] Failed to rotate refresh token: %v", err)
+		return nil, err
+	}
+	return token, nil
+}
+
+// RevokeRefreshToken deletes a refresh token; revoking an unknown token is not an error
+func (d *Database) RevokeRefreshToken(tokenHash string) error {
+	ctx, cancel := d.queryContext()
+	defer cancel()
+
+	if _, err := d.db.ExecContext(ctx, "DELETE FROM refresh_tokens WHERE token_hash = ?", tokenHash); err != nil {
+		log.Printf("[DB ERROR] Failed to revoke refresh token: %v", err)
+		return err
+	}
+	return nil
+}
+
+// DeleteExpiredRefreshTokens removes refresh tokens past their expiry and returns how many were removed
+func (d *Database) DeleteExpiredRefreshTokens() (int64, error) {
+	ctx, cancel := d.queryContext()
+	defer cancel()
+
+	result, err := d.db.ExecContext(ctx, "DELETE FROM refresh_tokens WHERE expires_at <= ?", time.Now().UTC())
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to delete expired refresh tokens: %v", err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// rowQuerier is satisfied by *sql.DB and *sql.Tx
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// getRefreshToken reads one refresh token by hash
+func getRefreshToken(ctx context.Context, q rowQuerier, tokenHash string) (*RefreshToken, error) {
+	token := &RefreshToken{}
+	err := q.QueryRowContext(ctx, `
+		SELECT token_hash, user_id, expires_at, created_at FROM refresh_tokens WHERE token_hash = ?
+	`, tokenHash).Scan(&token.TokenHash, &token.UserID, &token.ExpiresAt, &token.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
@@ -0,0 +1,68 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRotateRefreshToken(t *testing.T) {
+	database := newTestDatabase(t)
+	expires := time.Now().Add(time.Hour)
+	if err := database.StoreRefreshToken("h1", "7", expires); err != nil {
+		t.Fatalf("store: %v", err)
+	}
+
+	consumed, err := database.RotateRefreshToken("h1", "h2", expires)
+	if err != nil || consumed.UserID != "7" {
+		t.Fatalf("rotate: %+v, %v", consumed, err)
+	}
+	if _, err := database.RotateRefreshToken("h1", "h3", expires); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("second use: err = %v, want ErrRefreshTokenInvalid", err)
+	}
+	if token, err := database.GetRefreshToken("h2"); err != nil || token == nil || token.UserID != "7" {
+		t.Errorf("replacement token: %+v, %v; want it stored for user 7", token, err)
+	}
+	if token, _ := database.GetRefreshToken("h3"); token != nil {
+		t.Error("a failed rotation stored its replacement")
+	}
+}
+
+func TestExpiredRefreshToken(t *testing.T) {
+	database := newTestDatabase(t)
+	if err := database.StoreRefreshToken("old", "7", time.Now().Add(-time.Minute)); err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	if _, err := database.RotateRefreshToken("old", "new", time.Now().Add(time.Hour)); !errors.Is(err, ErrRefreshTokenExpired) {
+		t.Errorf("err = %v, want ErrRefreshTokenExpired", err)
+	}
+	for _, hash := range []string{"old", "new"} {
+		if token, _ := database.GetRefreshToken(hash); token != nil {
+			t.Errorf("token %s is stored after an expired rotation", hash)
+		}
+	}
+}
+
+func TestRevokeAndPurgeRefreshTokens(t *testing.T) {
+	database := newTestDatabase(t)
+	database.StoreRefreshToken("live", "7", time.Now().Add(time.Hour))
+	database.StoreRefreshToken("revoked", "7", time.Now().Add(time.Hour))
+	database.StoreRefreshToken("expired", "7", time.Now().Add(-time.Hour))
+
+	if err := database.RevokeRefreshToken("revoked"); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if err := database.RevokeRefreshToken("unknown"); err != nil {
+		t.Errorf("revoking an unknown token: %v", err)
+	}
+	if _, err := database.RotateRefreshToken("revoked", "x", time.Now().Add(time.Hour)); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("revoked token: err = %v, want ErrRefreshTokenInvalid", err)
+	}
+
+	if removed, err := database.DeleteExpiredRefreshTokens(); err != nil || removed != 1 {
+		t.Errorf("purge removed %d (err %v), want the expired token only", removed, err)
+	}
+	if token, _ := database.GetRefreshToken("live"); token == nil {
+		t.Error("purge removed a live token")
+	}
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"log"
+	"time"
+)
+
+// RevokeToken blocklists an access token by its jti until the token would have expired anyway.
+// Revoking a token twice is not an error.
+func (d *Database) RevokeToken(jti string, expiresAt time.Time) error {
+	ctx, cancel := d.queryContext()
+	defer cancel()
+
+	_, err := d.db.ExecContext(ctx, `
+		INSERT OR IGNORE INTO revoked_tokens (jti, expires_at, revoked_at) VALUES (?, ?, ?)
+	`, jti, expiresAt.UTC(), time.Now().UTC())
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to revoke token: %v", err)
+		return err
+	}
+	return nil
+}
+
+// IsTokenRevoked reports whether an access token's jti is on the blocklist
+func (d *Database) IsTokenRevoked(jti string) (bool, error) {
+	ctx, cancel := d.queryContext()
+	defer cancel()
+
+	var count int
+	if err := d.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM revoked_tokens WHERE jti = ?", jti).Scan(&count); err != nil {
+		log.Printf("[DB ERROR] Failed to check token revocation: %v", err)
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// DeleteExpiredRevokedTokens removes blocklist entries of tokens that have expired, which
+// validation rejects on their own, and returns how many were removed
+func (d *Database) DeleteExpiredRevokedTokens() (int64, error) {
+	ctx, cancel := d.queryContext()
+	defer cancel()
+
+	result, err := d.db.ExecContext(ctx, "DELETE FROM revoked_tokens WHERE expires_at <= ?", time.Now().UTC())
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to delete expired revoked tokens: %v", err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
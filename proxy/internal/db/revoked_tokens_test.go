@@ -0,0 +1,30 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRevokedTokens(t *testing.T) {
+	database := newTestDatabase(t)
+	if err := database.RevokeToken("live", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if err := database.RevokeToken("live", time.Now().Add(time.Hour)); err != nil {
+		t.Errorf("revoking twice: %v", err)
+	}
+	database.RevokeToken("expired", time.Now().Add(-time.Minute))
+
+	for jti, want := range map[string]bool{"live": true, "expired": true, "other": false} {
+		if revoked, err := database.IsTokenRevoked(jti); err != nil || revoked != want {
+			t.Errorf("IsTokenRevoked(%s) = %v, %v; want %v", jti, revoked, err, want)
+		}
+	}
+
+	if removed, err := database.DeleteExpiredRevokedTokens(); err != nil || removed != 1 {
+		t.Errorf("purge removed %d (err %v), want the expired entry only", removed, err)
+	}
+	if revoked, _ := database.IsTokenRevoked("live"); !revoked {
+		t.Error("purge removed a live entry")
+	}
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"log"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Defaults for provider-supplied profile fields
+const (
+	DefaultMaxNameLength = 100  // runes
+	maxAvatarURLLength   = 2048 // bytes
+)
+
+// sanitizeName strips control/format characters, collapses whitespace and caps the length in runes
+func sanitizeName(name string, maxLength int) string {
+	if !utf8.ValidString(name) {
+		name = strings.ToValidUTF8(name, "")
+	}
+
+	cleaned := strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsSpace(r):
+			return ' '
+		case unicode.IsControl(r), unicode.Is(unicode.Cf, r):
+			return -1
+		}
+		return r
+	}, name)
+	cleaned = strings.Join(strings.Fields(cleaned), " ")
+
+	if maxLength > 0 && utf8.RuneCountInString(cleaned) > maxLength {
+		cleaned = strings.TrimSpace(string([]rune(cleaned)[:maxLength]))
+	}
+	return cleaned
+}
+
+// cleanName sanitizes a display name about to be stored for owner (an email or user ID, for the log),
+// capped at MaxNameLength. Every write of users.name goes through it.
+func (d *Database) cleanName(owner, name string) string {
+	cleaned := sanitizeName(name, d.MaxNameLength)
+	if cleaned != name {
+		log.Printf("[DB] Sanitized display name for %s (%d -> %d bytes)", owner, len(name), len(cleaned))
+	}
+	return cleaned
+}
+
+// sanitizeAvatarURL returns the URL if it is an absolute http(s) URL, otherwise ""
+func sanitizeAvatarURL(avatarURL string) string {
+	avatarURL = strings.TrimSpace(avatarURL)
+	if avatarURL == "" {
+		return ""
+	}
+	if len(avatarURL) > maxAvatarURLLength {
+		log.Printf("[DB WARN] Avatar URL exceeds %d bytes, clearing it", maxAvatarURLLength)
+		return ""
+	}
+
+	parsed, err := url.Parse(avatarURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || parsed.User != nil {
+		log.Printf("[DB WARN] Avatar URL is not an absolute http(s) URL, clearing it")
+		return ""
+	}
+	for _, r := range avatarURL {
+		if unicode.IsControl(r) || unicode.IsSpace(r) {
+			log.Printf("[DB WARN] Avatar URL contains control or space characters, clearing it")
+			return ""
+		}
+	}
+	return parsed.String()
+}
@@ -1,11 +1,14 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"log"
+	"strconv"
+	"strings"
 	"time"
 
-	_ "github.com/mattn/go-sqlite3"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -21,15 +24,35 @@ type User struct {
 }
 
 type Database struct {
-	db *sql.DB
+	db        *sql.DB
+	connector *keyedConnector
+	encrypted bool
+
+	// MaxNameLength caps provider-supplied display names (in runes); 0 disables the cap
+	MaxNameLength int
+	queryTimeout  time.Duration // see SetQueryTimeout
+
+	ctx context.Context // set by WithContext
 }
 
 func NewDatabase(dbPath string) (*Database, error) {
+	return NewDatabaseWithKey(dbPath, "")
+}
+
+// NewDatabaseWithKey opens the database, encrypted with key when one is given (SQLCipher builds only)
+func NewDatabaseWithKey(dbPath, key string) (*Database, error) {
 	log.Printf("[DB] Opening SQLite database at: %s", dbPath)
 
-	db, err := sql.Open("sqlite3", dbPath)
-	if err != nil {
-		return nil, err
+	if key != "" && !encryptionSupported {
+		return nil, ErrEncryptionUnsupported
+	}
+
+	connector := &keyedConnector{driver: sqliteDriver(), path: dbPath, key: key, busyTimeout: DefaultQueryTimeout}
+	db := sql.OpenDB(connector)
+	if key != "" {
+		// All connections must share the key; a single connection also keeps re-keying consistent
+		db.SetMaxOpenConns(1)
+		log.Println("[DB] Opening with encryption key (SQLCipher)")
 	}
 
 	// Test connection
@@ -37,7 +60,7 @@ func NewDatabase(dbPath string) (*Database, error) {
 		return nil, err
 	}
 
-	database := &Database{db: db}
+	database := &Database{db: db, connector: connector, encrypted: key != "", MaxNameLength: DefaultMaxNameLength, queryTimeout: DefaultQueryTimeout}
 
 	// Initialize schema
 	if err := database.initSchema(); err != nil {
@@ -63,6 +86,68 @@ func (d *Database) initSchema() error {
 
 	CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
 	CREATE INDEX IF NOT EXISTS idx_users_provider ON users(provider);
+
+	CREATE TABLE IF NOT EXISTS summaries (
+		name TEXT PRIMARY KEY,
+		value REAL,
+		computed_at DATETIME,
+		stale INTEGER NOT NULL DEFAULT 0,
+		last_error TEXT
+	);
+
+	CREATE TABLE IF NOT EXISTS user_identities (
+		user_id INTEGER NOT NULL,
+		provider TEXT NOT NULL,
+		linked_at DATETIME NOT NULL,
+		PRIMARY KEY (user_id, provider)
+	);
+
+	CREATE TABLE IF NOT EXISTS comments (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		table_alias TEXT NOT NULL,
+		record_id TEXT NOT NULL,
+		user_id TEXT NOT NULL,
+		body TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		edited_at DATETIME,
+		deleted_at DATETIME
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_comments_record ON comments(table_alias, record_id, id);
+	CREATE INDEX IF NOT EXISTS idx_comments_user ON comments(user_id);
+
+	CREATE TABLE IF NOT EXISTS refresh_tokens (
+		token_hash TEXT PRIMARY KEY,
+		user_id TEXT NOT NULL,
+		expires_at DATETIME NOT NULL,
+		created_at DATETIME NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user ON refresh_tokens(user_id);
+
+	CREATE TABLE IF NOT EXISTS revoked_tokens (
+		jti TEXT PRIMARY KEY,
+		expires_at DATETIME NOT NULL,
+		revoked_at DATETIME NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS password_reset_tokens (
+		token_hash TEXT PRIMARY KEY,
+		user_id INTEGER NOT NULL,
+		expires_at DATETIME NOT NULL,
+		created_at DATETIME NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user ON password_reset_tokens(user_id);
+
+	CREATE TABLE IF NOT EXISTS login_failures (
+		kind TEXT NOT NULL,
+		subject TEXT NOT NULL, -- lower-cased email or client IP
+		failures INTEGER NOT NULL DEFAULT 0,
+		locked_until DATETIME,
+		updated_at DATETIME NOT NULL,
+		PRIMARY KEY (kind, subject)
+	);
 	`
 
 	_, err := d.db.Exec(schema)
@@ -134,6 +219,13 @@ func (d *Database) runMigrations() error {
 		log.Println("[DB] Updated existing users with default role")
 	}
 
+	// login_attempts counted failures per email and IP pair; login_failures replaced it. The counts are
+	// short-lived, so they are dropped rather than converted.
+	if _, err := d.db.Exec(`DROP TABLE IF EXISTS login_attempts`); err != nil {
+		log.Printf("[DB ERROR] Failed to drop login_attempts table: %v", err)
+		return err
+	}
+
 	log.Println("[DB] Migrations completed successfully")
 	return nil
 }
@@ -154,8 +246,15 @@ func (d *Database) CreateUser(email, provider, name, avatarURL string) (*User, e
 		return existingUser, nil
 	}
 
+	// Provider profile fields are untrusted: clean them before storing
+	name = d.cleanName(email, name)
+	avatarURL = sanitizeAvatarURL(avatarURL)
+
 	// Insert new user
-	result, err := d.db.Exec(
+	ctx, cancel := d.queryContext()
+	defer cancel()
+
+	result, err := d.db.ExecContext(ctx,
 		"INSERT INTO users (email, provider, name, avatar_url) VALUES (?, ?, ?, ?)",
 		email, provider, name, avatarURL,
 	)
@@ -178,7 +277,10 @@ func (d *Database) GetUserByID(id int64) (*User, error) {
 	user := &User{}
 	var name, avatarURL, passwordHash, role sql.NullString
 
-	err := d.db.QueryRow(
+	ctx, cancel := d.queryContext()
+	defer cancel()
+
+	err := d.db.QueryRowContext(ctx,
 		"SELECT id, email, provider, name, avatar_url, password_hash, role, created_at FROM users WHERE id = ?",
 		id,
 	).Scan(&user.ID, &user.Email, &user.Provider, &name, &avatarURL, &passwordHash, &role, &user.CreatedAt)
@@ -208,7 +310,10 @@ func (d *Database) GetUserByEmail(email string) (*User, error) {
 	user := &User{}
 	var name, avatarURL, passwordHash, role sql.NullString
 
-	err := d.db.QueryRow(
+	ctx, cancel := d.queryContext()
+	defer cancel()
+
+	err := d.db.QueryRowContext(ctx,
 		"SELECT id, email, provider, name, avatar_url, password_hash, role, created_at FROM users WHERE email = ?",
 		email,
 	).Scan(&user.ID, &user.Email, &user.Provider, &name, &avatarURL, &passwordHash, &role, &user.CreatedAt)
@@ -233,9 +338,114 @@ func (d *Database) GetUserByEmail(email string) (*User, error) {
 	return user, nil
 }
 
+// GetUsersByIDs retrieves the users with the given IDs in a single query.
+// IDs that don't exist are simply absent from the result.
+func (d *Database) GetUsersByIDs(ids []int64) ([]*User, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+
+	ctx, cancel := d.queryContext()
+	defer cancel()
+
+	rows, err := d.db.QueryContext(ctx,
+		"SELECT id, email, provider, name, avatar_url, password_hash, role, created_at FROM users WHERE id IN ("+strings.Join(placeholders, ",")+")",
+		args...,
+	)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to get users by IDs: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*User
+	for rows.Next() {
+		user := &User{}
+		var name, avatarURL, passwordHash, role sql.NullString
+
+		if err := rows.Scan(&user.ID, &user.Email, &user.Provider, &name, &avatarURL, &passwordHash, &role, &user.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		// Handle NULL values
+		user.Name = name.String
+		user.AvatarURL = avatarURL.String
+		user.PasswordHash = passwordHash.String
+		user.Role = role.String
+		if user.Role == "" {
+			user.Role = "user"
+		}
+
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+// GetUsersByEmails retrieves the users with the given emails (case-insensitive) in a single query.
+// Emails without an account are simply absent from the result.
+func (d *Database) GetUsersByEmails(emails []string) ([]*User, error) {
+	if len(emails) == 0 {
+		return nil, nil
+	}
+
+	placeholders := make([]string, len(emails))
+	args := make([]interface{}, len(emails))
+	for i, email := range emails {
+		placeholders[i] = "?"
+		args[i] = strings.ToLower(email)
+	}
+
+	ctx, cancel := d.queryContext()
+	defer cancel()
+
+	rows, err := d.db.QueryContext(ctx,
+		"SELECT id, email, provider, name, avatar_url, password_hash, role, created_at FROM users WHERE lower(email) IN ("+strings.Join(placeholders, ",")+")",
+		args...,
+	)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to get users by emails: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*User
+	for rows.Next() {
+		user := &User{}
+		var name, avatarURL, passwordHash, role sql.NullString
+
+		if err := rows.Scan(&user.ID, &user.Email, &user.Provider, &name, &avatarURL, &passwordHash, &role, &user.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		// Handle NULL values
+		user.Name = name.String
+		user.AvatarURL = avatarURL.String
+		user.PasswordHash = passwordHash.String
+		user.Role = role.String
+		if user.Role == "" {
+			user.Role = "user"
+		}
+
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
 // GetAllUsers retrieves all users
 func (d *Database) GetAllUsers() ([]*User, error) {
-	rows, err := d.db.Query(
+	ctx, cancel := d.queryContext()
+	defer cancel()
+
+	rows, err := d.db.QueryContext(ctx,
 		"SELECT id, email, provider, name, avatar_url, password_hash, role, created_at FROM users ORDER BY created_at DESC",
 	)
 	if err != nil {
@@ -270,7 +480,13 @@ func (d *Database) GetAllUsers() ([]*User, error) {
 
 // UpdateUser updates user information
 func (d *Database) UpdateUser(id int64, name, avatarURL string) error {
-	_, err := d.db.Exec(
+	name = d.cleanName("user "+strconv.FormatInt(id, 10), name)
+	avatarURL = sanitizeAvatarURL(avatarURL)
+
+	ctx, cancel := d.queryContext()
+	defer cancel()
+
+	_, err := d.db.ExecContext(ctx,
 		"UPDATE users SET name = ?, avatar_url = ? WHERE id = ?",
 		name, avatarURL, id,
 	)
@@ -283,18 +499,104 @@ func (d *Database) UpdateUser(id int64, name, avatarURL string) error {
 	return nil
 }
 
-// DeleteUser deletes a user by ID
+// DeleteUser deletes a user by ID and tombstones their comments
 func (d *Database) DeleteUser(id int64) error {
-	_, err := d.db.Exec("DELETE FROM users WHERE id = ?", id)
+	ctx, cancel := d.queryContext()
+	defer cancel()
+
+	tx, err := d.db.BeginTx(ctx, nil)
 	if err != nil {
 		log.Printf("[DB ERROR] Failed to delete user: %v", err)
 		return err
 	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM users WHERE id = ?", id); err != nil {
+		log.Printf("[DB ERROR] Failed to delete user: %v", err)
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM user_identities WHERE user_id = ?", id); err != nil {
+		log.Printf("[DB ERROR] Failed to delete identities of user %d: %v", id, err)
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM refresh_tokens WHERE user_id = ?", strconv.FormatInt(id, 10)); err != nil {
+		log.Printf("[DB ERROR] Failed to revoke refresh tokens of user %d: %v", id, err)
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM password_reset_tokens WHERE user_id = ?", id); err != nil {
+		log.Printf("[DB ERROR] Failed to delete reset tokens of user %d: %v", id, err)
+		return err
+	}
+	if err := tombstoneUserComments(ctx, tx, strconv.FormatInt(id, 10)); err != nil {
+		log.Printf("[DB ERROR] Failed to erase comments of user %d: %v", id, err)
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("[DB ERROR] Failed to delete user: %v", err)
+		return err
+	}
 
 	log.Printf("[DB] User deleted successfully: ID=%d", id)
 	return nil
 }
 
+// Errors returned by UpdateUserRole
+var (
+	ErrInvalidRole = errors.New("role must be 'user' or 'admin'")
+	ErrLastAdmin   = errors.New("cannot demote the last remaining admin")
+)
+
+// UpdateUserRole sets a user's role to "user" or "admin" and returns the updated user, or nil
+// if the user doesn't exist. Demoting the only admin fails with ErrLastAdmin.
+func (d *Database) UpdateUserRole(id int64, role string) (*User, error) {
+	if role != "user" && role != "admin" {
+		return nil, ErrInvalidRole
+	}
+
+	ctx, cancel := d.queryContext()
+	defer cancel()
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to update role: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var current sql.NullString
+	err = tx.QueryRowContext(ctx, "SELECT role FROM users WHERE id = ?", id).Scan(&current)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to update role: %v", err)
+		return nil, err
+	}
+
+	if current.String == "admin" && role != "admin" {
+		var admins int
+		if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE role = 'admin'").Scan(&admins); err != nil {
+			log.Printf("[DB ERROR] Failed to count admins: %v", err)
+			return nil, err
+		}
+		if admins <= 1 {
+			return nil, ErrLastAdmin
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE users SET role = ? WHERE id = ?", role, id); err != nil {
+		log.Printf("[DB ERROR] Failed to update role: %v", err)
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("[DB ERROR] Failed to update role: %v", err)
+		return nil, err
+	}
+
+	log.Printf("[DB] Role updated: ID=%d, role=%s", id, role)
+	return d.GetUserByID(id)
+}
+
 // CreateLocalUser creates a new user with email/password authentication
 func (d *Database) CreateLocalUser(email, password, name string) (*User, error) {
 	log.Printf("[DB] Creating local user: email=%s", email)
@@ -313,8 +615,14 @@ func (d *Database) CreateLocalUser(email, password, name string) (*User, error)
 		return nil, err
 	}
 
+	// Names typed at sign-up are as untrusted as provider profiles
+	name = d.cleanName(email, name)
+
 	// Insert new user with "local" provider
-	result, err := d.db.Exec(
+	ctx, cancel := d.queryContext()
+	defer cancel()
+
+	result, err := d.db.ExecContext(ctx,
 		"INSERT INTO users (email, provider, name, password_hash, role) VALUES (?, ?, ?, ?, ?)",
 		email, "local", name, string(hashedPassword), "user",
 	)
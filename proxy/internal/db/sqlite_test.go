@@ -0,0 +1,50 @@
+package db
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestUpdateUserRole(t *testing.T) {
+	database := newTestDatabase(t)
+	first, err := database.CreateLocalUser("first@example.com", "password", "First")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	second, err := database.CreateLocalUser("second@example.com", "password", "Second")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	if _, err := database.UpdateUserRole(first.ID, "owner"); !errors.Is(err, ErrInvalidRole) {
+		t.Errorf("invalid role: err = %v, want ErrInvalidRole", err)
+	}
+	if user, err := database.UpdateUserRole(9999, "admin"); err != nil || user != nil {
+		t.Errorf("unknown user: %+v, %v; want nil, nil", user, err)
+	}
+
+	user, err := database.UpdateUserRole(first.ID, "admin")
+	if err != nil || user.Role != "admin" {
+		t.Fatalf("promote: %+v, %v", user, err)
+	}
+	if _, err := database.UpdateUserRole(first.ID, "user"); !errors.Is(err, ErrLastAdmin) {
+		t.Errorf("demoting the only admin: err = %v, want ErrLastAdmin", err)
+	}
+	if user, _ := database.GetUserByID(first.ID); user.Role != "admin" {
+		t.Errorf("role after the rejected demotion = %q", user.Role)
+	}
+
+	// With a second admin either one may be demoted, but not both
+	if _, err := database.UpdateUserRole(second.ID, "admin"); err != nil {
+		t.Fatalf("promote second: %v", err)
+	}
+	if user, err := database.UpdateUserRole(first.ID, "user"); err != nil || user.Role != "user" {
+		t.Errorf("demote with another admin left: %+v, %v", user, err)
+	}
+	if _, err := database.UpdateUserRole(second.ID, "user"); !errors.Is(err, ErrLastAdmin) {
+		t.Errorf("demoting the remaining admin: err = %v, want ErrLastAdmin", err)
+	}
+	if user, err := database.UpdateUserRole(second.ID, "admin"); err != nil || user.Role != "admin" {
+		t.Errorf("re-setting an admin's role: %+v, %v", user, err)
+	}
+}
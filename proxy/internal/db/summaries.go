@@ -0,0 +1,80 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"time"
+)
+
+// Summary is the latest stored value of a scheduled summary
+type Summary struct {
+	Name       string
+	Value      *float64 // nil until the first successful computation
+	ComputedAt *time.Time
+	Stale      bool
+	LastError  string
+}
+
+// SaveSummary stores a freshly computed summary value and clears the stale flag
+func (d *Database) SaveSummary(name string, value float64, computedAt time.Time) error {
+	ctx, cancel := d.queryContext()
+	defer cancel()
+
+	_, err := d.db.ExecContext(ctx, `
+		INSERT INTO summaries (name, value, computed_at, stale, last_error) VALUES (?, ?, ?, 0, NULL)
+		ON CONFLICT(name) DO UPDATE SET value = excluded.value, computed_at = excluded.computed_at, stale = 0, last_error = NULL
+	`, name, value, computedAt.UTC())
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to save summary '%s': %v", name, err)
+	}
+	return err
+}
+
+// MarkSummaryStale records a failed computation, keeping the last good value
+func (d *Database) MarkSummaryStale(name, lastError string) error {
+	ctx, cancel := d.queryContext()
+	defer cancel()
+
+	_, err := d.db.ExecContext(ctx, `
+		INSERT INTO summaries (name, stale, last_error) VALUES (?, 1, ?)
+		ON CONFLICT(name) DO UPDATE SET stale = 1, last_error = excluded.last_error
+	`, name, lastError)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to mark summary '%s' stale: %v", name, err)
+	}
+	return err
+}
+
+// GetSummaries returns every stored summary keyed by name
+func (d *Database) GetSummaries() (map[string]*Summary, error) {
+	ctx, cancel := d.queryContext()
+	defer cancel()
+
+	rows, err := d.db.QueryContext(ctx, `SELECT name, value, computed_at, stale, last_error FROM summaries`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries := make(map[string]*Summary)
+	for rows.Next() {
+		var (
+			summary    Summary
+			value      sql.NullFloat64
+			computedAt sql.NullTime
+			lastError  sql.NullString
+		)
+		if err := rows.Scan(&summary.Name, &value, &computedAt, &summary.Stale, &lastError); err != nil {
+			return nil, err
+		}
+		if value.Valid {
+			summary.Value = &value.Float64
+		}
+		if computedAt.Valid {
+			summary.ComputedAt = &computedAt.Time
+		}
+		summary.LastError = lastError.String
+		summaries[summary.Name] = &summary
+	}
+	return summaries, rows.Err()
+}
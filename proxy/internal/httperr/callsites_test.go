@@ -0,0 +1,188 @@
+package httperr
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// moduleRoot is the proxy module, relative to this package
+const moduleRoot = "../.."
+
+// codeSinks are the functions that take an error code, with the index of that argument.
+// Unqualified names and methods only count inside pkg.
+var codeSinks = []struct {
+	name string
+	pkg  string
+	arg  int
+}{
+	{name: "httperr.WriteError", arg: 1},
+	{name: "httperr.WriteErrorParams", arg: 1},
+	{name: "httperr.WriteErrorWithFields", arg: 1},
+	{name: "newValidationError", pkg: "internal/proxy", arg: 0},
+	{name: "fail", pkg: "internal/proxy", arg: 0},
+}
+
+// forwardedCodes are the call sites that pass on a code checked somewhere else, keyed by
+// file, enclosing function and argument
+var forwardedCodes = map[string]string{
+	"internal/comments/handler.go ServeHTTP validationErr.Code":        "ValidationError codes come from newValidationError",
+	"internal/proxy/errors.go writeValidationError validationErr.Code": "ValidationError codes come from newValidationError",
+	"internal/proxy/errors.go newValidationError code":                 "every newValidationError call is checked",
+	"internal/proxy/response.go fail code":                             "every fail call is checked",
+	"internal/proxy/rewrite.go rewriteResponse code":                   "UPSTREAM_ERROR_MAP codes are checked against the catalog when the map is loaded",
+}
+
+// registeredConstants maps the names of the code constants in httperr.go to their values
+func registeredConstants(t *testing.T) map[string]string {
+	t.Helper()
+	file, err := parser.ParseFile(token.NewFileSet(), "httperr.go", nil, 0)
+	if err != nil {
+		t.Fatalf("parse httperr.go: %v", err)
+	}
+	constants := make(map[string]string)
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			value := spec.(*ast.ValueSpec)
+			for i, name := range value.Names {
+				lit, ok := value.Values[i].(*ast.BasicLit)
+				if !ok || lit.Kind != token.STRING {
+					continue
+				}
+				constants[name.Name], _ = strconv.Unquote(lit.Value)
+			}
+		}
+	}
+	return constants
+}
+
+// calleeName returns httperr.Name for package calls and the bare name for everything else
+func calleeName(call *ast.CallExpr) string {
+	switch fn := call.Fun.(type) {
+	case *ast.SelectorExpr:
+		if x, ok := fn.X.(*ast.Ident); ok && x.Name == "httperr" {
+			return "httperr." + fn.Sel.Name
+		}
+		return fn.Sel.Name
+	case *ast.Ident:
+		return fn.Name
+	}
+	return ""
+}
+
+// TestCallSitesUseRegisteredCodes walks every Go file of the module and fails for any error code
+// passed to WriteError & co. that is neither a registered httperr constant nor a known forward
+func TestCallSitesUseRegisteredCodes(t *testing.T) {
+	constants := registeredConstants(t)
+	fset := token.NewFileSet()
+	checked := 0
+	seenForwards := make(map[string]bool)
+
+	checkCode := func(rel, function string, expr ast.Expr) {
+		checked++
+		if sel, ok := expr.(*ast.SelectorExpr); ok {
+			if x, ok := sel.X.(*ast.Ident); ok && x.Name == "httperr" {
+				value, ok := constants[sel.Sel.Name]
+				if !ok {
+					t.Errorf("%s: httperr.%s is not an error code constant", fset.Position(expr.Pos()), sel.Sel.Name)
+				} else if _, ok := Lookup(value); !ok {
+					t.Errorf("%s: httperr.%s (%q) is not registered in the catalog", fset.Position(expr.Pos()), sel.Sel.Name, value)
+				}
+				return
+			}
+		}
+		var source strings.Builder
+		printer.Fprint(&source, fset, expr)
+		key := rel + " " + function + " " + source.String()
+		seenForwards[key] = true
+		if _, ok := forwardedCodes[key]; !ok {
+			t.Errorf("%s: error code %s must be an httperr constant (or a forward listed in forwardedCodes)", fset.Position(expr.Pos()), source.String())
+		}
+	}
+
+	err := filepath.WalkDir(moduleRoot, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return err
+		}
+		rel, _ := filepath.Rel(moduleRoot, path)
+		rel = filepath.ToSlash(rel)
+		if strings.HasPrefix(rel, "internal/httperr/") {
+			return nil
+		}
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return err
+		}
+
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Body == nil {
+				continue
+			}
+			ast.Inspect(fn.Body, func(node ast.Node) bool {
+				switch n := node.(type) {
+				case *ast.CallExpr:
+					name := calleeName(n)
+					for _, sink := range codeSinks {
+						if sink.name == name && (sink.pkg == "" || strings.HasPrefix(rel, sink.pkg+"/")) && len(n.Args) > sink.arg {
+							checkCode(rel, fn.Name.Name, n.Args[sink.arg])
+						}
+					}
+				case *ast.CompositeLit:
+					if ident, ok := n.Type.(*ast.Ident); ok && ident.Name == "ValidationError" {
+						for _, elt := range n.Elts {
+							if kv, ok := elt.(*ast.KeyValueExpr); ok {
+								if key, ok := kv.Key.(*ast.Ident); ok && key.Name == "Code" {
+									checkCode(rel, fn.Name.Name, kv.Value)
+								}
+							}
+						}
+					}
+				}
+				return true
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk module: %v", err)
+	}
+	if checked < 50 {
+		t.Fatalf("only %d call sites found; is moduleRoot right?", checked)
+	}
+	for key := range forwardedCodes {
+		if !seenForwards[key] {
+			t.Errorf("forwardedCodes entry %q matches no call site anymore", key)
+		}
+	}
+}
+
+func TestEveryConstantIsRegistered(t *testing.T) {
+	for name, value := range registeredConstants(t) {
+		if _, ok := Lookup(value); !ok {
+			t.Errorf("%s (%q) has no catalog entry", name, value)
+		}
+	}
+}
+
+func TestStatusPanicsOnUnregisteredCode(t *testing.T) {
+	if got := Status(TableNotFound); got != 404 {
+		t.Errorf("Status(TableNotFound) = %d, want 404", got)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("Status of an unregistered code did not panic")
+		}
+	}()
+	Status("no_such_code")
+}
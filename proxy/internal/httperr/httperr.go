@@ -0,0 +1,219 @@
+// Package httperr is the shared catalog of machine-readable error codes returned to clients.
+// Every code written with WriteError must be registered here so clients can rely on
+// GET /__proxy/errors as the authoritative list; callsites_test.go fails for any call site
+// that passes an unregistered code.
+package httperr
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+)
+
+// Error codes
+const (
+	InvalidPath         = "invalid_path"
+	InvalidRecordID     = "invalid_record_id"
+	InvalidBody         = "invalid_body"
+	InvalidCursor       = "invalid_cursor"
+	InvalidSelectOption = "invalid_select_option"
+	PayloadTooLarge     = "payload_too_large"
+	TableNotFound       = "table_not_found"
+	OperationNotAllowed = "operation_not_allowed"
+	LinkNotAllowed      = "link_not_allowed"
+	UnknownLinkField    = "unknown_link_field"
+	RecordNotFound      = "record_not_found"
+	UpstreamReadFailed  = "upstream_read_failed"
+	UpstreamTimeout     = "upstream_timeout"
+	UpstreamUnavailable = "upstream_unavailable"
+	InternalError       = "internal_error"
+	CommentNotFound     = "comment_not_found"
+	NotCommentAuthor    = "not_comment_author"
+	NotRecordOwner      = "not_record_owner"
+
+	TooManyConcurrentRequests = "too_many_concurrent_requests"
+	ComputedFieldWrite        = "computed_field_write"
+	AdminRequired             = "admin_required"
+	SchemaReloadFailed        = "schema_reload_failed"
+	UnknownUser               = "unknown_user"
+	FieldNotAllowed           = "field_not_allowed"
+	ConfigSnapshotNotFound    = "config_snapshot_not_found"
+	UnknownField              = "unknown_field"
+	FieldSunset               = "field_sunset"
+	RequestHeadersTooLarge    = "request_headers_too_large"
+	UpstreamRejected          = "upstream_rejected"
+	UpstreamBudgetExhausted   = "upstream_budget_exhausted"
+	RateLimited               = "rate_limited"
+	UserRateLimited           = "user_rate_limited"
+	FieldNotWritable          = "field_not_writable"
+	InvalidWhere              = "invalid_where"
+	DuplicateValue            = "duplicate_value"
+	ReloadInProgress          = "reload_in_progress"
+	WriteCooldown             = "write_cooldown"
+)
+
+// Entry describes one error code
+type Entry struct {
+	Code        string `json:"code"`
+	Status      int    `json:"status"`
+	Description string `json:"description"`
+	Retryable   bool   `json:"retryable"`
+}
+
+// catalog holds every registered code
+var catalog = map[string]Entry{
+	InvalidPath:         {Status: http.StatusBadRequest, Description: "The request path is empty or malformed"},
+	InvalidRecordID:     {Status: http.StatusBadRequest, Description: "A record ID in the request path doesn't match RECORD_ID_PATTERN"},
+	InvalidBody:         {Status: http.StatusBadRequest, Description: "The request body could not be read"},
+	InvalidCursor:       {Status: http.StatusBadRequest, Description: "The pagination cursor is tampered, expired or was issued for another table or query"},
+	InvalidSelectOption: {Status: http.StatusBadRequest, Description: "A single/multi-select field value is not one of the field's options"},
+	PayloadTooLarge:     {Status: http.StatusRequestEntityTooLarge, Description: "The request body exceeds the configured size limit"},
+	TableNotFound:       {Status: http.StatusNotFound, Description: "The table is not configured in proxy.yaml"},
+	OperationNotAllowed: {Status: http.StatusForbidden, Description: "The operation is not allowed on this table"},
+	LinkNotAllowed:      {Status: http.StatusForbidden, Description: "The link is not configured for this table"},
+	UnknownLinkField:    {Status: http.StatusBadRequest, Description: "The link alias does not match any link field of the table"},
+	RecordNotFound:      {Status: http.StatusNotFound, Description: "The record addressed by a link request does not exist, or a single-record read hit another user's record under owner_field, or an owner-checked update or delete addresses a missing record"},
+	UpstreamReadFailed:  {Status: http.StatusBadGateway, Description: "The NocoDB response could not be read", Retryable: true},
+	UpstreamTimeout:     {Status: http.StatusGatewayTimeout, Description: "NocoDB did not respond within the upstream timeout", Retryable: true},
+	UpstreamUnavailable: {Status: http.StatusBadGateway, Description: "The request could not be sent to NocoDB or no response arrived", Retryable: true},
+	InternalError:       {Status: http.StatusInternalServerError, Description: "The proxy failed to prepare the request for NocoDB"},
+	CommentNotFound:     {Status: http.StatusNotFound, Description: "The comment does not exist, was deleted or belongs to another record"},
+	NotCommentAuthor:    {Status: http.StatusForbidden, Description: "Only the comment's author or an admin can edit or delete it"},
+	NotRecordOwner:      {Status: http.StatusForbidden, Description: "An update or delete addresses records owned by another user under owner_field"},
+
+	TooManyConcurrentRequests: {Status: http.StatusTooManyRequests, Description: "The user already has the maximum number of requests in flight", Retryable: true},
+	ComputedFieldWrite:        {Status: http.StatusBadRequest, Description: "The write body sets formula, rollup or other computed fields (COMPUTED_FIELDS=reject)"},
+	AdminRequired:             {Status: http.StatusForbidden, Description: "The endpoint is restricted to users with the admin role"},
+	SchemaReloadFailed:        {Status: http.StatusBadGateway, Description: "A forced metadata refresh or re-resolving proxy.yaml failed; the previous schema stays active", Retryable: true},
+	UnknownUser:               {Status: http.StatusBadRequest, Description: "A user field write references a proxy user_id that doesn't exist"},
+	FieldNotAllowed:           {Status: http.StatusForbidden, Description: "The fields parameter names a field that is not configured for this table"},
+	ConfigSnapshotNotFound:    {Status: http.StatusNotFound, Description: "The rollback target is missing from the config history, or its hash prefix is too short or ambiguous"},
+	UnknownField:              {Status: http.StatusBadRequest, Description: "A where or sort parameter or a write body names a field that is neither an alias nor a field of the table (UNRESOLVED_FIELDS=strict)"},
+	FieldSunset:               {Status: http.StatusGone, Description: "The request writes, filters or sorts on a deprecated field past its sunset date"},
+	RequestHeadersTooLarge:    {Status: http.StatusRequestHeaderFieldsTooLarge, Description: "The request has more headers, or larger ones, than REQUEST_HEADER_MAX_COUNT / REQUEST_HEADER_MAX_BYTES allow"},
+	UpstreamRejected:          {Status: http.StatusBadRequest, Description: "NocoDB refused a row of a best-effort bulk write; the row's status is NocoDB's (BULK_WRITES=best_effort)"},
+	UpstreamBudgetExhausted:   {Status: http.StatusServiceUnavailable, Description: "The request used up its upstream call budget (UPSTREAM_CALL_BUDGET) before this part could be sent to NocoDB", Retryable: true},
+	RateLimited:               {Status: http.StatusTooManyRequests, Description: "The client address made more requests than IP_RATE_LIMIT allows per IP_RATE_LIMIT_WINDOW; wait for Retry-After", Retryable: true},
+	UserRateLimited:           {Status: http.StatusTooManyRequests, Description: "The user made more requests to a per-user limited endpoint (USER_DISPLAY_RATE_LIMIT) than allowed per minute; wait for Retry-After", Retryable: true},
+	FieldNotWritable:          {Status: http.StatusBadRequest, Description: "The write body sets fields outside the table's writable_fields"},
+	InvalidWhere:              {Status: http.StatusBadRequest, Description: "The where parameter has unbalanced parentheses and can't be combined with the owner filter"},
+	DuplicateValue:            {Status: http.StatusConflict, Description: "NocoDB rejected the write because a unique field already has the value (UPSTREAM_ERRORS=mapped)"},
+	WriteCooldown:             {Status: http.StatusTooManyRequests, Description: "A write arrived within the table's write_cooldown after the same user's previous write; wait for Retry-After", Retryable: true},
+	ReloadInProgress:          {Status: http.StatusConflict, Description: "Another metadata refresh or config rollback is running; retry once it is done, or set RELOAD_CONCURRENCY=queue to wait", Retryable: true},
+}
+
+// Lookup returns the catalog entry for a code
+func Lookup(code string) (Entry, bool) {
+	entry, ok := catalog[code]
+	if ok {
+		entry.Code = code
+	}
+	return entry, ok
+}
+
+// Status returns the HTTP status for a code. Unregistered codes are a programming error that
+// callsites_test.go catches, so they panic rather than reach clients with a made-up status.
+func Status(code string) int {
+	entry, ok := catalog[code]
+	if !ok {
+		panic("httperr: unregistered error code '" + code + "'")
+	}
+	return entry.Status
+}
+
+// Catalog returns every registered code sorted by name
+func Catalog() []Entry {
+	entries := make([]Entry, 0, len(catalog))
+	for code := range catalog {
+		entry, _ := Lookup(code)
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Code < entries[j].Code })
+	return entries
+}
+
+// Localizer is implemented by response writers that know the client's preferred locale
+type Localizer interface {
+	Locale() string
+}
+
+// Translate localizes the message for a code, or reports false to keep the English message.
+// It is installed at startup by the i18n package; nil leaves every message in English.
+var Translate func(locale, code string, params map[string]string) (string, bool)
+
+// localeOf finds the client's locale on w or any writer it wraps
+func localeOf(w http.ResponseWriter) string {
+	for w != nil {
+		if localizer, ok := w.(Localizer); ok {
+			return localizer.Locale()
+		}
+		unwrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return ""
+		}
+		w = unwrapper.Unwrap()
+	}
+	return ""
+}
+
+// WriteError writes {"error": message, "code": code} with the code's registered status
+func WriteError(w http.ResponseWriter, code, message string) {
+	writeError(w, code, message, nil, nil)
+}
+
+// WriteErrorParams is WriteError with the values the code's translated message is built from,
+// e.g. {"table": "orders"}. message is the English text used when no translation applies.
+func WriteErrorParams(w http.ResponseWriter, code, message string, params map[string]string) {
+	writeError(w, code, message, params, nil)
+}
+
+// WriteErrorWithFields writes the standard error envelope plus extra fields.
+// String fields double as the values of the translated message's placeholders.
+func WriteErrorWithFields(w http.ResponseWriter, code, message string, fields map[string]interface{}) {
+	params := make(map[string]string)
+	for key, value := range fields {
+		if s, ok := value.(string); ok {
+			params[key] = s
+		}
+	}
+	writeError(w, code, message, params, fields)
+}
+
+// writeError localizes the message for the client's locale and writes the envelope
+func writeError(w http.ResponseWriter, code, message string, params map[string]string, fields map[string]interface{}) {
+	if locale := localeOf(w); locale != "" && Translate != nil {
+		if translated, ok := Translate(locale, code, params); ok {
+			message = translated
+			w.Header().Set("Content-Language", locale)
+		}
+	}
+
+	body := map[string]interface{}{
+		"error": message,
+		"code":  code,
+	}
+	for key, value := range fields {
+		body[key] = value
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(Status(code))
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("[HTTPERR] Failed to encode error response: %v", err)
+	}
+}
+
+// ServeCatalog handles GET /__proxy/errors
+func ServeCatalog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"errors": Catalog()}); err != nil {
+		log.Printf("[HTTPERR] Failed to encode error catalog: %v", err)
+	}
+}
@@ -0,0 +1,189 @@
+// Package i18n localizes client-facing error messages by error code.
+// Catalogs map httperr codes to message templates with {name} placeholders; en and de are
+// embedded and a directory of <locale>.yaml files can add locales or override messages.
+// Log messages are never localized.
+package i18n
+
+import (
+	"embed"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/grove/generic-proxy/internal/httperr"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultLocale is the reference locale; its catalog defines each code's placeholders
+const DefaultLocale = "en"
+
+//go:embed catalogs/*.yaml
+var embedded embed.FS
+
+// placeholderPattern matches {name} placeholders in message templates
+var placeholderPattern = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+// Catalogs holds the message templates of every locale: locale -> code -> template
+type Catalogs struct {
+	messages map[string]map[string]string
+}
+
+// Load reads the embedded catalogs, merges the <locale>.yaml files from dir over them
+// (dir may be empty) and validates the result
+func Load(dir string) (*Catalogs, error) {
+	c := &Catalogs{messages: make(map[string]map[string]string)}
+
+	files, err := embedded.ReadDir("catalogs")
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		data, err := embedded.ReadFile("catalogs/" + file.Name())
+		if err != nil {
+			return nil, err
+		}
+		if err := c.merge(file.Name(), data); err != nil {
+			return nil, err
+		}
+	}
+
+	if dir != "" {
+		paths, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read catalog: %w", err)
+			}
+			if err := c.merge(filepath.Base(path), data); err != nil {
+				return nil, err
+			}
+			log.Printf("[I18N] Loaded error catalog %s", path)
+		}
+	}
+
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// merge adds the messages of one catalog file, named <locale>.yaml
+func (c *Catalogs) merge(name string, data []byte) error {
+	locale := normalize(strings.TrimSuffix(name, filepath.Ext(name)))
+
+	var messages map[string]string
+	if err := yaml.Unmarshal(data, &messages); err != nil {
+		return fmt.Errorf("catalog %s: %w", name, err)
+	}
+
+	if c.messages[locale] == nil {
+		c.messages[locale] = make(map[string]string)
+	}
+	for code, template := range messages {
+		c.messages[locale][code] = template
+	}
+	return nil
+}
+
+// validate checks every catalog uses registered codes and the same placeholders as English
+func (c *Catalogs) validate() error {
+	reference := c.messages[DefaultLocale]
+	var problems []string
+
+	for code := range reference {
+		if _, ok := httperr.Lookup(code); !ok {
+			problems = append(problems, fmt.Sprintf("%s: unregistered code '%s'", DefaultLocale, code))
+		}
+	}
+
+	for locale, messages := range c.messages {
+		if locale == DefaultLocale {
+			continue
+		}
+		for code, template := range messages {
+			english, ok := reference[code]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s: code '%s' has no English message", locale, code))
+				continue
+			}
+			want, got := placeholders(english), placeholders(template)
+			for name := range want {
+				if !got[name] {
+					problems = append(problems, fmt.Sprintf("%s: '%s' is missing placeholder {%s}", locale, code, name))
+				}
+			}
+			for name := range got {
+				if !want[name] {
+					problems = append(problems, fmt.Sprintf("%s: '%s' uses unknown placeholder {%s}", locale, code, name))
+				}
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("invalid error catalogs:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// Has reports whether a catalog exists for the locale
+func (c *Catalogs) Has(locale string) bool {
+	_, ok := c.messages[normalize(locale)]
+	return ok
+}
+
+// Locales returns the available locales sorted by name
+func (c *Catalogs) Locales() []string {
+	locales := make([]string, 0, len(c.messages))
+	for locale := range c.messages {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Translate renders the message for a code in the locale. It reports false when the locale has
+// no message for the code or a placeholder has no value, so the caller keeps its English text.
+// English itself is never rendered from the catalog: handlers already write it, usually with more detail.
+func (c *Catalogs) Translate(locale, code string, params map[string]string) (string, bool) {
+	locale = normalize(locale)
+	if locale == DefaultLocale {
+		return "", false
+	}
+	template, ok := c.messages[locale][code]
+	if !ok {
+		return "", false
+	}
+
+	complete := true
+	message := placeholderPattern.ReplaceAllStringFunc(template, func(match string) string {
+		value, ok := params[match[1:len(match)-1]]
+		if !ok {
+			complete = false
+		}
+		return value
+	})
+	return message, complete
+}
+
+// placeholders returns the set of placeholder names in a template
+func placeholders(template string) map[string]bool {
+	names := make(map[string]bool)
+	for _, match := range placeholderPattern.FindAllStringSubmatch(template, -1) {
+		names[match[1]] = true
+	}
+	return names
+}
+
+// normalize lower-cases a language tag and uses "-" as the separator (de_DE -> de-de)
+func normalize(tag string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+}
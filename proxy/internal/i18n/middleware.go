@@ -0,0 +1,86 @@
+package i18n
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Negotiate picks the best available locale from an Accept-Language header, matching the full
+// tag first and then its primary language (de-AT -> de). Returns fallback when nothing matches.
+func (c *Catalogs) Negotiate(acceptLanguage, fallback string) string {
+	type candidate struct {
+		tag string
+		q   float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		tag = normalize(tag)
+		if tag == "" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q <= 0 {
+			continue
+		}
+		candidates = append(candidates, candidate{tag: tag, q: q})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+
+	for _, cand := range candidates {
+		if cand.tag == "*" {
+			return fallback
+		}
+		if c.Has(cand.tag) {
+			return cand.tag
+		}
+		if primary, _, ok := strings.Cut(cand.tag, "-"); ok && c.Has(primary) {
+			return primary
+		}
+	}
+	return fallback
+}
+
+// localeWriter carries the negotiated locale down to httperr
+type localeWriter struct {
+	http.ResponseWriter
+	locale string
+}
+
+// Locale implements httperr.Localizer
+func (w *localeWriter) Locale() string {
+	return w.locale
+}
+
+// Flush forwards to the underlying writer when it supports flushing
+func (w *localeWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *localeWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Middleware negotiates each request's locale so error responses are written in it
+func (c *Catalogs) Middleware(defaultLocale string) func(http.Handler) http.Handler {
+	defaultLocale = normalize(defaultLocale)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			locale := c.Negotiate(r.Header.Get("Accept-Language"), defaultLocale)
+			next.ServeHTTP(&localeWriter{ResponseWriter: w, locale: locale}, r)
+		})
+	}
+}
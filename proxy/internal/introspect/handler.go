@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/grove/generic-proxy/internal/config"
@@ -16,6 +17,27 @@ type Handler struct {
 	resolvedConfig  *config.ResolvedConfig
 	proxyConfigPath string
 	mode            string
+
+	// Apply resolves a proxy configuration against the current metadata and installs the result
+	// in the proxy handler; nil in legacy mode
+	Apply func(*config.ProxyConfig) (*config.ResolvedConfig, error)
+
+	// DeprecationGrace is added to sunset dates when reporting whether a deprecation is enforced
+	DeprecationGrace time.Duration
+
+	// History records every applied proxy.yaml for GET /__proxy/config/history and rollbacks; nil in legacy mode
+	History *config.ConfigHistory
+
+	// ReloadConcurrency decides what a refresh or rollback does while another one runs (reject, queue)
+	ReloadConcurrency string
+
+	// Features are the feature flags the proxy started with, reported by /__proxy/status
+	Features config.Features
+
+	mu         sync.RWMutex // guards resolvedConfig, refreshing and fileHash
+	refreshing *refreshCall // forced refresh in flight, joined by concurrent callers
+	fileHash   string       // proxy.yaml content last read from disk
+	reloadMu   sync.Mutex   // held for a whole refresh (metadata and configuration) or rollback
 }
 
 // NewHandler creates a new introspection handler
@@ -40,6 +62,25 @@ type SchemaResponse struct {
 	Tables         map[string]TableInfo `json:"tables"`
 	MetaCacheReady bool                 `json:"metacache_ready"`
 	LastRefresh    string               `json:"last_refresh,omitempty"`
+
+	// ListOptions are the query parameters and headers the proxy itself handles on record lists
+	ListOptions []ListOption `json:"list_options"`
+}
+
+// ListOption documents one proxy-handled query parameter or header of record list requests
+type ListOption struct {
+	Name        string `json:"name"`
+	In          string `json:"in"` // query or header
+	Description string `json:"description"`
+}
+
+// listOptions is what ServeSchema reports under list_options
+var listOptions = []ListOption{
+	{Name: proxy.PaginateParam, In: "query", Description: "false returns the single upstream page as-is, including its next value, instead of merging all pages"},
+	{Name: proxy.PaginateHeader, In: "header", Description: "off has the same effect as " + proxy.PaginateParam + "=false"},
+	{Name: "cursor", In: "query", Description: "opaque cursor from a previous response's cursor.next, replacing limit/offset"},
+	{Name: "include", In: "query", Description: "comment_count adds each record's number of proxy comments"},
+	{Name: "include_archived", In: "query", Description: "true (admins only) includes records hidden by the table's archive_field"},
 }
 
 // TableInfo contains resolved table information
@@ -49,6 +90,33 @@ type TableInfo struct {
 	Operations  []string            `json:"operations,omitempty"`
 	Fields      map[string]string   `json:"fields,omitempty"`
 	Links       map[string]LinkInfo `json:"links,omitempty"`
+
+	// RoleOperations is the per-role operation matrix (role -> operations) of tables configured
+	// per role; operations is then omitted
+	RoleOperations map[string][]string `json:"role_operations,omitempty"`
+
+	// SelectOptions lists allowed options for SingleSelect/MultiSelect fields, keyed by field title
+	SelectOptions map[string]proxy.SelectField `json:"select_options,omitempty"`
+
+	// ComputedFields are formula, rollup and other upstream-computed columns, keyed by field title.
+	// They are returned on reads but stripped from (or rejected in) write bodies.
+	ComputedFields map[string]ComputedFieldInfo `json:"computed_fields,omitempty"`
+
+	// DeprecatedFields are fields scheduled for removal, keyed by field title
+	DeprecatedFields map[string]DeprecatedFieldInfo `json:"deprecated_fields,omitempty"`
+}
+
+// DeprecatedFieldInfo describes a deprecated field; enforced fields are stripped from reads and rejected in writes
+type DeprecatedFieldInfo struct {
+	Name     string `json:"name"` // as configured (alias or title)
+	Sunset   string `json:"sunset,omitempty"`
+	Enforced bool   `json:"enforced"`
+}
+
+// ComputedFieldInfo describes a read-only computed column
+type ComputedFieldInfo struct {
+	Type             string `json:"type"`
+	ComputedUpstream bool   `json:"computed_upstream"`
 }
 
 // LinkInfo contains resolved link information
@@ -64,6 +132,18 @@ type StatusResponse struct {
 	TablesResolved int    `json:"tables_resolved"`
 	LastRefresh    string `json:"last_refresh,omitempty"`
 	Mode           string `json:"mode"`
+	ConfigHash     string `json:"config_hash,omitempty"` // active proxy.yaml, see /__proxy/config/history
+
+	Features map[string]bool `json:"features"` // every feature flag and whether it is on
+
+	// Set when the last metadata refresh failed
+	MetaCacheError         string `json:"metacache_error,omitempty"`
+	MetaCacheErrorKind     string `json:"metacache_error_kind,omitempty"`
+	MetaCacheErrorStatus   int    `json:"metacache_error_status,omitempty"`
+	MetaCacheErrorEndpoint string `json:"metacache_error_endpoint,omitempty"`
+
+	// Timing and per-table failures of the last completed refresh
+	MetaCacheRefresh *proxy.RefreshStats `json:"metacache_refresh,omitempty"`
 }
 
 // ServeSchema handles GET /__proxy/schema
@@ -80,6 +160,7 @@ func (h *Handler) ServeSchema(w http.ResponseWriter, r *http.Request) {
 		ConfigPath:     h.proxyConfigPath,
 		Tables:         make(map[string]TableInfo),
 		MetaCacheReady: h.metaCache != nil && h.metaCache.IsReady(),
+		ListOptions:    listOptions,
 	}
 
 	if h.metaCache != nil && h.metaCache.IsReady() {
@@ -90,8 +171,8 @@ func (h *Handler) ServeSchema(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// If schema-driven mode, include resolved configuration
-	if h.resolvedConfig != nil {
-		for tableKey, table := range h.resolvedConfig.Tables {
+	if resolvedConfig := h.config(); resolvedConfig != nil {
+		for tableKey, table := range resolvedConfig.Tables {
 			tableInfo := TableInfo{
 				LogicalName: table.Name,
 				TableID:     table.TableID,
@@ -99,6 +180,13 @@ func (h *Handler) ServeSchema(w http.ResponseWriter, r *http.Request) {
 				Fields:      make(map[string]string),
 				Links:       make(map[string]LinkInfo),
 			}
+			if table.RoleOperations != nil {
+				// Every known role is listed, so roles without operations show up as []
+				tableInfo.RoleOperations = make(map[string][]string, len(config.KnownRoles))
+				for _, role := range config.KnownRoles {
+					tableInfo.RoleOperations[role] = append([]string{}, table.OperationsFor(role)...)
+				}
+			}
 
 			// Add field mappings
 			for fieldAlias, fieldID := range table.Fields {
@@ -113,6 +201,27 @@ func (h *Handler) ServeSchema(w http.ResponseWriter, r *http.Request) {
 				}
 			}
 
+			if h.metaCache != nil {
+				tableInfo.SelectOptions = h.metaCache.GetSelectFields(table.TableID)
+				for title, fieldType := range h.metaCache.GetComputedFields(table.TableID) {
+					if tableInfo.ComputedFields == nil {
+						tableInfo.ComputedFields = make(map[string]ComputedFieldInfo)
+					}
+					tableInfo.ComputedFields[title] = ComputedFieldInfo{Type: fieldType, ComputedUpstream: true}
+				}
+			}
+
+			for title, deprecation := range table.Deprecations {
+				if tableInfo.DeprecatedFields == nil {
+					tableInfo.DeprecatedFields = make(map[string]DeprecatedFieldInfo)
+				}
+				info := DeprecatedFieldInfo{Name: deprecation.Name, Enforced: proxy.DeprecationEnforced(deprecation, h.DeprecationGrace, time.Now())}
+				if !deprecation.Sunset.IsZero() {
+					info.Sunset = deprecation.Sunset.Format(config.SunsetDateLayout)
+				}
+				tableInfo.DeprecatedFields[title] = info
+			}
+
 			response.Tables[tableKey] = tableInfo
 		}
 	}
@@ -127,6 +236,54 @@ func (h *Handler) ServeSchema(w http.ResponseWriter, r *http.Request) {
 	log.Printf("[INTROSPECT] Schema introspection completed: mode=%s, tables=%d", h.mode, len(response.Tables))
 }
 
+// ReadyResponse represents the readiness endpoint response
+type ReadyResponse struct {
+	Ready        bool   `json:"ready"`
+	Reason       string `json:"reason,omitempty"`
+	TablesLoaded int    `json:"tables_loaded"`
+	MinTables    int    `json:"min_tables"`
+}
+
+// ServeReady handles GET /readyz
+// Returns 503 until MetaCache has loaded at least the configured minimum number of tables.
+func (h *Handler) ServeReady(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	response := ReadyResponse{}
+	status := http.StatusOK
+
+	switch {
+	case h.metaCache == nil:
+		response.Reason = "metacache disabled (NOCODB_BASE_ID not set)"
+		status = http.StatusServiceUnavailable
+	case !h.metaCache.IsLoaded():
+		response.Reason = "metacache not loaded"
+		if metaErr := h.metaCache.LastError(); metaErr != nil {
+			response.Reason += ": " + metaErr.Message
+		}
+		status = http.StatusServiceUnavailable
+	case !h.metaCache.IsReady():
+		response.Reason = "metacache loaded fewer tables than expected"
+		status = http.StatusServiceUnavailable
+	default:
+		response.Ready = true
+	}
+
+	if h.metaCache != nil {
+		response.TablesLoaded = h.metaCache.GetLoadedTableCount()
+		response.MinTables = h.metaCache.GetMinTables()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("[INTROSPECT ERROR] Failed to encode ready response: %v", err)
+	}
+}
+
 // ServeStatus handles GET /__proxy/status
 func (h *Handler) ServeStatus(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -134,16 +291,19 @@ func (h *Handler) ServeStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	resolvedConfig := h.config()
 	response := StatusResponse{
 		MetaCacheReady: h.metaCache != nil && h.metaCache.IsReady(),
-		SchemaResolved: h.resolvedConfig != nil,
+		SchemaResolved: resolvedConfig != nil,
 		TablesResolved: 0,
 		Mode:           h.mode,
 	}
 
-	if h.resolvedConfig != nil {
-		response.TablesResolved = len(h.resolvedConfig.Tables)
+	if resolvedConfig != nil {
+		response.TablesResolved = len(resolvedConfig.Tables)
 	}
+	response.ConfigHash = h.activeHash()
+	response.Features = h.Features.All()
 
 	if h.metaCache != nil && h.metaCache.IsReady() {
 		lastRefresh := h.metaCache.GetLastRefreshTime()
@@ -152,6 +312,16 @@ func (h *Handler) ServeStatus(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if h.metaCache != nil {
+		if metaErr := h.metaCache.LastError(); metaErr != nil {
+			response.MetaCacheError = metaErr.Message
+			response.MetaCacheErrorKind = string(metaErr.Kind)
+			response.MetaCacheErrorStatus = metaErr.StatusCode
+			response.MetaCacheErrorEndpoint = metaErr.Endpoint
+		}
+		response.MetaCacheRefresh = h.metaCache.LastRefreshStats()
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		log.Printf("[INTROSPECT ERROR] Failed to encode status response: %v", err)
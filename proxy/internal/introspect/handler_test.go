@@ -0,0 +1,143 @@
+package introspect
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/proxy"
+)
+
+// newMetaServer is a NocoDB meta API whose base lists tables (JSON array of {"id","title"})
+func newMetaServer(t *testing.T, tables string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/meta/bases/base/tables") {
+			io.WriteString(w, `{"list":`+tables+`}`)
+			return
+		}
+		io.WriteString(w, `{"id":"t1","title":"Quotes","fields":[]}`)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func serveReady(h *Handler) (int, ReadyResponse) {
+	rec := httptest.NewRecorder()
+	h.ServeReady(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	var response ReadyResponse
+	json.Unmarshal(rec.Body.Bytes(), &response)
+	return rec.Code, response
+}
+
+func TestReadyzTableCountGate(t *testing.T) {
+	tests := []struct {
+		name       string
+		tables     string
+		minTables  int
+		wantStatus int
+	}{
+		{"zero tables", `[]`, 1, http.StatusServiceUnavailable},
+		{"fewer than the minimum", `[{"id":"t1","title":"Quotes"}]`, 2, http.StatusServiceUnavailable},
+		{"exactly the minimum", `[{"id":"t1","title":"Quotes"},{"id":"t2","title":"Orders"}]`, 2, http.StatusOK},
+		{"minimum of zero", `[]`, 0, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newMetaServer(t, tt.tables)
+			metaCache := proxy.NewMetaCache(server.URL+"/api/v2/", "base", "test-token", server.Client())
+			metaCache.SetMinTables(tt.minTables)
+			h := NewHandler(metaCache, nil, "")
+
+			if status, response := serveReady(h); status != http.StatusServiceUnavailable || response.Reason != "metacache not loaded" {
+				t.Errorf("before the first load: status = %d, reason %q; want 503 not loaded", status, response.Reason)
+			}
+			if err := metaCache.Refresh(); err != nil {
+				t.Fatalf("refresh: %v", err)
+			}
+
+			status, response := serveReady(h)
+			if status != tt.wantStatus || response.Ready != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("status = %d, ready %v; want %d", status, response.Ready, tt.wantStatus)
+			}
+			if metaCache.IsReady() != response.Ready {
+				t.Errorf("IsReady = %v, readyz says %v", metaCache.IsReady(), response.Ready)
+			}
+			if !metaCache.IsLoaded() {
+				t.Error("IsLoaded = false after a successful refresh")
+			}
+			if response.MinTables != tt.minTables {
+				t.Errorf("min_tables = %d, want %d", response.MinTables, tt.minTables)
+			}
+		})
+	}
+}
+
+func TestReadyzWithoutMetaCache(t *testing.T) {
+	if status, response := serveReady(NewHandler(nil, nil, "")); status != http.StatusServiceUnavailable || response.Ready {
+		t.Errorf("status = %d, ready %v; want 503", status, response.Ready)
+	}
+}
+
+func TestStatusSurfacesMetaPermissionError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, `{"msg":"Forbidden"}`)
+	}))
+	t.Cleanup(server.Close)
+	metaCache := proxy.NewMetaCache(server.URL+"/api/v2/", "base", "test-token", server.Client())
+	metaCache.Refresh()
+
+	rec := httptest.NewRecorder()
+	NewHandler(metaCache, nil, "").ServeStatus(rec, httptest.NewRequest(http.MethodGet, "/__proxy/status", nil))
+	var response StatusResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decode %s: %v", rec.Body, err)
+	}
+	if response.MetaCacheError != "token lacks meta permissions" || response.MetaCacheErrorKind != string(proxy.MetaErrorPermission) ||
+		response.MetaCacheErrorStatus != http.StatusForbidden || response.MetaCacheErrorEndpoint != server.URL+"/api/v2/meta/bases/base/tables" {
+		t.Errorf("status = %s, want the permission error with upstream status and endpoint", rec.Body)
+	}
+}
+
+func TestStatusListsFeatures(t *testing.T) {
+	h := NewHandler(nil, nil, "")
+	h.Features = config.Features{config.FeatureStreaming: false}
+
+	rec := httptest.NewRecorder()
+	h.ServeStatus(rec, httptest.NewRequest(http.MethodGet, "/__proxy/status", nil))
+	var response StatusResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decode %s: %v", rec.Body, err)
+	}
+	want := map[string]bool{config.FeatureCaching: true, config.FeatureConcurrency: true, config.FeatureStreaming: false}
+	if len(response.Features) != len(want) {
+		t.Fatalf("features = %v, want %v", response.Features, want)
+	}
+	for name, on := range want {
+		if response.Features[name] != on {
+			t.Errorf("feature %s = %v, want %v", name, response.Features[name], on)
+		}
+	}
+}
+
+func TestSchemaListsProxyListOptions(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewHandler(nil, nil, "").ServeSchema(rec, httptest.NewRequest(http.MethodGet, "/__proxy/schema", nil))
+	var response SchemaResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("schema %s: %v", rec.Body, err)
+	}
+	documented := make(map[string]string)
+	for _, option := range response.ListOptions {
+		documented[option.Name] = option.In
+	}
+	if documented[proxy.PaginateParam] != "query" || documented[proxy.PaginateHeader] != "header" {
+		t.Errorf("list_options = %+v, want the pagination opt-out param and header", response.ListOptions)
+	}
+}
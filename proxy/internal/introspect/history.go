@@ -0,0 +1,193 @@
+package introspect
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/httperr"
+	"github.com/grove/generic-proxy/internal/middleware"
+)
+
+// ConfigHistoryResponse is returned by GET /__proxy/config/history
+type ConfigHistoryResponse struct {
+	ActiveHash string               `json:"active_hash"`
+	Snapshots  []ConfigHistoryEntry `json:"snapshots"`
+}
+
+// ConfigHistoryEntry is one applied proxy.yaml
+type ConfigHistoryEntry struct {
+	Hash      string `json:"hash"`
+	AppliedAt string `json:"applied_at"`
+	Active    bool   `json:"active"`
+}
+
+// RollbackResponse is returned by POST /__proxy/config/rollback
+type RollbackResponse struct {
+	From           string `json:"from"`
+	To             string `json:"to"`
+	AppliedAt      string `json:"applied_at"`
+	TablesResolved int    `json:"tables_resolved"`
+}
+
+// shortHash abbreviates a config hash for logs
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
+
+// activeHash returns the hash of the configuration in use, or "" without a history
+func (h *Handler) activeHash() string {
+	if h.History == nil {
+		return ""
+	}
+	active, _ := h.History.Active()
+	return active.Hash
+}
+
+// refreshSource picks the YAML a forced refresh re-resolves: proxy.yaml when it changed on disk
+// since it was last read, otherwise the active configuration, so a rollback isn't undone by the
+// next refresh. fromFile reports that the file was picked.
+func (h *Handler) refreshSource() (source []byte, fromFile bool, err error) {
+	source, readErr := os.ReadFile(h.proxyConfigPath)
+	if readErr != nil {
+		log.Printf("[INTROSPECT WARN] Could not re-read %s: %v", h.proxyConfigPath, readErr)
+	}
+
+	h.mu.RLock()
+	fileHash := h.fileHash
+	h.mu.RUnlock()
+
+	if readErr == nil && (h.History == nil || config.HashConfig(source) != fileHash) {
+		return source, true, nil
+	}
+	if h.History != nil {
+		if active, ok := h.History.Active(); ok {
+			return active.Source(), false, nil
+		}
+	}
+	if readErr != nil {
+		return nil, false, fmt.Errorf("proxy.yaml could not be read: %w", readErr)
+	}
+	return source, true, nil
+}
+
+// install parses and resolves a proxy.yaml, swaps it in through Apply and records it in History.
+// On any error the previous configuration stays active. Callers hold reloadMu.
+func (h *Handler) install(source []byte) (*config.ResolvedConfig, config.ConfigSnapshot, error) {
+	proxyConfig, err := config.ParseProxyConfig(source)
+	if err != nil {
+		return nil, config.ConfigSnapshot{}, err
+	}
+
+	resolvedConfig, err := h.Apply(proxyConfig)
+	if err != nil {
+		return nil, config.ConfigSnapshot{}, err
+	}
+	h.mu.Lock()
+	h.resolvedConfig = resolvedConfig
+	h.mu.Unlock()
+
+	var snapshot config.ConfigSnapshot
+	if h.History != nil {
+		snapshot = h.History.Record(source)
+	}
+	return resolvedConfig, snapshot, nil
+}
+
+// RecordStartupConfig records the proxy.yaml resolved at startup as the active configuration
+func (h *Handler) RecordStartupConfig(source []byte) {
+	h.mu.Lock()
+	h.fileHash = config.HashConfig(source)
+	h.mu.Unlock()
+	if h.History != nil {
+		snapshot := h.History.Record(source)
+		log.Printf("[INTROSPECT] Active proxy configuration: %s", shortHash(snapshot.Hash))
+	}
+}
+
+// ServeConfigHistory handles GET /__proxy/config/history
+func (h *Handler) ServeConfigHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.History == nil {
+		http.Error(w, "config history unavailable in legacy mode", http.StatusServiceUnavailable)
+		return
+	}
+
+	response := ConfigHistoryResponse{ActiveHash: h.activeHash(), Snapshots: []ConfigHistoryEntry{}}
+	for _, snapshot := range h.History.Snapshots() {
+		response.Snapshots = append(response.Snapshots, ConfigHistoryEntry{
+			Hash:      snapshot.Hash,
+			AppliedAt: snapshot.AppliedAt.Format(time.RFC3339),
+			Active:    snapshot.Hash == response.ActiveHash,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("[INTROSPECT ERROR] Failed to encode config history: %v", err)
+	}
+}
+
+// ServeConfigRollback handles POST /__proxy/config/rollback?to=<hash> (admin only).
+// The snapshot is re-resolved against current metadata and swapped in like a refresh would;
+// proxy.yaml on disk is left alone.
+func (h *Handler) ServeConfigRollback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	role, _ := r.Context().Value(middleware.RoleKey).(string)
+	if role != "admin" {
+		httperr.WriteError(w, httperr.AdminRequired, "admin role required")
+		return
+	}
+
+	if h.History == nil || h.Apply == nil {
+		http.Error(w, "config history unavailable in legacy mode", http.StatusServiceUnavailable)
+		return
+	}
+
+	target, err := h.History.FindSnapshot(r.URL.Query().Get("to"))
+	if err != nil {
+		httperr.WriteError(w, httperr.ConfigSnapshotNotFound, err.Error())
+		return
+	}
+
+	if !h.beginReload() {
+		log.Printf("[INTROSPECT ERROR] Rollback to %s rejected: %v", shortHash(target.Hash), errReloadInProgress)
+		httperr.WriteError(w, httperr.ReloadInProgress, errReloadInProgress.Error())
+		return
+	}
+	defer h.reloadMu.Unlock()
+
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	from := h.activeHash()
+	resolvedConfig, snapshot, err := h.install(target.Source())
+	if err != nil {
+		log.Printf("[INTROSPECT ERROR] Rollback to %s failed: %v", shortHash(target.Hash), err)
+		httperr.WriteError(w, httperr.SchemaReloadFailed, fmt.Sprintf("config %s no longer resolves (previous configuration kept): %v", shortHash(target.Hash), err))
+		return
+	}
+	log.Printf("[AUDIT] Admin %s rolled back proxy configuration %s -> %s", userID, shortHash(from), shortHash(snapshot.Hash))
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(RollbackResponse{
+		From:           from,
+		To:             snapshot.Hash,
+		AppliedAt:      snapshot.AppliedAt.Format(time.RFC3339),
+		TablesResolved: len(resolvedConfig.Tables),
+	}); err != nil {
+		log.Printf("[INTROSPECT ERROR] Failed to encode rollback response: %v", err)
+	}
+}
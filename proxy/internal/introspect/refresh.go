@@ -0,0 +1,184 @@
+package introspect
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/httperr"
+	"github.com/grove/generic-proxy/internal/middleware"
+)
+
+// RefreshResponse is returned by POST /__proxy/metacache/refresh
+type RefreshResponse struct {
+	LastRefresh    string `json:"last_refresh"`
+	Mode           string `json:"mode"`
+	TablesResolved int    `json:"tables_resolved"`
+	FieldsResolved int    `json:"fields_resolved"`
+	Coalesced      bool   `json:"coalesced,omitempty"` // joined a refresh another request had started
+}
+
+// Reload concurrency modes (RELOAD_CONCURRENCY): what a refresh or rollback does while another one runs
+const (
+	ReloadsReject = "reject" // answer 409 reload_in_progress (default)
+	ReloadsQueue  = "queue"  // wait for it; concurrent refreshes share one result
+)
+
+// errReloadInProgress rejects a refresh or rollback unless in ReloadsQueue mode
+var errReloadInProgress = errors.New("a configuration reload is already in progress")
+
+// refreshCall is a forced refresh in flight; requests arriving meanwhile wait for its result
+type refreshCall struct {
+	done     chan struct{}
+	response RefreshResponse
+	err      error
+}
+
+// config returns the current resolved configuration (nil in legacy mode)
+func (h *Handler) config() *config.ResolvedConfig {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.resolvedConfig
+}
+
+// ServeRefresh handles POST /__proxy/metacache/refresh (admin only).
+// It reloads NocoDB metadata synchronously and, in schema-driven mode, re-resolves proxy.yaml
+// so renamed tables and fields map correctly without a restart. An edited proxy.yaml is picked up too.
+func (h *Handler) ServeRefresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	role, _ := r.Context().Value(middleware.RoleKey).(string)
+	if role != "admin" {
+		httperr.WriteError(w, httperr.AdminRequired, "admin role required")
+		return
+	}
+
+	if h.metaCache == nil {
+		http.Error(w, "metacache disabled (NOCODB_BASE_ID not set)", http.StatusServiceUnavailable)
+		return
+	}
+
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	log.Printf("[INTROSPECT] Forced metadata refresh requested by user %s", userID)
+
+	response, err := h.refresh()
+	if errors.Is(err, errReloadInProgress) {
+		log.Printf("[INTROSPECT ERROR] Forced refresh rejected: %v", err)
+		httperr.WriteError(w, httperr.ReloadInProgress, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("[INTROSPECT ERROR] Forced refresh failed: %v", err)
+		httperr.WriteError(w, httperr.SchemaReloadFailed, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("[INTROSPECT ERROR] Failed to encode refresh response: %v", err)
+	}
+}
+
+// queues reports whether a reload waits for the one in progress instead of being rejected
+func (h *Handler) queues() bool {
+	return h.ReloadConcurrency == ReloadsQueue
+}
+
+// beginReload takes reloadMu, reporting false when a reload is in progress or, in ReloadsQueue
+// mode, waiting for it. A true result must be followed by reloadMu.Unlock.
+func (h *Handler) beginReload() bool {
+	if !h.queues() {
+		return h.reloadMu.TryLock()
+	}
+	h.reloadMu.Lock()
+	return true
+}
+
+// refresh runs one forced refresh and fails with errReloadInProgress while a refresh or rollback
+// runs. In ReloadsQueue mode it waits for the one already in flight instead and shares its result.
+func (h *Handler) refresh() (RefreshResponse, error) {
+	if !h.queues() {
+		if !h.beginReload() {
+			return RefreshResponse{}, errReloadInProgress
+		}
+		defer h.reloadMu.Unlock()
+		return h.reload()
+	}
+
+	h.mu.Lock()
+	if call := h.refreshing; call != nil {
+		h.mu.Unlock()
+		<-call.done
+		response := call.response
+		response.Coalesced = true
+		return response, call.err
+	}
+	call := &refreshCall{done: make(chan struct{})}
+	h.refreshing = call
+	h.mu.Unlock()
+
+	h.reloadMu.Lock()
+	call.response, call.err = h.reload()
+	h.reloadMu.Unlock()
+
+	h.mu.Lock()
+	h.refreshing = nil
+	h.mu.Unlock()
+	close(call.done)
+	return call.response, call.err
+}
+
+// reload refreshes MetaCache and re-resolves the schema. When resolving fails the previous
+// configuration stays active, so a half-renamed schema can't take the proxy down. A background
+// refresh of MetaCache in progress counts as a reload in progress too.
+func (h *Handler) reload() (RefreshResponse, error) {
+	if h.queues() {
+		if err := h.metaCache.Refresh(); err != nil {
+			return RefreshResponse{}, fmt.Errorf("metadata refresh failed: %w", err)
+		}
+	} else if started, err := h.metaCache.TryRefresh(); !started {
+		return RefreshResponse{}, errReloadInProgress
+	} else if err != nil {
+		return RefreshResponse{}, fmt.Errorf("metadata refresh failed: %w", err)
+	}
+
+	response := RefreshResponse{
+		LastRefresh:    h.metaCache.GetLastRefreshTime().Format(time.RFC3339),
+		Mode:           h.mode,
+		TablesResolved: h.metaCache.GetTableCount(),
+		FieldsResolved: h.metaCache.GetFieldCount(),
+	}
+
+	if h.Apply == nil || h.config() == nil {
+		return response, nil
+	}
+
+	source, fromFile, err := h.refreshSource()
+	if err != nil {
+		return RefreshResponse{}, fmt.Errorf("metadata refreshed but %w", err)
+	}
+	resolvedConfig, _, err := h.install(source)
+	if err != nil {
+		return RefreshResponse{}, fmt.Errorf("metadata refreshed but proxy.yaml no longer resolves (previous configuration kept): %w", err)
+	}
+	if fromFile {
+		h.mu.Lock()
+		h.fileHash = config.HashConfig(source)
+		h.mu.Unlock()
+	}
+
+	response.TablesResolved = len(resolvedConfig.Tables)
+	response.FieldsResolved = 0
+	for _, table := range resolvedConfig.Tables {
+		response.FieldsResolved += len(table.Fields) + len(table.Links)
+	}
+	log.Printf("[INTROSPECT] Re-resolved proxy configuration: %d tables, %d fields", response.TablesResolved, response.FieldsResolved)
+	return response, nil
+}
@@ -0,0 +1,135 @@
+package introspect
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/middleware"
+	"github.com/grove/generic-proxy/internal/proxy"
+)
+
+// newSlowMetaServer is a NocoDB meta API whose table list takes delay; tableLists counts those requests
+func newSlowMetaServer(t *testing.T, delay time.Duration, tableLists *atomic.Int32) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/meta/bases/base/tables") {
+			tableLists.Add(1)
+			time.Sleep(delay)
+			io.WriteString(w, `{"list":[{"id":"t1","title":"Quotes"}]}`)
+			return
+		}
+		io.WriteString(w, `{"id":"t1","title":"Quotes","fields":[]}`)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func serveRefresh(h *Handler) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/__proxy/metacache/refresh", nil)
+	ctx := context.WithValue(req.Context(), middleware.UserIDKey, "1")
+	ctx = context.WithValue(ctx, middleware.RoleKey, "admin")
+	rec := httptest.NewRecorder()
+	h.ServeRefresh(rec, req.WithContext(ctx))
+	return rec
+}
+
+// concurrentRefreshes sends n forced refreshes at once and returns their responses
+func concurrentRefreshes(h *Handler, n int) []*httptest.ResponseRecorder {
+	responses := make([]*httptest.ResponseRecorder, n)
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := range responses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			responses[i] = serveRefresh(h)
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	return responses
+}
+
+// Run with -race: of concurrent forced refreshes only one proceeds, the others get 409
+func TestConcurrentRefreshesAreRejected(t *testing.T) {
+	var tableLists atomic.Int32
+	server := newSlowMetaServer(t, 100*time.Millisecond, &tableLists)
+	h := NewHandler(proxy.NewMetaCache(server.URL+"/api/v2/", "base", "test-token", server.Client()), nil, "")
+
+	ok, conflicts := 0, 0
+	for _, rec := range concurrentRefreshes(h, 8) {
+		switch rec.Code {
+		case http.StatusOK:
+			ok++
+		case http.StatusConflict:
+			conflicts++
+			if !strings.Contains(rec.Body.String(), "reload_in_progress") {
+				t.Errorf("409 body = %s", rec.Body)
+			}
+		default:
+			t.Errorf("unexpected status %d: %s", rec.Code, rec.Body)
+		}
+	}
+	if ok != 1 || conflicts != 7 {
+		t.Errorf("%d refreshes proceeded and %d were rejected, want 1 and 7", ok, conflicts)
+	}
+	if n := tableLists.Load(); n != 1 {
+		t.Errorf("NocoDB got %d table list requests, want 1", n)
+	}
+}
+
+// A forced refresh is rejected while a background refresh of the same MetaCache runs, too
+func TestRefreshRejectedDuringBackgroundRefresh(t *testing.T) {
+	var tableLists atomic.Int32
+	server := newSlowMetaServer(t, 100*time.Millisecond, &tableLists)
+	metaCache := proxy.NewMetaCache(server.URL+"/api/v2/", "base", "test-token", server.Client())
+	h := NewHandler(metaCache, nil, "")
+
+	done := make(chan struct{})
+	go func() {
+		metaCache.Refresh()
+		close(done)
+	}()
+	for tableLists.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if rec := serveRefresh(h); rec.Code != http.StatusConflict {
+		t.Errorf("status = %d, want 409 while the background refresh runs", rec.Code)
+	}
+	<-done
+	if rec := serveRefresh(h); rec.Code != http.StatusOK {
+		t.Errorf("status = %d after the background refresh, want 200", rec.Code)
+	}
+}
+
+func TestConcurrentRefreshesQueue(t *testing.T) {
+	var tableLists atomic.Int32
+	server := newSlowMetaServer(t, 50*time.Millisecond, &tableLists)
+	h := NewHandler(proxy.NewMetaCache(server.URL+"/api/v2/", "base", "test-token", server.Client()), nil, "")
+	h.ReloadConcurrency = ReloadsQueue
+
+	coalesced := 0
+	for _, rec := range concurrentRefreshes(h, 8) {
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+		}
+		var response RefreshResponse
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		if response.Coalesced {
+			coalesced++
+		}
+	}
+	if coalesced == 0 || int(tableLists.Load())+coalesced != 8 {
+		t.Errorf("%d coalesced responses and %d refreshes, want every caller served by a shared or own refresh", coalesced, tableLists.Load())
+	}
+}
@@ -0,0 +1,231 @@
+// Package jsonpath implements the subset of JSONPath used for response filters:
+// the root $, child names (.name or ['name']), wildcards (.* or [*]), array indexes ([0], [-1])
+// and unions inside brackets ([0,1] or ['a','b']). Filters and recursive descent are not supported.
+package jsonpath
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Path is a parsed JSONPath expression
+type Path struct {
+	expr  string
+	steps []step
+}
+
+// step matches any of its selectors
+type step []selector
+
+type selector struct {
+	wildcard bool
+	name     string
+	index    *int
+}
+
+// String returns the expression the path was parsed from
+func (p Path) String() string {
+	return p.expr
+}
+
+// Parse compiles an expression such as "$.records[*].fields.Title"
+func Parse(expr string) (Path, error) {
+	path := Path{expr: expr}
+	rest := strings.TrimSpace(expr)
+	if !strings.HasPrefix(rest, "$") {
+		return Path{}, fmt.Errorf("jsonpath %q: must start with $", expr)
+	}
+	rest = rest[1:]
+
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			return Path{}, fmt.Errorf("jsonpath %q: recursive descent (..) is not supported", expr)
+		case strings.HasPrefix(rest, ".*"):
+			path.steps = append(path.steps, step{{wildcard: true}})
+			rest = rest[2:]
+		case strings.HasPrefix(rest, "."):
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			name := rest[1 : end+1]
+			if name == "" {
+				return Path{}, fmt.Errorf("jsonpath %q: empty name after '.'", expr)
+			}
+			path.steps = append(path.steps, step{{name: name}})
+			rest = rest[end+1:]
+		case strings.HasPrefix(rest, "["):
+			s, remaining, err := parseBracket(rest)
+			if err != nil {
+				return Path{}, fmt.Errorf("jsonpath %q: %v", expr, err)
+			}
+			path.steps = append(path.steps, s)
+			rest = remaining
+		default:
+			return Path{}, fmt.Errorf("jsonpath %q: unexpected %q", expr, rest)
+		}
+	}
+	return path, nil
+}
+
+// parseBracket parses "[...]" at the start of s and returns the step and what follows it
+func parseBracket(s string) (step, string, error) {
+	var result step
+	rest := s[1:]
+	for {
+		rest = strings.TrimLeft(rest, " ")
+		var sel selector
+		switch {
+		case strings.HasPrefix(rest, "*"):
+			sel.wildcard = true
+			rest = rest[1:]
+		case strings.HasPrefix(rest, "'") || strings.HasPrefix(rest, `"`):
+			quote := rest[0]
+			end := strings.IndexByte(rest[1:], quote)
+			if end < 0 {
+				return nil, "", fmt.Errorf("unterminated string in brackets")
+			}
+			sel.name = rest[1 : end+1]
+			rest = rest[end+2:]
+		default:
+			end := strings.IndexAny(rest, ",]")
+			if end < 0 {
+				return nil, "", fmt.Errorf("missing ']'")
+			}
+			n, err := strconv.Atoi(strings.TrimSpace(rest[:end]))
+			if err != nil {
+				return nil, "", fmt.Errorf("invalid index %q", strings.TrimSpace(rest[:end]))
+			}
+			sel.index = &n
+			rest = rest[end:]
+		}
+		result = append(result, sel)
+
+		rest = strings.TrimLeft(rest, " ")
+		switch {
+		case strings.HasPrefix(rest, ","):
+			rest = rest[1:]
+		case strings.HasPrefix(rest, "]"):
+			return result, rest[1:], nil
+		default:
+			return nil, "", fmt.Errorf("missing ']'")
+		}
+	}
+}
+
+func (s step) matchesKey(key string) bool {
+	for _, sel := range s {
+		if sel.wildcard || (sel.index == nil && sel.name == key) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s step) matchesAll() bool {
+	for _, sel := range s {
+		if sel.wildcard {
+			return true
+		}
+	}
+	return false
+}
+
+// emptyLike returns an empty container of the same kind as value
+func emptyLike(value interface{}) (interface{}, bool) {
+	switch value.(type) {
+	case map[string]interface{}:
+		return map[string]interface{}{}, true
+	case []interface{}:
+		return []interface{}{}, true
+	}
+	return nil, false
+}
+
+func (s step) matchesIndex(i, length int) bool {
+	for _, sel := range s {
+		if sel.wildcard {
+			return true
+		}
+		if sel.index != nil {
+			index := *sel.index
+			if index < 0 {
+				index += length
+			}
+			if index == i {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Prune returns the parts of a decoded JSON document matched by any of the paths, keeping their
+// position in the document: objects keep only matched keys and arrays only matched elements, in
+// order. An array element selected by the path but without matches below it is kept as an empty
+// object/array, so "$.records[*].fields.Title" returns one entry per record. ok is false when
+// nothing matched.
+func Prune(doc interface{}, paths []Path) (interface{}, bool) {
+	remaining := make([][]step, len(paths))
+	for i, path := range paths {
+		remaining[i] = path.steps
+	}
+	return prune(doc, remaining)
+}
+
+func prune(node interface{}, paths [][]step) (interface{}, bool) {
+	for _, steps := range paths {
+		if len(steps) == 0 {
+			return node, true // a path ends here: keep the whole subtree
+		}
+	}
+
+	switch value := node.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{})
+		for key, child := range value {
+			var next [][]step
+			for _, steps := range paths {
+				if steps[0].matchesKey(key) {
+					next = append(next, steps[1:])
+				}
+			}
+			if len(next) == 0 {
+				continue
+			}
+			if kept, ok := prune(child, next); ok {
+				out[key] = kept
+			}
+		}
+		return out, len(out) > 0
+	case []interface{}:
+		out := []interface{}{}
+		matched := false
+		for _, steps := range paths {
+			if steps[0].matchesAll() {
+				matched = true // even an empty array matches [*]
+			}
+		}
+		for i, child := range value {
+			var next [][]step
+			for _, steps := range paths {
+				if steps[0].matchesIndex(i, len(value)) {
+					next = append(next, steps[1:])
+				}
+			}
+			if len(next) == 0 {
+				continue
+			}
+			matched = true
+			if kept, ok := prune(child, next); ok {
+				out = append(out, kept)
+			} else if empty, ok := emptyLike(child); ok {
+				out = append(out, empty)
+			}
+		}
+		return out, matched
+	}
+	return nil, false
+}
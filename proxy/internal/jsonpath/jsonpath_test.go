@@ -0,0 +1,84 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseRejects(t *testing.T) {
+	for expr, want := range map[string]string{
+		"records[*]":        "must start with $",
+		"$..Title":          "recursive descent",
+		"$.":                "empty name",
+		"$.records[":        "missing ']'",
+		"$.records[x]":      "invalid index",
+		"$.records['Title]": "unterminated string",
+		"$.records[0,]":     "invalid index",
+		"$ records":         "unexpected",
+	} {
+		if _, err := Parse(expr); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Parse(%q) = %v, want an error containing %q", expr, err, want)
+		}
+	}
+}
+
+func TestPrune(t *testing.T) {
+	const doc = `{
+		"records": [
+			{"id": 1, "fields": {"Title": "a", "Total": 10, "Secret": "x"}},
+			{"id": 2, "fields": {"Total": 20, "Secret": "y"}},
+			{"id": 3, "fields": {"Title": "c", "Total": 30, "Secret": "z"}}
+		],
+		"pageInfo": {"isLastPage": true}
+	}`
+	tests := []struct {
+		name  string
+		paths []string
+		want  string
+	}{
+		{"root", []string{"$"}, ""},
+		{"ids", []string{"$.records[*].id"}, `{"records":[{"id":1},{"id":2},{"id":3}]}`},
+		{"union of names", []string{"$.records[*].fields['Title','Total']"},
+			`{"records":[{"fields":{"Title":"a","Total":10}},{"fields":{"Total":20}},{"fields":{"Title":"c","Total":30}}]}`},
+		{"records without a match stay in place", []string{"$.records[*].fields.Title"},
+			`{"records":[{"fields":{"Title":"a"}},{},{"fields":{"Title":"c"}}]}`},
+		{"indexes", []string{"$.records[0,-1].id"}, `{"records":[{"id":1},{"id":3}]}`},
+		{"several paths", []string{"$.records[1].id", "$.pageInfo"}, `{"pageInfo":{"isLastPage":true},"records":[{"id":2}]}`},
+		{"wildcard object", []string{"$.records[0].fields.*"}, `{"records":[{"fields":{"Secret":"x","Title":"a","Total":10}}]}`},
+		{"no match", []string{"$.rows[*]"}, `{}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var parsed interface{}
+			json.Unmarshal([]byte(doc), &parsed)
+			paths := make([]Path, len(tt.paths))
+			for i, expr := range tt.paths {
+				var err error
+				if paths[i], err = Parse(expr); err != nil {
+					t.Fatalf("Parse(%q): %v", expr, err)
+				}
+			}
+
+			pruned, ok := Prune(parsed, paths)
+			got, _ := json.Marshal(pruned)
+			want := tt.want
+			if want == "" {
+				compact, _ := json.Marshal(parsed)
+				want = string(compact)
+			}
+			if string(got) != want || ok != (tt.name != "no match") {
+				t.Errorf("Prune = %s (ok %v), want %s", got, ok, want)
+			}
+		})
+	}
+}
+
+func TestPruneEmptyArrayMatchesWildcard(t *testing.T) {
+	path, _ := Parse("$.records[*].id")
+	pruned, ok := Prune(map[string]interface{}{"records": []interface{}{}}, []Path{path})
+	got, _ := json.Marshal(pruned)
+	if !ok || string(got) != `{"records":[]}` {
+		t.Errorf("Prune = %s (ok %v), want the empty list kept", got, ok)
+	}
+}
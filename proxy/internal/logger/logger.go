@@ -1,25 +1,112 @@
 package logger
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// Level is the severity of a log message; messages below the minimum level (LOG_LEVEL) are dropped
+type Level int32
+
+// Log levels, least severe first. Fatal messages are always written.
+const (
+	LevelDebug Level = iota // per-request tracing, e.g. resolved paths and upstream URLs
+	LevelInfo
+	LevelError
+)
+
+// minLevel is the threshold set with SetLevel
+var minLevel atomic.Int32
+
+func init() {
+	minLevel.Store(int32(LevelInfo))
+}
+
+// ParseLevel converts a LOG_LEVEL value (debug, info, error; "" = info) to a Level
+func ParseLevel(name string) (Level, error) {
+	switch name {
+	case "debug":
+		return LevelDebug, nil
+	case "", "info":
+		return LevelInfo, nil
+	case "error":
+		return LevelError, nil
+	}
+	return LevelInfo, fmt.Errorf("unknown log level '%s' (expected debug, info or error)", name)
+}
+
+// SetLevel sets the minimum level of the messages that are written; it may be called at any time
+func SetLevel(level Level) {
+	minLevel.Store(int32(level))
+}
+
+// enabled reports whether messages of a level are written
+func enabled(level Level) bool {
+	return int32(level) >= minLevel.Load()
+}
+
+// Log formats (LOG_FORMAT)
+const (
+	FormatText = "text" // human-readable lines, the default
+	FormatJSON = "json" // one JSON object per line, for log aggregators
+)
+
+// Fields are the extra keys of a JSON log entry, e.g. the method and status of a request
+type Fields map[string]interface{}
+
 type Logger struct {
+	debugLogger *log.Logger
 	infoLogger  *log.Logger
 	errorLogger *log.Logger
 	logDir      string
-	logFile     *os.File
+	logFile     *rotatingFile
+	format      string
+
+	mu  sync.Mutex // serializes JSON entries
+	out io.Writer
 }
 
 var globalLogger *Logger
 
-// Initialize sets up the global logger with file output
+// Initialize sets up the global logger with file output in the text format
 func Initialize(logDir string) error {
+	return InitializeWithFormat(logDir, FormatText)
+}
+
+// Options configure the global logger
+type Options struct {
+	Format          string // FormatText ("" = text) or FormatJSON
+	MaxLogSizeBytes int64  // roll the day's file over before it exceeds this size, 0 = daily rotation only
+	MaxBackups      int    // rolled-over files kept, the oldest are deleted first; 0 = keep all
+}
+
+// InitializeWithFormat sets up the global logger with file output in the given format ("" = text)
+func InitializeWithFormat(logDir, format string) error {
+	return InitializeWithOptions(logDir, Options{Format: format})
+}
+
+// InitializeWithOptions sets up the global logger with file output
+func InitializeWithOptions(logDir string, options Options) error {
+	format := options.Format
+	if options.MaxLogSizeBytes < 0 || options.MaxBackups < 0 {
+		return fmt.Errorf("log size limit and backup count must not be negative")
+	}
+	switch format {
+	case "":
+		format = FormatText
+	case FormatText, FormatJSON:
+	default:
+		return fmt.Errorf("unknown log format '%s' (expected text or json)", format)
+	}
 	if logDir == "" {
 		logDir = "./logs"
 	}
@@ -30,10 +117,7 @@ func Initialize(logDir string) error {
 	}
 
 	// Create log file with timestamp
-	timestamp := time.Now().Format("2006-01-02")
-	logFileName := filepath.Join(logDir, fmt.Sprintf("app-%s.log", timestamp))
-
-	logFile, err := os.OpenFile(logFileName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	logFile, err := openRotatingFile(logDir, options.MaxLogSizeBytes, options.MaxBackups)
 	if err != nil {
 		return fmt.Errorf("failed to open log file: %v", err)
 	}
@@ -42,23 +126,58 @@ func Initialize(logDir string) error {
 	multiWriter := io.MultiWriter(os.Stdout, logFile)
 
 	globalLogger = &Logger{
+		debugLogger: log.New(multiWriter, "[DEBUG] ", log.LstdFlags|log.Lshortfile),
 		infoLogger:  log.New(multiWriter, "[INFO] ", log.LstdFlags|log.Lshortfile),
 		errorLogger: log.New(multiWriter, "[ERROR] ", log.LstdFlags|log.Lshortfile),
 		logDir:      logDir,
 		logFile:     logFile,
+		format:      format,
+		out:         multiWriter,
 	}
 
 	Info("Logger initialized successfully")
+	Info("Log format: %s", format)
 	Info("Log directory: %s", logDir)
-	Info("Log file: %s", logFileName)
+	Info("Log file: %s", logFile.path)
+	if options.MaxLogSizeBytes > 0 {
+		Info("Log files roll over at %d bytes, keeping %d backup(s) (0 = all)", options.MaxLogSizeBytes, options.MaxBackups)
+	}
 
 	return nil
 }
 
+// Debug logs a tracing message, written only with LOG_LEVEL=debug
+func Debug(format string, v ...interface{}) {
+	if !enabled(LevelDebug) {
+		return
+	}
+	if globalLogger != nil {
+		globalLogger.output("debug", nil, fmt.Sprintf(format, v...))
+	} else {
+		log.Printf("[DEBUG] "+format, v...)
+	}
+}
+
 // Info logs an informational message
 func Info(format string, v ...interface{}) {
+	if !enabled(LevelInfo) {
+		return
+	}
 	if globalLogger != nil {
-		globalLogger.infoLogger.Output(2, fmt.Sprintf(format, v...))
+		globalLogger.output("info", nil, fmt.Sprintf(format, v...))
+	} else {
+		log.Printf("[INFO] "+format, v...)
+	}
+}
+
+// InfoWith logs an informational message with fields. The JSON format adds them as keys of the
+// entry; the text format leaves them out, so the message should mention what matters.
+func InfoWith(fields Fields, format string, v ...interface{}) {
+	if !enabled(LevelInfo) {
+		return
+	}
+	if globalLogger != nil {
+		globalLogger.output("info", fields, fmt.Sprintf(format, v...))
 	} else {
 		log.Printf("[INFO] "+format, v...)
 	}
@@ -66,8 +185,23 @@ func Info(format string, v ...interface{}) {
 
 // Error logs an error message
 func Error(format string, v ...interface{}) {
+	if !enabled(LevelError) {
+		return
+	}
 	if globalLogger != nil {
-		globalLogger.errorLogger.Output(2, fmt.Sprintf(format, v...))
+		globalLogger.output("error", nil, fmt.Sprintf(format, v...))
+	} else {
+		log.Printf("[ERROR] "+format, v...)
+	}
+}
+
+// ErrorWith logs an error message with fields, like InfoWith
+func ErrorWith(fields Fields, format string, v ...interface{}) {
+	if !enabled(LevelError) {
+		return
+	}
+	if globalLogger != nil {
+		globalLogger.output("error", fields, fmt.Sprintf(format, v...))
 	} else {
 		log.Printf("[ERROR] "+format, v...)
 	}
@@ -76,7 +210,7 @@ func Error(format string, v ...interface{}) {
 // Fatal logs a fatal error and exits
 func Fatal(format string, v ...interface{}) {
 	if globalLogger != nil {
-		globalLogger.errorLogger.Output(2, fmt.Sprintf(format, v...))
+		globalLogger.output("fatal", nil, fmt.Sprintf(format, v...))
 		Close()
 	} else {
 		log.Printf("[FATAL] "+format, v...)
@@ -84,6 +218,52 @@ func Fatal(format string, v ...interface{}) {
 	os.Exit(1)
 }
 
+// output writes one entry; callerDepth skips output and the exported function that called it
+func (l *Logger) output(level string, fields Fields, message string) {
+	const callerDepth = 3
+	if l.format != FormatJSON {
+		switch level {
+		case "debug":
+			l.debugLogger.Output(callerDepth, message)
+		case "info":
+			l.infoLogger.Output(callerDepth, message)
+		default:
+			l.errorLogger.Output(callerDepth, message)
+		}
+		return
+	}
+
+	caller := "???"
+	if _, file, line, ok := runtime.Caller(callerDepth - 1); ok {
+		caller = filepath.Base(file) + ":" + strconv.Itoa(line)
+	}
+	line, err := jsonEntry(time.Now(), level, message, caller, fields)
+	if err != nil {
+		line, _ = jsonEntry(time.Now(), level, message, caller, Fields{"fields_error": err.Error()})
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.out.Write(line)
+}
+
+// jsonEntry encodes a JSON log line. The standard keys win over fields of the same name.
+func jsonEntry(timestamp time.Time, level, message, caller string, fields Fields) ([]byte, error) {
+	entry := make(map[string]interface{}, len(fields)+4)
+	for key, value := range fields {
+		entry[key] = value
+	}
+	entry["timestamp"] = timestamp.UTC().Format(time.RFC3339Nano)
+	entry["level"] = level
+	entry["message"] = message
+	entry["caller"] = caller
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	return append(line, '\n'), nil
+}
+
 // Close closes the log file
 func Close() error {
 	if globalLogger != nil && globalLogger.logFile != nil {
@@ -93,32 +273,17 @@ func Close() error {
 	return nil
 }
 
-// RotateLogs creates a new log file for the current day
+// RotateLogs creates a new log file for the current day; size rollovers happen on their own
 func RotateLogs() error {
 	if globalLogger == nil {
 		return fmt.Errorf("logger not initialized")
 	}
 
-	// Close current log file
-	if globalLogger.logFile != nil {
-		globalLogger.logFile.Close()
-	}
-
-	// Create new log file
-	timestamp := time.Now().Format("2006-01-02")
-	logFileName := filepath.Join(globalLogger.logDir, fmt.Sprintf("app-%s.log", timestamp))
-
-	logFile, err := os.OpenFile(logFileName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-	if err != nil {
+	logFileName := dailyLogPath(globalLogger.logDir, time.Now())
+	if err := globalLogger.logFile.reopen(logFileName); err != nil {
 		return fmt.Errorf("failed to open new log file: %v", err)
 	}
 
-	// Update multi-writer
-	multiWriter := io.MultiWriter(os.Stdout, logFile)
-	globalLogger.infoLogger.SetOutput(multiWriter)
-	globalLogger.errorLogger.SetOutput(multiWriter)
-	globalLogger.logFile = logFile
-
 	Info("Log file rotated: %s", logFileName)
 	return nil
 }
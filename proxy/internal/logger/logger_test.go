@@ -0,0 +1,124 @@
+package logger
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// initTestLogger sets up the global logger in a temporary directory and returns a function reading
+// the lines written to the log file so far; the logger is closed and reset when the test ends
+func initTestLogger(t *testing.T, format string) func() []string {
+	t.Helper()
+	if err := InitializeWithFormat(t.TempDir(), format); err != nil {
+		t.Fatalf("InitializeWithFormat: %v", err)
+	}
+	path := globalLogger.logFile.path
+	t.Cleanup(func() {
+		Close()
+		globalLogger = nil
+	})
+	return func() []string {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read log file: %v", err)
+		}
+		return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	}
+}
+
+func TestJSONFormat(t *testing.T) {
+	lines := initTestLogger(t, FormatJSON)
+	Info("hello %d", 5)
+	ErrorWith(Fields{"status": 404, "level": "spoofed"}, "not found")
+
+	var entries []map[string]interface{}
+	for _, line := range lines() {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("line %q is not JSON: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	hello, notFound := entries[len(entries)-2], entries[len(entries)-1]
+
+	if hello["message"] != "hello 5" || hello["level"] != "info" {
+		t.Errorf("entry = %v, want message \"hello 5\" at level info", hello)
+	}
+	if caller, _ := hello["caller"].(string); !strings.HasPrefix(caller, "logger_test.go:") {
+		t.Errorf("caller = %q, want the line that logged", caller)
+	}
+	if _, err := time.Parse(time.RFC3339Nano, hello["timestamp"].(string)); err != nil {
+		t.Errorf("timestamp: %v", err)
+	}
+	if notFound["level"] != "error" || notFound["status"] != float64(404) || notFound["message"] != "not found" {
+		t.Errorf("entry = %v, want the fields as keys and the standard keys kept", notFound)
+	}
+}
+
+func TestTextFormat(t *testing.T) {
+	lines := initTestLogger(t, FormatText)
+	InfoWith(Fields{"status": 200}, "hello")
+
+	all := lines()
+	last := all[len(all)-1]
+	if !strings.HasPrefix(last, "[INFO] ") || !strings.Contains(last, "logger_test.go:") || !strings.HasSuffix(last, " hello") {
+		t.Errorf("line = %q, want a text line with the caller", last)
+	}
+	if strings.Contains(last, "status") {
+		t.Errorf("line = %q, want fields left out of text lines", last)
+	}
+}
+
+func TestUnknownFormat(t *testing.T) {
+	if err := InitializeWithFormat(t.TempDir(), "xml"); err == nil {
+		t.Error("InitializeWithFormat accepted an unknown format")
+	}
+}
+
+func TestLevelThreshold(t *testing.T) {
+	t.Cleanup(func() { SetLevel(LevelInfo) })
+	tests := []struct {
+		level Level
+		want  []string
+	}{
+		{LevelDebug, []string{"debug", "info", "error"}},
+		{LevelInfo, []string{"info", "error"}},
+		{LevelError, []string{"error"}},
+	}
+	for _, tt := range tests {
+		lines := initTestLogger(t, FormatJSON)
+		SetLevel(tt.level)
+		Debug("debug")
+		Info("info")
+		InfoWith(Fields{}, "info with fields")
+		Error("error")
+
+		var got []string
+		for _, line := range lines() {
+			var entry map[string]interface{}
+			json.Unmarshal([]byte(line), &entry)
+			if message := entry["message"].(string); message == "debug" || message == "info" || message == "error" {
+				got = append(got, message)
+			}
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("level %d: written %v, want %v", tt.level, got, tt.want)
+		}
+		Close()
+		globalLogger = nil
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for name, want := range map[string]Level{"debug": LevelDebug, "": LevelInfo, "info": LevelInfo, "error": LevelError} {
+		if got, err := ParseLevel(name); err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %d, %v; want %d", name, got, err, want)
+		}
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("ParseLevel accepted an unknown level")
+	}
+}
@@ -0,0 +1,183 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rolledFileName matches the files a size rollover leaves behind: app-2024-01-02.1.log
+var rolledFileName = regexp.MustCompile(`^app-(\d{4}-\d{2}-\d{2})\.(\d+)\.log$`)
+
+// rotatingFile is the day's log file. Once a write would take it past maxSize it is renamed to
+// app-<day>.<n>.log and a fresh file is opened; the oldest rolled files beyond maxBackups are deleted.
+type rotatingFile struct {
+	dir        string
+	maxSize    int64 // 0 = no size limit, daily rotation only
+	maxBackups int   // 0 = keep every rolled file
+
+	mu   sync.Mutex
+	file *os.File
+	path string
+	size int64
+}
+
+// dailyLogPath is the path of the active log file for a day
+func dailyLogPath(dir string, day time.Time) string {
+	return filepath.Join(dir, fmt.Sprintf("app-%s.log", day.Format("2006-01-02")))
+}
+
+// openRotatingFile opens (or appends to) today's log file
+func openRotatingFile(dir string, maxSize int64, maxBackups int) (*rotatingFile, error) {
+	f := &rotatingFile{dir: dir, maxSize: maxSize, maxBackups: maxBackups}
+	if err := f.open(dailyLogPath(dir, time.Now())); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// open makes path the active file, counting what it already holds toward the size limit
+func (f *rotatingFile) open(path string) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file, f.path, f.size = file, path, info.Size()
+	return nil
+}
+
+// Write implements io.Writer. Every log entry is one write, so entries are never split across files.
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		if err := f.roll(); err != nil {
+			// Keep logging to the oversized file rather than losing entries
+			fmt.Fprintf(os.Stderr, "[LOGGER ERROR] Failed to roll %s over: %v\n", f.path, err)
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// roll renames the active file to app-<day>.<n>.log, numbered after the day's existing rolled
+// files (never reusing a deleted one's number), and opens a fresh one
+func (f *rotatingFile) roll() error {
+	base := f.path[:len(f.path)-len(".log")]
+	n := 1
+	for _, backup := range f.backups() {
+		if filepath.Join(f.dir, "app-"+backup.day) == base && backup.n >= n {
+			n = backup.n + 1
+		}
+	}
+	rolled := fmt.Sprintf("%s.%d.log", base, n)
+
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.path, rolled); err != nil {
+		if reopenErr := f.open(f.path); reopenErr != nil {
+			f.file = nil
+		}
+		return err
+	}
+	if err := f.open(f.path); err != nil {
+		f.file = nil
+		return err
+	}
+	f.pruneBackups()
+	return nil
+}
+
+// rolledFile is a file left behind by a size rollover
+type rolledFile struct {
+	path string
+	day  string // 2024-01-02
+	n    int
+}
+
+// backups returns the rolled files in the log directory, oldest first: by day, then by number.
+// Modification times aren't used; files rolled within one clock tick share them.
+func (f *rotatingFile) backups() []rolledFile {
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[LOGGER ERROR] Failed to list %s: %v\n", f.dir, err)
+		return nil
+	}
+	var backups []rolledFile
+	for _, entry := range entries {
+		match := rolledFileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		n, err := strconv.Atoi(match[2])
+		if err != nil {
+			continue
+		}
+		backups = append(backups, rolledFile{filepath.Join(f.dir, entry.Name()), match[1], n})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		if backups[i].day != backups[j].day {
+			return backups[i].day < backups[j].day
+		}
+		return backups[i].n < backups[j].n
+	})
+	return backups
+}
+
+// pruneBackups deletes the oldest rolled files beyond maxBackups
+func (f *rotatingFile) pruneBackups() {
+	if f.maxBackups <= 0 {
+		return
+	}
+	backups := f.backups()
+	if len(backups) <= f.maxBackups {
+		return
+	}
+	for _, old := range backups[:len(backups)-f.maxBackups] {
+		if err := os.Remove(old.path); err != nil {
+			fmt.Fprintf(os.Stderr, "[LOGGER ERROR] Failed to delete old log %s: %v\n", old.path, err)
+		}
+	}
+}
+
+// reopen switches to the log file of a new day
+func (f *rotatingFile) reopen(path string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file != nil {
+		f.file.Close()
+	}
+	if err := f.open(path); err != nil {
+		f.file = nil
+		return err
+	}
+	return nil
+}
+
+// Close closes the active file
+func (f *rotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
@@ -0,0 +1,129 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// logFiles returns the contents of the files in dir by name
+func logFiles(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("list %s: %v", dir, err)
+	}
+	files := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			t.Fatalf("read %s: %v", entry.Name(), err)
+		}
+		files[entry.Name()] = string(data)
+	}
+	return files
+}
+
+func TestRotatingFileRollsOverBySize(t *testing.T) {
+	dir := t.TempDir()
+	f, err := openRotatingFile(dir, 11, 0)
+	if err != nil {
+		t.Fatalf("openRotatingFile: %v", err)
+	}
+	defer f.Close()
+	active := filepath.Base(f.path)
+	base := strings.TrimSuffix(active, ".log")
+
+	for _, entry := range []string{"aaaaaa\n", "bbb\n", "cccccc\n", "ddddddddddddddd\n"} {
+		if _, err := f.Write([]byte(entry)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+
+	want := map[string]string{
+		base + ".1.log": "aaaaaa\nbbb\n", // fits exactly
+		base + ".2.log": "cccccc\n",
+		active:          "ddddddddddddddd\n", // an entry larger than the limit is never split
+	}
+	got := logFiles(t, dir)
+	if len(got) != len(want) {
+		t.Fatalf("files = %v, want %v", got, want)
+	}
+	for name, content := range want {
+		if got[name] != content {
+			t.Errorf("%s = %q, want %q", name, got[name], content)
+		}
+	}
+}
+
+func TestRotatingFileKeepsNewestBackups(t *testing.T) {
+	dir := t.TempDir()
+	f, err := openRotatingFile(dir, 4, 2)
+	if err != nil {
+		t.Fatalf("openRotatingFile: %v", err)
+	}
+	defer f.Close()
+	active := filepath.Base(f.path)
+
+	for _, entry := range []string{"1111", "2222", "3333", "4444", "5555", "6666"} {
+		if _, err := f.Write([]byte(entry)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+
+	// Rolled files are numbered in order, even after older ones were deleted
+	base := strings.TrimSuffix(active, ".log")
+	want := map[string]string{base + ".4.log": "4444", base + ".5.log": "5555", active: "6666"}
+	files := logFiles(t, dir)
+	if len(files) != len(want) {
+		t.Fatalf("files = %v, want %v", files, want)
+	}
+	for name, content := range want {
+		if files[name] != content {
+			t.Errorf("%s = %q, want %q", name, files[name], content)
+		}
+	}
+}
+
+// Backups are ordered by number, not modification time: files rolled within one clock tick share it
+func TestRotatingFileNumbersAfterExistingBackups(t *testing.T) {
+	dir := t.TempDir()
+	f, err := openRotatingFile(dir, 4, 1)
+	if err != nil {
+		t.Fatalf("openRotatingFile: %v", err)
+	}
+	defer f.Close()
+	base := strings.TrimSuffix(f.path, ".log")
+
+	stamp := time.Now().Add(time.Hour) // newer than the file rolled below
+	for _, name := range []string{base + ".9.log", base + ".10.log"} {
+		os.WriteFile(name, []byte(filepath.Base(name)), 0644)
+		os.Chtimes(name, stamp, stamp)
+	}
+	f.Write([]byte("1111"))
+	f.Write([]byte("2222")) // rolls 1111 over to .11 and keeps only it
+
+	files := logFiles(t, dir)
+	if len(files) != 2 || files[filepath.Base(base)+".11.log"] != "1111" {
+		t.Errorf("files = %v, want the active file and the newest backup", files)
+	}
+}
+
+func TestRotatingFileAppendsToExistingFile(t *testing.T) {
+	dir := t.TempDir()
+	path := dailyLogPath(dir, time.Now())
+	os.WriteFile(path, []byte("12345678"), 0644)
+
+	f, err := openRotatingFile(dir, 10, 0)
+	if err != nil {
+		t.Fatalf("openRotatingFile: %v", err)
+	}
+	defer f.Close()
+	f.Write([]byte("abc"))
+
+	if files := logFiles(t, dir); len(files) != 2 || files[filepath.Base(path)] != "abc" {
+		t.Errorf("files = %v, want the existing content counted and rolled over", files)
+	}
+}
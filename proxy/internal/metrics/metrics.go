@@ -0,0 +1,97 @@
+// Package metrics collects the proxy's Prometheus metrics and serves them on /metrics
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry holds every proxy metric plus the Go runtime and process collectors
+var Registry = prometheus.NewRegistry()
+
+var (
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "proxy_http_requests_total",
+		Help: "HTTP requests served, by method, route and status.",
+	}, []string{"method", "path", "status"})
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "proxy_http_request_duration_seconds",
+		Help:    "Time to serve an HTTP request, by method and route.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "path"})
+
+	upstreamDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "proxy_upstream_request_duration_seconds",
+		Help:    "Time until NocoDB answered a call made for a client request, by method, call kind and status (\"error\" when no response arrived).",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "call", "status"})
+
+	paginationPages = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "proxy_pagination_pages_fetched_total",
+		Help: "Follow-up list pages fetched from NocoDB to merge paginated lists.",
+	})
+
+	authFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "proxy_auth_failures_total",
+		Help: "Rejected authentication attempts, by reason.",
+	}, []string{"reason"})
+)
+
+// Auth failure reasons
+const (
+	AuthMissingToken       = "missing_token"
+	AuthInvalidToken       = "invalid_token"
+	AuthRevokedToken       = "revoked_token"
+	AuthCSRFMismatch       = "csrf_mismatch"
+	AuthInvalidCredentials = "invalid_credentials"
+	AuthLockedOut          = "locked_out"
+	AuthInvalidRefresh     = "invalid_refresh_token"
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		requestsTotal,
+		requestDuration,
+		upstreamDuration,
+		paginationPages,
+		authFailures,
+	)
+}
+
+// Handler serves the registry in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// ObserveRequest records a served HTTP request under its route label (see RouteLabel)
+func ObserveRequest(method, path string, status int, duration time.Duration) {
+	requestsTotal.WithLabelValues(method, path, strconv.Itoa(status)).Inc()
+	requestDuration.WithLabelValues(method, path).Observe(duration.Seconds())
+}
+
+// ObserveUpstream records one NocoDB call; status is 0 when the call failed without a response
+func ObserveUpstream(method, call string, status int, duration time.Duration) {
+	statusLabel := "error"
+	if status != 0 {
+		statusLabel = strconv.Itoa(status)
+	}
+	upstreamDuration.WithLabelValues(method, call, statusLabel).Observe(duration.Seconds())
+}
+
+// PaginationPageFetched counts a follow-up page fetched for a merged list
+func PaginationPageFetched() {
+	paginationPages.Inc()
+}
+
+// AuthFailure counts a rejected authentication attempt
+func AuthFailure(reason string) {
+	authFailures.WithLabelValues(reason).Inc()
+}
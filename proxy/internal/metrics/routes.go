@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"strings"
+	"sync/atomic"
+)
+
+// OtherRoute is the path label of requests that match no route, or a proxy route of a table (or
+// link) that isn't configured. Client-chosen paths never become labels of their own, so scraping
+// can't be flooded with series by requesting made-up URLs.
+const OtherRoute = "other"
+
+// proxyTables holds the configured table keys and their link names, set with SetTables
+var proxyTables atomic.Pointer[map[string]map[string]bool]
+
+// SetTables sets the tables (table key -> link names) whose /proxy paths get labels of their own;
+// it is called again whenever the proxy config is reloaded
+func SetTables(tables map[string][]string) {
+	known := make(map[string]map[string]bool, len(tables))
+	for table, links := range tables {
+		known[table] = make(map[string]bool, len(links))
+		for _, link := range links {
+			known[table][link] = true
+		}
+	}
+	proxyTables.Store(&known)
+}
+
+// RouteLabel returns the path label of a request the mux matched to pattern ("" = no match). Exact
+// routes are reported as themselves and subtrees as their pattern, except /proxy/, whose table,
+// record and link segments are resolved against the configured tables: /proxy/quotes/records/42
+// becomes /proxy/quotes/records/:id. Everything else is OtherRoute.
+func RouteLabel(pattern, path string) string {
+	switch {
+	case pattern == "":
+		return OtherRoute
+	case pattern == "/proxy/":
+		return proxyRouteLabel(strings.TrimPrefix(path, "/proxy/"))
+	default:
+		return pattern
+	}
+}
+
+// proxyRouteLabel labels the part of a /proxy path after /proxy/, following the path shapes the
+// proxy accepts
+func proxyRouteLabel(rest string) string {
+	parts := strings.Split(rest, "/")
+	table := parts[0]
+	tables := proxyTables.Load()
+	if tables == nil {
+		return OtherRoute
+	}
+	links, ok := (*tables)[table]
+	if !ok {
+		return OtherRoute
+	}
+	link := func(name string) bool { return links[name] }
+
+	shape := parts[1:]
+	var label []string
+	switch {
+	case len(shape) == 1 && shape[0] == "records":
+		label = []string{"records"}
+	case len(shape) == 2 && shape[0] == "records" && shape[1] == "count":
+		label = []string{"records", "count"}
+	case len(shape) == 2 && shape[0] == "records":
+		label = []string{"records", ":id"}
+	case len(shape) == 3 && shape[0] == "records" && shape[2] == "comments":
+		label = []string{"records", ":id", "comments"}
+	case len(shape) == 4 && shape[0] == "records" && shape[2] == "comments":
+		label = []string{"records", ":id", "comments", ":id"}
+	case len(shape) == 4 && shape[0] == "records" && shape[2] == "links" && link(shape[3]):
+		label = []string{"records", ":id", "links", shape[3]}
+	case len(shape) == 3 && shape[0] == "links" && link(shape[1]):
+		label = []string{"links", shape[1], ":id"}
+	case len(shape) == 4 && shape[0] == "links" && link(shape[1]) && shape[2] == "records":
+		label = []string{"links", shape[1], "records", ":id"}
+	default:
+		return OtherRoute
+	}
+	return "/proxy/" + table + "/" + strings.Join(label, "/")
+}
@@ -0,0 +1,43 @@
+package metrics
+
+import "testing"
+
+func TestRouteLabel(t *testing.T) {
+	SetTables(map[string][]string{"quotes": {"items"}})
+	t.Cleanup(func() { proxyTables.Store(nil) })
+
+	tests := []struct {
+		pattern string
+		path    string
+		want    string
+	}{
+		{"/health", "/health", "/health"},
+		{"/api/admin/users/", "/api/admin/users/42/role", "/api/admin/users/"},
+		{"", "/wp-login.php", OtherRoute},
+		{"/proxy/", "/proxy/quotes/records", "/proxy/quotes/records"},
+		{"/proxy/", "/proxy/quotes/records/count", "/proxy/quotes/records/count"},
+		{"/proxy/", "/proxy/quotes/records/42", "/proxy/quotes/records/:id"},
+		{"/proxy/", "/proxy/quotes/records/abc-def", "/proxy/quotes/records/:id"},
+		{"/proxy/", "/proxy/quotes/records/42/comments", "/proxy/quotes/records/:id/comments"},
+		{"/proxy/", "/proxy/quotes/records/42/comments/7", "/proxy/quotes/records/:id/comments/:id"},
+		{"/proxy/", "/proxy/quotes/records/42/links/items", "/proxy/quotes/records/:id/links/items"},
+		{"/proxy/", "/proxy/quotes/links/items/42", "/proxy/quotes/links/items/:id"},
+		{"/proxy/", "/proxy/quotes/links/items/records/42", "/proxy/quotes/links/items/records/:id"},
+		{"/proxy/", "/proxy/quotes/records/42/links/made-up", OtherRoute},
+		{"/proxy/", "/proxy/made-up/records/42", OtherRoute},
+		{"/proxy/", "/proxy/quotes/whatever/1/2/3", OtherRoute},
+		{"/proxy/", "/proxy/quotes", OtherRoute},
+	}
+	for _, tt := range tests {
+		if got := RouteLabel(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("RouteLabel(%q, %q) = %q, want %q", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRouteLabelWithoutTables(t *testing.T) {
+	proxyTables.Store(nil)
+	if got := RouteLabel("/proxy/", "/proxy/quotes/records/1"); got != OtherRoute {
+		t.Errorf("label without configured tables = %q, want %q", got, OtherRoute)
+	}
+}
@@ -3,10 +3,11 @@ package middleware
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
-	"strings"
 
+	"github.com/grove/generic-proxy/internal/metrics"
 	"github.com/grove/generic-proxy/internal/utils"
 )
 
@@ -17,38 +18,56 @@ const (
 	RoleKey   contextKey = "role"
 )
 
-// AuthMiddleware validates JWT tokens and extracts user claims
-func AuthMiddleware(jwtSecret string) func(http.Handler) http.Handler {
+// TokenBlocklist knows the access tokens revoked before they expire, by jti (see db.Database)
+type TokenBlocklist interface {
+	IsTokenRevoked(jti string) (bool, error)
+}
+
+// AuthMiddleware validates JWT tokens and extracts user claims. Tokens on the blocklist are
+// rejected; tokens issued without a jti can't be revoked and stay valid until they expire.
+// With cookies set, the token may also come from the auth cookie (see BearerToken).
+func AuthMiddleware(jwtSecret string, blocklist TokenBlocklist, cookies *CookieAuth) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			log.Printf("[AUTH] Validating request: %s %s", r.Method, r.URL.Path)
 
-			authHeader := r.Header.Get("Authorization")
-			if authHeader == "" {
-				log.Printf("[AUTH ERROR] Missing authorization header")
-				respondWithError(w, http.StatusUnauthorized, "missing authorization header")
+			// Extract token from "Bearer <token>" or the auth cookie
+			tokenString, fromCookie, err := BearerToken(r, cookies)
+			if errors.Is(err, ErrCSRFTokenMismatch) {
+				log.Printf("[AUTH ERROR] Cookie-authenticated %s without a valid CSRF token", r.Method)
+				metrics.AuthFailure(metrics.AuthCSRFMismatch)
+				respondWithError(w, http.StatusForbidden, err.Error())
 				return
 			}
-			log.Printf("[AUTH] Authorization header present")
-
-			// Extract token from "Bearer <token>"
-			parts := strings.Split(authHeader, " ")
-			if len(parts) != 2 || parts[0] != "Bearer" {
-				log.Printf("[AUTH ERROR] Invalid authorization header format")
-				respondWithError(w, http.StatusUnauthorized, "invalid authorization header format")
+			if err != nil {
+				log.Printf("[AUTH ERROR] %v", err)
+				metrics.AuthFailure(metrics.AuthMissingToken)
+				respondWithError(w, http.StatusUnauthorized, err.Error())
 				return
 			}
-
-			tokenString := parts[1]
-			log.Printf("[AUTH] Validating JWT token...")
+			log.Printf("[AUTH] Validating JWT token (from cookie: %v)...", fromCookie)
 
 			// Validate JWT
 			claims, err := utils.ValidateJWT(tokenString, jwtSecret)
 			if err != nil {
 				log.Printf("[AUTH ERROR] JWT validation failed: %v", err)
+				metrics.AuthFailure(metrics.AuthInvalidToken)
 				respondWithError(w, http.StatusUnauthorized, "invalid or expired token")
 				return
 			}
+			if blocklist != nil && claims.ID != "" {
+				revoked, err := blocklist.IsTokenRevoked(claims.ID)
+				if err != nil {
+					respondWithError(w, http.StatusInternalServerError, "failed to verify token")
+					return
+				}
+				if revoked {
+					log.Printf("[AUTH ERROR] Revoked token presented by user %s", claims.UserID)
+					metrics.AuthFailure(metrics.AuthRevokedToken)
+					respondWithError(w, http.StatusUnauthorized, "token has been revoked")
+					return
+				}
+			}
 			log.Printf("[AUTH] JWT validated successfully - User: %s, Role: %s", claims.UserID, claims.Role)
 
 			// Add claims to request context
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.10"})
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies: %v", err)
+	}
+	tests := []struct {
+		name         string
+		remote       string
+		forwardedFor string
+		want         string
+	}{
+		{"direct client", "203.0.113.5:1234", "", "203.0.113.5"},
+		{"direct client with a forged header", "203.0.113.5:1234", "198.51.100.1", "203.0.113.5"},
+		{"behind a trusted proxy", "10.0.0.2:1234", "203.0.113.5", "203.0.113.5"},
+		{"forged entry left of the real one", "10.0.0.2:1234", "198.51.100.1, 203.0.113.5", "203.0.113.5"},
+		{"proxy chain", "192.168.1.10:1234", "203.0.113.5, 10.1.2.3", "203.0.113.5"},
+		{"garbage entry", "10.0.0.2:1234", "203.0.113.5, nonsense", "10.0.0.2"},
+		{"only proxies", "10.0.0.2:1234", "10.0.0.3", "10.0.0.3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remote
+			if tt.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+			if got := proxies.ClientIP(req); got != tt.want {
+				t.Errorf("ClientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTrustedProxiesRejectsGarbage(t *testing.T) {
+	for _, entry := range []string{"proxy.local", "10.0.0.0/33"} {
+		if _, err := ParseTrustedProxies([]string{entry}); err == nil {
+			t.Errorf("%q accepted", entry)
+		}
+	}
+}
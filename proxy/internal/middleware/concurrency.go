@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/grove/generic-proxy/internal/httperr"
+)
+
+// ConcurrencyLimiter caps the requests a single user can have in flight at once.
+// Unlike a rate limit it doesn't care how many requests are made, only how many run concurrently.
+type ConcurrencyLimiter struct {
+	max         int  // per user, 0 = unlimited
+	adminBypass bool // admins are never limited
+
+	mu       sync.Mutex
+	inFlight map[string]int // user ID -> requests in flight
+}
+
+// NewConcurrencyLimiter creates a limiter allowing max concurrent requests per user
+func NewConcurrencyLimiter(max int, adminBypass bool) *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{
+		max:         max,
+		adminBypass: adminBypass,
+		inFlight:    make(map[string]int),
+	}
+}
+
+// acquire takes a slot for the user, reporting false when the user is at the cap
+func (l *ConcurrencyLimiter) acquire(userID string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.inFlight[userID] >= l.max {
+		return false
+	}
+	l.inFlight[userID]++
+	return true
+}
+
+// release frees a slot taken by acquire
+func (l *ConcurrencyLimiter) release(userID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inFlight[userID]--
+	if l.inFlight[userID] <= 0 {
+		delete(l.inFlight, userID) // keep the map as small as the set of active users
+	}
+}
+
+// Middleware rejects requests with 429 while the user already has max requests in flight.
+// It must run after AuthMiddleware, which puts the user ID in the context.
+func (l *ConcurrencyLimiter) Middleware(next http.Handler) http.Handler {
+	if l.max <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := r.Context().Value(UserIDKey).(string)
+		role, _ := r.Context().Value(RoleKey).(string)
+		if userID == "" || (l.adminBypass && role == "admin") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !l.acquire(userID) {
+			log.Printf("[CONCURRENCY] User %s already has %d requests in flight - rejecting %s %s", userID, l.max, r.Method, r.URL.Path)
+			w.Header().Set("Retry-After", "1")
+			httperr.WriteError(w, httperr.TooManyConcurrentRequests, "too many concurrent requests")
+			return
+		}
+		defer l.release(userID)
+
+		next.ServeHTTP(w, r)
+	})
+}
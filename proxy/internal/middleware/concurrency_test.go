@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// blockingHandler holds every request until release is closed and records the most requests
+// each user ever had inside at once
+type blockingHandler struct {
+	release chan struct{}
+	entered chan string
+
+	mu       sync.Mutex
+	inside   map[string]int
+	maxSeen  map[string]int
+	finished atomic.Int32
+}
+
+func newBlockingHandler() *blockingHandler {
+	return &blockingHandler{release: make(chan struct{}), entered: make(chan string, 100), inside: make(map[string]int), maxSeen: make(map[string]int)}
+}
+
+func (h *blockingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(UserIDKey).(string)
+	h.mu.Lock()
+	h.inside[userID]++
+	if h.inside[userID] > h.maxSeen[userID] {
+		h.maxSeen[userID] = h.inside[userID]
+	}
+	h.mu.Unlock()
+	h.entered <- userID
+
+	<-h.release
+
+	h.mu.Lock()
+	h.inside[userID]--
+	h.mu.Unlock()
+	h.finished.Add(1)
+	w.WriteHeader(http.StatusOK)
+}
+
+func serveAs(h http.Handler, userID, role string) int {
+	req := httptest.NewRequest(http.MethodGet, "/proxy/quotes/records", nil)
+	ctx := context.WithValue(req.Context(), UserIDKey, userID)
+	ctx = context.WithValue(ctx, RoleKey, role)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req.WithContext(ctx))
+	return rec.Code
+}
+
+// Run with -race: many concurrent requests per user, the cap holds for each user separately
+func TestConcurrencyLimiterCapsPerUser(t *testing.T) {
+	const limit, perUser = 3, 20
+	blocking := newBlockingHandler()
+	handler := NewConcurrencyLimiter(limit, true).Middleware(blocking)
+
+	users := []string{"7", "8"}
+	var wg sync.WaitGroup
+	var rejected, accepted atomic.Int32
+	for _, userID := range users {
+		for i := 0; i < perUser; i++ {
+			wg.Add(1)
+			go func(userID string) {
+				defer wg.Done()
+				switch code := serveAs(handler, userID, "user"); code {
+				case http.StatusOK:
+					accepted.Add(1)
+				case http.StatusTooManyRequests:
+					rejected.Add(1)
+				default:
+					t.Errorf("status %d", code)
+				}
+			}(userID)
+		}
+	}
+
+	// Every user fills its slots; all other requests are turned away while those are held
+	for i := 0; i < limit*len(users); i++ {
+		<-blocking.entered
+	}
+	for rejected.Load() != int32(len(users)*(perUser-limit)) {
+		if blocking.finished.Load() != 0 {
+			t.Fatal("a held request finished early")
+		}
+		select {
+		case userID := <-blocking.entered:
+			t.Fatalf("user %s got a request past the cap", userID)
+		default:
+		}
+	}
+	close(blocking.release)
+	wg.Wait()
+
+	if accepted.Load() != int32(limit*len(users)) {
+		t.Errorf("%d requests accepted, want %d", accepted.Load(), limit*len(users))
+	}
+	for _, userID := range users {
+		if blocking.maxSeen[userID] != limit {
+			t.Errorf("user %s had %d requests in flight, want the cap of %d", userID, blocking.maxSeen[userID], limit)
+		}
+	}
+}
+
+func TestConcurrencyLimiterReleasesSlots(t *testing.T) {
+	limiter := NewConcurrencyLimiter(1, false)
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for i := 0; i < 3; i++ {
+		if code := serveAs(handler, "7", "user"); code != http.StatusOK {
+			t.Fatalf("sequential request %d: status %d, want a slot freed by the previous one", i+1, code)
+		}
+	}
+	if len(limiter.inFlight) != 0 {
+		t.Errorf("inFlight = %v, want no idle users left", limiter.inFlight)
+	}
+}
+
+func TestConcurrencyLimiterAdminBypass(t *testing.T) {
+	blocking := newBlockingHandler()
+	handler := NewConcurrencyLimiter(1, true).Middleware(blocking)
+	done := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		go func() { done <- serveAs(handler, "1", "admin") }()
+		<-blocking.entered // both get in although the cap is 1
+	}
+	close(blocking.release)
+	for i := 0; i < 2; i++ {
+		if code := <-done; code != http.StatusOK {
+			t.Errorf("bypass: status %d, want 200", code)
+		}
+	}
+
+	blocking = newBlockingHandler()
+	handler = NewConcurrencyLimiter(1, false).Middleware(blocking)
+	go func() { done <- serveAs(handler, "1", "admin") }()
+	<-blocking.entered
+	if code := serveAs(handler, "1", "admin"); code != http.StatusTooManyRequests {
+		t.Errorf("no bypass: second admin request status %d, want 429", code)
+	}
+	close(blocking.release)
+	<-done
+}
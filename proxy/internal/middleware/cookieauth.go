@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Double-submit CSRF protection for cookie-authenticated requests: the CSRF cookie is readable by
+// the page's scripts, which echo it in the header; another site can send the cookie but can't read it
+const (
+	CSRFCookieName = "csrf_token"
+	CSRFHeader     = "X-CSRF-Token"
+)
+
+// Errors returned by BearerToken
+var (
+	ErrNoToken           = errors.New("missing authorization header")
+	ErrInvalidAuthHeader = errors.New("invalid authorization header format")
+	ErrCSRFTokenMismatch = errors.New("missing or invalid " + CSRFHeader + " header")
+)
+
+// CookieAuth lets browser clients keep the JWT in an HttpOnly cookie instead of sending it in
+// the Authorization header. A nil *CookieAuth means header-only authentication.
+type CookieAuth struct {
+	Name   string // cookie holding the JWT
+	Secure bool   // only sent over HTTPS; disable for plain-HTTP development only
+}
+
+// BearerToken returns the JWT of a request. The Authorization header wins when both it and the
+// cookie are present. A token from the cookie on a state-changing request (anything but
+// GET/HEAD/OPTIONS) needs a CSRFHeader matching the CSRF cookie.
+func BearerToken(r *http.Request, cookies *CookieAuth) (token string, fromCookie bool, err error) {
+	if authHeader := r.Header.Get("Authorization"); authHeader != "" {
+		parts := strings.Split(authHeader, " ")
+		if len(parts) != 2 || parts[0] != "Bearer" {
+			return "", false, ErrInvalidAuthHeader
+		}
+		return parts[1], false, nil
+	}
+	if cookies == nil {
+		return "", false, ErrNoToken
+	}
+	cookie, err := r.Cookie(cookies.Name)
+	if err != nil || cookie.Value == "" {
+		return "", false, ErrNoToken
+	}
+	if !csrfSafeMethod(r.Method) && !validCSRFToken(r) {
+		return "", true, ErrCSRFTokenMismatch
+	}
+	return cookie.Value, true, nil
+}
+
+// csrfSafeMethod reports whether a method must not change state and so needs no CSRF token
+func csrfSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// validCSRFToken reports whether the CSRF header matches the CSRF cookie
+func validCSRFToken(r *http.Request) bool {
+	cookie, err := r.Cookie(CSRFCookieName)
+	header := r.Header.Get(CSRFHeader)
+	if err != nil || cookie.Value == "" || header == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(header)) == 1
+}
+
+// SetTokenCookies stores a freshly issued JWT in the auth cookie, with a new CSRF cookie next to it.
+// Both expire with the token.
+func (c *CookieAuth) SetTokenCookies(w http.ResponseWriter, token string, ttl time.Duration) error {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return err
+	}
+	expires := time.Now().Add(ttl)
+	http.SetCookie(w, &http.Cookie{
+		Name: c.Name, Value: token, Path: "/", Expires: expires, MaxAge: int(ttl.Seconds()),
+		HttpOnly: true, Secure: c.Secure, SameSite: http.SameSiteStrictMode,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name: CSRFCookieName, Value: hex.EncodeToString(raw), Path: "/", Expires: expires, MaxAge: int(ttl.Seconds()),
+		Secure: c.Secure, SameSite: http.SameSiteStrictMode, // readable by scripts, see CSRFHeader
+	})
+	return nil
+}
+
+// ClearTokenCookies removes the auth and CSRF cookies
+func (c *CookieAuth) ClearTokenCookies(w http.ResponseWriter) {
+	for _, name := range []string{c.Name, CSRFCookieName} {
+		http.SetCookie(w, &http.Cookie{Name: name, Value: "", Path: "/", MaxAge: -1, Secure: c.Secure, SameSite: http.SameSiteStrictMode})
+	}
+}
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/utils"
+)
+
+func TestBearerToken(t *testing.T) {
+	cookies := &CookieAuth{Name: "auth_token", Secure: true}
+	tests := []struct {
+		name           string
+		method         string
+		cookies        *CookieAuth
+		header         string
+		authCookie     string
+		csrfCookie     string
+		csrfHeader     string
+		wantToken      string
+		wantFromCookie bool
+		wantErr        error
+	}{
+		{"header", http.MethodGet, nil, "Bearer h", "", "", "", "h", false, nil},
+		{"malformed header", http.MethodGet, cookies, "Token h", "c", "", "", "", false, ErrInvalidAuthHeader},
+		{"cookie auth disabled", http.MethodGet, nil, "", "c", "", "", "", false, ErrNoToken},
+		{"cookie on a read", http.MethodGet, cookies, "", "c", "", "", "c", true, nil},
+		{"header wins over cookie", http.MethodPost, cookies, "Bearer h", "c", "", "", "h", false, nil},
+		{"cookie write with CSRF token", http.MethodPost, cookies, "", "c", "x1", "x1", "c", true, nil},
+		{"cookie write without CSRF token", http.MethodPost, cookies, "", "c", "x1", "", "", true, ErrCSRFTokenMismatch},
+		{"cookie write with wrong CSRF token", http.MethodDelete, cookies, "", "c", "x1", "x2", "", true, ErrCSRFTokenMismatch},
+		{"cookie write without CSRF cookie", http.MethodPatch, cookies, "", "c", "", "x1", "", true, ErrCSRFTokenMismatch},
+		{"nothing", http.MethodGet, cookies, "", "", "", "", "", false, ErrNoToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/proxy/quotes/records", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			if tt.authCookie != "" {
+				req.AddCookie(&http.Cookie{Name: "auth_token", Value: tt.authCookie})
+			}
+			if tt.csrfCookie != "" {
+				req.AddCookie(&http.Cookie{Name: CSRFCookieName, Value: tt.csrfCookie})
+			}
+			if tt.csrfHeader != "" {
+				req.Header.Set(CSRFHeader, tt.csrfHeader)
+			}
+
+			token, fromCookie, err := BearerToken(req, tt.cookies)
+			if !errors.Is(err, tt.wantErr) || token != tt.wantToken || fromCookie != tt.wantFromCookie {
+				t.Errorf("BearerToken = %q, %v, %v; want %q, %v, %v", token, fromCookie, err, tt.wantToken, tt.wantFromCookie, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSetAndClearTokenCookies(t *testing.T) {
+	cookies := &CookieAuth{Name: "auth_token", Secure: true}
+	rec := httptest.NewRecorder()
+	if err := cookies.SetTokenCookies(rec, "jwt", time.Hour); err != nil {
+		t.Fatalf("SetTokenCookies: %v", err)
+	}
+	set := map[string]*http.Cookie{}
+	for _, cookie := range rec.Result().Cookies() {
+		set[cookie.Name] = cookie
+	}
+	auth, csrf := set["auth_token"], set[CSRFCookieName]
+	if auth == nil || auth.Value != "jwt" || !auth.HttpOnly || !auth.Secure || auth.SameSite != http.SameSiteStrictMode {
+		t.Errorf("auth cookie = %+v, want the token HttpOnly, Secure and SameSite=Strict", auth)
+	}
+	if csrf == nil || csrf.Value == "" || csrf.HttpOnly {
+		t.Errorf("CSRF cookie = %+v, want a token scripts can read", csrf)
+	}
+
+	rec = httptest.NewRecorder()
+	cookies.ClearTokenCookies(rec)
+	for _, header := range rec.Result().Header.Values("Set-Cookie") {
+		if !strings.Contains(header, "Max-Age=0") {
+			t.Errorf("Set-Cookie %q does not expire the cookie", header)
+		}
+	}
+}
+
+func TestAuthMiddlewareCookieToken(t *testing.T) {
+	const secret = "test-secret"
+	token, err := utils.GenerateJWT("7", "user", secret, utils.TokenOptions{})
+	if err != nil {
+		t.Fatalf("GenerateJWT: %v", err)
+	}
+	cookies := &CookieAuth{Name: "auth_token"}
+	var userID string
+	handler := AuthMiddleware(secret, nil, cookies)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, _ = r.Context().Value(UserIDKey).(string)
+	}))
+	request := func(method string) int {
+		req := httptest.NewRequest(method, "/proxy/quotes/records", nil)
+		req.AddCookie(&http.Cookie{Name: "auth_token", Value: token})
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := request(http.MethodGet); code != http.StatusOK || userID != "7" {
+		t.Errorf("GET with the cookie: status %d, user %q", code, userID)
+	}
+	// A cookie-authenticated write without the CSRF header is refused
+	if code := request(http.MethodPost); code != http.StatusForbidden {
+		t.Errorf("POST without CSRF header: status %d, want 403", code)
+	}
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/grove/generic-proxy/internal/httperr"
+)
+
+// HeaderLimitMiddleware rejects requests with more than maxCount header lines or more than
+// maxBytes of headers (names, values and separators) with 431, before any handler parses them.
+// It complements the server's MaxHeaderBytes, which only bounds the raw read and answers in plain text.
+// 0 disables a limit.
+func HeaderLimitMiddleware(maxCount int, maxBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if maxCount <= 0 && maxBytes <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			count, size := headerSize(r.Header)
+			if maxCount > 0 && count > maxCount {
+				log.Printf("[HEADERS] Rejecting %s %s: %d header lines (limit %d)", r.Method, r.URL.Path, count, maxCount)
+				httperr.WriteErrorParams(w, httperr.RequestHeadersTooLarge,
+					fmt.Sprintf("too many request headers (%d, limit %d)", count, maxCount),
+					map[string]string{"limit": strconv.Itoa(maxCount) + " headers"})
+				return
+			}
+			if maxBytes > 0 && size > maxBytes {
+				log.Printf("[HEADERS] Rejecting %s %s: %d header bytes (limit %d)", r.Method, r.URL.Path, size, maxBytes)
+				httperr.WriteErrorParams(w, httperr.RequestHeadersTooLarge,
+					fmt.Sprintf("request headers too large (%d bytes, limit %d)", size, maxBytes),
+					map[string]string{"limit": strconv.FormatInt(maxBytes, 10) + " bytes"})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// headerSize counts header lines and their wire size ("Name: value\r\n")
+func headerSize(header http.Header) (count int, size int64) {
+	for name, values := range header {
+		for _, value := range values {
+			count++
+			size += int64(len(name) + len(value) + 4)
+		}
+	}
+	return count, size
+}
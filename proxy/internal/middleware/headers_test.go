@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/grove/generic-proxy/internal/httperr"
+)
+
+func TestHeaderLimitMiddleware(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    int
+	}{
+		{"within limits", map[string]string{"Authorization": "Bearer t"}, http.StatusOK},
+		{"too many headers", manyHeaders(11), http.StatusRequestHeaderFieldsTooLarge},
+		{"headers too large", map[string]string{"Cookie": strings.Repeat("a", 600)}, http.StatusRequestHeaderFieldsTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reached := false
+			handler := HeaderLimitMiddleware(10, 512)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { reached = true }))
+			req := httptest.NewRequest(http.MethodGet, "/proxy/quotes/records", nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if tt.want == http.StatusOK {
+				return
+			}
+			if reached {
+				t.Error("rejected request reached the handler")
+			}
+			var body struct {
+				Code string `json:"code"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Code != httperr.RequestHeadersTooLarge {
+				t.Errorf("body %s, want code %s", rec.Body, httperr.RequestHeadersTooLarge)
+			}
+		})
+	}
+}
+
+func TestHeaderLimitMiddlewareDisabled(t *testing.T) {
+	handler := HeaderLimitMiddleware(0, 0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for name, value := range manyHeaders(200) {
+		req.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d with both limits off", rec.Code)
+	}
+}
+
+// manyHeaders returns n small distinct headers
+func manyHeaders(n int) map[string]string {
+	headers := make(map[string]string, n)
+	for i := 0; i < n; i++ {
+		headers["X-Test-"+strconv.Itoa(i)] = "1"
+	}
+	return headers
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/metrics"
+)
+
+// MetricsMiddleware counts every request and its duration by method, route and status. The route
+// label is the mux pattern the request matches (see metrics.RouteLabel), never the raw path.
+func MetricsMiddleware(mux *http.ServeMux) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			startTime := time.Now()
+			wrapped := &responseWriter{
+				ResponseWriter: w,
+				statusCode:     http.StatusOK,
+			}
+			_, pattern := mux.Handler(r)
+
+			next.ServeHTTP(wrapped, r)
+
+			metrics.ObserveRequest(r.Method, metrics.RouteLabel(pattern, r.URL.Path), wrapped.statusCode, time.Since(startTime))
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grove/generic-proxy/internal/metrics"
+)
+
+// TestMetricsPathLabelsAreBounded scrapes the registry after requests to made-up paths and checks
+// that none of them became a path label
+func TestMetricsPathLabelsAreBounded(t *testing.T) {
+	metrics.SetTables(map[string][]string{"quotes": nil})
+	mux := http.NewServeMux()
+	mux.Handle("/proxy/", http.NotFoundHandler())
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {})
+	handler := MetricsMiddleware(mux)(mux)
+
+	for i := 0; i < 50; i++ {
+		for _, path := range []string{fmt.Sprintf("/random-%d", i), fmt.Sprintf("/proxy/table-%d/records", i), fmt.Sprintf("/proxy/quotes/records/%d", i)} {
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		}
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	families, err := metrics.Registry.Gather()
+	if err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+	paths := make(map[string]bool)
+	for _, family := range families {
+		if family.GetName() != "proxy_http_requests_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "path" {
+					paths[label.GetValue()] = true
+				}
+			}
+		}
+	}
+	want := map[string]bool{metrics.OtherRoute: true, "/proxy/quotes/records/:id": true, "/health": true}
+	if len(paths) != len(want) {
+		t.Errorf("path labels = %v, want only %v", paths, want)
+	}
+	for path := range want {
+		if !paths[path] {
+			t.Errorf("path label %q missing (got %v)", path, paths)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package middleware
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/httperr"
+)
+
+// IP rate limit modes (IP_RATE_LIMIT_MODE)
+const (
+	IPRateLimitEnforce = "enforce"  // requests over the limit get 429
+	IPRateLimitLogOnly = "log_only" // requests over the limit are logged and served, to size the limit first
+)
+
+// IPRateLimiter caps the requests one client IP can make per window, whoever the caller claims to be.
+// It runs before authentication as a coarse first line of defense against floods of login, signup
+// and token refresh attempts; per-user limits apply further in.
+type IPRateLimiter struct {
+	limit   int // requests per window, 0 = unlimited
+	window  time.Duration
+	logOnly bool
+	proxies TrustedProxies
+	exempt  map[string]bool // paths never limited (health probes)
+	buckets *tokenBuckets
+}
+
+// tokenBuckets keeps one token bucket per key (client IP, user ID), each holding up to limit
+// requests and refilled evenly over the window
+type tokenBuckets struct {
+	limit  int
+	window time.Duration
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+func newTokenBuckets(limit int, window time.Duration) *tokenBuckets {
+	return &tokenBuckets{
+		limit:     limit,
+		window:    window,
+		buckets:   make(map[string]*tokenBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// NewIPRateLimiter creates a limiter allowing limit requests per window from each client IP,
+// with bursts of up to limit. exempt paths (e.g. /health) are never counted.
+func NewIPRateLimiter(limit int, window time.Duration, mode string, proxies TrustedProxies, exempt ...string) *IPRateLimiter {
+	l := &IPRateLimiter{
+		limit:   limit,
+		window:  window,
+		logOnly: mode == IPRateLimitLogOnly,
+		proxies: proxies,
+		exempt:  make(map[string]bool, len(exempt)),
+		buckets: newTokenBuckets(limit, window),
+	}
+	for _, path := range exempt {
+		l.exempt[path] = true
+	}
+	return l
+}
+
+// allow spends one request of key's bucket. When it is empty, retryAfter is the wait until the next request is allowed.
+func (l *tokenBuckets) allow(key string, now time.Time) (ok bool, retryAfter time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	perRequest := l.window / time.Duration(l.limit)
+	if now.Sub(l.lastSweep) > l.window {
+		l.sweep(now)
+	}
+
+	bucket, found := l.buckets[key]
+	if !found {
+		bucket = &tokenBucket{tokens: float64(l.limit), updated: now}
+		l.buckets[key] = bucket
+	}
+	elapsed := now.Sub(bucket.updated)
+	bucket.tokens = math.Min(float64(l.limit), bucket.tokens+float64(elapsed)/float64(perRequest))
+	bucket.updated = now
+
+	if bucket.tokens < 1 {
+		return false, time.Duration((1 - bucket.tokens) * float64(perRequest))
+	}
+	bucket.tokens--
+	return true, 0
+}
+
+// sweep drops buckets that have refilled completely, keeping the map as small as the set of active clients
+func (l *tokenBuckets) sweep(now time.Time) {
+	for key, bucket := range l.buckets {
+		if now.Sub(bucket.updated) >= l.window {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// Middleware rejects requests with 429 and Retry-After once the client IP has used its limit.
+// It must run outside every handler that does real work, authentication included.
+func (l *IPRateLimiter) Middleware(next http.Handler) http.Handler {
+	if l.limit <= 0 || l.window <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if l.exempt[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ip := l.proxies.ClientIP(r)
+		ok, retryAfter := l.buckets.allow(ip, time.Now())
+		if ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if l.logOnly {
+			log.Printf("[RATE LIMIT] %s is over %d requests per %v - serving %s %s (log_only)", ip, l.limit, l.window, r.Method, r.URL.Path)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		log.Printf("[RATE LIMIT] %s is over %d requests per %v - rejecting %s %s", ip, l.limit, l.window, r.Method, r.URL.Path)
+		setRetryAfter(w, retryAfter)
+		httperr.WriteError(w, httperr.RateLimited, "too many requests from this address")
+	})
+}
+
+// UserRateLimiter caps the requests one authenticated user can make per window to the endpoints
+// it wraps, for endpoints cheap enough to call in a loop but backed by the database
+type UserRateLimiter struct {
+	limit   int // requests per window, 0 = unlimited
+	window  time.Duration
+	buckets *tokenBuckets
+}
+
+// NewUserRateLimiter creates a limiter allowing limit requests per window from each user, with
+// bursts of up to limit
+func NewUserRateLimiter(limit int, window time.Duration) *UserRateLimiter {
+	return &UserRateLimiter{limit: limit, window: window, buckets: newTokenBuckets(limit, window)}
+}
+
+// Middleware rejects requests with 429 and Retry-After once the user has used its limit.
+// It must run after AuthMiddleware, which puts the user ID in the context.
+func (l *UserRateLimiter) Middleware(next http.Handler) http.Handler {
+	if l.limit <= 0 || l.window <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := r.Context().Value(UserIDKey).(string)
+		if userID == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if ok, retryAfter := l.buckets.allow(userID, time.Now()); !ok {
+			log.Printf("[RATE LIMIT] User %s is over %d requests per %v - rejecting %s %s", userID, l.limit, l.window, r.Method, r.URL.Path)
+			setRetryAfter(w, retryAfter)
+			httperr.WriteError(w, httperr.UserRateLimited, "too many requests for this user")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// setRetryAfter sets Retry-After to wait rounded up to whole seconds, at least one
+func setRetryAfter(w http.ResponseWriter, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+}
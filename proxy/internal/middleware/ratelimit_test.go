@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketsRefill(t *testing.T) {
+	buckets := newTokenBuckets(2, time.Minute)
+	now := time.Now()
+	for i := 0; i < 2; i++ {
+		if ok, _ := buckets.allow("a", now); !ok {
+			t.Fatalf("request %d rejected within the limit", i+1)
+		}
+	}
+	ok, retryAfter := buckets.allow("a", now)
+	if ok || retryAfter != 30*time.Second {
+		t.Errorf("over the limit: ok %v, retryAfter %v; want rejected for 30s", ok, retryAfter)
+	}
+	if ok, _ := buckets.allow("b", now); !ok {
+		t.Error("another key shares the bucket")
+	}
+	if ok, _ := buckets.allow("a", now.Add(30*time.Second)); !ok {
+		t.Error("bucket didn't refill")
+	}
+}
+
+func TestUserRateLimiter(t *testing.T) {
+	limiter := NewUserRateLimiter(2, time.Minute)
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	request := func(userID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/users/display?ids=1", nil)
+		req = req.WithContext(context.WithValue(req.Context(), UserIDKey, userID))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := 0; i < 2; i++ {
+		if rec := request("7"); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: status %d", i+1, rec.Code)
+		}
+	}
+	rec := request("7")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "30" {
+		t.Errorf("over the limit: status %d, Retry-After %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if rec := request("8"); rec.Code != http.StatusOK {
+		t.Errorf("other user: status %d, want 200", rec.Code)
+	}
+}
+
+func TestIPRateLimiter(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		mode     string
+		wantOver int
+	}{
+		{"enforce", IPRateLimitEnforce, http.StatusTooManyRequests},
+		{"log only", IPRateLimitLogOnly, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := NewIPRateLimiter(3, time.Minute, tt.mode, proxies, "/health")
+			handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			request := func(path, remote, forwardedFor string) *httptest.ResponseRecorder {
+				req := httptest.NewRequest(http.MethodPost, path, nil)
+				req.RemoteAddr = remote + ":4321"
+				if forwardedFor != "" {
+					req.Header.Set("X-Forwarded-For", forwardedFor)
+				}
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, req)
+				return rec
+			}
+
+			// Two clients behind the trusted proxy and one connecting directly
+			for i := 0; i < 3; i++ {
+				if rec := request("/login", "10.0.0.1", "203.0.113.5"); rec.Code != http.StatusOK {
+					t.Fatalf("request %d: status %d", i+1, rec.Code)
+				}
+			}
+			rec := request("/login", "10.0.0.1", "203.0.113.5")
+			if rec.Code != tt.wantOver {
+				t.Fatalf("over the limit: status %d, want %d", rec.Code, tt.wantOver)
+			}
+			if tt.mode == IPRateLimitEnforce && rec.Header().Get("Retry-After") != "20" {
+				t.Errorf("Retry-After = %q, want 20", rec.Header().Get("Retry-After"))
+			}
+			if rec := request("/login", "10.0.0.1", "203.0.113.6"); rec.Code != http.StatusOK {
+				t.Errorf("another client behind the proxy: status %d", rec.Code)
+			}
+			if rec := request("/health", "10.0.0.1", "203.0.113.5"); rec.Code != http.StatusOK {
+				t.Errorf("exempt path: status %d", rec.Code)
+			}
+		})
+	}
+}
+
+// A client can't dodge the limit by sending its own X-Forwarded-For
+func TestIPRateLimiterIgnoresSpoofedForwarding(t *testing.T) {
+	limiter := NewIPRateLimiter(1, time.Minute, IPRateLimitEnforce, nil)
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for i, forwardedFor := range []string{"198.51.100.1", "198.51.100.2"} {
+		req := httptest.NewRequest(http.MethodPost, "/signup", nil)
+		req.RemoteAddr = "203.0.113.5:4321"
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if want := []int{http.StatusOK, http.StatusTooManyRequests}[i]; rec.Code != want {
+			t.Errorf("request %d: status %d, want %d", i+1, rec.Code, want)
+		}
+	}
+}
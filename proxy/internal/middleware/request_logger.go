@@ -46,7 +46,8 @@ func RequestLoggerMiddleware(next http.Handler) http.Handler {
 		}
 
 		// Log incoming request
-		logger.Info("[REQUEST] %s %s from %s | User-Agent: %s",
+		logger.InfoWith(logger.Fields{"method": r.Method, "path": r.URL.Path, "ip": clientIP, "user_agent": r.Header.Get("User-Agent")},
+			"[REQUEST] %s %s from %s | User-Agent: %s",
 			r.Method,
 			r.URL.Path,
 			clientIP,
@@ -70,10 +71,18 @@ func RequestLoggerMiddleware(next http.Handler) http.Handler {
 
 		// Calculate request duration
 		duration := time.Since(startTime)
+		fields := logger.Fields{
+			"method":      r.Method,
+			"path":        r.URL.Path,
+			"status":      wrapped.statusCode,
+			"duration_ms": float64(duration.Microseconds()) / 1000,
+			"bytes":       wrapped.written,
+			"ip":          clientIP,
+		}
 
 		// Log response details
 		if wrapped.statusCode >= 200 && wrapped.statusCode < 300 {
-			logger.Info("[RESPONSE] %s %s | Status: %d | Duration: %v | Bytes: %d | IP: %s",
+			logger.InfoWith(fields, "[RESPONSE] %s %s | Status: %d | Duration: %v | Bytes: %d | IP: %s",
 				r.Method,
 				r.URL.Path,
 				wrapped.statusCode,
@@ -82,7 +91,7 @@ func RequestLoggerMiddleware(next http.Handler) http.Handler {
 				clientIP,
 			)
 		} else if wrapped.statusCode >= 400 && wrapped.statusCode < 500 {
-			logger.Error("[RESPONSE] %s %s | Status: %d (Client Error) | Duration: %v | IP: %s",
+			logger.ErrorWith(fields, "[RESPONSE] %s %s | Status: %d (Client Error) | Duration: %v | IP: %s",
 				r.Method,
 				r.URL.Path,
 				wrapped.statusCode,
@@ -90,7 +99,7 @@ func RequestLoggerMiddleware(next http.Handler) http.Handler {
 				clientIP,
 			)
 		} else if wrapped.statusCode >= 500 {
-			logger.Error("[RESPONSE] %s %s | Status: %d (Server Error) | Duration: %v | IP: %s",
+			logger.ErrorWith(fields, "[RESPONSE] %s %s | Status: %d (Server Error) | Duration: %v | IP: %s",
 				r.Method,
 				r.URL.Path,
 				wrapped.statusCode,
@@ -101,7 +110,7 @@ func RequestLoggerMiddleware(next http.Handler) http.Handler {
 
 		// Log slow requests (> 1 second)
 		if duration > time.Second {
-			logger.Error("[SLOW REQUEST] %s %s took %v | IP: %s",
+			logger.ErrorWith(fields, "[SLOW REQUEST] %s %s took %v | IP: %s",
 				r.Method,
 				r.URL.Path,
 				duration,
@@ -116,7 +125,13 @@ func ErrorLoggerMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
-				logger.Error("[PANIC] %s %s | Error: %v | IP: %s",
+				// Handlers abort responses that already started; let net/http close the connection
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+
+				logger.ErrorWith(logger.Fields{"method": r.Method, "path": r.URL.Path, "panic": fmt.Sprint(err), "ip": r.RemoteAddr},
+					"[PANIC] %s %s | Error: %v | IP: %s",
 					r.Method,
 					r.URL.Path,
 					err,
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/grove/generic-proxy/internal/logger"
+)
+
+func TestRequestLoggerJSONFields(t *testing.T) {
+	dir := t.TempDir()
+	if err := logger.InitializeWithFormat(dir, logger.FormatJSON); err != nil {
+		t.Fatalf("InitializeWithFormat: %v", err)
+	}
+	t.Cleanup(func() { logger.Close() })
+
+	handler := RequestLoggerMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("missing"))
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/proxy/quotes/records", nil)
+	req.Header.Set("X-Real-IP", "203.0.113.9")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.log"))
+	if len(files) != 1 {
+		t.Fatalf("log files = %v, want one", files)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("read log file: %v", err)
+	}
+	var response map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("line %q is not JSON: %v", line, err)
+		}
+		if message, _ := entry["message"].(string); strings.HasPrefix(message, "[RESPONSE]") {
+			response = entry
+		}
+	}
+	if response == nil {
+		t.Fatalf("no response entry in %s", data)
+	}
+	want := map[string]interface{}{"method": "GET", "path": "/proxy/quotes/records", "status": float64(404), "bytes": float64(7), "ip": "203.0.113.9", "level": "error"}
+	for key, value := range want {
+		if response[key] != value {
+			t.Errorf("%s = %v, want %v", key, response[key], value)
+		}
+	}
+	if _, ok := response["duration_ms"].(float64); !ok {
+		t.Errorf("duration_ms = %v, want a number", response["duration_ms"])
+	}
+}
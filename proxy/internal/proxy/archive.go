@@ -0,0 +1,60 @@
+package proxy
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/grove/generic-proxy/internal/httperr"
+	"github.com/grove/generic-proxy/internal/logger"
+	"github.com/grove/generic-proxy/internal/middleware"
+)
+
+// includeArchivedParam is the query flag an admin sends to see archived records; it never reaches NocoDB
+const includeArchivedParam = "include_archived"
+
+// archiveFilter is the NocoDB where clause hiding records whose archive checkbox is set.
+// neq also matches empty checkboxes, so records that were never archived stay visible.
+func archiveFilter(field string) string {
+	return fmt.Sprintf("(%s,neq,true)", field)
+}
+
+// injectArchiveFilter adds the archive filter to the query, combined with the client's own ?where= if any
+func injectArchiveFilter(query url.Values, field string) {
+	filter := archiveFilter(field)
+	if where := query.Get("where"); where != "" {
+		filter = "(" + where + ")~and" + filter
+	}
+	query.Set("where", filter)
+}
+
+// filterArchived hides archived records from a list read unless an admin asks for them with
+// ?include_archived=true. It reports false once it has rejected the request.
+func filterArchived(w http.ResponseWriter, r *http.Request, pathParts []string, archiveField string) bool {
+	if r.Method != http.MethodGet || !isRecordListPath(pathParts) || archiveField == "" {
+		return true
+	}
+	query := r.URL.Query()
+	includeArchived := query.Get(includeArchivedParam) == "true"
+	query.Del(includeArchivedParam)
+	if includeArchived {
+		if role, _ := r.Context().Value(middleware.RoleKey).(string); role != "admin" {
+			log.Printf("[PROXY ERROR] Non-admin asked for archived records (role: %s)", role)
+			httperr.WriteError(w, httperr.OperationNotAllowed, "include_archived requires the admin role")
+			return false
+		}
+		logger.Debug("[PROXY] Including archived records")
+	} else {
+		// An unbalanced where could close the group early and move its conditions out of the filter
+		if !balancedWhere(query.Get("where")) {
+			log.Printf("[PROXY ERROR] Rejected where with unbalanced parentheses: %s", query.Get("where"))
+			httperr.WriteError(w, httperr.InvalidWhere, "where has unbalanced parentheses")
+			return false
+		}
+		injectArchiveFilter(query, archiveField)
+		logger.Debug("[PROXY] Injected archive filter: %s", query.Get("where"))
+	}
+	r.URL.RawQuery = query.Encode()
+	return true
+}
@@ -0,0 +1,72 @@
+package proxy
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/httperr"
+)
+
+// archivedQuotes serves quotes with the archive checkbox "Archived"
+func archivedQuotes(up *fakeUpstream) *ProxyHandler {
+	table := quotesTable()
+	table.ArchiveField = "Archived"
+	return newSchemaHandler(up, map[string]config.ResolvedTable{"quotes": table})
+}
+
+func TestArchivedRecordsFilteredFromLists(t *testing.T) {
+	tests := []struct {
+		name      string
+		target    string
+		role      string
+		wantWhere string
+	}{
+		{"plain list", "/proxy/quotes/records", "user", "(Archived,neq,true)"},
+		{"client filter", "/proxy/quotes/records?where=(Status,eq,Open)", "user", "((Status,eq,Open))~and(Archived,neq,true)"},
+		{"admin without the flag", "/proxy/quotes/records", "admin", "(Archived,neq,true)"},
+		{"admin with the flag", "/proxy/quotes/records?include_archived=true", "admin", ""},
+		{"single record", "/proxy/quotes/records/5", "user", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			up := newFakeUpstream(t, jsonHandler(http.StatusOK, `{"list":[],"pageInfo":{"isLastPage":true}}`))
+			rec := serve(archivedQuotes(up), http.MethodGet, tt.target, "", "7", tt.role)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+			}
+			query := up.Requests()[0].Query
+			if got := query.Get("where"); got != tt.wantWhere {
+				t.Errorf("where = %q, want %q", got, tt.wantWhere)
+			}
+			if query.Has(includeArchivedParam) {
+				t.Errorf("%s reached NocoDB", includeArchivedParam)
+			}
+		})
+	}
+}
+
+func TestIncludeArchivedRequiresAdmin(t *testing.T) {
+	up := newFakeUpstream(t, jsonHandler(http.StatusOK, `{"list":[]}`))
+	rec := serve(archivedQuotes(up), http.MethodGet, "/proxy/quotes/records?include_archived=true", "", "7", "user")
+	if rec.Code != http.StatusForbidden || decodeError(t, rec.Body.Bytes()).Code != httperr.OperationNotAllowed {
+		t.Errorf("status = %d, body %s; want 403 operation_not_allowed", rec.Code, rec.Body)
+	}
+	if n := len(up.Requests()); n != 0 {
+		t.Errorf("NocoDB got %d requests, want none", n)
+	}
+}
+
+func TestArchiveFilterRejectsUnbalancedWhere(t *testing.T) {
+	for _, where := range []string{"x)~or(y", "(Status,eq,Open))~or((Archived,eq,true)", "((Status,eq,Open)"} {
+		up := newFakeUpstream(t, jsonHandler(http.StatusOK, `{"list":[]}`))
+		rec := serve(archivedQuotes(up), http.MethodGet, "/proxy/quotes/records?where="+url.QueryEscape(where), "", "7", "user")
+		if rec.Code != http.StatusBadRequest || decodeError(t, rec.Body.Bytes()).Code != httperr.InvalidWhere {
+			t.Errorf("where %s: status = %d, body %s; want 400 %s", where, rec.Code, rec.Body, httperr.InvalidWhere)
+		}
+		if n := len(up.Requests()); n != 0 {
+			t.Errorf("where %s: NocoDB got %d requests, want none", where, n)
+		}
+	}
+}
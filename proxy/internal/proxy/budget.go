@@ -0,0 +1,171 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultUpstreamCallBudget is the UpstreamCallBudget of a new ProxyHandler
+const DefaultUpstreamCallBudget = 100
+
+// DefaultUpstreamRetryBudget is the UpstreamRetryBudget of a new ProxyHandler
+const DefaultUpstreamRetryBudget = 10
+
+// What an upstream call is made for, as counted by the call budget
+const (
+	callRequest    = "request"     // the client's own request
+	callRetry      = "retry"       // a repeated attempt after a transient failure
+	callPagination = "pagination"  // a follow-up page of an aggregated list
+	callBulkRow    = "bulk_row"    // a single row of a refused best-effort bulk write
+	callOwnerCheck = "owner_check" // a record read to check its owner before an update or delete
+)
+
+// BudgetExhaustedHeader is set on responses completed after a call was refused for lack of budget
+const BudgetExhaustedHeader = "X-Proxy-Budget-Exhausted"
+
+// truncatedBudget is the truncation reason of a list cut short by the call budget
+const truncatedBudget = "upstream_call_budget"
+
+// ErrBudgetExhausted is returned instead of calling NocoDB once a request has spent its call budget
+var ErrBudgetExhausted = errors.New("upstream call budget exhausted")
+
+// callBudget limits the NocoDB calls made on behalf of one client request. Retries, pagination
+// and bulk row retries each look reasonable alone but multiply; the budget caps their product.
+// Retries of every stage (the request, pagination pages, owner checks) also share retryLimit, so
+// a struggling NocoDB makes the request fail fast instead of retrying each call in turn.
+type callBudget struct {
+	limit      int // 0 = unlimited, calls are still counted
+	retryLimit int // retries among those calls, 0 = unlimited
+
+	mu               sync.Mutex
+	used             int
+	byFeature        map[string]int
+	exhausted        bool // a call was refused
+	retriesExhausted bool // a retry was refused for retryLimit
+}
+
+type callBudgetKey struct{}
+
+// newCallBudget creates a budget of limit calls (0 = unlimited)
+func newCallBudget(limit int) *callBudget {
+	return &callBudget{limit: limit, byFeature: make(map[string]int)}
+}
+
+// withCallBudget attaches a budget to a request context; every upstream call made with the context draws from it
+func withCallBudget(ctx context.Context, budget *callBudget) context.Context {
+	return context.WithValue(ctx, callBudgetKey{}, budget)
+}
+
+// callBudgetFrom returns the budget of a request context, or nil if it has none
+func callBudgetFrom(ctx context.Context) *callBudget {
+	budget, _ := ctx.Value(callBudgetKey{}).(*callBudget)
+	return budget
+}
+
+// take spends one call on feature, reporting false (and marking the budget exhausted) when none is left.
+// A nil budget allows every call.
+func (b *callBudget) take(feature string) bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.limit > 0 && b.used >= b.limit {
+		b.exhausted = true
+		return false
+	}
+	if feature == callRetry && b.retryLimit > 0 && b.byFeature[callRetry] >= b.retryLimit {
+		// Not a lack of calls: the failed attempt's result stands, nothing is marked budget_exhausted
+		if !b.retriesExhausted {
+			log.Printf("[BUDGET] Retry budget of %d spent, failing fast", b.retryLimit)
+		}
+		b.retriesExhausted = true
+		return false
+	}
+	b.used++
+	b.byFeature[feature]++
+	return true
+}
+
+// Exhausted reports whether a call was refused for lack of budget
+func (b *callBudget) Exhausted() bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.exhausted
+}
+
+// usage lists the calls per feature, most first: "pagination 97, retry 2, request 1"
+func (b *callBudget) usage() (top string, summary string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	features := make([]string, 0, len(b.byFeature))
+	for feature := range b.byFeature {
+		features = append(features, feature)
+	}
+	sort.Slice(features, func(i, j int) bool {
+		if b.byFeature[features[i]] != b.byFeature[features[j]] {
+			return b.byFeature[features[i]] > b.byFeature[features[j]]
+		}
+		return features[i] < features[j]
+	})
+	parts := make([]string, len(features))
+	for i, feature := range features {
+		parts[i] = fmt.Sprintf("%s %d", feature, b.byFeature[feature])
+	}
+	if len(features) > 0 {
+		top = features[0]
+	}
+	return top, strings.Join(parts, ", ")
+}
+
+// reportBudget logs a request that ran out of upstream calls and counts it under the feature
+// that used the most of them
+func (p *ProxyHandler) reportBudget(r *http.Request, budget *callBudget) {
+	if !budget.Exhausted() {
+		return
+	}
+	top, summary := budget.usage()
+	log.Printf("[BUDGET] %s %s exhausted its upstream call budget of %d (%s); %s used the most", r.Method, r.URL.Path, budget.limit, summary, top)
+
+	p.budgetMu.Lock()
+	defer p.budgetMu.Unlock()
+	if p.budgetExhaustions == nil {
+		p.budgetExhaustions = make(map[string]int64)
+	}
+	p.budgetExhaustions[top]++
+}
+
+// BudgetExhaustions returns how many requests ran out of upstream calls, keyed by the feature that used the most
+func (p *ProxyHandler) BudgetExhaustions() map[string]int64 {
+	p.budgetMu.Lock()
+	defer p.budgetMu.Unlock()
+	counts := make(map[string]int64, len(p.budgetExhaustions))
+	for feature, count := range p.budgetExhaustions {
+		counts[feature] = count
+	}
+	return counts
+}
+
+// markBudgetExhausted adds "budget_exhausted": true to a JSON object response; other bodies are returned untouched
+func markBudgetExhausted(body []byte) ([]byte, bool, error) {
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return body, false, nil
+	}
+	envelope["budget_exhausted"] = json.RawMessage("true")
+	marked, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, false, err
+	}
+	return marked, true, nil
+}
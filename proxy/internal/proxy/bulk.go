@@ -0,0 +1,297 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/httperr"
+)
+
+// Bulk write modes (BULK_WRITES)
+const (
+	BulkWritesAtomic     = "atomic"      // one bad row fails the whole request, as NocoDB does
+	BulkWritesBestEffort = "best_effort" // write the good rows and answer 207 with per-row outcomes
+)
+
+// bulkRowResult is the outcome of one row of a best-effort bulk write
+type bulkRowResult struct {
+	Index   int                    `json:"index"`
+	Status  int                    `json:"status"`
+	Record  json.RawMessage        `json:"record,omitempty"`
+	Error   string                 `json:"error,omitempty"`
+	Code    string                 `json:"code,omitempty"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// bulkWrite tracks a best-effort bulk write: rows rejected by the proxy and rows sent upstream
+type bulkWrite struct {
+	total    int
+	accepted []int // indexes of the rows sent upstream, in order
+	rejected []bulkRowResult
+	sent     []byte // the body sent upstream, after every write transform
+	creates  bool   // a record create, whose response lists created_ids with BULK_CREATED_IDS
+}
+
+// isBulkWritePath reports whether a request may carry a bulk write: records or link writes
+func isBulkWritePath(method string, pathParts []string, isLinkPath bool) bool {
+	switch method {
+	case http.MethodPost, http.MethodPatch, http.MethodPut, http.MethodDelete:
+		return isRecordListPath(pathParts) || isLinkPath
+	}
+	return false
+}
+
+// splitBulkBody returns the rows of a JSON array body; ok is false for anything but an array of two or more
+func splitBulkBody(body []byte) (rows []json.RawMessage, ok bool) {
+	if !bytes.HasPrefix(bytes.TrimSpace(body), []byte("[")) {
+		return nil, false
+	}
+	if err := json.Unmarshal(body, &rows); err != nil || len(rows) < 2 {
+		return nil, false
+	}
+	return rows, true
+}
+
+// bulkWriteChecks are the proxy-side checks a row of a bulk record write has to pass
+type bulkWriteChecks struct {
+	tableKey       string
+	tableID        string
+	deprecations   map[string]config.FieldDeprecation
+	computedFields map[string]string
+	userFields     map[string]string
+	writableFields map[string]bool
+	fieldTitles    map[string]string // alias -> title, to report unwritable fields by alias
+}
+
+// checkBulkRow runs the proxy-side write validations on one row, returning the rejection or nil.
+// Transforms that can't fail (computed field stripping, user translation) are left to the
+// normal write pipeline, which runs on the accepted rows afterwards.
+func (p *ProxyHandler) checkBulkRow(ctx context.Context, row []byte, checks bulkWriteChecks) *bulkRowResult {
+	reject := func(code, message string, details map[string]interface{}) *bulkRowResult {
+		return &bulkRowResult{Status: httperr.Status(code), Code: code, Error: message, Details: details}
+	}
+
+	if len(checks.writableFields) > 0 {
+		if names := unwritableFields(row, checks.writableFields, checks.fieldTitles); len(names) > 0 {
+			return reject(httperr.FieldNotWritable,
+				fmt.Sprintf("fields are not writable in table '%s': %s", checks.tableKey, strings.Join(names, ", ")),
+				map[string]interface{}{"fields": names})
+		}
+	}
+	if len(checks.deprecations) > 0 {
+		if _, sunset := p.deprecatedWriteFields(row, checks.deprecations); sunset != nil {
+			date := sunset.Sunset.Format(config.SunsetDateLayout)
+			return reject(httperr.FieldSunset,
+				fmt.Sprintf("field '%s' of table '%s' was removed on %s", sunset.Name, checks.tableKey, date),
+				map[string]interface{}{"field": sunset.Name, "table": checks.tableKey, "sunset": date})
+		}
+	}
+	if len(checks.computedFields) > 0 && p.ComputedFields == ComputedFieldsReject {
+		if _, names, err := stripComputedFields(row, checks.computedFields); err == nil && len(names) > 0 {
+			return reject(httperr.ComputedFieldWrite,
+				"computed fields are read-only: "+strings.Join(names, ", "),
+				map[string]interface{}{"fields": names})
+		}
+	}
+	if len(checks.userFields) > 0 {
+		var unknownUser *unknownUserError
+		if _, err := translateUserWrites(ctx, row, checks.userFields, p.Collaborators); errors.As(err, &unknownUser) {
+			return reject(httperr.UnknownUser, unknownUser.Error(),
+				map[string]interface{}{"field": unknownUser.field, "user_id": strconv.FormatInt(unknownUser.userID, 10)})
+		}
+	}
+	if violation := p.validateSelectValues(ctx, checks.tableID, row); violation != nil {
+		return reject(httperr.InvalidSelectOption, violation.Error(),
+			map[string]interface{}{"field": violation.Field, "value": violation.Value, "allowed": violation.Allowed})
+	}
+	return nil
+}
+
+// prepareBulkWrite checks every row and returns the bulk write with the body of the accepted rows.
+// check is nil for writes without proxy-side validation (link writes).
+func (p *ProxyHandler) prepareBulkWrite(ctx context.Context, rows []json.RawMessage, check func(row []byte) *bulkRowResult) (*bulkWrite, []byte, error) {
+	bulk := &bulkWrite{total: len(rows)}
+	accepted := make([]json.RawMessage, 0, len(rows))
+	for i, row := range rows {
+		if check != nil {
+			if rejection := check(row); rejection != nil {
+				rejection.Index = i
+				bulk.rejected = append(bulk.rejected, *rejection)
+				continue
+			}
+		}
+		bulk.accepted = append(bulk.accepted, i)
+		accepted = append(accepted, row)
+	}
+	body, err := json.Marshal(accepted)
+	if err != nil {
+		return nil, nil, err
+	}
+	bulk.sent = body
+	if len(bulk.rejected) > 0 {
+		log.Printf("[BULK] Rejected %d of %d rows before forwarding", len(bulk.rejected), bulk.total)
+	}
+	return bulk, body, nil
+}
+
+// finishBulkWrite answers a best-effort bulk write some rows of which failed. When NocoDB rejected
+// the batch (it writes all rows or none) the accepted rows are retried one at a time to find out
+// which ones it takes. upstreamBody is the response to the batch request, nil if none was sent.
+func (p *ProxyHandler) finishBulkWrite(ctx context.Context, w http.ResponseWriter, proxyReq *http.Request, bulk *bulkWrite, status int, upstreamBody []byte) {
+	results := append([]bulkRowResult(nil), bulk.rejected...)
+
+	switch {
+	case len(bulk.accepted) == 0:
+		// Nothing left to send
+	case status < 400:
+		records := upstreamRowRecords(upstreamBody)
+		for i, index := range bulk.accepted {
+			result := bulkRowResult{Index: index, Status: status}
+			if i < len(records) {
+				result.Record = records[i]
+			}
+			results = append(results, result)
+		}
+	case len(bulk.accepted) == 1:
+		results = append(results, upstreamRowFailure(bulk.accepted[0], status, upstreamBody))
+	default:
+		log.Printf("[BULK] NocoDB rejected the batch (status %d), retrying %d rows one at a time", status, len(bulk.accepted))
+		var rows []json.RawMessage
+		if err := json.Unmarshal(bulk.sent, &rows); err != nil || len(rows) != len(bulk.accepted) {
+			httperr.WriteError(w, httperr.InvalidBody, "failed to split bulk body")
+			return
+		}
+		for i, index := range bulk.accepted {
+			rowStatus, rowBody, err := p.sendBulkRow(ctx, proxyReq, rows[i])
+			if errors.Is(err, ErrBudgetExhausted) {
+				results = append(results, bulkRowResult{Index: index, Status: httperr.Status(httperr.UpstreamBudgetExhausted), Code: httperr.UpstreamBudgetExhausted, Error: err.Error()})
+				continue
+			}
+			if err != nil {
+				if ctx.Err() != nil {
+					log.Printf("[BULK] Client went away after %d of %d rows", i, len(rows))
+					return
+				}
+				results = append(results, bulkRowResult{Index: index, Status: httperr.Status(httperr.UpstreamReadFailed), Code: httperr.UpstreamReadFailed, Error: err.Error()})
+				continue
+			}
+			if rowStatus >= 400 {
+				results = append(results, upstreamRowFailure(index, rowStatus, rowBody))
+				continue
+			}
+			result := bulkRowResult{Index: index, Status: rowStatus}
+			if records := upstreamRowRecords(rowBody); len(records) > 0 {
+				result.Record = records[0]
+			}
+			results = append(results, result)
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Index < results[j].Index })
+	failed := 0
+	for _, result := range results {
+		if result.Status >= 400 {
+			failed++
+		}
+	}
+	log.Printf("[BULK] Best-effort bulk write: %d of %d rows succeeded", len(results)-failed, len(results))
+
+	response := map[string]interface{}{
+		"results":   results,
+		"succeeded": len(results) - failed,
+		"failed":    failed,
+	}
+	if bulk.creates && p.BulkCreatedIDs {
+		apiVersion := detectAPIVersion(p.NocoDBURL)
+		createdIDs := make([]json.RawMessage, 0, len(results)-failed)
+		for _, result := range results {
+			if id, ok := createdRecordID(result.Record, apiVersion); ok && result.Status < 400 {
+				createdIDs = append(createdIDs, id)
+			}
+		}
+		response["created_ids"] = createdIDs
+	}
+	if callBudgetFrom(ctx).Exhausted() {
+		response["budget_exhausted"] = true
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Del("Content-Length")
+	w.WriteHeader(http.StatusMultiStatus)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("[BULK ERROR] Failed to encode response: %v", err)
+	}
+}
+
+// sendBulkRow sends one row of a bulk write as a single-element batch, with the batch request's method, URL and headers
+func (p *ProxyHandler) sendBulkRow(ctx context.Context, batch *http.Request, row json.RawMessage) (int, []byte, error) {
+	ctx, cancel := p.upstreamContext(ctx)
+	defer cancel()
+
+	body := append(append([]byte("["), row...), ']')
+	req, err := http.NewRequestWithContext(ctx, batch.Method, batch.URL.String(), bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header = batch.Header.Clone()
+	req.Header.Del("Content-Length")
+
+	resp, err := p.doUpstream(req, callBulkRow)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	return resp.StatusCode, respBody, nil
+}
+
+// upstreamRowRecords returns the per-row items of a NocoDB bulk response: a bare array, or the
+// records/list array of an envelope. Responses without rows (e.g. link writes' true) give nil.
+func upstreamRowRecords(body []byte) []json.RawMessage {
+	var rows []json.RawMessage
+	if err := json.Unmarshal(body, &rows); err == nil {
+		return rows
+	}
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil
+	}
+	for _, key := range recordListKeys {
+		if err := json.Unmarshal(envelope[key], &rows); err == nil {
+			return rows
+		}
+	}
+	return nil
+}
+
+// upstreamRowFailure describes a row NocoDB refused, keeping its error body when it is JSON
+func upstreamRowFailure(index, status int, body []byte) bulkRowResult {
+	result := bulkRowResult{Index: index, Status: status, Code: httperr.UpstreamRejected, Error: http.StatusText(status)}
+	var upstream struct {
+		Msg     string `json:"msg"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &upstream) == nil {
+		if upstream.Msg != "" {
+			result.Error = upstream.Msg
+		} else if upstream.Message != "" {
+			result.Error = upstream.Message
+		}
+	}
+	if json.Valid(body) {
+		result.Details = map[string]interface{}{"upstream_error": json.RawMessage(body)}
+	}
+	return result
+}
@@ -0,0 +1,109 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/httperr"
+)
+
+// bulkUpstream creates records the way NocoDB does, all or none: a batch containing a row with
+// Title "reject" fails as a whole with 400
+func bulkUpstream(t *testing.T) *fakeUpstream {
+	return newFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		var rows []map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&rows)
+		created := make([]map[string]interface{}, len(rows))
+		for i, row := range rows {
+			if row["Title"] == "reject" {
+				jsonHandler(http.StatusBadRequest, `{"msg":"Title must not be reject"}`)(w, r)
+				return
+			}
+			created[i] = map[string]interface{}{"Id": 100 + i}
+		}
+		body, _ := json.Marshal(created)
+		jsonHandler(http.StatusOK, string(body))(w, r)
+	})
+}
+
+// writableQuotes is quotes where writes may only set Title
+func writableQuotes(up *fakeUpstream, mode string) *ProxyHandler {
+	table := quotesTable()
+	table.WritableFields = map[string]bool{"Title": true}
+	return newSchemaHandler(up, map[string]config.ResolvedTable{"quotes": table}, func(p *ProxyHandler) { p.BulkWrites = mode })
+}
+
+type bulkResponse struct {
+	Results []struct {
+		Index  int             `json:"index"`
+		Status int             `json:"status"`
+		Code   string          `json:"code"`
+		Record json.RawMessage `json:"record"`
+	} `json:"results"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+}
+
+func TestBestEffortBulkWritePartialSuccess(t *testing.T) {
+	up := bulkUpstream(t)
+	body := `[{"Title":"a"},{"Title":"b","Secret":"x"},{"Title":"reject"},{"Title":"c"}]`
+	rec := serve(writableQuotes(up, BulkWritesBestEffort), http.MethodPost, "/proxy/quotes/records", body, "7", "user")
+	if rec.Code != http.StatusMultiStatus {
+		t.Fatalf("status = %d, body %s; want 207", rec.Code, rec.Body)
+	}
+	var response bulkResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("body %s: %v", rec.Body, err)
+	}
+	if response.Succeeded != 2 || response.Failed != 2 || len(response.Results) != 4 {
+		t.Fatalf("response = %s, want 2 of 4 rows succeeded", rec.Body)
+	}
+	want := []struct {
+		status int
+		code   string
+	}{
+		{http.StatusOK, ""},
+		{httperr.Status(httperr.FieldNotWritable), httperr.FieldNotWritable},
+		{http.StatusBadRequest, httperr.UpstreamRejected},
+		{http.StatusOK, ""},
+	}
+	for i, result := range response.Results {
+		if result.Index != i || result.Status != want[i].status || result.Code != want[i].code {
+			t.Errorf("result %d = %+v, want status %d code %q", i, result, want[i].status, want[i].code)
+		}
+	}
+	if len(response.Results[3].Record) == 0 {
+		t.Error("created record missing from a successful row")
+	}
+
+	// The rejected row never reaches NocoDB; the refused batch is retried row by row
+	requests := up.Requests()
+	if len(requests) != 4 {
+		t.Fatalf("NocoDB got %d requests, want the batch and three single rows", len(requests))
+	}
+	if strings.Contains(requests[0].Body, "Secret") {
+		t.Errorf("batch %s contains the row the proxy rejected", requests[0].Body)
+	}
+}
+
+func TestBestEffortBulkWriteAllSucceed(t *testing.T) {
+	up := bulkUpstream(t)
+	rec := serve(writableQuotes(up, BulkWritesBestEffort), http.MethodPost, "/proxy/quotes/records", `[{"Title":"a"},{"Title":"b"}]`, "7", "user")
+	if rec.Code != http.StatusOK || rec.Body.String() != `[{"Id":100},{"Id":101}]` {
+		t.Errorf("status = %d, body %s; want NocoDB's response", rec.Code, rec.Body)
+	}
+}
+
+func TestAtomicBulkWriteFailsWhole(t *testing.T) {
+	up := bulkUpstream(t)
+	rec := serve(writableQuotes(up, BulkWritesAtomic), http.MethodPost, "/proxy/quotes/records", `[{"Title":"a"},{"Title":"b","Secret":"x"}]`, "7", "user")
+	if rec.Code != httperr.Status(httperr.FieldNotWritable) || decodeError(t, rec.Body.Bytes()).Code != httperr.FieldNotWritable {
+		t.Errorf("status = %d, body %s; want the whole request rejected", rec.Code, rec.Body)
+	}
+	if n := len(up.Requests()); n != 0 {
+		t.Errorf("NocoDB got %d requests, want none", n)
+	}
+}
@@ -0,0 +1,45 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+// UpstreamClientOptions tunes the HTTP client shared by every NocoDB call of a ProxyHandler
+type UpstreamClientOptions struct {
+	Timeout             time.Duration // whole request including the body, 0 = none
+	MaxIdleConns        int           // across all hosts, 0 = unlimited
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+}
+
+// DefaultUpstreamClientOptions are used when NewProxyHandler gets no client
+var DefaultUpstreamClientOptions = UpstreamClientOptions{
+	Timeout:             DefaultUpstreamTimeout,
+	MaxIdleConns:        32,
+	MaxIdleConnsPerHost: 32,
+	IdleConnTimeout:     90 * time.Second,
+}
+
+// NewUpstreamClient builds a client with its own connection pool for NocoDB. All proxy traffic
+// goes to one host, so MaxIdleConnsPerHost (Go's default is 2) decides how many connections are reused.
+func NewUpstreamClient(opts UpstreamClientOptions) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = opts.MaxIdleConns
+	transport.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+	transport.IdleConnTimeout = opts.IdleConnTimeout
+	return &http.Client{Transport: transport, Timeout: opts.Timeout}
+}
+
+// isTimeout reports whether an upstream call failed because a deadline passed
+// (the request context's or the client's own Timeout)
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
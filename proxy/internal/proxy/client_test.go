@@ -0,0 +1,154 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/config"
+)
+
+// countingTransport counts the requests sent through it
+type countingTransport struct {
+	requests atomic.Int32
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.requests.Add(1)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// hangingHandler answers only once the request is cancelled, or after a minute
+func hangingHandler(w http.ResponseWriter, r *http.Request) {
+	select {
+	case <-r.Context().Done():
+	case <-time.After(time.Minute):
+	}
+}
+
+func TestSlowUpstreamTimesOut(t *testing.T) {
+	up := newFakeUpstream(t, hangingHandler)
+	p := newLegacyHandler(up)
+	p.SetTimeout(50 * time.Millisecond)
+
+	started := time.Now()
+	rec := serve(p, http.MethodGet, "/proxy/t1/records/1", "", "7", "user")
+	if rec.Code != http.StatusGatewayTimeout || !strings.Contains(rec.Body.String(), `"upstream_timeout"`) {
+		t.Errorf("status = %d, body %s; want 504 upstream_timeout", rec.Code, rec.Body)
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("request took %v, the timeout didn't cut it short", elapsed)
+	}
+}
+
+// UpstreamTimeout also bounds each pagination follow-up; a page that doesn't arrive in time ends the
+// merge, and the first page is returned as it was
+func TestSlowPaginationPageTimesOut(t *testing.T) {
+	for _, workers := range []int{1, DefaultPaginationWorkers} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			up := newFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Get("offset") != "" && r.URL.Query().Get("offset") != "0" {
+					hangingHandler(w, r)
+					return
+				}
+				jsonHandler(http.StatusOK, `{"list":[{"Id":1}],"pageInfo":{"page":1,"pageSize":1,"totalRows":3,"isLastPage":false}}`)(w, r)
+			})
+			p := newLegacyHandler(up)
+			p.PaginationWorkers = workers
+			p.SetTimeout(100 * time.Millisecond)
+
+			started := time.Now()
+			rec := serve(p, http.MethodGet, "/proxy/t1/records", "", "7", "user")
+			if elapsed := time.Since(started); elapsed > 5*time.Second {
+				t.Fatalf("request took %v, the page timeout didn't cut it short", elapsed)
+			}
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s; want the first page", rec.Code, rec.Body)
+			}
+			if list := decodeList(t, rec.Body.Bytes()); len(list.List) != 1 {
+				t.Errorf("got %d records, want the first page's one", len(list.List))
+			}
+			if len(up.Requests()) < 2 {
+				t.Error("no follow-up page was requested")
+			}
+		})
+	}
+}
+
+// A client that goes away cancels the NocoDB request it caused
+func TestClientDisconnectCancelsUpstream(t *testing.T) {
+	arrived, cancelled := make(chan struct{}), make(chan struct{})
+	up := newFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		close(arrived)
+		<-r.Context().Done()
+		close(cancelled)
+	})
+	p := newLegacyHandler(up)
+
+	ctx, cancel := context.WithCancel(withUser(context.Background(), "7", "user"))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/proxy/t1/records/1", nil).WithContext(ctx))
+	}()
+
+	<-arrived
+	cancel()
+	for name, ch := range map[string]chan struct{}{"upstream request cancelled": cancelled, "handler returned": done} {
+		select {
+		case <-ch:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: not within 5s of the client going away", name)
+		}
+	}
+}
+
+// Metadata, proxied requests and background fetches all go through the client given to the constructors
+func TestUpstreamClientIsShared(t *testing.T) {
+	up := newFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/meta/bases/base/tables"):
+			jsonHandler(http.StatusOK, `{"list":[{"id":"t1","title":"Quotes"}]}`)(w, r)
+		case strings.Contains(r.URL.Path, "/meta/"):
+			jsonHandler(http.StatusOK, `{"id":"t1","title":"Quotes","fields":[]}`)(w, r)
+		default:
+			jsonHandler(http.StatusOK, `{"list":[{"Id":1}],"pageInfo":{"isLastPage":true}}`)(w, r)
+		}
+	})
+	transport := &countingTransport{}
+	client := &http.Client{Transport: transport}
+
+	meta := NewMetaCache(up.URL+"/api/v2/", "base", "test-token", client)
+	if err := meta.Refresh(); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	afterMeta := transport.requests.Load()
+	if afterMeta == 0 {
+		t.Fatal("metadata refresh bypassed the shared client")
+	}
+
+	p := NewProxyHandler(up.URL+"/api/v2/tables/", "test-token", meta, client)
+	p.SetResolvedConfig(&config.ResolvedConfig{BaseID: "base", Tables: map[string]config.ResolvedTable{"quotes": quotesTable()}})
+	if rec := serve(p, http.MethodGet, "/proxy/quotes/records/1", "", "7", "user"); rec.Code != http.StatusOK {
+		t.Fatalf("proxied read: status = %d, body %s", rec.Code, rec.Body)
+	}
+	afterProxy := transport.requests.Load()
+	if afterProxy == afterMeta {
+		t.Error("proxied request bypassed the shared client")
+	}
+
+	if _, err := p.FetchRecords("quotes", ""); err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if transport.requests.Load() == afterProxy {
+		t.Error("background fetch bypassed the shared client")
+	}
+	if total := int32(len(up.Requests())); transport.requests.Load() != total {
+		t.Errorf("shared client sent %d of the %d upstream requests", transport.requests.Load(), total)
+	}
+}
@@ -0,0 +1,242 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// userFieldTypes are NocoDB column types whose values are collaborators (objects carrying an email)
+var userFieldTypes = map[string]bool{
+	"User":           true,
+	"CreatedBy":      true,
+	"LastModifiedBy": true,
+}
+
+// ProxyUser is the compact view of a proxy user that replaces a NocoDB collaborator in responses
+type ProxyUser struct {
+	UserID    int64  `json:"user_id"`
+	Name      string `json:"name"`
+	AvatarURL string `json:"avatar_url,omitempty"`
+}
+
+// CollaboratorDirectory maps NocoDB collaborators to proxy users by email, and back for writes
+type CollaboratorDirectory interface {
+	// UsersByEmail returns the proxy users with the given emails, keyed by lower-cased email
+	UsersByEmail(ctx context.Context, emails []string) (map[string]ProxyUser, error)
+	// EmailsByUserID returns the emails of the given proxy users; unknown IDs are absent
+	EmailsByUserID(ctx context.Context, ids []int64) (map[int64]string, error)
+}
+
+// unknownUserError is a write naming a proxy user ID that doesn't exist
+type unknownUserError struct {
+	field  string
+	userID int64
+}
+
+func (e *unknownUserError) Error() string {
+	return fmt.Sprintf("field '%s' references unknown user %d", e.field, e.userID)
+}
+
+// metaUserFields returns the cached collaborator fields of a table, or nil when translation is off
+func (p *ProxyHandler) metaUserFields(tableID string) map[string]string {
+	if p.Meta == nil || p.Collaborators == nil {
+		return nil
+	}
+	return p.Meta.GetUserFields(tableID)
+}
+
+// recordFields returns the field map of a record: v3 wraps fields in "fields", v2 records are flat
+func recordFields(record map[string]interface{}) map[string]interface{} {
+	if fields, ok := record["fields"].(map[string]interface{}); ok {
+		return fields
+	}
+	return record
+}
+
+// collaborators returns the collaborator objects of a user field value (a single object or an array)
+func collaborators(value interface{}) []map[string]interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return []map[string]interface{}{v}
+	case []interface{}:
+		var objects []map[string]interface{}
+		for _, item := range v {
+			if object, ok := item.(map[string]interface{}); ok {
+				objects = append(objects, object)
+			}
+		}
+		return objects
+	}
+	return nil
+}
+
+// mapCollaborators rebuilds a user field value, keeping its single/array shape
+func mapCollaborators(value interface{}, fn func(map[string]interface{}) interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return fn(v)
+	case []interface{}:
+		mapped := make([]interface{}, len(v))
+		for i, item := range v {
+			mapped[i] = item
+			if object, ok := item.(map[string]interface{}); ok {
+				mapped[i] = fn(object)
+			}
+		}
+		return mapped
+	}
+	return value
+}
+
+// translateCollaborators replaces the collaborators in user fields of a list or single-record response
+// with proxy users matched by email. Collaborators without a proxy account keep their NocoDB object,
+// marked "external": true.
+func translateCollaborators(ctx context.Context, body []byte, userFields map[string]string, directory CollaboratorDirectory) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber() // keep large numeric IDs exact
+	var envelope map[string]interface{}
+	if err := decoder.Decode(&envelope); err != nil {
+		return body, nil
+	}
+
+	var records []map[string]interface{}
+	listKey := ""
+	for _, key := range recordListKeys {
+		if list, ok := envelope[key].([]interface{}); ok {
+			listKey = key
+			for _, item := range list {
+				if record, ok := item.(map[string]interface{}); ok {
+					records = append(records, recordFields(record))
+				}
+			}
+			break
+		}
+	}
+	if listKey == "" {
+		records = []map[string]interface{}{recordFields(envelope)} // single record
+	}
+
+	seen := make(map[string]bool)
+	var emails []string
+	for _, fields := range records {
+		for title := range userFields {
+			for _, collaborator := range collaborators(fields[title]) {
+				if email, ok := collaborator["email"].(string); ok && !seen[strings.ToLower(email)] {
+					seen[strings.ToLower(email)] = true
+					emails = append(emails, email)
+				}
+			}
+		}
+	}
+	if len(emails) == 0 {
+		return body, nil
+	}
+
+	users, err := directory.UsersByEmail(ctx, emails)
+	if err != nil {
+		return nil, err
+	}
+
+	translate := func(collaborator map[string]interface{}) interface{} {
+		email, _ := collaborator["email"].(string)
+		if user, ok := users[strings.ToLower(email)]; ok {
+			return user
+		}
+		collaborator["external"] = true
+		return collaborator
+	}
+	for _, fields := range records {
+		for title := range userFields {
+			if value, ok := fields[title]; ok && value != nil {
+				fields[title] = mapCollaborators(value, translate)
+			}
+		}
+	}
+	return json.Marshal(envelope)
+}
+
+// translateUserWrites replaces {"user_id": n} values of writable user fields in a write body with
+// {"email": ...}, the form NocoDB expects. Other values (e.g. plain emails) are left alone.
+// Returns an *unknownUserError when an ID has no proxy account.
+func translateUserWrites(ctx context.Context, body []byte, userFields map[string]string, directory CollaboratorDirectory) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return body, nil
+	}
+
+	var records []map[string]interface{}
+	items, isList := doc.([]interface{})
+	if !isList {
+		items = []interface{}{doc}
+	}
+	for _, item := range items {
+		if record, ok := item.(map[string]interface{}); ok {
+			records = append(records, recordFields(record))
+		}
+	}
+
+	// userIDOf reads the user_id of a collaborator object written by a client
+	userIDOf := func(collaborator map[string]interface{}) (int64, bool) {
+		number, ok := collaborator["user_id"].(json.Number)
+		if !ok {
+			return 0, false
+		}
+		id, err := number.Int64()
+		return id, err == nil
+	}
+
+	var ids []int64
+	for _, fields := range records {
+		for title, fieldType := range userFields {
+			if fieldType != "User" {
+				continue // CreatedBy/LastModifiedBy are computed upstream
+			}
+			for _, collaborator := range collaborators(fields[title]) {
+				if id, ok := userIDOf(collaborator); ok {
+					ids = append(ids, id)
+				}
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return body, nil
+	}
+
+	emails, err := directory.EmailsByUserID(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	var unknown *unknownUserError
+	for _, fields := range records {
+		for title, fieldType := range userFields {
+			value, ok := fields[title]
+			if !ok || fieldType != "User" {
+				continue
+			}
+			fields[title] = mapCollaborators(value, func(collaborator map[string]interface{}) interface{} {
+				id, ok := userIDOf(collaborator)
+				if !ok {
+					return collaborator
+				}
+				email, ok := emails[id]
+				if !ok {
+					if unknown == nil {
+						unknown = &unknownUserError{field: title, userID: id}
+					}
+					return collaborator
+				}
+				return map[string]interface{}{"email": email}
+			})
+		}
+	}
+	if unknown != nil {
+		return nil, unknown
+	}
+	return json.Marshal(doc)
+}
@@ -0,0 +1,77 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// CommentCounter returns the number of comments per record ID of a table
+type CommentCounter func(ctx context.Context, tableKey string, recordIDs []string) (map[string]int, error)
+
+// addCommentCounts sets "comment_count" on each record of a list or single-record response
+func addCommentCounts(ctx context.Context, body []byte, tableKey, apiVersion string, counter CommentCounter) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber() // keep large numeric IDs exact
+	var envelope map[string]interface{}
+	if err := decoder.Decode(&envelope); err != nil {
+		return body, nil
+	}
+
+	var records []map[string]interface{}
+	listKey := ""
+	for _, key := range recordListKeys {
+		if list, ok := envelope[key].([]interface{}); ok {
+			listKey = key
+			for _, item := range list {
+				if record, ok := item.(map[string]interface{}); ok {
+					records = append(records, record)
+				}
+			}
+			break
+		}
+	}
+	if listKey == "" {
+		records = []map[string]interface{}{envelope} // single record
+	}
+
+	ids := make([]string, 0, len(records))
+	for _, record := range records {
+		if id, ok := recordIDString(record, apiVersion); ok {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return body, nil
+	}
+
+	counts, err := counter(ctx, tableKey, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		if id, ok := recordIDString(record, apiVersion); ok {
+			record["comment_count"] = counts[id]
+		}
+	}
+	return json.Marshal(envelope)
+}
+
+// recordIDString returns NocoDB's record id (v3 "id", v2 "Id") as text
+func recordIDString(record map[string]interface{}, apiVersion string) (string, bool) {
+	idField := "id"
+	if apiVersion == "v2" {
+		idField = "Id"
+	}
+	switch id := record[idField].(type) {
+	case string:
+		return id, true
+	case json.Number:
+		return id.String(), true
+	case nil:
+		return "", false
+	default:
+		return fmt.Sprint(id), true
+	}
+}
@@ -0,0 +1,86 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+)
+
+// Computed field modes (COMPUTED_FIELDS)
+const (
+	ComputedFieldsStrip  = "strip"  // drop computed fields from write bodies and list them in X-Proxy-Stripped-Fields
+	ComputedFieldsReject = "reject" // reject writes that contain computed fields
+)
+
+// computedFieldTypes are NocoDB column types whose values are computed upstream and can't be written
+var computedFieldTypes = map[string]bool{
+	"Formula":          true,
+	"Rollup":           true,
+	"Lookup":           true,
+	"CreatedTime":      true,
+	"LastModifiedTime": true,
+	"CreatedBy":        true,
+	"LastModifiedBy":   true,
+	"Barcode":          true,
+	"QrCode":           true,
+}
+
+// metaComputedFields returns the cached computed fields for a table, if MetaCache is available
+func (p *ProxyHandler) metaComputedFields(tableID string) map[string]string {
+	if p.Meta == nil {
+		return nil
+	}
+	return p.Meta.GetComputedFields(tableID)
+}
+
+// stripComputedFields removes computed fields from a write body (object, array, or v3 "fields" wrappers)
+// and returns the rewritten body with the sorted names of the removed fields. The body is returned
+// untouched when nothing was removed or it isn't JSON.
+func stripComputedFields(body []byte, computed map[string]string) ([]byte, []string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber() // keep large numbers exact when re-encoding
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return body, nil, nil
+	}
+
+	stripped := make(map[string]bool)
+	strip := func(item interface{}) {
+		record, ok := item.(map[string]interface{})
+		if !ok {
+			return
+		}
+		if fields, ok := record["fields"].(map[string]interface{}); ok {
+			record = fields
+		}
+		for name := range record {
+			if _, ok := computed[name]; ok {
+				delete(record, name)
+				stripped[name] = true
+			}
+		}
+	}
+
+	if items, ok := doc.([]interface{}); ok {
+		for _, item := range items {
+			strip(item)
+		}
+	} else {
+		strip(doc)
+	}
+	if len(stripped) == 0 {
+		return body, nil, nil
+	}
+
+	names := make([]string, 0, len(stripped))
+	for name := range stripped {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rewritten, err := json.Marshal(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rewritten, names, nil
+}
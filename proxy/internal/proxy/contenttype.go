@@ -0,0 +1,42 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+)
+
+// JSON content type modes (JSON_CONTENT_TYPE)
+const (
+	JSONContentTypePassthrough = "passthrough" // relay NocoDB's Content-Type
+	JSONContentTypeCanonical   = "canonical"   // JSON bodies always get CanonicalJSONContentType
+)
+
+// CanonicalJSONContentType is the Content-Type of JSON responses with JSON_CONTENT_TYPE=canonical
+const CanonicalJSONContentType = "application/json; charset=utf-8"
+
+// canonicalizeJSONType sets the canonical Content-Type on a buffered response whose body is JSON,
+// whatever NocoDB declared; other bodies keep their type
+func (p *ProxyHandler) canonicalizeJSONType(header http.Header, body []byte) {
+	if p.JSONContentType == JSONContentTypeCanonical && json.Valid(body) {
+		header.Set("Content-Type", CanonicalJSONContentType)
+	}
+}
+
+// canonicalizeStreamedJSONType is canonicalizeJSONType for a streamed response. Bodies that fit the
+// peek window are validated whole; longer ones count as JSON when they open an object or array.
+func (p *ProxyHandler) canonicalizeStreamedJSONType(header http.Header, body *bufio.Reader) {
+	if p.JSONContentType != JSONContentTypeCanonical {
+		return
+	}
+	prefix, err := body.Peek(paginationPeekBytes)
+	if err != nil {
+		// a shorter prefix means the whole body was read
+		p.canonicalizeJSONType(header, prefix)
+		return
+	}
+	if trimmed := bytes.TrimLeft(prefix, " \t\r\n"); len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		header.Set("Content-Type", CanonicalJSONContentType)
+	}
+}
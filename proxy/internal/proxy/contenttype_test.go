@@ -0,0 +1,44 @@
+package proxy
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalJSONContentType(t *testing.T) {
+	large := `{"list":[` + strings.Repeat(`{"Id":1},`, 10000) + `{"Id":2}],"pageInfo":{"isLastPage":true}}`
+	tests := []struct {
+		name        string
+		mode        string
+		target      string
+		contentType string
+		body        string
+		want        string
+	}{
+		{"record", JSONContentTypeCanonical, "/proxy/quotes/records/1", "text/plain", `{"Id":1}`, CanonicalJSONContentType},
+		{"list", JSONContentTypeCanonical, "/proxy/quotes/records", "text/html", `{"list":[],"pageInfo":{"isLastPage":true}}`, CanonicalJSONContentType},
+		{"json without charset", JSONContentTypeCanonical, "/proxy/quotes/records/1", "application/json", `{"Id":1}`, CanonicalJSONContentType},
+		{"large streamed list", JSONContentTypeCanonical, "/proxy/quotes/records?limit=10001", "text/plain", large, CanonicalJSONContentType},
+		{"not json", JSONContentTypeCanonical, "/proxy/quotes/records/1", "text/plain", `Record 1`, "text/plain"},
+		{"passthrough", JSONContentTypePassthrough, "/proxy/quotes/records/1", "text/plain", `{"Id":1}`, "text/plain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			up := newFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.Write([]byte(tt.body))
+			})
+			p := newLegacyHandler(up)
+			p.JSONContentType = tt.mode
+
+			rec := serve(p, http.MethodGet, tt.target, "", "7", "user")
+			if rec.Code != http.StatusOK || rec.Body.String() != tt.body {
+				t.Fatalf("status = %d, body of %d bytes; want NocoDB's body", rec.Code, rec.Body.Len())
+			}
+			if got := rec.Header().Get("Content-Type"); got != tt.want {
+				t.Errorf("Content-Type = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package proxy
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/httperr"
+)
+
+// cooldownSweepInterval is how often expired cooldowns are dropped
+const cooldownSweepInterval = time.Minute
+
+// WriteCooldowns enforces the write_cooldown of tables: the minimum time between two writes of
+// one user to one table
+type WriteCooldowns struct {
+	mu        sync.Mutex
+	until     map[cooldownKey]time.Time // when the user may write to the table again
+	lastSweep time.Time
+}
+
+// cooldownKey identifies a user's writes to a table
+type cooldownKey struct {
+	table  string
+	userID string
+}
+
+// NewWriteCooldowns creates an empty cooldown tracker
+func NewWriteCooldowns() *WriteCooldowns {
+	return &WriteCooldowns{until: make(map[cooldownKey]time.Time), lastSweep: time.Now()}
+}
+
+// allow records a write of userID to table unless the previous one was less than cooldown ago;
+// then retryAfter is the wait until the next write is allowed
+func (c *WriteCooldowns) allow(table, userID string, cooldown time.Duration, now time.Time) (ok bool, retryAfter time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now.Sub(c.lastSweep) > cooldownSweepInterval {
+		for key, until := range c.until {
+			if !until.After(now) {
+				delete(c.until, key)
+			}
+		}
+		c.lastSweep = now
+	}
+
+	key := cooldownKey{table: table, userID: userID}
+	if until, found := c.until[key]; found && until.After(now) {
+		return false, until.Sub(now)
+	}
+	c.until[key] = now.Add(cooldown)
+	return true, 0
+}
+
+// retryAfterSeconds rounds a wait up to the whole seconds of a Retry-After header, at least 1
+func retryAfterSeconds(wait time.Duration) int {
+	seconds := int(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	return seconds
+}
+
+// checkWriteCooldown answers a write of the user to a table within its write_cooldown with 429 and
+// Retry-After. It reports whether the write may go ahead.
+func (p *ProxyHandler) checkWriteCooldown(w http.ResponseWriter, r *http.Request, table, userID string, cooldown time.Duration) bool {
+	if cooldown <= 0 || p.WriteCooldowns == nil || r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return true
+	}
+	ok, retryAfter := p.WriteCooldowns.allow(table, userID, cooldown, time.Now())
+	if !ok {
+		seconds := retryAfterSeconds(retryAfter)
+		log.Printf("[PROXY ERROR] Write of user %s to '%s' within its %v cooldown, retry in %ds", userID, table, cooldown, seconds)
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		httperr.WriteErrorParams(w, httperr.WriteCooldown, "writes to table '"+table+"' are limited to one per "+cooldown.String(),
+			map[string]string{"table": table, "cooldown": cooldown.String()})
+	}
+	return ok
+}
@@ -0,0 +1,64 @@
+package proxy
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/httperr"
+)
+
+func TestWriteCooldownThrottlesBackToBackWrites(t *testing.T) {
+	up := newFakeUpstream(t, jsonHandler(http.StatusOK, `{"Id":1}`))
+	table := quotesTable()
+	table.WriteCooldown = time.Minute
+	p := newSchemaHandler(up, map[string]config.ResolvedTable{"quotes": table})
+	p.WriteCooldowns = NewWriteCooldowns()
+
+	if rec := serve(p, http.MethodPost, "/proxy/quotes/records", `{"Title":"a"}`, "7", "user"); rec.Code != http.StatusOK {
+		t.Fatalf("first write: status = %d, body %s", rec.Code, rec.Body)
+	}
+	rec := serve(p, http.MethodPatch, "/proxy/quotes/records", `{"Id":1,"Title":"b"}`, "7", "user")
+	if rec.Code != http.StatusTooManyRequests || decodeError(t, rec.Body.Bytes()).Code != httperr.WriteCooldown {
+		t.Errorf("second write: status = %d, body %s; want 429 %s", rec.Code, rec.Body, httperr.WriteCooldown)
+	}
+	if retryAfter := rec.Header().Get("Retry-After"); retryAfter != "60" {
+		t.Errorf("Retry-After = %q, want 60", retryAfter)
+	}
+
+	if rec := serve(p, http.MethodGet, "/proxy/quotes/records/1", "", "7", "user"); rec.Code != http.StatusOK {
+		t.Errorf("read: status = %d, want reads not throttled", rec.Code)
+	}
+	if rec := serve(p, http.MethodPost, "/proxy/quotes/records", `{"Title":"c"}`, "8", "user"); rec.Code != http.StatusOK {
+		t.Errorf("another user's write: status = %d, want it allowed", rec.Code)
+	}
+	if n := len(up.Requests()); n != 3 {
+		t.Errorf("NocoDB got %d requests, want 3", n)
+	}
+}
+
+func TestWriteCooldownExpires(t *testing.T) {
+	c := NewWriteCooldowns()
+	now := time.Now()
+	if ok, _ := c.allow("quotes", "7", 10*time.Second, now); !ok {
+		t.Fatal("first write throttled")
+	}
+	if ok, retryAfter := c.allow("quotes", "7", 10*time.Second, now.Add(4*time.Second)); ok || retryAfter != 6*time.Second {
+		t.Errorf("write after 4s: allowed %v, retry after %v; want throttled for 6s", ok, retryAfter)
+	}
+	if ok, _ := c.allow("orders", "7", 10*time.Second, now.Add(4*time.Second)); !ok {
+		t.Error("write to another table throttled")
+	}
+	if ok, _ := c.allow("quotes", "7", 10*time.Second, now.Add(10*time.Second)); !ok {
+		t.Error("write after the cooldown throttled")
+	}
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	for wait, want := range map[time.Duration]int{0: 1, 200 * time.Millisecond: 1, 1500 * time.Millisecond: 2, time.Minute: 60} {
+		if got := retryAfterSeconds(wait); got != want {
+			t.Errorf("retryAfterSeconds(%v) = %d, want %d", wait, got, want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// createdRecordIDs returns the IDs of the records of a bulk create response, in row order. NocoDB
+// answers with bare IDs, {"Id": n} objects or full records depending on version and endpoint; the
+// ID's JSON type is kept. ok is false for responses without a row list or with a row lacking an ID.
+func createdRecordIDs(body []byte, apiVersion string) (ids []json.RawMessage, ok bool) {
+	rows := upstreamRowRecords(body)
+	if rows == nil {
+		return nil, false
+	}
+	ids = make([]json.RawMessage, 0, len(rows))
+	for _, row := range rows {
+		id, found := createdRecordID(row, apiVersion)
+		if !found {
+			return nil, false
+		}
+		ids = append(ids, id)
+	}
+	return ids, true
+}
+
+// createdRecordID returns the ID of one row of a create response: the row itself when it is a bare
+// ID, else its Id (v2) or id (v3) field, trying the other spelling when the expected one is missing
+func createdRecordID(row json.RawMessage, apiVersion string) (json.RawMessage, bool) {
+	row = bytes.TrimSpace(row)
+	if len(row) == 0 || string(row) == "null" {
+		return nil, false
+	}
+	if row[0] != '{' {
+		return row, true
+	}
+	var record map[string]json.RawMessage
+	if err := json.Unmarshal(row, &record); err != nil {
+		return nil, false
+	}
+	idField, otherField := "id", "Id"
+	if apiVersion == "v2" {
+		idField, otherField = "Id", "id"
+	}
+	id, found := record[idField]
+	if !found {
+		id, found = record[otherField]
+	}
+	if !found || string(id) == "null" {
+		return nil, false
+	}
+	return id, true
+}
+
+// addCreatedIDs adds "created_ids" to a bulk create response (BULK_CREATED_IDS). A bare array
+// response has no room for it and is wrapped as {"records": [...]}; an envelope keeps its keys.
+// changed is false when the IDs can't be found, and the body is returned untouched.
+func addCreatedIDs(body []byte, apiVersion string) (rewritten []byte, changed bool, err error) {
+	ids, ok := createdRecordIDs(body, apiVersion)
+	if !ok {
+		return body, false, nil
+	}
+
+	var envelope map[string]json.RawMessage
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		envelope = map[string]json.RawMessage{"records": trimmed}
+	} else if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, false, err
+	}
+	encodedIDs, err := json.Marshal(ids)
+	if err != nil {
+		return nil, false, err
+	}
+	envelope["created_ids"] = encodedIDs
+
+	rewritten, err = json.Marshal(envelope)
+	if err != nil {
+		return nil, false, err
+	}
+	return rewritten, true, nil
+}
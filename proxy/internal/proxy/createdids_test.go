@@ -0,0 +1,80 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/grove/generic-proxy/internal/config"
+)
+
+func TestAddCreatedIDs(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		apiVersion string
+		want       string // "" = body left untouched
+	}{
+		{"v2 id objects", `[{"Id":1},{"Id":2}]`, "v2", `{"created_ids":[1,2],"records":[{"Id":1},{"Id":2}]}`},
+		{"v2 full records", `[{"Id":1,"Title":"a"},{"Id":2,"Title":"b"}]`, "v2", `{"created_ids":[1,2],"records":[{"Id":1,"Title":"a"},{"Id":2,"Title":"b"}]}`},
+		{"bare ids", `[7,"rec_8"]`, "v2", `{"created_ids":[7,"rec_8"],"records":[7,"rec_8"]}`},
+		{"v3 envelope", `{"records":[{"id":3,"fields":{"Title":"a"}}]}`, "v3", `{"created_ids":[3],"records":[{"id":3,"fields":{"Title":"a"}}]}`},
+		{"other spelling", `[{"id":4}]`, "v2", `{"created_ids":[4],"records":[{"id":4}]}`},
+		{"row without id", `[{"Id":1},{"Title":"b"}]`, "v2", ""},
+		{"single record", `{"Id":1}`, "v2", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed, err := addCreatedIDs([]byte(tt.body), tt.apiVersion)
+			if err != nil {
+				t.Fatalf("addCreatedIDs: %v", err)
+			}
+			if tt.want == "" {
+				if changed || string(got) != tt.body {
+					t.Errorf("got %s, want the body untouched", got)
+				}
+				return
+			}
+			if !changed || string(got) != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBulkCreateReturnsCreatedIDs(t *testing.T) {
+	for _, mode := range []string{BulkWritesAtomic, BulkWritesBestEffort} {
+		t.Run(mode, func(t *testing.T) {
+			up := bulkUpstream(t)
+			p := writableQuotes(up, mode)
+			p.BulkCreatedIDs = true
+
+			body := `[{"Title":"a"},{"Title":"b"}]`
+			if mode == BulkWritesBestEffort {
+				body = `[{"Title":"a"},{"Title":"reject"},{"Title":"b"}]`
+			}
+			rec := serve(p, http.MethodPost, "/proxy/quotes/records", body, "7", "user")
+			if rec.Code >= 300 {
+				t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+			}
+			var response struct {
+				CreatedIDs []int `json:"created_ids"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("body %s: %v", rec.Body, err)
+			}
+			if len(response.CreatedIDs) != 2 {
+				t.Errorf("created_ids = %v in %s, want the two created records", response.CreatedIDs, rec.Body)
+			}
+		})
+	}
+}
+
+func TestCreatedIDsOffByDefault(t *testing.T) {
+	up := bulkUpstream(t)
+	p := newSchemaHandler(up, map[string]config.ResolvedTable{"quotes": quotesTable()})
+	rec := serve(p, http.MethodPost, "/proxy/quotes/records", `[{"Title":"a"}]`, "7", "user")
+	if rec.Body.String() != `[{"Id":100}]` {
+		t.Errorf("body %s, want NocoDB's response as is", rec.Body)
+	}
+}
@@ -0,0 +1,214 @@
+package proxy
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// cursorVersion is the first byte of every cursor so the format can evolve
+const cursorVersion byte = 1
+
+// defaultCursorPageSize is used when a paginated request doesn't set a page size (NocoDB's default)
+const defaultCursorPageSize = 25
+
+// cursorMACSize is the truncated HMAC-SHA256 length appended to each cursor
+const cursorMACSize = 16
+
+// pagingParams are the upstream paging parameters a cursor replaces; they are excluded from the query hash
+var pagingParams = []string{"cursor", "limit", "offset", "page", "pageSize"}
+
+// ErrInvalidCursor is returned for tampered, expired, mismatched or malformed cursors
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// cursorPayload is the signed content of a pagination cursor
+type cursorPayload struct {
+	Table     string `json:"t"`
+	QueryHash string `json:"q"`
+	Offset    int    `json:"o"`
+	Limit     int    `json:"l"`
+	Expires   int64  `json:"e"`
+}
+
+// CursorCodec issues and verifies opaque, HMAC-signed pagination cursors
+type CursorCodec struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+// NewCursorCodec creates a codec; cursors expire after ttl
+func NewCursorCodec(secret []byte, ttl time.Duration) *CursorCodec {
+	return &CursorCodec{secret: secret, ttl: ttl}
+}
+
+// encode signs a payload: base64url(version | json | mac)
+func (c *CursorCodec) encode(payload cursorPayload) (string, error) {
+	payload.Expires = time.Now().Add(c.ttl).Unix()
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	raw := append([]byte{cursorVersion}, data...)
+	raw = append(raw, c.mac(raw)...)
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// decode verifies a cursor's version, signature and expiry
+func (c *CursorCodec) decode(token string) (*cursorPayload, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) < 1+cursorMACSize {
+		return nil, fmt.Errorf("%w: malformed", ErrInvalidCursor)
+	}
+	if raw[0] != cursorVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidCursor, raw[0])
+	}
+
+	signed, mac := raw[:len(raw)-cursorMACSize], raw[len(raw)-cursorMACSize:]
+	if !hmac.Equal(mac, c.mac(signed)) {
+		return nil, fmt.Errorf("%w: bad signature", ErrInvalidCursor)
+	}
+
+	var payload cursorPayload
+	if err := json.Unmarshal(signed[1:], &payload); err != nil {
+		return nil, fmt.Errorf("%w: malformed payload", ErrInvalidCursor)
+	}
+	if time.Now().Unix() > payload.Expires {
+		return nil, fmt.Errorf("%w: expired", ErrInvalidCursor)
+	}
+	return &payload, nil
+}
+
+func (c *CursorCodec) mac(data []byte) []byte {
+	h := hmac.New(sha256.New, c.secret)
+	h.Write(data)
+	return h.Sum(nil)[:cursorMACSize]
+}
+
+// queryHash fingerprints the query without paging parameters, so a cursor can't be spliced onto another filter
+func queryHash(query url.Values) string {
+	filtered := url.Values{}
+	for key, values := range query {
+		filtered[key] = values
+	}
+	for _, key := range pagingParams {
+		filtered.Del(key)
+	}
+	sum := sha256.Sum256([]byte(filtered.Encode()))
+	return hex.EncodeToString(sum[:8])
+}
+
+// isRecordListPath reports whether the path is a table's record list ({table}/records)
+func isRecordListPath(pathParts []string) bool {
+	return len(pathParts) == 2 && pathParts[1] == "records"
+}
+
+// applyCursor replaces ?cursor= with the upstream paging parameters it encodes
+func (c *CursorCodec) applyCursor(query url.Values, tableKey, apiVersion string) error {
+	payload, err := c.decode(query.Get("cursor"))
+	if err != nil {
+		return err
+	}
+	if payload.Table != tableKey {
+		return fmt.Errorf("%w: issued for another table", ErrInvalidCursor)
+	}
+	if payload.QueryHash != queryHash(query) {
+		return fmt.Errorf("%w: query does not match the one the cursor was issued for", ErrInvalidCursor)
+	}
+
+	for _, key := range pagingParams {
+		query.Del(key)
+	}
+	setPaging(query, payload.Offset, payload.Limit, apiVersion)
+	return nil
+}
+
+// setPaging writes offset/limit in the shape the upstream API version expects
+func setPaging(query url.Values, offset, limit int, apiVersion string) {
+	if apiVersion == "v2" {
+		query.Set("offset", strconv.Itoa(offset))
+		query.Set("limit", strconv.Itoa(limit))
+		return
+	}
+	query.Set("page", strconv.Itoa(offset/limit+1))
+	query.Set("pageSize", strconv.Itoa(limit))
+}
+
+// currentPaging reads the page position of an upstream query (after any cursor was applied)
+func currentPaging(query url.Values, apiVersion string) (offset, limit int) {
+	limit = defaultCursorPageSize
+	if apiVersion == "v2" {
+		if n, err := strconv.Atoi(query.Get("limit")); err == nil && n > 0 {
+			limit = n
+		}
+		if n, err := strconv.Atoi(query.Get("offset")); err == nil && n > 0 {
+			offset = n
+		}
+		return offset, limit
+	}
+
+	if n, err := strconv.Atoi(query.Get("pageSize")); err == nil && n > 0 {
+		limit = n
+	}
+	if n, err := strconv.Atoi(query.Get("page")); err == nil && n > 1 {
+		offset = (n - 1) * limit
+	}
+	return offset, limit
+}
+
+// addNextCursor adds {"cursor": {"next": ...}} to a list response; next is null on the last page
+func (c *CursorCodec) addNextCursor(body []byte, tableKey string, query url.Values, apiVersion string) ([]byte, error) {
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return body, nil
+	}
+
+	if !hasMorePages(envelope, apiVersion) {
+		envelope["cursor"] = json.RawMessage(`{"next":null}`)
+		return json.Marshal(envelope)
+	}
+
+	offset, limit := currentPaging(query, apiVersion)
+	next, err := c.encode(cursorPayload{
+		Table:     tableKey,
+		QueryHash: queryHash(query),
+		Offset:    offset + limit,
+		Limit:     limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := json.Marshal(map[string]string{"next": next})
+	if err != nil {
+		return nil, err
+	}
+	envelope["cursor"] = cursor
+	return json.Marshal(envelope)
+}
+
+// hasMorePages inspects NocoDB's paging info: v3 "next" URL, v2 pageInfo.isLastPage
+func hasMorePages(envelope map[string]json.RawMessage, apiVersion string) bool {
+	if apiVersion == "v2" {
+		var pageInfo struct {
+			IsLastPage *bool `json:"isLastPage"`
+		}
+		if err := json.Unmarshal(envelope["pageInfo"], &pageInfo); err != nil || pageInfo.IsLastPage == nil {
+			return false
+		}
+		return !*pageInfo.IsLastPage
+	}
+
+	var next *string
+	if err := json.Unmarshal(envelope["next"], &next); err != nil || next == nil {
+		return false
+	}
+	return *next != ""
+}
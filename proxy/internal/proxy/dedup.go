@@ -0,0 +1,54 @@
+package proxy
+
+import (
+	"encoding/json"
+)
+
+// recordIdentity returns a key identifying a record. With no primary key configured it is NocoDB's
+// record id (v3 "id", v2 "Id"); otherwise it is the tuple of the key fields. ok is false when a
+// key value is missing, in which case the record can't be matched against others.
+func recordIdentity(record map[string]interface{}, primaryKey []string, apiVersion string) (string, bool) {
+	if len(primaryKey) == 0 {
+		idField := "id"
+		if apiVersion == "v2" {
+			idField = "Id"
+		}
+		if record[idField] == nil {
+			return "", false
+		}
+		key, err := json.Marshal(record[idField])
+		return string(key), err == nil
+	}
+
+	tuple := make([]interface{}, len(primaryKey))
+	for i, field := range primaryKey {
+		value := recordValue(record, field, apiVersion)
+		if value == nil {
+			return "", false
+		}
+		tuple[i] = value
+	}
+	key, err := json.Marshal(tuple)
+	return string(key), err == nil
+}
+
+// dedupRecords drops records whose identity already appeared earlier in the list, keeping the
+// first occurrence. Rows shift between upstream pages when data changes mid-aggregation, so the
+// same record can show up at the end of one page and the start of the next.
+func dedupRecords(records []json.RawMessage, primaryKey []string, apiVersion string) ([]json.RawMessage, int) {
+	seen := make(map[string]bool, len(records))
+	unique := records[:0:0]
+	for _, raw := range records {
+		var record map[string]interface{}
+		if err := json.Unmarshal(raw, &record); err == nil {
+			if key, ok := recordIdentity(record, primaryKey, apiVersion); ok {
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+			}
+		}
+		unique = append(unique, raw)
+	}
+	return unique, len(records) - len(unique)
+}
@@ -0,0 +1,72 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/grove/generic-proxy/internal/config"
+)
+
+func rawRecords(records ...string) []json.RawMessage {
+	raw := make([]json.RawMessage, len(records))
+	for i, record := range records {
+		raw[i] = json.RawMessage(record)
+	}
+	return raw
+}
+
+func TestDedupRecords(t *testing.T) {
+	tests := []struct {
+		name       string
+		primaryKey []string
+		apiVersion string
+		records    []json.RawMessage
+		want       int
+	}{
+		{"v2 record id", nil, "v2", rawRecords(`{"Id":1}`, `{"Id":2}`, `{"Id":1}`), 2},
+		{"v3 record id", nil, "v3", rawRecords(`{"id":1}`, `{"id":"1"}`, `{"id":1}`), 2},
+		{"composite key", []string{"Region", "Number"}, "v2", rawRecords(
+			`{"Region":"EU","Number":1}`, `{"Region":"EU","Number":2}`, `{"Region":"US","Number":1}`, `{"Region":"EU","Number":1,"Note":"moved"}`), 3},
+		{"composite key values don't run together", []string{"A", "B"}, "v2", rawRecords(`{"A":"x,y","B":"z"}`, `{"A":"x","B":"y,z"}`), 2},
+		{"records without the key are kept", []string{"Region", "Number"}, "v2", rawRecords(`{"Region":"EU"}`, `{"Region":"EU"}`), 2},
+		{"v3 fields", []string{"Region", "Number"}, "v3", rawRecords(
+			`{"id":1,"fields":{"Region":"EU","Number":1}}`, `{"id":2,"fields":{"Region":"EU","Number":1}}`), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unique, dropped := dedupRecords(tt.records, tt.primaryKey, tt.apiVersion)
+			if len(unique) != tt.want || dropped != len(tt.records)-tt.want {
+				t.Errorf("kept %d, dropped %d; want %d kept", len(unique), dropped, tt.want)
+			}
+			if len(unique) > 0 && string(unique[0]) != string(tt.records[0]) {
+				t.Errorf("first record = %s, want the first occurrence kept", unique[0])
+			}
+		})
+	}
+}
+
+// Rows shift between pages: the last record of page 1 shows up again at the start of page 2
+func TestPaginationDedupsCompositeKeys(t *testing.T) {
+	up := newFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("offset") == "2" {
+			jsonHandler(http.StatusOK, `{"list":[{"Region":"EU","Number":2},{"Region":"US","Number":2}],"pageInfo":{"page":2,"pageSize":2,"totalRows":4,"isLastPage":true}}`)(w, r)
+			return
+		}
+		jsonHandler(http.StatusOK, `{"list":[{"Region":"EU","Number":1},{"Region":"EU","Number":2}],"pageInfo":{"page":1,"pageSize":2,"totalRows":4,"isLastPage":false}}`)(w, r)
+	})
+	table := quotesTable()
+	table.PrimaryKey = []string{"Region", "Number"}
+	p := newSchemaHandler(up, map[string]config.ResolvedTable{"quotes": table})
+
+	list := decodeList(t, serve(p, http.MethodGet, "/proxy/quotes/records", "", "7", "user").Body.Bytes())
+	var keys []string
+	for _, record := range list.List {
+		keys = append(keys, fmt.Sprintf("%v-%v", record["Region"], record["Number"]))
+	}
+	if got, want := strings.Join(keys, " "), "EU-1 EU-2 US-2"; got != want {
+		t.Errorf("merged records = %s, want %s", got, want)
+	}
+}
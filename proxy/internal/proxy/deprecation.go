@@ -0,0 +1,282 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/httperr"
+	"github.com/grove/generic-proxy/internal/middleware"
+)
+
+// DeprecatedFieldUsage counts one client's uses of one deprecated field
+type DeprecatedFieldUsage struct {
+	Table    string    `json:"table"`
+	Field    string    `json:"field"`
+	Client   string    `json:"client"` // user ID
+	Reads    int64     `json:"reads"`
+	Writes   int64     `json:"writes"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// DeprecationTracker counts uses of deprecated fields per table, field and client
+type DeprecationTracker struct {
+	mu    sync.Mutex
+	usage map[string]*DeprecatedFieldUsage // table \x00 field \x00 client
+}
+
+// NewDeprecationTracker creates an empty tracker
+func NewDeprecationTracker() *DeprecationTracker {
+	return &DeprecationTracker{usage: make(map[string]*DeprecatedFieldUsage)}
+}
+
+// record counts one read or write of a deprecated field
+func (t *DeprecationTracker) record(table, field, client string, write bool) {
+	key := table + "\x00" + field + "\x00" + client
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	usage, ok := t.usage[key]
+	if !ok {
+		usage = &DeprecatedFieldUsage{Table: table, Field: field, Client: client}
+		t.usage[key] = usage
+	}
+	if write {
+		usage.Writes++
+	} else {
+		usage.Reads++
+	}
+	usage.LastSeen = time.Now()
+}
+
+// Usage returns every counted use, sorted by table, field and client
+func (t *DeprecationTracker) Usage() []DeprecatedFieldUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	usage := make([]DeprecatedFieldUsage, 0, len(t.usage))
+	for _, u := range t.usage {
+		usage = append(usage, *u)
+	}
+	sort.Slice(usage, func(i, j int) bool {
+		a, b := usage[i], usage[j]
+		if a.Table != b.Table {
+			return a.Table < b.Table
+		}
+		if a.Field != b.Field {
+			return a.Field < b.Field
+		}
+		return a.Client < b.Client
+	})
+	return usage
+}
+
+// DeprecationEnforced reports whether a deprecated field is past its sunset date plus grace
+func DeprecationEnforced(deprecation config.FieldDeprecation, grace time.Duration, now time.Time) bool {
+	return !deprecation.Sunset.IsZero() && !now.Before(deprecation.Sunset.Add(grace))
+}
+
+// sunsetFields returns the titles of the deprecated fields that are enforced now
+func (p *ProxyHandler) sunsetFields(deprecations map[string]config.FieldDeprecation) map[string]string {
+	now := time.Now()
+	var titles map[string]string
+	for title, deprecation := range deprecations {
+		if DeprecationEnforced(deprecation, p.DeprecationGrace, now) {
+			if titles == nil {
+				titles = make(map[string]string)
+			}
+			titles[title] = deprecation.Name
+		}
+	}
+	return titles
+}
+
+// deprecatedReadFields finds deprecated fields a read names in fields, where or sort. Enforced ones
+// are dropped from the fields projection in place; in where/sort they are returned as sunset,
+// since silently removing a filter would change the result.
+func (p *ProxyHandler) deprecatedReadFields(query url.Values, deprecations map[string]config.FieldDeprecation) (used []config.FieldDeprecation, sunset *config.FieldDeprecation) {
+	now := time.Now()
+	seen := make(map[string]bool)
+	use := func(deprecation config.FieldDeprecation) {
+		if !seen[deprecation.Title] {
+			seen[deprecation.Title] = true
+			used = append(used, deprecation)
+		}
+	}
+
+	if query.Has("fields") {
+		var kept []string
+		for _, name := range strings.Split(query.Get("fields"), ",") {
+			deprecation, ok := deprecationByName(deprecations, strings.TrimSpace(name))
+			if ok && DeprecationEnforced(deprecation, p.DeprecationGrace, now) {
+				continue
+			}
+			if ok {
+				use(deprecation)
+			}
+			kept = append(kept, name)
+		}
+		query.Set("fields", strings.Join(kept, ","))
+	}
+
+	find := func(name string) (string, error) {
+		if deprecation, ok := deprecations[name]; ok {
+			if DeprecationEnforced(deprecation, p.DeprecationGrace, now) && sunset == nil {
+				sunset = &deprecation
+			}
+			use(deprecation)
+		}
+		return name, nil
+	}
+	if where := query.Get("where"); where != "" {
+		rewriteWhereFields(where, find)
+	}
+	if sort := query.Get("sort"); sort != "" {
+		rewriteSortFields(sort, find)
+	}
+	return used, sunset
+}
+
+// deprecationByName finds a deprecation by field title or field ID (?fields= carries IDs once aliases are resolved)
+func deprecationByName(deprecations map[string]config.FieldDeprecation, name string) (config.FieldDeprecation, bool) {
+	if deprecation, ok := deprecations[name]; ok {
+		return deprecation, true
+	}
+	for _, deprecation := range deprecations {
+		if deprecation.FieldID != "" && deprecation.FieldID == name {
+			return deprecation, true
+		}
+	}
+	return config.FieldDeprecation{}, false
+}
+
+// deprecatedWriteFields finds deprecated fields set in a write body; the first enforced one is returned as sunset
+func (p *ProxyHandler) deprecatedWriteFields(body []byte, deprecations map[string]config.FieldDeprecation) (used []config.FieldDeprecation, sunset *config.FieldDeprecation) {
+	titles := make(map[string]string, len(deprecations))
+	for title, deprecation := range deprecations {
+		titles[title] = deprecation.Name
+	}
+	_, names, err := stripComputedFields(body, titles) // only the names of the fields present are needed
+	if err != nil {
+		return nil, nil
+	}
+
+	now := time.Now()
+	for _, title := range names {
+		deprecation := deprecations[title]
+		if DeprecationEnforced(deprecation, p.DeprecationGrace, now) && sunset == nil {
+			sunset = &deprecation
+		}
+		used = append(used, deprecation)
+	}
+	return used, sunset
+}
+
+// announceDeprecations adds Deprecation, Sunset and Warning headers for the deprecated fields a
+// request used and counts the uses per client
+func (p *ProxyHandler) announceDeprecations(w http.ResponseWriter, r *http.Request, tableKey string, used []config.FieldDeprecation, write bool) {
+	if len(used) == 0 {
+		return
+	}
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+
+	var earliest time.Time
+	for _, deprecation := range used {
+		warning := fmt.Sprintf("field '%s' of table '%s' is deprecated", deprecation.Name, tableKey)
+		if !deprecation.Sunset.IsZero() {
+			warning += " and will be removed after " + deprecation.Sunset.Format(config.SunsetDateLayout)
+			if earliest.IsZero() || deprecation.Sunset.Before(earliest) {
+				earliest = deprecation.Sunset
+			}
+		}
+		w.Header().Add("Warning", fmt.Sprintf("299 - %q", warning))
+		if p.Deprecations != nil {
+			p.Deprecations.record(tableKey, deprecation.Name, userID, write)
+		}
+		log.Printf("[DEPRECATION] User %s used deprecated field '%s' of table '%s'", userID, deprecation.Name, tableKey)
+	}
+	w.Header().Set("Deprecation", "true")
+	if !earliest.IsZero() {
+		w.Header().Set("Sunset", earliest.UTC().Format(http.TimeFormat))
+	}
+}
+
+// writeFieldSunset rejects a request that uses a field past its sunset date
+func writeFieldSunset(w http.ResponseWriter, tableKey string, deprecation config.FieldDeprecation) {
+	sunset := deprecation.Sunset.Format(config.SunsetDateLayout)
+	httperr.WriteErrorParams(w, httperr.FieldSunset,
+		fmt.Sprintf("field '%s' of table '%s' was removed on %s", deprecation.Name, tableKey, sunset),
+		map[string]string{"field": deprecation.Name, "table": tableKey, "sunset": sunset})
+}
+
+// stripRecordFields removes fields from every record of a list or single-record response
+func stripRecordFields(body []byte, titles map[string]string) ([]byte, bool, error) {
+	var envelope map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber() // keep large numbers exact when re-encoding
+	if err := decoder.Decode(&envelope); err != nil {
+		return body, false, nil
+	}
+
+	var records []map[string]interface{}
+	listKey := ""
+	for _, key := range recordListKeys {
+		if list, ok := envelope[key].([]interface{}); ok {
+			listKey = key
+			for _, item := range list {
+				if record, ok := item.(map[string]interface{}); ok {
+					records = append(records, recordFields(record))
+				}
+			}
+			break
+		}
+	}
+	if listKey == "" {
+		records = []map[string]interface{}{recordFields(envelope)}
+	}
+
+	stripped := false
+	for _, fields := range records {
+		for title := range titles {
+			if _, ok := fields[title]; ok {
+				delete(fields, title)
+				stripped = true
+			}
+		}
+	}
+	if !stripped {
+		return body, false, nil
+	}
+	rewritten, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, false, err
+	}
+	return rewritten, true, nil
+}
+
+// ServeDeprecationUsage handles GET /api/admin/deprecations (admin only): uses of deprecated fields per client
+func (p *ProxyHandler) ServeDeprecationUsage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if role, _ := r.Context().Value(middleware.RoleKey).(string); role != "admin" {
+		httperr.WriteError(w, httperr.AdminRequired, "admin role required")
+		return
+	}
+
+	usage := []DeprecatedFieldUsage{}
+	if p.Deprecations != nil {
+		usage = p.Deprecations.Usage()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"usage": usage}); err != nil {
+		log.Printf("[DEPRECATION ERROR] Failed to encode usage: %v", err)
+	}
+}
@@ -0,0 +1,159 @@
+package proxy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/httperr"
+)
+
+// ErrUnknownLinkField is returned when a link alias can't be resolved to a link field
+var ErrUnknownLinkField = errors.New("unknown link field")
+
+// ValidationError is a request validation failure with a machine-readable code (see httperr)
+type ValidationError struct {
+	Code    string
+	Message string
+	Err     error             // underlying sentinel, e.g. ErrUnknownLinkField
+	Params  map[string]string // values for the code's translated message, e.g. {"table": "orders"}
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// newValidationError builds a ValidationError with a formatted message
+func newValidationError(code, format string, args ...interface{}) *ValidationError {
+	return &ValidationError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// withParams sets the values the translated message is built from
+func (e *ValidationError) withParams(params map[string]string) *ValidationError {
+	e.Params = params
+	return e
+}
+
+// unknownLinkFieldError reports a link alias that doesn't resolve to a link field
+func unknownLinkFieldError(alias, tableName string) *ValidationError {
+	err := newValidationError(httperr.UnknownLinkField, "%v '%s' for table '%s'", ErrUnknownLinkField, alias, tableName)
+	err.Err = ErrUnknownLinkField
+	return err.withParams(map[string]string{"link": alias, "table": tableName})
+}
+
+// Validation error response modes (VALIDATION_ERRORS)
+const (
+	ValidationErrorsTyped  = "typed"  // JSON body with a registered code and its status
+	ValidationErrorsLegacy = "legacy" // 403 operation_not_allowed for every failure, as before the typed codes existed
+)
+
+// writeValidationError reports a validation failure according to the configured mode
+func (p *ProxyHandler) writeValidationError(w http.ResponseWriter, err error) {
+	if p.ValidationErrors == ValidationErrorsLegacy {
+		httperr.WriteError(w, httperr.OperationNotAllowed, "forbidden: "+err.Error())
+		return
+	}
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		httperr.WriteError(w, httperr.OperationNotAllowed, err.Error())
+		return
+	}
+	httperr.WriteErrorParams(w, validationErr.Code, validationErr.Message, validationErr.Params)
+}
+
+// linkRecordNotFoundBody builds the structured body returned when NocoDB answers a link request with 404.
+// The upstream error is embedded when it is valid JSON so nothing is lost for debugging.
+func linkRecordNotFoundBody(tableName, recordID string, upstreamBody []byte) ([]byte, error) {
+	response := map[string]interface{}{
+		"error":           "record '" + recordID + "' not found in table '" + tableName + "'",
+		"code":            httperr.RecordNotFound,
+		"upstream_status": http.StatusNotFound,
+	}
+	if json.Valid(upstreamBody) {
+		response["upstream_error"] = json.RawMessage(upstreamBody)
+	}
+	return json.Marshal(response)
+}
+
+// mapUpstreamError finds the first rule matching a NocoDB error response and returns its proxy code
+// with NocoDB's message (the status text when the body has none)
+func mapUpstreamError(rules []config.UpstreamErrorRule, status int, body []byte) (string, string, bool) {
+	var upstream struct {
+		Error   interface{} `json:"error"`
+		Msg     string      `json:"msg"`
+		Message string      `json:"message"`
+	}
+	json.Unmarshal(body, &upstream) // non-JSON bodies have neither a code nor a message
+	upstreamCode, _ := upstream.Error.(string)
+	message := upstream.Msg
+	if message == "" {
+		message = upstream.Message
+	}
+
+	for _, rule := range rules {
+		if rule.Matches(status, upstreamCode, message) {
+			if message == "" {
+				message = http.StatusText(status)
+			}
+			return rule.Code, message, true
+		}
+	}
+	return "", "", false
+}
+
+// upstreamErrorDetail keeps NocoDB's error body in a mapped error: as JSON when it is, as text otherwise
+func upstreamErrorDetail(body []byte) interface{} {
+	if json.Valid(body) {
+		return json.RawMessage(body)
+	}
+	return string(body)
+}
+
+// writePayloadTooLarge reports a request body over the limit
+func writePayloadTooLarge(w http.ResponseWriter, limit int64) {
+	message := fmt.Sprintf("request body exceeds %d bytes", limit)
+	httperr.WriteErrorParams(w, httperr.PayloadTooLarge, message, map[string]string{"limit": strconv.FormatInt(limit, 10)})
+}
+
+// writeUnknownUser reports a user field write naming a proxy user that doesn't exist
+func writeUnknownUser(w http.ResponseWriter, err *unknownUserError) {
+	userID := strconv.FormatInt(err.userID, 10)
+	httperr.WriteErrorWithFields(w, httperr.UnknownUser, err.Error(), map[string]interface{}{
+		"field":   err.field,
+		"user_id": userID,
+	})
+}
+
+// writeComputedFieldViolation reports a write body containing read-only computed fields
+func writeComputedFieldViolation(w http.ResponseWriter, names []string) {
+	message := fmt.Sprintf("computed fields are read-only: %s", strings.Join(names, ", "))
+	httperr.WriteErrorWithFields(w, httperr.ComputedFieldWrite, message, map[string]interface{}{
+		"fields": names,
+	})
+}
+
+// writeUnwritableFields reports a write setting fields outside the table's writable_fields
+func writeUnwritableFields(w http.ResponseWriter, tableKey string, names []string) {
+	message := fmt.Sprintf("fields are not writable in table '%s': %s", tableKey, strings.Join(names, ", "))
+	httperr.WriteErrorWithFields(w, httperr.FieldNotWritable, message, map[string]interface{}{
+		"fields": names,
+	})
+}
+
+// writeSelectViolation reports an invalid select value with the field, value and allowed options
+func writeSelectViolation(w http.ResponseWriter, violation *selectViolation) {
+	httperr.WriteErrorWithFields(w, httperr.InvalidSelectOption, violation.Error(), map[string]interface{}{
+		"field":   violation.Field,
+		"value":   violation.Value,
+		"allowed": violation.Allowed,
+	})
+}
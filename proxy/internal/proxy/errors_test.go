@@ -0,0 +1,153 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/httperr"
+)
+
+// errorBody is the structured error the proxy answers with
+type errorBody struct {
+	Code           string          `json:"code"`
+	Error          string          `json:"error"`
+	UpstreamStatus int             `json:"upstream_status"`
+	UpstreamError  json.RawMessage `json:"upstream_error"`
+}
+
+func decodeError(t *testing.T, body []byte) errorBody {
+	t.Helper()
+	var e errorBody
+	if err := json.Unmarshal(body, &e); err != nil {
+		t.Fatalf("error body %s is not JSON: %v", body, err)
+	}
+	return e
+}
+
+// An unknown link field is the proxy's 400; a known link to a missing record is NocoDB's 404
+func TestLinkErrorsAreDistinct(t *testing.T) {
+	up := newFakeUpstream(t, jsonHandler(http.StatusNotFound, `{"msg":"Record '9' not found"}`))
+	table := quotesTable()
+	table.Operations = append(table.Operations, "link")
+	table.Links = map[string]config.ResolvedLink{
+		"items": {FieldID: "c1", Title: "Items", Pinned: true},
+		"notes": {Title: "Notes"}, // configured, but NocoDB has no such link field
+	}
+	tables := map[string]config.ResolvedTable{"quotes": table}
+	p := newSchemaHandler(up, tables, func(p *ProxyHandler) { p.Meta = NewMetaCache(up.URL+"/api/v2/", "base", "test-token", up.Client()) })
+
+	rec := serve(p, http.MethodGet, "/proxy/quotes/records/9/links/notes", "", "7", "user")
+	if rec.Code != http.StatusBadRequest || decodeError(t, rec.Body.Bytes()).Code != "unknown_link_field" {
+		t.Errorf("unknown link field: status = %d, body %s; want 400 unknown_link_field", rec.Code, rec.Body)
+	}
+	if len(up.Requests()) != 0 {
+		t.Error("a request for an unknown link field reached NocoDB")
+	}
+
+	rec = serve(p, http.MethodGet, "/proxy/quotes/records/9/links/items", "", "7", "user")
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("missing record: status = %d, body %s; want 404", rec.Code, rec.Body)
+	}
+	e := decodeError(t, rec.Body.Bytes())
+	if e.Code != "record_not_found" || e.UpstreamStatus != http.StatusNotFound || string(e.UpstreamError) != `{"msg":"Record '9' not found"}` {
+		t.Errorf("missing record: body %s; want record_not_found with the upstream error", rec.Body)
+	}
+
+	passthrough := newSchemaHandler(up, tables, func(p *ProxyHandler) { p.LinkNotFoundMode = "passthrough" })
+	rec = serve(passthrough, http.MethodGet, "/proxy/quotes/records/9/links/items", "", "7", "user")
+	if rec.Code != http.StatusNotFound || rec.Body.String() != `{"msg":"Record '9' not found"}` {
+		t.Errorf("passthrough: status = %d, body %s; want NocoDB's 404 as is", rec.Code, rec.Body)
+	}
+}
+
+func TestValidationErrorCodes(t *testing.T) {
+	up := newFakeUpstream(t, jsonHandler(http.StatusOK, `{}`))
+	table := quotesTable()
+	table.Operations = []string{"read", "link"}
+	table.Links = map[string]config.ResolvedLink{
+		"items": {FieldID: "c1", Title: "Items", Pinned: true},
+		"notes": {Title: "Notes"}, // configured, but NocoDB has no such link field
+	}
+	tables := map[string]config.ResolvedTable{"quotes": table}
+	withMeta := func(p *ProxyHandler) { p.Meta = NewMetaCache(up.URL+"/api/v2/", "base", "test-token", up.Client()) }
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		wantStatus int
+		wantCode   string
+	}{
+		{"unknown table", http.MethodGet, "/proxy/orders/records", http.StatusNotFound, httperr.TableNotFound},
+		{"operation not allowed", http.MethodDelete, "/proxy/quotes/records/1", http.StatusForbidden, httperr.OperationNotAllowed},
+		{"link not configured", http.MethodGet, "/proxy/quotes/records/1/links/tags", http.StatusForbidden, httperr.LinkNotAllowed},
+		{"unknown link field", http.MethodGet, "/proxy/quotes/records/1/links/notes", http.StatusBadRequest, httperr.UnknownLinkField},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			typed := newSchemaHandler(up, tables, withMeta)
+			rec := serve(typed, tt.method, tt.target, "", "7", "user")
+			if rec.Code != tt.wantStatus || decodeError(t, rec.Body.Bytes()).Code != tt.wantCode {
+				t.Errorf("status = %d, body %s; want %d %s", rec.Code, rec.Body, tt.wantStatus, tt.wantCode)
+			}
+
+			legacy := newSchemaHandler(up, tables, withMeta, func(p *ProxyHandler) { p.ValidationErrors = ValidationErrorsLegacy })
+			rec = serve(legacy, tt.method, tt.target, "", "7", "user")
+			if rec.Code != http.StatusForbidden || decodeError(t, rec.Body.Bytes()).Code != httperr.OperationNotAllowed {
+				t.Errorf("VALIDATION_ERRORS=legacy: status = %d, body %s; want 403 %s", rec.Code, rec.Body, httperr.OperationNotAllowed)
+			}
+		})
+	}
+	if len(up.Requests()) != 0 {
+		t.Errorf("NocoDB got %d requests for invalid requests", len(up.Requests()))
+	}
+}
+
+func TestUpstreamErrorsMapped(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		wantStatus int
+		wantCode   string
+	}{
+		{"record code", http.StatusNotFound, `{"error":"ERR_RECORD_NOT_FOUND","message":"Record '9' not found"}`, http.StatusNotFound, httperr.RecordNotFound},
+		{"record message", http.StatusNotFound, `{"msg":"Record '9' not found"}`, http.StatusNotFound, httperr.RecordNotFound},
+		{"unique constraint", http.StatusBadRequest, `{"msg":"SQLITE_CONSTRAINT: UNIQUE constraint failed: quotes.code"}`, http.StatusConflict, httperr.DuplicateValue},
+		{"unknown column", http.StatusBadRequest, `{"msg":"column \"nope\" does not exist"}`, httperr.Status(httperr.UnknownField), httperr.UnknownField},
+	}
+	rules, err := config.LoadUpstreamErrorRules("")
+	if err != nil {
+		t.Fatalf("LoadUpstreamErrorRules: %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			up := newFakeUpstream(t, jsonHandler(tt.status, tt.body))
+			p := newLegacyHandler(up)
+			p.UpstreamErrorRules = rules
+
+			rec := serve(p, http.MethodGet, "/proxy/quotes/records/9", "", "7", "user")
+			e := decodeError(t, rec.Body.Bytes())
+			if rec.Code != tt.wantStatus || e.Code != tt.wantCode {
+				t.Errorf("status = %d, code %q; want %d %s", rec.Code, e.Code, tt.wantStatus, tt.wantCode)
+			}
+			if e.UpstreamStatus != tt.status || string(e.UpstreamError) != tt.body {
+				t.Errorf("body %s; want NocoDB's status and error kept", rec.Body)
+			}
+		})
+	}
+}
+
+func TestUnmappedUpstreamErrorsRelayed(t *testing.T) {
+	body := `{"msg":"Something else went wrong"}`
+	up := newFakeUpstream(t, jsonHandler(http.StatusBadRequest, body))
+	p := newLegacyHandler(up)
+	p.UpstreamErrorRules = config.DefaultUpstreamErrorRules
+
+	rec := serve(p, http.MethodGet, "/proxy/quotes/records/9", "", "7", "user")
+	if rec.Code != http.StatusBadRequest || rec.Body.String() != body {
+		t.Errorf("status = %d, body %s; want NocoDB's error as is", rec.Code, rec.Body)
+	}
+}
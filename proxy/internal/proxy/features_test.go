@@ -0,0 +1,49 @@
+package proxy
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/grove/generic-proxy/internal/config"
+)
+
+func TestConcurrencyFeatureOff(t *testing.T) {
+	var maxInFlight atomic.Int32
+	up := overlapUpstream(t, 12, true, &maxInFlight)
+	p := newLegacyHandler(up)
+	p.MaxPaginationFanout = 0
+	p.Features = config.Features{config.FeatureConcurrency: false}
+
+	list := decodeList(t, serve(p, http.MethodGet, "/proxy/quotes/records", "", "7", "user").Body.Bytes())
+	if len(list.List) != 12 {
+		t.Fatalf("got %d records, want 12", len(list.List))
+	}
+	if got := maxInFlight.Load(); got != 1 {
+		t.Errorf("%d page requests overlapped, want pages fetched one by one", got)
+	}
+}
+
+func TestStreamingFeatureOff(t *testing.T) {
+	if testing.Short() {
+		t.Skip("proxies 8MB")
+	}
+	for _, streaming := range []bool{true, false} {
+		up, size := syntheticUpstream(t, "application/json", `{"Id":1,"Notes":"`, strings.Repeat("lorem ipsum ", 100), 7000, `"}`)
+		p := newLegacyHandler(up)
+		p.Features = config.Features{config.FeatureStreaming: streaming}
+
+		var n int64
+		allocated := allocatedWhile(func() { _, n = getThrough(t, p, "/proxy/quotes/records/1") })
+		if n != size {
+			t.Fatalf("streaming %v: got %d bytes, want %d", streaming, n, size)
+		}
+		if streaming && allocated > uint64(size/10) {
+			t.Errorf("streaming on: allocated %d KB to proxy %d KB", allocated>>10, size>>10)
+		}
+		if !streaming && allocated < uint64(size) {
+			t.Errorf("streaming off: allocated %d KB to proxy %d KB, want the body buffered", allocated>>10, size>>10)
+		}
+	}
+}
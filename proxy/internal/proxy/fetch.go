@@ -0,0 +1,124 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Limits for server-side record fetches (summaries and other background jobs)
+const (
+	fetchPageSize = 1000
+	fetchMaxPages = 100
+)
+
+// backgroundRole is the role background fetches are validated with; the config loader requires
+// summary tables to allow it read
+const backgroundRole = "admin"
+
+// fetchTimeout bounds each background fetch request, which has no client request to bound it
+const fetchTimeout = 30 * time.Second
+
+// FetchRecords reads every record of a configured table matching where, following pagination.
+// The request goes through the Validator like a client GET, so only readable tables can be fetched.
+// Returned maps hold field values keyed by field title (v3 "fields" wrappers are unwrapped).
+func (p *ProxyHandler) FetchRecords(tableKey, where string) ([]map[string]interface{}, error) {
+	_, validator := p.schema()
+	if validator == nil {
+		return nil, fmt.Errorf("record fetches require schema-driven mode")
+	}
+
+	validation, err := validator.ValidateRequest(http.MethodGet, tableKey+"/records", backgroundRole, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	apiVersion := detectAPIVersion(p.NocoDBURL)
+	var records []map[string]interface{}
+	for page := 1; page <= fetchMaxPages; page++ {
+		query := url.Values{}
+		if where != "" {
+			query.Set("where", where)
+		}
+		if apiVersion == "v2" {
+			query.Set("limit", strconv.Itoa(fetchPageSize))
+			query.Set("offset", strconv.Itoa((page-1)*fetchPageSize))
+		} else {
+			query.Set("pageSize", strconv.Itoa(fetchPageSize))
+			query.Set("page", strconv.Itoa(page))
+		}
+
+		body, err := p.fetchUpstream(p.NocoDBURL + validation.ResolvedPath + "?" + query.Encode())
+		if err != nil {
+			return nil, err
+		}
+
+		var response struct {
+			Records  []map[string]interface{} `json:"records"`
+			List     []map[string]interface{} `json:"list"`
+			Next     *string                  `json:"next"`
+			PageInfo struct {
+				IsLastPage bool `json:"isLastPage"`
+			} `json:"pageInfo"`
+		}
+		if err := json.Unmarshal(body, &response); err != nil {
+			return nil, fmt.Errorf("failed to parse records for '%s': %w", tableKey, err)
+		}
+
+		items := response.Records
+		if apiVersion == "v2" {
+			items = response.List
+		}
+		for _, item := range items {
+			if fields, ok := item["fields"].(map[string]interface{}); ok {
+				records = append(records, fields)
+			} else {
+				records = append(records, item)
+			}
+		}
+
+		lastPage := len(items) < fetchPageSize
+		if apiVersion == "v2" {
+			lastPage = lastPage || response.PageInfo.IsLastPage
+		} else {
+			lastPage = lastPage || response.Next == nil || *response.Next == ""
+		}
+		if lastPage {
+			return records, nil
+		}
+	}
+
+	return nil, fmt.Errorf("table '%s' has more than %d records matching the filter", tableKey, fetchMaxPages*fetchPageSize)
+}
+
+// fetchUpstream performs an authenticated GET against NocoDB with the handler's shared client and
+// returns the body of a 200 response
+func (p *ProxyHandler) fetchUpstream(targetURL string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, targetURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create upstream request: %w", err)
+	}
+	req.Header.Set("xc-token", p.NocoDBToken)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("upstream request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upstream response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("upstream returned status %d: %s", resp.StatusCode, string(body))
+	}
+	return body, nil
+}
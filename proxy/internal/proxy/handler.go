@@ -1,13 +1,25 @@
 package proxy
 
 import (
-	"fmt"
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
 	"io"
 	"log"
 	"net/http"
+	"regexp"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/httperr"
+	"github.com/grove/generic-proxy/internal/jsonpath"
+	"github.com/grove/generic-proxy/internal/logger"
+	"github.com/grove/generic-proxy/internal/metrics"
+	"github.com/grove/generic-proxy/internal/middleware"
 )
 
 type ProxyHandler struct {
@@ -16,57 +28,343 @@ type ProxyHandler struct {
 	Meta           *MetaCache
 	ResolvedConfig *config.ResolvedConfig
 	Validator      *Validator
+
+	// schemaMu guards ResolvedConfig and Validator, which a forced metadata refresh may replace
+	schemaMu sync.RWMutex
+
+	// MaxResponseRecords caps the records returned to the client after all transforms (0 = unlimited)
+	MaxResponseRecords int
+
+	// ResponseAliasDepth is how many levels of linked records nested in a record have their fields
+	// renamed to aliases (rename_response_fields); keys further down are left as NocoDB sent them
+	ResponseAliasDepth int
+
+	// CommentCounts answers ?include=comment_count on record reads (nil = not supported)
+	CommentCounts CommentCounter
+
+	// SortVerifyMaxRecords bounds the proxy-side re-sort done for verify_sort tables (0 = unlimited)
+	SortVerifyMaxRecords int
+
+	// UpstreamTimeout bounds each request to NocoDB, pagination follow-ups included (0 = no limit)
+	UpstreamTimeout time.Duration
+
+	// client is shared by every NocoDB call so connections are pooled
+	client *http.Client
+
+	// MaxBodyBytes limits request bodies forwarded upstream (0 = unlimited); tables may override it
+	MaxBodyBytes int64
+
+	// responseHeaders is the allowlist of upstream headers relayed to clients
+	responseHeaders map[string]bool
+
+	// LegacyOperations restricts every table in legacy mode (no proxy.yaml); empty allows all operations
+	LegacyOperations []string
+
+	// MaxPaginationFanout caps upstream requests (first page included) per aggregated list request; 0 = unlimited
+	MaxPaginationFanout int
+	// MaxPaginationFanoutRatio caps an aggregated list request at this multiple of the average fan-out
+	// so far, once fanoutRatioMinRequests lists were aggregated; 0 = no ratio cap
+	MaxPaginationFanoutRatio int
+	// MaxPaginationRecords stops aggregation once this many records are merged; 0 = unlimited
+	MaxPaginationRecords int
+	// PaginationTimeout bounds the whole aggregation; pages missing when it passes truncate the list (0 = none)
+	PaginationTimeout time.Duration
+	// PaginationWorkers fetches v2 offset pages concurrently when above 1 (v3 next links are always followed serially)
+	PaginationWorkers int
+	// PaginationOnCancel decides what a cancelled aggregation returns (abort, partial)
+	PaginationOnCancel string
+
+	// Fan-out tracking for aggregated list requests
+	paginationRequests atomic.Int64
+	paginationPages    atomic.Int64
+
+	// Cursors issues signed pagination cursors on list responses; nil disables them
+	Cursors *CursorCodec
+
+	// ValidationErrors selects how validation failures are reported: typed (default) or legacy
+	ValidationErrors string
+
+	// PathValidation rejects traversal and encoded separators in proxy paths, paths of unexpected shape
+	// and record IDs not matching RecordIDPattern (strict, off)
+	PathValidation string
+	// RecordIDPattern is what record IDs in proxy paths must match; nil = DefaultRecordIDPattern.
+	// Takes effect with the next SetResolvedConfig in schema-driven mode.
+	RecordIDPattern *regexp.Regexp
+
+	// UnresolvedFields decides what happens to where/sort and write body fields that don't resolve (lenient, strict).
+	// Takes effect with the next SetResolvedConfig.
+	UnresolvedFields string
+
+	// LinkTargetPermissions also checks link requests against the target table's operations (off, enforce).
+	// Takes effect with the next SetResolvedConfig.
+	LinkTargetPermissions string
+
+	// LinkImpliesUnlink lets tables that allow link also unlink, as configs written before the unlink
+	// operation expect (default true). Takes effect with the next SetResolvedConfig.
+	LinkImpliesUnlink bool
+
+	// WriteCooldowns tracks the last write per user and table for tables with a write_cooldown (nil = not enforced)
+	WriteCooldowns *WriteCooldowns
+
+	// RowLevelDefault decides what non-admin users read of tables without an owner_field (open, deny)
+	RowLevelDefault string
+
+	// UpstreamErrorRules map NocoDB error responses to proxy error codes (nil = relayed as they are)
+	UpstreamErrorRules []config.UpstreamErrorRule
+
+	// Deprecations counts uses of deprecated fields per client (nil = not counted)
+	Deprecations *DeprecationTracker
+	// DeprecationGrace delays enforcement of a deprecated field's sunset date
+	DeprecationGrace time.Duration
+
+	// UpstreamMaxAttempts is how often an idempotent NocoDB request is tried on connection errors
+	// and 502/503/504 (1 = no retries)
+	UpstreamMaxAttempts int
+	// RetryIdempotencyKey also retries other methods when the client sent an Idempotency-Key, but
+	// only after connection errors that happened before the request was written
+	RetryIdempotencyKey bool
+
+	// UpstreamCallBudget caps the NocoDB calls (retries and pages included) made for one client
+	// request; tables may override it (0 = unlimited)
+	UpstreamCallBudget int
+	// UpstreamRetryBudget caps the retries among those calls, shared by the request, its pagination
+	// pages and owner checks (0 = unlimited)
+	UpstreamRetryBudget int
+	budgetMu            sync.Mutex
+	budgetExhaustions   map[string]int64 // by the feature that used the most calls
+
+	// PublicBaseURL is the proxy's external URL used in rewritten next/prev links ("" = the request's host)
+	PublicBaseURL string
+
+	// SelectValidation controls checking of select values in write bodies (off, strict, refresh)
+	SelectValidation string
+
+	// Collaborators translates NocoDB user fields to proxy users (nil = collaborator values pass through)
+	Collaborators CollaboratorDirectory
+	// UserFieldsAdminRaw leaves collaborator values untranslated in responses to admins
+	UserFieldsAdminRaw bool
+
+	// ComputedFields decides what happens to formula/rollup/... values in write bodies (strip, reject)
+	ComputedFields string
+
+	// BulkWrites decides what a bulk write with failing rows does: atomic (default) fails it as a
+	// whole, best_effort writes the other rows and answers 207 with per-row outcomes
+	BulkWrites string
+	// BulkCreatedIDs adds the IDs of the created records to bulk create responses as created_ids
+	BulkCreatedIDs bool
+
+	// TransformMaxBytes skips the optional response transforms (verify_sort, comment counts, response
+	// filters, field renaming) on bodies larger than this, listing them in X-Proxy-Transforms-Skipped; 0 = unlimited
+	TransformMaxBytes int64
+
+	// JSONContentType decides the Content-Type of JSON responses: passthrough (default) relays
+	// NocoDB's, canonical sets application/json; charset=utf-8 on every body that is JSON
+	JSONContentType string
+
+	// LinkNotFoundMode controls upstream 404s on link requests:
+	// "structured" (default) rewrites them to a record_not_found error, "passthrough" relays NocoDB's body
+	LinkNotFoundMode string
+
+	// Features switches experimental behavior off (streaming, concurrency); nil enables everything
+	Features config.Features
 }
 
-// NewProxyHandler creates a new proxy handler
-func NewProxyHandler(nocoDBURL, nocoDBToken string, meta *MetaCache) *ProxyHandler {
+// NewProxyHandler creates a new proxy handler. client is used for every NocoDB call;
+// nil means NewUpstreamClient(DefaultUpstreamClientOptions).
+func NewProxyHandler(nocoDBURL, nocoDBToken string, meta *MetaCache, client *http.Client) *ProxyHandler {
+	if client == nil {
+		client = NewUpstreamClient(DefaultUpstreamClientOptions)
+	}
 	return &ProxyHandler{
-		NocoDBURL:   nocoDBURL,
-		NocoDBToken: nocoDBToken,
-		Meta:        meta,
+		NocoDBURL:                nocoDBURL,
+		NocoDBToken:              nocoDBToken,
+		Meta:                     meta,
+		UpstreamTimeout:          client.Timeout,
+		PaginationWorkers:        DefaultPaginationWorkers,
+		MaxPaginationFanoutRatio: DefaultPaginationFanoutRatio,
+		PathValidation:           PathValidationStrict,
+		UpstreamMaxAttempts:      DefaultUpstreamMaxAttempts,
+		UpstreamCallBudget:       DefaultUpstreamCallBudget,
+		UpstreamRetryBudget:      DefaultUpstreamRetryBudget,
+		ResponseAliasDepth:       DefaultResponseAliasDepth,
+		MaxPaginationFanout:      DefaultPaginationFanout,
+		LinkImpliesUnlink:        true,
+		client:                   client,
+		responseHeaders:          newResponseHeaderAllowlist(),
+	}
+}
+
+// DefaultUpstreamTimeout is the UpstreamTimeout of a new ProxyHandler
+const DefaultUpstreamTimeout = 30 * time.Second
+
+// SetTimeout sets the upstream request timeout (0 = no limit). Call it before serving requests.
+func (p *ProxyHandler) SetTimeout(timeout time.Duration) {
+	p.UpstreamTimeout = timeout
+	p.client.Timeout = timeout
+}
+
+// upstreamContext derives the context for one NocoDB request: cancelled when the client goes away
+// and after UpstreamTimeout
+func (p *ProxyHandler) upstreamContext(parent context.Context) (context.Context, context.CancelFunc) {
+	if p.UpstreamTimeout <= 0 {
+		return context.WithCancel(parent)
 	}
+	return context.WithTimeout(parent, p.UpstreamTimeout)
 }
 
-// SetResolvedConfig sets the resolved configuration and initializes the validator
+// SetResolvedConfig sets the resolved configuration and initializes the validator.
+// Safe to call while requests are served: in-flight requests keep the schema they started with.
 func (p *ProxyHandler) SetResolvedConfig(config *config.ResolvedConfig) {
+	validator := NewValidator(config, p.Meta, detectAPIVersion(p.NocoDBURL))
+	validator.unresolvedFields = p.UnresolvedFields
+	validator.linkTargets = p.LinkTargetPermissions
+	validator.linkImpliesUnlink = p.LinkImpliesUnlink
+	if p.PathValidation != PathValidationOff {
+		validator.recordIDPattern = p.recordIDPattern()
+	}
+	p.schemaMu.Lock()
 	p.ResolvedConfig = config
-	p.Validator = NewValidator(config, p.Meta)
+	p.Validator = validator
+	p.schemaMu.Unlock()
+	metrics.SetTables(metricsTables(config))
 	log.Printf("[PROXY] Resolved configuration set with %d tables", len(config.Tables))
 }
 
+// metricsTables lists the configured tables and their link names for the metrics path label
+func metricsTables(config *config.ResolvedConfig) map[string][]string {
+	tables := make(map[string][]string, len(config.Tables))
+	for tableKey, table := range config.Tables {
+		links := make([]string, 0, len(table.Links))
+		for linkName := range table.Links {
+			links = append(links, linkName)
+		}
+		tables[tableKey] = links
+	}
+	return tables
+}
+
+// schema returns the current resolved configuration and validator (both nil in legacy mode)
+func (p *ProxyHandler) schema() (*config.ResolvedConfig, *Validator) {
+	p.schemaMu.RLock()
+	defer p.schemaMu.RUnlock()
+	return p.ResolvedConfig, p.Validator
+}
+
 // ServeHTTP handles proxying requests to NocoDB
-func (p *ProxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	log.Printf("[PROXY] Incoming request: %s %s", r.Method, r.URL.Path)
+func (p *ProxyHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	logger.Debug("[PROXY] Incoming request: %s %s", r.Method, r.URL.Path)
+
+	// All writes go through the tracking writer so late failures can't double-write the status
+	w := newTrackingWriter(rw)
 
 	// Extract the path after /proxy/
 	path := strings.TrimPrefix(r.URL.Path, "/proxy/")
-	log.Printf("[PROXY] Extracted path: %s", path)
+	logger.Debug("[PROXY] Extracted path: %s", path)
+
+	// Suspicious paths are rejected before either mode resolves them or builds the target URL
+	if p.PathValidation != PathValidationOff {
+		if err := checkRequestPath(r, path); err != nil {
+			log.Printf("[PROXY ERROR] Rejected path %q: %v", r.URL.EscapedPath(), err)
+			httperr.WriteError(w, httperr.InvalidPath, err.Error())
+			return
+		}
+	}
 
 	var resolvedPath string
+	var tableID string
+	bodyLimit := p.MaxBodyBytes
+	var defaultSort []config.SortKey
+	verifySort := false
+	pagination := p.paginationOptions(config.ResolvedTable{}) // handler-wide limits in legacy mode
+	var responseFilter []jsonpath.Path
+	archiveField := ""
+	ownerField := ""
+	ownerOverride := false
+	var deprecations map[string]config.FieldDeprecation
+	var responseAliases *config.ResponseAliases
+	callBudgetLimit := p.UpstreamCallBudget
+	var writeCooldown time.Duration
+	var schemaTable *config.ResolvedTable // the request's table in config-driven mode, nil in legacy mode
+	var writableFields map[string]bool
+	var hiddenFields map[string]bool
+	var hiddenLinks map[string]map[string]bool
+	var fieldTitles map[string]string
 
 	// If we have a validator (config-driven mode), use it
-	if p.Validator != nil && p.ResolvedConfig != nil {
-		log.Printf("[PROXY] Using config-driven validation")
+	if resolvedConfig, validator := p.schema(); validator != nil && resolvedConfig != nil {
+		logger.Debug("[PROXY] Using config-driven validation")
 
-		validation, err := p.Validator.ValidateRequest(r.Method, path)
+		query := r.URL.Query()
+		role, _ := r.Context().Value(middleware.RoleKey).(string)
+		validation, err := validator.ValidateRequest(r.Method, path, role, query)
 		if err != nil {
 			log.Printf("[PROXY ERROR] Validation failed: %v", err)
-			http.Error(w, "forbidden: "+err.Error(), http.StatusForbidden)
+			p.writeValidationError(w, err)
 			return
 		}
+		if query.Has("fields") || query.Has("where") || query.Has("sort") {
+			r.URL.RawQuery = query.Encode() // aliases in ?fields= now name field IDs, in where/sort field titles
+		}
 
 		resolvedPath = validation.ResolvedPath
-		log.Printf("[PROXY] Validated and resolved: %s -> %s", path, resolvedPath)
+		tableID = validation.TableID
+		table := resolvedConfig.Tables[validation.TableKey]
+		if table.MaxBodyBytes > 0 {
+			bodyLimit = table.MaxBodyBytes
+		}
+		defaultSort = table.DefaultSort
+		verifySort = table.VerifySort
+		pagination = p.paginationOptions(table)
+		responseFilter = table.ResponseFilter
+		archiveField = table.ArchiveField
+		ownerField = table.OwnerField
+		ownerOverride = table.OwnerOverride
+		deprecations = table.Deprecations
+		responseAliases = table.ResponseAliases
+		schemaTable = &table
+		fieldTitles = table.FieldTitles
+		if role, _ := r.Context().Value(middleware.RoleKey).(string); !table.AdminBypass || role != "admin" {
+			writableFields = table.WritableFields
+			if writableFields != nil && table.OwnerField != "" && !writableFields[table.OwnerField] {
+				// The proxy sets or drops the owner itself, so sending it isn't an error
+				writableFields = make(map[string]bool, len(table.WritableFields)+1)
+				for field := range table.WritableFields {
+					writableFields[field] = true
+				}
+				writableFields[table.OwnerField] = true
+			}
+		}
+		if table.UpstreamCallBudget > 0 {
+			callBudgetLimit = table.UpstreamCallBudget
+		}
+		writeCooldown = table.WriteCooldown
+		logger.Debug("[PROXY] Validated and resolved: %s -> %s", path, resolvedPath)
 	} else {
 		// Fallback to MetaCache-only resolution (legacy mode)
-		log.Printf("[PROXY] Using legacy MetaCache-only mode")
+		logger.Debug("[PROXY] Using legacy MetaCache-only mode")
+
+		if p.PathValidation != PathValidationOff {
+			parts := strings.Split(strings.Trim(path, "/"), "/")
+			if err := checkPathShape(parts[1:], p.recordIDPattern()); err != nil {
+				log.Printf("[PROXY ERROR] Rejected path %q: %v", r.URL.EscapedPath(), err)
+				p.writeValidationError(w, err)
+				return
+			}
+		}
+
+		if operation, allowed := p.isLegacyOperationAllowed(r.Method, path); !allowed {
+			log.Printf("[PROXY ERROR] Operation '%s' not allowed by legacy operations allowlist", operation)
+			p.writeValidationError(w, newValidationError(httperr.OperationNotAllowed, "operation '%s' not allowed in legacy mode", operation))
+			return
+		}
 
 		if p.Meta != nil {
 			parts := strings.SplitN(path, "/", 2)
 			if len(parts) > 0 && parts[0] != "" {
 				tableName := parts[0]
-				if tableID, ok := p.Meta.Resolve(tableName); ok {
-					log.Printf("[META] Resolved table '%s' -> '%s'", tableName, tableID)
+				if resolvedID, ok := p.Meta.Resolve(tableName); ok {
+					tableID = resolvedID
+					logger.Debug("[META] Resolved table '%s' -> '%s'", tableName, tableID)
 
 					// Check if this is a link request and resolve link field alias
 					if len(parts) == 2 {
@@ -74,7 +372,7 @@ func (p *ProxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 						resolvedRemainingPath, err := p.resolveLinkFieldInPath(tableID, tableName, remainingPath)
 						if err != nil {
 							log.Printf("[PROXY ERROR] Link field resolution failed: %v", err)
-							http.Error(w, "bad request: "+err.Error(), http.StatusBadRequest)
+							p.writeValidationError(w, err)
 							return
 						}
 						resolvedPath = tableID + "/" + resolvedRemainingPath
@@ -82,7 +380,7 @@ func (p *ProxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 						resolvedPath = tableID
 					}
 				} else {
-					log.Printf("[META] No mapping found for table '%s', using raw name", tableName)
+					logger.Debug("[META] No mapping found for table '%s', using raw name", tableName)
 					resolvedPath = path
 				}
 			} else {
@@ -93,23 +391,348 @@ func (p *ProxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	pathParts := strings.Split(strings.Trim(path, "/"), "/")
+	if schemaTable != nil {
+		hiddenFields, hiddenLinks = hiddenResponseFields(*schemaTable, pathParts)
+	}
+	apiVersion := detectAPIVersion(p.NocoDBURL)
+
+	// Every NocoDB call made for this request draws from one budget (see doUpstream)
+	budget := newCallBudget(callBudgetLimit)
+	budget.retryLimit = p.UpstreamRetryBudget
+	r = r.WithContext(withCallBudget(r.Context(), budget))
+	defer p.reportBudget(r, budget)
+
+	// ?include=comment_count is answered by the proxy, NocoDB never sees it
+	includeCommentCount := false
+	if r.Method == http.MethodGet && p.CommentCounts != nil && len(pathParts) >= 2 && len(pathParts) <= 3 && pathParts[1] == "records" {
+		query := r.URL.Query()
+		if includes := query["include"]; containsExact(includes, "comment_count") {
+			includeCommentCount = true
+			query.Del("include")
+			for _, include := range includes {
+				if include != "comment_count" {
+					query.Add("include", include)
+				}
+			}
+			r.URL.RawQuery = query.Encode()
+		}
+	}
+
+	// Deprecated fields named in fields/where/sort: warn until their sunset, then drop or reject them
+	var deprecatedReads []config.FieldDeprecation
+	if r.Method == http.MethodGet && len(deprecations) > 0 {
+		query := r.URL.Query()
+		used, sunset := p.deprecatedReadFields(query, deprecations)
+		if sunset != nil {
+			log.Printf("[PROXY ERROR] Read uses field '%s' past its sunset", sunset.Name)
+			writeFieldSunset(w, pathParts[0], *sunset)
+			return
+		}
+		if query.Get("fields") != r.URL.Query().Get("fields") {
+			r.URL.RawQuery = query.Encode()
+		}
+		deprecatedReads = used
+	}
+	p.announceDeprecations(w, r, pathParts[0], deprecatedReads, false)
+
+	// ?proxyPaginate=false / X-Proxy-Paginate: off return the upstream page untouched, next link included
+	paginate := true
+	if r.Method == http.MethodGet && isRecordListPath(pathParts) && paginationOptedOut(r) {
+		logger.Debug("[PAGINATION] Client opted out of aggregation")
+		paginate = false
+	}
+
+	// Accept: application/x-ndjson / ?format=ndjson stream the list as JSON Lines, page by page
+	ndjson := r.Method == http.MethodGet && isRecordListPath(pathParts) && wantsNDJSON(r)
+
+	// Row-level filtering: non-admin users read only the records they own
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	checksOwner, ok := p.filterOwnedReads(w, r, pathParts, ownerField, userID)
+	if !ok {
+		return
+	}
+
+	// Tables with a write_cooldown take one write per user per cooldown
+	if !p.checkWriteCooldown(w, r, pathParts[0], userID, writeCooldown) {
+		return
+	}
+
+	// Archived records are hidden from list reads unless an admin asks for them with ?include_archived=true
+	if !filterArchived(w, r, pathParts, archiveField) {
+		return
+	}
+
+	// Default sort keeps list ordering stable across pages; an explicit ?sort= wins
+	sortInjected := injectListSort(r, pathParts, defaultSort, apiVersion)
+
+	// Opaque pagination cursors: translate ?cursor= into upstream paging parameters
+	// (after sort injection so the cursor's query hash sees the same query every time)
+	isListRequest := p.Cursors != nil && r.Method == http.MethodGet && isRecordListPath(pathParts)
+	if isListRequest && r.URL.Query().Get("cursor") != "" {
+		query := r.URL.Query()
+		if err := p.Cursors.applyCursor(query, pathParts[0], apiVersion); err != nil {
+			log.Printf("[PROXY ERROR] Rejected cursor: %v", err)
+			httperr.WriteError(w, httperr.InvalidCursor, err.Error())
+			return
+		}
+		r.URL.RawQuery = query.Encode()
+	}
+
 	// Construct the target URL
 	targetURL := p.NocoDBURL + resolvedPath
 	if r.URL.RawQuery != "" {
 		targetURL += "?" + r.URL.RawQuery
 	}
-	log.Printf("[PROXY] Target URL: %s", targetURL)
+	logger.Debug("[PROXY] Target URL: %s", targetURL)
+
+	// Enforce the request body limit before anything is sent upstream
+	if bodyLimit > 0 {
+		if r.ContentLength > bodyLimit {
+			log.Printf("[PROXY ERROR] Request body too large: %d > %d bytes", r.ContentLength, bodyLimit)
+			writePayloadTooLarge(w, bodyLimit)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, bodyLimit)
+	}
+
+	// Record writes: drop read-only computed fields, map proxy user IDs in user fields to emails
+	// and validate select values against the cached option lists
+	var reqBody io.Reader = r.Body
+	_, isLinkPath := parseLinkPath(pathParts[1:])
+	isRecordWrite := (r.Method == http.MethodPost || r.Method == http.MethodPatch || r.Method == http.MethodPut) && !isLinkPath
+	validatesSelects := p.SelectValidation != SelectValidationOff && len(p.metaSelectFields(tableID)) > 0
+	computedFields := p.metaComputedFields(tableID)
+	userFields := p.metaUserFields(tableID)
+	setsOwner := isRecordWrite && ownerField != "" && isRecordPath(pathParts)
+
+	// Field aliases in record writes become NocoDB field titles before any other write check sees the body
+	if isRecordWrite && schemaTable != nil && (len(schemaTable.FieldTitles) > 0 || p.UnresolvedFields == UnresolvedFieldsStrict) {
+		requestBody, err := io.ReadAll(r.Body)
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				writePayloadTooLarge(w, maxBytesErr.Limit)
+				return
+			}
+			log.Printf("[PROXY ERROR] Failed to read request body: %v", err)
+			httperr.WriteError(w, httperr.InvalidBody, "failed to read request body")
+			return
+		}
+		translated, err := p.translateWriteAliases(pathParts[0], *schemaTable, requestBody)
+		if err != nil {
+			log.Printf("[PROXY ERROR] Write field translation failed: %v", err)
+			p.writeValidationError(w, err)
+			return
+		}
+		if !bytes.Equal(translated, requestBody) {
+			logger.Debug("[PROXY] Translated field aliases in write body")
+		}
+		r.Body = io.NopCloser(bytes.NewReader(translated))
+		r.ContentLength = int64(len(translated))
+		reqBody = bytes.NewReader(translated)
+	}
 
-	// Create a new request to NocoDB
-	proxyReq, err := http.NewRequest(r.Method, targetURL, r.Body)
+	// Updates, deletes, links and unlinks on tables with an owner_field must only address the user's own
+	// records; NocoDB has no conditional writes, so the targets are read and checked before the write is sent
+	if role, _ := r.Context().Value(middleware.RoleKey).(string); ownerField != "" && role != "admin" && isOwnedWrite(r.Method, pathParts, isLinkPath) {
+		var requestBody []byte // bulk bodies carry the IDs; single-record paths have theirs in the path
+		if isRecordListPath(pathParts) {
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					writePayloadTooLarge(w, maxBytesErr.Limit)
+					return
+				}
+				log.Printf("[PROXY ERROR] Failed to read request body: %v", err)
+				httperr.WriteError(w, httperr.InvalidBody, "failed to read request body")
+				return
+			}
+			requestBody = body
+			r.Body = io.NopCloser(bytes.NewReader(requestBody))
+			reqBody = bytes.NewReader(requestBody)
+		}
+
+		ids, ok := writeRecordIDs(pathParts, requestBody, apiVersion)
+		if !ok {
+			log.Printf("[PROXY ERROR] %s on '%s' has rows without a record ID, can't check their owners", r.Method, pathParts[0])
+			httperr.WriteError(w, httperr.InvalidBody, "every record of an update or delete needs an ID")
+			return
+		}
+		if userID == "" {
+			log.Printf("[PROXY ERROR] %s on '%s' without a user ID, can't check record owners", r.Method, pathParts[0])
+			httperr.WriteError(w, httperr.NotRecordOwner, "records are owned by another user")
+			return
+		}
+		notOwned, missing, err := p.recordOwners(r.Context(), p.tableRecordsURL(tableID), ids, ownerField, userID)
+		var validationErr *ValidationError
+		switch {
+		case errors.As(err, &validationErr):
+			log.Printf("[PROXY ERROR] Owner check rejected: %v", err)
+			p.writeValidationError(w, err)
+			return
+		case errors.Is(err, ErrBudgetExhausted):
+			log.Printf("[PROXY ERROR] Owner check of %d record(s) ran out of upstream calls", len(ids))
+			httperr.WriteError(w, httperr.UpstreamBudgetExhausted, err.Error())
+			return
+		case err != nil:
+			log.Printf("[PROXY ERROR] Owner check failed: %v", err)
+			httperr.WriteError(w, httperr.UpstreamReadFailed, "failed to check record owners")
+			return
+		case len(notOwned) > 0:
+			log.Printf("[PROXY ERROR] User %s tried to %s records of '%s' they don't own: %s", userID, r.Method, pathParts[0], strings.Join(notOwned, ", "))
+			httperr.WriteErrorWithFields(w, httperr.NotRecordOwner, "records are owned by another user", map[string]interface{}{"ids": notOwned})
+			return
+		case len(missing) > 0:
+			log.Printf("[PROXY ERROR] %s on '%s' addresses missing records: %s", r.Method, pathParts[0], strings.Join(missing, ", "))
+			httperr.WriteErrorWithFields(w, httperr.RecordNotFound, "record not found", map[string]interface{}{"ids": missing})
+			return
+		}
+		logger.Debug("[PROXY] Owner check passed for %d record(s) of '%s'", len(ids), pathParts[0])
+	}
+
+	// BULK_WRITES=best_effort: rows failing proxy-side checks are set aside instead of failing the whole batch
+	var bulk *bulkWrite
+	if p.BulkWrites == BulkWritesBestEffort && isBulkWritePath(r.Method, pathParts, isLinkPath) {
+		requestBody, err := io.ReadAll(r.Body)
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				writePayloadTooLarge(w, maxBytesErr.Limit)
+				return
+			}
+			log.Printf("[PROXY ERROR] Failed to read request body: %v", err)
+			httperr.WriteError(w, httperr.InvalidBody, "failed to read request body")
+			return
+		}
+		if rows, ok := splitBulkBody(requestBody); ok {
+			var check func(row []byte) *bulkRowResult
+			if isRecordWrite && tableID != "" {
+				checks := bulkWriteChecks{tableKey: pathParts[0], tableID: tableID, deprecations: deprecations, computedFields: computedFields, userFields: userFields, writableFields: writableFields, fieldTitles: fieldTitles}
+				check = func(row []byte) *bulkRowResult { return p.checkBulkRow(r.Context(), row, checks) }
+			}
+			if bulk, requestBody, err = p.prepareBulkWrite(r.Context(), rows, check); err != nil {
+				log.Printf("[PROXY ERROR] Failed to prepare bulk write: %v", err)
+				httperr.WriteError(w, httperr.InvalidBody, "failed to read request body")
+				return
+			}
+			bulk.creates = r.Method == http.MethodPost && !isLinkPath
+			if len(bulk.accepted) == 0 {
+				p.finishBulkWrite(r.Context(), w, nil, bulk, 0, nil)
+				return
+			}
+		}
+		r.Body = io.NopCloser(bytes.NewReader(requestBody))
+		reqBody = bytes.NewReader(requestBody)
+	}
+
+	if isRecordWrite && tableID != "" && (validatesSelects || len(computedFields) > 0 || len(userFields) > 0 || len(deprecations) > 0 || len(writableFields) > 0 || setsOwner) {
+		requestBody, err := io.ReadAll(r.Body)
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				writePayloadTooLarge(w, maxBytesErr.Limit)
+				return
+			}
+			log.Printf("[PROXY ERROR] Failed to read request body: %v", err)
+			httperr.WriteError(w, httperr.InvalidBody, "failed to read request body")
+			return
+		}
+
+		if len(writableFields) > 0 {
+			if names := unwritableFields(requestBody, writableFields, fieldTitles); len(names) > 0 {
+				log.Printf("[PROXY ERROR] Write sets fields outside writable_fields: %s", strings.Join(names, ", "))
+				writeUnwritableFields(w, pathParts[0], names)
+				return
+			}
+		}
+
+		if len(deprecations) > 0 {
+			used, sunset := p.deprecatedWriteFields(requestBody, deprecations)
+			if sunset != nil {
+				log.Printf("[PROXY ERROR] Write sets field '%s' past its sunset", sunset.Name)
+				writeFieldSunset(w, pathParts[0], *sunset)
+				return
+			}
+			p.announceDeprecations(w, r, pathParts[0], used, true)
+		}
+
+		if len(computedFields) > 0 {
+			stripped, names, err := stripComputedFields(requestBody, computedFields)
+			if err != nil {
+				log.Printf("[PROXY ERROR] Failed to strip computed fields: %v", err)
+				httperr.WriteError(w, httperr.InvalidBody, "failed to read request body")
+				return
+			}
+			if len(names) > 0 {
+				if p.ComputedFields == ComputedFieldsReject {
+					log.Printf("[PROXY ERROR] Write contains computed fields: %s", strings.Join(names, ", "))
+					writeComputedFieldViolation(w, names)
+					return
+				}
+				logger.Debug("[PROXY] Stripped computed fields from write body: %s", strings.Join(names, ", "))
+				w.Header().Set("X-Proxy-Stripped-Fields", strings.Join(names, ", "))
+				requestBody = stripped
+			}
+		}
+
+		// The owner comes from the token, never from the client (see owner_field)
+		if setsOwner {
+			role, _ := r.Context().Value(middleware.RoleKey).(string)
+			create := r.Method == http.MethodPost && isRecordListPath(pathParts)
+			owned, err := setRecordOwner(requestBody, ownerField, userID, create, ownerOverride && role == "admin")
+			if err != nil {
+				log.Printf("[PROXY ERROR] Failed to set record owner: %v", err)
+				httperr.WriteError(w, httperr.InvalidBody, "failed to read request body")
+				return
+			}
+			if !bytes.Equal(owned, requestBody) {
+				logger.Debug("[PROXY] Set owner field '%s' of write body for user %s", ownerField, userID)
+			}
+			requestBody = owned
+		}
+
+		if len(userFields) > 0 {
+			translated, err := translateUserWrites(r.Context(), requestBody, userFields, p.Collaborators)
+			var unknownUser *unknownUserError
+			if errors.As(err, &unknownUser) {
+				log.Printf("[PROXY ERROR] %v", err)
+				writeUnknownUser(w, unknownUser)
+				return
+			}
+			if err != nil {
+				log.Printf("[PROXY ERROR] Failed to translate user fields: %v", err)
+				httperr.WriteError(w, httperr.InternalError, "failed to resolve user fields")
+				return
+			}
+			requestBody = translated
+		}
+
+		if violation := p.validateSelectValues(r.Context(), tableID, requestBody); violation != nil {
+			log.Printf("[PROXY ERROR] Select validation failed: %v", violation)
+			writeSelectViolation(w, violation)
+			return
+		}
+		reqBody = bytes.NewReader(requestBody)
+		if bulk != nil {
+			bulk.sent = requestBody
+		}
+	}
+
+	// Create a new request to NocoDB; it is cancelled if the client disconnects or the timeout passes
+	upstreamCtx, cancel := p.upstreamContext(r.Context())
+	defer cancel()
+	proxyReq, err := http.NewRequestWithContext(upstreamCtx, r.Method, targetURL, reqBody)
 	if err != nil {
 		log.Printf("[PROXY ERROR] Failed to create proxy request: %v", err)
-		http.Error(w, "failed to create proxy request", http.StatusInternalServerError)
+		httperr.WriteError(w, httperr.InternalError, "failed to create proxy request")
 		return
 	}
-	log.Printf("[PROXY] Created proxy request successfully")
+	logger.Debug("[PROXY] Created proxy request successfully")
 
-	// Copy headers from original request (except Authorization)
+	// Copy headers from original request (except Authorization).
+	// Conditional headers (If-None-Match, If-Modified-Since) must survive so NocoDB can answer 304.
 	for key, values := range r.Header {
 		if key != "Authorization" {
 			for _, value := range values {
@@ -118,38 +741,171 @@ func (p *ProxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// NocoDB only speaks JSON; the proxy does the JSON Lines conversion
+	if ndjson {
+		proxyReq.Header.Set("Accept", "application/json")
+	}
+
 	// Add NocoDB authentication token
 	proxyReq.Header.Set("xc-token", p.NocoDBToken)
-	log.Printf("[PROXY] Added xc-token header")
+	logger.Debug("[PROXY] Added xc-token header")
 
 	// Execute the request
-	log.Printf("[PROXY] Executing request to NocoDB...")
-	client := &http.Client{}
-	resp, err := client.Do(proxyReq)
+	logger.Debug("[PROXY] Executing request to NocoDB...")
+	resp, err := p.doUpstream(proxyReq, callRequest)
 	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			log.Printf("[PROXY ERROR] Request body exceeded %d bytes", maxBytesErr.Limit)
+			writePayloadTooLarge(w, maxBytesErr.Limit)
+			return
+		}
+		if r.Context().Err() != nil {
+			logger.Debug("[PROXY] Client went away, upstream request cancelled: %v", err)
+			return
+		}
+		if isTimeout(err) {
+			log.Printf("[PROXY ERROR] NocoDB did not respond within %v", p.UpstreamTimeout)
+			timeout := p.UpstreamTimeout.String()
+			httperr.WriteErrorParams(w, httperr.UpstreamTimeout, "upstream did not respond within "+timeout, map[string]string{"timeout": timeout})
+			return
+		}
+		if errors.Is(err, ErrBudgetExhausted) {
+			log.Printf("[PROXY ERROR] No upstream calls left for the request itself")
+			httperr.WriteError(w, httperr.UpstreamBudgetExhausted, err.Error())
+			return
+		}
 		log.Printf("[PROXY ERROR] Failed to execute proxy request: %v", err)
-		http.Error(w, "failed to proxy request", http.StatusBadGateway)
+		httperr.WriteError(w, httperr.UpstreamUnavailable, "failed to proxy request")
 		return
 	}
 	defer resp.Body.Close()
-	log.Printf("[PROXY] NocoDB responded with status: %d %s", resp.StatusCode, resp.Status)
+	logger.Debug("[PROXY] NocoDB responded with status: %d %s", resp.StatusCode, resp.Status)
 
-	// Copy response headers (excluding CORS headers to prevent duplicates)
-	for key, values := range resp.Header {
-		// Skip CORS headers - these are handled by CORSMiddleware
-		if strings.HasPrefix(key, "Access-Control-") {
-			continue
+	// Best-effort bulk writes with rejected rows or a refused batch answer 207 with per-row outcomes
+	if bulk != nil && (len(bulk.rejected) > 0 || resp.StatusCode >= 400) {
+		upstreamBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			log.Printf("[PROXY ERROR] Failed to read response body: %v", err)
+			w.fail(httperr.UpstreamReadFailed, "failed to read upstream response", nil)
+			return
+		}
+		p.finishBulkWrite(r.Context(), w, proxyReq, bulk, resp.StatusCode, upstreamBody)
+		return
+	}
+
+	// Copy allowlisted response headers (CORS headers are handled by CORSMiddleware)
+	p.copyResponseHeaders(w.Header(), resp.Header)
+
+	// 304 Not Modified carries no body: relay it as-is, skipping every body transform
+	if resp.StatusCode == http.StatusNotModified {
+		logger.Debug("[PROXY] Upstream returned 304 Not Modified, relaying without body")
+		w.Header().Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	// Only bodies the proxy rewrites (or logs, for errors) are buffered; everything else, e.g. attachments,
+	// single records and writes, streams through with upstream's Content-Length or chunked encoding
+	isOK := resp.StatusCode == http.StatusOK
+	isGet := r.Method == http.MethodGet
+	role, _ := r.Context().Value(middleware.RoleKey).(string)
+	translatesUsers := isGet && isOK && len(userFields) > 0 && !(p.UserFieldsAdminRaw && role == "admin")
+	// Only v3 lists carry page URLs; v2's pageInfo has none, so v2 lists can still stream
+	rewritesPageLinks := apiVersion == "v3" && isGet && isOK && (isRecordListPath(pathParts) || isLinkPath) && isJSONResponse(resp)
+	sunsetFields := p.sunsetFields(deprecations)
+	stripsSunsetFields := isGet && isOK && len(sunsetFields) > 0 && isJSONResponse(resp)
+	// Hidden fields are stripped whatever Content-Type NocoDB declares; a body that isn't JSON is refused
+	stripsHiddenFields := isGet && isOK && (len(hiddenFields) > 0 || len(hiddenLinks) > 0)
+	renamesFields := isGet && isOK && responseAliases != nil && isRecordPath(pathParts) && isJSONResponse(resp)
+	aggregates := isGet && isOK && isRecordListPath(pathParts) && !hasPagingParams(r.URL.Query()) && paginate
+	// The owner is read from the body whatever Content-Type NocoDB declares; one that isn't JSON reads as missing
+	checksOwner = checksOwner && isOK
+	addsCreatedIDs := p.BulkCreatedIDs && r.Method == http.MethodPost && isRecordListPath(pathParts) &&
+		resp.StatusCode >= 200 && resp.StatusCode < 300 && isJSONResponse(resp)
+	// What rewriteResponse does to a buffered body; a JSON Lines stream applies the record transforms per page
+	rewrites := responseRewrites{
+		recordTransforms: recordTransforms{tableKey: pathParts[0], apiVersion: apiVersion, directory: p.Collaborators, aliasDepth: p.ResponseAliasDepth},
+		pathParts:        pathParts,
+		tableID:          tableID,
+		targetURL:        targetURL,
+		createdIDs:       addsCreatedIDs,
+		aggregates:       aggregates,
+		pagination:       pagination,
+		cursor:           isListRequest && isOK,
+		pageLinks:        rewritesPageLinks,
+		budget:           budget,
+	}
+	if checksOwner {
+		rewrites.ownerField, rewrites.userID = ownerField, userID
+	}
+	if sortInjected && verifySort {
+		rewrites.verifySort = defaultSort
+	}
+	if includeCommentCount && isOK {
+		rewrites.commentCounts = p.CommentCounts
+	}
+	if translatesUsers {
+		rewrites.userFields = userFields
+	}
+	if stripsSunsetFields {
+		rewrites.sunsetFields = sunsetFields
+	}
+	if stripsHiddenFields {
+		rewrites.hiddenFields, rewrites.hiddenLinks = hiddenFields, hiddenLinks
+	}
+	if isGet && isOK {
+		rewrites.responseFilter = responseFilter
+		rewrites.maxRecords = p.MaxResponseRecords
+	}
+	if renamesFields {
+		rewrites.responseAliases = responseAliases
+	}
+	if ndjson && isOK && isJSONResponse(resp) {
+		firstPage, err := io.ReadAll(resp.Body)
+		if err != nil {
+			log.Printf("[PROXY ERROR] Failed to read response body: %v", err)
+			w.fail(httperr.UpstreamReadFailed, "failed to read upstream response", nil)
+			return
+		}
+		p.streamNDJSON(r.Context(), w, firstPage, targetURL, apiVersion, aggregates, pagination, rewrites.recordTransforms)
+		return
+	}
+	rewritesBody := resp.StatusCode >= 400 ||
+		(aggregates && sortInjected && verifySort) ||
+		(isGet && isOK && (p.MaxResponseRecords > 0 || len(responseFilter) > 0)) ||
+		(isOK && (includeCommentCount || isListRequest)) ||
+		translatesUsers || rewritesPageLinks || stripsSunsetFields || stripsHiddenFields || renamesFields || checksOwner || addsCreatedIDs
+	// With the streaming feature off every response is read in full before it is sent
+	buffers := rewritesBody || !p.Features.Enabled(config.FeatureStreaming)
+	respBody := bufio.NewReaderSize(resp.Body, paginationPeekBytes)
+	if !buffers && aggregates {
+		// A list is only merged when it has a next page; peek instead of reading it all to find out
+		if hasNext, known := p.peekNextPage(respBody, targetURL, apiVersion); known && !hasNext {
+			logger.Debug("[PAGINATION] Single-page list, streaming")
+			p.recordFanout(1) // still a list request for the fan-out average
+			aggregates = false
 		}
-		for _, value := range values {
-			w.Header().Add(key, value)
+	}
+	if !buffers && !aggregates {
+		if budget.Exhausted() {
+			w.Header().Set(BudgetExhaustedHeader, "true") // retries were cut short
 		}
+		p.canonicalizeStreamedJSONType(w.Header(), respBody)
+		p.streamResponse(w, resp.StatusCode, respBody)
+		return
 	}
 
 	// Read response body for logging
-	body, err := io.ReadAll(resp.Body)
+	body, err := io.ReadAll(respBody)
 	if err != nil {
 		log.Printf("[PROXY ERROR] Failed to read response body: %v", err)
-		http.Error(w, "failed to read response", http.StatusInternalServerError)
+		if isTimeout(err) {
+			timeout := p.UpstreamTimeout.String()
+			w.fail(httperr.UpstreamTimeout, "upstream did not respond within "+timeout, map[string]string{"timeout": timeout})
+			return
+		}
+		w.fail(httperr.UpstreamReadFailed, "failed to read upstream response", nil)
 		return
 	}
 
@@ -157,59 +913,61 @@ func (p *ProxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if resp.StatusCode >= 400 {
 		log.Printf("[PROXY ERROR] NocoDB error response (status %d): %s", resp.StatusCode, string(body))
 	} else {
-		log.Printf("[PROXY] Response body length: %d bytes", len(body))
+		logger.Debug("[PROXY] Response body length: %d bytes", len(body))
 		if len(body) < 500 {
-			log.Printf("[PROXY] Response body: %s", string(body))
+			logger.Debug("[PROXY] Response body: %s", string(body))
 		}
 	}
 
+	body, ok = p.rewriteResponse(w, r, resp.StatusCode, body, rewrites)
+	if !ok {
+		return
+	}
+	p.canonicalizeJSONType(w.Header(), body)
+
 	// Set status code
 	w.WriteHeader(resp.StatusCode)
 
-	// Write response body
-	_, err = w.Write(body)
-	if err != nil {
-		log.Printf("[PROXY ERROR] Failed to write response: %v", err)
+	// Write response body; a client write error leaves nothing to salvage, so only log it
+	if _, err := w.Write(body); err != nil {
+		log.Printf("[PROXY ERROR] Failed to write response (client gone?): %v", err)
+		return
 	}
-	log.Printf("[PROXY] Request completed successfully")
+	logger.Debug("[PROXY] Request completed successfully")
 }
 
 // resolveLinkFieldInPath detects link requests and resolves link field aliases to field IDs
-// Handles paths like: links/{linkAlias}/{recordId} -> links/{linkFieldID}/{recordId}
+// Handles both links-first and records-first shapes and normalizes them to the upstream API version:
+// links/{linkAlias}/{recordId} or records/{recordId}/links/{linkAlias} -> links/{linkFieldID}/{recordId}
 func (p *ProxyHandler) resolveLinkFieldInPath(tableID, tableName, remainingPath string) (string, error) {
 	// Split the remaining path to check if it's a link request
-	parts := strings.Split(remainingPath, "/")
-
-	// Check if this is a link request: links/{linkAlias}/{recordId}
-	// Pattern: parts[0] = "links", parts[1] = linkAlias, parts[2] = recordId
-	if len(parts) >= 3 && parts[0] == "links" {
-		linkAlias := parts[1]
-		log.Printf("[LINK RESOLVER] Detected link request for table '%s', alias '%s'", tableName, linkAlias)
+	link, isLink := parseLinkPath(strings.Split(remainingPath, "/"))
+	if !isLink {
+		return remainingPath, nil
+	}
 
-		// Try to resolve the link field alias to field ID using MetaCache
-		if p.Meta != nil {
-			// Try direct match first
-			linkFieldID, ok := p.Meta.ResolveLinkField(tableID, linkAlias)
-			if !ok {
-				// Try normalized version (replace underscores with spaces)
-				normalizedAlias := strings.ReplaceAll(linkAlias, "_", " ")
-				linkFieldID, ok = p.Meta.ResolveLinkField(tableID, normalizedAlias)
-			}
+	linkAlias := link.Alias
+	logger.Debug("[LINK RESOLVER] Detected link request for table '%s', alias '%s'", tableName, linkAlias)
 
-			if ok {
-				log.Printf("[LINK RESOLVER] %s.%s → %s", tableName, linkAlias, linkFieldID)
-				// Replace the alias with the resolved field ID
-				parts[1] = linkFieldID
-				return strings.Join(parts, "/"), nil
-			}
+	// Try to resolve the link field alias to field ID using MetaCache
+	if p.Meta == nil {
+		log.Printf("[LINK RESOLVER WARNING] MetaCache not available, using alias as-is")
+		return link.build(linkAlias, detectAPIVersion(p.NocoDBURL)), nil
+	}
 
-			// Link field not found in cache
-			return "", fmt.Errorf("unknown link field '%s' for table '%s'", linkAlias, tableName)
-		}
+	// Try direct match first
+	linkFieldID, ok := p.Meta.ResolveLinkField(tableID, linkAlias)
+	if !ok {
+		// Try normalized version (replace underscores with spaces)
+		normalizedAlias := strings.ReplaceAll(linkAlias, "_", " ")
+		linkFieldID, ok = p.Meta.ResolveLinkField(tableID, normalizedAlias)
+	}
 
-		log.Printf("[LINK RESOLVER WARNING] MetaCache not available, using alias as-is")
+	if !ok {
+		// Link field not found in cache
+		return "", unknownLinkFieldError(linkAlias, tableName)
 	}
 
-	// Not a link request or MetaCache unavailable, return path as-is
-	return remainingPath, nil
+	logger.Debug("[LINK RESOLVER] %s.%s → %s", tableName, linkAlias, linkFieldID)
+	return link.build(linkFieldID, detectAPIVersion(p.NocoDBURL)), nil
 }
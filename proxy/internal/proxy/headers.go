@@ -0,0 +1,52 @@
+package proxy
+
+import (
+	"log"
+	"net/http"
+)
+
+// defaultResponseHeaders are the upstream response headers relayed to clients.
+// Everything else (Server, X-Powered-By, Set-Cookie, debugging headers) is dropped
+// so NocoDB internals don't leak to the browser.
+var defaultResponseHeaders = []string{
+	"Content-Type",
+	"Content-Length",
+	"Content-Encoding",
+	"Content-Disposition",
+	"Cache-Control",
+	"ETag",
+	"Last-Modified",
+}
+
+// newResponseHeaderAllowlist builds the allowlist from the defaults
+func newResponseHeaderAllowlist() map[string]bool {
+	allow := make(map[string]bool, len(defaultResponseHeaders))
+	for _, h := range defaultResponseHeaders {
+		allow[http.CanonicalHeaderKey(h)] = true
+	}
+	return allow
+}
+
+// AllowResponseHeaders adds extra upstream headers to the relay allowlist.
+// Deployments that need Set-Cookie or Server passed through list them here.
+func (p *ProxyHandler) AllowResponseHeaders(headers ...string) {
+	for _, h := range headers {
+		if h == "" {
+			continue
+		}
+		p.responseHeaders[http.CanonicalHeaderKey(h)] = true
+		log.Printf("[PROXY] Response header allowlisted: %s", http.CanonicalHeaderKey(h))
+	}
+}
+
+// copyResponseHeaders copies allowlisted upstream headers to the client response
+func (p *ProxyHandler) copyResponseHeaders(dst, src http.Header) {
+	for key, values := range src {
+		if !p.responseHeaders[http.CanonicalHeaderKey(key)] {
+			continue
+		}
+		for _, value := range values {
+			dst.Add(key, value)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/grove/generic-proxy/internal/config"
+)
+
+// leakyUpstream answers with body and the given headers plus the ones NocoDB and its host add
+func leakyUpstream(t *testing.T, headers map[string]string, body string) *fakeUpstream {
+	return newFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "nginx/1.25")
+		w.Header().Set("X-Powered-By", "Express")
+		w.Header().Set("Set-Cookie", "nocodb_session=abc; Path=/")
+		w.Header().Set("X-Nc-Debug", "trace=123")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		for key, value := range headers {
+			w.Header().Set(key, value)
+		}
+		io.WriteString(w, body)
+	})
+}
+
+// headerNames returns the sorted header names of h
+func headerNames(h http.Header) []string {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestResponseHeaderAllowlist(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string
+		headers map[string]string
+		body    string
+		want    []string
+	}{
+		{
+			name:   "record list",
+			target: "/proxy/quotes/records",
+			headers: map[string]string{
+				"Content-Type":  "application/json; charset=utf-8",
+				"Cache-Control": "no-cache",
+				"Etag":          `W/"1a"`,
+			},
+			body: `{"list":[{"Id":1}],"pageInfo":{"isLastPage":true}}`,
+			want: []string{"Cache-Control", "Content-Length", "Content-Type", "Etag"},
+		},
+		{
+			name:   "file download",
+			target: "/proxy/quotes/records/1",
+			headers: map[string]string{
+				"Content-Type":        "application/pdf",
+				"Content-Length":      "4",
+				"Content-Disposition": `attachment; filename="quote.pdf"`,
+				"Last-Modified":       "Mon, 02 Jan 2006 15:04:05 GMT",
+			},
+			body: "%PDF",
+			want: []string{"Content-Disposition", "Content-Length", "Content-Type", "Last-Modified"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			up := leakyUpstream(t, tt.headers, tt.body)
+			p := newSchemaHandler(up, map[string]config.ResolvedTable{"quotes": quotesTable()})
+
+			rec := serve(p, http.MethodGet, tt.target, "", "7", "user")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+			}
+			if got := headerNames(rec.Header()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("response headers = %v, want exactly %v", got, tt.want)
+			}
+			for key, value := range tt.headers {
+				if key != "Content-Length" && rec.Header().Get(key) != value {
+					t.Errorf("%s = %q, want %q relayed unchanged", key, rec.Header().Get(key), value)
+				}
+			}
+		})
+	}
+}
+
+func TestResponseHeaderAllowlistExtra(t *testing.T) {
+	up := leakyUpstream(t, map[string]string{"Content-Type": "application/pdf"}, "%PDF")
+	p := newSchemaHandler(up, map[string]config.ResolvedTable{"quotes": quotesTable()})
+	p.AllowResponseHeaders("set-cookie", "")
+
+	rec := serve(p, http.MethodGet, "/proxy/quotes/records/1", "", "7", "user")
+	if got, want := headerNames(rec.Header()), []string{"Content-Length", "Content-Type", "Set-Cookie"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("response headers = %v, want exactly %v", got, want)
+	}
+}
+
+func TestConditionalGetRelays304(t *testing.T) {
+	up := newFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Etag", `W/"1a"`)
+		if r.Header.Get("If-None-Match") == `W/"1a"` || r.Header.Get("If-Modified-Since") != "" {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		jsonHandler(http.StatusOK, `{"list":[{"Id":1}],"pageInfo":{"isLastPage":false}}`)(w, r)
+	})
+	p := newSchemaHandler(up, map[string]config.ResolvedTable{"quotes": quotesTable()})
+
+	for _, header := range []string{"If-None-Match", "If-Modified-Since"} {
+		t.Run(header, func(t *testing.T) {
+			before := len(up.Requests())
+			req := httptest.NewRequest(http.MethodGet, "/proxy/quotes/records", nil)
+			req.Header.Set("If-None-Match", `W/"1a"`)
+			if header == "If-Modified-Since" {
+				req.Header.Del("If-None-Match")
+				req.Header.Set("If-Modified-Since", "Mon, 02 Jan 2006 15:04:05 GMT")
+			}
+			req = req.WithContext(withUser(req.Context(), "7", "user"))
+			rec := httptest.NewRecorder()
+			p.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+				t.Fatalf("status = %d, body %q; want 304 without a body", rec.Code, rec.Body)
+			}
+			if rec.Header().Get("Etag") != `W/"1a"` {
+				t.Errorf("Etag = %q, want it relayed", rec.Header().Get("Etag"))
+			}
+			requests := up.Requests()[before:]
+			if len(requests) != 1 {
+				t.Fatalf("upstream got %d requests, want 1 (a 304 must not be paginated)", len(requests))
+			}
+			if requests[0].Header.Get(header) != req.Header.Get(header) {
+				t.Errorf("%s = %q upstream, want it forwarded", header, requests[0].Header.Get(header))
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/middleware"
+)
+
+// upstreamRequest is a request the fake NocoDB received
+type upstreamRequest struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   string
+}
+
+// fakeUpstream is a NocoDB stand-in that records every request before handing it to its handler
+type fakeUpstream struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []upstreamRequest
+}
+
+// newFakeUpstream starts a fake NocoDB; the server is closed when the test ends
+func newFakeUpstream(t *testing.T, handler http.HandlerFunc) *fakeUpstream {
+	t.Helper()
+	u := &fakeUpstream{}
+	u.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		u.mu.Lock()
+		u.requests = append(u.requests, upstreamRequest{Method: r.Method, Path: r.URL.Path, Query: r.URL.Query(), Header: r.Header.Clone(), Body: string(body)})
+		u.mu.Unlock()
+		r.Body = io.NopCloser(strings.NewReader(string(body)))
+		handler(w, r)
+	}))
+	t.Cleanup(u.Close)
+	return u
+}
+
+// Requests returns the requests received so far
+func (u *fakeUpstream) Requests() []upstreamRequest {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return append([]upstreamRequest(nil), u.requests...)
+}
+
+// jsonHandler answers every request with status and body as JSON
+func jsonHandler(status int, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}
+}
+
+// newLegacyHandler returns a handler for a v2 NocoDB at u without a resolved config
+func newLegacyHandler(u *fakeUpstream) *ProxyHandler {
+	return NewProxyHandler(u.URL+"/api/v2/tables/", "test-token", nil, u.Client())
+}
+
+// newSchemaHandler returns a handler for a v2 NocoDB at u serving tables. Options are applied
+// before the config is resolved, since some of them only take effect with SetResolvedConfig.
+func newSchemaHandler(u *fakeUpstream, tables map[string]config.ResolvedTable, options ...func(*ProxyHandler)) *ProxyHandler {
+	p := newLegacyHandler(u)
+	for _, option := range options {
+		option(p)
+	}
+	p.SetResolvedConfig(&config.ResolvedConfig{BaseID: "base", Tables: tables})
+	return p
+}
+
+// quotesTable is a readable and writable table "quotes" with NocoDB ID t1
+func quotesTable() config.ResolvedTable {
+	return config.ResolvedTable{Name: "Quotes", TableID: "t1", Operations: []string{"read", "create", "update", "delete"}}
+}
+
+// withUser returns ctx carrying an authenticated user the way AuthMiddleware leaves it
+func withUser(ctx context.Context, userID, role string) context.Context {
+	ctx = context.WithValue(ctx, middleware.UserIDKey, userID)
+	return context.WithValue(ctx, middleware.RoleKey, role)
+}
+
+// serve sends a request through h as the given user and returns the recorded response
+func serve(h http.Handler, method, target, body, userID, role string) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, target, reader)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req = req.WithContext(withUser(req.Context(), userID, role))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+// newFrontServer serves h over a real HTTP connection as the given user, for tests that check
+// framing or connection handling; the server is closed when the test ends
+func newFrontServer(t *testing.T, h http.Handler, userID, role string) *httptest.Server {
+	t.Helper()
+	front := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r.WithContext(withUser(r.Context(), userID, role)))
+	}))
+	t.Cleanup(front.Close)
+	return front
+}
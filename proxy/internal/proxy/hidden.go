@@ -0,0 +1,139 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/httperr"
+)
+
+// hiddenResponseFields returns the hidden fields of the records a read returns: the table's own
+// for its records, with the target tables' hidden fields per embedded link field, or the target
+// table's for a link read, which lists linked records
+func hiddenResponseFields(table config.ResolvedTable, pathParts []string) (map[string]bool, map[string]map[string]bool) {
+	if link, isLink := parseLinkPath(pathParts[1:]); isLink {
+		if resolved, ok := configuredLink(table, link.Alias); ok {
+			return resolved.HiddenFields, nil
+		}
+		return nil, nil
+	}
+
+	var links map[string]map[string]bool
+	for _, link := range table.Links {
+		if len(link.HiddenFields) > 0 {
+			if links == nil {
+				links = make(map[string]map[string]bool)
+			}
+			links[link.Title] = link.HiddenFields
+		}
+	}
+	return table.HiddenFields, links
+}
+
+// isHiddenField reports whether a field named in a request (alias, title or field ID) is hidden
+func isHiddenField(table config.ResolvedTable, name string) bool {
+	if len(table.HiddenFields) == 0 {
+		return false
+	}
+	return table.HiddenFields[name] || table.HiddenFields[table.FieldTitles[name]] || table.HiddenFields[table.Fields[name]]
+}
+
+// hiddenFieldError reports a request selecting, filtering or sorting on a hidden field
+func hiddenFieldError(name, tableKey string) *ValidationError {
+	return newValidationError(httperr.FieldNotAllowed, "field '%s' is not exposed for table '%s'", name, tableKey).
+		withParams(map[string]string{"field": name, "table": tableKey})
+}
+
+// checkHiddenProjection rejects a fields parameter that names a hidden field
+func checkHiddenProjection(tableKey string, table config.ResolvedTable, values []string) error {
+	for _, value := range values {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); isHiddenField(table, name) {
+				return hiddenFieldError(name, tableKey)
+			}
+		}
+	}
+	return nil
+}
+
+// stripHiddenFields removes hidden fields from every record of a list or single-record response, and
+// from the linked records embedded in its link fields (links maps a link field title to the hidden
+// fields of its target table). The body is parsed whatever its Content-Type claims; one that isn't a
+// JSON object can't be checked and is an error, so it never reaches the client unstripped.
+func stripHiddenFields(body []byte, hidden map[string]bool, links map[string]map[string]bool) ([]byte, bool, error) {
+	var envelope map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber() // keep large numbers exact when re-encoding
+	if err := decoder.Decode(&envelope); err != nil {
+		return nil, false, fmt.Errorf("response is not a JSON object: %w", err)
+	}
+
+	var records []map[string]interface{}
+	listKey := ""
+	for _, key := range recordListKeys {
+		if list, ok := envelope[key].([]interface{}); ok {
+			listKey = key
+			for _, item := range list {
+				if record, ok := item.(map[string]interface{}); ok {
+					records = append(records, recordFields(record))
+				}
+			}
+			break
+		}
+	}
+	if listKey == "" {
+		records = []map[string]interface{}{recordFields(envelope)}
+	}
+
+	stripped := false
+	for _, fields := range records {
+		if stripFields(fields, hidden) {
+			stripped = true
+		}
+		for title, nestedHidden := range links {
+			if stripLinked(fields[title], nestedHidden) {
+				stripped = true
+			}
+		}
+	}
+	if !stripped {
+		return body, false, nil
+	}
+	rewritten, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, false, err
+	}
+	return rewritten, true, nil
+}
+
+// stripFields deletes the hidden keys of one field map
+func stripFields(fields map[string]interface{}, hidden map[string]bool) bool {
+	stripped := false
+	for name := range fields {
+		if hidden[name] {
+			delete(fields, name)
+			stripped = true
+		}
+	}
+	return stripped
+}
+
+// stripLinked strips hidden fields from the value of a link field: one linked record or an array of them
+func stripLinked(value interface{}, hidden map[string]bool) bool {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return stripFields(recordFields(v), hidden)
+	case []interface{}:
+		stripped := false
+		for _, item := range v {
+			if stripLinked(item, hidden) {
+				stripped = true
+			}
+		}
+		return stripped
+	}
+	return false
+}
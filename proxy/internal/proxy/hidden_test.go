@@ -0,0 +1,43 @@
+package proxy
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/httperr"
+)
+
+// hiddenQuotes serves quotes with the hidden field Margin
+func hiddenQuotes(up *fakeUpstream) *ProxyHandler {
+	table := quotesTable()
+	table.HiddenFields = map[string]bool{"Margin": true}
+	return newSchemaHandler(up, map[string]config.ResolvedTable{"quotes": table})
+}
+
+// typedHandler answers 200 with body and the given Content-Type
+func typedHandler(contentType, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.Write([]byte(body))
+	}
+}
+
+func TestHiddenFieldsStrippedWhateverTheContentType(t *testing.T) {
+	for _, contentType := range []string{"application/json", "text/plain", "application/octet-stream", ""} {
+		up := newFakeUpstream(t, typedHandler(contentType, `{"Id":1,"Title":"a","Margin":40}`))
+		rec := serve(hiddenQuotes(up), http.MethodGet, "/proxy/quotes/records/1", "", "7", "user")
+		if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "Margin") {
+			t.Errorf("Content-Type %q: status = %d, body %s; want Margin stripped", contentType, rec.Code, rec.Body)
+		}
+	}
+}
+
+func TestHiddenFieldsRefuseNonJSONBody(t *testing.T) {
+	up := newFakeUpstream(t, typedHandler("text/html", `<p>Margin: 40</p>`))
+	rec := serve(hiddenQuotes(up), http.MethodGet, "/proxy/quotes/records/1", "", "7", "user")
+	if rec.Code != http.StatusBadGateway || decodeError(t, rec.Body.Bytes()).Code != httperr.UpstreamReadFailed {
+		t.Errorf("status = %d, body %s; want 502 %s", rec.Code, rec.Body, httperr.UpstreamReadFailed)
+	}
+}
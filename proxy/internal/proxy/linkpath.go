@@ -0,0 +1,54 @@
+package proxy
+
+import "strings"
+
+// linkPath is a parsed link sub-path (everything after the table segment).
+// Clients may send either of the shapes found in the NocoDB docs:
+//
+//	links/{linkAlias}/{recordId}          (v3 data API, links-first)
+//	links/{linkAlias}/records/{recordId}  (v2 tables API, links-first)
+//	records/{recordId}/links/{linkAlias}  (records-first)
+type linkPath struct {
+	Alias    string
+	RecordID string
+	Rest     []string // trailing segments after the link/record pair
+}
+
+// parseLinkPath detects a link sub-path in either links-first or records-first order
+func parseLinkPath(parts []string) (*linkPath, bool) {
+	if len(parts) >= 3 && parts[0] == "links" {
+		// links/{alias}/records/{recordId}
+		if len(parts) >= 4 && parts[2] == "records" {
+			return &linkPath{Alias: parts[1], RecordID: parts[3], Rest: parts[4:]}, true
+		}
+		// links/{alias}/{recordId}
+		return &linkPath{Alias: parts[1], RecordID: parts[2], Rest: parts[3:]}, true
+	}
+
+	// records/{recordId}/links/{alias}
+	if len(parts) >= 4 && parts[0] == "records" && parts[2] == "links" {
+		return &linkPath{Alias: parts[3], RecordID: parts[1], Rest: parts[4:]}, true
+	}
+
+	return nil, false
+}
+
+// build renders the link path in the shape the configured NocoDB API version expects
+func (l *linkPath) build(fieldID, apiVersion string) string {
+	var parts []string
+	if apiVersion == "v2" {
+		parts = []string{"links", fieldID, "records", l.RecordID}
+	} else {
+		parts = []string{"links", fieldID, l.RecordID}
+	}
+	return strings.Join(append(parts, l.Rest...), "/")
+}
+
+// detectAPIVersion infers the NocoDB data API version from the upstream URL
+// Example: "http://host:8090/api/v2/tables/" -> "v2", anything else -> "v3"
+func detectAPIVersion(nocoDBURL string) string {
+	if strings.Contains(nocoDBURL, "/api/v2/") {
+		return "v2"
+	}
+	return "v3"
+}
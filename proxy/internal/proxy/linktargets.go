@@ -0,0 +1,62 @@
+package proxy
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/httperr"
+)
+
+// Link target permission modes (LINK_TARGET_PERMISSIONS): whether a link request is also checked
+// against the operations of the table the link points to
+const (
+	LinkTargetsOff     = "off"     // only the source table's operations count
+	LinkTargetsEnforce = "enforce" // link reads need read on the target table, link writes need link or unlink
+)
+
+// checkLinkTarget requires the operation a link request implies on the link's target table: read
+// to list the linked records, link to add links and unlink to remove them. The target must be
+// configured, since its permissions are unknown otherwise. The target's operations are those of
+// the caller's role.
+func (v *Validator) checkLinkTarget(tableKey string, table config.ResolvedTable, alias, method, role string) error {
+	operation := "link"
+	switch method {
+	case http.MethodGet:
+		operation = "read"
+	case http.MethodDelete:
+		operation = "unlink"
+	}
+
+	link, ok := configuredLink(table, alias)
+	if !ok {
+		return newValidationError(httperr.LinkNotAllowed, "link '%s' is not configured for table '%s'", alias, tableKey).
+			withParams(map[string]string{"link": alias, "table": tableKey})
+	}
+	targetKey, target, ok := v.linkTargetTable(link.TargetTable)
+	if !ok {
+		log.Printf("[VALIDATOR] Link '%s' of table '%s' targets unconfigured table '%s'", alias, tableKey, link.TargetTable)
+		return newValidationError(httperr.OperationNotAllowed, "operation '%s' not allowed for table '%s'", operation, link.TargetTable).
+			withParams(map[string]string{"operation": operation, "table": link.TargetTable})
+	}
+	if !v.isOperationAllowed(target, role, operation) {
+		log.Printf("[VALIDATOR] Link '%s' of table '%s' denied: target table '%s' does not allow %s", alias, tableKey, targetKey, operation)
+		return newValidationError(httperr.OperationNotAllowed, "operation '%s' not allowed for table '%s'", operation, targetKey).
+			withParams(map[string]string{"operation": operation, "table": targetKey})
+	}
+	return nil
+}
+
+// linkTargetTable finds a link's target table by table key or, failing that, by NocoDB table name
+func (v *Validator) linkTargetTable(targetTable string) (string, config.ResolvedTable, bool) {
+	if table, ok := v.config.Tables[targetTable]; ok {
+		return targetTable, table, true
+	}
+	for tableKey, table := range v.config.Tables {
+		if strings.EqualFold(table.Name, targetTable) {
+			return tableKey, table, true
+		}
+	}
+	return "", config.ResolvedTable{}, false
+}
@@ -0,0 +1,74 @@
+package proxy
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/httperr"
+)
+
+// linkTargetConfig has quotes, which allows every link operation, linking to a table named by
+// target; items is configured with itemOperations, items by role with itemRoles
+func linkTargetConfig(target string, itemOperations []string, itemRoles map[string][]string) *config.ResolvedConfig {
+	quotes := quotesTable()
+	quotes.Operations = append(quotes.Operations, "link", "unlink")
+	quotes.Links = map[string]config.ResolvedLink{"items": {FieldID: "c1", Title: "Items", TargetTable: target, Pinned: true}}
+	items := config.ResolvedTable{Name: "Line Items", TableID: "t2", Operations: itemOperations, RoleOperations: itemRoles}
+	return &config.ResolvedConfig{BaseID: "base", Tables: map[string]config.ResolvedTable{"quotes": quotes, "items": items}}
+}
+
+func TestLinkTargetPermissions(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       string
+		target     string
+		operations []string
+		roles      map[string][]string
+		method     string
+		wantAllow  bool
+	}{
+		{"off ignores the target", LinkTargetsOff, "items", nil, nil, http.MethodGet, true},
+		{"target forbids reads", LinkTargetsEnforce, "items", []string{"create"}, nil, http.MethodGet, false},
+		{"target allows reads", LinkTargetsEnforce, "items", []string{"read"}, nil, http.MethodGet, true},
+		{"target matched by name", LinkTargetsEnforce, "Line Items", []string{"read"}, nil, http.MethodGet, true},
+		{"link needs link on target", LinkTargetsEnforce, "items", []string{"read"}, nil, http.MethodPost, false},
+		{"unlink needs unlink on target", LinkTargetsEnforce, "items", []string{"read", "link"}, nil, http.MethodDelete, false},
+		{"target unlink allowed", LinkTargetsEnforce, "items", []string{"unlink"}, nil, http.MethodDelete, true},
+		{"target role denies", LinkTargetsEnforce, "items", []string{"read"}, map[string][]string{"user": {"create"}}, http.MethodGet, false},
+		{"unconfigured target", LinkTargetsEnforce, "orders", []string{"read"}, nil, http.MethodGet, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewValidator(linkTargetConfig(tt.target, tt.operations, tt.roles), nil, "v2")
+			v.linkTargets = tt.mode
+			_, err := v.ValidateRequest(tt.method, "quotes/records/5/links/items", "user", nil)
+			if tt.wantAllow {
+				if err != nil {
+					t.Errorf("%s: %v, want allowed", tt.method, err)
+				}
+				return
+			}
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) || validationErr.Code != httperr.OperationNotAllowed {
+				t.Errorf("%s: %v, want %s", tt.method, err, httperr.OperationNotAllowed)
+			}
+		})
+	}
+}
+
+func TestLinkTargetDeniedThroughHandler(t *testing.T) {
+	up := newFakeUpstream(t, jsonHandler(http.StatusOK, `{"list":[]}`))
+	p := newLegacyHandler(up)
+	p.LinkTargetPermissions = LinkTargetsEnforce
+	p.SetResolvedConfig(linkTargetConfig("items", []string{"create"}, nil))
+
+	rec := serve(p, http.MethodGet, "/proxy/quotes/records/5/links/items", "", "7", "user")
+	if rec.Code != http.StatusForbidden || decodeError(t, rec.Body.Bytes()).Code != httperr.OperationNotAllowed {
+		t.Errorf("status = %d, body %s; want 403 %s", rec.Code, rec.Body, httperr.OperationNotAllowed)
+	}
+	if n := len(up.Requests()); n != 0 {
+		t.Errorf("NocoDB got %d requests, want none", n)
+	}
+}
@@ -1,7 +1,9 @@
 package proxy
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -13,9 +15,46 @@ import (
 
 // FieldMeta represents metadata for a single field/column
 type FieldMeta struct {
-	ID    string `json:"id"`
+	ID         string        `json:"id"`
+	Title      string        `json:"title"`
+	Type       string        `json:"type"`
+	Options    *FieldOptions `json:"options,omitempty"`    // v3 meta: select choices
+	ColOptions *FieldOptions `json:"colOptions,omitempty"` // v2 meta: select options
+}
+
+// FieldOptions holds the choice list of a SingleSelect/MultiSelect field
+type FieldOptions struct {
+	Choices []SelectChoice `json:"choices,omitempty"`
+	Options []SelectChoice `json:"options,omitempty"`
+}
+
+// SelectChoice is a single allowed select value
+type SelectChoice struct {
 	Title string `json:"title"`
-	Type  string `json:"type"`
+}
+
+// SelectField describes a select column and its allowed values
+type SelectField struct {
+	Type    string   `json:"type"` // SingleSelect or MultiSelect
+	Options []string `json:"options"`
+}
+
+// selectFieldFromMeta extracts the allowed values of a select field, if it is one
+func selectFieldFromMeta(field FieldMeta) (SelectField, bool) {
+	if field.Type != "SingleSelect" && field.Type != "MultiSelect" {
+		return SelectField{}, false
+	}
+
+	selectField := SelectField{Type: field.Type, Options: []string{}}
+	for _, opts := range []*FieldOptions{field.Options, field.ColOptions} {
+		if opts == nil {
+			continue
+		}
+		for _, choice := range append(opts.Choices, opts.Options...) {
+			selectField.Options = append(selectField.Options, choice.Title)
+		}
+	}
+	return selectField, true
 }
 
 // TableMeta represents metadata for a single NocoDB table
@@ -32,32 +71,79 @@ type TablesResponse struct {
 	List []TableMeta `json:"list"`
 }
 
+// metaRetryInterval is the auto-refresh interval used until the first successful load
+const metaRetryInterval = 30 * time.Second
+
 // MetaCache maintains a thread-safe cache of table name to ID mappings
 type MetaCache struct {
+	refreshMu         sync.Mutex    // held for a whole refresh, whoever started it, so refreshes never interleave
+	driftMu           sync.Mutex    // guards drift
+	drift             *driftRefresh // drift refresh in flight, joined by concurrent callers
 	mu                sync.RWMutex
-	tableByName       map[string]string            // lowercase friendly title -> table ID
-	fieldsByTable     map[string]map[string]string // table ID -> (lowercase field name -> field ID)
-	linkFieldsByTable map[string]map[string]string // table ID -> (lowercase link field name -> field ID)
-	metaBaseURL       string                       // e.g. http://100.103.198.65:8090/api/v2/
-	baseID            string                       // NocoDB base ID
-	token             string                       // NOCODB_TOKEN
+	tableByName       map[string]string                 // lowercase friendly title -> table ID
+	fieldsByTable     map[string]map[string]string      // table ID -> (lowercase field name -> field ID)
+	linkFieldsByTable map[string]map[string]string      // table ID -> (lowercase link field name -> field ID)
+	selectsByTable    map[string]map[string]SelectField // table ID -> (field title -> select options)
+	computedByTable   map[string]map[string]string      // table ID -> (field title -> type) of read-only computed fields
+	userFieldsByTable map[string]map[string]string      // table ID -> (field title -> type) of collaborator fields
+	metaBaseURL       string                            // e.g. http://100.103.198.65:8090/api/v2/
+	baseID            string                            // NocoDB base ID
+	token             string                            // NOCODB_TOKEN
 	httpClient        *http.Client
 	lastLoadedAt      time.Time
 	refreshInterval   time.Duration
+	tableCount        int             // tables returned by the last refresh
+	minTables         int             // readiness requires at least this many tables
+	lastErr           *MetaFetchError // most recent failure, cleared by a clean refresh
+	refreshStats      *RefreshStats   // timing and failures of the last completed refresh
+	fetchConcurrency  int             // table details fetched in parallel
+	fetchRate         float64         // table details requests per second, 0 = unlimited
+	fetchRetries      int             // retries of a failing table details request
 }
 
-// NewMetaCache creates a new MetaCache instance
-func NewMetaCache(metaBaseURL, baseID, token string) *MetaCache {
+// metaRequestTimeout bounds each metadata request, whatever the shared client's Timeout
+const metaRequestTimeout = 10 * time.Second
+
+// NewMetaCache creates a new MetaCache instance. client is shared with the proxy's other NocoDB
+// calls; nil means NewUpstreamClient(DefaultUpstreamClientOptions).
+func NewMetaCache(metaBaseURL, baseID, token string, client *http.Client) *MetaCache {
+	if client == nil {
+		client = NewUpstreamClient(DefaultUpstreamClientOptions)
+	}
 	return &MetaCache{
 		tableByName:       make(map[string]string),
 		fieldsByTable:     make(map[string]map[string]string),
 		linkFieldsByTable: make(map[string]map[string]string),
+		selectsByTable:    make(map[string]map[string]SelectField),
+		computedByTable:   make(map[string]map[string]string),
+		userFieldsByTable: make(map[string]map[string]string),
 		metaBaseURL:       strings.TrimRight(metaBaseURL, "/") + "/",
 		baseID:            baseID,
 		token:             token,
-		httpClient:        &http.Client{Timeout: 10 * time.Second},
+		httpClient:        client,
 		refreshInterval:   10 * time.Minute,
+		minTables:         1,
+		fetchConcurrency:  DefaultMetaFetchConcurrency,
+		fetchRate:         DefaultMetaFetchRate,
+		fetchRetries:      DefaultMetaFetchRetries,
+	}
+}
+
+// SetRefreshInterval overrides the auto-refresh interval; must be called before StartAutoRefresh
+func (m *MetaCache) SetRefreshInterval(interval time.Duration) {
+	if interval <= 0 {
+		return
 	}
+	m.mu.Lock()
+	m.refreshInterval = interval
+	m.mu.Unlock()
+}
+
+// SetMinTables sets the minimum table count required before the cache reports ready
+func (m *MetaCache) SetMinTables(n int) {
+	m.mu.Lock()
+	m.minTables = n
+	m.mu.Unlock()
 }
 
 // fetchTableDetails fetches detailed metadata for a specific table including fields
@@ -66,7 +152,9 @@ func (m *MetaCache) fetchTableDetails(tableID string) (*TableMeta, error) {
 	url := fmt.Sprintf("%sapi/v3/meta/bases/%s/tables/%s", strings.TrimSuffix(m.metaBaseURL, "api/v2/"), m.baseID, tableID)
 
 	// Create request
-	req, err := http.NewRequest("GET", url, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), metaRequestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create table details request: %w", err)
 	}
@@ -77,14 +165,14 @@ func (m *MetaCache) fetchTableDetails(tableID string) (*TableMeta, error) {
 	// Execute request
 	resp, err := m.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch table details: %w", err)
+		return nil, &MetaFetchError{Kind: MetaErrorUnreachable, Endpoint: url, Message: "failed to fetch table details", Err: err}
 	}
 	defer resp.Body.Close()
 
 	// Check status code
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("table details API returned status %d: %s", resp.StatusCode, string(body))
+		return nil, classifyMetaResponse(url, resp.StatusCode, body)
 	}
 
 	// Parse response
@@ -101,18 +189,68 @@ func (m *MetaCache) fetchTableDetails(tableID string) (*TableMeta, error) {
 	return &tableMeta, nil
 }
 
-// Refresh fetches table metadata from NocoDB and updates the cache
+// Refresh fetches table metadata from NocoDB and updates the cache, waiting for a refresh already
+// running (auto-refresh, drift refresh or a forced one) to finish first.
+// Failures are recorded (see LastError) so status endpoints can explain why the cache isn't ready.
 func (m *MetaCache) Refresh() error {
+	m.refreshMu.Lock()
+	defer m.refreshMu.Unlock()
+	return m.refreshLocked()
+}
+
+// TryRefresh is Refresh, except that it reports started=false at once instead of waiting when
+// another refresh is running
+func (m *MetaCache) TryRefresh() (started bool, err error) {
+	if !m.refreshMu.TryLock() {
+		return false, nil
+	}
+	defer m.refreshMu.Unlock()
+	return true, m.refreshLocked()
+}
+
+// refreshLocked runs one refresh and records its outcome; the caller holds refreshMu
+func (m *MetaCache) refreshLocked() error {
+	detailErr, err := m.refresh()
+
+	var metaErr *MetaFetchError
+	if err != nil && !errors.As(err, &metaErr) {
+		metaErr = &MetaFetchError{Kind: MetaErrorInvalidResponse, Endpoint: m.tablesURL(), Message: err.Error(), Err: err}
+	}
+	if metaErr == nil {
+		// A permission failure on table details still matters even though the table list loaded
+		metaErr = detailErr
+	}
+
+	m.mu.Lock()
+	m.lastErr = metaErr
+	m.mu.Unlock()
+
+	if metaErr != nil && metaErr.Kind == MetaErrorPermission {
+		log.Printf("[META ERROR] %s: upstream returned %d for %s", metaErr.Message, metaErr.StatusCode, metaErr.Endpoint)
+	}
+	return err
+}
+
+// tablesURL is the meta API endpoint listing the base's tables
+func (m *MetaCache) tablesURL() string {
+	return fmt.Sprintf("%smeta/bases/%s/tables", m.metaBaseURL, m.baseID)
+}
+
+// refresh does the actual fetch; detailErr reports a table-details permission failure
+func (m *MetaCache) refresh() (detailErr *MetaFetchError, err error) {
 	log.Printf("[META] Fetching table metadata from NocoDB...")
+	started := time.Now()
 
 	// Build the metadata API URL
-	url := fmt.Sprintf("%smeta/bases/%s/tables", m.metaBaseURL, m.baseID)
+	url := m.tablesURL()
 	log.Printf("[META] Metadata URL: %s", url)
 
 	// Create request
-	req, err := http.NewRequest("GET", url, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), metaRequestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return fmt.Errorf("failed to create metadata request: %w", err)
+		return nil, fmt.Errorf("failed to create metadata request: %w", err)
 	}
 
 	// Add authentication header
@@ -121,31 +259,34 @@ func (m *MetaCache) Refresh() error {
 	// Execute request
 	resp, err := m.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to fetch metadata: %w", err)
+		return nil, &MetaFetchError{Kind: MetaErrorUnreachable, Endpoint: url, Message: "failed to fetch metadata", Err: err}
 	}
 	defer resp.Body.Close()
 
 	// Check status code
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("metadata API returned status %d: %s", resp.StatusCode, string(body))
+		return nil, classifyMetaResponse(url, resp.StatusCode, body)
 	}
 
 	// Parse response
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("failed to read metadata response: %w", err)
+		return nil, fmt.Errorf("failed to read metadata response: %w", err)
 	}
 
 	var tablesResp TablesResponse
 	if err := json.Unmarshal(body, &tablesResp); err != nil {
-		return fmt.Errorf("failed to parse metadata JSON: %w", err)
+		return nil, fmt.Errorf("failed to parse metadata JSON: %w", err)
 	}
 
 	// Build new mapping
 	newMapping := make(map[string]string)
 	newFieldMappings := make(map[string]map[string]string)
 	newLinkFieldMappings := make(map[string]map[string]string)
+	newSelectMappings := make(map[string]map[string]SelectField)
+	newComputedMappings := make(map[string]map[string]string)
+	newUserFieldMappings := make(map[string]map[string]string)
 
 	for _, table := range tablesResp.List {
 		// Map both lowercase title and table_name to ID
@@ -169,12 +310,40 @@ func (m *MetaCache) Refresh() error {
 			}
 			newFieldMappings[table.ID] = fieldMap
 		}
+	}
+
+	// Fetch detailed table metadata (link fields, select options) in parallel
+	log.Printf("[META] Fetching field metadata for %d tables...", len(tablesResp.List))
+	details := m.fetchAllTableDetails(tablesResp.List)
 
-		// Fetch detailed table metadata to get link fields
-		log.Printf("[META] Fetching field metadata for table '%s' (%s)...", table.Title, table.ID)
-		tableDetails, err := m.fetchTableDetails(table.ID)
+	m.mu.RLock()
+	previousLinks, previousSelects, previousComputed := m.linkFieldsByTable, m.selectsByTable, m.computedByTable
+	previousUserFields := m.userFieldsByTable
+	m.mu.RUnlock()
+
+	var failures []TableFetchFailure
+	for i, table := range tablesResp.List {
+		tableDetails, err := details[i].details, details[i].err
 		if err != nil {
-			log.Printf("[META WARNING] Failed to fetch field details for table '%s': %v", table.Title, err)
+			// Keep what the last refresh knew about this table rather than dropping it
+			log.Printf("[META WARNING] Failed to fetch field details for table '%s' after %d attempt(s), keeping previous metadata: %v", table.Title, details[i].attempts, err)
+			failures = append(failures, TableFetchFailure{TableID: table.ID, Title: table.Title, Error: err.Error()})
+			if links, ok := previousLinks[table.ID]; ok {
+				newLinkFieldMappings[table.ID] = links
+			}
+			if selects, ok := previousSelects[table.ID]; ok {
+				newSelectMappings[table.ID] = selects
+			}
+			if computed, ok := previousComputed[table.ID]; ok {
+				newComputedMappings[table.ID] = computed
+			}
+			if userFields, ok := previousUserFields[table.ID]; ok {
+				newUserFieldMappings[table.ID] = userFields
+			}
+			var metaErr *MetaFetchError
+			if detailErr == nil && errors.As(err, &metaErr) && metaErr.Kind == MetaErrorPermission {
+				detailErr = metaErr
+			}
 			continue
 		}
 
@@ -193,6 +362,53 @@ func (m *MetaCache) Refresh() error {
 			newLinkFieldMappings[table.ID] = linkFieldMap
 			log.Printf("[META] Cached %d link field(s) for table '%s'", len(linkFieldMap), table.Title)
 		}
+
+		// Capture select option lists for write validation
+		selectMap := make(map[string]SelectField)
+		for _, field := range tableDetails.Fields {
+			if selectField, ok := selectFieldFromMeta(field); ok && field.Title != "" {
+				selectMap[field.Title] = selectField
+			}
+		}
+		if len(selectMap) > 0 {
+			newSelectMappings[table.ID] = selectMap
+			log.Printf("[META] Cached %d select field(s) for table '%s'", len(selectMap), table.Title)
+		}
+
+		// Formula, rollup and other computed columns are read-only upstream
+		computedMap := make(map[string]string)
+		for _, field := range tableDetails.Fields {
+			if computedFieldTypes[field.Type] && field.Title != "" {
+				computedMap[field.Title] = field.Type
+			}
+		}
+		if len(computedMap) > 0 {
+			newComputedMappings[table.ID] = computedMap
+			log.Printf("[META] Cached %d computed field(s) for table '%s'", len(computedMap), table.Title)
+		}
+
+		// User fields hold NocoDB collaborators, translated to proxy users in responses
+		userFieldMap := make(map[string]string)
+		for _, field := range tableDetails.Fields {
+			if userFieldTypes[field.Type] && field.Title != "" {
+				userFieldMap[field.Title] = field.Type
+			}
+		}
+		if len(userFieldMap) > 0 {
+			newUserFieldMappings[table.ID] = userFieldMap
+			log.Printf("[META] Cached %d user field(s) for table '%s'", len(userFieldMap), table.Title)
+		}
+	}
+
+	stats := &RefreshStats{
+		StartedAt:     started,
+		Duration:      time.Since(started).Round(time.Millisecond).String(),
+		Tables:        len(tablesResp.List),
+		SlowestTables: slowestTables(tablesResp.List, details, metaSlowestTables),
+		FailedTables:  failures,
+	}
+	if len(failures) > 0 {
+		log.Printf("[META WARN] Partial refresh: details of %d of %d tables failed", len(failures), len(tablesResp.List))
 	}
 
 	// Count total link fields
@@ -206,11 +422,21 @@ func (m *MetaCache) Refresh() error {
 	m.tableByName = newMapping
 	m.fieldsByTable = newFieldMappings
 	m.linkFieldsByTable = newLinkFieldMappings
+	m.selectsByTable = newSelectMappings
+	m.computedByTable = newComputedMappings
+	m.userFieldsByTable = newUserFieldMappings
 	m.lastLoadedAt = time.Now()
+	m.tableCount = len(tablesResp.List)
+	m.refreshStats = stats
+	minTables := m.minTables
 	m.mu.Unlock()
 
-	log.Printf("[META] ✅ Successfully loaded %d tables and %d link field mappings", len(tablesResp.List), totalLinkFields)
-	return nil
+	if len(tablesResp.List) < minTables {
+		log.Printf("[META WARN] Loaded %d tables but at least %d are expected (META_MIN_TABLES) - check NOCODB_BASE_ID; reporting not ready", len(tablesResp.List), minTables)
+	}
+
+	log.Printf("[META] ✅ Successfully loaded %d tables and %d link field mappings in %s", len(tablesResp.List), totalLinkFields, stats.Duration)
+	return detailErr, nil
 }
 
 // Resolve looks up a table ID by its friendly name
@@ -272,6 +498,74 @@ func (m *MetaCache) ResolveLinkField(tableID, fieldName string) (string, bool) {
 	return fieldID, ok
 }
 
+// GetSelectFields returns the select fields of a table keyed by field title
+func (m *MetaCache) GetSelectFields(tableID string) map[string]SelectField {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.selectsByTable[tableID]
+}
+
+// GetComputedFields returns the read-only computed fields of a table, field title -> NocoDB type
+func (m *MetaCache) GetComputedFields(tableID string) map[string]string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.computedByTable[tableID]
+}
+
+// GetUserFields returns the collaborator fields of a table, field title -> NocoDB type
+func (m *MetaCache) GetUserFields(tableID string) map[string]string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.userFieldsByTable[tableID]
+}
+
+// driftRefresh is a drift refresh in flight; refreshed is set before done is closed
+type driftRefresh struct {
+	done      chan struct{}
+	refreshed bool
+}
+
+// RefreshIfOlderThan refreshes unless the cache was loaded within minAge, and reports whether it did.
+// Used when live data suggests the cached schema drifted (e.g. a new select option). Callers arriving
+// while a drift refresh runs wait for that one instead of starting their own. A caller whose ctx
+// ends stops waiting and gets false; the refresh itself carries on for the others.
+func (m *MetaCache) RefreshIfOlderThan(ctx context.Context, minAge time.Duration) bool {
+	m.driftMu.Lock()
+	call := m.drift
+	if call == nil {
+		if time.Since(m.GetLastRefreshTime()) < minAge {
+			m.driftMu.Unlock()
+			return false
+		}
+		call = &driftRefresh{done: make(chan struct{})}
+		m.drift = call
+		go m.refreshForDrift(call)
+	}
+	m.driftMu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.refreshed
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// refreshForDrift runs the drift refresh call and releases its waiters
+func (m *MetaCache) refreshForDrift(call *driftRefresh) {
+	log.Printf("[META] Schema drift suspected, refreshing metadata...")
+	err := m.Refresh()
+	if err != nil {
+		log.Printf("[META ERROR] Drift refresh failed: %v", err)
+	}
+	call.refreshed = err == nil
+
+	m.driftMu.Lock()
+	m.drift = nil
+	m.driftMu.Unlock()
+	close(call.done)
+}
+
 // ShouldRefresh checks if the cache should be refreshed
 func (m *MetaCache) ShouldRefresh() bool {
 	m.mu.RLock()
@@ -306,11 +600,14 @@ func (m *MetaCache) StartAutoRefresh() {
 	go func() {
 		log.Printf("[META] Starting auto-refresh goroutine (interval: %v)", m.refreshInterval)
 
-		// Periodic refresh
-		ticker := time.NewTicker(m.refreshInterval)
-		defer ticker.Stop()
+		// Periodic refresh; retry sooner while the cache has never loaded (e.g. missing meta permissions)
+		for {
+			interval := m.refreshInterval
+			if !m.IsLoaded() && interval > metaRetryInterval {
+				interval = metaRetryInterval
+			}
+			time.Sleep(interval)
 
-		for range ticker.C {
 			log.Printf("[META] Auto-refreshing metadata cache...")
 			if err := m.Refresh(); err != nil {
 				log.Printf("[META ERROR] Auto-refresh failed: %v", err)
@@ -320,13 +617,41 @@ func (m *MetaCache) StartAutoRefresh() {
 	}()
 }
 
-// IsReady returns true if the cache has been loaded at least once
+// IsReady returns true if the cache has been loaded at least once with the minimum expected table count
 func (m *MetaCache) IsReady() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return !m.lastLoadedAt.IsZero() && m.tableCount >= m.minTables
+}
+
+// IsLoaded returns true if the cache has been loaded at least once, regardless of table count
+func (m *MetaCache) IsLoaded() bool {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 	return !m.lastLoadedAt.IsZero()
 }
 
+// GetLoadedTableCount returns the number of tables returned by the last refresh
+func (m *MetaCache) GetLoadedTableCount() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.tableCount
+}
+
+// LastError returns the most recent metadata fetch failure, or nil if the last refresh was clean
+func (m *MetaCache) LastError() *MetaFetchError {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.lastErr
+}
+
+// GetMinTables returns the minimum table count required for readiness
+func (m *MetaCache) GetMinTables() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.minTables
+}
+
 // GetTableCount returns the number of cached table mappings
 func (m *MetaCache) GetTableCount() int {
 	m.mu.RLock()
@@ -334,6 +659,17 @@ func (m *MetaCache) GetTableCount() int {
 	return len(m.tableByName)
 }
 
+// GetFieldCount returns the number of cached field mappings across all tables
+func (m *MetaCache) GetFieldCount() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	count := 0
+	for _, fields := range m.fieldsByTable {
+		count += len(fields)
+	}
+	return count
+}
+
 // GetLinkFieldTableCount returns the number of tables with cached link field mappings
 func (m *MetaCache) GetLinkFieldTableCount() int {
 	m.mu.RLock()
@@ -0,0 +1,154 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// slowMetaUpstream is a NocoDB meta API with one table whose table list takes delay to answer. It
+// counts the table list requests and the most that were ever in flight at once.
+type slowMetaUpstream struct {
+	*fakeUpstream
+	tableLists  atomic.Int32
+	inFlight    atomic.Int32
+	maxInFlight atomic.Int32
+}
+
+func newSlowMetaUpstream(t *testing.T, delay time.Duration) *slowMetaUpstream {
+	u := &slowMetaUpstream{}
+	u.fakeUpstream = newFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/meta/bases/base/tables") {
+			jsonHandler(http.StatusOK, `{"id":"t1","title":"Quotes","fields":[]}`)(w, r)
+			return
+		}
+		u.tableLists.Add(1)
+		n := u.inFlight.Add(1)
+		defer u.inFlight.Add(-1)
+		for {
+			max := u.maxInFlight.Load()
+			if n <= max || u.maxInFlight.CompareAndSwap(max, n) {
+				break
+			}
+		}
+		time.Sleep(delay)
+		jsonHandler(http.StatusOK, `{"list":[{"id":"t1","title":"Quotes"}]}`)(w, r)
+	})
+	return u
+}
+
+func (u *slowMetaUpstream) metaCache() *MetaCache {
+	return NewMetaCache(u.URL+"/api/v2/", "base", "test-token", u.Client())
+}
+
+// Run with -race: refreshes started from every entry point at once must run one after another
+func TestMetaCacheRefreshesNeverOverlap(t *testing.T) {
+	up := newSlowMetaUpstream(t, 20*time.Millisecond)
+	m := up.metaCache()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var err error
+			switch i % 3 {
+			case 0:
+				err = m.Refresh()
+			case 1:
+				_, err = m.TryRefresh()
+			case 2:
+				m.RefreshIfOlderThan(context.Background(), 0)
+			}
+			if err != nil {
+				t.Errorf("refresh %d: %v", i, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if max := up.maxInFlight.Load(); max != 1 {
+		t.Errorf("%d refreshes ran at once, want 1", max)
+	}
+	if _, ok := m.ResolveTable("Quotes"); !ok {
+		t.Error("cache not loaded after the refreshes")
+	}
+}
+
+func TestMetaCacheTryRefreshWhileRefreshing(t *testing.T) {
+	up := newSlowMetaUpstream(t, 100*time.Millisecond)
+	m := up.metaCache()
+
+	done := make(chan error)
+	go func() { done <- m.Refresh() }()
+	for up.inFlight.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if started, _ := m.TryRefresh(); started {
+		t.Error("TryRefresh started a second refresh while one was running")
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if started, err := m.TryRefresh(); !started || err != nil {
+		t.Errorf("TryRefresh after the refresh = %v, %v; want started", started, err)
+	}
+}
+
+func TestDriftRefreshesCoalesce(t *testing.T) {
+	up := newSlowMetaUpstream(t, 50*time.Millisecond)
+	m := up.metaCache()
+
+	var wg sync.WaitGroup
+	var refreshed atomic.Int32
+	start := make(chan struct{})
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if m.RefreshIfOlderThan(context.Background(), time.Minute) {
+				refreshed.Add(1)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if n := up.tableLists.Load(); n != 1 {
+		t.Errorf("NocoDB got %d table list requests, want one shared refresh", n)
+	}
+	if n := refreshed.Load(); n != 10 {
+		t.Errorf("%d callers saw the refresh, want all 10", n)
+	}
+	if m.RefreshIfOlderThan(context.Background(), time.Minute) {
+		t.Error("refreshed again although the cache is younger than minAge")
+	}
+}
+
+func TestDriftRefreshHonorsContext(t *testing.T) {
+	up := newSlowMetaUpstream(t, 200*time.Millisecond)
+	m := up.metaCache()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	if m.RefreshIfOlderThan(ctx, 0) {
+		t.Error("reported a refresh the caller didn't wait for")
+	}
+	if waited := time.Since(started); waited > 150*time.Millisecond {
+		t.Errorf("waited %v after the context ended", waited)
+	}
+
+	// The refresh carries on for everyone else
+	if !m.RefreshIfOlderThan(context.Background(), 0) {
+		t.Error("joined refresh failed")
+	}
+	if n := up.tableLists.Load(); n != 1 {
+		t.Errorf("NocoDB got %d table list requests, want the one refresh", n)
+	}
+}
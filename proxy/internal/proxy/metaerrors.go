@@ -0,0 +1,91 @@
+package proxy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// MetaErrorKind classifies why a metadata fetch failed
+type MetaErrorKind string
+
+const (
+	MetaErrorPermission      MetaErrorKind = "permission_denied" // token rejected or lacks meta-API scope
+	MetaErrorUnreachable     MetaErrorKind = "unreachable"       // network/connection failure
+	MetaErrorUpstream        MetaErrorKind = "upstream_error"    // any other non-200 from NocoDB
+	MetaErrorInvalidResponse MetaErrorKind = "invalid_response"  // 200 with a body we couldn't parse
+)
+
+// metaPermissionMessage is surfaced in /__proxy/status when the token can't read metadata
+const metaPermissionMessage = "token lacks meta permissions"
+
+// MetaFetchError describes a failed metadata request with the upstream status and endpoint
+type MetaFetchError struct {
+	Kind       MetaErrorKind
+	StatusCode int    // upstream HTTP status, 0 if no response was received
+	Endpoint   string // metadata URL that failed
+	Message    string
+	Err        error
+}
+
+func (e *MetaFetchError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("%s (status %d from %s)", e.Message, e.StatusCode, e.Endpoint)
+	}
+	if e.Err != nil {
+		return fmt.Sprintf("%s (%s): %v", e.Message, e.Endpoint, e.Err)
+	}
+	return fmt.Sprintf("%s (%s)", e.Message, e.Endpoint)
+}
+
+func (e *MetaFetchError) Unwrap() error {
+	return e.Err
+}
+
+// IsPermissionError reports whether err is a metadata permission failure
+func IsPermissionError(err error) bool {
+	var metaErr *MetaFetchError
+	return errors.As(err, &metaErr) && metaErr.Kind == MetaErrorPermission
+}
+
+// nocoDBErrorBody covers the error shapes returned by the v2 and v3 meta APIs
+type nocoDBErrorBody struct {
+	Msg     string `json:"msg"`
+	Message string `json:"message"`
+	Error   string `json:"error"`
+}
+
+// permissionMarkers appear in NocoDB error bodies for auth/scope failures,
+// which some versions return with a 400 or 404 instead of 401/403
+var permissionMarkers = []string{"unauthorized", "forbidden", "permission", "not allowed", "invalid token", "err_forbidden"}
+
+// classifyMetaResponse turns a non-200 metadata response into a MetaFetchError
+func classifyMetaResponse(endpoint string, statusCode int, body []byte) *MetaFetchError {
+	metaErr := &MetaFetchError{
+		Kind:       MetaErrorUpstream,
+		StatusCode: statusCode,
+		Endpoint:   endpoint,
+		Message:    fmt.Sprintf("metadata API returned status %d: %s", statusCode, strings.TrimSpace(string(body))),
+	}
+
+	if statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden {
+		metaErr.Kind = MetaErrorPermission
+		metaErr.Message = metaPermissionMessage
+		return metaErr
+	}
+
+	var errBody nocoDBErrorBody
+	if err := json.Unmarshal(body, &errBody); err == nil {
+		text := strings.ToLower(errBody.Msg + " " + errBody.Message + " " + errBody.Error)
+		for _, marker := range permissionMarkers {
+			if strings.Contains(text, marker) {
+				metaErr.Kind = MetaErrorPermission
+				metaErr.Message = metaPermissionMessage
+				break
+			}
+		}
+	}
+	return metaErr
+}
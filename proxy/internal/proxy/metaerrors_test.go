@@ -0,0 +1,89 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+)
+
+func TestClassifyMetaResponse(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   MetaErrorKind
+	}{
+		{"401", http.StatusUnauthorized, `{"msg":"Authentication required"}`, MetaErrorPermission},
+		{"403 without a body", http.StatusForbidden, ``, MetaErrorPermission},
+		{"v2 forbidden as 400", http.StatusBadRequest, `{"msg":"Forbidden: insufficient privilege"}`, MetaErrorPermission},
+		{"v3 error code as 404", http.StatusNotFound, `{"error":"ERR_FORBIDDEN","message":"Not allowed to access base"}`, MetaErrorPermission},
+		{"invalid token", http.StatusBadRequest, `{"message":"Invalid token"}`, MetaErrorPermission},
+		{"missing base", http.StatusNotFound, `{"msg":"Base 'b1' not found"}`, MetaErrorUpstream},
+		{"server error", http.StatusInternalServerError, `{"msg":"Internal Server Error"}`, MetaErrorUpstream},
+		{"HTML error page", http.StatusBadGateway, `<html>Bad Gateway</html>`, MetaErrorUpstream},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classifyMetaResponse("http://nocodb/api/v2/meta/bases/b1/tables", tt.status, []byte(tt.body))
+			if err.Kind != tt.want {
+				t.Errorf("kind = %s, want %s", err.Kind, tt.want)
+			}
+			if err.StatusCode != tt.status || err.Endpoint != "http://nocodb/api/v2/meta/bases/b1/tables" {
+				t.Errorf("status %d, endpoint %q; want the upstream status and endpoint", err.StatusCode, err.Endpoint)
+			}
+			if IsPermissionError(fmt.Errorf("refresh: %w", err)) != (tt.want == MetaErrorPermission) {
+				t.Errorf("IsPermissionError disagrees with kind %s", err.Kind)
+			}
+			if tt.want == MetaErrorPermission && err.Message != metaPermissionMessage {
+				t.Errorf("message = %q, want %q", err.Message, metaPermissionMessage)
+			}
+		})
+	}
+}
+
+func TestMetaCacheRecordsPermissionError(t *testing.T) {
+	var denied atomic.Bool
+	denied.Store(true)
+	up := newFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		if denied.Load() {
+			jsonHandler(http.StatusForbidden, `{"msg":"Forbidden"}`)(w, r)
+			return
+		}
+		jsonHandler(http.StatusOK, `{"list":[{"id":"t1","title":"Quotes"}]}`)(w, r)
+	})
+	m := NewMetaCache(up.URL+"/api/v2/", "base", "test-token", up.Client())
+
+	if err := m.Refresh(); !IsPermissionError(err) {
+		t.Fatalf("Refresh = %v, want a permission error", err)
+	}
+	metaErr := m.LastError()
+	if metaErr == nil || metaErr.Kind != MetaErrorPermission || metaErr.StatusCode != http.StatusForbidden || metaErr.Endpoint != up.URL+"/api/v2/meta/bases/base/tables" {
+		t.Fatalf("LastError = %+v, want permission_denied with status 403 and the tables endpoint", metaErr)
+	}
+	if m.IsReady() {
+		t.Error("cache is ready after a permission failure")
+	}
+
+	// Once the token is fixed the next refresh clears the error
+	denied.Store(false)
+	if err := m.Refresh(); err != nil {
+		t.Fatalf("Refresh after the fix: %v", err)
+	}
+	if metaErr := m.LastError(); metaErr != nil {
+		t.Errorf("LastError = %+v after a clean refresh, want nil", metaErr)
+	}
+}
+
+func TestMetaCacheUnreachable(t *testing.T) {
+	up := newFakeUpstream(t, jsonHandler(http.StatusOK, `{}`))
+	up.Close()
+	m := NewMetaCache(up.URL+"/api/v2/", "base", "test-token", nil)
+
+	err := m.Refresh()
+	var metaErr *MetaFetchError
+	if !errors.As(err, &metaErr) || metaErr.Kind != MetaErrorUnreachable || metaErr.StatusCode != 0 {
+		t.Errorf("Refresh = %v, want an unreachable error without a status", err)
+	}
+}
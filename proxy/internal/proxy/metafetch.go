@@ -0,0 +1,194 @@
+package proxy
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Defaults for the per-table metadata fetches of a refresh
+const (
+	DefaultMetaFetchConcurrency = 4
+	DefaultMetaFetchRate        = 10 // requests per second across all workers
+	DefaultMetaFetchRetries     = 2
+	metaRetryBackoff            = 500 * time.Millisecond // doubled on every retry
+	metaSlowestTables           = 5                      // slowest tables kept in RefreshStats
+)
+
+// TableFetchTiming is how long one table's details took to fetch, retries included
+type TableFetchTiming struct {
+	TableID  string `json:"table_id"`
+	Title    string `json:"title"`
+	Duration string `json:"duration"`
+	Attempts int    `json:"attempts"`
+}
+
+// TableFetchFailure is a table whose details couldn't be fetched; its previous metadata is kept
+type TableFetchFailure struct {
+	TableID string `json:"table_id"`
+	Title   string `json:"title"`
+	Error   string `json:"error"`
+}
+
+// RefreshStats describes the last completed metadata refresh
+type RefreshStats struct {
+	StartedAt     time.Time           `json:"started_at"`
+	Duration      string              `json:"duration"`
+	Tables        int                 `json:"tables"`
+	SlowestTables []TableFetchTiming  `json:"slowest_tables,omitempty"`
+	FailedTables  []TableFetchFailure `json:"failed_tables,omitempty"`
+}
+
+// tableDetailsResult is the outcome of fetching one table's details
+type tableDetailsResult struct {
+	details  *TableMeta
+	err      error
+	duration time.Duration
+	attempts int
+}
+
+// rateBudget spaces out requests shared by several workers, at most rate per second
+type rateBudget struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// newRateBudget creates a budget; rate <= 0 means unlimited
+func newRateBudget(rate float64) *rateBudget {
+	if rate <= 0 {
+		return &rateBudget{}
+	}
+	return &rateBudget{interval: time.Duration(float64(time.Second) / rate)}
+}
+
+// wait blocks until the caller may send its request
+func (b *rateBudget) wait() {
+	if b.interval == 0 {
+		return
+	}
+	b.mu.Lock()
+	now := time.Now()
+	if b.next.Before(now) {
+		b.next = now
+	}
+	slot := b.next
+	b.next = b.next.Add(b.interval)
+	b.mu.Unlock()
+
+	time.Sleep(time.Until(slot))
+}
+
+// SetFetchLimits tunes the per-table detail fetches: how many run at once, the shared request rate
+// (per second, 0 = unlimited) and how often a failing table is retried. Must be called before the first refresh.
+func (m *MetaCache) SetFetchLimits(concurrency int, rate float64, retries int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if concurrency > 0 {
+		m.fetchConcurrency = concurrency
+	}
+	m.fetchRate = rate
+	if retries >= 0 {
+		m.fetchRetries = retries
+	}
+}
+
+// LastRefreshStats returns timing and failures of the last completed refresh, or nil before the first one
+func (m *MetaCache) LastRefreshStats() *RefreshStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.refreshStats
+}
+
+// fetchAllTableDetails fetches the details of every table with a bounded number of workers
+// sharing one rate budget. Results are in the order of tables.
+func (m *MetaCache) fetchAllTableDetails(tables []TableMeta) []tableDetailsResult {
+	m.mu.RLock()
+	concurrency, rate, retries := m.fetchConcurrency, m.fetchRate, m.fetchRetries
+	m.mu.RUnlock()
+
+	results := make([]tableDetailsResult, len(tables))
+	budget := newRateBudget(rate)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	for worker := 0; worker < concurrency && worker < len(tables); worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = m.fetchTableDetailsWithRetry(tables[i], budget, retries)
+			}
+		}()
+	}
+	for i := range tables {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+// fetchTableDetailsWithRetry retries transient failures with exponential backoff.
+// Permission failures aren't retried: they won't go away until the token changes.
+func (m *MetaCache) fetchTableDetailsWithRetry(table TableMeta, budget *rateBudget, retries int) tableDetailsResult {
+	start := time.Now()
+	backoff := metaRetryBackoff
+	result := tableDetailsResult{}
+
+	for {
+		budget.wait()
+		result.attempts++
+		result.details, result.err = m.fetchTableDetails(table.ID)
+		if result.err == nil || result.attempts > retries || !retryableMetaError(result.err) {
+			break
+		}
+		log.Printf("[META WARNING] Fetching details for table '%s' failed (attempt %d), retrying in %v: %v", table.Title, result.attempts, backoff, result.err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+
+	result.duration = time.Since(start)
+	return result
+}
+
+// retryableMetaError reports whether a table details fetch may succeed when tried again
+func retryableMetaError(err error) bool {
+	var metaErr *MetaFetchError
+	if !errors.As(err, &metaErr) {
+		return false // unparsable body: NocoDB answered, asking again won't help
+	}
+	switch metaErr.Kind {
+	case MetaErrorUnreachable:
+		return true
+	case MetaErrorUpstream:
+		return metaErr.StatusCode == http.StatusTooManyRequests || metaErr.StatusCode >= 500
+	}
+	return false
+}
+
+// slowestTables returns the n slowest successful or failed fetches, slowest first
+func slowestTables(tables []TableMeta, results []tableDetailsResult, n int) []TableFetchTiming {
+	order := make([]int, len(results))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return results[order[a]].duration > results[order[b]].duration })
+
+	if len(order) > n {
+		order = order[:n]
+	}
+	timings := make([]TableFetchTiming, 0, len(order))
+	for _, i := range order {
+		timings = append(timings, TableFetchTiming{
+			TableID:  tables[i].ID,
+			Title:    tables[i].Title,
+			Duration: results[i].duration.Round(time.Millisecond).String(),
+			Attempts: results[i].attempts,
+		})
+	}
+	return timings
+}
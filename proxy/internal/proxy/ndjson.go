@@ -0,0 +1,249 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/httperr"
+	"github.com/grove/generic-proxy/internal/jsonpath"
+)
+
+// JSON Lines output for record lists: Accept: application/x-ndjson or ?format=ndjson
+const (
+	NDJSONContentType = "application/x-ndjson"
+	FormatParam       = "format"
+	FormatNDJSON      = "ndjson"
+)
+
+// wantsNDJSON reports whether the client asked for a record list as JSON Lines, removing
+// ?format=ndjson so it never reaches NocoDB
+func wantsNDJSON(r *http.Request) bool {
+	wants := false
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		if mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted)); err == nil && mediaType == NDJSONContentType {
+			wants = true
+		}
+	}
+	query := r.URL.Query()
+	if strings.EqualFold(query.Get(FormatParam), FormatNDJSON) {
+		wants = true
+		query.Del(FormatParam)
+		r.URL.RawQuery = query.Encode()
+	}
+	return wants
+}
+
+// recordTransforms are the per-response rewrites of a record list that also apply to every page
+// of a JSON Lines stream
+type recordTransforms struct {
+	tableKey        string
+	apiVersion      string
+	userFields      map[string]string // translated unless nil
+	directory       CollaboratorDirectory
+	sunsetFields    map[string]string
+	hiddenFields    map[string]bool // stripped unless nil, with hiddenLinks from linked records
+	hiddenLinks     map[string]map[string]bool
+	commentCounts   CommentCounter // added unless nil
+	responseFilter  []jsonpath.Path
+	responseAliases *config.ResponseAliases // renamed per record, after deduplication, unless nil
+	aliasDepth      int                     // levels of linked records renamed with their aliases
+}
+
+// apply runs the transforms on one page in the order ServeHTTP applies them to a buffered list
+func (t recordTransforms) apply(ctx context.Context, page []byte) ([]byte, error) {
+	var err error
+	if t.commentCounts != nil {
+		if page, err = addCommentCounts(ctx, page, t.tableKey, t.apiVersion, t.commentCounts); err != nil {
+			return nil, fmt.Errorf("failed to add comment counts: %w", err)
+		}
+	}
+	if len(t.userFields) > 0 {
+		if page, err = translateCollaborators(ctx, page, t.userFields, t.directory); err != nil {
+			return nil, fmt.Errorf("failed to translate user fields: %w", err)
+		}
+	}
+	if len(t.sunsetFields) > 0 {
+		if page, _, err = stripRecordFields(page, t.sunsetFields); err != nil {
+			return nil, fmt.Errorf("failed to strip sunset fields: %w", err)
+		}
+	}
+	if len(t.hiddenFields) > 0 || len(t.hiddenLinks) > 0 {
+		if page, _, err = stripHiddenFields(page, t.hiddenFields, t.hiddenLinks); err != nil {
+			return nil, fmt.Errorf("failed to strip hidden fields: %w", err)
+		}
+	}
+	if len(t.responseFilter) > 0 {
+		if page, err = applyResponseFilter(page, t.responseFilter); err != nil {
+			return nil, fmt.Errorf("failed to apply response filter: %w", err)
+		}
+	}
+	return page, nil
+}
+
+// ndjsonMeta is the last line of a JSON Lines stream
+type ndjsonMeta struct {
+	Count           int    `json:"count"`
+	Truncated       bool   `json:"truncated"`
+	TruncatedReason string `json:"truncated_reason,omitempty"`
+	BudgetExhausted bool   `json:"budget_exhausted,omitempty"`
+}
+
+// streamNDJSON writes a record list as JSON Lines: one record per line as NocoDB returns its pages,
+// then {"_meta":{"count":...,"truncated":...}}. Only one page is held at a time and each is flushed
+// before the next is requested, so a slow client slows paging down instead of growing a buffer.
+// follow is false when the client asked for a specific page or opted out of aggregation.
+// Once the first line is out a failure ends the stream with an {"_error":...} line and an aborted
+// connection, so a client can't mistake a cut-off stream for a complete one.
+func (p *ProxyHandler) streamNDJSON(ctx context.Context, w *trackingWriter, firstPage []byte, targetURL, apiVersion string, follow bool, opts paginationOptions, transforms recordTransforms) {
+	listKey := "records"
+	if apiVersion == "v2" {
+		listKey = "list"
+	}
+	if _, _, err := parsePage(firstPage, listKey); err != nil {
+		log.Printf("[NDJSON ERROR] First page is not a record list: %v", err)
+		w.fail(httperr.UpstreamReadFailed, "upstream response is not a record list", nil)
+		return
+	}
+
+	pagingCtx := ctx
+	if follow && p.PaginationTimeout > 0 {
+		var cancel context.CancelFunc
+		pagingCtx, cancel = context.WithTimeout(ctx, p.PaginationTimeout)
+		defer cancel()
+	}
+
+	w.Header().Set("Content-Type", NDJSONContentType)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Del("Content-Length")
+	w.WriteHeader(http.StatusOK)
+
+	seen := make(map[string]bool)
+	meta := ndjsonMeta{}
+	pageBody, pageURL, requests := firstPage, targetURL, 1
+stream:
+	for {
+		page, _, err := parsePage(pageBody, listKey)
+		if err != nil {
+			p.failNDJSON(w, httperr.UpstreamReadFailed, fmt.Sprintf("page %d: %v", requests, err))
+			return
+		}
+		transformed, err := transforms.apply(ctx, pageBody)
+		if err != nil {
+			p.failNDJSON(w, httperr.UpstreamReadFailed, err.Error())
+			return
+		}
+		_, records, err := parsePage(transformed, listKey)
+		if err != nil {
+			records = nil // a response filter removed the list
+		}
+
+		for _, raw := range records {
+			if reason := p.ndjsonLimit(meta.Count, opts); reason != "" {
+				meta.Truncated, meta.TruncatedReason = true, reason
+				break stream
+			}
+			var record map[string]interface{}
+			if err := json.Unmarshal(raw, &record); err == nil {
+				if key, ok := recordIdentity(record, opts.primaryKey, apiVersion); ok {
+					if seen[key] {
+						continue // repeated across pages, see dedupRecords
+					}
+					seen[key] = true
+				}
+			}
+			if aliases := transforms.responseAliases; aliases != nil {
+				var fields map[string]json.RawMessage
+				if err := json.Unmarshal(raw, &fields); err == nil {
+					var renamed bytes.Buffer
+					renameRecord(&renamed, fields, aliases, transforms.aliasDepth)
+					raw = renamed.Bytes()
+				}
+			}
+			if _, err := w.Write(append(raw, '\n')); err != nil {
+				log.Printf("[NDJSON ERROR] Failed to write record (client gone?) after %d records: %v", meta.Count, err)
+				return
+			}
+			meta.Count++
+		}
+		w.Flush()
+
+		if !follow {
+			break
+		}
+		nextURL, err := p.nextPageURL(page, pageURL, apiVersion)
+		if err != nil {
+			p.failNDJSON(w, httperr.UpstreamReadFailed, fmt.Sprintf("failed to determine page %d: %v", requests+1, err))
+			return
+		}
+		if nextURL == "" {
+			break
+		}
+		if opts.maxPages > 0 && requests >= opts.maxPages {
+			meta.Truncated, meta.TruncatedReason = true, opts.pageCapReason()
+			break
+		}
+
+		pageBody, err = p.fetchPage(pagingCtx, nextURL)
+		if errors.Is(err, ErrBudgetExhausted) {
+			meta.Truncated, meta.TruncatedReason = true, truncatedBudget
+			break
+		}
+		requests++
+		if ctx.Err() != nil {
+			log.Printf("[NDJSON] Client went away after %d records, stopping", meta.Count)
+			return
+		}
+		if errors.Is(pagingCtx.Err(), context.DeadlineExceeded) {
+			meta.Truncated, meta.TruncatedReason = true, truncatedTimeout
+			break
+		}
+		if err != nil {
+			p.failNDJSON(w, httperr.UpstreamReadFailed, fmt.Sprintf("failed to fetch page %d: %v", requests, err))
+			return
+		}
+		pageURL = nextURL
+	}
+
+	if follow {
+		p.recordFanout(requests)
+	}
+	meta.BudgetExhausted = callBudgetFrom(ctx).Exhausted()
+	if meta.Truncated {
+		log.Printf("[NDJSON WARN] Stream truncated (%s) after %d records", meta.TruncatedReason, meta.Count)
+	}
+	line, _ := json.Marshal(map[string]ndjsonMeta{"_meta": meta})
+	if _, err := w.Write(append(line, '\n')); err != nil {
+		log.Printf("[NDJSON ERROR] Failed to write metadata line (client gone?): %v", err)
+		return
+	}
+	log.Printf("[NDJSON] Streamed %d records from %d upstream pages", meta.Count, requests)
+}
+
+// ndjsonLimit returns why no further record may be streamed after count, or "" if one may
+func (p *ProxyHandler) ndjsonLimit(count int, opts paginationOptions) string {
+	if opts.maxRecords > 0 && count >= opts.maxRecords {
+		return truncatedMaxRecords
+	}
+	if p.MaxResponseRecords > 0 && count >= p.MaxResponseRecords {
+		return "max_response_records"
+	}
+	return ""
+}
+
+// failNDJSON ends a JSON Lines stream that broke after its status was sent: an
+// {"_error":{"code":...,"message":...}} line, then an aborted connection
+func (p *ProxyHandler) failNDJSON(w *trackingWriter, code, message string) {
+	log.Printf("[NDJSON ERROR] %s", message)
+	line, _ := json.Marshal(map[string]map[string]string{"_error": {"code": code, "message": message}})
+	w.Write(append(line, '\n'))
+	w.Flush()
+	panic(http.ErrAbortHandler)
+}
@@ -0,0 +1,187 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/grove/generic-proxy/internal/httperr"
+)
+
+// ownerCheckBatch is the most records one owner-check list read asks for
+const ownerCheckBatch = 100
+
+// isOwnedWrite reports whether a request updates, deletes, links or unlinks records, the writes that
+// must address records the user already owns (creates get their owner set instead, see setRecordOwner)
+func isOwnedWrite(method string, pathParts []string, isLinkPath bool) bool {
+	switch method {
+	case http.MethodPatch, http.MethodPut, http.MethodDelete:
+		return isRecordPath(pathParts) || isLinkPath
+	case http.MethodPost:
+		return isLinkPath
+	}
+	return false
+}
+
+// writeRecordIDs returns the IDs of the records a write addresses: the one in the path (a record or
+// the source record of a link), or every row of the body (object or array). ok is false when a row
+// carries no ID, since its owner can't be checked.
+func writeRecordIDs(pathParts []string, body []byte, apiVersion string) (ids []string, ok bool) {
+	if link, isLink := parseLinkPath(pathParts[1:]); isLink {
+		return []string{link.RecordID}, true
+	}
+	if len(pathParts) == 3 {
+		return []string{pathParts[2]}, true
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, false
+	}
+	items, isList := doc.([]interface{})
+	if !isList {
+		items = []interface{}{doc}
+	}
+	seen := make(map[string]bool, len(items))
+	for _, item := range items {
+		record, isRecord := item.(map[string]interface{})
+		if !isRecord {
+			return nil, false
+		}
+		id, hasID := recordIDString(record, apiVersion)
+		if !hasID || id == "" {
+			return nil, false
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids, len(ids) > 0
+}
+
+// recordOwners sorts out the records behind ids that the user doesn't own and the ones NocoDB doesn't
+// have. A single record is read by ID from recordsURL (the table's records endpoint); several are read
+// with one list request per ownerCheckBatch records, filtered with where=(Id,in,...). Every read draws
+// from the request's call budget. IDs that can't be placed in a where clause are rejected with a
+// *ValidationError.
+func (p *ProxyHandler) recordOwners(ctx context.Context, recordsURL string, ids []string, field, userID string) (notOwned, missing []string, err error) {
+	if len(ids) == 1 {
+		body, status, err := p.fetchOwnerCheck(ctx, recordsURL+"/"+url.PathEscape(ids[0]))
+		if err != nil {
+			return nil, nil, err
+		}
+		switch {
+		case status == http.StatusNotFound:
+			missing = append(missing, ids[0])
+		case !ownsRecord(body, field, userID):
+			notOwned = append(notOwned, ids[0])
+		}
+		return notOwned, missing, nil
+	}
+
+	for _, id := range ids {
+		if !validOwnerID(id) {
+			return nil, nil, newValidationError(httperr.InvalidRecordID, "invalid record ID '%s'", id).
+				withParams(map[string]string{"id": id})
+		}
+	}
+	apiVersion := detectAPIVersion(p.NocoDBURL)
+	for start := 0; start < len(ids); start += ownerCheckBatch {
+		batch := ids[start:min(start+ownerCheckBatch, len(ids))]
+		owners, err := p.fetchRecordOwners(ctx, recordsURL, batch, field, apiVersion)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, id := range batch {
+			owner, found := owners[id]
+			switch {
+			case !found:
+				missing = append(missing, id)
+			case owner != userID:
+				notOwned = append(notOwned, id)
+			}
+		}
+	}
+	return notOwned, missing, nil
+}
+
+// fetchRecordOwners lists the records behind ids and returns their owners by record ID; records
+// NocoDB doesn't have are absent, records without an owner map to ""
+func (p *ProxyHandler) fetchRecordOwners(ctx context.Context, recordsURL string, ids []string, field, apiVersion string) (map[string]string, error) {
+	query := url.Values{}
+	query.Set("where", "(Id,in,"+strings.Join(ids, ",")+")")
+	if apiVersion == "v2" {
+		query.Set("limit", strconv.Itoa(len(ids)))
+	} else {
+		query.Set("pageSize", strconv.Itoa(len(ids)))
+	}
+	body, status, err := p.fetchOwnerCheck(ctx, recordsURL+"?"+query.Encode())
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("upstream returned status %d", status)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var response struct {
+		Records []map[string]interface{} `json:"records"`
+		List    []map[string]interface{} `json:"list"`
+	}
+	if err := decoder.Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to parse owner check response: %w", err)
+	}
+	items := response.Records
+	if apiVersion == "v2" {
+		items = response.List
+	}
+
+	owners := make(map[string]string, len(items))
+	for _, record := range items {
+		if id, ok := recordIDString(record, apiVersion); ok {
+			owners[id], _ = recordOwner(record, field)
+		}
+	}
+	return owners, nil
+}
+
+// fetchOwnerCheck makes one owner-check read; statuses other than 200 and 404 are errors
+func (p *ProxyHandler) fetchOwnerCheck(ctx context.Context, target string) ([]byte, int, error) {
+	ctx, cancel := p.upstreamContext(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("xc-token", p.NocoDBToken)
+
+	resp, err := p.doUpstream(req, callOwnerCheck)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return nil, 0, fmt.Errorf("upstream returned status %d", resp.StatusCode)
+	}
+	return body, resp.StatusCode, nil
+}
+
+// tableRecordsURL returns the upstream records endpoint of a table in schema-driven mode
+func (p *ProxyHandler) tableRecordsURL(tableID string) string {
+	return p.NocoDBURL + tableID + "/records"
+}
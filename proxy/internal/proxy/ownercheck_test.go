@@ -0,0 +1,184 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/grove/generic-proxy/internal/config"
+)
+
+// ownerUpstream is a fake NocoDB whose table t1 has the records in owners (record ID -> owner user
+// ID). It answers single-record reads, owner-check lists filtered with where=(Id,in,...) and
+// accepts every write.
+func ownerUpstream(t *testing.T, owners map[string]string) *fakeUpstream {
+	return newFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			jsonHandler(http.StatusOK, `{"ok":true}`)(w, r)
+			return
+		}
+		if id, ok := strings.CutPrefix(r.URL.Path, "/api/v2/tables/t1/records/"); ok {
+			owner, found := owners[id]
+			if !found {
+				jsonHandler(http.StatusNotFound, `{"msg":"Record not found"}`)(w, r)
+				return
+			}
+			jsonHandler(http.StatusOK, fmt.Sprintf(`{"Id":%s,"Owner":%q}`, id, owner))(w, r)
+			return
+		}
+
+		where := r.URL.Query().Get("where")
+		ids, ok := strings.CutPrefix(where, "(Id,in,")
+		if !ok {
+			jsonHandler(http.StatusOK, `{"list":[]}`)(w, r)
+			return
+		}
+		var list []map[string]interface{}
+		for _, id := range strings.Split(strings.TrimSuffix(ids, ")"), ",") {
+			if owner, found := owners[id]; found {
+				list = append(list, map[string]interface{}{"Id": json.Number(id), "Owner": owner})
+			}
+		}
+		body, _ := json.Marshal(map[string]interface{}{"list": list, "pageInfo": map[string]interface{}{"isLastPage": true}})
+		jsonHandler(http.StatusOK, string(body))(w, r)
+	})
+}
+
+func ownedQuotes() map[string]config.ResolvedTable {
+	table := quotesTable()
+	table.OwnerField = "Owner"
+	table.Operations = append(table.Operations, "link", "unlink")
+	table.Links = map[string]config.ResolvedLink{"items": {FieldID: "c1", Title: "Items", Pinned: true}}
+	return map[string]config.ResolvedTable{"quotes": table}
+}
+
+// writesSent counts the non-GET requests NocoDB received
+func writesSent(u *fakeUpstream) int {
+	n := 0
+	for _, req := range u.Requests() {
+		if req.Method != http.MethodGet {
+			n++
+		}
+	}
+	return n
+}
+
+func TestOwnerCheckSingleRecord(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		userID     string
+		role       string
+		wantStatus int
+		wantWrite  bool
+	}{
+		{"owned update", http.MethodPatch, "/proxy/quotes/records/1", "7", "user", http.StatusOK, true},
+		{"owned delete", http.MethodDelete, "/proxy/quotes/records/1", "7", "user", http.StatusOK, true},
+		{"foreign update", http.MethodPatch, "/proxy/quotes/records/2", "7", "user", http.StatusForbidden, false},
+		{"foreign delete", http.MethodDelete, "/proxy/quotes/records/2", "7", "user", http.StatusForbidden, false},
+		{"missing record", http.MethodDelete, "/proxy/quotes/records/9", "7", "user", http.StatusNotFound, false},
+		{"admin bypass", http.MethodDelete, "/proxy/quotes/records/2", "1", "admin", http.StatusOK, true},
+		{"link own record", http.MethodPost, "/proxy/quotes/records/1/links/items", "7", "user", http.StatusOK, true},
+		{"link foreign record", http.MethodPost, "/proxy/quotes/records/2/links/items", "7", "user", http.StatusForbidden, false},
+		{"unlink foreign record", http.MethodDelete, "/proxy/quotes/links/items/2", "7", "user", http.StatusForbidden, false},
+		{"unlink own record", http.MethodDelete, "/proxy/quotes/links/items/records/1", "7", "user", http.StatusOK, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			up := ownerUpstream(t, map[string]string{"1": "7", "2": "8"})
+			p := newSchemaHandler(up, ownedQuotes())
+
+			body := `{"Title":"x"}`
+			if strings.Contains(tt.target, "/links/") {
+				body = `[{"Id":40}]`
+			}
+			rec := serve(p, tt.method, tt.target, body, tt.userID, tt.role)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if sent := writesSent(up) > 0; sent != tt.wantWrite {
+				t.Errorf("write forwarded = %v, want %v", sent, tt.wantWrite)
+			}
+		})
+	}
+}
+
+func TestOwnerCheckBulkMixed(t *testing.T) {
+	up := ownerUpstream(t, map[string]string{"1": "7", "2": "8", "3": "7"})
+	p := newSchemaHandler(up, ownedQuotes())
+
+	rec := serve(p, http.MethodPatch, "/proxy/quotes/records", `[{"Id":1,"Title":"a"},{"Id":2,"Title":"b"},{"Id":3,"Title":"c"}]`, "7", "user")
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want 403 (body %s)", rec.Code, rec.Body)
+	}
+	var body struct {
+		Code string   `json:"code"`
+		IDs  []string `json:"ids"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	if body.Code != "not_record_owner" || len(body.IDs) != 1 || body.IDs[0] != "2" {
+		t.Errorf("body = %s, want not_record_owner for id 2", rec.Body)
+	}
+	if writesSent(up) != 0 {
+		t.Error("a bulk write mixing foreign records was forwarded")
+	}
+
+	rec = serve(p, http.MethodDelete, "/proxy/quotes/records", `[{"Id":1},{"Id":4}]`, "7", "user")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("bulk with a missing record: status = %d, want 404 (body %s)", rec.Code, rec.Body)
+	}
+}
+
+// A bulk write of as many owned rows as the call budget allows must not spend the budget on owner
+// checks: the rows are checked with one list read, leaving room for the write itself
+func TestOwnerCheckBulkIsBatched(t *testing.T) {
+	owners := make(map[string]string)
+	rows := make([]string, 0, DefaultUpstreamCallBudget)
+	for i := 1; i <= DefaultUpstreamCallBudget; i++ {
+		owners[fmt.Sprint(i)] = "7"
+		rows = append(rows, fmt.Sprintf(`{"Id":%d}`, i))
+	}
+	up := ownerUpstream(t, owners)
+	p := newSchemaHandler(up, ownedQuotes())
+
+	rec := serve(p, http.MethodDelete, "/proxy/quotes/records", "["+strings.Join(rows, ",")+"]", "7", "user")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body)
+	}
+	requests := up.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("upstream got %d requests, want one owner check and the write", len(requests))
+	}
+	if where := requests[0].Query.Get("where"); !strings.HasPrefix(where, "(Id,in,1,2,3,") {
+		t.Errorf("owner check where = %q", where)
+	}
+}
+
+func TestOwnerCheckRejectsUnsafeBulkIDs(t *testing.T) {
+	up := ownerUpstream(t, map[string]string{"1": "7"})
+	p := newSchemaHandler(up, ownedQuotes())
+
+	rec := serve(p, http.MethodDelete, "/proxy/quotes/records", `[{"Id":1},{"Id":"2),(Owner,eq,7"}]`, "7", "user")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400 (body %s)", rec.Code, rec.Body)
+	}
+	if len(up.Requests()) != 0 {
+		t.Error("an ID that breaks the where clause reached NocoDB")
+	}
+}
+
+func TestBudgetExhaustedBeforeWrite(t *testing.T) {
+	up := ownerUpstream(t, map[string]string{"1": "7"})
+	p := newSchemaHandler(up, ownedQuotes(), func(p *ProxyHandler) { p.UpstreamCallBudget = 1 })
+
+	rec := serve(p, http.MethodPatch, "/proxy/quotes/records/1", `{"Title":"x"}`, "7", "user")
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "upstream_budget_exhausted") {
+		t.Errorf("status = %d, body %s; want 503 upstream_budget_exhausted", rec.Code, rec.Body)
+	}
+	if writesSent(up) != 0 {
+		t.Error("write was sent beyond the call budget")
+	}
+}
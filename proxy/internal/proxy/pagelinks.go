@@ -0,0 +1,105 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// pageLinkKeys are the top-level fields of a NocoDB list response that hold page URLs
+var pageLinkKeys = []string{"next", "prev"}
+
+// isJSONResponse reports whether an upstream response declares a JSON body
+func isJSONResponse(resp *http.Response) bool {
+	return strings.Contains(resp.Header.Get("Content-Type"), "json")
+}
+
+// publicBaseURL returns the proxy's external URL: PUBLIC_BASE_URL, or the scheme and host the
+// request arrived on (set PUBLIC_BASE_URL behind a reverse proxy)
+func (p *ProxyHandler) publicBaseURL(r *http.Request) string {
+	if p.PublicBaseURL != "" {
+		return strings.TrimRight(p.PublicBaseURL, "/")
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// rewritePageLinks points absolute next/prev URLs of a list response at the proxy, so clients
+// never see NocoDB's address: the path from the table ID on is kept, with the table ID replaced
+// by the table key of the original request. Relative URLs, non-object bodies and responses
+// without page links are returned untouched (changed = false).
+func (p *ProxyHandler) rewritePageLinks(body []byte, publicBase, tableKey, tableID string) ([]byte, bool, error) {
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return body, false, nil
+	}
+
+	changed := false
+	for _, key := range pageLinkKeys {
+		var link string
+		if err := json.Unmarshal(envelope[key], &link); err != nil || link == "" {
+			continue // missing, null or not a string
+		}
+		proxyLink, ok := p.proxyPageURL(link, publicBase, tableKey, tableID)
+		if !ok {
+			continue
+		}
+		encoded, err := json.Marshal(proxyLink)
+		if err != nil {
+			return nil, false, err
+		}
+		envelope[key] = encoded
+		changed = true
+	}
+	if !changed {
+		return body, false, nil
+	}
+
+	rewritten, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, false, err
+	}
+	return rewritten, true, nil
+}
+
+// proxyPageURL maps an absolute upstream page URL to the proxy. The table ID segment anchors the
+// mapping; without one (legacy raw table names) the NOCODB_URL path prefix is stripped instead.
+func (p *ProxyHandler) proxyPageURL(link, publicBase, tableKey, tableID string) (string, bool) {
+	parsed, err := url.Parse(link)
+	if err != nil || !parsed.IsAbs() {
+		return "", false
+	}
+
+	segments := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	start := -1
+	for i, segment := range segments {
+		if tableID != "" && segment == tableID {
+			start = i
+			break
+		}
+	}
+	if start >= 0 {
+		segments[start] = tableKey
+		segments = segments[start:]
+	} else {
+		base, err := url.Parse(p.NocoDBURL)
+		if err != nil {
+			return "", false
+		}
+		rest, ok := strings.CutPrefix(strings.Trim(parsed.Path, "/"), strings.Trim(base.Path, "/")+"/")
+		if !ok {
+			return "", false
+		}
+		segments = strings.Split(rest, "/")
+	}
+
+	proxyLink := publicBase + "/proxy/" + strings.Join(segments, "/")
+	if parsed.RawQuery != "" {
+		proxyLink += "?" + parsed.RawQuery
+	}
+	return proxyLink, true
+}
@@ -0,0 +1,542 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/metrics"
+)
+
+// DefaultPaginationWorkers is the PaginationWorkers of a new ProxyHandler
+const DefaultPaginationWorkers = 4
+
+// Aggregation opt-out for clients that page themselves (e.g. infinite scroll)
+const (
+	PaginateParam  = "proxyPaginate"    // ?proxyPaginate=false
+	PaginateHeader = "X-Proxy-Paginate" // X-Proxy-Paginate: off
+)
+
+// paginationOptedOut reports whether the client asked for NocoDB's page as-is, removing the query
+// flag so it never reaches NocoDB
+func paginationOptedOut(r *http.Request) bool {
+	optedOut := isOffSwitch(r.Header.Get(PaginateHeader))
+	query := r.URL.Query()
+	if query.Has(PaginateParam) {
+		optedOut = optedOut || isOffSwitch(query.Get(PaginateParam))
+		query.Del(PaginateParam)
+		r.URL.RawQuery = query.Encode()
+	}
+	return optedOut
+}
+
+// isOffSwitch matches the values that turn a proxy feature off
+func isOffSwitch(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "false", "off", "0", "no":
+		return true
+	}
+	return false
+}
+
+// hasPagingParams reports whether the client asked for a specific page, which disables aggregation
+func hasPagingParams(query url.Values) bool {
+	for _, key := range pagingParams {
+		if query.Has(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// handlePagination follows NocoDB's paging (v3 "next", v2 pageInfo) and merges every page into one
+// record list. The first page has already been fetched by ServeHTTP. If a follow-up page fails the
+// first page is returned unchanged, so aggregation never turns a good response into an error.
+// Records repeated across pages are dropped by the table's primary key (see recordIdentity).
+// The returned reason is non-empty when aggregation stopped early. Once ctx is cancelled no further
+// pages are requested; ctx's error is returned, or with PaginationOnCancel=partial the records
+// merged so far, marked truncated.
+func (p *ProxyHandler) handlePagination(ctx context.Context, firstBody []byte, targetURL, apiVersion string, opts paginationOptions) ([]byte, string, error) {
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(firstBody, &envelope); err != nil {
+		return firstBody, "", nil
+	}
+
+	listKey := "records"
+	if apiVersion == "v2" {
+		listKey = "list"
+	}
+	var records []json.RawMessage
+	if err := json.Unmarshal(envelope[listKey], &records); err != nil {
+		return firstBody, "", nil
+	}
+
+	nextURL, err := p.nextPageURL(envelope, targetURL, apiVersion)
+	if err != nil || nextURL == "" {
+		if err == nil {
+			p.recordFanout(1) // single-page lists count toward the average fan-out too
+		}
+		return firstBody, "", err
+	}
+
+	// The overall deadline bounds the whole aggregation on top of each page's upstream timeout
+	pagingCtx := ctx
+	if p.PaginationTimeout > 0 {
+		var cancel context.CancelFunc
+		pagingCtx, cancel = context.WithTimeout(ctx, p.PaginationTimeout)
+		defer cancel()
+	}
+
+	var follow followResult
+	totalPages := totalPageCount(envelope, apiVersion)
+	if p.PaginationWorkers > 1 && totalPages > 1 && p.Features.Enabled(config.FeatureConcurrency) {
+		follow, err = p.fetchOffsetPages(pagingCtx, envelope, targetURL, listKey, totalPages, opts)
+	} else {
+		follow, err = p.followNextPages(pagingCtx, nextURL, listKey, apiVersion, len(records), opts)
+	}
+	cancelled := ctx.Err()
+	if cancelled != nil && p.PaginationOnCancel != PaginationCancelPartial {
+		// The client went away: stop paging, nobody is left to read the merged list
+		log.Printf("[PAGINATION] Request cancelled, stopping after %d upstream requests", follow.requests+1)
+		return nil, "", cancelled
+	}
+	if err != nil && cancelled == nil {
+		log.Printf("[PAGINATION ERROR] %v, returning first page only", err)
+		return firstBody, "", nil
+	}
+
+	// Fan-out: upstream requests made for this single client request (the first page counts)
+	fanout := follow.requests + 1
+	truncatedReason := follow.truncated
+	if cancelled != nil {
+		// The pages fetched before the cancellation are still good; the one in flight is lost
+		truncatedReason = truncatedCancelled
+		log.Printf("[PAGINATION] Request cancelled (%v), returning the pages merged so far", cancelled)
+	}
+	records = append(records, follow.records...)
+	if truncatedReason != "" {
+		requested := "an unknown number of"
+		if totalPages > 0 {
+			requested = strconv.Itoa(totalPages)
+		}
+		log.Printf("[PAGINATION WARN] Aggregation truncated (%s): fetched %d of %s pages, returning %d records; a filter is probably missing", truncatedReason, fanout, requested, len(records))
+	}
+	p.recordFanout(fanout)
+	records, duplicates := dedupRecords(records, opts.primaryKey, apiVersion)
+	if duplicates > 0 {
+		log.Printf("[PAGINATION] Dropped %d records repeated across pages", duplicates)
+	}
+	if opts.maxRecords > 0 && len(records) > opts.maxRecords {
+		records = records[:opts.maxRecords]
+		if truncatedReason == "" {
+			truncatedReason = truncatedMaxRecords
+			log.Printf("[PAGINATION WARN] Aggregation truncated (%s): returning %d records", truncatedReason, len(records))
+		}
+	}
+	log.Printf("[PAGINATION] Merged %d records from %d upstream pages (average fan-out %.1f)", len(records), fanout, p.averageFanout())
+
+	merged, err := json.Marshal(records)
+	if err != nil {
+		return nil, "", err
+	}
+	envelope[listKey] = merged
+
+	// The merged response is a single complete (or truncated) page
+	if apiVersion == "v2" {
+		delete(envelope, "pageInfo")
+	} else {
+		envelope["next"] = json.RawMessage("null")
+	}
+	if truncatedReason != "" {
+		envelope["truncated"] = json.RawMessage("true")
+		envelope["pagination_truncated"] = json.RawMessage("true")
+		reason, _ := json.Marshal(truncatedReason)
+		envelope["truncated_reason"] = reason
+	}
+	if cancelled != nil {
+		note, _ := json.Marshal("request context cancelled: " + cancelled.Error())
+		envelope["truncated_note"] = note
+	}
+
+	body, err := json.Marshal(envelope)
+	return body, truncatedReason, err
+}
+
+// Truncation reasons of an aggregated list
+const (
+	truncatedMaxFanout  = "max_pagination_fanout"
+	truncatedFanoutRate = "max_pagination_fanout_ratio"
+	truncatedMaxRecords = "max_pagination_records"
+	truncatedTimeout    = "pagination_timeout"
+	truncatedCancelled  = "context_cancelled"
+)
+
+// What an aggregation does when the request context is cancelled mid-merge (PAGINATION_ON_CANCEL)
+const (
+	PaginationCancelAbort   = "abort"   // drop the response, for clients that went away
+	PaginationCancelPartial = "partial" // answer with the records merged so far, marked truncated
+)
+
+// paginationOptions are the per-table settings of an aggregated list
+type paginationOptions struct {
+	primaryKey []string // identifies records repeated across pages, empty = record id
+	maxPages   int      // upstream requests including the first page, 0 = unlimited
+	maxRecords int      // records in the merged list, 0 = unlimited
+	ratioCap   bool     // maxPages comes from MaxPaginationFanoutRatio rather than a fixed cap
+}
+
+// pageCapReason is the truncation reason of a list stopped by maxPages
+func (o paginationOptions) pageCapReason() string {
+	if o.ratioCap {
+		return truncatedFanoutRate
+	}
+	return truncatedMaxFanout
+}
+
+// Default aggregation caps of a new ProxyHandler (MAX_PAGINATION_FANOUT, MAX_PAGINATION_FANOUT_RATIO)
+const (
+	DefaultPaginationFanout      = 50
+	DefaultPaginationFanoutRatio = 10
+)
+
+// fanoutRatioMinRequests is how many lists must have been aggregated before MaxPaginationFanoutRatio
+// applies, so the first few requests after a start don't set the average alone
+const fanoutRatioMinRequests = 20
+
+// paginationOptions combines a table's limits with the handler-wide ones; table settings win. Without
+// a table page cap, the fan-out ratio cap applies whenever it is lower than MaxPaginationFanout.
+func (p *ProxyHandler) paginationOptions(table config.ResolvedTable) paginationOptions {
+	opts := paginationOptions{
+		primaryKey: table.PrimaryKey,
+		maxPages:   p.MaxPaginationFanout,
+		maxRecords: p.MaxPaginationRecords,
+	}
+	if table.MaxPaginationPages > 0 {
+		opts.maxPages = table.MaxPaginationPages
+	} else if ratioPages := p.fanoutRatioPages(); ratioPages > 0 && (opts.maxPages == 0 || ratioPages < opts.maxPages) {
+		opts.maxPages = ratioPages
+		opts.ratioCap = true
+	}
+	if table.MaxPaginationRecords > 0 {
+		opts.maxRecords = table.MaxPaginationRecords
+	}
+	return opts
+}
+
+// fanoutRatioPages is the page cap MaxPaginationFanoutRatio sets for the next list: that multiple of
+// the average fan-out so far, rounded up and at least 2. 0 while the ratio cap doesn't apply.
+func (p *ProxyHandler) fanoutRatioPages() int {
+	if p.MaxPaginationFanoutRatio <= 0 || p.paginationRequests.Load() < fanoutRatioMinRequests {
+		return 0
+	}
+	pages := int(math.Ceil(float64(p.MaxPaginationFanoutRatio) * p.averageFanout()))
+	if pages < 2 {
+		pages = 2
+	}
+	return pages
+}
+
+// followResult is what fetching the pages after the first produced
+type followResult struct {
+	records   []json.RawMessage // records of the follow-up pages, in page order
+	requests  int               // upstream requests made, the first page not included
+	truncated string            // why aggregation stopped early, "" if every page was fetched
+}
+
+// followNextPages fetches pages one after another by following each page's next link.
+// An error means the merge has to be abandoned; hitting the fan-out cap or ctx's deadline
+// truncates the result instead.
+func (p *ProxyHandler) followNextPages(ctx context.Context, nextURL, listKey, apiVersion string, firstRecords int, opts paginationOptions) (followResult, error) {
+	var result followResult
+	for nextURL != "" {
+		if opts.maxPages > 0 && result.requests+1 >= opts.maxPages {
+			result.truncated = opts.pageCapReason()
+			return result, nil
+		}
+		if opts.maxRecords > 0 && firstRecords+len(result.records) >= opts.maxRecords {
+			result.truncated = truncatedMaxRecords
+			return result, nil
+		}
+		if ctx.Err() != nil {
+			result.truncated = truncatedTimeout
+			return result, nil
+		}
+
+		pageBody, err := p.fetchPage(ctx, nextURL)
+		if errors.Is(err, ErrBudgetExhausted) {
+			result.truncated = truncatedBudget
+			return result, nil
+		}
+		result.requests++
+		if err != nil {
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				result.truncated = truncatedTimeout
+				return result, nil
+			}
+			return result, fmt.Errorf("failed to fetch page %d: %w", result.requests+1, err)
+		}
+
+		page, pageRecords, err := parsePage(pageBody, listKey)
+		if err != nil {
+			return result, fmt.Errorf("page %d: %w", result.requests+1, err)
+		}
+		result.records = append(result.records, pageRecords...)
+
+		if nextURL, err = p.nextPageURL(page, nextURL, apiVersion); err != nil {
+			return result, fmt.Errorf("failed to determine page %d: %w", result.requests+2, err)
+		}
+	}
+	return result, nil
+}
+
+// fetchOffsetPages fetches pages 2..totalPages of a v2 list concurrently: their offsets are known up
+// front from pageInfo, so nothing has to wait for the previous page. Pages missing when ctx's deadline
+// passes truncate the result at the first gap so records stay in order.
+func (p *ProxyHandler) fetchOffsetPages(ctx context.Context, first map[string]json.RawMessage, targetURL, listKey string, totalPages int, opts paginationOptions) (followResult, error) {
+	var pageInfo struct {
+		PageSize int `json:"pageSize"`
+	}
+	if err := json.Unmarshal(first["pageInfo"], &pageInfo); err != nil || pageInfo.PageSize <= 0 {
+		return followResult{}, fmt.Errorf("invalid pageInfo: %v", err)
+	}
+
+	var result followResult
+	lastPage := totalPages
+	if opts.maxPages > 0 && lastPage > opts.maxPages {
+		lastPage = opts.maxPages
+		result.truncated = opts.pageCapReason()
+	}
+	if recordPages := (opts.maxRecords + pageInfo.PageSize - 1) / pageInfo.PageSize; opts.maxRecords > 0 && lastPage > recordPages {
+		lastPage = recordPages
+		result.truncated = truncatedMaxRecords
+	}
+
+	type pageResult struct {
+		records []json.RawMessage
+		err     error
+	}
+	results := make([]pageResult, lastPage+1) // indexed by page number, 2..lastPage used
+	pages := make(chan int)
+	var wg sync.WaitGroup
+	for worker := 0; worker < p.PaginationWorkers && worker < lastPage-1; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for page := range pages {
+				pageURL, err := offsetPageURL(targetURL, pageInfo.PageSize, (page-1)*pageInfo.PageSize)
+				if err != nil {
+					results[page].err = err
+					continue
+				}
+				pageBody, err := p.fetchPage(ctx, pageURL)
+				if err != nil {
+					results[page].err = err
+					continue
+				}
+				_, results[page].records, results[page].err = parsePage(pageBody, listKey)
+			}
+		}()
+	}
+feed:
+	for page := 2; page <= lastPage; page++ {
+		if ctx.Err() != nil {
+			break // select picks at random when a worker is also ready
+		}
+		select {
+		case pages <- page:
+			result.requests++
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(pages)
+	wg.Wait()
+
+	for page := 2; page <= lastPage; page++ {
+		if page-2 >= result.requests || results[page].err != nil {
+			if errors.Is(results[page].err, ErrBudgetExhausted) {
+				result.truncated = truncatedBudget
+				return result, nil
+			}
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				result.truncated = truncatedTimeout
+				return result, nil
+			}
+			return result, fmt.Errorf("failed to fetch page %d: %w", page, results[page].err)
+		}
+		result.records = append(result.records, results[page].records...)
+	}
+	return result, nil
+}
+
+// parsePage decodes a follow-up page and its record list
+func parsePage(pageBody []byte, listKey string) (map[string]json.RawMessage, []json.RawMessage, error) {
+	var page map[string]json.RawMessage
+	var records []json.RawMessage
+	if err := json.Unmarshal(pageBody, &page); err != nil {
+		return nil, nil, fmt.Errorf("invalid page: %w", err)
+	}
+	if err := json.Unmarshal(page[listKey], &records); err != nil {
+		return nil, nil, fmt.Errorf("no '%s' array", listKey)
+	}
+	return page, records, nil
+}
+
+// totalPageCount returns how many pages a v2 list has from its pageInfo, or 0 when unknown (v3)
+func totalPageCount(first map[string]json.RawMessage, apiVersion string) int {
+	if apiVersion != "v2" {
+		return 0
+	}
+	var pageInfo struct {
+		PageSize  int `json:"pageSize"`
+		TotalRows int `json:"totalRows"`
+	}
+	if err := json.Unmarshal(first["pageInfo"], &pageInfo); err != nil || pageInfo.PageSize <= 0 {
+		return 0
+	}
+	return (pageInfo.TotalRows + pageInfo.PageSize - 1) / pageInfo.PageSize
+}
+
+// offsetPageURL sets limit/offset paging on a v2 list URL
+func offsetPageURL(listURL string, pageSize, offset int) (string, error) {
+	next, err := url.Parse(listURL)
+	if err != nil {
+		return "", err
+	}
+	query := next.Query()
+	query.Set("limit", strconv.Itoa(pageSize))
+	query.Set("offset", strconv.Itoa(offset))
+	next.RawQuery = query.Encode()
+	return next.String(), nil
+}
+
+// paginationPeekBytes is how much of a record list is inspected before deciding whether to buffer it
+const paginationPeekBytes = 64 << 10
+
+// peekNextPage inspects the start of a record list for its pagination marker (v3 "next", v2 "pageInfo")
+// without consuming it. known is false when the marker lies beyond the peeked bytes - common, since
+// NocoDB writes it after the records - and the caller has to buffer the page to find out.
+func (p *ProxyHandler) peekNextPage(body *bufio.Reader, targetURL, apiVersion string) (hasNext, known bool) {
+	prefix, _ := body.Peek(paginationPeekBytes) // a shorter prefix means the whole body fits
+
+	markerKey := "next"
+	if apiVersion == "v2" {
+		markerKey = "pageInfo"
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(prefix))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return false, err == nil // not an object: handlePagination would return it unchanged
+	}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return false, false
+		}
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return false, false // value cut off by the end of the prefix
+		}
+		if token == markerKey {
+			nextURL, err := p.nextPageURL(map[string]json.RawMessage{markerKey: value}, targetURL, apiVersion)
+			return nextURL != "" || err != nil, true
+		}
+	}
+	if _, err := decoder.Token(); err != nil {
+		return false, false
+	}
+	return false, true // complete object without a marker
+}
+
+// recordFanout tracks upstream page requests against aggregated client requests
+func (p *ProxyHandler) recordFanout(pages int) {
+	p.paginationRequests.Add(1)
+	p.paginationPages.Add(int64(pages))
+}
+
+// averageFanout is the ratio of upstream page requests to aggregated client requests so far
+func (p *ProxyHandler) averageFanout() float64 {
+	requests := p.paginationRequests.Load()
+	if requests == 0 {
+		return 0
+	}
+	return float64(p.paginationPages.Load()) / float64(requests)
+}
+
+// nextPageURL returns the upstream URL of the page after the given one, or "" on the last page
+func (p *ProxyHandler) nextPageURL(page map[string]json.RawMessage, currentURL, apiVersion string) (string, error) {
+	if apiVersion == "v2" {
+		var pageInfo struct {
+			Page       int  `json:"page"`
+			PageSize   int  `json:"pageSize"`
+			IsLastPage bool `json:"isLastPage"`
+		}
+		if err := json.Unmarshal(page["pageInfo"], &pageInfo); err != nil || pageInfo.IsLastPage || pageInfo.PageSize <= 0 {
+			return "", nil
+		}
+
+		return offsetPageURL(currentURL, pageInfo.PageSize, pageInfo.Page*pageInfo.PageSize)
+	}
+
+	var next *string
+	if err := json.Unmarshal(page["next"], &next); err != nil || next == nil || *next == "" {
+		return "", nil
+	}
+	return p.upstreamURL(*next)
+}
+
+// upstreamURL pins a NocoDB-provided next URL to the configured upstream: relative URLs are resolved
+// against NOCODB_URL and absolute ones keep only their path and query, so a response can't point
+// the proxy (and its token) at another host
+func (p *ProxyHandler) upstreamURL(next string) (string, error) {
+	base, err := url.Parse(p.NocoDBURL)
+	if err != nil {
+		return "", err
+	}
+	ref, err := url.Parse(next)
+	if err != nil {
+		return "", fmt.Errorf("invalid next URL %q: %w", next, err)
+	}
+	ref.Scheme, ref.Host, ref.User = "", "", nil
+	return base.ResolveReference(ref).String(), nil
+}
+
+// fetchPage GETs one follow-up page from NocoDB, with its own UpstreamTimeout
+func (p *ProxyHandler) fetchPage(ctx context.Context, pageURL string) ([]byte, error) {
+	ctx, cancel := p.upstreamContext(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("xc-token", p.NocoDBToken)
+
+	resp, err := p.doUpstream(req, callPagination)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("upstream returned status %d", resp.StatusCode)
+	}
+	metrics.PaginationPageFetched()
+	return body, nil
+}
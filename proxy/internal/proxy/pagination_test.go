@@ -0,0 +1,388 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/config"
+)
+
+// pagedUpstream is a v2 NocoDB list of total one-record pages; requests with where= match one record only
+func pagedUpstream(t *testing.T, total int) *fakeUpstream {
+	return newFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("where") != "" {
+			jsonHandler(http.StatusOK, `{"list":[{"Id":1}],"pageInfo":{"page":1,"pageSize":1,"totalRows":1,"isLastPage":true}}`)(w, r)
+			return
+		}
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		page := offset + 1
+		jsonHandler(http.StatusOK, fmt.Sprintf(`{"list":[{"Id":%d}],"pageInfo":{"page":%d,"pageSize":1,"totalRows":%d,"isLastPage":%v}}`, page, page, total, page == total))(w, r)
+	})
+}
+
+type mergedList struct {
+	List            []map[string]interface{} `json:"list"`
+	Truncated       bool                     `json:"truncated"`
+	TruncatedReason string                   `json:"truncated_reason"`
+}
+
+func decodeList(t *testing.T, body []byte) mergedList {
+	t.Helper()
+	var list mergedList
+	if err := json.Unmarshal(body, &list); err != nil {
+		t.Fatalf("decode %s: %v", body, err)
+	}
+	return list
+}
+
+func TestPaginationFanoutCapTruncates(t *testing.T) {
+	for _, workers := range []int{1, DefaultPaginationWorkers} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			up := pagedUpstream(t, 100)
+			p := newLegacyHandler(up)
+			p.PaginationWorkers = workers
+			p.MaxPaginationFanout = 3
+
+			rec := serve(p, http.MethodGet, "/proxy/quotes/records", "", "7", "user")
+			list := decodeList(t, rec.Body.Bytes())
+			if !list.Truncated || list.TruncatedReason != truncatedMaxFanout || len(list.List) != 3 {
+				t.Errorf("got %d records, truncated %v (%s); want 3, max_pagination_fanout", len(list.List), list.Truncated, list.TruncatedReason)
+			}
+			if rec.Header().Get("X-Proxy-Truncated") != "true" {
+				t.Error("X-Proxy-Truncated header missing")
+			}
+			if n := len(up.Requests()); n != 3 {
+				t.Errorf("NocoDB got %d page requests, want 3", n)
+			}
+		})
+	}
+}
+
+func TestPaginationFanoutDefault(t *testing.T) {
+	up := pagedUpstream(t, 1000)
+	p := newLegacyHandler(up)
+
+	list := decodeList(t, serve(p, http.MethodGet, "/proxy/quotes/records", "", "7", "user").Body.Bytes())
+	if list.TruncatedReason != truncatedMaxFanout || len(list.List) != DefaultPaginationFanout {
+		t.Errorf("got %d records (%s), want the default cap of %d pages", len(list.List), list.TruncatedReason, DefaultPaginationFanout)
+	}
+}
+
+// Once enough lists were aggregated, a list fetching far more pages than the average is cut off
+func TestPaginationFanoutRatioTruncates(t *testing.T) {
+	up := pagedUpstream(t, 100)
+	p := newLegacyHandler(up)
+	p.MaxPaginationFanout = 0
+	p.MaxPaginationFanoutRatio = 3
+
+	// Before there is an average, only the absolute cap (here none) applies
+	list := decodeList(t, serve(p, http.MethodGet, "/proxy/quotes/records", "", "7", "user").Body.Bytes())
+	if list.Truncated || len(list.List) != 100 {
+		t.Fatalf("first scan: %d records, truncated %v; want all 100", len(list.List), list.Truncated)
+	}
+
+	// Typical traffic: filtered single-page lists
+	for i := 0; i < fanoutRatioMinRequests; i++ {
+		serve(p, http.MethodGet, "/proxy/quotes/records?where=(Id,eq,1)", "", "7", "user")
+	}
+	want := int(math.Ceil(float64(p.MaxPaginationFanoutRatio) * p.averageFanout()))
+
+	list = decodeList(t, serve(p, http.MethodGet, "/proxy/quotes/records", "", "7", "user").Body.Bytes())
+	if !list.Truncated || list.TruncatedReason != truncatedFanoutRate {
+		t.Fatalf("unfiltered scan: truncated %v (%s), want max_pagination_fanout_ratio", list.Truncated, list.TruncatedReason)
+	}
+	if len(list.List) != want {
+		t.Errorf("unfiltered scan returned %d pages, want %d (ratio 3 x average %.2f)", len(list.List), want, p.averageFanout())
+	}
+}
+
+func TestPaginationTableCapOverridesRatio(t *testing.T) {
+	p := &ProxyHandler{MaxPaginationFanout: 50, MaxPaginationFanoutRatio: 2}
+	for i := 0; i < fanoutRatioMinRequests; i++ {
+		p.recordFanout(1)
+	}
+	if opts := p.paginationOptions(quotesTable()); opts.maxPages != 2 || opts.pageCapReason() != truncatedFanoutRate {
+		t.Errorf("handler-wide: maxPages %d (%s), want the ratio cap of 2", opts.maxPages, opts.pageCapReason())
+	}
+	table := quotesTable()
+	table.MaxPaginationPages = 20
+	if opts := p.paginationOptions(table); opts.maxPages != 20 || opts.ratioCap {
+		t.Errorf("table cap: maxPages %d, ratio %v; want the table's 20", opts.maxPages, opts.ratioCap)
+	}
+}
+
+// A client that goes away mid-aggregation stops the page requests
+func TestClientCancelStopsPagination(t *testing.T) {
+	for _, workers := range []int{1, DefaultPaginationWorkers} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			ctx, cancel := context.WithCancel(withUser(context.Background(), "7", "user"))
+			defer cancel()
+			pages := pagedUpstream(t, 100)
+			var requested atomic.Int32
+			up := newFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+				if requested.Add(1) == 3 {
+					cancel() // the browser aborts while page 3 is on its way
+				}
+				resp, err := pages.Client().Get(pages.URL + r.URL.RequestURI())
+				if err != nil {
+					return
+				}
+				defer resp.Body.Close()
+				w.Header().Set("Content-Type", "application/json")
+				io.Copy(w, resp.Body)
+			})
+			p := newLegacyHandler(up)
+			p.PaginationWorkers = workers
+
+			p.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/proxy/quotes/records", nil).WithContext(ctx))
+			time.Sleep(50 * time.Millisecond) // let requests already sent arrive
+			// Pages handed to workers before the cancel may still go out, nothing after
+			if got, limit := requested.Load(), int32(3+workers); got > limit {
+				t.Errorf("NocoDB got %d page requests, want at most %d after the client went away at page 3", got, limit)
+			}
+		})
+	}
+}
+
+func TestCancelledPaginationReturnsPartialPages(t *testing.T) {
+	for _, mode := range []string{PaginationCancelPartial, PaginationCancelAbort} {
+		t.Run(mode, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(withUser(context.Background(), "7", "user"))
+			defer cancel()
+			pages := pagedUpstream(t, 10)
+			var requested atomic.Int32
+			up := newFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+				if requested.Add(1) == 3 {
+					cancel() // cancelled between pages 2 and 3
+					return
+				}
+				resp, err := pages.Client().Get(pages.URL + r.URL.RequestURI())
+				if err != nil {
+					return
+				}
+				defer resp.Body.Close()
+				w.Header().Set("Content-Type", "application/json")
+				io.Copy(w, resp.Body)
+			})
+			p := newLegacyHandler(up)
+			p.PaginationWorkers = 1
+			p.PaginationOnCancel = mode
+
+			rec := httptest.NewRecorder()
+			p.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/proxy/quotes/records", nil).WithContext(ctx))
+			if mode == PaginationCancelAbort {
+				if rec.Body.Len() != 0 {
+					t.Errorf("body = %s, want the response dropped", rec.Body)
+				}
+				return
+			}
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+			}
+			list := decodeList(t, rec.Body.Bytes())
+			if len(list.List) != 2 || !list.Truncated || list.TruncatedReason != truncatedCancelled {
+				t.Errorf("got %d records, truncated %v (%q); want the 2 pages merged before the cancel", len(list.List), list.Truncated, list.TruncatedReason)
+			}
+			var note struct {
+				TruncatedNote string `json:"truncated_note"`
+			}
+			json.Unmarshal(rec.Body.Bytes(), &note)
+			if !strings.Contains(note.TruncatedNote, "cancel") {
+				t.Errorf("truncated_note = %q, want the cancellation named", note.TruncatedNote)
+			}
+			if rec.Header().Get("X-Proxy-Truncated") != "true" {
+				t.Error("X-Proxy-Truncated header missing")
+			}
+		})
+	}
+}
+
+func TestPaginationRecordCapTruncates(t *testing.T) {
+	for _, workers := range []int{1, DefaultPaginationWorkers} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			up := pagedUpstream(t, 100)
+			p := newLegacyHandler(up)
+			p.PaginationWorkers = workers
+			p.MaxPaginationFanout = 0
+			p.MaxPaginationRecords = 5
+
+			rec := serve(p, http.MethodGet, "/proxy/quotes/records", "", "7", "user")
+			list := decodeList(t, rec.Body.Bytes())
+			if !list.Truncated || list.TruncatedReason != truncatedMaxRecords || len(list.List) != 5 {
+				t.Errorf("got %d records, truncated %v (%s); want 5, max_pagination_records", len(list.List), list.Truncated, list.TruncatedReason)
+			}
+			if rec.Header().Get("X-Proxy-Truncated") != "true" {
+				t.Error("X-Proxy-Truncated header missing")
+			}
+			if n := len(up.Requests()); n > 5+workers {
+				t.Errorf("NocoDB got %d page requests, want fetching to stop near the cap", n)
+			}
+		})
+	}
+}
+
+func TestPaginationUnlimited(t *testing.T) {
+	up := pagedUpstream(t, 150)
+	p := newLegacyHandler(up)
+	p.MaxPaginationFanout = 0
+	p.MaxPaginationRecords = 0
+	p.UpstreamCallBudget = 0
+
+	rec := serve(p, http.MethodGet, "/proxy/quotes/records", "", "7", "user")
+	list := decodeList(t, rec.Body.Bytes())
+	if list.Truncated || len(list.List) != 150 {
+		t.Errorf("got %d records, truncated %v (%s); want all 150", len(list.List), list.Truncated, list.TruncatedReason)
+	}
+	if rec.Header().Get("X-Proxy-Truncated") != "" {
+		t.Error("complete list flagged as truncated")
+	}
+}
+
+// Table settings replace the handler-wide caps
+func TestPaginationTableCaps(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxPages   int
+		maxRecords int
+		want       int
+		reason     string
+	}{
+		{"page cap", 4, 10, 4, truncatedMaxFanout},
+		{"record cap", 20, 6, 6, truncatedMaxRecords},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			up := pagedUpstream(t, 50)
+			table := quotesTable()
+			table.MaxPaginationPages = tt.maxPages
+			table.MaxPaginationRecords = tt.maxRecords
+			p := newSchemaHandler(up, map[string]config.ResolvedTable{"quotes": table}, func(p *ProxyHandler) {
+				p.MaxPaginationFanout = 2
+				p.MaxPaginationRecords = 2
+			})
+
+			list := decodeList(t, serve(p, http.MethodGet, "/proxy/quotes/records", "", "7", "user").Body.Bytes())
+			if list.TruncatedReason != tt.reason || len(list.List) != tt.want {
+				t.Errorf("got %d records (%s), want %d (%s)", len(list.List), list.TruncatedReason, tt.want, tt.reason)
+			}
+		})
+	}
+}
+
+// overlapUpstream is a v2 list of total one-record pages that tracks how many page requests
+// overlap. Earlier pages answer slower, so pages complete out of order. Without totalRows the
+// pageInfo only says whether more pages follow.
+func overlapUpstream(t *testing.T, total int, withTotalRows bool, maxInFlight *atomic.Int32) *fakeUpstream {
+	var inFlight atomic.Int32
+	return newFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			seen := maxInFlight.Load()
+			if n <= seen || maxInFlight.CompareAndSwap(seen, n) {
+				break
+			}
+		}
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		page := offset + 1
+		if page > 1 {
+			time.Sleep(time.Duration(total-page+1) * 5 * time.Millisecond)
+		}
+		totalRows := ""
+		if withTotalRows {
+			totalRows = fmt.Sprintf(`,"totalRows":%d`, total)
+		}
+		jsonHandler(http.StatusOK, fmt.Sprintf(`{"list":[{"Id":%d}],"pageInfo":{"page":%d,"pageSize":1%s,"isLastPage":%v}}`, page, page, totalRows, page == total))(w, r)
+	})
+}
+
+func TestPaginationWorkersOverlapAndKeepOrder(t *testing.T) {
+	tests := []struct {
+		name          string
+		withTotalRows bool
+		wantParallel  bool
+	}{
+		{"offset pages", true, true},
+		{"no totalRows", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var maxInFlight atomic.Int32
+			up := overlapUpstream(t, 12, tt.withTotalRows, &maxInFlight)
+			p := newLegacyHandler(up)
+			p.MaxPaginationFanout = 0
+
+			list := decodeList(t, serve(p, http.MethodGet, "/proxy/quotes/records", "", "7", "user").Body.Bytes())
+			if len(list.List) != 12 {
+				t.Fatalf("got %d records, want 12", len(list.List))
+			}
+			for i, record := range list.List {
+				if record["Id"] != float64(i+1) {
+					t.Fatalf("record %d has Id %v: merged list out of page order", i, record["Id"])
+				}
+			}
+			got := maxInFlight.Load()
+			if tt.wantParallel && (got < 2 || got > DefaultPaginationWorkers) {
+				t.Errorf("%d page requests overlapped, want 2 to %d", got, DefaultPaginationWorkers)
+			}
+			if !tt.wantParallel && got != 1 {
+				t.Errorf("%d page requests overlapped, want next links followed one by one", got)
+			}
+		})
+	}
+}
+
+// Opting out returns NocoDB's first page untouched; without it every page is merged
+func TestPaginationOptOut(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		header string
+		want   int
+	}{
+		{"default", "/proxy/quotes/records", "", 3},
+		{"query flag", "/proxy/quotes/records?proxyPaginate=false", "", 1},
+		{"header", "/proxy/quotes/records", "off", 1},
+		{"query flag on", "/proxy/quotes/records?proxyPaginate=true", "", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			up := pagedUpstream(t, 3)
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.header != "" {
+				req.Header.Set(PaginateHeader, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			newLegacyHandler(up).ServeHTTP(rec, req.WithContext(withUser(req.Context(), "7", "user")))
+
+			var body struct {
+				List     []map[string]interface{} `json:"list"`
+				PageInfo *struct {
+					IsLastPage bool `json:"isLastPage"`
+				} `json:"pageInfo"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("body %s: %v", rec.Body, err)
+			}
+			if len(body.List) != tt.want {
+				t.Errorf("got %d records, want %d", len(body.List), tt.want)
+			}
+			if tt.want == 1 && (body.PageInfo == nil || body.PageInfo.IsLastPage) {
+				t.Errorf("pageInfo = %+v, want NocoDB's, pointing at the next page", body.PageInfo)
+			}
+			if up.Requests()[0].Query.Has(PaginateParam) {
+				t.Errorf("%s reached NocoDB", PaginateParam)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package proxy
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/grove/generic-proxy/internal/httperr"
+)
+
+// Path validation modes (PATH_VALIDATION)
+const (
+	PathValidationStrict = "strict" // reject traversal, encoded separators and control characters
+	PathValidationOff    = "off"
+)
+
+// DefaultRecordIDPattern is what record IDs in proxy paths must match unless RECORD_ID_PATTERN says
+// otherwise: letters, digits, '_' and '-', which covers numeric IDs, rec... IDs, UUIDs and composite keys
+const DefaultRecordIDPattern = `^[A-Za-z0-9_-]+$`
+
+var defaultRecordIDPattern = regexp.MustCompile(DefaultRecordIDPattern)
+
+// expectedPathShapes lists the paths a table serves, for the error message of any other path
+const expectedPathShapes = "records, records/count, records/{id}, records/{id}/links/{link}, links/{link}/{recordId} or links/{link}/records/{recordId}"
+
+// encodedSeparators are escapes that decode to path separators or NUL; they never appear in a
+// legitimate table/record path and would let a segment smuggle in extra path levels
+var encodedSeparators = []string{"%2f", "%5c", "%00"}
+
+// checkRequestPath rejects proxy paths that could escape the NocoDB data API base once appended to it:
+// "." and ".." segments, encoded slashes or backslashes, NUL and other control characters.
+// path is the decoded path after /proxy/.
+func checkRequestPath(r *http.Request, path string) error {
+	rawPath := strings.ToLower(r.URL.EscapedPath())
+	for _, encoded := range encodedSeparators {
+		if strings.Contains(rawPath, encoded) {
+			return newValidationError(httperr.InvalidPath, "invalid path: encoded separator '%s'", encoded)
+		}
+	}
+
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "." || segment == ".." {
+			return newValidationError(httperr.InvalidPath, "invalid path: '%s' segment", segment)
+		}
+		if strings.ContainsRune(segment, '\\') {
+			return newValidationError(httperr.InvalidPath, "invalid path: backslash in segment")
+		}
+		for _, c := range segment {
+			if c < 0x20 || c == 0x7f {
+				return newValidationError(httperr.InvalidPath, "invalid path: control character in segment")
+			}
+		}
+	}
+	return nil
+}
+
+// checkPathShape accepts only the paths the data API serves after the table key (see
+// expectedPathShapes), without empty segments, and requires their record ID to match idPattern.
+// parts is the path after the table key, split on "/" with surrounding slashes trimmed.
+func checkPathShape(parts []string, idPattern *regexp.Regexp) error {
+	for _, part := range parts {
+		if part == "" {
+			return newValidationError(httperr.InvalidPath, "invalid path: empty segment")
+		}
+	}
+
+	var recordID string
+	switch {
+	case len(parts) == 1 && parts[0] == "records":
+		return nil
+	case len(parts) == 2 && parts[0] == "records" && parts[1] == "count":
+		return nil
+	case len(parts) == 2 && parts[0] == "records":
+		recordID = parts[1]
+	case len(parts) == 4 && parts[0] == "records" && parts[2] == "links":
+		recordID = parts[1]
+	case len(parts) == 3 && parts[0] == "links":
+		recordID = parts[2]
+	case len(parts) == 4 && parts[0] == "links" && parts[2] == "records":
+		recordID = parts[3]
+	default:
+		return newValidationError(httperr.InvalidPath, "invalid path: expected %s after the table", expectedPathShapes)
+	}
+
+	if !idPattern.MatchString(recordID) {
+		return newValidationError(httperr.InvalidRecordID, "invalid record ID '%s'", recordID).
+			withParams(map[string]string{"id": recordID})
+	}
+	return nil
+}
+
+// recordIDPattern returns RecordIDPattern, or DefaultRecordIDPattern when it isn't set
+func (p *ProxyHandler) recordIDPattern() *regexp.Regexp {
+	if p.RecordIDPattern != nil {
+		return p.RecordIDPattern
+	}
+	return defaultRecordIDPattern
+}
@@ -0,0 +1,142 @@
+package proxy
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/httperr"
+)
+
+func TestSuspiciousPathsRejected(t *testing.T) {
+	targets := []string{
+		"/proxy/quotes/../../meta/bases",
+		"/proxy/quotes/records/..",
+		"/proxy/quotes/./records",
+		"/proxy/quotes/records/1%2F..%2F..%2Fmeta",
+		"/proxy/quotes/records/1%2f2",
+		"/proxy/quotes/records/1%5C2",
+		"/proxy/quotes/records/1%00",
+		"/proxy/quotes/records/1%0A",
+	}
+	handlers := map[string]func(*fakeUpstream) *ProxyHandler{
+		"legacy": newLegacyHandler,
+		"schema": func(up *fakeUpstream) *ProxyHandler {
+			return newSchemaHandler(up, map[string]config.ResolvedTable{"quotes": quotesTable()})
+		},
+	}
+	for mode, newHandler := range handlers {
+		for _, target := range targets {
+			t.Run(mode+" "+target, func(t *testing.T) {
+				up := newFakeUpstream(t, jsonHandler(http.StatusOK, `{}`))
+				rec := serve(newHandler(up), http.MethodGet, target, "", "7", "user")
+				if rec.Code != http.StatusBadRequest || decodeError(t, rec.Body.Bytes()).Code != httperr.InvalidPath {
+					t.Errorf("status = %d, body %s; want 400 %s", rec.Code, rec.Body, httperr.InvalidPath)
+				}
+				if n := len(up.Requests()); n != 0 {
+					t.Errorf("NocoDB got %d requests, want none", n)
+				}
+			})
+		}
+	}
+}
+
+func TestPathValidationOff(t *testing.T) {
+	up := newFakeUpstream(t, jsonHandler(http.StatusOK, `{"Id":1}`))
+	p := newLegacyHandler(up)
+	p.PathValidation = PathValidationOff
+
+	if rec := serve(p, http.MethodGet, "/proxy/quotes/records/1%2f2", "", "7", "user"); rec.Code == http.StatusBadRequest {
+		t.Errorf("status = %d, body %s; want the path passed on", rec.Code, rec.Body)
+	}
+}
+
+func TestOrdinaryPathsAccepted(t *testing.T) {
+	up := newFakeUpstream(t, jsonHandler(http.StatusOK, `{"Id":1}`))
+	p := newLegacyHandler(up)
+	for _, target := range []string{"/proxy/quotes/records/rec_1-a", "/proxy/quotes/records/1?where=(Title,like,a%2Fb)"} {
+		if rec := serve(p, http.MethodGet, target, "", "7", "user"); rec.Code != http.StatusOK {
+			t.Errorf("%s: status = %d, body %s", target, rec.Code, rec.Body)
+		}
+	}
+}
+
+func TestPathShapes(t *testing.T) {
+	tests := []struct {
+		target   string
+		wantCode string // "" = passed on to NocoDB
+	}{
+		{"/proxy/quotes/records", ""},
+		{"/proxy/quotes/records/", ""},
+		{"/proxy/quotes/records/count", ""},
+		{"/proxy/quotes/records/rec_Ab-12", ""},
+		{"/proxy/quotes/records/5/", ""},
+		{"/proxy/quotes/records//5", httperr.InvalidPath},
+		{"/proxy/quotes/records/5/extra", httperr.InvalidPath},
+		{"/proxy/quotes/meta/bases", httperr.InvalidPath},
+		{"/proxy/quotes/", httperr.InvalidPath},
+		{"/proxy/quotes/records/5;drop", httperr.InvalidRecordID},
+		{"/proxy/quotes/records/5%20or%201", httperr.InvalidRecordID},
+		{"/proxy/quotes/records/a.b", httperr.InvalidRecordID},
+	}
+	handlers := map[string]func(*fakeUpstream) *ProxyHandler{
+		"legacy": newLegacyHandler,
+		"schema": func(up *fakeUpstream) *ProxyHandler {
+			return newSchemaHandler(up, map[string]config.ResolvedTable{"quotes": quotesTable()})
+		},
+	}
+	for mode, newHandler := range handlers {
+		for _, tt := range tests {
+			t.Run(mode+" "+tt.target, func(t *testing.T) {
+				up := newFakeUpstream(t, jsonHandler(http.StatusOK, `{"list":[],"pageInfo":{"isLastPage":true}}`))
+				rec := serve(newHandler(up), http.MethodGet, tt.target, "", "7", "user")
+				if tt.wantCode == "" {
+					if rec.Code != http.StatusOK || len(up.Requests()) != 1 {
+						t.Errorf("status = %d, body %s; want the request passed on", rec.Code, rec.Body)
+					}
+					return
+				}
+				if rec.Code != http.StatusBadRequest || decodeError(t, rec.Body.Bytes()).Code != tt.wantCode {
+					t.Errorf("status = %d, body %s; want 400 %s", rec.Code, rec.Body, tt.wantCode)
+				}
+				if n := len(up.Requests()); n != 0 {
+					t.Errorf("NocoDB got %d requests, want none", n)
+				}
+			})
+		}
+	}
+}
+
+func TestLinkPathShapes(t *testing.T) {
+	for target, wantCode := range map[string]string{
+		"/proxy/quotes/links/items/5":         "",
+		"/proxy/quotes/links/items/records/5": "",
+		"/proxy/quotes/records/5/links/items": "",
+		"/proxy/quotes/links/items/5$":        httperr.InvalidRecordID,
+		"/proxy/quotes/links/items":           httperr.InvalidPath,
+	} {
+		up := newFakeUpstream(t, jsonHandler(http.StatusOK, `{"list":[]}`))
+		rec := serve(newLegacyHandler(up), http.MethodGet, target, "", "7", "user")
+		if wantCode == "" && rec.Code != http.StatusOK {
+			t.Errorf("%s: status = %d, body %s; want the request passed on", target, rec.Code, rec.Body)
+		}
+		if wantCode != "" && (rec.Code != http.StatusBadRequest || decodeError(t, rec.Body.Bytes()).Code != wantCode) {
+			t.Errorf("%s: status = %d, body %s; want 400 %s", target, rec.Code, rec.Body, wantCode)
+		}
+	}
+}
+
+func TestRecordIDPatternConfigurable(t *testing.T) {
+	up := newFakeUpstream(t, jsonHandler(http.StatusOK, `{"Id":1}`))
+	p := newLegacyHandler(up)
+	p.RecordIDPattern = regexp.MustCompile(`^\d+$`)
+
+	if rec := serve(p, http.MethodGet, "/proxy/quotes/records/12", "", "7", "user"); rec.Code != http.StatusOK {
+		t.Errorf("numeric ID: status = %d, body %s", rec.Code, rec.Body)
+	}
+	rec := serve(p, http.MethodGet, "/proxy/quotes/records/rec1", "", "7", "user")
+	if rec.Code != http.StatusBadRequest || decodeError(t, rec.Body.Bytes()).Code != httperr.InvalidRecordID {
+		t.Errorf("rec ID: status = %d, body %s; want 400 %s", rec.Code, rec.Body, httperr.InvalidRecordID)
+	}
+}
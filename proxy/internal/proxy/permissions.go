@@ -0,0 +1,184 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/httperr"
+	"github.com/grove/generic-proxy/internal/middleware"
+)
+
+// PermissionsResponse describes what the calling user may do on each visible table
+type PermissionsResponse struct {
+	UserID string                     `json:"user_id"`
+	Role   string                     `json:"role"`
+	Mode   string                     `json:"mode"`
+	Tables map[string]TablePermission `json:"tables"`
+
+	// DefaultOperations applies to every table in legacy mode, where no per-table config exists
+	DefaultOperations []string `json:"default_operations,omitempty"`
+}
+
+// TablePermission lists the allowed operations and configured links for one table. The field
+// lists are only present when the table configures writable_fields or hidden_fields.
+type TablePermission struct {
+	Operations []string `json:"operations"`
+	Links      []string `json:"links,omitempty"`
+
+	// WritableFields are the only fields creates and updates may set; absent = any field
+	WritableFields []string `json:"writable_fields,omitempty"`
+
+	// HiddenFields are never returned by reads; every other field is readable
+	HiddenFields []string `json:"hidden_fields,omitempty"`
+}
+
+// ServePermissions handles GET /api/me/permissions
+func (p *ProxyHandler) ServePermissions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	role, _ := r.Context().Value(middleware.RoleKey).(string)
+
+	response := PermissionsResponse{
+		UserID:            userID,
+		Role:              role,
+		Mode:              "legacy",
+		Tables:            make(map[string]TablePermission),
+		DefaultOperations: p.legacyOperations(),
+	}
+	if p.rowLevelDenied("", role) {
+		response.DefaultOperations = withoutRead(response.DefaultOperations)
+	}
+
+	// Schema-driven mode: evaluate every configured table with the validator's own logic
+	if resolvedConfig, validator := p.schema(); validator != nil && resolvedConfig != nil {
+		response.Mode = "schema-driven"
+		response.DefaultOperations = nil
+		for tableKey, table := range resolvedConfig.Tables {
+			operations, ok := validator.AllowedOperations(tableKey, role)
+			if p.rowLevelDenied(table.OwnerField, role) {
+				operations = withoutRead(operations)
+			}
+			if !ok || len(operations) == 0 {
+				// Tables the caller can't touch at all are not disclosed
+				continue
+			}
+
+			links := make([]string, 0, len(table.Links))
+			for linkName := range table.Links {
+				links = append(links, linkName)
+			}
+			sort.Strings(links)
+
+			response.Tables[tableKey] = TablePermission{
+				Operations:     operations,
+				Links:          links,
+				WritableFields: writableFieldList(table, role),
+				HiddenFields:   sortedCopy(table.HiddenTitles),
+			}
+		}
+	}
+
+	log.Printf("[PERMISSIONS] Effective permissions for user %s (role: %s): %d table(s), mode=%s", userID, role, len(response.Tables), response.Mode)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "private, max-age=30")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("[PERMISSIONS ERROR] Failed to encode response: %v", err)
+	}
+}
+
+// writableFieldList returns the fields role may write on table, or nil when any field may be written
+func writableFieldList(table config.ResolvedTable, role string) []string {
+	if len(table.WritableFields) == 0 || (table.AdminBypass && role == "admin") {
+		return nil
+	}
+	fields := make([]string, 0, len(table.WritableFields))
+	for field := range table.WritableFields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// sortedCopy returns values sorted without touching the original, nil for an empty slice
+func sortedCopy(values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+	sorted := append([]string(nil), values...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+// legacyOperations returns the operations every table allows in legacy mode
+func (p *ProxyHandler) legacyOperations() []string {
+	if len(p.LegacyOperations) == 0 {
+		return knownOperations
+	}
+	allowed := []string{}
+	for _, op := range knownOperations {
+		if operationAllowed(p.LegacyOperations, op, p.LinkImpliesUnlink) {
+			allowed = append(allowed, op)
+		}
+	}
+	return allowed
+}
+
+// isLegacyOperationAllowed checks a legacy-mode request against LegacyOperations.
+// "read_links" is only split out from "read" when the allowlist mentions it.
+func (p *ProxyHandler) isLegacyOperationAllowed(method, path string) (string, bool) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	operation := classifyOperation(method, parts, containsExact(p.LegacyOperations, "read_links"))
+	if len(p.LegacyOperations) == 0 {
+		return operation, true
+	}
+	return operation, operationAllowed(p.LegacyOperations, operation, p.LinkImpliesUnlink)
+}
+
+// CheckRead returns a *ValidationError unless the user may read a record of the table, decided
+// like a single-record GET: the table's operations, ROW_LEVEL_DEFAULT and, on tables with an
+// owner_field, the record's owner, which is read from NocoDB (missing and foreign records are both
+// record_not_found). Features stored in the proxy itself (e.g. comments) use it to follow the
+// record's visibility. Other errors mean the owner couldn't be checked.
+func (p *ProxyHandler) CheckRead(ctx context.Context, tableKey, recordID, userID, role string) error {
+	readDenied := newValidationError(httperr.OperationNotAllowed, "operation 'read' not allowed for table '%s'", tableKey).
+		withParams(map[string]string{"operation": "read", "table": tableKey})
+
+	resolvedConfig, validator := p.schema()
+	if validator == nil || resolvedConfig == nil {
+		if !containsExact(p.legacyOperations(), "read") || p.rowLevelDenied("", role) {
+			return readDenied
+		}
+		return nil
+	}
+
+	validation, err := validator.ValidateRequest(http.MethodGet, tableKey+"/records/"+recordID, role, nil)
+	if err != nil {
+		return err
+	}
+	ownerField := resolvedConfig.Tables[tableKey].OwnerField
+	switch {
+	case role == "admin" || (ownerField == "" && !p.rowLevelDenied(ownerField, role)):
+		return nil
+	case ownerField == "" || !validOwnerID(userID):
+		return readDenied
+	}
+
+	notOwned, missing, err := p.recordOwners(ctx, p.tableRecordsURL(validation.TableID), []string{recordID}, ownerField, userID)
+	if err != nil {
+		return err
+	}
+	if len(notOwned) > 0 || len(missing) > 0 {
+		return newValidationError(httperr.RecordNotFound, "record '%s' not found in table '%s'", recordID, tableKey)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/httperr"
+)
+
+func servePermissions(t *testing.T, p *ProxyHandler, userID, role string) PermissionsResponse {
+	t.Helper()
+	rec := serve(http.HandlerFunc(p.ServePermissions), http.MethodGet, "/api/me/permissions", "", userID, role)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	var response PermissionsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decode %s: %v", rec.Body, err)
+	}
+	return response
+}
+
+func TestPermissionsFieldLists(t *testing.T) {
+	up := newFakeUpstream(t, jsonHandler(http.StatusOK, `{}`))
+	quotes := quotesTable()
+	quotes.WritableFields = map[string]bool{"Title": true, "Amount": true}
+	quotes.AdminBypass = true
+	quotes.HiddenFields = map[string]bool{"Margin": true, "c9": true, "Cost": true, "c8": true}
+	quotes.HiddenTitles = []string{"Margin", "Cost"}
+	plain := quotesTable()
+	plain.TableID = "t2"
+	p := newSchemaHandler(up, map[string]config.ResolvedTable{"quotes": quotes, "plain": plain})
+
+	user := servePermissions(t, p, "7", "user")
+	if got, want := user.Tables["quotes"].WritableFields, []string{"Amount", "Title"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("writable_fields = %v, want %v", got, want)
+	}
+	if got, want := user.Tables["quotes"].HiddenFields, []string{"Cost", "Margin"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("hidden_fields = %v, want %v (titles only, no field IDs)", got, want)
+	}
+	if got := user.Tables["plain"]; got.WritableFields != nil || got.HiddenFields != nil {
+		t.Errorf("table without field permissions got field lists: %+v", got)
+	}
+
+	admin := servePermissions(t, p, "1", "admin")
+	if got := admin.Tables["quotes"]; got.WritableFields != nil || len(got.HiddenFields) != 2 {
+		t.Errorf("admin with admin_bypass: %+v, want no writable list and the hidden fields", got)
+	}
+}
+
+func TestPermissionsHideInaccessibleTables(t *testing.T) {
+	up := newFakeUpstream(t, jsonHandler(http.StatusOK, `{}`))
+	adminOnly := quotesTable()
+	adminOnly.TableID = "t2"
+	adminOnly.Operations = nil
+	adminOnly.RoleOperations = map[string][]string{"admin": {"read"}}
+	p := newSchemaHandler(up, map[string]config.ResolvedTable{"quotes": quotesTable(), "audit": adminOnly})
+
+	rec := serve(http.HandlerFunc(p.ServePermissions), http.MethodGet, "/api/me/permissions", "", "7", "user")
+	if cache := rec.Header().Get("Cache-Control"); cache != "private, max-age=30" {
+		t.Errorf("Cache-Control = %q", cache)
+	}
+	user := servePermissions(t, p, "7", "user")
+	if _, ok := user.Tables["audit"]; ok {
+		t.Error("a table the user can't use was disclosed")
+	}
+	if _, ok := servePermissions(t, p, "1", "admin").Tables["audit"]; !ok {
+		t.Error("admin table missing for admin")
+	}
+}
+
+func TestLegacyOperationsAllowlist(t *testing.T) {
+	up := newFakeUpstream(t, jsonHandler(http.StatusOK, `{"Id":1}`))
+	p := newLegacyHandler(up)
+	p.LegacyOperations = []string{"read"}
+
+	for _, write := range []struct{ method, target string }{
+		{http.MethodPost, "/proxy/t1/records"},
+		{http.MethodPatch, "/proxy/t1/records"},
+		{http.MethodDelete, "/proxy/t1/records"},
+		{http.MethodPost, "/proxy/t1/links/c1/records/1"},
+	} {
+		rec := serve(p, write.method, write.target, `{"Title":"x"}`, "7", "user")
+		if rec.Code != http.StatusForbidden || decodeError(t, rec.Body.Bytes()).Code != httperr.OperationNotAllowed {
+			t.Errorf("%s %s: status = %d, body %s; want 403 %s", write.method, write.target, rec.Code, rec.Body, httperr.OperationNotAllowed)
+		}
+	}
+	if len(up.Requests()) != 0 {
+		t.Error("a write blocked by the legacy allowlist reached NocoDB")
+	}
+	if rec := serve(p, http.MethodGet, "/proxy/t1/records/1", "", "7", "user"); rec.Code != http.StatusOK {
+		t.Errorf("read: status = %d, want 200", rec.Code)
+	}
+	if got, want := servePermissions(t, p, "7", "user").DefaultOperations, []string{"read"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("default_operations = %v, want %v", got, want)
+	}
+
+	p.LegacyOperations = nil
+	if rec := serve(p, http.MethodPost, "/proxy/t1/records", `{"Title":"x"}`, "7", "user"); rec.Code != http.StatusOK {
+		t.Errorf("write without an allowlist: status = %d, want 200", rec.Code)
+	}
+}
@@ -0,0 +1,152 @@
+package proxy
+
+import (
+	"encoding/json"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/httperr"
+)
+
+// Unresolved field modes (UNRESOLVED_FIELDS): what happens to where/sort fields that are neither
+// an alias nor a field of the table
+const (
+	UnresolvedFieldsLenient = "lenient" // pass them to NocoDB unchanged
+	UnresolvedFieldsStrict  = "strict"  // reject the request with 400 unknown_field
+)
+
+// resolveQueryFields translates field aliases in the where and sort parameters to NocoDB field
+// titles in place. Titles NocoDB knows and the record id pass unchanged; anything else is
+// handled according to the validator's unresolved field mode.
+func (v *Validator) resolveQueryFields(tableKey string, table config.ResolvedTable, query url.Values) error {
+	resolve := func(name string) (string, error) {
+		if isHiddenField(table, name) {
+			return "", hiddenFieldError(name, tableKey)
+		}
+		if title, ok := table.FieldTitles[name]; ok {
+			return title, nil
+		}
+		if name == "id" || name == "Id" {
+			return name, nil
+		}
+		if v.metaCache != nil {
+			if _, ok := v.metaCache.ResolveField(table.TableID, name); ok {
+				return name, nil
+			}
+		}
+		if v.unresolvedFields == UnresolvedFieldsStrict {
+			return "", newValidationError(httperr.UnknownField, "field '%s' does not exist in table '%s'", name, tableKey).
+				withParams(map[string]string{"field": name, "table": tableKey})
+		}
+		log.Printf("[VALIDATOR] Unresolved field '%s' in table '%s', passing through", name, tableKey)
+		return name, nil
+	}
+
+	if where := query.Get("where"); where != "" {
+		resolved, err := rewriteWhereFields(where, resolve)
+		if err != nil {
+			return err
+		}
+		if resolved != where {
+			query.Set("where", resolved)
+			log.Printf("[VALIDATOR] Resolved where: %s", resolved)
+		}
+	}
+
+	if sort := query.Get("sort"); sort != "" {
+		resolved, err := rewriteSortFields(sort, resolve)
+		if err != nil {
+			return err
+		}
+		if resolved != sort {
+			query.Set("sort", resolved)
+			log.Printf("[VALIDATOR] Resolved sort: %s", resolved)
+		}
+	}
+	return nil
+}
+
+// rewriteWhereFields resolves the field of every (field,op,value) condition in a NocoDB where
+// clause, leaving the operators, values and ~and/~or/~not grouping untouched
+func rewriteWhereFields(where string, resolve func(string) (string, error)) (string, error) {
+	var out strings.Builder
+	i := 0
+	for i < len(where) {
+		if where[i] != '(' || (i+1 < len(where) && where[i+1] == '(') {
+			out.WriteByte(where[i]) // grouping or text between conditions
+			i++
+			continue
+		}
+
+		rest := where[i+1:]
+		comma := strings.IndexByte(rest, ',')
+		if closing := strings.IndexByte(rest, ')'); comma < 0 || (closing >= 0 && closing < comma) {
+			out.WriteByte('(') // not a condition
+			i++
+			continue
+		}
+		field, err := resolve(strings.TrimSpace(rest[:comma]))
+		if err != nil {
+			return "", err
+		}
+		out.WriteByte('(')
+		out.WriteString(field)
+
+		// Copy the operator and value up to the condition's closing parenthesis
+		end := len(rest)
+		if closing := strings.IndexByte(rest[comma:], ')'); closing >= 0 {
+			end = comma + closing
+		}
+		out.WriteString(rest[comma:end])
+		i += 1 + end
+	}
+	return out.String(), nil
+}
+
+// rewriteSortFields resolves the fields of a sort parameter: v2's "-Title,Name" list or v3's
+// JSON array of {field, direction}
+func rewriteSortFields(sort string, resolve func(string) (string, error)) (string, error) {
+	if strings.HasPrefix(strings.TrimSpace(sort), "[") {
+		var keys []map[string]interface{}
+		if err := json.Unmarshal([]byte(sort), &keys); err != nil {
+			return sort, nil // let NocoDB report the malformed sort
+		}
+		for _, key := range keys {
+			field, ok := key["field"].(string)
+			if !ok {
+				continue
+			}
+			resolved, err := resolve(field)
+			if err != nil {
+				return "", err
+			}
+			key["field"] = resolved
+		}
+		encoded, err := json.Marshal(keys)
+		if err != nil {
+			return "", err
+		}
+		return string(encoded), nil
+	}
+
+	parts := strings.Split(sort, ",")
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		desc := strings.HasPrefix(part, "-")
+		field := strings.TrimPrefix(part, "-")
+		if field == "" {
+			continue
+		}
+		resolved, err := resolve(field)
+		if err != nil {
+			return "", err
+		}
+		parts[i] = resolved
+		if desc {
+			parts[i] = "-" + resolved
+		}
+	}
+	return strings.Join(parts, ","), nil
+}
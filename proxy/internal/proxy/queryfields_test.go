@@ -0,0 +1,101 @@
+package proxy
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/httperr"
+)
+
+// upperAliases resolves the aliases "title" and "status" to their titles and keeps anything else
+func upperAliases(name string) (string, error) {
+	switch name {
+	case "title":
+		return "Title", nil
+	case "status":
+		return "Status", nil
+	}
+	return name, nil
+}
+
+func TestRewriteWhereFields(t *testing.T) {
+	tests := []struct {
+		where string
+		want  string
+	}{
+		{"(title,eq,Hello)", "(Title,eq,Hello)"},
+		{"(title,eq,a,b)~and(status,neq,Closed)", "(Title,eq,a,b)~and(Status,neq,Closed)"},
+		{"((title,like,x)~or(status,eq,Open))~and~not(Id,eq,3)", "((Title,like,x)~or(Status,eq,Open))~and~not(Id,eq,3)"},
+		{"( title ,eq,x)", "(Title,eq,x)"},
+		{"(title,blank)", "(Title,blank)"},
+		{"not a clause", "not a clause"},
+	}
+	for _, tt := range tests {
+		got, err := rewriteWhereFields(tt.where, upperAliases)
+		if err != nil || got != tt.want {
+			t.Errorf("rewriteWhereFields(%q) = %q, %v; want %q", tt.where, got, err, tt.want)
+		}
+	}
+}
+
+func TestRewriteSortFields(t *testing.T) {
+	tests := []struct {
+		sort string
+		want string
+	}{
+		{"title", "Title"},
+		{"-title,status", "-Title,Status"},
+		{`[{"field":"title","direction":"desc"}]`, `[{"direction":"desc","field":"Title"}]`},
+		{"[not json", "[not json"},
+	}
+	for _, tt := range tests {
+		got, err := rewriteSortFields(tt.sort, upperAliases)
+		if err != nil || got != tt.want {
+			t.Errorf("rewriteSortFields(%q) = %q, %v; want %q", tt.sort, got, err, tt.want)
+		}
+	}
+}
+
+func TestUnresolvedQueryFields(t *testing.T) {
+	tests := []struct {
+		mode       string
+		target     string
+		wantStatus int
+		wantWhere  string
+		wantSort   string
+	}{
+		{UnresolvedFieldsLenient, "/proxy/quotes/records?where=(title,eq,a)~and(Nope,eq,b)", http.StatusOK, "(Title,eq,a)~and(Nope,eq,b)", ""},
+		{UnresolvedFieldsLenient, "/proxy/quotes/records?sort=-title", http.StatusOK, "", "-Title"},
+		{UnresolvedFieldsStrict, "/proxy/quotes/records?where=(title,eq,a)~and(Nope,eq,b)", http.StatusBadRequest, "", ""},
+		{UnresolvedFieldsStrict, "/proxy/quotes/records?sort=Nope", http.StatusBadRequest, "", ""},
+		{UnresolvedFieldsStrict, "/proxy/quotes/records?where=(title,eq,a)~and(Id,gt,3)", http.StatusOK, "(Title,eq,a)~and(Id,gt,3)", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode+" "+tt.target, func(t *testing.T) {
+			up := newFakeUpstream(t, jsonHandler(http.StatusOK, `{"list":[],"pageInfo":{"isLastPage":true}}`))
+			table := quotesTable()
+			table.Fields = map[string]string{"title": "c_title"}
+			table.FieldTitles = map[string]string{"title": "Title"}
+			p := newSchemaHandler(up, map[string]config.ResolvedTable{"quotes": table}, func(p *ProxyHandler) { p.UnresolvedFields = tt.mode })
+
+			rec := serve(p, http.MethodGet, tt.target, "", "7", "user")
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, body %s; want %d", rec.Code, rec.Body, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if e := decodeError(t, rec.Body.Bytes()); e.Code != httperr.UnknownField {
+					t.Errorf("code = %s, want %s", e.Code, httperr.UnknownField)
+				}
+				if n := len(up.Requests()); n != 0 {
+					t.Errorf("NocoDB got %d requests, want none", n)
+				}
+				return
+			}
+			query := up.Requests()[0].Query
+			if query.Get("where") != tt.wantWhere || query.Get("sort") != tt.wantSort {
+				t.Errorf("NocoDB got where=%q sort=%q; want %q, %q", query.Get("where"), query.Get("sort"), tt.wantWhere, tt.wantSort)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+
+	"github.com/grove/generic-proxy/internal/config"
+)
+
+// DefaultResponseAliasDepth is the ResponseAliasDepth of a new ProxyHandler: records linked from a
+// record are renamed, the records linked from those keep their keys
+const DefaultResponseAliasDepth = 1
+
+// isRecordPath reports whether a path reads records of the table itself: the list or a single record
+func isRecordPath(pathParts []string) bool {
+	return (len(pathParts) == 2 || len(pathParts) == 3) && pathParts[1] == "records"
+}
+
+// renameResponseFields renames the fields of the records in a response (a record list or a single
+// record) to their aliases. Field values stay raw JSON and the body is written out once, into a
+// buffer, instead of being decoded into interface values and marshaled again; only link fields are
+// decoded further, down to depth levels of linked records. Fields without an alias pass through.
+func renameResponseFields(body []byte, aliases *config.ResponseAliases, depth int) ([]byte, bool) {
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return body, false
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(body)))
+	for _, key := range recordListKeys {
+		list, ok := envelope[key]
+		if !ok || !bytes.HasPrefix(bytes.TrimSpace(list), []byte("[")) {
+			continue
+		}
+		var records []map[string]json.RawMessage
+		if err := json.Unmarshal(list, &records); err != nil {
+			return body, false // not a list of objects
+		}
+		writeObject(out, envelope, func(name string, value json.RawMessage) {
+			if name != key {
+				out.Write(value)
+				return
+			}
+			out.WriteByte('[')
+			for i, record := range records {
+				if i > 0 {
+					out.WriteByte(',')
+				}
+				renameRecord(out, record, aliases, depth)
+			}
+			out.WriteByte(']')
+		})
+		return out.Bytes(), true
+	}
+
+	renameRecord(out, envelope, aliases, depth)
+	return out.Bytes(), true
+}
+
+// renameRecord writes a record with its fields renamed: a v2 record is the field map itself, a v3
+// record keeps its fields under "fields". The records nested in its link fields are renamed with
+// their own aliases while depth is above 0; deeper ones keep their keys.
+func renameRecord(out *bytes.Buffer, record map[string]json.RawMessage, aliases *config.ResponseAliases, depth int) {
+	if raw, ok := record["fields"]; ok {
+		var fields map[string]json.RawMessage
+		if json.Unmarshal(raw, &fields) == nil {
+			writeObject(out, record, func(name string, value json.RawMessage) {
+				if name == "fields" {
+					renameFields(out, fields, aliases, depth)
+				} else {
+					out.Write(value)
+				}
+			})
+			return
+		}
+	}
+	renameFields(out, record, aliases, depth)
+}
+
+// renamedField is a field of a record on its way out
+type renamedField struct {
+	name  string // alias, or the title when there is none
+	title string // NocoDB title or field ID as received
+	value json.RawMessage
+}
+
+// renameFields writes a field map with its keys renamed. When an alias matches the title of
+// another field, the aliased field wins.
+func renameFields(out *bytes.Buffer, fields map[string]json.RawMessage, aliases *config.ResponseAliases, depth int) {
+	renamed := make([]renamedField, 0, len(fields))
+	for title, value := range fields {
+		name, aliased := aliases.Fields[title]
+		if !aliased {
+			name = title
+		}
+		renamed = append(renamed, renamedField{name: name, title: title, value: value})
+	}
+	sort.Slice(renamed, func(i, j int) bool {
+		if renamed[i].name != renamed[j].name {
+			return renamed[i].name < renamed[j].name
+		}
+		return renamed[i].title != renamed[i].name && renamed[j].title == renamed[j].name // the aliased field first
+	})
+
+	out.WriteByte('{')
+	for i, field := range renamed {
+		if i > 0 {
+			if field.name == renamed[i-1].name {
+				continue
+			}
+			out.WriteByte(',')
+		}
+		writeKey(out, field.name)
+		if nested, isLink := aliases.Links[field.title]; isLink && depth > 0 {
+			renameLinked(out, field.value, nested, depth-1)
+		} else {
+			out.Write(field.value)
+		}
+	}
+	out.WriteByte('}')
+}
+
+// renameLinked writes the value of a link field (one linked record or an array of them) with the
+// linked records' fields renamed; depth is what is left for the links of the linked records
+func renameLinked(out *bytes.Buffer, value json.RawMessage, aliases *config.ResponseAliases, depth int) {
+	var record map[string]json.RawMessage
+	if json.Unmarshal(value, &record) == nil {
+		renameRecord(out, record, aliases, depth)
+		return
+	}
+	var items []json.RawMessage
+	if json.Unmarshal(value, &items) != nil {
+		out.Write(value) // null, a count or anything else NocoDB puts in a link field
+		return
+	}
+	out.WriteByte('[')
+	for i, item := range items {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		renameLinked(out, item, aliases, depth)
+	}
+	out.WriteByte(']')
+}
+
+// writeObject writes an object with sorted keys, as json.Marshal would, leaving each value to writeValue
+func writeObject(out *bytes.Buffer, object map[string]json.RawMessage, writeValue func(name string, value json.RawMessage)) {
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		writeKey(out, name)
+		writeValue(name, object[name])
+	}
+	out.WriteByte('}')
+}
+
+// writeKey writes an object key and its colon, quoting plain keys directly and escaping others like json.Marshal
+func writeKey(out *bytes.Buffer, key string) {
+	plain := true
+	for i := 0; i < len(key); i++ {
+		if c := key[i]; c < 0x20 || c == '"' || c == '\\' || c == '<' || c == '>' || c == '&' || c >= 0x80 {
+			plain = false
+			break
+		}
+	}
+	if plain {
+		out.WriteByte('"')
+		out.WriteString(key)
+		out.WriteByte('"')
+	} else {
+		quoted, _ := json.Marshal(key) // a string always marshals
+		out.Write(quoted)
+	}
+	out.WriteByte(':')
+}
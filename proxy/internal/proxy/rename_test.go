@@ -0,0 +1,58 @@
+package proxy
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/grove/generic-proxy/internal/config"
+)
+
+// linkedAliases are the aliases of quotes, their items and the items' products, whose Quote link
+// leads back to the quotes
+func linkedAliases() *config.ResponseAliases {
+	quotes := &config.ResponseAliases{Fields: map[string]string{"Title": "title"}, Links: map[string]*config.ResponseAliases{}}
+	products := &config.ResponseAliases{Fields: map[string]string{"Product Name": "product_name"}, Links: map[string]*config.ResponseAliases{"Quote": quotes}}
+	items := &config.ResponseAliases{Fields: map[string]string{"Quantity": "qty"}, Links: map[string]*config.ResponseAliases{"Product": products}}
+	quotes.Links["Items"] = items
+	return quotes
+}
+
+func TestResponseAliasDepth(t *testing.T) {
+	const record = `{"Id":1,"Items":[{"Product":{"Product Name":"x","Quote":{"Title":"a"}},"Quantity":2}],"Title":"a"}`
+	tests := []struct {
+		depth int
+		want  string
+	}{
+		{0, `{"Id":1,"Items":[{"Product":{"Product Name":"x","Quote":{"Title":"a"}},"Quantity":2}],"title":"a"}`},
+		{1, `{"Id":1,"Items":[{"Product":{"Product Name":"x","Quote":{"Title":"a"}},"qty":2}],"title":"a"}`},
+		{2, `{"Id":1,"Items":[{"Product":{"Quote":{"Title":"a"},"product_name":"x"},"qty":2}],"title":"a"}`},
+		{3, `{"Id":1,"Items":[{"Product":{"Quote":{"title":"a"},"product_name":"x"},"qty":2}],"title":"a"}`},
+		{10, `{"Id":1,"Items":[{"Product":{"Quote":{"title":"a"},"product_name":"x"},"qty":2}],"title":"a"}`},
+	}
+	for _, tt := range tests {
+		up := newFakeUpstream(t, jsonHandler(http.StatusOK, record))
+		table := quotesTable()
+		table.ResponseAliases = linkedAliases()
+		p := newSchemaHandler(up, map[string]config.ResolvedTable{"quotes": table}, func(p *ProxyHandler) { p.ResponseAliasDepth = tt.depth })
+
+		rec := serve(p, http.MethodGet, "/proxy/quotes/records/1", "", "7", "user")
+		if rec.Code != http.StatusOK || rec.Body.String() != tt.want {
+			t.Errorf("depth %d: status = %d, body %s; want %s", tt.depth, rec.Code, rec.Body, tt.want)
+		}
+	}
+}
+
+func TestResponseAliasDepthStreamed(t *testing.T) {
+	up := newFakeUpstream(t, jsonHandler(http.StatusOK,
+		`{"list":[{"Id":1,"Items":[{"Product":{"Product Name":"x"},"Quantity":2}],"Title":"a"}],"pageInfo":{"isLastPage":true}}`))
+	table := quotesTable()
+	table.ResponseAliases = linkedAliases()
+	p := newSchemaHandler(up, map[string]config.ResolvedTable{"quotes": table}, func(p *ProxyHandler) { p.ResponseAliasDepth = 1 })
+
+	rec := serve(p, http.MethodGet, "/proxy/quotes/records?format=ndjson", "", "7", "user")
+	const want = `{"Id":1,"Items":[{"Product":{"Product Name":"x"},"qty":2}],"title":"a"}`
+	if line, _, _ := strings.Cut(rec.Body.String(), "\n"); rec.Code != http.StatusOK || line != want {
+		t.Errorf("status = %d, body %q; want the first line %s", rec.Code, rec.Body, want)
+	}
+}
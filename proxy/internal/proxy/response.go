@@ -0,0 +1,109 @@
+package proxy
+
+import (
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/grove/generic-proxy/internal/httperr"
+)
+
+// trackingWriter records whether the response status has been sent so that late
+// failures (pagination, body rewriting, streaming) never issue a second WriteHeader
+// or append an error message to a half-written body.
+type trackingWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+	status      int
+	written     int64
+	writeErr    error // first error returned by the client connection
+}
+
+func newTrackingWriter(w http.ResponseWriter) *trackingWriter {
+	return &trackingWriter{ResponseWriter: w}
+}
+
+// WriteHeader sends the status once; later calls are logged and ignored
+func (t *trackingWriter) WriteHeader(code int) {
+	if t.wroteHeader {
+		log.Printf("[PROXY WARN] Ignoring WriteHeader(%d): status %d already sent", code, t.status)
+		return
+	}
+	t.wroteHeader = true
+	t.status = code
+	t.ResponseWriter.WriteHeader(code)
+}
+
+// Write sends body bytes, implicitly committing a 200 status like http.ResponseWriter
+func (t *trackingWriter) Write(b []byte) (int, error) {
+	if !t.wroteHeader {
+		t.WriteHeader(http.StatusOK)
+	}
+	n, err := t.ResponseWriter.Write(b)
+	t.written += int64(n)
+	if err != nil && t.writeErr == nil {
+		t.writeErr = err
+	}
+	return n, err
+}
+
+// Flush forwards to the underlying writer when it supports flushing
+func (t *trackingWriter) Flush() {
+	if f, ok := t.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (t *trackingWriter) Unwrap() http.ResponseWriter {
+	return t.ResponseWriter
+}
+
+// upstreamBodyHeaders describe NocoDB's response body; they are wrong for any body the proxy writes instead
+var upstreamBodyHeaders = []string{"Content-Length", "Content-Encoding", "Content-Disposition", "ETag", "Last-Modified"}
+
+// dropUpstreamBodyHeaders removes the copied headers that describe NocoDB's body before the proxy
+// replaces it with an error envelope, so the client doesn't frame or decode it as the upstream body
+func (t *trackingWriter) dropUpstreamBodyHeaders() {
+	for _, name := range upstreamBodyHeaders {
+		t.Header().Del(name)
+	}
+}
+
+// fail reports an error to the client if nothing has been sent yet. Once the status
+// (and possibly part of the body) is on the wire the status can't change anymore, so
+// the connection is aborted instead: the client sees a truncated response rather than
+// a body with an error message spliced into it.
+func (t *trackingWriter) fail(code, message string, params map[string]string) {
+	if !t.wroteHeader {
+		t.dropUpstreamBodyHeaders()
+		httperr.WriteErrorParams(t, code, message, params)
+		return
+	}
+
+	log.Printf("[PROXY ERROR] %s after response started (status %d, %d bytes sent) - aborting connection", message, t.status, t.written)
+	panic(http.ErrAbortHandler)
+}
+
+// streamResponse copies the upstream body to the client as it arrives. A client write error leaves
+// nothing to salvage; an upstream read error after the status is sent aborts the connection (see fail).
+func (p *ProxyHandler) streamResponse(w *trackingWriter, status int, body io.Reader) {
+	w.WriteHeader(status)
+	n, err := io.Copy(w, body)
+	if err == nil {
+		log.Printf("[PROXY] Streamed %d bytes, request completed successfully", n)
+		return
+	}
+	if w.writeErr != nil {
+		log.Printf("[PROXY ERROR] Failed to write response (client gone?) after %d bytes: %v", n, w.writeErr)
+		return
+	}
+
+	log.Printf("[PROXY ERROR] Failed to read upstream response after %d bytes: %v", n, err)
+	if isTimeout(err) {
+		timeout := p.UpstreamTimeout.String()
+		w.fail(httperr.UpstreamTimeout, "upstream did not respond within "+timeout, map[string]string{"timeout": timeout})
+		return
+	}
+	w.fail(httperr.UpstreamReadFailed, "failed to read upstream response", nil)
+}
@@ -0,0 +1,331 @@
+package proxy
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestTrackingWriterIgnoresSecondWriteHeader(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := newTrackingWriter(rec)
+	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(http.StatusInternalServerError)
+	if rec.Code != http.StatusCreated || w.status != http.StatusCreated {
+		t.Errorf("status = %d (tracked %d), want the first one, 201", rec.Code, w.status)
+	}
+}
+
+func TestFailBeforeHeaderDropsUpstreamBodyHeaders(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := newTrackingWriter(rec)
+	for _, name := range upstreamBodyHeaders {
+		w.Header().Set(name, "from-upstream")
+	}
+	w.Header().Set("Content-Length", "1000")
+
+	w.fail("upstream_read_failed", "failed to read upstream response", nil)
+
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("status = %d, want 502", rec.Code)
+	}
+	for _, name := range upstreamBodyHeaders {
+		if got := rec.Header().Get(name); got != "" {
+			t.Errorf("%s = %q survived into the error response", name, got)
+		}
+	}
+	var body struct {
+		Code string `json:"code"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Code != "upstream_read_failed" {
+		t.Errorf("body = %q (err %v), want the error envelope", rec.Body, err)
+	}
+}
+
+func TestFailAfterHeaderAbortsConnection(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := newTrackingWriter(rec)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"list":[`))
+
+	defer func() {
+		if got := recover(); got != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler", got)
+		}
+		if strings.Contains(rec.Body.String(), "error") {
+			t.Errorf("error message spliced into a started body: %q", rec.Body)
+		}
+	}()
+	w.fail("upstream_read_failed", "failed to read upstream response", nil)
+}
+
+// truncatedUpstream announces a 1000-byte body with the given encoding and sends only part of it
+func truncatedUpstream(t *testing.T, encoding string) *fakeUpstream {
+	return newFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Length", "1000")
+		if encoding != "" {
+			w.Header().Set("Content-Encoding", encoding)
+		}
+		w.WriteHeader(http.StatusOK)
+		io.WriteString(w, `{"list":[{"Id":1}`)
+		w.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	})
+}
+
+// A buffered response that breaks off is replaced by an error envelope framed on its own, without
+// NocoDB's Content-Length or Content-Encoding
+func TestBufferedReadErrorHasCleanFraming(t *testing.T) {
+	up := truncatedUpstream(t, "br")
+	p := newLegacyHandler(up)
+	p.MaxResponseRecords = 10 // list responses are buffered to be capped
+	front := newFrontServer(t, p, "7", "user")
+
+	resp, err := http.Get(front.URL + "/proxy/quotes/records")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading the error body failed (broken framing?): %v", err)
+	}
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Errorf("status = %d, want 502", resp.StatusCode)
+	}
+	if enc := resp.Header.Get("Content-Encoding"); enc != "" {
+		t.Errorf("Content-Encoding = %q on the proxy's own error body", enc)
+	}
+	var envelope struct {
+		Code string `json:"code"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil || envelope.Code != "upstream_read_failed" {
+		t.Errorf("body = %q (err %v), want the upstream_read_failed envelope", body, err)
+	}
+}
+
+// A streamed response that breaks off after the status was sent is cut off, not patched up: the
+// client sees the connection end (before or after the buffered status reached it), never a status
+// of 200 followed by an error envelope
+func TestStreamedReadErrorTruncatesResponse(t *testing.T) {
+	up := truncatedUpstream(t, "")
+	p := newLegacyHandler(up)
+	front := newFrontServer(t, p, "7", "user")
+
+	resp, err := http.Get(front.URL + "/proxy/quotes/records/1")
+	if err != nil {
+		return // aborted before the buffered status was flushed
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want the upstream 200", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err == nil {
+		t.Errorf("body %q read completely, want a truncated response", body)
+	}
+	if strings.Contains(string(body), "upstream_read_failed") {
+		t.Errorf("error envelope spliced into the streamed body: %q", body)
+	}
+}
+
+// A follow-up page failing mid-merge falls back to the first page with consistent framing
+func TestPaginationFailureReturnsFirstPage(t *testing.T) {
+	up := newFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("offset") != "" {
+			jsonHandler(http.StatusInternalServerError, `{"msg":"boom"}`)(w, r)
+			return
+		}
+		jsonHandler(http.StatusOK, `{"list":[{"Id":1},{"Id":2}],"pageInfo":{"page":1,"pageSize":2,"totalRows":4,"isLastPage":false}}`)(w, r)
+	})
+	p := newLegacyHandler(up)
+	front := newFrontServer(t, p, "7", "user")
+
+	resp, err := http.Get(front.URL + "/proxy/quotes/records")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading the body failed (broken framing?): %v", err)
+	}
+	var list struct {
+		List []map[string]interface{} `json:"list"`
+	}
+	if err := json.Unmarshal(body, &list); err != nil || len(list.List) != 2 {
+		t.Errorf("status %d, body %q (err %v), want the first page's 2 records", resp.StatusCode, body, err)
+	}
+}
+
+// failingWriter is a client connection that breaks on the first body write
+type failingWriter struct {
+	header http.Header
+	status int
+}
+
+func (f *failingWriter) Header() http.Header    { return f.header }
+func (f *failingWriter) WriteHeader(status int) { f.status = status }
+func (f *failingWriter) Write(b []byte) (int, error) {
+	return 0, errors.New("connection reset by peer")
+}
+
+// A client that goes away mid-response is logged and dropped, without a panic or a second status
+func TestClientWriteErrorDoesNotPanic(t *testing.T) {
+	up := newFakeUpstream(t, jsonHandler(http.StatusOK, `{"Id":1,"Title":"x"}`))
+	p := newLegacyHandler(up)
+
+	for _, target := range []string{"/proxy/quotes/records/1", "/proxy/quotes/records"} {
+		w := &failingWriter{header: make(http.Header)}
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req = req.WithContext(withUser(req.Context(), "7", "user"))
+		p.ServeHTTP(w, req)
+		if w.status != http.StatusOK {
+			t.Errorf("%s: status = %d, want the upstream 200 sent once", target, w.status)
+		}
+	}
+}
+
+// repeatReader yields chunk n times without holding more than one copy of it
+type repeatReader struct {
+	chunk []byte
+	n     int
+	off   int
+}
+
+func (r *repeatReader) Read(b []byte) (int, error) {
+	if r.n == 0 {
+		return 0, io.EOF
+	}
+	copied := copy(b, r.chunk[r.off:])
+	r.off += copied
+	if r.off == len(r.chunk) {
+		r.off, r.n = 0, r.n-1
+	}
+	return copied, nil
+}
+
+// syntheticUpstream answers every GET with prefix, chunk repeated n times and suffix, generated
+// as it is written, with an exact Content-Length
+func syntheticUpstream(t *testing.T, contentType, prefix, chunk string, n int, suffix string) (*fakeUpstream, int64) {
+	size := int64(len(prefix) + len(chunk)*n + len(suffix))
+	up := newFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+		io.Copy(w, io.MultiReader(strings.NewReader(prefix), &repeatReader{chunk: []byte(chunk), n: n}, strings.NewReader(suffix)))
+	})
+	return up, size
+}
+
+// allocatedWhile returns the bytes allocated by the whole process while f runs
+func allocatedWhile(f func()) uint64 {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	f()
+	runtime.ReadMemStats(&after)
+	return after.TotalAlloc - before.TotalAlloc
+}
+
+// getThrough fetches target from a front server for h, discarding the body, and returns the
+// response with the number of body bytes read
+func getThrough(t *testing.T, h http.Handler, target string) (*http.Response, int64) {
+	t.Helper()
+	front := newFrontServer(t, h, "7", "user")
+	resp, err := front.Client().Get(front.URL + target)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	defer resp.Body.Close()
+	n, err := io.Copy(io.Discard, resp.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	return resp, n
+}
+
+// A 100MB download passes through with a fixed amount of memory, not one proportional to its size
+func TestLargeResponseIsStreamed(t *testing.T) {
+	if testing.Short() {
+		t.Skip("proxies 100MB")
+	}
+	up, size := syntheticUpstream(t, "application/octet-stream", "", strings.Repeat("x", 64<<10), 1600, "")
+	p := newLegacyHandler(up)
+
+	var resp *http.Response
+	var n int64
+	allocated := allocatedWhile(func() { resp, n = getThrough(t, p, "/proxy/t1/records/1") })
+	if n != size || resp.ContentLength != size {
+		t.Fatalf("got %d bytes, Content-Length %d; want %d", n, resp.ContentLength, size)
+	}
+	if allocated > uint64(size/10) {
+		t.Errorf("allocated %d MB to proxy %d MB", allocated>>20, size>>20)
+	}
+}
+
+func TestPeekNextPage(t *testing.T) {
+	records := strings.Repeat(`{"Id":1},`, paginationPeekBytes/8)
+	tests := []struct {
+		name        string
+		apiVersion  string
+		body        string
+		wantHasNext bool
+		wantKnown   bool
+	}{
+		{"v2 last page", "v2", `{"pageInfo":{"isLastPage":true},"list":[{"Id":1}]}`, false, true},
+		{"v2 more pages", "v2", `{"pageInfo":{"isLastPage":false,"page":1,"pageSize":1,"totalRows":2},"list":[{"Id":1}]}`, true, true},
+		{"v2 marker after a small list", "v2", `{"list":[{"Id":1}],"pageInfo":{"isLastPage":true}}`, false, true},
+		{"v2 marker beyond the peek", "v2", `{"list":[` + records + `{"Id":1}],"pageInfo":{"isLastPage":true}}`, false, false},
+		{"v3 last page", "v3", `{"records":[{"id":1}]}`, false, true},
+		{"v3 next", "v3", `{"next":"http://nocodb/api/v3/data/base/t1/records?page=2","records":[]}`, true, true},
+		{"not an object", "v2", `[1,2,3]`, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			up := newFakeUpstream(t, jsonHandler(http.StatusOK, `{}`))
+			p := newLegacyHandler(up)
+			body := bufio.NewReaderSize(strings.NewReader(tt.body), paginationPeekBytes)
+			hasNext, known := p.peekNextPage(body, up.URL+"/api/v2/tables/t1/records", tt.apiVersion)
+			if hasNext != tt.wantHasNext || known != tt.wantKnown {
+				t.Errorf("hasNext %v, known %v; want %v, %v", hasNext, known, tt.wantHasNext, tt.wantKnown)
+			}
+			if rest, _ := io.ReadAll(body); string(rest) != tt.body {
+				t.Error("peeking consumed the body")
+			}
+		})
+	}
+}
+
+// A single-page record list whose pageInfo comes first is streamed; one with more pages is merged
+func TestLargeSinglePageListIsStreamed(t *testing.T) {
+	if testing.Short() {
+		t.Skip("proxies 50MB")
+	}
+	up, size := syntheticUpstream(t, "application/json", `{"pageInfo":{"isLastPage":true},"list":[`, `{"Id":1,"Title":"quote"},`, 2<<20, `{"Id":2}]}`)
+	p := newLegacyHandler(up)
+
+	var resp *http.Response
+	var n int64
+	allocated := allocatedWhile(func() { resp, n = getThrough(t, p, "/proxy/t1/records") })
+	if n != size || resp.ContentLength != size {
+		t.Fatalf("got %d bytes, Content-Length %d; want %d", n, resp.ContentLength, size)
+	}
+	if allocated > uint64(size/10) {
+		t.Errorf("allocated %d MB to proxy a %d MB single-page list", allocated>>20, size>>20)
+	}
+
+	// With a next page the list has to be merged, so it is read in full
+	paged := pagedUpstream(t, 2)
+	list := decodeList(t, serve(newLegacyHandler(paged), http.MethodGet, "/proxy/t1/records", "", "7", "user").Body.Bytes())
+	if len(list.List) != 2 {
+		t.Errorf("multi-page list: got %d records, want both pages merged", len(list.List))
+	}
+}
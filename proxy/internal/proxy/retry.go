@@ -0,0 +1,123 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/http/httptrace"
+	"sync/atomic"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/metrics"
+)
+
+// Defaults for retrying upstream requests
+const (
+	DefaultUpstreamMaxAttempts = 3
+	retryBaseDelay             = 100 * time.Millisecond // doubled on every retry
+	retryMaxDelay              = 2 * time.Second
+)
+
+// retryableStatus reports whether an upstream status is worth retrying: NocoDB or a proxy in
+// front of it was momentarily unavailable
+func retryableStatus(status int) bool {
+	return status == http.StatusBadGateway || status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout
+}
+
+// retriesRequest reports whether req may be sent again. GET and HEAD always may; other methods
+// only with an Idempotency-Key (when RetryIdempotencyKey is on) and a body that can be replayed.
+// A streamed body can't be sent twice, so such requests are never retried once it went out.
+// Writes are further limited to connection errors before anything was written, see doUpstream.
+func (p *ProxyHandler) retriesRequest(req *http.Request) bool {
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		return true
+	}
+	if !p.RetryIdempotencyKey || req.Header.Get("Idempotency-Key") == "" {
+		return false
+	}
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// retryDelay is the backoff before retry n (1-based): exponential with jitter in [d/2, d)
+func retryDelay(n int) time.Duration {
+	d := retryBaseDelay << (n - 1)
+	if d <= 0 || d > retryMaxDelay {
+		d = retryMaxDelay
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)))
+}
+
+// doUpstream sends req to NocoDB, retrying GET and HEAD on connection errors and 502/503/504 up
+// to UpstreamMaxAttempts times. Retried writes are only sent again after a connection error that
+// happened before their headers went out: a 502/503/504 or a broken connection mid-request may
+// come after NocoDB applied the write. The request's context bounds the whole sequence.
+// It is the single path of per-request NocoDB calls: each attempt draws from the context's call
+// budget under feature (retries under "retry"), and ErrBudgetExhausted is returned once it is spent.
+func (p *ProxyHandler) doUpstream(req *http.Request, feature string) (*http.Response, error) {
+	attempts := p.UpstreamMaxAttempts
+	if attempts < 1 || !p.retriesRequest(req) {
+		attempts = 1
+	}
+
+	budget := callBudgetFrom(req.Context())
+	if !budget.take(feature) {
+		return nil, ErrBudgetExhausted
+	}
+	safe := req.Method == http.MethodGet || req.Method == http.MethodHead
+	call := feature
+	for attempt := 1; ; attempt++ {
+		var wrote atomic.Bool
+		sent := req
+		if !safe && attempts > 1 {
+			sent = req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{WroteHeaders: func() { wrote.Store(true) }}))
+		}
+		started := time.Now()
+		resp, err := p.client.Do(sent)
+		status := 0
+		if err == nil {
+			status = resp.StatusCode
+		}
+		metrics.ObserveUpstream(req.Method, call, status, time.Since(started))
+		call = callRetry
+		retry := shouldRetry(req.Context(), resp, err) && (safe || err != nil && !wrote.Load())
+		if attempt >= attempts || !retry || !budget.take(callRetry) {
+			return resp, err
+		}
+		if err != nil {
+			log.Printf("[PROXY WARN] Upstream %s failed (attempt %d/%d), retrying: %v", req.Method, attempt, attempts, err)
+		} else {
+			log.Printf("[PROXY WARN] Upstream %s returned %d (attempt %d/%d), retrying", req.Method, resp.StatusCode, attempt, attempts)
+			io.Copy(io.Discard, resp.Body) // let the connection be reused
+			resp.Body.Close()
+		}
+
+		select {
+		case <-time.After(retryDelay(attempt)):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+	}
+}
+
+// shouldRetry decides whether one upstream attempt failed transiently
+func shouldRetry(ctx context.Context, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false // client gone or deadline passed: no time left to retry
+	}
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		return !errors.As(err, &maxBytesErr)
+	}
+	return retryableStatus(resp.StatusCode)
+}
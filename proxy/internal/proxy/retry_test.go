@@ -0,0 +1,189 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/grove/generic-proxy/internal/config"
+)
+
+// flakyUpstream answers the first failures requests with status and every later one with 200
+func flakyUpstream(t *testing.T, failures int32, status int) *fakeUpstream {
+	var seen atomic.Int32
+	return newFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		if seen.Add(1) <= failures {
+			jsonHandler(status, `{"msg":"unavailable"}`)(w, r)
+			return
+		}
+		jsonHandler(http.StatusOK, `{"Id":1}`)(w, r)
+	})
+}
+
+// bufferedQuotes is quotesTable with a field alias, so record writes are read into memory for the
+// alias translation and can be replayed; streamed bodies are never retried
+func bufferedQuotes() map[string]config.ResolvedTable {
+	table := quotesTable()
+	table.FieldTitles = map[string]string{"title": "Title"}
+	return map[string]config.ResolvedTable{"quotes": table}
+}
+
+// postWithKey creates a record through h with an Idempotency-Key
+func postWithKey(h http.Handler) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/proxy/quotes/records", strings.NewReader(`{"Title":"x"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", "k1")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req.WithContext(withUser(req.Context(), "7", "user")))
+	return rec
+}
+
+func TestRetryReadFailsTwiceThenSucceeds(t *testing.T) {
+	up := flakyUpstream(t, 2, http.StatusServiceUnavailable)
+	p := newLegacyHandler(up)
+
+	rec := serve(p, http.MethodGet, "/proxy/t1/records/1", "", "7", "user")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 after two retries (body %s)", rec.Code, rec.Body)
+	}
+	if n := len(up.Requests()); n != 3 {
+		t.Errorf("upstream got %d requests, want 3", n)
+	}
+}
+
+func TestRetryReadGivesUpAfterMaxAttempts(t *testing.T) {
+	up := flakyUpstream(t, 5, http.StatusBadGateway)
+	p := newLegacyHandler(up)
+
+	if rec := serve(p, http.MethodGet, "/proxy/t1/records/1", "", "7", "user"); rec.Code == http.StatusOK {
+		t.Fatalf("status = 200, want the upstream failure")
+	}
+	if n := len(up.Requests()); n != DefaultUpstreamMaxAttempts {
+		t.Errorf("upstream got %d requests, want %d", n, DefaultUpstreamMaxAttempts)
+	}
+}
+
+// A 502/503/504 may come from a proxy that timed out after NocoDB applied the write, so a write is
+// never sent again because of one, Idempotency-Key or not
+func TestRetryWriteNotOnStatus(t *testing.T) {
+	for _, status := range []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout} {
+		up := flakyUpstream(t, 1, status)
+		p := newSchemaHandler(up, bufferedQuotes())
+		p.RetryIdempotencyKey = true
+
+		rec := postWithKey(p)
+		if rec.Code == http.StatusOK {
+			t.Errorf("%d: write was retried to a 200", status)
+		}
+		if n := len(up.Requests()); n != 1 {
+			t.Errorf("%d: upstream got %d requests, want 1", status, n)
+		}
+	}
+}
+
+// A connection that breaks after the request was written may have delivered the write
+func TestRetryWriteNotAfterItWasWritten(t *testing.T) {
+	up := newFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			conn.Close()
+		}
+	})
+	p := newSchemaHandler(up, bufferedQuotes())
+	p.RetryIdempotencyKey = true
+
+	postWithKey(p)
+	if n := len(up.Requests()); n != 1 {
+		t.Errorf("upstream got %d requests, want 1", n)
+	}
+}
+
+// A write whose connection could not even be opened never reached NocoDB and is safe to send again
+func TestRetryWriteOnConnectionErrorBeforeWrite(t *testing.T) {
+	up := newFakeUpstream(t, jsonHandler(http.StatusOK, `{"Id":1}`))
+	var dials atomic.Int32
+	client := &http.Client{Transport: &http.Transport{DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if dials.Add(1) == 1 {
+			return nil, errors.New("connection refused")
+		}
+		return (&net.Dialer{}).DialContext(ctx, network, addr)
+	}}}
+
+	for _, enabled := range []bool{false, true} {
+		dials.Store(0)
+		p := NewProxyHandler(up.URL+"/api/v2/tables/", "test-token", nil, client)
+		p.RetryIdempotencyKey = enabled
+		p.SetResolvedConfig(&config.ResolvedConfig{BaseID: "base", Tables: bufferedQuotes()})
+		rec := postWithKey(p)
+		if got := rec.Code == http.StatusOK; got != enabled {
+			t.Errorf("RetryIdempotencyKey=%v: status = %d", enabled, rec.Code)
+		}
+	}
+	if n := len(up.Requests()); n != 1 {
+		t.Errorf("upstream got %d requests, want only the retried one", n)
+	}
+}
+
+func TestRetryBudgetFailsFast(t *testing.T) {
+	up := flakyUpstream(t, 2, http.StatusServiceUnavailable)
+	p := newLegacyHandler(up)
+	p.UpstreamRetryBudget = 1
+
+	if rec := serve(p, http.MethodGet, "/proxy/t1/records/1", "", "7", "user"); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want NocoDB's 503 once the one retry was spent", rec.Code)
+	}
+	if n := len(up.Requests()); n != 2 {
+		t.Errorf("upstream got %d requests, want the request and one retry", n)
+	}
+}
+
+// Pages of a merged list draw retries from the same budget as the first page
+func TestRetryBudgetSharedAcrossPages(t *testing.T) {
+	pages := pagedUpstream(t, 4)
+	var failed sync.Map
+	up := newFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		// Every page fails on its first attempt
+		if _, seen := failed.LoadOrStore(r.URL.RawQuery, true); !seen {
+			jsonHandler(http.StatusBadGateway, `{"msg":"unavailable"}`)(w, r)
+			return
+		}
+		resp, err := pages.Client().Get(pages.URL + r.URL.RequestURI())
+		if err != nil {
+			return
+		}
+		defer resp.Body.Close()
+		w.Header().Set("Content-Type", "application/json")
+		io.Copy(w, resp.Body)
+	})
+	tests := []struct {
+		budget       int
+		wantRecords  int
+		wantRequests int
+	}{
+		{0, 4, 8},  // unlimited: every page retried once
+		{10, 4, 8}, // enough for all of them
+		{2, 1, 5},  // pages 1 and 2 spend the budget, page 3 fails once and the list falls back to page 1
+	}
+	for _, tt := range tests {
+		failed.Clear()
+		before := len(up.Requests())
+		p := newLegacyHandler(up)
+		p.PaginationWorkers = 1
+		p.UpstreamRetryBudget = tt.budget
+
+		list := decodeList(t, serve(p, http.MethodGet, "/proxy/quotes/records", "", "7", "user").Body.Bytes())
+		if len(list.List) != tt.wantRecords {
+			t.Errorf("budget %d: got %d records, want %d", tt.budget, len(list.List), tt.wantRecords)
+		}
+		if n := len(up.Requests()) - before; n != tt.wantRequests {
+			t.Errorf("budget %d: upstream got %d requests, want %d", tt.budget, n, tt.wantRequests)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package proxy
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/httperr"
+	"github.com/grove/generic-proxy/internal/logger"
+)
+
+// responseRewrites are the rewrites ServeHTTP decided on for a buffered response. The embedded
+// record transforms are the ones a JSON Lines stream applies page by page; each is skipped when nil.
+type responseRewrites struct {
+	recordTransforms
+	pathParts  []string
+	tableID    string
+	targetURL  string
+	ownerField string // a single record read must be owned by userID unless empty
+	userID     string
+	createdIDs bool // bulk creates list the new records' IDs
+	aggregates bool // every page of the list is merged
+	pagination paginationOptions
+	verifySort []config.SortKey // the merged list is re-sorted by these keys unless nil
+	cursor     bool             // the list gets an opaque cursor for its next page
+	pageLinks  bool             // next/prev links are pointed at the proxy
+	maxRecords int              // 0 = no record cap
+	budget     *callBudget
+}
+
+// rewriteResponse applies the rewrites to a buffered upstream body, in order: owner check, error
+// mapping, created IDs, page merging, comment counts, cursor, user fields, page links, sunset and
+// hidden fields, response filter, field aliases, record cap. A response that had to be refused is
+// written to w and ok is false; otherwise the rewritten body is left for the caller to send.
+func (p *ProxyHandler) rewriteResponse(w *trackingWriter, r *http.Request, status int, body []byte, rw responseRewrites) (_ []byte, ok bool) {
+	// Optional transforms are skipped on bodies above TRANSFORM_MAX_BYTES
+	optional := transformBudget{maxBytes: p.TransformMaxBytes}
+	pathParts := rw.pathParts
+
+	// Another user's record reads as missing, so its existence isn't revealed either
+	if rw.ownerField != "" && !ownsRecord(body, rw.ownerField, rw.userID) {
+		logger.Debug("[PROXY] Record %s of table '%s' is not owned by user %s", pathParts[2], pathParts[0], rw.userID)
+		w.fail(httperr.RecordNotFound, "record not found", nil)
+		return nil, false
+	}
+
+	// A resolved link whose record doesn't exist: distinguish it from an unknown link field (400)
+	linkNotFound := false
+	if link, isLink := parseLinkPath(pathParts[1:]); isLink && status == http.StatusNotFound && p.LinkNotFoundMode != "passthrough" {
+		notFoundBody, err := linkRecordNotFoundBody(pathParts[0], link.RecordID, body)
+		if err != nil {
+			log.Printf("[PROXY WARN] Failed to build record_not_found body: %v", err)
+		} else {
+			linkNotFound = true
+			logger.Debug("[PROXY] Link target record '%s' not found upstream", link.RecordID)
+			body = notFoundBody
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		}
+	}
+
+	// Other NocoDB errors get a stable proxy code when a rule recognizes them
+	if status >= 400 && !linkNotFound && len(p.UpstreamErrorRules) > 0 {
+		if code, message, ok := mapUpstreamError(p.UpstreamErrorRules, status, body); ok {
+			logger.Debug("[PROXY] Mapped NocoDB error (status %d) to %s", status, code)
+			w.dropUpstreamBodyHeaders()
+			httperr.WriteErrorWithFields(w, code, message, map[string]interface{}{
+				"upstream_status": status,
+				"upstream_error":  upstreamErrorDetail(body),
+			})
+			return nil, false
+		}
+	}
+
+	// Bulk creates list the new records' IDs, so clients needn't read them back (BULK_CREATED_IDS)
+	if rw.createdIDs {
+		withIDs, changed, err := addCreatedIDs(body, rw.apiVersion)
+		if err != nil {
+			log.Printf("[PROXY WARN] Failed to add created_ids: %v", err)
+		} else if changed {
+			body = withIDs
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		}
+	}
+
+	// Record lists: merge every upstream page unless the client asked for a specific page or opted out
+	if rw.aggregates {
+		merged, truncatedReason, err := p.handlePagination(r.Context(), body, rw.targetURL, rw.apiVersion, rw.pagination)
+		if r.Context().Err() != nil && p.PaginationOnCancel != PaginationCancelPartial {
+			logger.Debug("[PROXY] Client went away during pagination, dropping response")
+			return nil, false
+		}
+		if err != nil {
+			log.Printf("[PAGINATION ERROR] Failed to merge pages: %v", err)
+		} else {
+			body = merged
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+			if truncatedReason != "" {
+				w.Header().Set("X-Proxy-Truncated", "true")
+			}
+		}
+
+		if rw.verifySort != nil && optional.allows(transformVerifySort, body) {
+			if sorted, err := verifySortOrder(body, rw.verifySort, rw.apiVersion, p.SortVerifyMaxRecords); err != nil {
+				log.Printf("[SORT ERROR] Failed to re-sort records: %v", err)
+			} else {
+				body = sorted
+				w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+			}
+		}
+	}
+
+	if rw.commentCounts != nil && optional.allows(transformCommentCounts, body) {
+		withCounts, err := addCommentCounts(r.Context(), body, rw.tableKey, rw.apiVersion, rw.commentCounts)
+		if err != nil {
+			log.Printf("[PROXY WARN] Failed to add comment counts: %v", err)
+		} else {
+			body = withCounts
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		}
+	}
+
+	// List responses carry an opaque cursor for the next page
+	if rw.cursor {
+		withCursor, err := p.Cursors.addNextCursor(body, rw.tableKey, r.URL.Query(), rw.apiVersion)
+		if err != nil {
+			log.Printf("[PROXY WARN] Failed to add pagination cursor: %v", err)
+		} else {
+			body = withCursor
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		}
+	}
+
+	// NocoDB collaborators in user fields become proxy users (user_id, name, avatar_url)
+	if rw.userFields != nil {
+		translated, err := translateCollaborators(r.Context(), body, rw.userFields, rw.directory)
+		if err != nil {
+			log.Printf("[PROXY WARN] Failed to translate user fields: %v", err)
+		} else {
+			body = translated
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		}
+	}
+
+	// next/prev links left in the body (explicit pages, opt-outs, failed merges, link lists) point at the proxy
+	if rw.pageLinks {
+		rewritten, changed, err := p.rewritePageLinks(body, p.publicBaseURL(r), rw.tableKey, rw.tableID)
+		if err != nil {
+			log.Printf("[PROXY WARN] Failed to rewrite page links: %v", err)
+		} else if changed {
+			logger.Debug("[PAGINATION] Rewrote upstream page links to %s", p.publicBaseURL(r))
+			body = rewritten
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		}
+	}
+
+	// Fields past their sunset are no longer returned
+	if len(rw.sunsetFields) > 0 {
+		stripped, changed, err := stripRecordFields(body, rw.sunsetFields)
+		if err != nil {
+			log.Printf("[PROXY WARN] Failed to strip sunset fields: %v", err)
+		} else if changed {
+			body = stripped
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		}
+	}
+
+	// Hidden fields never leave the proxy, whatever the response filter or a linked record would let through
+	if len(rw.hiddenFields) > 0 || len(rw.hiddenLinks) > 0 {
+		stripped, changed, err := stripHiddenFields(body, rw.hiddenFields, rw.hiddenLinks)
+		if err != nil {
+			log.Printf("[PROXY ERROR] Failed to strip hidden fields: %v", err)
+			w.fail(httperr.UpstreamReadFailed, "failed to read upstream response", nil)
+			return nil, false
+		}
+		if changed {
+			body = stripped
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		}
+	}
+
+	// Per-table JSONPath filters run last, on the merged response, so paging and comment counts see the full body
+	if len(rw.responseFilter) > 0 && optional.allows(transformResponseFilter, body) {
+		filtered, err := applyResponseFilter(body, rw.responseFilter)
+		if err != nil {
+			log.Printf("[PROXY WARN] Failed to apply response filter: %v", err)
+		} else {
+			body = filtered
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		}
+	}
+
+	// Fields are renamed to their aliases after the filters, which address NocoDB titles
+	if rw.responseAliases != nil && optional.allows(transformRenameFields, body) {
+		if renamed, changed := renameResponseFields(body, rw.responseAliases, rw.aliasDepth); changed {
+			body = renamed
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		}
+	}
+
+	// Final guard, after every transform: cap the number of records returned to the client
+	if rw.maxRecords > 0 {
+		capped, truncated, err := capResponseRecords(body, rw.maxRecords)
+		if err != nil {
+			log.Printf("[PROXY WARN] Failed to apply response record cap: %v", err)
+		} else if truncated {
+			log.Printf("[PROXY WARN] Response truncated to %d records (MAX_RESPONSE_RECORDS)", rw.maxRecords)
+			body = capped
+			w.Header().Set("X-Proxy-Truncated", "true")
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		}
+	}
+
+	// Pages or retries left out for lack of budget are flagged in the response
+	if rw.budget.Exhausted() {
+		w.Header().Set(BudgetExhaustedHeader, "true")
+		if marked, changed, err := markBudgetExhausted(body); err != nil {
+			log.Printf("[PROXY WARN] Failed to flag exhausted call budget: %v", err)
+		} else if changed {
+			body = marked
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		}
+	}
+
+	optional.setHeader(w.Header())
+	return body, true
+}
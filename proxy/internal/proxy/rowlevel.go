@@ -0,0 +1,163 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/grove/generic-proxy/internal/httperr"
+	"github.com/grove/generic-proxy/internal/logger"
+	"github.com/grove/generic-proxy/internal/middleware"
+)
+
+// Row-level defaults (ROW_LEVEL_DEFAULT): what non-admin users see of tables without an owner_field
+const (
+	RowLevelOpen = "open" // every record, as before row-level filtering
+	RowLevelDeny = "deny" // nothing: record reads are rejected with 403
+)
+
+// rowLevelDenied reports whether ROW_LEVEL_DEFAULT keeps a role from reading the records of a
+// table without an owner field (legacy mode tables never have one)
+func (p *ProxyHandler) rowLevelDenied(ownerField, role string) bool {
+	return ownerField == "" && role != "admin" && p.RowLevelDefault == RowLevelDeny
+}
+
+// withoutRead returns the operations minus read
+func withoutRead(operations []string) []string {
+	kept := make([]string, 0, len(operations))
+	for _, operation := range operations {
+		if operation != "read" {
+			kept = append(kept, operation)
+		}
+	}
+	return kept
+}
+
+// ownerFilter is the NocoDB where clause limiting a read to the records owned by a user
+func ownerFilter(field, userID string) string {
+	return fmt.Sprintf("(%s,eq,%s)", field, userID)
+}
+
+// injectOwnerFilter adds the owner filter to the query, combined with the client's own ?where= if any
+func injectOwnerFilter(query url.Values, field, userID string) {
+	filter := ownerFilter(field, userID)
+	if where := query.Get("where"); where != "" {
+		filter = filter + "~and(" + where + ")"
+	}
+	query.Set("where", filter)
+}
+
+// balancedWhere reports whether a where clause's parentheses nest properly. Wrapping an unbalanced
+// clause in a group could close the group early and move its conditions out of the owner filter.
+func balancedWhere(where string) bool {
+	depth := 0
+	for _, c := range where {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return false
+			}
+		}
+	}
+	return depth == 0
+}
+
+// validOwnerID reports whether a user ID can be placed in a where clause as is
+func validOwnerID(userID string) bool {
+	return userID != "" && !strings.ContainsAny(userID, "(),~")
+}
+
+// setRecordOwner makes the owner field of a record write the proxy's decision: creates get the
+// user's ID whatever the client sent, updates lose the field so records can't change hands. With
+// keepSent (admins on tables with owner_admin_override) an owner in the body is left alone.
+func setRecordOwner(body []byte, field, userID string, create, keepSent bool) ([]byte, error) {
+	return rewriteWriteRecords(body, func(record map[string]interface{}) (bool, error) {
+		sent, ok := record[field]
+		switch {
+		case ok && keepSent:
+			return false, nil
+		case create:
+			if owner, isString := sent.(string); isString && owner == userID {
+				return false, nil
+			}
+			record[field] = userID
+			return true, nil
+		case ok:
+			delete(record, field)
+			return true, nil
+		}
+		return false, nil
+	})
+}
+
+// ownsRecord reports whether a single-record response belongs to the user. NocoDB ignores ?where= on
+// single-record reads, so the owner field is compared after the fact; a record without it, or a body
+// that isn't a JSON record whatever its Content-Type, isn't owned.
+func ownsRecord(body []byte, field, userID string) bool {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var record map[string]interface{}
+	if err := decoder.Decode(&record); err != nil {
+		return false
+	}
+	owner, ok := recordOwner(record, field)
+	return ok && owner == userID
+}
+
+// recordOwner returns a decoded record's owner field as a string; ok is false when it has none
+func recordOwner(record map[string]interface{}, field string) (string, bool) {
+	if fields, ok := record["fields"].(map[string]interface{}); ok {
+		record = fields // v3 wraps the record's fields
+	}
+	switch owner := record[field].(type) {
+	case string:
+		return owner, true
+	case json.Number:
+		return owner.String(), true
+	}
+	return "", false
+}
+
+// filterOwnedReads applies row-level filtering to a record read of a non-admin user: a list gets an
+// owner filter, a single record has to be checked once read (checksOwner), since NocoDB ignores
+// ?where= there. A read that is rejected has its error written and ok false.
+func (p *ProxyHandler) filterOwnedReads(w http.ResponseWriter, r *http.Request, pathParts []string, ownerField, userID string) (checksOwner, ok bool) {
+	role, _ := r.Context().Value(middleware.RoleKey).(string)
+	if r.Method != http.MethodGet || !isRecordPath(pathParts) || role == "admin" {
+		return false, true
+	}
+	switch {
+	case p.rowLevelDenied(ownerField, role):
+		log.Printf("[PROXY ERROR] Table '%s' has no owner_field, denying record read for user %s", pathParts[0], userID)
+		p.writeValidationError(w, newValidationError(httperr.OperationNotAllowed, "operation 'read' not allowed for table '%s'", pathParts[0]).
+			withParams(map[string]string{"operation": "read", "table": pathParts[0]}))
+		return false, false
+	case ownerField == "":
+		return false, true
+	case !validOwnerID(userID):
+		log.Printf("[PROXY ERROR] User ID %q can't be used in an owner filter", userID)
+		p.writeValidationError(w, newValidationError(httperr.OperationNotAllowed, "operation 'read' not allowed for table '%s'", pathParts[0]).
+			withParams(map[string]string{"operation": "read", "table": pathParts[0]}))
+		return false, false
+	case isRecordListPath(pathParts):
+		query := r.URL.Query()
+		if !balancedWhere(query.Get("where")) {
+			log.Printf("[PROXY ERROR] Rejected where with unbalanced parentheses: %s", query.Get("where"))
+			httperr.WriteError(w, httperr.InvalidWhere, "where has unbalanced parentheses")
+			return false, false
+		}
+		injectOwnerFilter(query, ownerField, userID)
+		r.URL.RawQuery = query.Encode()
+		logger.Debug("[PROXY] Injected owner filter: %s", query.Get("where"))
+		return false, true
+	default:
+		return true, true
+	}
+}
@@ -0,0 +1,286 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/httperr"
+)
+
+func TestInjectOwnerFilter(t *testing.T) {
+	tests := []struct {
+		name  string
+		where string
+		want  string
+	}{
+		{"no where", "", "(Owner,eq,7)"},
+		{"single condition", "(Status,eq,open)", "(Owner,eq,7)~and((Status,eq,open))"},
+		{"or stays inside the group", "(Status,eq,open)~or(Status,eq,draft)", "(Owner,eq,7)~and((Status,eq,open)~or(Status,eq,draft))"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := url.Values{}
+			if tt.where != "" {
+				query.Set("where", tt.where)
+			}
+			injectOwnerFilter(query, "Owner", "7")
+			if got := query.Get("where"); got != tt.want {
+				t.Errorf("where = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInjectOwnerFilterEncoding(t *testing.T) {
+	query := url.Values{"where": {"(Title,eq,a&b=c)"}, "limit": {"5"}}
+	injectOwnerFilter(query, "Owner", "7")
+
+	encoded := query.Encode()
+	decoded, err := url.ParseQuery(encoded)
+	if err != nil {
+		t.Fatalf("encoded query doesn't parse: %v", err)
+	}
+	if got, want := decoded.Get("where"), "(Owner,eq,7)~and((Title,eq,a&b=c))"; got != want {
+		t.Errorf("where after round trip = %q, want %q (encoded %s)", got, want, encoded)
+	}
+	if decoded.Get("limit") != "5" {
+		t.Errorf("limit lost in %s", encoded)
+	}
+}
+
+func TestBalancedWhere(t *testing.T) {
+	for where, want := range map[string]bool{
+		"":                                  true,
+		"(a,eq,1)":                          true,
+		"(a,eq,1)~or((b,eq,2)~and(c,eq,3))": true,
+		"(a,eq,1))~or((b,eq,2)":             false,
+		")(":                                false,
+		"((a,eq,1)":                         false,
+	} {
+		if got := balancedWhere(where); got != want {
+			t.Errorf("balancedWhere(%q) = %v, want %v", where, got, want)
+		}
+	}
+}
+
+func TestRecordListGetsOwnerFilter(t *testing.T) {
+	up := newFakeUpstream(t, jsonHandler(http.StatusOK, `{"list":[],"pageInfo":{"isLastPage":true}}`))
+	table := quotesTable()
+	table.OwnerField = "Owner"
+	p := newSchemaHandler(up, map[string]config.ResolvedTable{"quotes": table})
+
+	rec := serve(p, http.MethodGet, "/proxy/quotes/records?where=(Status,eq,open)", "", "7", "user")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	rec = serve(p, http.MethodGet, "/proxy/quotes/records?where=(Status,eq,open)", "", "1", "admin")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("admin status = %d, body %s", rec.Code, rec.Body)
+	}
+
+	requests := up.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("upstream got %d requests, want 2", len(requests))
+	}
+	if got, want := requests[0].Query.Get("where"), "(Owner,eq,7)~and((Status,eq,open))"; got != want {
+		t.Errorf("user where = %q, want %q", got, want)
+	}
+	if got, want := requests[1].Query.Get("where"), "(Status,eq,open)"; got != want {
+		t.Errorf("admin where = %q, want %q (admins bypass the owner filter)", got, want)
+	}
+}
+
+func TestRecordListRejectsUnbalancedWhere(t *testing.T) {
+	up := newFakeUpstream(t, jsonHandler(http.StatusOK, `{"list":[]}`))
+	table := quotesTable()
+	table.OwnerField = "Owner"
+	p := newSchemaHandler(up, map[string]config.ResolvedTable{"quotes": table})
+
+	rec := serve(p, http.MethodGet, "/proxy/quotes/records?where="+url.QueryEscape("(a,eq,1))~or((b,eq,2)"), "", "7", "user")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+	if len(up.Requests()) != 0 {
+		t.Error("request with an unbalanced where reached NocoDB")
+	}
+}
+
+func TestSingleRecordReadOfForeignRecord(t *testing.T) {
+	up := newFakeUpstream(t, jsonHandler(http.StatusOK, `{"Id":5,"Owner":"8"}`))
+	table := quotesTable()
+	table.OwnerField = "Owner"
+	p := newSchemaHandler(up, map[string]config.ResolvedTable{"quotes": table})
+
+	if rec := serve(p, http.MethodGet, "/proxy/quotes/records/5", "", "7", "user"); rec.Code != http.StatusNotFound {
+		t.Errorf("foreign record: status = %d, want 404", rec.Code)
+	}
+	if rec := serve(p, http.MethodGet, "/proxy/quotes/records/5", "", "8", "user"); rec.Code != http.StatusOK {
+		t.Errorf("own record: status = %d, want 200", rec.Code)
+	}
+	if rec := serve(p, http.MethodGet, "/proxy/quotes/records/5", "", "1", "admin"); rec.Code != http.StatusOK {
+		t.Errorf("admin: status = %d, want 200", rec.Code)
+	}
+}
+
+func TestSingleRecordOwnerCheckIgnoresContentType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		body        string
+		want        int
+	}{
+		{"text/plain", `{"Id":5,"Owner":"7"}`, http.StatusOK},
+		{"text/plain", `{"Id":5,"Owner":"8"}`, http.StatusNotFound},
+		{"text/html", `<p>Owner: 7</p>`, http.StatusNotFound},
+		{"application/octet-stream", ``, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		up := newFakeUpstream(t, typedHandler(tt.contentType, tt.body))
+		table := quotesTable()
+		table.OwnerField = "Owner"
+		p := newSchemaHandler(up, map[string]config.ResolvedTable{"quotes": table})
+
+		if rec := serve(p, http.MethodGet, "/proxy/quotes/records/5", "", "7", "user"); rec.Code != tt.want {
+			t.Errorf("%s %s: status = %d, body %s; want %d", tt.contentType, tt.body, rec.Code, rec.Body, tt.want)
+		}
+	}
+}
+
+func TestRowLevelDefault(t *testing.T) {
+	up := newFakeUpstream(t, jsonHandler(http.StatusOK, `{"list":[]}`))
+	tables := map[string]config.ResolvedTable{"quotes": quotesTable()}
+
+	open := newSchemaHandler(up, tables)
+	if rec := serve(open, http.MethodGet, "/proxy/quotes/records", "", "7", "user"); rec.Code != http.StatusOK {
+		t.Errorf("open: status = %d, want 200", rec.Code)
+	}
+
+	deny := newSchemaHandler(up, tables, func(p *ProxyHandler) { p.RowLevelDefault = RowLevelDeny })
+	if rec := serve(deny, http.MethodGet, "/proxy/quotes/records", "", "7", "user"); rec.Code != http.StatusForbidden {
+		t.Errorf("deny: status = %d, want 403", rec.Code)
+	}
+	if rec := serve(deny, http.MethodGet, "/proxy/quotes/records", "", "1", "admin"); rec.Code != http.StatusOK {
+		t.Errorf("deny, admin: status = %d, want 200", rec.Code)
+	}
+}
+
+// ownedRecordsUpstream serves record 5 owned by user 7 and record 6 owned by user 8; others are missing
+func ownedRecordsUpstream(t *testing.T) *fakeUpstream {
+	return newFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2/tables/t1/records/5":
+			jsonHandler(http.StatusOK, `{"Id":5,"Owner":"7"}`)(w, r)
+		case "/api/v2/tables/t1/records/6":
+			jsonHandler(http.StatusOK, `{"Id":6,"Owner":8}`)(w, r)
+		default:
+			jsonHandler(http.StatusNotFound, `{"msg":"Record not found"}`)(w, r)
+		}
+	})
+}
+
+func TestCheckRead(t *testing.T) {
+	up := ownedRecordsUpstream(t)
+	owned := quotesTable()
+	owned.OwnerField = "Owner"
+	writeOnly := quotesTable()
+	writeOnly.TableID = "t2"
+	writeOnly.Operations = []string{"create"}
+	tables := map[string]config.ResolvedTable{"quotes": owned, "open": {Name: "Open", TableID: "t3", Operations: []string{"read"}}, "drop": writeOnly}
+
+	p := newSchemaHandler(up, tables)
+	deny := newSchemaHandler(up, tables, func(p *ProxyHandler) { p.RowLevelDefault = RowLevelDeny })
+
+	tests := []struct {
+		name     string
+		handler  *ProxyHandler
+		table    string
+		recordID string
+		userID   string
+		role     string
+		wantCode string // "" = allowed
+	}{
+		{"owner", p, "quotes", "5", "7", "user", ""},
+		{"numeric owner value", p, "quotes", "6", "8", "user", ""},
+		{"not the owner", p, "quotes", "5", "8", "user", httperr.RecordNotFound},
+		{"missing record", p, "quotes", "9", "7", "user", httperr.RecordNotFound},
+		{"admin reads any record", p, "quotes", "5", "1", "admin", ""},
+		{"no owner_field, open", p, "open", "5", "7", "user", ""},
+		{"no owner_field, deny", deny, "open", "5", "7", "user", httperr.OperationNotAllowed},
+		{"no owner_field, deny, admin", deny, "open", "5", "1", "admin", ""},
+		{"no read operation", p, "drop", "5", "7", "user", httperr.OperationNotAllowed},
+		{"unknown table", p, "nope", "5", "7", "user", httperr.TableNotFound},
+		{"invalid record ID", p, "quotes", "5;x", "7", "user", httperr.InvalidRecordID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.handler.CheckRead(context.Background(), tt.table, tt.recordID, tt.userID, tt.role)
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Fatalf("CheckRead = %v, want allowed", err)
+				}
+				return
+			}
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("CheckRead = %v, want a ValidationError with code %s", err, tt.wantCode)
+			}
+			if validationErr.Code != tt.wantCode {
+				t.Errorf("code = %s, want %s", validationErr.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestCheckReadUpstreamFailure(t *testing.T) {
+	up := newFakeUpstream(t, jsonHandler(http.StatusInternalServerError, `{"msg":"boom"}`))
+	table := quotesTable()
+	table.OwnerField = "Owner"
+	p := newSchemaHandler(up, map[string]config.ResolvedTable{"quotes": table})
+
+	err := p.CheckRead(context.Background(), "quotes", "5", "7", "user")
+	var validationErr *ValidationError
+	if err == nil || errors.As(err, &validationErr) {
+		t.Errorf("CheckRead = %v, want an upstream error", err)
+	}
+}
+
+func TestRecordWritesGetOwnerFromToken(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		body     string
+		userID   string
+		role     string
+		override bool
+		want     string
+	}{
+		{"create adds owner", http.MethodPost, `{"Title":"a"}`, "7", "user", false, `{"Owner":"7","Title":"a"}`},
+		{"spoofed create", http.MethodPost, `{"Title":"a","Owner":"8"}`, "7", "user", false, `{"Owner":"7","Title":"a"}`},
+		{"spoofed bulk create", http.MethodPost, `[{"Owner":"8"},{"Owner":8},{"Title":"c"}]`, "7", "user", false, `[{"Owner":"7"},{"Owner":"7"},{"Owner":"7","Title":"c"}]`},
+		{"admin without override", http.MethodPost, `{"Owner":"8"}`, "1", "admin", false, `{"Owner":"1"}`},
+		{"admin with override", http.MethodPost, `{"Owner":"8"}`, "1", "admin", true, `{"Owner":"8"}`},
+		{"user with override", http.MethodPost, `{"Owner":"8"}`, "7", "user", true, `{"Owner":"7"}`},
+		{"update drops owner", http.MethodPatch, `{"Id":5,"Owner":"8","Title":"b"}`, "1", "admin", false, `{"Id":5,"Title":"b"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			up := echoUpstream(t)
+			table := quotesTable()
+			table.OwnerField = "Owner"
+			table.OwnerOverride = tt.override
+			p := newSchemaHandler(up, map[string]config.ResolvedTable{"quotes": table})
+
+			rec := serve(p, tt.method, "/proxy/quotes/records", tt.body, tt.userID, tt.role)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+			}
+			requests := up.Requests()
+			if got := requests[len(requests)-1].Body; got != tt.want {
+				t.Errorf("NocoDB got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Select validation modes (SELECT_VALIDATION)
+const (
+	SelectValidationOff     = "off"     // forward bodies untouched
+	SelectValidationStrict  = "strict"  // reject unknown options immediately
+	SelectValidationRefresh = "refresh" // refresh MetaCache once before rejecting, so new upstream options are accepted
+)
+
+// selectDriftRefreshAge limits how often an unknown option can trigger a metadata refresh
+const selectDriftRefreshAge = time.Minute
+
+// selectViolation describes a write body value that isn't an allowed select option
+type selectViolation struct {
+	Field   string   `json:"field"`
+	Value   string   `json:"value"`
+	Allowed []string `json:"allowed"`
+}
+
+func (v *selectViolation) Error() string {
+	return fmt.Sprintf("invalid value '%s' for field '%s' (allowed: %s)", v.Value, v.Field, strings.Join(v.Allowed, ", "))
+}
+
+// validateSelectValues checks every select field in a create/update body against the cached options.
+// Both single-object and bulk array bodies are accepted, as are v3 {"fields": {...}} wrappers.
+func (p *ProxyHandler) validateSelectValues(ctx context.Context, tableID string, body []byte) *selectViolation {
+	if p.Meta == nil || p.SelectValidation == SelectValidationOff || p.SelectValidation == "" {
+		return nil
+	}
+
+	selects := p.Meta.GetSelectFields(tableID)
+	if len(selects) == 0 {
+		return nil
+	}
+
+	violation := findSelectViolation(selects, body)
+	if violation == nil || p.SelectValidation != SelectValidationRefresh {
+		return violation
+	}
+
+	// The option may have been added upstream after our last refresh
+	if p.Meta.RefreshIfOlderThan(ctx, selectDriftRefreshAge) {
+		return findSelectViolation(p.Meta.GetSelectFields(tableID), body)
+	}
+	return violation
+}
+
+// metaSelectFields returns the cached select fields for a table, if MetaCache is available
+func (p *ProxyHandler) metaSelectFields(tableID string) map[string]SelectField {
+	if p.Meta == nil {
+		return nil
+	}
+	return p.Meta.GetSelectFields(tableID)
+}
+
+// findSelectViolation returns the first invalid select value in the body, if any
+func findSelectViolation(selects map[string]SelectField, body []byte) *selectViolation {
+	for _, record := range decodeWriteRecords(body) {
+		for fieldName, value := range record {
+			selectField, ok := selects[fieldName]
+			if !ok || value == nil {
+				continue
+			}
+			for _, item := range selectValues(selectField.Type, value) {
+				if !containsExact(selectField.Options, item) {
+					return &selectViolation{Field: fieldName, Value: item, Allowed: selectField.Options}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// decodeWriteRecords extracts the field maps from a write body (object, array, or v3 "fields" wrappers)
+func decodeWriteRecords(body []byte) []map[string]interface{} {
+	var items []map[string]interface{}
+	if err := json.Unmarshal(body, &items); err != nil {
+		var single map[string]interface{}
+		if err := json.Unmarshal(body, &single); err != nil {
+			return nil
+		}
+		items = []map[string]interface{}{single}
+	}
+
+	records := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		if fields, ok := item["fields"].(map[string]interface{}); ok {
+			records = append(records, fields)
+		} else {
+			records = append(records, item)
+		}
+	}
+	return records
+}
+
+// selectValues normalizes a select value: multi-selects may be arrays or comma-separated strings
+func selectValues(fieldType string, value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		if v == "" {
+			return nil
+		}
+		if fieldType == "MultiSelect" {
+			var values []string
+			for _, part := range strings.Split(v, ",") {
+				if part = strings.TrimSpace(part); part != "" {
+					values = append(values, part)
+				}
+			}
+			return values
+		}
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			values = append(values, fmt.Sprint(item))
+		}
+		return values
+	default:
+		return []string{fmt.Sprint(v)}
+	}
+}
+
+// containsExact reports whether list contains value (case-sensitive)
+func containsExact(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,172 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/logger"
+)
+
+// sortQueryValue renders sort keys in the upstream API format:
+// v2 takes "-Title,Name", v3 takes a JSON array of {field, direction}
+func sortQueryValue(keys []config.SortKey, apiVersion string) string {
+	if apiVersion == "v2" {
+		parts := make([]string, len(keys))
+		for i, key := range keys {
+			parts[i] = key.Field
+			if key.Desc {
+				parts[i] = "-" + key.Field
+			}
+		}
+		return strings.Join(parts, ",")
+	}
+
+	type v3Sort struct {
+		Field     string `json:"field"`
+		Direction string `json:"direction"`
+	}
+	sorts := make([]v3Sort, len(keys))
+	for i, key := range keys {
+		sorts[i] = v3Sort{Field: key.Field, Direction: "asc"}
+		if key.Desc {
+			sorts[i].Direction = "desc"
+		}
+	}
+	encoded, _ := json.Marshal(sorts)
+	return string(encoded)
+}
+
+// injectDefaultSort adds the table's default sort unless the client sent ?sort= itself
+func injectDefaultSort(query url.Values, keys []config.SortKey, apiVersion string) bool {
+	if len(keys) == 0 || query.Has("sort") {
+		return false
+	}
+	query.Set("sort", sortQueryValue(keys, apiVersion))
+	return true
+}
+
+// injectListSort adds the default sort to a list read, keeping the ordering stable across pages, and
+// reports whether it did; an explicit ?sort= wins
+func injectListSort(r *http.Request, pathParts []string, keys []config.SortKey, apiVersion string) bool {
+	if r.Method != http.MethodGet || !isRecordListPath(pathParts) {
+		return false
+	}
+	query := r.URL.Query()
+	if !injectDefaultSort(query, keys, apiVersion) {
+		return false
+	}
+	r.URL.RawQuery = query.Encode()
+	logger.Debug("[PROXY] Injected default sort: %s", query.Get("sort"))
+	return true
+}
+
+// verifySortOrder checks that an aggregated record list is ordered by keys. Out-of-order records
+// (pages that interleave upstream) are logged and stable re-sorted, as long as the list has at
+// most maxRecords entries (0 = no ceiling). Returns the (possibly) rewritten body.
+func verifySortOrder(body []byte, keys []config.SortKey, apiVersion string, maxRecords int) ([]byte, error) {
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return body, nil
+	}
+
+	listKey := "records"
+	if apiVersion == "v2" {
+		listKey = "list"
+	}
+	var records []map[string]interface{}
+	if err := json.Unmarshal(envelope[listKey], &records); err != nil {
+		return body, nil
+	}
+
+	less := func(a, b map[string]interface{}) bool {
+		return compareRecords(a, b, keys, apiVersion) < 0
+	}
+	violation := -1
+	for i := 1; i < len(records); i++ {
+		if less(records[i], records[i-1]) {
+			violation = i
+			break
+		}
+	}
+	if violation < 0 {
+		return body, nil
+	}
+
+	if maxRecords > 0 && len(records) > maxRecords {
+		log.Printf("[SORT WARN] Records out of order at index %d of %d, too many to re-sort (SORT_VERIFY_MAX_RECORDS=%d)", violation, len(records), maxRecords)
+		return body, nil
+	}
+	log.Printf("[SORT WARN] Records out of order at index %d of %d - re-sorting in the proxy", violation, len(records))
+
+	sort.SliceStable(records, func(i, j int) bool { return less(records[i], records[j]) })
+	sorted, err := json.Marshal(records)
+	if err != nil {
+		return nil, err
+	}
+	envelope[listKey] = sorted
+	return json.Marshal(envelope)
+}
+
+// compareRecords compares two records key by key; v3 keeps values under "fields"
+func compareRecords(a, b map[string]interface{}, keys []config.SortKey, apiVersion string) int {
+	for _, key := range keys {
+		c := compareValues(recordValue(a, key.Field, apiVersion), recordValue(b, key.Field, apiVersion))
+		if key.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func recordValue(record map[string]interface{}, field, apiVersion string) interface{} {
+	if apiVersion != "v2" {
+		fields, _ := record["fields"].(map[string]interface{})
+		return fields[field]
+	}
+	return record[field]
+}
+
+// compareValues orders JSON values; nulls sort first and mixed types fall back to their text form
+func compareValues(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+
+	switch av := a.(type) {
+	case float64:
+		if bv, ok := b.(float64); ok {
+			switch {
+			case av < bv:
+				return -1
+			case av > bv:
+				return 1
+			}
+			return 0
+		}
+	case bool:
+		if bv, ok := b.(bool); ok {
+			switch {
+			case av == bv:
+				return 0
+			case !av:
+				return -1
+			}
+			return 1
+		}
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
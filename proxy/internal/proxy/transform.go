@@ -0,0 +1,79 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/grove/generic-proxy/internal/jsonpath"
+)
+
+// recordListKeys are the JSON keys NocoDB uses for record arrays (v3 "records", v2 "list")
+var recordListKeys = []string{"records", "list"}
+
+// capResponseRecords truncates the record list in a JSON response body to max entries.
+// It is a last-line guard applied after all other transforms. Returns the (possibly) rewritten
+// body and whether truncation happened; bodies that aren't record lists are returned untouched.
+func capResponseRecords(body []byte, max int) ([]byte, bool, error) {
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		// Not a JSON object (e.g. single value or non-JSON), nothing to cap
+		return body, false, nil
+	}
+
+	for _, key := range recordListKeys {
+		raw, ok := envelope[key]
+		if !ok {
+			continue
+		}
+
+		var records []json.RawMessage
+		if err := json.Unmarshal(raw, &records); err != nil {
+			return body, false, nil
+		}
+		if len(records) <= max {
+			return body, false, nil
+		}
+
+		truncatedList, err := json.Marshal(records[:max])
+		if err != nil {
+			return nil, false, err
+		}
+		envelope[key] = truncatedList
+		envelope["truncated"] = json.RawMessage("true")
+		envelope["truncated_reason"] = json.RawMessage(`"max_response_records"`)
+
+		capped, err := json.Marshal(envelope)
+		if err != nil {
+			return nil, false, err
+		}
+		return capped, true, nil
+	}
+
+	return body, false, nil
+}
+
+// proxyEnvelopeKeys are added by the proxy itself and survive response filters
+var proxyEnvelopeKeys = []string{"truncated", "truncated_reason", "pagination_truncated", "cursor", "budget_exhausted"}
+
+// applyResponseFilter prunes a JSON response to the subtrees matched by the table's JSONPath filters.
+// Bodies that aren't JSON objects are returned untouched.
+func applyResponseFilter(body []byte, filters []jsonpath.Path) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber() // keep large numbers exact
+	var doc map[string]interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return body, nil
+	}
+
+	pruned, _ := jsonpath.Prune(doc, filters)
+	out, _ := pruned.(map[string]interface{})
+	if out == nil {
+		out = map[string]interface{}{}
+	}
+	for _, key := range proxyEnvelopeKeys {
+		if value, ok := doc[key]; ok {
+			out[key] = value
+		}
+	}
+	return json.Marshal(out)
+}
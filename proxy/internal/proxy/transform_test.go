@@ -0,0 +1,124 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/jsonpath"
+)
+
+// MAX_RESPONSE_RECORDS runs after every other transform, so filters and renaming see the whole
+// merged list and the cap applies to what the client actually gets
+func TestResponseRecordCapRunsLast(t *testing.T) {
+	up := newFakeUpstream(t, jsonHandler(http.StatusOK, `{"list":[
+		{"Id":1,"Title":"a","Secret":"s"},{"Id":2,"Title":"b","Secret":"s"},{"Id":3,"Title":"c","Secret":"s"},
+		{"Id":4,"Title":"d","Secret":"s"},{"Id":5,"Title":"e","Secret":"s"}],"pageInfo":{"isLastPage":true}}`))
+	table := quotesTable()
+	filter, err := jsonpath.Parse("$.list[2,3,4]['Id','Title']")
+	if err != nil {
+		t.Fatalf("parse filter: %v", err)
+	}
+	table.ResponseFilter = []jsonpath.Path{filter}
+	table.ResponseAliases = &config.ResponseAliases{Fields: map[string]string{"Title": "title"}}
+	p := newSchemaHandler(up, map[string]config.ResolvedTable{"quotes": table}, func(p *ProxyHandler) { p.MaxResponseRecords = 2 })
+
+	rec := serve(p, http.MethodGet, "/proxy/quotes/records", "", "7", "user")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	var body struct {
+		List            []map[string]interface{} `json:"list"`
+		Truncated       bool                     `json:"truncated"`
+		TruncatedReason string                   `json:"truncated_reason"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("body %s: %v", rec.Body, err)
+	}
+	if len(body.List) != 2 || body.List[0]["Id"] != 3.0 || body.List[1]["Id"] != 4.0 {
+		t.Fatalf("list = %v, want records 3 and 4: the filter picks 3-5 from the full list, the cap keeps two", body.List)
+	}
+	if body.List[0]["title"] != "c" || body.List[0]["Secret"] != nil {
+		t.Errorf("record = %v, want only Id and the renamed title", body.List[0])
+	}
+	if !body.Truncated || body.TruncatedReason != "max_response_records" || rec.Header().Get("X-Proxy-Truncated") != "true" {
+		t.Errorf("truncation not flagged: %s (header %q)", rec.Body, rec.Header().Get("X-Proxy-Truncated"))
+	}
+}
+
+// Filters run on the merged list, not page by page, and keep the proxy's own envelope keys
+func TestResponseFilterAfterPaginationMerge(t *testing.T) {
+	filter, err := jsonpath.Parse("$.list[-1]")
+	if err != nil {
+		t.Fatalf("parse filter: %v", err)
+	}
+	tests := []struct {
+		name      string
+		fanout    int
+		wantID    float64
+		truncated bool
+	}{
+		{"complete list", 0, 3, false},
+		{"truncated list", 2, 2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			up := pagedUpstream(t, 3)
+			table := quotesTable()
+			table.ResponseFilter = []jsonpath.Path{filter}
+			p := newSchemaHandler(up, map[string]config.ResolvedTable{"quotes": table}, func(p *ProxyHandler) { p.MaxPaginationFanout = tt.fanout })
+
+			rec := serve(p, http.MethodGet, "/proxy/quotes/records", "", "7", "user")
+			var body map[string]interface{}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("body %s: %v", rec.Body, err)
+			}
+			list := decodeList(t, rec.Body.Bytes())
+			if len(list.List) != 1 || list.List[0]["Id"] != tt.wantID {
+				t.Errorf("list = %v, want only record %v, the last of the merged list", list.List, tt.wantID)
+			}
+			if _, ok := body["pageInfo"]; ok {
+				t.Errorf("pageInfo survived the filter: %s", rec.Body)
+			}
+			if list.Truncated != tt.truncated || (tt.truncated && list.TruncatedReason != truncatedMaxFanout) {
+				t.Errorf("truncated = %v (%s), want %v", list.Truncated, list.TruncatedReason, tt.truncated)
+			}
+		})
+	}
+}
+
+func TestTransformBudget(t *testing.T) {
+	body := `{"Id":1,"Title":"a","Secret":"s"}`
+	tests := []struct {
+		name        string
+		maxBytes    int64
+		want        string
+		wantSkipped string
+	}{
+		{"unlimited", 0, `{"Id":1,"title":"a"}`, ""},
+		{"below the budget", int64(len(body)), `{"Id":1,"title":"a"}`, ""},
+		{"above the budget", int64(len(body)) - 1, body, transformResponseFilter + ", " + transformRenameFields},
+	}
+	filter, err := jsonpath.Parse("$['Id','Title']")
+	if err != nil {
+		t.Fatalf("parse filter: %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			up := newFakeUpstream(t, jsonHandler(http.StatusOK, body))
+			table := quotesTable()
+			table.ResponseFilter = []jsonpath.Path{filter}
+			table.ResponseAliases = &config.ResponseAliases{Fields: map[string]string{"Title": "title"}}
+			p := newSchemaHandler(up, map[string]config.ResolvedTable{"quotes": table}, func(p *ProxyHandler) { p.TransformMaxBytes = tt.maxBytes })
+
+			rec := serve(p, http.MethodGet, "/proxy/quotes/records/1", "", "7", "user")
+			if rec.Code != http.StatusOK || rec.Body.String() != tt.want {
+				t.Errorf("status = %d, body %s; want %s", rec.Code, rec.Body, tt.want)
+			}
+			if got := rec.Header().Get(TransformsSkippedHeader); got != tt.wantSkipped {
+				t.Errorf("%s = %q, want %q", TransformsSkippedHeader, got, tt.wantSkipped)
+			}
+		})
+	}
+}
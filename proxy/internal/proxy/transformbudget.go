@@ -0,0 +1,44 @@
+package proxy
+
+import (
+	"log"
+	"net/http"
+	"strings"
+)
+
+// TransformsSkippedHeader lists the optional transforms a response skipped for exceeding TRANSFORM_MAX_BYTES
+const TransformsSkippedHeader = "X-Proxy-Transforms-Skipped"
+
+// Optional response transforms, named as in TransformsSkippedHeader. Transforms that protect data
+// or the response contract (hidden and sunset fields, owner checks, record caps, cursors, page
+// links, user translation) always run.
+const (
+	transformVerifySort     = "verify_sort"
+	transformCommentCounts  = "comment_count"
+	transformResponseFilter = "response_filter"
+	transformRenameFields   = "rename_response_fields"
+)
+
+// transformBudget skips the optional transforms of a response whose body is larger than maxBytes,
+// since each one decodes and re-encodes the whole body
+type transformBudget struct {
+	maxBytes int64 // 0 = unlimited
+	skipped  []string
+}
+
+// allows reports whether an optional transform may run on body, and records it as skipped if not
+func (b *transformBudget) allows(transform string, body []byte) bool {
+	if b.maxBytes <= 0 || int64(len(body)) <= b.maxBytes {
+		return true
+	}
+	log.Printf("[PROXY WARN] Skipping %s: response of %d bytes exceeds TRANSFORM_MAX_BYTES (%d)", transform, len(body), b.maxBytes)
+	b.skipped = append(b.skipped, transform)
+	return false
+}
+
+// setHeader names the skipped transforms in TransformsSkippedHeader
+func (b *transformBudget) setHeader(header http.Header) {
+	if len(b.skipped) > 0 {
+		header.Set(TransformsSkippedHeader, strings.Join(b.skipped, ", "))
+	}
+}
@@ -1,58 +1,99 @@
 package proxy
 
 import (
-	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"regexp"
 	"strings"
 
 	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/httperr"
+	"github.com/grove/generic-proxy/internal/logger"
 )
 
 // Validator validates requests against the resolved configuration
 type Validator struct {
-	config    *config.ResolvedConfig
-	metaCache *MetaCache
+	config     *config.ResolvedConfig
+	metaCache  *MetaCache
+	apiVersion string // NocoDB data API version ("v2" or "v3") used to shape link paths
+
+	unresolvedFields  string // UnresolvedFieldsLenient (default) or UnresolvedFieldsStrict
+	linkTargets       string // LinkTargetsOff (default) or LinkTargetsEnforce
+	linkImpliesUnlink bool   // link also grants unlink (LINK_IMPLIES_UNLINK)
+
+	recordIDPattern *regexp.Regexp // path shapes and record IDs are checked when set (PATH_VALIDATION=strict)
 }
 
 // NewValidator creates a new validator with the given resolved configuration
-func NewValidator(config *config.ResolvedConfig, metaCache *MetaCache) *Validator {
+func NewValidator(config *config.ResolvedConfig, metaCache *MetaCache, apiVersion string) *Validator {
 	return &Validator{
-		config:    config,
-		metaCache: metaCache,
+		config:     config,
+		metaCache:  metaCache,
+		apiVersion: apiVersion,
 	}
 }
 
-// ValidateRequest validates an incoming proxy request
-func (v *Validator) ValidateRequest(method, path string) (*ValidationResult, error) {
-	log.Printf("[VALIDATOR] Validating request: %s %s", method, path)
+// ValidateRequest validates an incoming proxy request made with the caller's role. On reads, aliases
+// in query's fields parameter are checked and rewritten to field IDs in place, and aliases in
+// where/sort to field titles; query may be nil.
+func (v *Validator) ValidateRequest(method, path, role string, query url.Values) (*ValidationResult, error) {
+	logger.Debug("[VALIDATOR] Validating request: %s %s", method, path)
 
 	// Parse the path to extract table identifier and operation
 	parts := strings.Split(strings.Trim(path, "/"), "/")
 	if len(parts) == 0 {
-		return nil, fmt.Errorf("invalid path: empty")
+		return nil, newValidationError(httperr.InvalidPath, "invalid path: empty")
 	}
 
 	tableKey := parts[0]
-	log.Printf("[VALIDATOR] Table key: %s", tableKey)
+	logger.Debug("[VALIDATOR] Table key: %s", tableKey)
 
 	// Find the table in resolved config
 	table, ok := v.config.Tables[tableKey]
 	if !ok {
-		return nil, fmt.Errorf("table '%s' not found in configuration", tableKey)
+		return nil, newValidationError(httperr.TableNotFound, "table '%s' not found in configuration", tableKey).
+			withParams(map[string]string{"table": tableKey})
 	}
 
 	// Determine the operation from HTTP method and path
-	operation := v.determineOperation(method, parts)
-	log.Printf("[VALIDATOR] Operation: %s", operation)
+	operation := v.determineOperation(method, parts, table)
+	logger.Debug("[VALIDATOR] Operation: %s", operation)
 
 	// Check if operation is allowed
-	if !v.isOperationAllowed(table, operation) {
-		return nil, fmt.Errorf("operation '%s' not allowed for table '%s'", operation, tableKey)
+	if !v.isOperationAllowed(table, role, operation) {
+		return nil, newValidationError(httperr.OperationNotAllowed, "operation '%s' not allowed for table '%s'", operation, tableKey).
+			withParams(map[string]string{"operation": operation, "table": tableKey})
+	}
+
+	// Link reads count as reads on the parent table, but only for configured links
+	if link, isLink := parseLinkPath(parts[1:]); isLink && method == http.MethodGet {
+		if !v.isLinkConfigured(table, link.Alias) {
+			return nil, newValidationError(httperr.LinkNotAllowed, "link '%s' is not configured for table '%s'", link.Alias, tableKey).
+				withParams(map[string]string{"link": link.Alias, "table": tableKey})
+		}
+	}
+	if link, isLink := parseLinkPath(parts[1:]); isLink && v.linkTargets == LinkTargetsEnforce {
+		if err := v.checkLinkTarget(tableKey, table, link.Alias, method, role); err != nil {
+			return nil, err
+		}
+	}
+
+	// Column projection may only name configured fields
+	if method == http.MethodGet && query != nil {
+		if err := checkHiddenProjection(tableKey, table, query["fields"]); err != nil {
+			return nil, err
+		}
+		if err := v.validateFieldProjection(tableKey, table, query); err != nil {
+			return nil, err
+		}
+		if err := v.resolveQueryFields(tableKey, table, query); err != nil {
+			return nil, err
+		}
 	}
 
 	// Build resolved path with link field resolution if needed
-	resolvedPath, err := v.buildResolvedPath(table.TableID, table.Name, parts[1:])
+	resolvedPath, err := v.buildResolvedPath(table, parts[1:])
 	if err != nil {
 		return nil, err
 	}
@@ -66,10 +107,43 @@ func (v *Validator) ValidateRequest(method, path string) (*ValidationResult, err
 		ResolvedPath: resolvedPath,
 	}
 
-	log.Printf("[VALIDATOR] Validation successful: %+v", result)
+	logger.Debug("[VALIDATOR] Validation successful: %+v", result)
 	return result, nil
 }
 
+// validateFieldProjection checks the fields query parameter (NocoDB's column selector) against the
+// table's field aliases and rewrites them to field IDs. Tables without field aliases aren't restricted,
+// and the record id is always allowed since NocoDB returns it anyway.
+func (v *Validator) validateFieldProjection(tableKey string, table config.ResolvedTable, query url.Values) error {
+	if !query.Has("fields") || len(table.Fields) == 0 {
+		return nil
+	}
+
+	var resolved []string
+	for _, value := range query["fields"] {
+		for _, alias := range strings.Split(value, ",") {
+			alias = strings.TrimSpace(alias)
+			if alias == "" {
+				continue
+			}
+			if alias == "id" || alias == "Id" {
+				resolved = append(resolved, alias)
+				continue
+			}
+			fieldID, ok := table.Fields[alias]
+			if !ok {
+				return newValidationError(httperr.FieldNotAllowed, "field '%s' is not exposed for table '%s'", alias, tableKey).
+					withParams(map[string]string{"field": alias, "table": tableKey})
+			}
+			resolved = append(resolved, fieldID)
+		}
+	}
+
+	query.Set("fields", strings.Join(resolved, ","))
+	logger.Debug("[VALIDATOR] Resolved field projection: %s", query.Get("fields"))
+	return nil
+}
+
 // ValidationResult contains the result of request validation
 type ValidationResult struct {
 	TableKey     string
@@ -81,69 +155,154 @@ type ValidationResult struct {
 }
 
 // determineOperation determines the operation type from HTTP method and path
-func (v *Validator) determineOperation(method string, parts []string) string {
+func (v *Validator) determineOperation(method string, parts []string, table config.ResolvedTable) string {
+	return classifyOperation(method, parts, table.RequireReadLinks)
+}
+
+// classifyOperation maps a request to an operation name; shared by schema-driven and legacy mode.
+// With splitReadLinks, GET link paths are classified as "read_links" instead of "read". Adding a link
+// (POST on a link path) is "link" and removing one (DELETE) is "unlink", never "create" or "delete".
+func classifyOperation(method string, parts []string, splitReadLinks bool) string {
 	switch method {
 	case http.MethodGet:
+		// Tables can gate relationship traversal separately from plain record reads
+		if _, isLink := parseLinkPath(parts[1:]); isLink && splitReadLinks {
+			return "read_links"
+		}
 		return "read"
 	case http.MethodPost:
-		if len(parts) > 2 && parts[2] == "links" {
+		if _, isLink := parseLinkPath(parts[1:]); isLink {
 			return "link"
 		}
 		return "create"
 	case http.MethodPatch, http.MethodPut:
 		return "update"
 	case http.MethodDelete:
+		if _, isLink := parseLinkPath(parts[1:]); isLink {
+			return "unlink"
+		}
 		return "delete"
 	default:
 		return "unknown"
 	}
 }
 
-// isOperationAllowed checks if an operation is allowed for a table
-func (v *Validator) isOperationAllowed(table config.ResolvedTable, operation string) bool {
-	for _, allowedOp := range table.Operations {
-		if allowedOp == operation {
-			return true
+// knownOperations lists every operation determineOperation can classify a request as
+var knownOperations = []string{"read", "read_links", "create", "update", "delete", "link", "unlink"}
+
+// AllowedOperations returns the operations a role may perform on a table, evaluated with the same
+// checks ValidateRequest applies. The second return value is false if the table isn't configured.
+func (v *Validator) AllowedOperations(tableKey, role string) ([]string, bool) {
+	table, ok := v.config.Tables[tableKey]
+	if !ok {
+		return nil, false
+	}
+
+	allowed := []string{}
+	for _, op := range knownOperations {
+		if v.isOperationAllowed(table, role, op) {
+			allowed = append(allowed, op)
+		}
+	}
+	return allowed, true
+}
+
+// isOperationAllowed checks if a role may perform an operation on a table
+func (v *Validator) isOperationAllowed(table config.ResolvedTable, role, operation string) bool {
+	return operationAllowed(table.OperationsFor(role), operation, v.linkImpliesUnlink)
+}
+
+// operationAllowed reports whether the allowed operations grant operation. With linkImpliesUnlink,
+// link grants unlink too, as it did before unlink was an operation of its own.
+func operationAllowed(allowed []string, operation string, linkImpliesUnlink bool) bool {
+	if containsExact(allowed, operation) {
+		return true
+	}
+	return operation == "unlink" && linkImpliesUnlink && containsExact(allowed, "link")
+}
+
+// isLinkConfigured checks if a link alias is declared in the table's links config.
+// The alias may be the configured link name or the name of its underlying field.
+func (v *Validator) isLinkConfigured(table config.ResolvedTable, alias string) bool {
+	_, ok := configuredLink(table, alias)
+	return ok
+}
+
+// configuredLink returns the configured link a link alias names, matched like isLinkConfigured
+func configuredLink(table config.ResolvedTable, alias string) (config.ResolvedLink, bool) {
+	normalizedAlias := strings.ReplaceAll(alias, "_", " ")
+	for linkName, link := range table.Links {
+		if strings.EqualFold(linkName, alias) || strings.EqualFold(link.FieldID, alias) {
+			return link, true
+		}
+		if strings.EqualFold(strings.ReplaceAll(linkName, "_", " "), normalizedAlias) {
+			return link, true
 		}
 	}
-	return false
+	return config.ResolvedLink{}, false
 }
 
-// buildResolvedPath constructs the resolved path with table ID and resolves link field aliases
-// Path format: {tableID}/links/{linkAlias}/{recordId} -> {tableID}/links/{linkFieldID}/{recordId}
-func (v *Validator) buildResolvedPath(tableID, tableName string, remainingParts []string) (string, error) {
+// pinnedLinkField returns the field ID of a configured link whose ID is pinned in overrides
+func pinnedLinkField(table config.ResolvedTable, alias string) (string, bool) {
+	normalizedAlias := strings.ReplaceAll(alias, "_", " ")
+	for linkName, link := range table.Links {
+		if !link.Pinned {
+			continue
+		}
+		if strings.EqualFold(linkName, alias) || strings.EqualFold(strings.ReplaceAll(linkName, "_", " "), normalizedAlias) {
+			return link.FieldID, true
+		}
+	}
+	return "", false
+}
+
+// buildResolvedPath constructs the resolved path with table ID and resolves link field aliases.
+// Both links-first and records-first link paths are accepted and normalized to the configured API version:
+// {tableID}/links/{linkAlias}/{recordId} -> {tableID}/links/{linkFieldID}/{recordId}                 (v3)
+// {tableID}/records/{recordId}/links/{linkAlias} -> {tableID}/links/{linkFieldID}/records/{recordId} (v2)
+func (v *Validator) buildResolvedPath(table config.ResolvedTable, remainingParts []string) (string, error) {
+	tableID, tableName := table.TableID, table.Name
+	if v.recordIDPattern != nil {
+		if err := checkPathShape(remainingParts, v.recordIDPattern); err != nil {
+			return "", err
+		}
+	}
 	if len(remainingParts) == 0 {
 		return tableID, nil
 	}
 
-	// Check if this is a link request: /links/{linkAlias}/{recordId}
-	// Pattern: parts[0] = "links", parts[1] = linkAlias, parts[2] = recordId
-	if len(remainingParts) >= 3 && remainingParts[0] == "links" {
-		linkAlias := remainingParts[1]
-		log.Printf("[LINK RESOLVER] Detected link request for table '%s', alias '%s'", tableName, linkAlias)
+	link, isLink := parseLinkPath(remainingParts)
+	if !isLink {
+		return tableID + "/" + strings.Join(remainingParts, "/"), nil
+	}
 
-		// Try to resolve the link field alias to field ID using MetaCache
-		if v.metaCache != nil {
-			// Try direct match first
-			linkFieldID, ok := v.metaCache.ResolveLinkField(tableID, linkAlias)
-			if !ok {
-				// Try normalized version (replace spaces/underscores)
-				normalizedAlias := strings.ReplaceAll(linkAlias, "_", " ")
-				linkFieldID, ok = v.metaCache.ResolveLinkField(tableID, normalizedAlias)
-			}
+	linkAlias := link.Alias
+	logger.Debug("[LINK RESOLVER] Detected link request for table '%s', alias '%s'", tableName, linkAlias)
 
-			if ok {
-				log.Printf("[LINK RESOLVER] %s.%s → %s", tableName, linkAlias, linkFieldID)
-				// Replace the alias with the resolved field ID
-				remainingParts[1] = linkFieldID
-			} else {
-				// Link field not found in cache
-				return "", fmt.Errorf("unknown link field '%s' for table '%s'", linkAlias, tableName)
-			}
-		} else {
-			log.Printf("[LINK RESOLVER WARNING] MetaCache not available, using alias as-is")
+	// Try to resolve the link field alias to field ID using MetaCache; links pinned in overrides skip it
+	linkFieldID := linkAlias
+	if pinnedID, ok := pinnedLinkField(table, linkAlias); ok {
+		logger.Debug("[LINK RESOLVER] %s.%s → %s (override)", tableName, linkAlias, pinnedID)
+		linkFieldID = pinnedID
+	} else if v.metaCache != nil {
+		// Try direct match first
+		resolvedID, ok := v.metaCache.ResolveLinkField(tableID, linkAlias)
+		if !ok {
+			// Try normalized version (replace spaces/underscores)
+			normalizedAlias := strings.ReplaceAll(linkAlias, "_", " ")
+			resolvedID, ok = v.metaCache.ResolveLinkField(tableID, normalizedAlias)
 		}
+
+		if !ok {
+			// Link field not found in cache
+			return "", unknownLinkFieldError(linkAlias, tableName)
+		}
+
+		logger.Debug("[LINK RESOLVER] %s.%s → %s", tableName, linkAlias, resolvedID)
+		linkFieldID = resolvedID
+	} else {
+		log.Printf("[LINK RESOLVER WARNING] MetaCache not available, using alias as-is")
 	}
 
-	return tableID + "/" + strings.Join(remainingParts, "/"), nil
+	return tableID + "/" + link.build(linkFieldID, v.apiVersion), nil
 }
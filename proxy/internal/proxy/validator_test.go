@@ -0,0 +1,232 @@
+package proxy
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/httperr"
+)
+
+func TestParseLinkPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want *linkPath
+	}{
+		{"links/items/5", &linkPath{Alias: "items", RecordID: "5", Rest: []string{}}},
+		{"links/items/records/5", &linkPath{Alias: "items", RecordID: "5", Rest: []string{}}},
+		{"records/5/links/items", &linkPath{Alias: "items", RecordID: "5", Rest: []string{}}},
+		{"links/items/5/extra", &linkPath{Alias: "items", RecordID: "5", Rest: []string{"extra"}}},
+		{"records/5/links/items/extra", &linkPath{Alias: "items", RecordID: "5", Rest: []string{"extra"}}},
+		{"records", nil},
+		{"records/5", nil},
+		{"records/5/links", nil},
+		{"links/items", nil},
+		{"records/5/comments/items", nil},
+	}
+	for _, tt := range tests {
+		got, ok := parseLinkPath(strings.Split(tt.path, "/"))
+		if ok != (tt.want != nil) || (ok && !reflect.DeepEqual(got, tt.want)) {
+			t.Errorf("parseLinkPath(%q) = %+v, %v; want %+v", tt.path, got, ok, tt.want)
+		}
+	}
+}
+
+func TestLinkPathBuild(t *testing.T) {
+	link := &linkPath{Alias: "items", RecordID: "5", Rest: []string{"extra"}}
+	if got, want := link.build("c1", "v2"), "links/c1/records/5/extra"; got != want {
+		t.Errorf("v2: %q, want %q", got, want)
+	}
+	if got, want := link.build("c1", "v3"), "links/c1/5/extra"; got != want {
+		t.Errorf("v3: %q, want %q", got, want)
+	}
+}
+
+// linkedQuotes is quotes with the link "items", pinned to field c1 so no MetaCache is needed
+func linkedQuotes() *config.ResolvedConfig {
+	table := quotesTable()
+	table.Operations = append(table.Operations, "link", "unlink")
+	table.Links = map[string]config.ResolvedLink{"items": {FieldID: "c1", Title: "Items", Pinned: true}}
+	return &config.ResolvedConfig{BaseID: "base", Tables: map[string]config.ResolvedTable{"quotes": table}}
+}
+
+// Every link path shape resolves to the shape of the configured API version, alias in either position
+func TestValidatorResolvesLinkPathShapes(t *testing.T) {
+	shapes := []string{"quotes/links/items/5", "quotes/links/items/records/5", "quotes/records/5/links/items"}
+	want := map[string]string{"v2": "t1/links/c1/records/5", "v3": "t1/links/c1/5"}
+	for apiVersion, resolved := range want {
+		v := NewValidator(linkedQuotes(), nil, apiVersion)
+		for _, path := range shapes {
+			for method, operation := range map[string]string{http.MethodGet: "read", http.MethodPost: "link", http.MethodDelete: "unlink"} {
+				result, err := v.ValidateRequest(method, path, "user", nil)
+				if err != nil {
+					t.Errorf("%s %s %s: %v", apiVersion, method, path, err)
+					continue
+				}
+				if result.ResolvedPath != resolved || result.Operation != operation {
+					t.Errorf("%s %s %s: path %q, operation %q; want %q, %q", apiVersion, method, path, result.ResolvedPath, result.Operation, resolved, operation)
+				}
+			}
+		}
+	}
+}
+
+func TestValidatorLinkReadsNeedConfiguredLinks(t *testing.T) {
+	v := NewValidator(linkedQuotes(), nil, "v2")
+	for _, path := range []string{"quotes/links/notes/5", "quotes/records/5/links/notes"} {
+		_, err := v.ValidateRequest(http.MethodGet, path, "user", nil)
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) || validationErr.Code != httperr.LinkNotAllowed {
+			t.Errorf("GET %s: %v, want %s", path, err, httperr.LinkNotAllowed)
+		}
+	}
+	if _, err := v.ValidateRequest(http.MethodGet, "quotes/records/5", "user", nil); err != nil {
+		t.Errorf("plain record read: %v", err)
+	}
+}
+
+func TestValidatorReadLinksGatesLinkReads(t *testing.T) {
+	tests := []struct {
+		name           string
+		operations     []string
+		requireLinks   bool
+		wantRecordRead bool
+		wantLinkRead   bool
+	}{
+		{"read covers links by default", []string{"read"}, false, true, true},
+		{"read without read_links", []string{"read"}, true, true, false},
+		{"read_links without read", []string{"read_links"}, true, false, true},
+		{"both", []string{"read", "read_links"}, true, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolved := linkedQuotes()
+			table := resolved.Tables["quotes"]
+			table.Operations = tt.operations
+			table.RequireReadLinks = tt.requireLinks
+			resolved.Tables["quotes"] = table
+			v := NewValidator(resolved, nil, "v2")
+
+			for path, want := range map[string]bool{"quotes/records/5": tt.wantRecordRead, "quotes/records/5/links/items": tt.wantLinkRead} {
+				_, err := v.ValidateRequest(http.MethodGet, path, "user", nil)
+				if want {
+					if err != nil {
+						t.Errorf("GET %s: %v, want allowed", path, err)
+					}
+					continue
+				}
+				var validationErr *ValidationError
+				if !errors.As(err, &validationErr) || validationErr.Code != httperr.OperationNotAllowed {
+					t.Errorf("GET %s: %v, want %s", path, err, httperr.OperationNotAllowed)
+				}
+			}
+		})
+	}
+}
+
+func TestValidatorFieldProjection(t *testing.T) {
+	tests := []struct {
+		name       string
+		fields     []string // nil = no fields parameter
+		wantFields string
+		wantErr    string // field named in the error, "" = allowed
+	}{
+		{"absent", nil, "", ""},
+		{"aliases", []string{"title, author"}, "c_title,c_author", ""},
+		{"repeated parameter", []string{"title", "Id"}, "c_title,Id", ""},
+		{"unknown alias", []string{"title,Secret"}, "", "Secret"},
+		{"field ID instead of alias", []string{"c_title"}, "", "c_title"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolved := linkedQuotes()
+			table := resolved.Tables["quotes"]
+			table.Fields = map[string]string{"title": "c_title", "author": "c_author"}
+			resolved.Tables["quotes"] = table
+			v := NewValidator(resolved, nil, "v2")
+
+			query := url.Values{"where": {"(Status,eq,Open)"}}
+			if tt.fields != nil {
+				query["fields"] = tt.fields
+			}
+			_, err := v.ValidateRequest(http.MethodGet, "quotes/records", "user", query)
+			if tt.wantErr != "" {
+				var validationErr *ValidationError
+				if !errors.As(err, &validationErr) || validationErr.Code != httperr.FieldNotAllowed || validationErr.Params["field"] != tt.wantErr {
+					t.Fatalf("error = %v, want %s naming %q", err, httperr.FieldNotAllowed, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ValidateRequest: %v", err)
+			}
+			if got := query.Get("fields"); got != tt.wantFields {
+				t.Errorf("fields = %q, want %q", got, tt.wantFields)
+			}
+			if query.Get("where") != "(Status,eq,Open)" {
+				t.Errorf("where = %q, want it untouched", query.Get("where"))
+			}
+		})
+	}
+}
+
+// The rewritten projection is what NocoDB receives; a rejected one never reaches it
+func TestFieldProjectionReachesUpstream(t *testing.T) {
+	up := newFakeUpstream(t, jsonHandler(http.StatusOK, `{"list":[],"pageInfo":{"isLastPage":true}}`))
+	table := quotesTable()
+	table.Fields = map[string]string{"title": "c_title"}
+	p := newSchemaHandler(up, map[string]config.ResolvedTable{"quotes": table})
+
+	if rec := serve(p, http.MethodGet, "/proxy/quotes/records?fields=title", "", "7", "user"); rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	if got := up.Requests()[0].Query.Get("fields"); got != "c_title" {
+		t.Errorf("NocoDB got fields=%q, want the field ID", got)
+	}
+
+	rec := serve(p, http.MethodGet, "/proxy/quotes/records?fields=Secret", "", "7", "user")
+	if rec.Code != http.StatusForbidden || decodeError(t, rec.Body.Bytes()).Code != httperr.FieldNotAllowed {
+		t.Errorf("status = %d, body %s; want 403 %s", rec.Code, rec.Body, httperr.FieldNotAllowed)
+	}
+	if n := len(up.Requests()); n != 1 {
+		t.Errorf("NocoDB got %d requests, want the rejected one kept from it", n)
+	}
+}
+
+// Linking and unlinking need link, not create or delete, and link doesn't grant record creates
+func TestValidatorLinkOperations(t *testing.T) {
+	tests := []struct {
+		operations []string
+		method     string
+		path       string
+		wantAllow  bool
+	}{
+		{[]string{"link"}, http.MethodPost, "quotes/links/items/5", true},
+		{[]string{"link"}, http.MethodDelete, "quotes/links/items/5", true},
+		{[]string{"link"}, http.MethodPost, "quotes/records", false},
+		{[]string{"create", "delete"}, http.MethodPost, "quotes/links/items/5", false},
+		{[]string{"create", "delete"}, http.MethodDelete, "quotes/records/5/links/items", false},
+		{[]string{"create", "delete"}, http.MethodPost, "quotes/records", true},
+		{[]string{"create", "delete"}, http.MethodDelete, "quotes/records/5", true},
+	}
+	for _, tt := range tests {
+		config := linkedQuotes()
+		table := config.Tables["quotes"]
+		table.Operations = tt.operations
+		config.Tables["quotes"] = table
+		v := NewValidator(config, nil, "v2")
+		v.linkImpliesUnlink = true
+
+		_, err := v.ValidateRequest(tt.method, tt.path, "user", nil)
+		var validationErr *ValidationError
+		if tt.wantAllow && err != nil {
+			t.Errorf("%v: %s %s: %v, want allowed", tt.operations, tt.method, tt.path, err)
+		} else if !tt.wantAllow && (!errors.As(err, &validationErr) || validationErr.Code != httperr.OperationNotAllowed) {
+			t.Errorf("%v: %s %s: %v, want %s", tt.operations, tt.method, tt.path, err, httperr.OperationNotAllowed)
+		}
+	}
+}
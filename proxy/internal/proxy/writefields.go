@@ -0,0 +1,127 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"sort"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/httperr"
+)
+
+// translateWriteAliases renames field aliases in a record write body (object, array, or v3
+// "fields" wrappers) to NocoDB field titles. Titles NocoDB knows and the record id pass unchanged;
+// anything else is handled according to UNRESOLVED_FIELDS, like where/sort fields. The body is
+// returned untouched when nothing was renamed or it isn't JSON.
+func (p *ProxyHandler) translateWriteAliases(tableKey string, table config.ResolvedTable, body []byte) ([]byte, error) {
+	knownTitles := make(map[string]bool, len(table.FieldTitles))
+	for _, title := range table.FieldTitles {
+		knownTitles[title] = true
+	}
+	known := func(name string) bool {
+		if knownTitles[name] || name == "id" || name == "Id" {
+			return true
+		}
+		if p.Meta != nil {
+			if _, ok := p.Meta.ResolveField(table.TableID, name); ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	return rewriteWriteRecords(body, func(record map[string]interface{}) (bool, error) {
+		// Sorted so the alias of a field wins over its title deterministically when a body sends both
+		names := make([]string, 0, len(record))
+		for name := range record {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		renamed := false
+		for _, name := range names {
+			title, aliased := table.FieldTitles[name]
+			if !aliased {
+				if !known(name) {
+					if p.UnresolvedFields == UnresolvedFieldsStrict {
+						return false, newValidationError(httperr.UnknownField, "field '%s' does not exist in table '%s'", name, tableKey).
+							withParams(map[string]string{"field": name, "table": tableKey})
+					}
+					log.Printf("[PROXY] Unresolved write field '%s' in table '%s', passing through", name, tableKey)
+				}
+				continue
+			}
+			if title == name {
+				continue
+			}
+			record[title] = record[name]
+			delete(record, name)
+			renamed = true
+		}
+		return renamed, nil
+	})
+}
+
+// rewriteWriteRecords applies rewrite to every record of a write body (object, array, or v3 "fields"
+// wrappers) and re-encodes the body if any call reported a change. Bodies that aren't JSON are
+// returned untouched; the first error stops the rewrite.
+func rewriteWriteRecords(body []byte, rewrite func(record map[string]interface{}) (bool, error)) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber() // keep large numbers exact when re-encoding
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return body, nil
+	}
+
+	items, isList := doc.([]interface{})
+	if !isList {
+		items = []interface{}{doc}
+	}
+	changed := false
+	for _, item := range items {
+		record, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if fields, ok := record["fields"].(map[string]interface{}); ok {
+			record = fields
+		}
+		rewritten, err := rewrite(record)
+		if err != nil {
+			return nil, err
+		}
+		changed = changed || rewritten
+	}
+	if !changed {
+		return body, nil
+	}
+	return json.Marshal(doc)
+}
+
+// unwritableFields returns the sorted names of the fields a write body (object, array, or v3
+// "fields" wrappers) sets outside the writable titles, reported by alias where titles has one.
+// Every row of a bulk body is checked; the record id is always allowed so updates can address their rows.
+func unwritableFields(body []byte, writable map[string]bool, titles map[string]string) []string {
+	aliases := make(map[string]string, len(titles))
+	for alias, title := range titles {
+		aliases[title] = alias
+	}
+	found := make(map[string]bool)
+	for _, record := range decodeWriteRecords(body) {
+		for name := range record {
+			if writable[name] || name == "id" || name == "Id" {
+				continue
+			}
+			if alias, ok := aliases[name]; ok {
+				name = alias
+			}
+			found[name] = true
+		}
+	}
+	names := make([]string, 0, len(found))
+	for name := range found {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,83 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/httperr"
+)
+
+// echoUpstream answers every request with the body it was sent
+func echoUpstream(t *testing.T) *fakeUpstream {
+	return newFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.Copy(w, r.Body)
+	})
+}
+
+// aliasedQuotes serves quotes with the aliases customer_name and title
+func aliasedQuotes(up *fakeUpstream, mode string) *ProxyHandler {
+	table := quotesTable()
+	table.Fields = map[string]string{"customer_name": "c_customer", "title": "c_title"}
+	table.FieldTitles = map[string]string{"customer_name": "Customer Name", "title": "Title"}
+	return newSchemaHandler(up, map[string]config.ResolvedTable{"quotes": table}, func(p *ProxyHandler) { p.UnresolvedFields = mode })
+}
+
+func TestWriteBodyAliasesTranslated(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		want   interface{}
+	}{
+		{"single create", http.MethodPost, "/proxy/quotes/records", `{"customer_name":"Acme","title":"Hi"}`,
+			map[string]interface{}{"Customer Name": "Acme", "Title": "Hi"}},
+		{"bulk create", http.MethodPost, "/proxy/quotes/records", `[{"customer_name":"Acme"},{"title":"Hi","Id":3}]`,
+			[]interface{}{map[string]interface{}{"Customer Name": "Acme"}, map[string]interface{}{"Title": "Hi", "Id": json.Number("3")}}},
+		{"update", http.MethodPatch, "/proxy/quotes/records", `{"Id":5,"customer_name":"Acme"}`,
+			map[string]interface{}{"Id": json.Number("5"), "Customer Name": "Acme"}},
+		{"v3 fields wrapper", http.MethodPost, "/proxy/quotes/records", `[{"fields":{"title":"Hi"}}]`,
+			[]interface{}{map[string]interface{}{"fields": map[string]interface{}{"Title": "Hi"}}}},
+		{"title and unknown key kept", http.MethodPost, "/proxy/quotes/records", `{"Title":"Hi","Extra":1}`,
+			map[string]interface{}{"Title": "Hi", "Extra": json.Number("1")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			up := echoUpstream(t)
+			rec := serve(aliasedQuotes(up, UnresolvedFieldsLenient), tt.method, tt.target, tt.body, "7", "user")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+			}
+			sent := up.Requests()[0]
+			if length := sent.Header.Get("Content-Length"); length != strconv.Itoa(len(sent.Body)) {
+				t.Errorf("Content-Length = %q, body is %d bytes", length, len(sent.Body))
+			}
+			var got interface{}
+			decoder := json.NewDecoder(rec.Body)
+			decoder.UseNumber()
+			if err := decoder.Decode(&got); err != nil {
+				t.Fatalf("echoed body: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NocoDB got %s, want %v", sent.Body, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteBodyUnknownFieldStrict(t *testing.T) {
+	up := echoUpstream(t)
+	rec := serve(aliasedQuotes(up, UnresolvedFieldsStrict), http.MethodPost, "/proxy/quotes/records", `[{"title":"a"},{"Nope":1}]`, "7", "user")
+	if rec.Code != http.StatusBadRequest || decodeError(t, rec.Body.Bytes()).Code != httperr.UnknownField {
+		t.Errorf("status = %d, body %s; want 400 %s", rec.Code, rec.Body, httperr.UnknownField)
+	}
+	if n := len(up.Requests()); n != 0 {
+		t.Errorf("NocoDB got %d requests, want none", n)
+	}
+}
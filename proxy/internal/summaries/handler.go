@@ -0,0 +1,129 @@
+package summaries
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/db"
+	"github.com/grove/generic-proxy/internal/middleware"
+)
+
+// SummaryValue is the public view of a stored summary
+type SummaryValue struct {
+	Value      *float64 `json:"value"`
+	ComputedAt string   `json:"computed_at,omitempty"`
+	Stale      bool     `json:"stale"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// ServeHTTP handles GET /proxy/_summaries and GET /proxy/_summaries/{name}/refresh (admin only)
+func (s *Scheduler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	role, _ := r.Context().Value(middleware.RoleKey).(string)
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/proxy/_summaries"), "/")
+
+	switch {
+	case path == "":
+		s.serveList(r.Context(), w, role)
+	case strings.HasSuffix(path, "/refresh") && strings.Count(path, "/") == 1:
+		s.serveRefresh(r.Context(), w, role, strings.TrimSuffix(path, "/refresh"))
+	default:
+		respondWithError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// serveList returns the latest value of every summary the caller's role may read
+func (s *Scheduler) serveList(ctx context.Context, w http.ResponseWriter, role string) {
+	stored, err := s.database.WithContext(ctx).GetSummaries()
+	if err != nil {
+		log.Printf("[SUMMARIES ERROR] Failed to load summaries: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "failed to load summaries")
+		return
+	}
+
+	values := make(map[string]SummaryValue)
+	for name := range s.summaries {
+		if !s.canRead(name, role) {
+			continue
+		}
+		values[name] = toSummaryValue(stored[name])
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "private, max-age=30")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"summaries": values}); err != nil {
+		log.Printf("[SUMMARIES ERROR] Failed to encode summaries response: %v", err)
+	}
+}
+
+// serveRefresh forces a recompute of one summary
+func (s *Scheduler) serveRefresh(ctx context.Context, w http.ResponseWriter, role, name string) {
+	if role != "admin" {
+		respondWithError(w, http.StatusForbidden, "admin role required")
+		return
+	}
+	if _, ok := s.summaries[name]; !ok {
+		respondWithError(w, http.StatusNotFound, "unknown summary '"+name+"'")
+		return
+	}
+
+	log.Printf("[SUMMARIES] Forced refresh of '%s'", name)
+	computeErr := s.Compute(name)
+
+	stored, err := s.database.WithContext(ctx).GetSummaries()
+	if err != nil {
+		log.Printf("[SUMMARIES ERROR] Failed to load summaries: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "failed to load summaries")
+		return
+	}
+
+	status := http.StatusOK
+	if computeErr != nil {
+		status = http.StatusBadGateway
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{name: toSummaryValue(stored[name])}); err != nil {
+		log.Printf("[SUMMARIES ERROR] Failed to encode refresh response: %v", err)
+	}
+}
+
+// canRead checks the summary's role restriction; admins can read everything
+func (s *Scheduler) canRead(name, role string) bool {
+	roles := s.summaries[name].Roles
+	if len(roles) == 0 || role == "admin" {
+		return true
+	}
+	for _, allowed := range roles {
+		if allowed == role {
+			return true
+		}
+	}
+	return false
+}
+
+// toSummaryValue converts a stored summary; summaries that never computed report a null value
+func toSummaryValue(stored *db.Summary) SummaryValue {
+	if stored == nil {
+		return SummaryValue{Stale: true}
+	}
+	value := SummaryValue{Value: stored.Value, Stale: stored.Stale, Error: stored.LastError}
+	if stored.ComputedAt != nil {
+		value.ComputedAt = stored.ComputedAt.UTC().Format(time.RFC3339)
+	}
+	return value
+}
+
+func respondWithError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
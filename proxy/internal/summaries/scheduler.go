@@ -0,0 +1,161 @@
+package summaries
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/db"
+)
+
+// RecordFetcher reads all records of a configured table through the validated read path
+type RecordFetcher interface {
+	FetchRecords(tableKey, where string) ([]map[string]interface{}, error)
+}
+
+// Scheduler recomputes configured summaries on their intervals and stores them in SQLite,
+// so dashboards read a single precomputed value instead of aggregating on every visit
+type Scheduler struct {
+	fetcher   RecordFetcher
+	database  *db.Database
+	summaries map[string]config.SummaryConfig
+
+	// computing serializes computations per summary (scheduled run vs. forced refresh)
+	mu        sync.Mutex
+	computing map[string]*sync.Mutex
+}
+
+// NewScheduler creates a scheduler for the summaries defined in proxy.yaml
+func NewScheduler(fetcher RecordFetcher, database *db.Database, summaries map[string]config.SummaryConfig) *Scheduler {
+	return &Scheduler{
+		fetcher:   fetcher,
+		database:  database,
+		summaries: summaries,
+		computing: make(map[string]*sync.Mutex),
+	}
+}
+
+// Start computes every summary once and then on its configured interval
+func (s *Scheduler) Start() {
+	for name, summary := range s.summaries {
+		go func(name string, interval time.Duration) {
+			log.Printf("[SUMMARIES] Scheduling '%s' every %v", name, interval)
+			s.Compute(name)
+
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for range ticker.C {
+				s.Compute(name)
+			}
+		}(name, summary.Interval.Duration())
+	}
+}
+
+// Compute recomputes one summary. On failure the last good value is kept and flagged stale.
+func (s *Scheduler) Compute(name string) error {
+	summary, ok := s.summaries[name]
+	if !ok {
+		return fmt.Errorf("unknown summary '%s'", name)
+	}
+
+	lock := s.lockFor(name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	start := time.Now()
+	records, err := s.fetcher.FetchRecords(summary.Table, summary.Filter)
+	var value float64
+	if err == nil {
+		value, err = aggregate(records, summary.Aggregation, summary.Field)
+	}
+	if err != nil {
+		log.Printf("[SUMMARIES ERROR] Failed to compute '%s': %v", name, err)
+		if storeErr := s.database.MarkSummaryStale(name, err.Error()); storeErr != nil {
+			return storeErr
+		}
+		return err
+	}
+
+	if err := s.database.SaveSummary(name, value, time.Now()); err != nil {
+		return err
+	}
+	log.Printf("[SUMMARIES] Computed '%s' = %v from %d records in %v", name, value, len(records), time.Since(start))
+	return nil
+}
+
+// lockFor returns the per-summary computation lock
+func (s *Scheduler) lockFor(name string) *sync.Mutex {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lock, ok := s.computing[name]
+	if !ok {
+		lock = &sync.Mutex{}
+		s.computing[name] = lock
+	}
+	return lock
+}
+
+// aggregate reduces records to a single value; empty and non-numeric field values are skipped
+func aggregate(records []map[string]interface{}, aggregation, field string) (float64, error) {
+	if aggregation == "count" {
+		return float64(len(records)), nil
+	}
+
+	var values []float64
+	for _, record := range records {
+		raw, ok := record[field]
+		if !ok || raw == nil {
+			continue
+		}
+		if n, ok := toFloat(raw); ok {
+			values = append(values, n)
+		}
+	}
+	if len(values) == 0 {
+		return 0, nil
+	}
+
+	switch aggregation {
+	case "sum", "avg":
+		total := 0.0
+		for _, v := range values {
+			total += v
+		}
+		if aggregation == "avg" {
+			return total / float64(len(values)), nil
+		}
+		return total, nil
+	case "min":
+		result := math.Inf(1)
+		for _, v := range values {
+			result = math.Min(result, v)
+		}
+		return result, nil
+	case "max":
+		result := math.Inf(-1)
+		for _, v := range values {
+			result = math.Max(result, v)
+		}
+		return result, nil
+	default:
+		return 0, fmt.Errorf("unsupported aggregation '%s'", aggregation)
+	}
+}
+
+// toFloat converts JSON numbers and numeric strings (NocoDB returns Decimal/Currency as either)
+func toFloat(raw interface{}) (float64, bool) {
+	switch v := raw.(type) {
+	case float64:
+		return v, true
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return n, err == nil
+	default:
+		return 0, false
+	}
+}
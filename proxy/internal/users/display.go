@@ -0,0 +1,238 @@
+package users
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/db"
+)
+
+// maxDisplayIDs caps how many users can be resolved in one request
+const maxDisplayIDs = 200
+
+// deletedUserLabel is shown for IDs that don't (or no longer) exist
+const deletedUserLabel = "Deleted user"
+
+// DisplayUser is the public-safe view of a user: no email, role or provider
+type DisplayUser struct {
+	Name      string `json:"name"`
+	AvatarURL string `json:"avatar_url,omitempty"`
+	Deleted   bool   `json:"deleted,omitempty"`
+}
+
+type displayEntry struct {
+	user      DisplayUser
+	expiresAt time.Time
+}
+
+// IdentifiedUser is a DisplayUser together with its user ID, for callers that must link back to the account
+type IdentifiedUser struct {
+	ID int64
+	DisplayUser
+}
+
+// emailEntry caches an email lookup; found is false for emails without an account
+type emailEntry struct {
+	user      IdentifiedUser
+	found     bool
+	expiresAt time.Time
+}
+
+// DefaultDisplayCacheSize caps each of the resolver's caches when USER_DISPLAY_CACHE_SIZE is unset
+const DefaultDisplayCacheSize = 10000
+
+// DisplayResolver resolves user IDs to display names with a small in-process TTL cache,
+// so rendering a page full of user references doesn't turn into one SQLite query per row.
+// Each cache holds at most size entries and drops the least recently used one beyond that.
+type DisplayResolver struct {
+	database *db.Database
+	ttl      time.Duration
+
+	mu      sync.Mutex
+	cache   *lruCache[int64, displayEntry]
+	byEmail *lruCache[string, emailEntry] // lower-cased email
+}
+
+// NewDisplayResolver creates a resolver backed by the given database; size <= 0 uses DefaultDisplayCacheSize
+func NewDisplayResolver(database *db.Database, ttl time.Duration, size int) *DisplayResolver {
+	if size <= 0 {
+		size = DefaultDisplayCacheSize
+	}
+	return &DisplayResolver{
+		database: database,
+		ttl:      ttl,
+		cache:    newLRUCache[int64, displayEntry](size),
+		byEmail:  newLRUCache[string, emailEntry](size),
+	}
+}
+
+// Resolve returns display info for every requested ID; unknown IDs resolve to a tombstone
+func (d *DisplayResolver) Resolve(ctx context.Context, ids []int64) (map[int64]DisplayUser, error) {
+	result := make(map[int64]DisplayUser, len(ids))
+	var missing []int64
+
+	now := time.Now()
+	d.mu.Lock()
+	for _, id := range ids {
+		if entry, ok := d.cache.get(id); ok && now.Before(entry.expiresAt) {
+			result[id] = entry.user
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	d.mu.Unlock()
+
+	if len(missing) == 0 {
+		return result, nil
+	}
+
+	log.Printf("[USERS] Display cache miss for %d of %d user(s)", len(missing), len(ids))
+	found, err := d.database.WithContext(ctx).GetUsersByIDs(missing)
+	if err != nil {
+		return nil, err
+	}
+
+	fetched := make(map[int64]DisplayUser, len(missing))
+	for _, user := range found {
+		fetched[user.ID] = DisplayUser{Name: user.Name, AvatarURL: user.AvatarURL}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, id := range missing {
+		user, ok := fetched[id]
+		if !ok {
+			user = DisplayUser{Name: deletedUserLabel, Deleted: true}
+		}
+		result[id] = user
+		d.cache.put(id, displayEntry{user: user, expiresAt: now.Add(d.ttl)})
+	}
+
+	return result, nil
+}
+
+// ResolveEmails looks up users by email (case-insensitive) through the same TTL cache. The result is
+// keyed by lower-cased email; emails without an account are absent. Not exposed over HTTP: emails
+// are never disclosed by this package, callers only use them to recognize users they already know.
+func (d *DisplayResolver) ResolveEmails(ctx context.Context, emails []string) (map[string]IdentifiedUser, error) {
+	result := make(map[string]IdentifiedUser, len(emails))
+	var missing []string
+
+	now := time.Now()
+	d.mu.Lock()
+	for _, email := range emails {
+		email = strings.ToLower(email)
+		if entry, ok := d.byEmail.get(email); ok && now.Before(entry.expiresAt) {
+			if entry.found {
+				result[email] = entry.user
+			}
+		} else {
+			missing = append(missing, email)
+		}
+	}
+	d.mu.Unlock()
+
+	if len(missing) == 0 {
+		return result, nil
+	}
+
+	log.Printf("[USERS] Email cache miss for %d of %d address(es)", len(missing), len(emails))
+	found, err := d.database.WithContext(ctx).GetUsersByEmails(missing)
+	if err != nil {
+		return nil, err
+	}
+
+	fetched := make(map[string]IdentifiedUser, len(found))
+	for _, user := range found {
+		fetched[strings.ToLower(user.Email)] = IdentifiedUser{
+			ID:          user.ID,
+			DisplayUser: DisplayUser{Name: user.Name, AvatarURL: user.AvatarURL},
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, email := range missing {
+		user, ok := fetched[email]
+		if ok {
+			result[email] = user
+		}
+		d.byEmail.put(email, emailEntry{user: user, found: ok, expiresAt: now.Add(d.ttl)})
+	}
+
+	return result, nil
+}
+
+// ServeHTTP handles GET /api/users/display?ids=1,2,3
+func (d *DisplayResolver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ids, err := parseIDList(r.URL.Query().Get("ids"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(ids) > maxDisplayIDs {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("too many ids (max %d)", maxDisplayIDs))
+		return
+	}
+
+	resolved, err := d.Resolve(r.Context(), ids)
+	if err != nil {
+		log.Printf("[USERS ERROR] Failed to resolve display names: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "failed to resolve users")
+		return
+	}
+
+	users := make(map[string]DisplayUser, len(resolved))
+	for id, user := range resolved {
+		users[strconv.FormatInt(id, 10)] = user
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "private, max-age=60")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"users": users}); err != nil {
+		log.Printf("[USERS ERROR] Failed to encode display response: %v", err)
+	}
+}
+
+// parseIDList parses a comma-separated list of user IDs, dropping duplicates
+func parseIDList(raw string) ([]int64, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, fmt.Errorf("ids parameter is required")
+	}
+
+	seen := make(map[int64]bool)
+	var ids []int64
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(part, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("invalid user id '%s'", part)
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func respondWithError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
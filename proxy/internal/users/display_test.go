@@ -0,0 +1,107 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/db"
+)
+
+func TestLRUCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newLRUCache[int, string](2)
+	c.put(1, "a")
+	c.put(2, "b")
+	c.get(1) // 2 is now the least recently used
+	c.put(3, "c")
+
+	if _, ok := c.get(2); ok {
+		t.Error("least recently used entry survived")
+	}
+	for _, key := range []int{1, 3} {
+		if _, ok := c.get(key); !ok {
+			t.Errorf("entry %d was evicted", key)
+		}
+	}
+	c.put(3, "c2")
+	if value, _ := c.get(3); value != "c2" || c.len() != 2 {
+		t.Errorf("overwrite: value %q, len %d", value, c.len())
+	}
+}
+
+func newTestResolver(t *testing.T, size, users int) (*DisplayResolver, *db.Database, []int64) {
+	t.Helper()
+	database, err := db.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	var ids []int64
+	for i := 0; i < users; i++ {
+		user, err := database.CreateUser(fmt.Sprintf("user%d@example.com", i), "google", fmt.Sprintf("User %d", i), "")
+		if err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+		ids = append(ids, user.ID)
+	}
+	return NewDisplayResolver(database, time.Hour, size), database, ids
+}
+
+func TestDisplayCacheIsBounded(t *testing.T) {
+	resolver, database, ids := newTestResolver(t, 3, 5)
+	ctx := context.Background()
+
+	if _, err := resolver.Resolve(ctx, ids); err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if got := resolver.cache.len(); got != 3 {
+		t.Errorf("cache holds %d entries, want the cap of 3", got)
+	}
+
+	// The last resolved IDs are cached: a rename doesn't show until the entry expires or is evicted
+	last := ids[len(ids)-1]
+	if err := database.UpdateUser(last, "Renamed", ""); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	resolved, err := resolver.Resolve(ctx, []int64{last})
+	if err != nil || resolved[last].Name != fmt.Sprintf("User %d", len(ids)-1) {
+		t.Errorf("cached entry = %+v (err %v), want the cached name", resolved[last], err)
+	}
+
+	// The first ID was evicted and reads the database again
+	first := ids[0]
+	if err := database.UpdateUser(first, "Renamed", ""); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	resolved, err = resolver.Resolve(ctx, []int64{first})
+	if err != nil || resolved[first].Name != "Renamed" {
+		t.Errorf("evicted entry = %+v (err %v), want a fresh read", resolved[first], err)
+	}
+}
+
+func TestResolveEmailsCacheIsBounded(t *testing.T) {
+	resolver, _, _ := newTestResolver(t, 2, 3)
+	if _, err := resolver.ResolveEmails(context.Background(), []string{"user0@example.com", "USER1@example.com", "user2@example.com", "nobody@example.com"}); err != nil {
+		t.Fatalf("ResolveEmails: %v", err)
+	}
+	if got := resolver.byEmail.len(); got != 2 {
+		t.Errorf("email cache holds %d entries, want the cap of 2", got)
+	}
+}
+
+func TestDisplayTombstoneForUnknownIDs(t *testing.T) {
+	resolver, _, ids := newTestResolver(t, 0, 1)
+	resolved, err := resolver.Resolve(context.Background(), []int64{ids[0], 9999})
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if !resolved[9999].Deleted || resolved[9999].Name != deletedUserLabel {
+		t.Errorf("unknown ID = %+v, want the tombstone", resolved[9999])
+	}
+	if resolved[ids[0]].Deleted {
+		t.Errorf("known ID resolved as deleted")
+	}
+}
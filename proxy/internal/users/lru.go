@@ -0,0 +1,51 @@
+package users
+
+import "container/list"
+
+// lruCache is a size-capped map that evicts the least recently used entry when full. It is not
+// safe for concurrent use; DisplayResolver guards it with its mutex.
+type lruCache[K comparable, V any] struct {
+	max     int // 0 = unbounded
+	order   *list.List
+	entries map[K]*list.Element
+}
+
+type lruItem[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+func newLRUCache[K comparable, V any](max int) *lruCache[K, V] {
+	return &lruCache[K, V]{max: max, order: list.New(), entries: make(map[K]*list.Element)}
+}
+
+// get returns the value for key and marks it as recently used
+func (c *lruCache[K, V]) get(key K) (V, bool) {
+	element, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*lruItem[K, V]).value, true
+}
+
+// put stores value under key, evicting the least recently used entry beyond the cap
+func (c *lruCache[K, V]) put(key K, value V) {
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*lruItem[K, V]).value = value
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&lruItem[K, V]{key: key, value: value})
+	if c.max > 0 && c.order.Len() > c.max {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruItem[K, V]).key)
+	}
+}
+
+// len returns the number of cached entries
+func (c *lruCache[K, V]) len() int {
+	return c.order.Len()
+}
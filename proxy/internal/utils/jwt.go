@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"time"
 
@@ -13,14 +15,36 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
+// DefaultTokenTTL is how long a token is valid when TokenOptions.TTL is not set
+const DefaultTokenTTL = 24 * time.Hour
+
+// TokenOptions are the optional settings of a generated token
+type TokenOptions struct {
+	TTL time.Duration // lifetime, 0 = DefaultTokenTTL
+}
+
+// Expiry returns when a token generated now with these options expires
+func (o TokenOptions) Expiry(now time.Time) time.Time {
+	if o.TTL <= 0 {
+		return now.Add(DefaultTokenTTL)
+	}
+	return now.Add(o.TTL)
+}
+
 // GenerateJWT creates a new JWT token with user claims
-func GenerateJWT(userID, role, secret string) (string, error) {
+func GenerateJWT(userID, role, secret string, opts TokenOptions) (string, error) {
+	jti, err := NewTokenID()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
 	claims := Claims{
 		UserID: userID,
 		Role:   role,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(opts.Expiry(now)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        jti,
 		},
 	}
 
@@ -28,6 +52,15 @@ func GenerateJWT(userID, role, secret string) (string, error) {
 	return token.SignedString([]byte(secret))
 }
 
+// NewTokenID returns a random jti, the key a token is revoked by
+func NewTokenID() (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
+
 // ValidateJWT validates and parses a JWT token
 func ValidateJWT(tokenString, secret string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
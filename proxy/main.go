@@ -1,22 +1,31 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
-	"github.com/gorilla/sessions"
+	"github.com/grove/generic-proxy/internal/adminui"
 	"github.com/grove/generic-proxy/internal/auth"
+	"github.com/grove/generic-proxy/internal/comments"
 	"github.com/grove/generic-proxy/internal/config"
 	"github.com/grove/generic-proxy/internal/db"
+	"github.com/grove/generic-proxy/internal/httperr"
+	"github.com/grove/generic-proxy/internal/i18n"
 	"github.com/grove/generic-proxy/internal/introspect"
 	"github.com/grove/generic-proxy/internal/logger"
+	"github.com/grove/generic-proxy/internal/metrics"
 	"github.com/grove/generic-proxy/internal/middleware"
 	"github.com/grove/generic-proxy/internal/proxy"
-	"github.com/grove/generic-proxy/internal/utils"
+	"github.com/grove/generic-proxy/internal/summaries"
+	"github.com/grove/generic-proxy/internal/users"
 	"github.com/markbates/goth/gothic"
 )
 
@@ -26,9 +35,11 @@ type LoginRequest struct {
 }
 
 type LoginResponse struct {
-	Token  string `json:"token"`
-	UserID string `json:"user_id"`
-	Role   string `json:"role"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn    int    `json:"expires_in,omitempty"` // access token lifetime in seconds
+	UserID       string `json:"user_id"`
+	Role         string `json:"role"`
 }
 
 // Demo users for testing
@@ -58,8 +69,28 @@ func main() {
 		logDir = "./logs"
 	}
 
-	// Initialize the logger package
-	if err := logger.Initialize(logDir); err != nil {
+	// LOG_LEVEL drops messages below it; per-request tracing is only written with debug
+	logLevel, err := logger.ParseLevel(os.Getenv("LOG_LEVEL"))
+	if err != nil {
+		log.Fatalf("[STARTUP FATAL] %v", err)
+	}
+	logger.SetLevel(logLevel)
+
+	// LOG_MAX_SIZE rolls the day's log file over once it is full, LOG_MAX_BACKUPS caps the rolled files
+	logOptions := logger.Options{Format: os.Getenv("LOG_FORMAT")}
+	if maxSize := os.Getenv("LOG_MAX_SIZE"); maxSize != "" {
+		if logOptions.MaxLogSizeBytes, err = config.ParseByteSize(maxSize); err != nil {
+			log.Fatalf("[STARTUP FATAL] LOG_MAX_SIZE: %v", err)
+		}
+	}
+	if maxBackups := os.Getenv("LOG_MAX_BACKUPS"); maxBackups != "" {
+		if logOptions.MaxBackups, err = strconv.Atoi(maxBackups); err != nil {
+			log.Fatalf("[STARTUP FATAL] LOG_MAX_BACKUPS: %v", err)
+		}
+	}
+
+	// Initialize the logger package; LOG_FORMAT=json writes one JSON object per line
+	if err := logger.InitializeWithOptions(logDir, logOptions); err != nil {
 		log.Fatalf("[STARTUP FATAL] Failed to initialize logger: %v", err)
 	}
 	defer logger.Close()
@@ -75,6 +106,7 @@ func main() {
 
 	// Load proxy configuration (optional - for config-driven mode)
 	var proxyConfig *config.ProxyConfig
+	var proxyConfigSource []byte
 	var resolvedConfig *config.ResolvedConfig
 	proxyConfigPath := os.Getenv("PROXY_CONFIG_PATH")
 	if proxyConfigPath == "" {
@@ -82,7 +114,10 @@ func main() {
 	}
 	if _, err := os.Stat(proxyConfigPath); err == nil {
 		log.Printf("[STARTUP] Loading proxy configuration from: %s", proxyConfigPath)
-		proxyConfig, err = config.LoadProxyConfig(proxyConfigPath)
+		proxyConfigSource, err = os.ReadFile(proxyConfigPath)
+		if err == nil {
+			proxyConfig, err = config.ParseProxyConfig(proxyConfigSource)
+		}
 		if err != nil {
 			log.Printf("[STARTUP WARN] Failed to load proxy config: %v", err)
 			log.Printf("[STARTUP] Continuing in legacy mode without config-driven schema")
@@ -97,24 +132,29 @@ func main() {
 	log.Printf("  - NocoDB Base ID: %s", cfg.NocoDBBaseID)
 	log.Printf("  - JWT Secret: %s", cfg.MaskSecret(cfg.JWTSecret))
 	log.Printf("  - Database Path: %s", cfg.DatabasePath)
+	log.Printf("  - Database Encryption: %v (supported by this build: %v)", cfg.DatabaseKey != "", db.EncryptionSupported())
+	log.Printf("  - OAuth Session Max Age: %v", cfg.SessionMaxAge)
+	log.Printf("  - Max Response Records: %d (0 = unlimited)", cfg.MaxResponseRecords)
+	log.Printf("  - Max Pagination Fan-out: %d (0 = unlimited), at most %dx the average (0 = no ratio cap)", cfg.MaxPaginationFanout, cfg.MaxPaginationFanoutRatio)
+	log.Printf("  - Upstream Timeout: %v (0 = none)", cfg.UpstreamTimeout)
+	log.Printf("  - Upstream Idle Connections: %d (idle timeout %v)", cfg.UpstreamMaxIdleConns, cfg.UpstreamIdleConnTimeout)
+	log.Printf("  - Sort Verify Max Records: %d (0 = unlimited)", cfg.SortVerifyMaxRecords)
+	log.Printf("  - Max Body Bytes: %d (0 = unlimited)", cfg.MaxBodyBytes)
 
 	// Initialize SQLite database for user storage
-	database, err := db.NewDatabase(cfg.DatabasePath)
+	database, err := db.NewDatabaseWithKey(cfg.DatabasePath, cfg.DatabaseKey)
 	if err != nil {
 		log.Fatalf("[STARTUP ERROR] Failed to initialize database: %v", err)
 	}
 	defer database.Close()
+	database.MaxNameLength = cfg.UserNameMaxLength
+	database.SetQueryTimeout(cfg.DatabaseQueryTimeout)
 
 	// Initialize Goth OAuth providers
 	initializeGothProviders(cfg)
 
 	// Setup gothic session store
-	store := sessions.NewCookieStore([]byte(cfg.SessionSecret))
-	store.MaxAge(86400 * 30) // 30 days
-	store.Options.Path = "/"
-	store.Options.HttpOnly = true
-	store.Options.Secure = false // Set to true in production with HTTPS
-	gothic.Store = store
+	gothic.Store = newOAuthSessionStore(cfg.SessionSecret, cfg.SessionMaxAge)
 
 	// Ensure NocoDB URL ends with /
 	nocoDBURL := cfg.NocoDBURL
@@ -122,24 +162,48 @@ func main() {
 		nocoDBURL += "/"
 	}
 
+	// One client and connection pool for every NocoDB call: metadata, proxied requests and background fetches
+	upstreamClient := proxy.NewUpstreamClient(proxy.UpstreamClientOptions{
+		Timeout:             cfg.UpstreamTimeout,
+		MaxIdleConns:        cfg.UpstreamMaxIdleConns,
+		MaxIdleConnsPerHost: cfg.UpstreamMaxIdleConnsPerHost,
+		IdleConnTimeout:     cfg.UpstreamIdleConnTimeout,
+	})
+
 	// Initialize MetaCache for table name resolution
 	var metaCache *proxy.MetaCache
 	if cfg.NocoDBBaseID != "" {
 		metaBaseURL := deriveMetaBaseURL(nocoDBURL)
 		log.Printf("[STARTUP] Meta Base URL: %s", metaBaseURL)
 
-		metaCache = proxy.NewMetaCache(metaBaseURL, cfg.NocoDBBaseID, cfg.NocoDBToken)
+		metaCache = proxy.NewMetaCache(metaBaseURL, cfg.NocoDBBaseID, cfg.NocoDBToken, upstreamClient)
+
+		// proxy.yaml takes precedence over META_REFRESH_INTERVAL
+		refreshInterval := cfg.MetaRefreshInterval
+		if proxyConfig != nil && proxyConfig.NocoDB.MetaRefreshInterval != 0 {
+			refreshInterval = proxyConfig.NocoDB.MetaRefreshInterval.Duration()
+		}
+		metaCache.SetRefreshInterval(refreshInterval)
+		metaCache.SetMinTables(cfg.MetaMinTables)
+		metaCache.SetFetchLimits(cfg.MetaFetchConcurrency, float64(cfg.MetaFetchRate), cfg.MetaFetchRetries)
 
 		// Perform initial synchronous metadata load
+		metaLoaded := true
 		if err := metaCache.LoadInitial(); err != nil {
-			log.Fatalf("[STARTUP FATAL] MetaCache initial load failed: %v", err)
+			if !proxy.IsPermissionError(err) || !cfg.MetaLegacyFallback {
+				log.Fatalf("[STARTUP FATAL] MetaCache initial load failed: %v", err)
+			}
+			metaErr := metaCache.LastError()
+			log.Printf("[STARTUP ERROR] ❌ MetaCache: %s (status %d from %s)", metaErr.Message, metaErr.StatusCode, metaErr.Endpoint)
+			log.Printf("[STARTUP] META_LEGACY_FALLBACK enabled: continuing in legacy pass-through mode, metadata load will be retried")
+			metaLoaded = false
 		}
 
 		// Start background auto-refresh
 		metaCache.StartAutoRefresh()
 
 		// If we have a proxy config, resolve it using MetaCache (only after MetaCache is ready)
-		if proxyConfig != nil {
+		if proxyConfig != nil && metaLoaded {
 			log.Printf("[STARTUP] Resolving proxy configuration using loaded MetaCache...")
 			resolver := config.NewResolver(metaCache)
 			resolvedConfig, err = resolver.Resolve(proxyConfig)
@@ -157,8 +221,67 @@ func main() {
 		log.Println("[STARTUP WARN] NOCODB_BASE_ID not set - MetaCache disabled")
 	}
 
+	// Feature flags: proxy.yaml's features override FEATURES
+	features := cfg.Features
+	if proxyConfig != nil {
+		features = features.Merge(proxyConfig.Features)
+	}
+	log.Printf("[STARTUP] Features: %v", features.All())
+
 	// Create proxy handler
-	proxyHandler := proxy.NewProxyHandler(nocoDBURL, cfg.NocoDBToken, metaCache)
+	proxyHandler := proxy.NewProxyHandler(nocoDBURL, cfg.NocoDBToken, metaCache, upstreamClient)
+	proxyHandler.MaxResponseRecords = cfg.MaxResponseRecords
+	proxyHandler.ResponseAliasDepth = cfg.ResponseAliasDepth
+	proxyHandler.MaxPaginationFanout = cfg.MaxPaginationFanout
+	proxyHandler.MaxPaginationFanoutRatio = cfg.MaxPaginationFanoutRatio
+	proxyHandler.MaxPaginationRecords = cfg.MaxPaginationRecords
+	proxyHandler.PaginationTimeout = cfg.PaginationTimeout
+	proxyHandler.PaginationWorkers = cfg.PaginationWorkers
+	proxyHandler.PaginationOnCancel = cfg.PaginationOnCancel
+	proxyHandler.UpstreamMaxAttempts = cfg.UpstreamMaxAttempts
+	proxyHandler.UpstreamCallBudget = cfg.UpstreamCallBudget
+	proxyHandler.UpstreamRetryBudget = cfg.UpstreamRetryBudget
+	proxyHandler.RetryIdempotencyKey = cfg.UpstreamRetryIdempotencyKey
+	proxyHandler.SortVerifyMaxRecords = cfg.SortVerifyMaxRecords
+	proxyHandler.CommentCounts = func(ctx context.Context, tableKey string, recordIDs []string) (map[string]int, error) {
+		return database.WithContext(ctx).CountComments(tableKey, recordIDs)
+	}
+	proxyHandler.LinkNotFoundMode = cfg.LinkNotFoundMode
+	proxyHandler.SelectValidation = cfg.SelectValidation
+	proxyHandler.ComputedFields = cfg.ComputedFields
+	proxyHandler.BulkWrites = cfg.BulkWrites
+	proxyHandler.BulkCreatedIDs = cfg.BulkCreatedIDs
+	proxyHandler.JSONContentType = cfg.JSONContentType
+	proxyHandler.TransformMaxBytes = cfg.TransformMaxBytes
+	proxyHandler.ValidationErrors = cfg.ValidationErrors
+	proxyHandler.PathValidation = cfg.PathValidation
+	if cfg.RecordIDPattern != "" {
+		pattern, err := regexp.Compile(cfg.RecordIDPattern)
+		if err != nil {
+			log.Fatalf("[STARTUP FATAL] RECORD_ID_PATTERN: %v", err)
+		}
+		proxyHandler.RecordIDPattern = pattern
+	}
+	proxyHandler.UnresolvedFields = cfg.UnresolvedFields
+	proxyHandler.LinkTargetPermissions = cfg.LinkTargetPermissions
+	proxyHandler.LinkImpliesUnlink = cfg.LinkImpliesUnlink
+	proxyHandler.RowLevelDefault = cfg.RowLevelDefault
+	proxyHandler.Features = features
+	if cfg.UpstreamErrors == "mapped" {
+		rules, err := config.LoadUpstreamErrorRules(cfg.UpstreamErrorMap)
+		if err != nil {
+			log.Fatalf("[STARTUP ERROR] %v", err)
+		}
+		proxyHandler.UpstreamErrorRules = rules
+	}
+	proxyHandler.Deprecations = proxy.NewDeprecationTracker()
+	proxyHandler.WriteCooldowns = proxy.NewWriteCooldowns()
+	proxyHandler.DeprecationGrace = cfg.DeprecationGrace
+	proxyHandler.PublicBaseURL = cfg.PublicBaseURL
+	proxyHandler.Cursors = proxy.NewCursorCodec([]byte(cfg.CursorSecret), cfg.CursorTTL)
+	proxyHandler.LegacyOperations = cfg.LegacyOperations
+	proxyHandler.MaxBodyBytes = cfg.MaxBodyBytes
+	proxyHandler.AllowResponseHeaders(cfg.ResponseHeadersExtra...)
 
 	// Set resolved configuration if available (config-driven mode)
 	if resolvedConfig != nil {
@@ -170,21 +293,92 @@ func main() {
 
 	// Create auth handler
 	authHandler := auth.NewHandler(database, cfg.JWTSecret, "http://localhost:4321")
+	authHandler.CollisionPolicy = cfg.OAuthCollisionPolicy
+	authHandler.TokenTTL = cfg.JWTTTL
 
 	// Create introspection handler
 	introspectHandler := introspect.NewHandler(metaCache, resolvedConfig, proxyConfigPath)
+	introspectHandler.DeprecationGrace = cfg.DeprecationGrace
+	introspectHandler.ReloadConcurrency = cfg.ReloadConcurrency
+	introspectHandler.Features = features
+	if resolvedConfig != nil {
+		introspectHandler.Apply = func(proxyConfig *config.ProxyConfig) (*config.ResolvedConfig, error) {
+			resolved, err := config.NewResolver(metaCache).Resolve(proxyConfig)
+			if err != nil {
+				return nil, err
+			}
+			proxyHandler.SetResolvedConfig(resolved)
+			return resolved, nil
+		}
+		configHistory, err := config.NewConfigHistory(cfg.ConfigHistoryDir, cfg.ConfigHistorySize)
+		if err != nil {
+			log.Printf("[STARTUP WARN] %v - keeping config history in memory only", err)
+			configHistory, _ = config.NewConfigHistory("", cfg.ConfigHistorySize)
+		}
+		introspectHandler.History = configHistory
+		introspectHandler.RecordStartupConfig(proxyConfigSource)
+	}
+
+	// Password policy for local signups
+	passwordPolicy, err := auth.NewPasswordPolicy(cfg.PasswordMinLength, float64(cfg.PasswordMinEntropy), cfg.PasswordDenylist)
+	if err != nil {
+		log.Fatalf("[STARTUP ERROR] %v", err)
+	}
+	log.Printf("[STARTUP] Password policy: min length %d, min entropy %d bits, %d denied passwords", cfg.PasswordMinLength, cfg.PasswordMinEntropy, passwordPolicy.DenylistSize())
+
+	// Browser clients may keep the access token in a cookie instead of the Authorization header
+	var cookieAuth *middleware.CookieAuth
+	if cfg.AuthCookieName != "" {
+		cookieAuth = &middleware.CookieAuth{Name: cfg.AuthCookieName, Secure: cfg.AuthCookieSecure}
+		log.Printf("[STARTUP] Cookie authentication enabled (cookie %s, secure=%v, CSRF header %s required on writes)", cfg.AuthCookieName, cfg.AuthCookieSecure, middleware.CSRFHeader)
+	}
+	authHandler.Cookies = cookieAuth
+
+	// Access tokens are short-lived; clients renew them with single-use refresh tokens
+	tokens := &tokenIssuer{database: database, secret: cfg.JWTSecret, accessTTL: cfg.AccessTokenTTL, refreshTTL: cfg.RefreshTokenTTL, cookies: cookieAuth}
+	if err := startTokenPurge(database, cfg.TokenCleanupInterval); err != nil {
+		log.Fatalf("[STARTUP ERROR] %v", err)
+	}
+	log.Printf("[STARTUP] Access tokens valid for %v, refresh tokens for %v", cfg.AccessTokenTTL, cfg.RefreshTokenTTL)
+	trustedProxies, err := middleware.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		log.Fatalf("[STARTUP ERROR] TRUSTED_PROXIES: %v", err)
+	}
+	lockout := &loginLockout{maxFailures: cfg.LoginMaxFailures, maxIPFailures: cfg.LoginMaxIPFailures, cooldown: cfg.LoginLockoutCooldown, proxies: trustedProxies}
+	if cfg.LoginMaxFailures > 0 {
+		log.Printf("[STARTUP] Logins locked for %v after %d consecutive failures per email or %d per IP", cfg.LoginLockoutCooldown, cfg.LoginMaxFailures, cfg.LoginMaxIPFailures)
+	}
+	resets := newPasswordResets(database, passwordPolicy, cfg.PasswordResetTTL, cfg.PasswordResetWebhook, &http.Client{Timeout: passwordResetWebhookTimeout}, cfg.PasswordResetInterval, cfg.PasswordResetQueueSize)
 
 	// Create router
 	mux := http.NewServeMux()
 
 	// Public endpoints
-	mux.HandleFunc("/login", loginHandler(database, cfg.JWTSecret))
-	mux.HandleFunc("/signup", signupHandler(database, cfg.JWTSecret))
+	mux.HandleFunc("/login", loginHandler(tokens, lockout))
+	mux.HandleFunc("/signup", signupHandler(tokens, passwordPolicy))
+	mux.HandleFunc("/api/auth/refresh", refreshHandler(tokens))
+	mux.HandleFunc("/api/auth/logout", tokenLogoutHandler(tokens))
+	mux.HandleFunc("/api/auth/forgot-password", resets.forgotPasswordHandler)
+	mux.HandleFunc("/api/auth/reset-password", resets.resetPasswordHandler)
 	mux.HandleFunc("/health", healthHandler)
 
 	// Introspection endpoints (read-only, no auth required for ops visibility)
 	mux.HandleFunc("/__proxy/status", introspectHandler.ServeStatus)
 	mux.HandleFunc("/__proxy/schema", introspectHandler.ServeSchema)
+	mux.HandleFunc("/readyz", introspectHandler.ServeReady)
+	mux.HandleFunc("/__proxy/errors", httperr.ServeCatalog)
+	mux.HandleFunc("/__proxy/config/history", introspectHandler.ServeConfigHistory)
+
+	// Prometheus metrics, unauthenticated like /__proxy/status
+	if cfg.MetricsEnabled {
+		mux.Handle("/metrics", metrics.Handler())
+	}
+
+	// Embedded admin UI: static files only, every action goes through the authenticated JSON API
+	if cfg.AdminUIEnabled {
+		mux.Handle("/admin/", adminui.Handler())
+		mux.Handle("/admin", http.RedirectHandler("/admin/", http.StatusMovedPermanently))
+	}
 
 	// OAuth endpoints
 	mux.HandleFunc("/auth/google", authHandler.BeginAuth)
@@ -194,29 +388,101 @@ func main() {
 	mux.HandleFunc("/auth/logout", authHandler.Logout)
 
 	// Protected auth endpoints
-	protectedUserHandler := auth.AuthMiddleware(cfg.JWTSecret)(
+	protectedUserHandler := auth.AuthMiddleware(cfg.JWTSecret, database, cookieAuth)(
 		http.HandlerFunc(authHandler.GetCurrentUser),
 	)
 	mux.Handle("/auth/me", protectedUserHandler)
 
 	// Protected secure ping endpoint (example)
-	protectedPingHandler := auth.AuthMiddleware(cfg.JWTSecret)(
+	protectedPingHandler := auth.AuthMiddleware(cfg.JWTSecret, database, cookieAuth)(
 		http.HandlerFunc(securePingHandler(database)),
 	)
 	mux.Handle("/api/secure/ping", protectedPingHandler)
 
-	// Protected proxy endpoints (ONLY data access path)
-	protectedHandler := middleware.AuthMiddleware(cfg.JWTSecret)(
-		middleware.AuthorizeMiddleware(proxyHandler),
+	// Protected proxy endpoints (ONLY data access path); record comments live under the same prefix
+	commentsHandler := comments.NewHandler(database, proxyHandler, cfg.CommentMaxLength)
+	concurrencyLimiter := middleware.NewConcurrencyLimiter(cfg.MaxConcurrentPerUser, cfg.ConcurrencyAdminBypass)
+	protectedHandler := middleware.AuthMiddleware(cfg.JWTSecret, database, cookieAuth)(
+		concurrencyLimiter.Middleware(
+			middleware.AuthorizeMiddleware(commentsHandler.Wrap(proxyHandler)),
+		),
 	)
 	mux.Handle("/proxy/", protectedHandler)
 
-	// Apply middleware chain (order matters: logging -> error handling -> CORS)
+	// Scheduled dashboard summaries (schema-driven mode only: they read through the validator)
+	if resolvedConfig != nil && len(proxyConfig.Summaries) > 0 {
+		summaryScheduler := summaries.NewScheduler(proxyHandler, database, proxyConfig.Summaries)
+		summaryScheduler.Start()
+		summariesHandler := middleware.AuthMiddleware(cfg.JWTSecret, database, cookieAuth)(summaryScheduler)
+		mux.Handle("/proxy/_summaries", summariesHandler)
+		mux.Handle("/proxy/_summaries/", summariesHandler)
+		log.Printf("[STARTUP] Scheduled %d summaries", len(proxyConfig.Summaries))
+	}
+
+	// Effective permissions for the calling user (drives create/edit/delete buttons in frontends)
+	permissionsHandler := middleware.AuthMiddleware(cfg.JWTSecret, database, cookieAuth)(
+		http.HandlerFunc(proxyHandler.ServePermissions),
+	)
+	mux.Handle("/api/me/permissions", permissionsHandler)
+
+	// Uses of deprecated fields per client, to find out who still needs them before the sunset
+	mux.Handle("/api/admin/deprecations", middleware.AuthMiddleware(cfg.JWTSecret, database, cookieAuth)(http.HandlerFunc(proxyHandler.ServeDeprecationUsage)))
+
+	// Admin-triggered schema reload, for tables and fields added or renamed in NocoDB
+	mux.Handle("/__proxy/metacache/refresh", middleware.AuthMiddleware(cfg.JWTSecret, database, cookieAuth)(http.HandlerFunc(introspectHandler.ServeRefresh)))
+	mux.Handle("/__proxy/config/rollback", middleware.AuthMiddleware(cfg.JWTSecret, database, cookieAuth)(http.HandlerFunc(introspectHandler.ServeConfigRollback)))
+
+	// Admin-triggered SQLCipher re-key
+	mux.Handle("/api/admin/db/rekey", middleware.AuthMiddleware(cfg.JWTSecret, database, cookieAuth)(rekeyHandler(database)))
+	mux.Handle("/api/admin/users/identities", middleware.AuthMiddleware(cfg.JWTSecret, database, cookieAuth)(linkIdentityHandler(database)))
+	mux.Handle("/api/admin/users/", middleware.AuthMiddleware(cfg.JWTSecret, database, cookieAuth)(adminUsersHandler(database)))
+
+	// Batch display-name resolution for any authenticated user (no emails exposed)
+	displayCacheTTL := cfg.UserDisplayCacheTTL
+	if !features.Enabled(config.FeatureCaching) {
+		displayCacheTTL = 0 // every lookup reads the database
+	}
+	displayResolver := users.NewDisplayResolver(database, displayCacheTTL, cfg.UserDisplayCacheSize)
+	if cfg.UserFieldTranslation {
+		proxyHandler.Collaborators = collaboratorDirectory{resolver: displayResolver, database: database}
+		proxyHandler.UserFieldsAdminRaw = cfg.UserFieldsAdminRaw
+	}
+	displayRateLimiter := middleware.NewUserRateLimiter(cfg.UserDisplayRateLimit, time.Minute)
+	mux.Handle("/api/users/display", middleware.AuthMiddleware(cfg.JWTSecret, database, cookieAuth)(displayRateLimiter.Middleware(displayResolver)))
+
+	// Localized error messages; catalogs with missing or unknown placeholders stop startup
+	errorCatalogs, err := i18n.Load(cfg.ErrorCatalogDir)
+	if err != nil {
+		log.Fatalf("[STARTUP ERROR] %v", err)
+	}
+	if !errorCatalogs.Has(cfg.ErrorLocale) {
+		log.Fatalf("[STARTUP ERROR] ERROR_LOCALE=%s has no catalog (available: %s)", cfg.ErrorLocale, strings.Join(errorCatalogs.Locales(), ", "))
+	}
+	httperr.Translate = errorCatalogs.Translate
+	log.Printf("[STARTUP] Error message locales: %s (default %s)", strings.Join(errorCatalogs.Locales(), ", "), cfg.ErrorLocale)
+
+	// Per-IP rate limit, ahead of everything that does work (authentication, login, signup)
+	ipRateLimiter := middleware.NewIPRateLimiter(cfg.IPRateLimit, cfg.IPRateLimitWindow, cfg.IPRateLimitMode, trustedProxies, "/health", "/readyz")
+	if cfg.IPRateLimit > 0 {
+		log.Printf("[STARTUP] IP rate limit: %d requests per %v (%s, %d trusted proxies)", cfg.IPRateLimit, cfg.IPRateLimitWindow, cfg.IPRateLimitMode, len(trustedProxies))
+	}
+
+	// Apply middleware chain (order matters: metrics -> logging -> error handling -> CORS -> locale -> IP rate limit -> header limits)
+	// Header limits run before any handler parses Authorization or other headers
 	handler := middleware.RequestLoggerMiddleware(
 		middleware.ErrorLoggerMiddleware(
-			middleware.CORSMiddleware(mux),
+			middleware.CORSMiddleware(
+				errorCatalogs.Middleware(cfg.ErrorLocale)(
+					ipRateLimiter.Middleware(
+						middleware.HeaderLimitMiddleware(cfg.RequestHeaderMaxCount, cfg.RequestHeaderMaxBytes)(mux),
+					),
+				),
+			),
 		),
 	)
+	if cfg.MetricsEnabled {
+		handler = middleware.MetricsMiddleware(mux)(handler)
+	}
 
 	// Start server
 	addr := ":" + cfg.Port
@@ -234,14 +500,35 @@ func main() {
 		log.Printf("[STARTUP]    Validation: ENABLED")
 	} else {
 		log.Printf("\n[STARTUP] 🔓 PROXY MODE: Legacy (No Validation)")
-		log.Printf("[STARTUP]    All operations allowed")
+		if len(cfg.LegacyOperations) > 0 {
+			log.Printf("[STARTUP]    Operations allowed: %s", strings.Join(cfg.LegacyOperations, ", "))
+		} else {
+			log.Printf("[STARTUP]    All operations allowed")
+		}
 	}
 
 	log.Printf("\n[STARTUP] Endpoints:")
 	log.Printf("  - Data Access:    /proxy/*")
+	log.Printf("  - Comments:       /proxy/{table}/records/{id}/comments")
+	log.Printf("  - Permissions:    /api/me/permissions")
+	log.Printf("  - Deprecations:   /api/admin/deprecations (admin)")
+	if resolvedConfig != nil && len(proxyConfig.Summaries) > 0 {
+		log.Printf("  - Summaries:      /proxy/_summaries")
+	}
+	log.Printf("  - User Display:   /api/users/display?ids=1,2,3")
 	log.Printf("  - Status:         /__proxy/status")
 	log.Printf("  - Schema Info:    /__proxy/schema")
+	log.Printf("  - Schema Reload:  POST /__proxy/metacache/refresh (admin)")
+	log.Printf("  - Config History: /__proxy/config/history, POST /__proxy/config/rollback?to=<hash> (admin)")
+	log.Printf("  - Error Catalog:  /__proxy/errors")
+	if cfg.AdminUIEnabled {
+		log.Printf("  - Admin UI:       /admin/")
+	}
+	if cfg.MetricsEnabled {
+		log.Printf("  - Metrics:        /metrics")
+	}
 	log.Printf("  - Health Check:   /health")
+	log.Printf("  - Readiness:      /readyz")
 
 	log.Printf("\n[STARTUP] OAuth Providers:")
 	if cfg.GoogleClientID != "" {
@@ -257,6 +544,8 @@ func main() {
 		log.Printf("  ✗ GitHub OAuth disabled (set GITHUB_CLIENT_ID)")
 	}
 
+	log.Printf("  Email collision policy: %s", cfg.OAuthCollisionPolicy)
+
 	log.Printf("\n[STARTUP] Demo users (legacy login):")
 	log.Printf("  - admin@example.com / admin123 (role: admin)")
 	log.Printf("  - user@example.com / user123 (role: user)")
@@ -264,13 +553,19 @@ func main() {
 	log.Println("[STARTUP] ✅ Server ready!")
 	log.Printf("[STARTUP] ========================================\n")
 
-	if err := http.ListenAndServe(addr, handler); err != nil {
+	logServerLimits(cfg)
+	listener, err := listen(addr, cfg.ServerMaxConnections)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := newHTTPServer(cfg, addr, handler).Serve(listener); err != nil {
 		log.Fatal(err)
 	}
 }
 
-func loginHandler(database *db.Database, jwtSecret string) http.HandlerFunc {
+func loginHandler(tokens *tokenIssuer, lockout *loginLockout) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		database := tokens.database.WithContext(r.Context())
 		log.Printf("[LOGIN] Login attempt from %s", r.RemoteAddr)
 
 		if r.Method != http.MethodPost {
@@ -286,27 +581,31 @@ func loginHandler(database *db.Database, jwtSecret string) http.HandlerFunc {
 			return
 		}
 		log.Printf("[LOGIN] Login request for email: %s", req.Email)
+		if !lockout.check(w, r, database, req.Email) {
+			return
+		}
 
 		// Try database authentication first
 		dbUser, err := database.ValidatePassword(req.Email, req.Password)
 		if err == nil && dbUser != nil {
 			log.Printf("[LOGIN] Database user authenticated: %s (role: %s)", dbUser.Email, dbUser.Role)
+			lockout.succeeded(r, database, req.Email)
 
-			// Generate JWT
-			token, err := utils.GenerateJWT(fmt.Sprintf("%d", dbUser.ID), dbUser.Role, jwtSecret)
+			// Generate access and refresh tokens
+			response, err := tokens.issue(database, fmt.Sprintf("%d", dbUser.ID), dbUser.Role)
 			if err != nil {
-				log.Printf("[LOGIN ERROR] Failed to generate JWT: %v", err)
+				log.Printf("[LOGIN ERROR] Failed to generate tokens: %v", err)
 				respondWithError(w, http.StatusInternalServerError, "failed to generate token")
 				return
 			}
 
-			// Return token
-			w.Header().Set("Content-Type", "application/json")
-			response := LoginResponse{
-				Token:  token,
-				UserID: fmt.Sprintf("%d", dbUser.ID),
-				Role:   dbUser.Role,
+			// Return tokens
+			if err := tokens.setCookies(w, response); err != nil {
+				log.Printf("[LOGIN ERROR] Failed to set auth cookies: %v", err)
+				respondWithError(w, http.StatusInternalServerError, "failed to generate token")
+				return
 			}
+			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(response)
 			log.Printf("[LOGIN] Login successful for database user: %s", dbUser.Email)
 			return
@@ -316,28 +615,31 @@ func loginHandler(database *db.Database, jwtSecret string) http.HandlerFunc {
 		user, exists := demoUsers[req.Email]
 		if !exists || user.Password != req.Password {
 			log.Printf("[LOGIN ERROR] Invalid credentials for email: %s", req.Email)
+			metrics.AuthFailure(metrics.AuthInvalidCredentials)
+			lockout.failed(r, database, req.Email)
 			respondWithError(w, http.StatusUnauthorized, "invalid credentials")
 			return
 		}
 		log.Printf("[LOGIN] Credentials validated for demo user: %s (role: %s)", user.UserID, user.Role)
+		lockout.succeeded(r, database, req.Email)
 
-		// Generate JWT
+		// Generate access and refresh tokens
 		log.Printf("[LOGIN] Generating JWT token...")
-		token, err := utils.GenerateJWT(user.UserID, user.Role, jwtSecret)
+		response, err := tokens.issue(database, user.UserID, user.Role)
 		if err != nil {
-			log.Printf("[LOGIN ERROR] Failed to generate JWT: %v", err)
+			log.Printf("[LOGIN ERROR] Failed to generate tokens: %v", err)
 			respondWithError(w, http.StatusInternalServerError, "failed to generate token")
 			return
 		}
 		log.Printf("[LOGIN] JWT generated successfully")
 
-		// Return token
-		w.Header().Set("Content-Type", "application/json")
-		response := LoginResponse{
-			Token:  token,
-			UserID: user.UserID,
-			Role:   user.Role,
+		// Return tokens
+		if err := tokens.setCookies(w, response); err != nil {
+			log.Printf("[LOGIN ERROR] Failed to set auth cookies: %v", err)
+			respondWithError(w, http.StatusInternalServerError, "failed to generate token")
+			return
 		}
+		w.Header().Set("Content-Type", "application/json")
 		if err := json.NewEncoder(w).Encode(response); err != nil {
 			log.Printf("[LOGIN ERROR] Failed to encode response: %v", err)
 			return
@@ -352,8 +654,9 @@ type SignupRequest struct {
 	Name     string `json:"name"`
 }
 
-func signupHandler(database *db.Database, jwtSecret string) http.HandlerFunc {
+func signupHandler(tokens *tokenIssuer, passwords *auth.PasswordPolicy) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		database := tokens.database.WithContext(r.Context())
 		log.Printf("[SIGNUP] Signup attempt from %s", r.RemoteAddr)
 
 		if r.Method != http.MethodPost {
@@ -376,9 +679,9 @@ func signupHandler(database *db.Database, jwtSecret string) http.HandlerFunc {
 			return
 		}
 
-		if len(req.Password) < 6 {
-			log.Printf("[SIGNUP ERROR] Password too short")
-			respondWithError(w, http.StatusBadRequest, "password must be at least 6 characters")
+		if err := passwords.Check(req.Password); err != nil {
+			log.Printf("[SIGNUP ERROR] Password rejected by policy: %v", err)
+			respondWithError(w, http.StatusBadRequest, err.Error())
 			return
 		}
 
@@ -402,22 +705,22 @@ func signupHandler(database *db.Database, jwtSecret string) http.HandlerFunc {
 
 		log.Printf("[SIGNUP] User created successfully: ID=%d, Email=%s", user.ID, user.Email)
 
-		// Generate JWT token
-		token, err := utils.GenerateJWT(fmt.Sprintf("%d", user.ID), user.Role, jwtSecret)
+		// Generate access and refresh tokens
+		response, err := tokens.issue(database, fmt.Sprintf("%d", user.ID), user.Role)
 		if err != nil {
-			log.Printf("[SIGNUP ERROR] Failed to generate JWT: %v", err)
+			log.Printf("[SIGNUP ERROR] Failed to generate tokens: %v", err)
 			respondWithError(w, http.StatusInternalServerError, "failed to generate token")
 			return
 		}
 
-		// Return token
+		// Return tokens
+		if err := tokens.setCookies(w, response); err != nil {
+			log.Printf("[SIGNUP ERROR] Failed to set auth cookies: %v", err)
+			respondWithError(w, http.StatusInternalServerError, "failed to generate token")
+			return
+		}
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusCreated)
-		response := LoginResponse{
-			Token:  token,
-			UserID: fmt.Sprintf("%d", user.ID),
-			Role:   user.Role,
-		}
 		json.NewEncoder(w).Encode(response)
 		log.Printf("[SIGNUP] Signup successful for user: %s", user.Email)
 	}
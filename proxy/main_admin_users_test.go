@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/grove/generic-proxy/internal/db"
+	"github.com/grove/generic-proxy/internal/middleware"
+)
+
+// newTestDatabase opens an empty database that is closed when the test ends
+func newTestDatabase(t *testing.T) *db.Database {
+	t.Helper()
+	database, err := db.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	return database
+}
+
+// serveAdmin sends a request to /api/admin/users/... as the given user
+func serveAdmin(h http.Handler, method, target, body, userID, role string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	ctx := context.WithValue(req.Context(), middleware.UserIDKey, userID)
+	ctx = context.WithValue(ctx, middleware.RoleKey, role)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req.WithContext(ctx))
+	return rec
+}
+
+func TestDeleteUser(t *testing.T) {
+	database := newTestDatabase(t)
+	admin, err := database.CreateLocalUser("admin@example.com", "password", "Admin")
+	if err != nil {
+		t.Fatalf("create admin: %v", err)
+	}
+	user, err := database.CreateLocalUser("user@example.com", "password", "User")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	adminID := strconv.FormatInt(admin.ID, 10)
+	userPath := "/api/admin/users/" + strconv.FormatInt(user.ID, 10)
+	h := adminUsersHandler(database)
+
+	tests := []struct {
+		name       string
+		target     string
+		callerID   string
+		role       string
+		wantStatus int
+		wantError  string
+	}{
+		{"not an admin", userPath, strconv.FormatInt(user.ID, 10), "user", http.StatusForbidden, "admin role required"},
+		{"own account", "/api/admin/users/" + adminID, adminID, "admin", http.StatusBadRequest, "own account"},
+		{"unknown user", "/api/admin/users/9999", adminID, "admin", http.StatusNotFound, "user not found"},
+		{"not an integer", "/api/admin/users/abc", adminID, "admin", http.StatusBadRequest, "invalid user id 'abc'"},
+		{"not positive", "/api/admin/users/0", adminID, "admin", http.StatusBadRequest, "invalid user id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveAdmin(h, http.MethodDelete, tt.target, "", tt.callerID, tt.role)
+			if rec.Code != tt.wantStatus || !strings.Contains(rec.Body.String(), tt.wantError) {
+				t.Errorf("status = %d, body %s; want %d %q", rec.Code, rec.Body, tt.wantStatus, tt.wantError)
+			}
+		})
+	}
+	for _, id := range []int64{admin.ID, user.ID} {
+		if found, err := database.GetUserByID(id); err != nil || found == nil {
+			t.Fatalf("user %d is gone after rejected deletes (err %v)", id, err)
+		}
+	}
+
+	if rec := serveAdmin(h, http.MethodDelete, userPath, "", adminID, "admin"); rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: status = %d, body %s; want 204", rec.Code, rec.Body)
+	}
+	if found, err := database.GetUserByID(user.ID); err != nil || found != nil {
+		t.Errorf("deleted user still found: %+v (err %v)", found, err)
+	}
+	if rec := serveAdmin(h, http.MethodDelete, userPath, "", adminID, "admin"); rec.Code != http.StatusNotFound {
+		t.Errorf("second delete: status = %d, want 404", rec.Code)
+	}
+	if rec := serveAdmin(h, http.MethodGet, userPath, "", adminID, "admin"); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET: status = %d, want 405", rec.Code)
+	}
+}
+
+func TestUpdateUserRole(t *testing.T) {
+	database := newTestDatabase(t)
+	admin, err := database.CreateLocalUser("admin@example.com", "password", "Admin")
+	if err != nil {
+		t.Fatalf("create admin: %v", err)
+	}
+	if _, err := database.UpdateUserRole(admin.ID, "admin"); err != nil {
+		t.Fatalf("promote admin: %v", err)
+	}
+	user, err := database.CreateLocalUser("user@example.com", "password", "User")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	adminID := strconv.FormatInt(admin.ID, 10)
+	adminPath := "/api/admin/users/" + adminID + "/role"
+	userPath := "/api/admin/users/" + strconv.FormatInt(user.ID, 10) + "/role"
+	h := adminUsersHandler(database)
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		role       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"not an admin", http.MethodPatch, userPath, `{"role":"admin"}`, "user", http.StatusForbidden, "admin role required"},
+		{"invalid role", http.MethodPatch, userPath, `{"role":"owner"}`, "admin", http.StatusBadRequest, "role must be 'user' or 'admin'"},
+		{"missing role", http.MethodPatch, userPath, `{}`, "admin", http.StatusBadRequest, "role is required"},
+		{"last admin", http.MethodPatch, adminPath, `{"role":"user"}`, "admin", http.StatusBadRequest, "last remaining admin"},
+		{"unknown user", http.MethodPatch, "/api/admin/users/9999/role", `{"role":"admin"}`, "admin", http.StatusNotFound, "user not found"},
+		{"bad id", http.MethodPatch, "/api/admin/users/abc/role", `{"role":"admin"}`, "admin", http.StatusBadRequest, "invalid user id 'abc'"},
+		{"wrong method", http.MethodPost, userPath, `{"role":"admin"}`, "admin", http.StatusMethodNotAllowed, ""},
+		{"promote", http.MethodPatch, userPath, `{"role":"admin"}`, "admin", http.StatusOK, `"role":"admin"`},
+		{"demote with another admin left", http.MethodPatch, adminPath, `{"role":"user"}`, "admin", http.StatusOK, `"role":"user"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveAdmin(h, tt.method, tt.target, tt.body, adminID, tt.role)
+			if rec.Code != tt.wantStatus || !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("status = %d, body %s; want %d %q", rec.Code, rec.Body, tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
@@ -1,19 +1,70 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/gorilla/sessions"
 	"github.com/grove/generic-proxy/internal/auth"
 	"github.com/grove/generic-proxy/internal/config"
 	"github.com/grove/generic-proxy/internal/db"
+	"github.com/grove/generic-proxy/internal/middleware"
+	"github.com/grove/generic-proxy/internal/proxy"
+	"github.com/grove/generic-proxy/internal/users"
 	"github.com/markbates/goth"
 	"github.com/markbates/goth/providers/github"
 	"github.com/markbates/goth/providers/google"
 )
 
+// collaboratorDirectory matches NocoDB collaborators to proxy users through the display resolver's cache
+type collaboratorDirectory struct {
+	resolver *users.DisplayResolver
+	database *db.Database
+}
+
+// UsersByEmail implements proxy.CollaboratorDirectory
+func (c collaboratorDirectory) UsersByEmail(ctx context.Context, emails []string) (map[string]proxy.ProxyUser, error) {
+	found, err := c.resolver.ResolveEmails(ctx, emails)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]proxy.ProxyUser, len(found))
+	for email, user := range found {
+		result[email] = proxy.ProxyUser{UserID: user.ID, Name: user.Name, AvatarURL: user.AvatarURL}
+	}
+	return result, nil
+}
+
+// EmailsByUserID implements proxy.CollaboratorDirectory
+func (c collaboratorDirectory) EmailsByUserID(ctx context.Context, ids []int64) (map[int64]string, error) {
+	found, err := c.database.WithContext(ctx).GetUsersByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	emails := make(map[int64]string, len(found))
+	for _, user := range found {
+		emails[user.ID] = user.Email
+	}
+	return emails, nil
+}
+
+// newOAuthSessionStore returns the gothic session store. The session only carries OAuth state
+// between BeginAuth and the callback, so it is kept short-lived and cleared after a successful callback.
+func newOAuthSessionStore(secret string, maxAge time.Duration) *sessions.CookieStore {
+	store := sessions.NewCookieStore([]byte(secret))
+	store.MaxAge(int(maxAge.Seconds()))
+	store.Options.Path = "/"
+	store.Options.HttpOnly = true
+	store.Options.Secure = false // Set to true in production with HTTPS
+	return store
+}
+
 // initializeGothProviders sets up OAuth providers
 func initializeGothProviders(cfg *config.Config) {
 	var providers []goth.Provider
@@ -68,7 +119,7 @@ func securePingHandler(database *db.Database) http.HandlerFunc {
 		}
 
 		// Query user from database
-		user, err := database.GetUserByID(userID)
+		user, err := database.WithContext(r.Context()).GetUserByID(userID)
 		if err != nil {
 			log.Printf("[SECURE PING ERROR] Failed to query user: %v", err)
 			http.Error(w, "Failed to fetch user info", http.StatusInternalServerError)
@@ -107,6 +158,227 @@ func securePingHandler(database *db.Database) http.HandlerFunc {
 	}
 }
 
+// RekeyRequest is the body of POST /api/admin/db/rekey
+type RekeyRequest struct {
+	NewKey string `json:"new_key"`
+}
+
+// rekeyHandler re-encrypts the SQLite database with a new key (admin only, SQLCipher builds only)
+func rekeyHandler(database *db.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		role, _ := r.Context().Value(middleware.RoleKey).(string)
+		if role != "admin" {
+			respondWithError(w, http.StatusForbidden, "admin role required")
+			return
+		}
+
+		if !db.EncryptionSupported() {
+			respondWithError(w, http.StatusNotImplemented, db.ErrEncryptionUnsupported.Error())
+			return
+		}
+
+		var req RekeyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+
+		userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+		log.Printf("[DB] Re-key requested by admin user %s", userID)
+		if err := database.Rekey(req.NewKey); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "rekeyed",
+			"message": "update DATABASE_KEY to the new key before the next restart",
+		})
+	}
+}
+
+// LinkIdentityRequest is the body of POST /api/admin/users/identities
+type LinkIdentityRequest struct {
+	UserID   int64  `json:"user_id"`
+	Provider string `json:"provider"`
+}
+
+// linkIdentityHandler lets a user sign in through another OAuth provider under the strict
+// email collision policy (admin only)
+func linkIdentityHandler(database *db.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		role, _ := r.Context().Value(middleware.RoleKey).(string)
+		if role != "admin" {
+			respondWithError(w, http.StatusForbidden, "admin role required")
+			return
+		}
+
+		var req LinkIdentityRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.UserID <= 0 || req.Provider == "" {
+			respondWithError(w, http.StatusBadRequest, "user_id and provider are required")
+			return
+		}
+
+		database := database.WithContext(r.Context())
+		user, err := database.GetUserByID(req.UserID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "failed to load user")
+			return
+		}
+		if user == nil {
+			respondWithError(w, http.StatusNotFound, "user not found")
+			return
+		}
+		if err := database.LinkIdentity(user.ID, req.Provider); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "failed to link identity")
+			return
+		}
+
+		adminID, _ := r.Context().Value(middleware.UserIDKey).(string)
+		log.Printf("[AUDIT] Admin %s linked %s identity to user %d (%s)", adminID, req.Provider, user.ID, user.Email)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"user_id":  user.ID,
+			"provider": req.Provider,
+			"status":   "linked",
+		})
+	}
+}
+
+// deleteUserHandler handles DELETE /api/admin/users/{id} (admin only). Admins can't delete
+// their own account, so an instance can't lose its last admin by accident.
+func deleteUserHandler(database *db.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		role, _ := r.Context().Value(middleware.RoleKey).(string)
+		if role != "admin" {
+			respondWithError(w, http.StatusForbidden, "admin role required")
+			return
+		}
+
+		rawID := strings.TrimPrefix(r.URL.Path, "/api/admin/users/")
+		userID, err := strconv.ParseInt(rawID, 10, 64)
+		if err != nil || userID <= 0 {
+			respondWithError(w, http.StatusBadRequest, "invalid user id '"+rawID+"'")
+			return
+		}
+
+		adminID, _ := r.Context().Value(middleware.UserIDKey).(string)
+		if adminID == strconv.FormatInt(userID, 10) {
+			respondWithError(w, http.StatusBadRequest, "admins cannot delete their own account")
+			return
+		}
+
+		database := database.WithContext(r.Context())
+		user, err := database.GetUserByID(userID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "failed to load user")
+			return
+		}
+		if user == nil {
+			respondWithError(w, http.StatusNotFound, "user not found")
+			return
+		}
+		if err := database.DeleteUser(user.ID); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "failed to delete user")
+			return
+		}
+
+		log.Printf("[AUDIT] Admin %s deleted user %d (%s)", adminID, user.ID, user.Email)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// UpdateRoleRequest is the body of PATCH /api/admin/users/{id}/role
+type UpdateRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// adminUsersHandler routes /api/admin/users/{id} (DELETE) and /api/admin/users/{id}/role (PATCH)
+func adminUsersHandler(database *db.Database) http.HandlerFunc {
+	deleteUser := deleteUserHandler(database)
+	updateRole := updateUserRoleHandler(database)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/role") {
+			updateRole(w, r)
+			return
+		}
+		deleteUser(w, r)
+	}
+}
+
+// updateUserRoleHandler promotes or demotes a user (admin only)
+func updateUserRoleHandler(database *db.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		role, _ := r.Context().Value(middleware.RoleKey).(string)
+		if role != "admin" {
+			respondWithError(w, http.StatusForbidden, "admin role required")
+			return
+		}
+
+		rawID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/admin/users/"), "/role")
+		userID, err := strconv.ParseInt(rawID, 10, 64)
+		if err != nil || userID <= 0 {
+			respondWithError(w, http.StatusBadRequest, "invalid user id '"+rawID+"'")
+			return
+		}
+
+		var req UpdateRoleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Role == "" {
+			respondWithError(w, http.StatusBadRequest, "role is required")
+			return
+		}
+
+		user, err := database.WithContext(r.Context()).UpdateUserRole(userID, req.Role)
+		switch {
+		case errors.Is(err, db.ErrInvalidRole), errors.Is(err, db.ErrLastAdmin):
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		case err != nil:
+			respondWithError(w, http.StatusInternalServerError, "failed to update role")
+			return
+		case user == nil:
+			respondWithError(w, http.StatusNotFound, "user not found")
+			return
+		}
+
+		adminID, _ := r.Context().Value(middleware.UserIDKey).(string)
+		log.Printf("[AUDIT] Admin %s set role of user %d (%s) to %s", adminID, user.ID, user.Email, user.Role)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"user_id":    user.ID,
+			"email":      user.Email,
+			"provider":   user.Provider,
+			"name":       user.Name,
+			"avatar_url": user.AvatarURL,
+			"role":       user.Role,
+			"created_at": user.CreatedAt,
+		})
+	}
+}
+
 func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
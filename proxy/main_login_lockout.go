@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/db"
+	"github.com/grove/generic-proxy/internal/metrics"
+	"github.com/grove/generic-proxy/internal/middleware"
+)
+
+// loginLockout throttles password guessing: after maxFailures consecutive failed logins for an email,
+// from any address, that email can't log in for cooldown; after maxIPFailures failed logins from one
+// client IP, for any email, that IP can't either. Counts live in SQLite, so restarts keep them.
+type loginLockout struct {
+	maxFailures   int // per email, 0 = no lockout at all
+	maxIPFailures int // per client IP, 0 = only emails are locked
+	cooldown      time.Duration
+	proxies       middleware.TrustedProxies
+}
+
+// check answers 429 with the remaining cooldown and reports false while the email or the IP is locked out
+func (l *loginLockout) check(w http.ResponseWriter, r *http.Request, database *db.Database, email string) bool {
+	if l.maxFailures <= 0 || email == "" {
+		return true
+	}
+	remaining, err := database.IsLockedOut(email, l.proxies.ClientIP(r))
+	if err != nil || remaining <= 0 {
+		return true // a failed check leaves the password check to decide
+	}
+
+	log.Printf("[LOGIN ERROR] Login for %s from %s locked out for another %v", email, l.proxies.ClientIP(r), remaining.Round(time.Second))
+	metrics.AuthFailure(metrics.AuthLockedOut)
+	seconds := int(math.Ceil(remaining.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":       "too many failed login attempts, try again in " + (time.Duration(seconds) * time.Second).String(),
+		"retry_after": seconds,
+	})
+	return false
+}
+
+// failed counts a failed login for the email and for the client IP
+func (l *loginLockout) failed(r *http.Request, database *db.Database, email string) {
+	if l.maxFailures <= 0 || email == "" {
+		return
+	}
+	ip := l.proxies.ClientIP(r)
+	lockedUntil, err := database.RecordFailedLogin(email, ip, l.maxFailures, l.maxIPFailures, l.cooldown)
+	if err == nil && !lockedUntil.IsZero() {
+		log.Printf("[LOGIN] Failed login for %s from %s, locked until %s", email, ip, lockedUntil.Format(time.RFC3339))
+	}
+}
+
+// succeeded resets the failure count of the email; the client IP keeps its count
+func (l *loginLockout) succeeded(r *http.Request, database *db.Database, email string) {
+	if l.maxFailures <= 0 || email == "" {
+		return
+	}
+	database.ClearFailedLogins(email)
+}
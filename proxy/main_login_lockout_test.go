@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/db"
+)
+
+func TestLoginLockout(t *testing.T) {
+	database, err := db.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	lockout := &loginLockout{maxFailures: 2, maxIPFailures: 10, cooldown: time.Minute}
+
+	request := func(ip string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/login", nil)
+		r.RemoteAddr = ip + ":1234"
+		return r
+	}
+	check := func(ip string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		lockout.check(rec, request(ip), database, "alice@example.com")
+		return rec
+	}
+
+	lockout.failed(request("10.0.0.1"), database, "alice@example.com")
+	lockout.succeeded(request("10.0.0.1"), database, "alice@example.com")
+	lockout.failed(request("10.0.0.1"), database, "alice@example.com")
+	if rec := check("10.0.0.1"); rec.Code != http.StatusOK {
+		t.Fatalf("locked after a success reset the count: status %d", rec.Code)
+	}
+
+	lockout.failed(request("10.0.0.2"), database, "alice@example.com")
+	rec := check("10.0.0.3")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429 once the email reached the limit", rec.Code)
+	}
+	if retryAfter := rec.Header().Get("Retry-After"); retryAfter != "60" {
+		t.Errorf("Retry-After = %q, want 60", retryAfter)
+	}
+}
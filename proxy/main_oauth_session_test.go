@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/markbates/goth/gothic"
+)
+
+func TestOAuthSessionStore(t *testing.T) {
+	store := newOAuthSessionStore("test-secret", 10*time.Minute)
+	if store.Options.MaxAge != 600 || !store.Options.HttpOnly || store.Options.Path != "/" {
+		t.Fatalf("options = %+v, want MaxAge 600, HttpOnly, Path /", *store.Options)
+	}
+
+	// An OAuth flow in progress: the session cookie lives for SESSION_MAX_AGE
+	req := httptest.NewRequest(http.MethodGet, "/auth/google", nil)
+	rec := httptest.NewRecorder()
+	session, _ := store.Get(req, gothic.SessionName)
+	session.Values["google"] = "state"
+	if err := session.Save(req, rec); err != nil {
+		t.Fatalf("save session: %v", err)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != gothic.SessionName || cookies[0].MaxAge != 600 {
+		t.Fatalf("cookies = %v, want %s with Max-Age 600", cookies, gothic.SessionName)
+	}
+
+	// The callback clears it with gothic.Logout
+	previous := gothic.Store
+	gothic.Store = store
+	t.Cleanup(func() { gothic.Store = previous })
+	callback := httptest.NewRequest(http.MethodGet, "/auth/google/callback", nil)
+	callback.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	if err := gothic.Logout(rec, callback); err != nil {
+		t.Fatalf("logout: %v", err)
+	}
+	cleared := rec.Result().Cookies()
+	if len(cleared) != 1 || cleared[0].Name != gothic.SessionName || cleared[0].MaxAge >= 0 {
+		t.Errorf("cookies after the callback = %v, want %s expired", cleared, gothic.SessionName)
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/auth"
+	"github.com/grove/generic-proxy/internal/db"
+	"github.com/grove/generic-proxy/internal/utils"
+)
+
+// forgotPasswordResponse is returned for every well-formed request, so the response doesn't reveal
+// whether an account exists
+const forgotPasswordResponse = "if a local account with that email exists, a reset link has been sent"
+
+// passwordResetWebhookTimeout bounds a delivery to PASSWORD_RESET_WEBHOOK
+const passwordResetWebhookTimeout = 10 * time.Second
+
+// passwordResetTrackedEmails bounds how many recently served emails the per-email throttle remembers;
+// while it is full of entries younger than the interval, further requests are dropped
+const passwordResetTrackedEmails = 10000
+
+// passwordResets lets local users who forgot their password set a new one with a single-use token.
+// The proxy doesn't send email itself: tokens are POSTed to a webhook that delivers them.
+type passwordResets struct {
+	database  *db.Database
+	passwords *auth.PasswordPolicy
+	ttl       time.Duration
+	webhook   string // receives {email, token, expires_at}; empty = no tokens are issued
+	client    *http.Client
+	interval  time.Duration // minimum time between two requests served for one email; 0 = no throttle
+	queue     chan string   // emails waiting for delivery; requests are dropped while it is full
+
+	mu       sync.Mutex
+	lastSent map[string]time.Time // normalized email -> when a request for it was last queued
+}
+
+// newPasswordResets returns the reset endpoints with one delivery worker behind a queue of queueSize
+// emails, so a flood of requests costs at most queueSize pending lookups and never more goroutines
+func newPasswordResets(database *db.Database, passwords *auth.PasswordPolicy, ttl time.Duration, webhook string, client *http.Client, interval time.Duration, queueSize int) *passwordResets {
+	if queueSize < 1 {
+		queueSize = 1
+	}
+	p := &passwordResets{database: database, passwords: passwords, ttl: ttl, webhook: webhook, client: client,
+		interval: interval, queue: make(chan string, queueSize), lastSent: make(map[string]time.Time)}
+	go p.deliver()
+	return p
+}
+
+// deliver sends the queued reset tokens one at a time
+func (p *passwordResets) deliver() {
+	for email := range p.queue {
+		p.sendResetToken(email)
+	}
+}
+
+// enqueue queues a reset for email unless one was queued for it within the interval or the queue is
+// full. Either way the caller answers the same, so throttled and dropped requests look like sent ones.
+func (p *passwordResets) enqueue(email string) bool {
+	key := strings.ToLower(email)
+	now := time.Now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.interval > 0 {
+		if last, ok := p.lastSent[key]; ok && now.Sub(last) < p.interval {
+			log.Printf("[PASSWORD RESET] Reset for the same email requested again within %v, ignored", p.interval)
+			return false
+		}
+		if len(p.lastSent) >= passwordResetTrackedEmails {
+			for tracked, last := range p.lastSent {
+				if now.Sub(last) >= p.interval {
+					delete(p.lastSent, tracked)
+				}
+			}
+			if len(p.lastSent) >= passwordResetTrackedEmails {
+				log.Printf("[PASSWORD RESET ERROR] %d emails requested a reset within %v, request dropped", len(p.lastSent), p.interval)
+				return false
+			}
+		}
+	}
+
+	select {
+	case p.queue <- email:
+	default:
+		log.Printf("[PASSWORD RESET ERROR] Delivery queue full (%d pending), request dropped", cap(p.queue))
+		return false
+	}
+	if p.interval > 0 {
+		p.lastSent[key] = now
+	}
+	return true
+}
+
+// ForgotPasswordRequest is the body of POST /api/auth/forgot-password
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// ResetPasswordRequest is the body of POST /api/auth/reset-password
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// passwordResetDelivery is the webhook payload carrying a reset token to its owner
+type passwordResetDelivery struct {
+	Email     string    `json:"email"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// forgotPasswordHandler starts a password reset. It always answers 200 and leaves the lookup and
+// delivery to the worker, so neither the response nor its timing tells whether the email exists.
+func (p *passwordResets) forgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Email) == "" {
+		respondWithError(w, http.StatusBadRequest, "email is required")
+		return
+	}
+
+	p.enqueue(strings.TrimSpace(req.Email))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": forgotPasswordResponse})
+}
+
+// sendResetToken issues a reset token for a local account and hands it to the webhook.
+// Unknown emails and OAuth-only accounts are skipped silently.
+func (p *passwordResets) sendResetToken(email string) {
+	if p.webhook == "" {
+		log.Printf("[PASSWORD RESET] Reset requested but PASSWORD_RESET_WEBHOOK is not set, no token issued")
+		return
+	}
+	user, err := p.database.GetUserByEmail(email)
+	if err != nil || user == nil || user.PasswordHash == "" {
+		log.Printf("[PASSWORD RESET] No local account for the requested email, nothing sent")
+		return
+	}
+
+	token, tokenHash, err := utils.NewRefreshToken()
+	if err != nil {
+		log.Printf("[PASSWORD RESET ERROR] Failed to generate reset token: %v", err)
+		return
+	}
+	expiresAt := time.Now().Add(p.ttl)
+	if err := p.database.CreatePasswordResetToken(tokenHash, user.ID, expiresAt); err != nil {
+		return
+	}
+
+	payload, _ := json.Marshal(passwordResetDelivery{Email: user.Email, Token: token, ExpiresAt: expiresAt.UTC()})
+	resp, err := p.client.Post(p.webhook, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		log.Printf("[PASSWORD RESET ERROR] Failed to deliver reset token for user %d: %v", user.ID, err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Printf("[PASSWORD RESET ERROR] Webhook answered %d for user %d", resp.StatusCode, user.ID)
+		return
+	}
+	log.Printf("[PASSWORD RESET] Reset token sent for user %d, valid until %s", user.ID, expiresAt.Format(time.RFC3339))
+}
+
+// resetPasswordHandler consumes a reset token and sets the new password. The user's refresh tokens
+// are revoked, so they have to log in again everywhere.
+func (p *passwordResets) resetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" || req.Password == "" {
+		respondWithError(w, http.StatusBadRequest, "token and password are required")
+		return
+	}
+	// Checked before the token is consumed, so a rejected password can be retried with the same link
+	if err := p.passwords.Check(req.Password); err != nil {
+		log.Printf("[PASSWORD RESET ERROR] Password rejected by policy: %v", err)
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	database := p.database.WithContext(r.Context())
+	userID, err := database.ConsumePasswordResetToken(utils.HashRefreshToken(req.Token))
+	if errors.Is(err, db.ErrResetTokenInvalid) || errors.Is(err, db.ErrResetTokenExpired) {
+		log.Printf("[PASSWORD RESET ERROR] Rejected reset token: %v", err)
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to reset password")
+		return
+	}
+
+	if err := database.UpdatePassword(userID, req.Password); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusBadRequest, db.ErrResetTokenInvalid.Error())
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "failed to reset password")
+		return
+	}
+
+	log.Printf("[PASSWORD RESET] Password reset for user %d", userID)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/auth"
+	"github.com/grove/generic-proxy/internal/db"
+)
+
+// resetWebhook records the deliveries PASSWORD_RESET_WEBHOOK receives
+type resetWebhook struct {
+	*httptest.Server
+	mu         sync.Mutex
+	deliveries []passwordResetDelivery
+}
+
+func newResetWebhook(t *testing.T) *resetWebhook {
+	t.Helper()
+	hook := &resetWebhook{}
+	hook.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var delivery passwordResetDelivery
+		json.NewDecoder(r.Body).Decode(&delivery)
+		hook.mu.Lock()
+		hook.deliveries = append(hook.deliveries, delivery)
+		hook.mu.Unlock()
+	}))
+	t.Cleanup(hook.Close)
+	return hook
+}
+
+func (h *resetWebhook) Deliveries() []passwordResetDelivery {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]passwordResetDelivery(nil), h.deliveries...)
+}
+
+// newTestResets returns reset endpoints for a database with the local user alice@example.com and an
+// OAuth-only user bob@example.com. No worker runs: tests call sendResetToken or inspect the queue.
+func newTestResets(t *testing.T, hook *resetWebhook, ttl time.Duration) *passwordResets {
+	t.Helper()
+	database, err := db.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	if _, err := database.CreateLocalUser("alice@example.com", "old-password", "Alice"); err != nil {
+		t.Fatalf("create local user: %v", err)
+	}
+	if _, err := database.CreateUser("bob@example.com", "google", "Bob", ""); err != nil {
+		t.Fatalf("create OAuth user: %v", err)
+	}
+	policy, err := auth.NewPasswordPolicy(6, 0, "off")
+	if err != nil {
+		t.Fatalf("password policy: %v", err)
+	}
+	return &passwordResets{database: database, passwords: policy, ttl: ttl, webhook: hook.URL, client: hook.Client(),
+		interval: time.Minute, queue: make(chan string, 4), lastSent: make(map[string]time.Time)}
+}
+
+func postJSON(handler http.HandlerFunc, target, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodPost, target, strings.NewReader(body)))
+	return rec
+}
+
+// issueToken runs a reset for email synchronously and returns the token the webhook received
+func issueToken(t *testing.T, p *passwordResets, hook *resetWebhook, email string) string {
+	t.Helper()
+	before := len(hook.Deliveries())
+	p.sendResetToken(email)
+	deliveries := hook.Deliveries()
+	if len(deliveries) != before+1 {
+		t.Fatalf("webhook got %d new deliveries, want 1", len(deliveries)-before)
+	}
+	return deliveries[len(deliveries)-1].Token
+}
+
+func TestPasswordResetConsumesTokenOnce(t *testing.T) {
+	hook := newResetWebhook(t)
+	p := newTestResets(t, hook, time.Hour)
+	token := issueToken(t, p, hook, "alice@example.com")
+
+	if rec := postJSON(p.resetPasswordHandler, "/api/auth/reset-password", `{"token":"`+token+`","password":"new-password"}`); rec.Code != http.StatusNoContent {
+		t.Fatalf("reset: status = %d, body %s", rec.Code, rec.Body)
+	}
+	if _, err := p.database.ValidatePassword("alice@example.com", "new-password"); err != nil {
+		t.Errorf("new password rejected after reset: %v", err)
+	}
+
+	rec := postJSON(p.resetPasswordHandler, "/api/auth/reset-password", `{"token":"`+token+`","password":"other-password"}`)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), db.ErrResetTokenInvalid.Error()) {
+		t.Errorf("reused token: status = %d, body %s; want 400 %q", rec.Code, rec.Body, db.ErrResetTokenInvalid)
+	}
+}
+
+func TestPasswordResetExpiredToken(t *testing.T) {
+	hook := newResetWebhook(t)
+	p := newTestResets(t, hook, -time.Minute)
+	token := issueToken(t, p, hook, "alice@example.com")
+
+	rec := postJSON(p.resetPasswordHandler, "/api/auth/reset-password", `{"token":"`+token+`","password":"new-password"}`)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), db.ErrResetTokenExpired.Error()) {
+		t.Errorf("status = %d, body %s; want 400 %q", rec.Code, rec.Body, db.ErrResetTokenExpired)
+	}
+	if _, err := p.database.ValidatePassword("alice@example.com", "old-password"); err != nil {
+		t.Errorf("password changed by an expired token: %v", err)
+	}
+}
+
+func TestPasswordResetNewTokenReplacesOld(t *testing.T) {
+	hook := newResetWebhook(t)
+	p := newTestResets(t, hook, time.Hour)
+	first := issueToken(t, p, hook, "alice@example.com")
+	issueToken(t, p, hook, "alice@example.com")
+
+	if rec := postJSON(p.resetPasswordHandler, "/api/auth/reset-password", `{"token":"`+first+`","password":"new-password"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("superseded token: status = %d, want 400", rec.Code)
+	}
+}
+
+// The answer must be the same whether or not a local account exists, and only local accounts get a token
+func TestForgotPasswordDoesNotRevealAccounts(t *testing.T) {
+	hook := newResetWebhook(t)
+	p := newTestResets(t, hook, time.Hour)
+
+	var bodies []string
+	for _, email := range []string{"alice@example.com", "bob@example.com", "nobody@example.com"} {
+		rec := postJSON(p.forgotPasswordHandler, "/api/auth/forgot-password", `{"email":"`+email+`"}`)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, want 200", email, rec.Code)
+		}
+		bodies = append(bodies, rec.Body.String())
+	}
+	if bodies[0] != bodies[1] || bodies[0] != bodies[2] {
+		t.Errorf("answers differ by account: %q", bodies)
+	}
+
+	close(p.queue)
+	p.deliver()
+	deliveries := hook.Deliveries()
+	if len(deliveries) != 1 || deliveries[0].Email != "alice@example.com" {
+		t.Errorf("deliveries = %+v, want one for the local account only", deliveries)
+	}
+}
+
+func TestForgotPasswordThrottlesPerEmail(t *testing.T) {
+	hook := newResetWebhook(t)
+	p := newTestResets(t, hook, time.Hour)
+
+	for _, email := range []string{"alice@example.com", "ALICE@example.com", "carol@example.com"} {
+		if rec := postJSON(p.forgotPasswordHandler, "/api/auth/forgot-password", `{"email":"`+email+`"}`); rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, want 200 even when throttled", email, rec.Code)
+		}
+	}
+	if len(p.queue) != 2 {
+		t.Errorf("queued %d requests, want 2 (the repeat for alice is throttled)", len(p.queue))
+	}
+
+	p.lastSent["alice@example.com"] = time.Now().Add(-p.interval)
+	if !p.enqueue("alice@example.com") {
+		t.Error("request after the interval was not queued")
+	}
+}
+
+func TestForgotPasswordQueueIsBounded(t *testing.T) {
+	hook := newResetWebhook(t)
+	p := newTestResets(t, hook, time.Hour)
+	p.interval = 0
+
+	for i := 0; i < cap(p.queue)+3; i++ {
+		if rec := postJSON(p.forgotPasswordHandler, "/api/auth/forgot-password", `{"email":"alice@example.com"}`); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want 200 even when dropped", i, rec.Code)
+		}
+	}
+	if len(p.queue) != cap(p.queue) {
+		t.Errorf("queue holds %d requests, want it full at %d", len(p.queue), cap(p.queue))
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/grove/generic-proxy/internal/config"
+)
+
+// newHTTPServer builds the server with connection-level limits from config.
+// Handlers that stream for longer than WriteTimeout must extend their own deadline with
+// http.NewResponseController(w).SetWriteDeadline.
+func newHTTPServer(cfg *config.Config, addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.ServerReadHeaderTimeout,
+		ReadTimeout:       cfg.ServerReadTimeout,
+		WriteTimeout:      cfg.ServerWriteTimeout,
+		IdleTimeout:       cfg.ServerIdleTimeout,
+		MaxHeaderBytes:    int(cfg.ServerMaxHeaderBytes),
+	}
+}
+
+// listen opens the server socket, capped at maxConns concurrent connections (0 = unlimited)
+func listen(addr string, maxConns int) (net.Listener, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if maxConns > 0 {
+		listener = newLimitListener(listener, maxConns)
+	}
+	return listener, nil
+}
+
+// limitListener blocks Accept while maxConns connections are open, like netutil.LimitListener
+type limitListener struct {
+	net.Listener
+	sem       chan struct{}
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+func newLimitListener(l net.Listener, maxConns int) *limitListener {
+	return &limitListener{
+		Listener: l,
+		sem:      make(chan struct{}, maxConns),
+		done:     make(chan struct{}),
+	}
+}
+
+// Accept waits for a free slot before accepting, so excess clients queue in the kernel backlog
+func (l *limitListener) Accept() (net.Conn, error) {
+	select {
+	case l.sem <- struct{}{}:
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		<-l.sem
+		return nil, err
+	}
+	return &limitListenerConn{Conn: conn, release: func() { <-l.sem }}, nil
+}
+
+func (l *limitListener) Close() error {
+	err := l.Listener.Close()
+	l.closeOnce.Do(func() { close(l.done) })
+	return err
+}
+
+// limitListenerConn frees its slot exactly once when closed
+type limitListenerConn struct {
+	net.Conn
+	releaseOnce sync.Once
+	release     func()
+}
+
+func (c *limitListenerConn) Close() error {
+	err := c.Conn.Close()
+	c.releaseOnce.Do(c.release)
+	return err
+}
+
+// logServerLimits prints the connection limits at startup
+func logServerLimits(cfg *config.Config) {
+	log.Printf("[STARTUP] Server limits: read_header=%v read=%v write=%v idle=%v max_header_bytes=%d max_connections=%d (0 = unlimited)",
+		cfg.ServerReadHeaderTimeout, cfg.ServerReadTimeout, cfg.ServerWriteTimeout, cfg.ServerIdleTimeout,
+		cfg.ServerMaxHeaderBytes, cfg.ServerMaxConnections)
+}
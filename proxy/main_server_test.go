@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/config"
+)
+
+// startServer serves handler with the limits in cfg on a free local port; it is shut down when
+// the test ends
+func startServer(t *testing.T, cfg *config.Config, handler http.Handler) string {
+	t.Helper()
+	listener, err := listen("127.0.0.1:0", cfg.ServerMaxConnections)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	server := newHTTPServer(cfg, listener.Addr().String(), handler)
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Close() })
+	return listener.Addr().String()
+}
+
+func okHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "ok") })
+}
+
+func TestStalledClientIsDisconnected(t *testing.T) {
+	cfg := &config.Config{ServerReadHeaderTimeout: 200 * time.Millisecond, ServerReadTimeout: time.Minute, ServerMaxHeaderBytes: 64 << 10}
+	addr := startServer(t, cfg, okHandler())
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	// A slowloris client: headers started, never finished
+	io.WriteString(conn, "GET / HTTP/1.1\r\nHost: proxy\r\nX-Slow: 1\r\n")
+
+	start := time.Now()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	io.Copy(io.Discard, conn) // returns once the server hangs up
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("stalled client was held for %v, want about SERVER_READ_HEADER_TIMEOUT (200ms)", elapsed)
+	}
+}
+
+func TestOversizedHeadersAreRejected(t *testing.T) {
+	cfg := &config.Config{ServerReadHeaderTimeout: time.Second, ServerMaxHeaderBytes: 1 << 10}
+	addr := startServer(t, cfg, okHandler())
+
+	req, _ := http.NewRequest(http.MethodGet, "http://"+addr+"/", nil)
+	req.Header.Set("X-Big", strings.Repeat("a", 8<<10))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestHeaderFieldsTooLarge {
+		t.Errorf("status = %d, want 431", resp.StatusCode)
+	}
+}
+
+func TestConnectionCap(t *testing.T) {
+	cfg := &config.Config{ServerReadHeaderTimeout: time.Second, ServerMaxHeaderBytes: 64 << 10, ServerMaxConnections: 1}
+	addr := startServer(t, cfg, okHandler())
+
+	// get sends a request on conn and reports the status line on answered
+	get := func(conn net.Conn, answered chan<- string) {
+		io.WriteString(conn, "GET / HTTP/1.1\r\nHost: proxy\r\n\r\n")
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		answered <- line
+	}
+
+	first, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	firstAnswered := make(chan string, 1)
+	go get(first, firstAnswered)
+	if line := <-firstAnswered; !strings.Contains(line, "200") {
+		t.Fatalf("first connection: %q", line)
+	}
+
+	// The first connection is kept alive, so the second waits for its slot
+	second, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer second.Close()
+	secondAnswered := make(chan string, 1)
+	go get(second, secondAnswered)
+	select {
+	case line := <-secondAnswered:
+		t.Fatalf("second connection answered while the first was open: %q", line)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	first.Close()
+	select {
+	case line := <-secondAnswered:
+		if !strings.Contains(line, "200") {
+			t.Errorf("second connection: %q", line)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("second connection was not served after the first closed")
+	}
+}
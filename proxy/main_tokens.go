@@ -0,0 +1,242 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/db"
+	"github.com/grove/generic-proxy/internal/metrics"
+	"github.com/grove/generic-proxy/internal/middleware"
+	"github.com/grove/generic-proxy/internal/utils"
+)
+
+// tokenIssuer mints short-lived access tokens together with single-use refresh tokens
+type tokenIssuer struct {
+	database   *db.Database
+	secret     string
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	cookies    *middleware.CookieAuth // nil = tokens are only returned in the body
+}
+
+// RefreshRequest is the body of POST /api/auth/refresh and POST /api/auth/logout
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// issue creates an access token and a stored refresh token for a user
+func (t *tokenIssuer) issue(database *db.Database, userID, role string) (LoginResponse, error) {
+	token, err := utils.GenerateJWT(userID, role, t.secret, utils.TokenOptions{TTL: t.accessTTL})
+	if err != nil {
+		return LoginResponse{}, err
+	}
+	refreshToken, refreshHash, err := utils.NewRefreshToken()
+	if err != nil {
+		return LoginResponse{}, err
+	}
+	if err := database.StoreRefreshToken(refreshHash, userID, time.Now().Add(t.refreshTTL)); err != nil {
+		return LoginResponse{}, err
+	}
+	return LoginResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(t.accessTTL.Seconds()),
+		UserID:       userID,
+		Role:         role,
+	}, nil
+}
+
+// setCookies hands an issued access token to the browser in the auth cookie, when cookie auth is enabled
+func (t *tokenIssuer) setCookies(w http.ResponseWriter, response LoginResponse) error {
+	if t.cookies == nil {
+		return nil
+	}
+	return t.cookies.SetTokenCookies(w, response.Token, t.accessTTL)
+}
+
+// currentRole looks up a user's role at refresh time, so role changes and deletions take effect
+// with the next access token. ok is false when the user no longer exists.
+func currentRole(database *db.Database, userID string) (role string, ok bool, err error) {
+	if id, parseErr := strconv.ParseInt(userID, 10, 64); parseErr == nil {
+		user, err := database.GetUserByID(id)
+		if err != nil || user == nil {
+			return "", false, err
+		}
+		return user.Role, true, nil
+	}
+	for _, demo := range demoUsers {
+		if demo.UserID == userID {
+			return demo.Role, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// refreshHandler exchanges a refresh token for a new access token and a new refresh token.
+// The presented token is consumed, so a stolen token stops working once its owner refreshes.
+func refreshHandler(tokens *tokenIssuer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req RefreshRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+			respondWithError(w, http.StatusBadRequest, "refresh_token is required")
+			return
+		}
+
+		database := tokens.database.WithContext(r.Context())
+		refreshToken, refreshHash, err := utils.NewRefreshToken()
+		if err != nil {
+			log.Printf("[REFRESH ERROR] Failed to generate refresh token: %v", err)
+			respondWithError(w, http.StatusInternalServerError, "failed to generate token")
+			return
+		}
+		consumed, err := database.RotateRefreshToken(utils.HashRefreshToken(req.RefreshToken), refreshHash, time.Now().Add(tokens.refreshTTL))
+		if errors.Is(err, db.ErrRefreshTokenInvalid) || errors.Is(err, db.ErrRefreshTokenExpired) {
+			log.Printf("[REFRESH ERROR] Rejected refresh token: %v", err)
+			metrics.AuthFailure(metrics.AuthInvalidRefresh)
+			respondWithError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "failed to refresh token")
+			return
+		}
+
+		role, ok, err := currentRole(database, consumed.UserID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "failed to load user")
+			return
+		}
+		if !ok {
+			database.RevokeRefreshToken(refreshHash)
+			log.Printf("[REFRESH ERROR] User %s no longer exists", consumed.UserID)
+			respondWithError(w, http.StatusUnauthorized, "user no longer exists")
+			return
+		}
+
+		token, err := utils.GenerateJWT(consumed.UserID, role, tokens.secret, utils.TokenOptions{TTL: tokens.accessTTL})
+		if err != nil {
+			log.Printf("[REFRESH ERROR] Failed to generate JWT: %v", err)
+			respondWithError(w, http.StatusInternalServerError, "failed to generate token")
+			return
+		}
+
+		response := LoginResponse{
+			Token:        token,
+			RefreshToken: refreshToken,
+			ExpiresIn:    int(tokens.accessTTL.Seconds()),
+			UserID:       consumed.UserID,
+			Role:         role,
+		}
+		if err := tokens.setCookies(w, response); err != nil {
+			log.Printf("[REFRESH ERROR] Failed to set auth cookies: %v", err)
+			respondWithError(w, http.StatusInternalServerError, "failed to generate token")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(response)
+		log.Printf("[REFRESH] Rotated refresh token for user %s", consumed.UserID)
+	}
+}
+
+// tokenLogoutHandler revokes the refresh token in the body and blocklists the access token in the
+// Authorization header or auth cookie; at least one of them is required
+func tokenLogoutHandler(tokens *tokenIssuer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req RefreshRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		accessToken, _, err := middleware.BearerToken(r, tokens.cookies)
+		if errors.Is(err, middleware.ErrCSRFTokenMismatch) {
+			respondWithError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		hasAccessToken := err == nil
+		if req.RefreshToken == "" && !hasAccessToken {
+			respondWithError(w, http.StatusBadRequest, "refresh_token or an Authorization header is required")
+			return
+		}
+
+		database := tokens.database.WithContext(r.Context())
+		if req.RefreshToken != "" {
+			if err := database.RevokeRefreshToken(utils.HashRefreshToken(req.RefreshToken)); err != nil {
+				respondWithError(w, http.StatusInternalServerError, "failed to revoke token")
+				return
+			}
+			log.Printf("[LOGOUT] Refresh token revoked")
+		}
+		if hasAccessToken {
+			// An invalid or expired access token is harmless already
+			if claims, err := utils.ValidateJWT(accessToken, tokens.secret); err == nil && claims.ID != "" && claims.ExpiresAt != nil {
+				if err := database.RevokeToken(claims.ID, claims.ExpiresAt.Time); err != nil {
+					respondWithError(w, http.StatusInternalServerError, "failed to revoke token")
+					return
+				}
+				log.Printf("[LOGOUT] Access token of user %s revoked", claims.UserID)
+			}
+		}
+		if tokens.cookies != nil {
+			tokens.cookies.ClearTokenCookies(w)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// staleLoginAttemptAge is how long a failed login count is kept after the last failure
+const staleLoginAttemptAge = 24 * time.Hour
+
+// startTokenPurge runs purgeExpiredTokens in the background. A zero or negative interval would
+// make it a busy loop, so it is refused.
+func startTokenPurge(database *db.Database, interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("TOKEN_CLEANUP_INTERVAL must be positive, got %v", interval)
+	}
+	go purgeExpiredTokens(database, interval)
+	return nil
+}
+
+// purgeExpiredTokens removes expired refresh tokens, blocklist entries and password reset tokens,
+// and failed login counts untouched for a day, now and then every interval
+func purgeExpiredTokens(database *db.Database, interval time.Duration) {
+	for {
+		purgeTokensOnce(database)
+		time.Sleep(interval)
+	}
+}
+
+// purgeTokensOnce is one pass of purgeExpiredTokens; a failed delete is logged and the others still run
+func purgeTokensOnce(database *db.Database) {
+	refreshRemoved, err := database.DeleteExpiredRefreshTokens()
+	if err != nil {
+		log.Printf("[TOKENS ERROR] Failed to purge expired refresh tokens: %v", err)
+	}
+	revokedRemoved, err := database.DeleteExpiredRevokedTokens()
+	if err != nil {
+		log.Printf("[TOKENS ERROR] Failed to purge expired blocklist entries: %v", err)
+	}
+	resetRemoved, err := database.DeleteExpiredPasswordResetTokens()
+	if err != nil {
+		log.Printf("[TOKENS ERROR] Failed to purge expired reset tokens: %v", err)
+	}
+	if _, err := database.DeleteStaleLoginAttempts(time.Now().Add(-staleLoginAttemptAge)); err != nil {
+		log.Printf("[TOKENS ERROR] Failed to purge stale login attempts: %v", err)
+	}
+	if refreshRemoved > 0 || revokedRemoved > 0 || resetRemoved > 0 {
+		log.Printf("[TOKENS] Purged %d expired refresh tokens, %d expired blocklist entries and %d expired reset tokens", refreshRemoved, revokedRemoved, resetRemoved)
+	}
+}
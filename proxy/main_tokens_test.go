@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/middleware"
+	"github.com/grove/generic-proxy/internal/utils"
+)
+
+const testJWTSecret = "test-secret-at-least-32-bytes-long!!"
+
+func refreshBody(token string) string {
+	return `{"refresh_token":"` + token + `"}`
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	database := newTestDatabase(t)
+	user, err := database.CreateLocalUser("user@example.com", "password", "User")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	tokens := &tokenIssuer{database: database, secret: testJWTSecret, accessTTL: 15 * time.Minute, refreshTTL: time.Hour}
+	login, err := tokens.issue(database, strconv.FormatInt(user.ID, 10), "user")
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+	refresh := refreshHandler(tokens)
+
+	rec := postJSON(refresh, "/api/auth/refresh", refreshBody(login.RefreshToken))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("refresh: status = %d, body %s", rec.Code, rec.Body)
+	}
+	var refreshed LoginResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &refreshed); err != nil {
+		t.Fatalf("refresh body %s: %v", rec.Body, err)
+	}
+	if refreshed.RefreshToken == "" || refreshed.RefreshToken == login.RefreshToken {
+		t.Errorf("refresh token was not rotated")
+	}
+	claims, err := utils.ValidateJWT(refreshed.Token, testJWTSecret)
+	if err != nil || claims.UserID != login.UserID || time.Until(claims.ExpiresAt.Time) > 15*time.Minute {
+		t.Errorf("access token claims %+v (err %v), want a 15 minute token for user %s", claims, err, login.UserID)
+	}
+
+	if rec := postJSON(refresh, "/api/auth/refresh", refreshBody(login.RefreshToken)); rec.Code != http.StatusUnauthorized {
+		t.Errorf("reused token: status = %d, want 401", rec.Code)
+	}
+
+	// Logging out revokes the current refresh token
+	if rec := postJSON(tokenLogoutHandler(tokens), "/api/auth/logout", refreshBody(refreshed.RefreshToken)); rec.Code != http.StatusNoContent {
+		t.Fatalf("logout: status = %d, body %s", rec.Code, rec.Body)
+	}
+	if rec := postJSON(refresh, "/api/auth/refresh", refreshBody(refreshed.RefreshToken)); rec.Code != http.StatusUnauthorized {
+		t.Errorf("token after logout: status = %d, want 401", rec.Code)
+	}
+}
+
+func TestRefreshTokenExpiry(t *testing.T) {
+	database := newTestDatabase(t)
+	user, err := database.CreateLocalUser("user@example.com", "password", "User")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	tokens := &tokenIssuer{database: database, secret: testJWTSecret, accessTTL: time.Minute, refreshTTL: -time.Second}
+	login, err := tokens.issue(database, strconv.FormatInt(user.ID, 10), "user")
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+	rec := postJSON(refreshHandler(tokens), "/api/auth/refresh", refreshBody(login.RefreshToken))
+	if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), "expired") {
+		t.Errorf("status = %d, body %s; want 401 expired", rec.Code, rec.Body)
+	}
+}
+
+// Role changes and deletions apply from the next refresh on
+func TestRefreshUsesCurrentRole(t *testing.T) {
+	database := newTestDatabase(t)
+	admin, _ := database.CreateLocalUser("admin@example.com", "password", "Admin")
+	database.UpdateUserRole(admin.ID, "admin")
+	user, err := database.CreateLocalUser("user@example.com", "password", "User")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	tokens := &tokenIssuer{database: database, secret: testJWTSecret, accessTTL: time.Minute, refreshTTL: time.Hour}
+	userID := strconv.FormatInt(user.ID, 10)
+	login, _ := tokens.issue(database, userID, "user")
+	refresh := refreshHandler(tokens)
+
+	database.UpdateUserRole(user.ID, "admin")
+	rec := postJSON(refresh, "/api/auth/refresh", refreshBody(login.RefreshToken))
+	var refreshed LoginResponse
+	json.Unmarshal(rec.Body.Bytes(), &refreshed)
+	if refreshed.Role != "admin" {
+		t.Errorf("role after promotion = %q, body %s", refreshed.Role, rec.Body)
+	}
+
+	serveAdmin(adminUsersHandler(database), http.MethodDelete, "/api/admin/users/"+userID, "", strconv.FormatInt(admin.ID, 10), "admin")
+	if rec := postJSON(refresh, "/api/auth/refresh", refreshBody(refreshed.RefreshToken)); rec.Code != http.StatusUnauthorized {
+		t.Errorf("deleted user: status = %d, body %s; want 401", rec.Code, rec.Body)
+	}
+}
+
+// Logging out with the access token blocklists it for AuthMiddleware
+func TestLogoutRevokesAccessToken(t *testing.T) {
+	database := newTestDatabase(t)
+	tokens := &tokenIssuer{database: database, secret: testJWTSecret, accessTTL: time.Hour, refreshTTL: time.Hour}
+	login, err := tokens.issue(database, "1", "user")
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+	protected := middleware.AuthMiddleware(testJWTSecret, database, nil)(okHandler())
+	request := func(handler http.Handler, path string) int {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set("Authorization", "Bearer "+login.Token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := request(protected, "/proxy/quotes/records"); code != http.StatusOK {
+		t.Fatalf("before logout: status %d", code)
+	}
+	if code := request(tokenLogoutHandler(tokens), "/api/auth/logout"); code != http.StatusNoContent {
+		t.Fatalf("logout: status %d", code)
+	}
+	if code := request(protected, "/proxy/quotes/records"); code != http.StatusUnauthorized {
+		t.Errorf("after logout: status %d, want 401", code)
+	}
+}
+
+func TestTokenPurgeRejectsNonPositiveInterval(t *testing.T) {
+	database := newTestDatabase(t)
+	for _, interval := range []time.Duration{0, -time.Minute} {
+		if err := startTokenPurge(database, interval); err == nil {
+			t.Errorf("interval %v: started a purge loop, want an error", interval)
+		}
+	}
+}
+
+func TestTokenPurgeLogsFailures(t *testing.T) {
+	database := newTestDatabase(t)
+	database.Close() // every delete fails from here on
+
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+	purgeTokensOnce(database)
+
+	for _, want := range []string{"expired refresh tokens", "expired blocklist entries", "expired reset tokens", "stale login attempts"} {
+		if !strings.Contains(logged.String(), "Failed to purge "+want) {
+			t.Errorf("no error logged for %s in:\n%s", want, logged.String())
+		}
+	}
+}